@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/lamim/vellumforge2/internal/config"
 	"github.com/lamim/vellumforge2/internal/hfhub"
 )
 
@@ -18,13 +20,15 @@ func main() {
 	repoID := os.Args[1]
 	sessionDir := os.Args[2]
 
-	// Get token from environment
-	token := os.Getenv("HUGGINGFACE_TOKEN")
-	if token == "" {
-		token = os.Getenv("HF_TOKEN")
+	// Get token from environment (same resolution as the main CLI)
+	secrets, err := config.LoadSecrets()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load secrets: %v\n", err)
+		os.Exit(1)
 	}
+	token := secrets.HuggingFaceToken
 	if token == "" {
-		fmt.Fprintf(os.Stderr, "Error: HUGGINGFACE_TOKEN environment variable not set\n")
+		fmt.Fprintf(os.Stderr, "Error: no Hugging Face token set (%s)\n", strings.Join(config.HuggingFaceTokenEnvVars, ", "))
 		os.Exit(1)
 	}
 
@@ -40,7 +44,7 @@ func main() {
 	}))
 
 	// Create uploader
-	uploader := hfhub.NewUploader(token, logger)
+	uploader := hfhub.NewUploader(token, logger, hfhub.UploaderOptions{})
 
 	fmt.Printf("Uploading session to HuggingFace Hub...\n")
 	fmt.Printf("  Repository: %s\n", repoID)
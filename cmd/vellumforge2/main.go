@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -17,8 +21,10 @@ import (
 	"github.com/lamim/vellumforge2/internal/config"
 	"github.com/lamim/vellumforge2/internal/dataset"
 	"github.com/lamim/vellumforge2/internal/hfhub"
+	"github.com/lamim/vellumforge2/internal/metrics"
 	"github.com/lamim/vellumforge2/internal/orchestrator"
 	"github.com/lamim/vellumforge2/internal/writer"
+	"github.com/lamim/vellumforge2/pkg/models"
 )
 
 var (
@@ -28,11 +34,24 @@ var (
 )
 
 var (
-	configPath string
-	envFile    string
-	uploadToHF bool
-	hfRepoID   string
-	verbose    bool
+	configPath   string
+	envFile      string
+	uploadToHF   bool
+	hfRepoID     string
+	hfBranch     string
+	hfAppend     bool
+	hfShard      bool
+	hfForce      bool
+	noCache      bool
+	noJudgeCache bool
+	keepFailed   bool
+	benchmark    time.Duration
+	debugDump    string
+	verbose      bool
+	logFormat    string
+
+	metricsFile     string
+	metricsInterval time.Duration
 
 	transformMode                string
 	transformInputPath           string
@@ -41,6 +60,25 @@ var (
 	transformResume              bool
 	transformInputReasoningPath  string
 	transformOutputReasoningPath string
+	transformContinueOnError     bool
+	transformFailuresPath        string
+	transformRetryFailures       bool
+	transformForce               bool
+	transformAllowOutputChange   bool
+	transformConcurrency         int
+	transformCheckpointInterval  int
+
+	convertFrom      string
+	convertTo        string
+	convertSFTFormat string
+	convertForce     bool
+
+	initMode string
+
+	repairDryRun bool
+	replayDryRun bool
+
+	planOutput string
 )
 
 func main() {
@@ -64,11 +102,42 @@ high-quality Direct Preference Optimization (DPO) datasets using LLMs.`,
 		RunE: runGeneration,
 	}
 
-	runCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file")
+	runCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file, - to read it from stdin, or an http(s) URL to fetch it from")
 	runCmd.Flags().StringVar(&envFile, "env-file", ".env", "Path to environment file")
 	runCmd.Flags().BoolVar(&uploadToHF, "upload-to-hf", false, "Upload results to Hugging Face Hub")
 	runCmd.Flags().StringVar(&hfRepoID, "hf-repo-id", "", "Hugging Face repository ID (e.g., username/dataset-name)")
+	runCmd.Flags().StringVar(&hfBranch, "hf-branch", "", "Hugging Face branch to commit to (default: main, created if missing)")
+	runCmd.Flags().BoolVar(&hfAppend, "hf-append", false, "Append rows to the existing remote dataset instead of replacing it")
+	runCmd.Flags().BoolVar(&hfShard, "hf-shard", false, "Split dataset files above HF's 10MB text limit into dataset-00001.jsonl, ... shards so the viewer can render them")
+	runCmd.Flags().BoolVar(&hfForce, "hf-force", false, "Upload even when the remote dataset files already match the session")
 	runCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	runCmd.Flags().StringVar(&logFormat, "log-format", "", "Console log format: text or json (default: logging.format, or text); the session log file format is set by logging.file_format")
+	runCmd.Flags().BoolVar(&noCache, "no-cache", false, "Ignore the prompt cache for this run (always regenerate prompts)")
+	runCmd.Flags().BoolVar(&noJudgeCache, "no-judge-cache", false, "Ignore the judge cache for this run (always call the judge)")
+	runCmd.Flags().BoolVar(&keepFailed, "keep-failed", false, "Write each failed job's prompt and error to failures.jsonl in the session directory (generation.keep_failed)")
+	runCmd.Flags().StringVar(&debugDump, "debug-dump", "", "Write every API request/response body to this directory (Authorization redacted)")
+	runCmd.Flags().DurationVar(&benchmark, "benchmark", 0, "Run for this long (e.g. 5m), then report throughput and a projected completion time instead of finishing")
+	runCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Periodically write Prometheus text-format metrics to this file (e.g. for node_exporter's textfile collector)")
+	runCmd.Flags().DurationVar(&metricsInterval, "metrics-interval", 15*time.Second, "How often --metrics-file is rewritten")
+
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Generate only the subtopics and prompts and write them to a plan file",
+		Long: `Run the planning phases of the pipeline and stop before any preference pairs are generated:
+1. Generate subtopics from main topic
+2. Generate prompts for each subtopic
+
+The result is written to plan.json (--output) for reviewing topical coverage and templates
+before committing to a full run. No session or dataset is created. With the prompt cache
+enabled, a following run with the same templates reuses the planned prompts.`,
+		RunE: runPlan,
+	}
+
+	planCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file, - to read it from stdin, or an http(s) URL to fetch it from")
+	planCmd.Flags().StringVar(&envFile, "env-file", ".env", "Path to environment file")
+	planCmd.Flags().StringVarP(&planOutput, "output", "o", "plan.json", "Path to write the plan to")
+	planCmd.Flags().BoolVar(&noCache, "no-cache", false, "Ignore the prompt cache for this run (always regenerate prompts)")
+	planCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
 
 	// Checkpoint management commands
 	checkpointCmd := &cobra.Command{
@@ -100,15 +169,64 @@ high-quality Direct Preference Optimization (DPO) datasets using LLMs.`,
 		RunE:  resumeFromCheckpoint,
 	}
 
-	resumeCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file")
+	resumeCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file, - to read it from stdin, or an http(s) URL to fetch it from")
 	resumeCmd.Flags().StringVar(&envFile, "env-file", ".env", "Path to environment file")
 	resumeCmd.Flags().BoolVar(&uploadToHF, "upload-to-hf", false, "Upload results to Hugging Face Hub after resume completes")
 	resumeCmd.Flags().StringVar(&hfRepoID, "hf-repo-id", "", "Hugging Face repository ID (e.g., username/dataset-name) for resume uploads")
+	resumeCmd.Flags().StringVar(&hfBranch, "hf-branch", "", "Hugging Face branch to commit to for resume uploads (default: main)")
+	resumeCmd.Flags().BoolVar(&hfAppend, "hf-append", false, "Append rows to the existing remote dataset instead of replacing it")
+	resumeCmd.Flags().BoolVar(&hfShard, "hf-shard", false, "Split dataset files above HF's 10MB text limit into dataset-00001.jsonl, ... shards so the viewer can render them")
+	resumeCmd.Flags().BoolVar(&hfForce, "hf-force", false, "Upload even when the remote dataset files already match the session")
 	resumeCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	resumeCmd.Flags().StringVar(&logFormat, "log-format", "", "Console log format: text or json (default: logging.format, or text); the session log file format is set by logging.file_format")
+	resumeCmd.Flags().BoolVar(&noCache, "no-cache", false, "Ignore the prompt cache for this run (always regenerate prompts)")
+	resumeCmd.Flags().BoolVar(&noJudgeCache, "no-judge-cache", false, "Ignore the judge cache for this run (always call the judge)")
+	resumeCmd.Flags().BoolVar(&keepFailed, "keep-failed", false, "Write each failed job's prompt and error to failures.jsonl in the session directory (generation.keep_failed)")
+	resumeCmd.Flags().StringVar(&debugDump, "debug-dump", "", "Write every API request/response body to this directory (Authorization redacted)")
+	resumeCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "Periodically write Prometheus text-format metrics to this file (e.g. for node_exporter's textfile collector)")
+	resumeCmd.Flags().DurationVar(&metricsInterval, "metrics-interval", 15*time.Second, "How often --metrics-file is rewritten")
+
+	repairCmd := &cobra.Command{
+		Use:   "repair <session-dir>",
+		Short: "Reconcile a killed session's checkpoint with its dataset",
+		Long: `Repair a session interrupted by a hard kill (SIGKILL, OOM, power loss) so it can be resumed safely.
+
+Dataset rows are matched to checkpoint jobs by prompt text:
+  - Jobs with rows on disk that the checkpoint missed are marked completed (no duplicate rows on resume)
+  - Jobs the checkpoint counted without rows on disk (e.g. a lost MO-DPO buffer) are marked pending
+  - A partial last line left by an interrupted write is truncated
+The previous checkpoint is kept as checkpoint.json.bak.
+
+In mo-dpo mode with judge_filtering.min_preference_margin, rows dropped by the filter also look
+missing and will be regenerated on resume.`,
+		Args: cobra.ExactArgs(1),
+		RunE: repairCheckpoint,
+	}
+	repairCmd.Flags().BoolVar(&repairDryRun, "dry-run", false, "Report what would be repaired without changing any files")
 
 	checkpointCmd.AddCommand(listCmd)
 	checkpointCmd.AddCommand(inspectCmd)
 	checkpointCmd.AddCommand(resumeCmd)
+	checkpointCmd.AddCommand(repairCmd)
+
+	replayCmd := &cobra.Command{
+		Use:   "replay <session-dir>",
+		Short: "Regenerate only the missing rows of a finished session",
+		Long: `Regenerate the jobs a finished session has no row for (failed jobs, and filtered ones) and
+append the new rows to its dataset. Completed jobs are not redone.
+
+The session's checkpoint is reopened with its failure counts reset, so the final summary only
+counts jobs that failed again. Use checkpoint resume for sessions that were interrupted.`,
+		Args: cobra.ExactArgs(1),
+		RunE: replaySession,
+	}
+	replayCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file, - to read it from stdin, or an http(s) URL to fetch it from")
+	replayCmd.Flags().StringVar(&envFile, "env-file", ".env", "Path to environment file")
+	replayCmd.Flags().BoolVar(&replayDryRun, "dry-run", false, "List the jobs that would be replayed without changing any files")
+	replayCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	replayCmd.Flags().BoolVar(&keepFailed, "keep-failed", false, "Write each failed job's prompt and error to failures.jsonl in the session directory (generation.keep_failed)")
+	replayCmd.Flags().StringVar(&logFormat, "log-format", "", "Console log format: text or json (default: logging.format, or text); the session log file format is set by logging.file_format")
+	replayCmd.Flags().StringVar(&debugDump, "debug-dump", "", "Write every API request/response body to this directory (Authorization redacted)")
 
 	transformCmd := &cobra.Command{
 		Use:   "transform",
@@ -117,26 +235,129 @@ high-quality Direct Preference Optimization (DPO) datasets using LLMs.`,
 
 Modes:
   - sft-to-dpo:       Convert an SFT dataset into a DPO dataset by generating rejected responses
-  - regen-rejected:   Regenerate rejected responses for an existing DPO dataset`,
+  - regen-rejected:   Regenerate rejected responses for an existing DPO dataset
+
+Reasoning datasets (regen-rejected only):
+  --input-reasoning reads prompts and chosen responses from a reasoning DPO dataset
+  (chosen/rejected with <think> blocks). With --output-reasoning the rejected reasoning
+  is preserved around the new rejected answer; with --output the non-reasoning dataset
+  is reconstructed from the reasoning chosen with think blocks removed. Set both outputs
+  to regenerate the reasoning and non-reasoning datasets in one run.`,
 		RunE: runTransform,
 	}
 
-	transformCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file")
+	transformCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file, - to read it from stdin, or an http(s) URL to fetch it from")
 	transformCmd.Flags().StringVar(&envFile, "env-file", ".env", "Path to environment file")
 	transformCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+	transformCmd.Flags().StringVar(&debugDump, "debug-dump", "", "Write every API request/response body to this directory (Authorization redacted)")
 	transformCmd.Flags().StringVar(&transformMode, "mode", "", "Transform mode: 'sft-to-dpo' or 'regen-rejected'")
-	transformCmd.Flags().StringVar(&transformInputPath, "input", "", "Path to input JSONL dataset (non-reasoning)")
-	transformCmd.Flags().StringVar(&transformOutputPath, "output", "", "Path to output JSONL dataset (non-reasoning)")
+	transformCmd.Flags().StringVar(&transformInputPath, "input", "", "Path to input JSONL dataset (non-reasoning), or - to read it from stdin")
+	transformCmd.Flags().StringVar(&transformOutputPath, "output", "", "Path to output JSONL dataset (non-reasoning), or - to write it to stdout")
 	transformCmd.Flags().StringVar(&transformCheckpointPath, "checkpoint", "", "Path to transform checkpoint file (defaults to derived from output paths)")
 	transformCmd.Flags().BoolVar(&transformResume, "resume", false, "Resume transform from an existing checkpoint")
-	transformCmd.Flags().StringVar(&transformInputReasoningPath, "input-reasoning", "", "Path to reasoning JSONL dataset input (optional)")
-	transformCmd.Flags().StringVar(&transformOutputReasoningPath, "output-reasoning", "", "Path to reasoning JSONL dataset output (optional)")
+	transformCmd.Flags().StringVar(&transformInputReasoningPath, "input-reasoning", "", "Path to reasoning DPO JSONL input; replaces --input as the source of prompts and chosen (regen-rejected only)")
+	transformCmd.Flags().StringVar(&transformOutputReasoningPath, "output-reasoning", "", "Path to reasoning DPO JSONL output; requires --input-reasoning (regen-rejected only)")
+	transformCmd.Flags().BoolVar(&transformContinueOnError, "continue-on-error", false, "Skip jobs that exhaust their retries instead of aborting the transform")
+	transformCmd.Flags().StringVar(&transformFailuresPath, "failures", "", "Path to failures JSONL file for skipped jobs (defaults to <output>.failures.jsonl)")
+	transformCmd.Flags().BoolVar(&transformRetryFailures, "retry-failures", false, "Re-run jobs skipped in a previous run (requires --resume)")
+	transformCmd.Flags().BoolVar(&transformForce, "force", false, "Resume even if the input files changed since the checkpoint was created (requires --resume)")
+	transformCmd.Flags().BoolVar(&transformAllowOutputChange, "allow-output-change", false, "Resume into different --output/--output-reasoning paths; earlier rows stay in the old files (requires --resume and --checkpoint)")
+	transformCmd.Flags().IntVar(&transformConcurrency, "concurrency", 0, "Parallel rejected generations (default: generation.concurrency from config)")
+	transformCmd.Flags().IntVar(&transformCheckpointInterval, "checkpoint-interval", 0, "Save transform checkpoint every N completed jobs (default: generation.checkpoint_interval from config)")
 
 	_ = transformCmd.MarkFlagRequired("mode")
 
+	convertCmd := &cobra.Command{
+		Use:   "convert <input> <output>",
+		Short: "Convert a dataset between formats offline (DPO, KTO, SFT)",
+		Long: `Convert a JSONL dataset from one format to another without calling any model.
+
+Conversions:
+  - dpo -> kto:     Each pair becomes a desirable and an undesirable row (pairing is not kept)
+  - dpo -> sft:     Chosen becomes the output; rejected responses are dropped
+  - kto -> dpo:     Completions are paired by prompt; prompts missing either label are skipped
+  - kto -> sft:     Desirable completions become the output; undesirable ones are dropped
+  - mo-dpo -> any:  Read as DPO; judge scores and topic columns are dropped
+SFT input cannot be converted (there are no rejected responses); use transform --mode sft-to-dpo.
+Rows repeated by rejected_output_shape = "rows" are written once to KTO and SFT outputs.
+
+Example:
+  vellumforge2 convert --from dpo --to kto dataset.jsonl dataset.kto.jsonl`,
+		Args: cobra.ExactArgs(2),
+		RunE: runConvert,
+	}
+
+	convertCmd.Flags().StringVar(&convertFrom, "from", "", "Input format: dpo, kto or mo-dpo")
+	convertCmd.Flags().StringVar(&convertTo, "to", "", "Output format: dpo, kto or sft")
+	convertCmd.Flags().StringVar(&convertSFTFormat, "sft-format", string(models.SFTFormatShareGPT), "SFT output format: alpaca, sharegpt or openai")
+	convertCmd.Flags().BoolVar(&convertForce, "force", false, "Replace the output file if it exists")
+	_ = convertCmd.MarkFlagRequired("from")
+	_ = convertCmd.MarkFlagRequired("to")
+
+	// Config scaffolding commands
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage configuration files",
+		Long:  "Generate starter configuration files and inspect resolved configs",
+	}
+
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Print a starter config for a dataset mode",
+		Long: `Print a commented starter config with placeholder models and prompt templates.
+All other values are the defaults the config loader applies.
+
+Example:
+  vellumforge2 config init --mode dpo > config.toml`,
+		Args: cobra.NoArgs,
+		RunE: initConfig,
+	}
+
+	initCmd.Flags().StringVar(&initMode, "mode", string(models.DatasetModeDPO), "Dataset mode: sft, dpo, kto, or mo-dpo")
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Validate a config and print it with defaults applied",
+		Long: `Load and validate a config file, then print the fully-resolved config as TOML.
+Every default the loader and validation apply is filled in, so this shows the values
+a run would actually use. Credentials in base_url or extra_body are redacted.
+
+Example:
+  vellumforge2 config check --config config.toml`,
+		Args: cobra.NoArgs,
+		RunE: checkConfig,
+	}
+
+	checkCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file, - to read it from stdin, or an http(s) URL to fetch it from")
+
+	configCmd.AddCommand(initCmd)
+	configCmd.AddCommand(checkCmd)
+
+	probeCmd := &cobra.Command{
+		Use:   "probe <model>",
+		Short: "Detect which request features a configured model supports",
+		Long: `Send a few small requests to models.<model> from the config to detect JSON mode,
+streaming (with reasoning_content) and seed support, then print the recommended
+settings as a TOML snippet.
+
+Example:
+  vellumforge2 probe main --config config.toml`,
+		Args: cobra.ExactArgs(1),
+		RunE: runProbe,
+	}
+
+	probeCmd.Flags().StringVar(&configPath, "config", "config.toml", "Path to configuration file, - to read it from stdin, or an http(s) URL to fetch it from")
+	probeCmd.Flags().StringVar(&envFile, "env-file", ".env", "Path to environment file")
+	probeCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(planCmd)
 	rootCmd.AddCommand(checkpointCmd)
+	rootCmd.AddCommand(replayCmd)
 	rootCmd.AddCommand(transformCmd)
+	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(probeCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -158,11 +379,29 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
+	if noCache {
+		cfg.Generation.EnablePromptCache = false
+	}
+	if noJudgeCache {
+		cfg.Generation.EnableJudgeCache = false
+	}
+	if keepFailed {
+		cfg.Generation.KeepFailed = true
+	}
+	if benchmark < 0 {
+		return fmt.Errorf("--benchmark must be positive (got %s)", benchmark)
+	}
+	if benchmark > 0 {
+		// The benchmark is a time budget; the session stays resumable like any max_runtime stop
+		cfg.Generation.MaxRuntime = benchmark.String()
+	}
 
-	// Determine log level
-	logLevel := slog.LevelInfo
-	if verbose {
-		logLevel = slog.LevelDebug
+	logOpts, err := logOptions(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkUploadConfig(cfg); err != nil {
+		return err
 	}
 
 	// Check for resume mode
@@ -175,7 +414,7 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 	}
 
 	// Set up logger
-	logger, logFile, err := writer.SetupLogger(sessionMgr, logLevel)
+	logger, logFile, err := writer.SetupLogger(sessionMgr, logOpts)
 	if err != nil {
 		return fmt.Errorf("failed to setup logger: %w", err)
 	}
@@ -201,13 +440,33 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create API client
-	apiClient := api.NewClient(logger)
+	apiClient := api.NewClientWithNetwork(logger, cfg.Network)
+	apiClient.SetUserAgent(userAgent(cfg.Network))
+	apiClient.SetRetryConfig(cfg.Retry)
+	apiClient.SetSeed(cfg.Generation.Seed)
+	apiClient.SetKeyRotator(secrets)
+	if err := apiClient.ConfigureTLS(cfg.Network, cfg.Models); err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	if debugDump != "" {
+		if err := apiClient.SetDebugDumpDir(debugDump); err != nil {
+			return err
+		}
+		logger.Warn("Debug dump enabled - request and response bodies will be written to disk", "dir", debugDump)
+	}
 
 	// Set provider-level rate limits if configured
 	if len(cfg.ProviderRateLimits) > 0 {
 		apiClient.SetProviderRateLimits(cfg.ProviderRateLimits, cfg.ProviderBurstPercent)
 		logger.Info("Provider rate limits configured", "providers", cfg.ProviderRateLimits, "burst_percent", cfg.ProviderBurstPercent)
 	}
+	if cfg.ProviderAdaptiveRateLimit {
+		apiClient.SetAdaptiveRateLimit(true)
+		logger.Info("Adaptive rate limiting enabled - request rate drops after repeated 429s and recovers gradually")
+	}
+	if err := detectContextSizes(cfg, secrets, apiClient); err != nil {
+		return err
+	}
 
 	// Set up checkpoint manager
 	var checkpointMgr *checkpoint.Manager
@@ -235,7 +494,7 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 
 	// Create dataset writer (append mode for resume)
 	// Use dual dataset writer if reasoning capture is enabled
-	expectedRecords := cfg.Generation.NumSubtopics * cfg.Generation.NumPromptsPerSubtopic
+	expectedRecords := cfg.Generation.NumSubtopics * cfg.Generation.NumPromptsPerSubtopic * len(cfg.Generation.Topics())
 	var dataWriter writer.Writer
 	if cfg.Generation.EnableReasoningCapture {
 		dataWriter, err = writer.NewDualDatasetWriter(sessionMgr, logger, resumeMode, expectedRecords)
@@ -249,7 +508,27 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("failed to create dataset writer: %w", err)
 		}
 	}
+	if cfg.Generation.DatasetMode == models.DatasetModeMODPO && cfg.JudgeFiltering.MinPreferenceMargin > 0 {
+		dataWriter.SetMinPreferenceMargin(cfg.JudgeFiltering.MinPreferenceMargin)
+		logger.Info("Minimum preference margin filter enabled", "min_preference_margin", cfg.JudgeFiltering.MinPreferenceMargin)
+	}
+	if cfg.Generation.DatasetMode == models.DatasetModeMODPO && cfg.Generation.MaxBufferedRecords > 0 {
+		dataWriter.SetMaxBufferedRecords(cfg.Generation.MaxBufferedRecords)
+		logger.Info("MO-DPO record buffer capped, judged records are written early", "max_buffered_records", cfg.Generation.MaxBufferedRecords)
+	}
+	if cfg.Generation.IncludeID {
+		dataWriter.SetIncludeIDs(true)
+	}
+	if cfg.Generation.RecordFilterCommand != "" {
+		timeout := time.Duration(cfg.Generation.RecordFilterTimeoutSeconds) * time.Second
+		dataWriter = writer.NewFilteredWriter(dataWriter, writer.NewRecordFilter(cfg.Generation.RecordFilterCommand, timeout), logger)
+		logger.Info("Record filter command enabled", "command", cfg.Generation.RecordFilterCommand)
+	}
+	writerClosed := false
 	defer func() {
+		if writerClosed {
+			return
+		}
 		if err := dataWriter.Close(); err != nil {
 			logger.Error("failed to close data writer", "error", err)
 		}
@@ -257,18 +536,33 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 
 	// Create orchestrator with checkpoint manager
 	orch := orchestrator.New(cfg, secrets, apiClient, dataWriter, checkpointMgr, resumeMode, logger)
+	stopMetrics, err := startMetricsExport(apiClient, orch, logger)
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
+	closeFailureLog, err := openFailureLog(cfg, sessionMgr, resumeMode, orch, logger)
+	if err != nil {
+		return err
+	}
+	defer closeFailureLog()
 
 	// Run generation pipeline with signal-aware context for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	if err := orch.Run(ctx); err != nil {
-		if err == context.Canceled {
+		if benchmark > 0 && errors.Is(err, orchestrator.ErrMaxRuntimeExceeded) {
+			printBenchmarkReport(orch.ThroughputReport(), benchmark, filepath.Base(sessionMgr.GetSessionDir()))
+			return nil
+		}
+		if err == context.Canceled || errors.Is(err, orchestrator.ErrMaxRuntimeExceeded) {
 			sessionDir := filepath.Base(sessionMgr.GetSessionDir())
 			logger.Warn("Generation interrupted - resume from checkpoint",
+				"reason", interruptReason(err),
 				"session_dir", sessionDir,
 				"resume_command", fmt.Sprintf("Set resume_from_session = \"%s\" in config.toml", sessionDir))
-			return fmt.Errorf("generation interrupted (resume by setting resume_from_session in config)")
+			return fmt.Errorf("generation %s (resume by setting resume_from_session in config)", interruptReason(err))
 		}
 		return fmt.Errorf("generation failed: %w", err)
 	}
@@ -279,8 +573,30 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 		"total_prompts", stats.TotalPrompts,
 		"successful", stats.SuccessCount,
 		"failed", stats.FailureCount,
+		"error_breakdown", stats.ErrorCounts,
 		"duration", stats.TotalDuration,
 		"session_dir", sessionMgr.GetSessionDir())
+	logKeyPoolStats(logger, secrets)
+	if len(stats.SystemFingerprints) > 0 {
+		logger.Info("System fingerprints", "fingerprints", stats.SystemFingerprints)
+	}
+	if cfg.Generation.DatasetMode == models.DatasetModeMODPO && stats.JudgeSuccesses+stats.JudgeFailures > 0 {
+		logger.Info("Judge summary",
+			"judge_successes", stats.JudgeSuccesses,
+			"judge_failures", stats.JudgeFailures)
+	}
+
+	// Close the writer now so buffered MO-DPO rows are on disk for the report and upload
+	writerClosed = true
+	if err := dataWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close data writer: %w", err)
+	}
+	if _, err := writer.WriteQualityReport(sessionMgr, cfg.Generation.DatasetMode, logger); err != nil {
+		logger.Warn("Failed to write quality report", "error", err)
+	}
+	if benchmark > 0 {
+		printBenchmarkReport(orch.ThroughputReport(), benchmark, "")
+	}
 
 	if err := maybeUploadToHuggingFace(cfg, secrets, sessionMgr, logger); err != nil {
 		return err
@@ -290,8 +606,161 @@ func runGeneration(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runPlan runs the subtopics and prompts phases only and writes their output to --output
+func runPlan(cmd *cobra.Command, args []string) error {
+	if envFile != "" {
+		if err := loadEnvFile(envFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load env file: %v\n", err)
+		}
+	}
+
+	cfg, secrets, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if noCache {
+		cfg.Generation.EnablePromptCache = false
+	}
+
+	logLevel := slog.LevelInfo
+	if verbose {
+		logLevel = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+	apiClient := api.NewClientWithNetwork(logger, cfg.Network)
+	apiClient.SetUserAgent(userAgent(cfg.Network))
+	apiClient.SetRetryConfig(cfg.Retry)
+	apiClient.SetSeed(cfg.Generation.Seed)
+	apiClient.SetKeyRotator(secrets)
+	if err := apiClient.ConfigureTLS(cfg.Network, cfg.Models); err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	if len(cfg.ProviderRateLimits) > 0 {
+		apiClient.SetProviderRateLimits(cfg.ProviderRateLimits, cfg.ProviderBurstPercent)
+	}
+	if cfg.ProviderAdaptiveRateLimit {
+		apiClient.SetAdaptiveRateLimit(true)
+	}
+	if err := detectContextSizes(cfg, secrets, apiClient); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// No writer or checkpoint: planning never reaches the worker pool
+	orch := orchestrator.New(cfg, secrets, apiClient, nil, nil, false, logger)
+	plan, err := orch.Plan(ctx)
+	if err != nil {
+		return fmt.Errorf("planning failed: %w", err)
+	}
+	if err := plan.Write(planOutput); err != nil {
+		return err
+	}
+
+	fmt.Printf("Plan written to %s\n", planOutput)
+	for _, topic := range plan.Topics {
+		prompts := 0
+		for _, prompt := range plan.Prompts {
+			if prompt.MainTopic == topic.MainTopic {
+				prompts++
+			}
+		}
+		fmt.Printf("  %-30s %d subtopics, %d prompts\n", topic.MainTopic+":", len(topic.Subtopics), prompts)
+	}
+	fmt.Printf("Total prompts:       %d\n", len(plan.Prompts))
+	return nil
+}
+
+// printBenchmarkReport prints throughput for a run --benchmark sample
+// resumeSession is the session to resume when the time budget stopped the run ("" = finished)
+func printBenchmarkReport(r orchestrator.ThroughputReport, budget time.Duration, resumeSession string) {
+	fmt.Println()
+	fmt.Printf("Benchmark Report (%s sample)\n", budget)
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Println("Phase Timings:")
+	fmt.Printf("  Subtopics:         %s\n", r.SubtopicsDuration.Round(time.Millisecond))
+	fmt.Printf("  Prompts:           %s\n", r.PromptsDuration.Round(time.Millisecond))
+	if r.PairsDuration == 0 {
+		fmt.Printf("  Preference Pairs:  not reached\n")
+		fmt.Println()
+		fmt.Println("The sample ended before preference pair generation started; rerun with a longer --benchmark.")
+	} else {
+		fmt.Printf("  Preference Pairs:  %s (%d jobs)\n", r.PairsDuration.Round(time.Millisecond), r.JobsProcessed)
+		fmt.Println()
+
+		fmt.Println("Job Latency (average per successful job):")
+		fmt.Printf("  Chosen:            %s\n", r.AvgChosen.Round(time.Millisecond))
+		if r.AvgRejected > 0 {
+			fmt.Printf("  Rejected:          %s\n", r.AvgRejected.Round(time.Millisecond))
+		}
+		fmt.Printf("  Total:             %s\n", r.AvgJob.Round(time.Millisecond))
+		fmt.Println()
+
+		fmt.Println("Throughput:")
+		fmt.Printf("  Rows Written:      %d\n", r.RowsWritten)
+		fmt.Printf("  Rows / Minute:     %.1f\n", r.RowsPerMinute)
+		fmt.Printf("  API Requests:      %d\n", r.APIRequests)
+		fmt.Printf("  Rate Limit Wait:   %.1f%% of request time\n", r.RateLimitWaitFraction*100)
+		fmt.Println()
+
+		fmt.Println("Projection:")
+		fmt.Printf("  Progress:          %d / %d jobs\n", r.CompletedJobs, r.TotalJobs)
+		if r.ProjectedTotal > 0 {
+			fmt.Printf("  Remaining:         %s\n", r.ProjectedRemaining.Round(time.Second))
+			fmt.Printf("  Full Run:          %s\n", r.ProjectedTotal.Round(time.Second))
+		} else {
+			fmt.Printf("  Full Run:          unknown (no jobs finished during the sample)\n")
+		}
+	}
+	if resumeSession != "" {
+		fmt.Println()
+		fmt.Println("To continue this run, resume the session:")
+		fmt.Printf("  Set resume_from_session = \"%s\" in config.toml\n", resumeSession)
+	}
+}
+
+// runConvert converts a dataset between formats without a config or any model
+func runConvert(cmd *cobra.Command, args []string) error {
+	from := models.DatasetMode(strings.ToLower(strings.TrimSpace(convertFrom)))
+	to := models.DatasetMode(strings.ToLower(strings.TrimSpace(convertTo)))
+	loss, err := dataset.ConversionLoss(from, to)
+	if err != nil {
+		return err
+	}
+	if loss != "" {
+		fmt.Fprintf(os.Stderr, "Note: %s -> %s is lossy: %s\n", from, to, loss)
+	}
+
+	stats, err := dataset.Convert(dataset.ConvertOptions{
+		From:       from,
+		To:         to,
+		SFTFormat:  models.SFTFormat(strings.ToLower(strings.TrimSpace(convertSFTFormat))),
+		InputPath:  args[0],
+		OutputPath: args[1],
+		Force:      convertForce,
+	})
+	if err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+
+	fmt.Printf("Converted %d %s rows to %d %s rows: %s\n", stats.Read, from, stats.Written, to, args[1])
+	if stats.Skipped > 0 {
+		fmt.Printf("  Skipped:    %d input rows with nothing to write\n", stats.Skipped)
+	}
+	if stats.Duplicates > 0 {
+		fmt.Printf("  Duplicates: %d repeated rows written once\n", stats.Duplicates)
+	}
+	return nil
+}
+
 // runTransform performs offline dataset transforms using existing config.toml settings.
 func runTransform(cmd *cobra.Command, args []string) error {
+	if configPath == config.StdinSource && transformInputPath == dataset.StdioPath {
+		return fmt.Errorf("--config and --input cannot both read from stdin")
+	}
+
 	// Load environment variables from file if it exists
 	if envFile != "" {
 		if err := loadEnvFile(envFile); err != nil {
@@ -316,13 +785,33 @@ func runTransform(cmd *cobra.Command, args []string) error {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
 
 	// Create API client
-	apiClient := api.NewClient(logger)
+	apiClient := api.NewClientWithNetwork(logger, cfg.Network)
+	apiClient.SetUserAgent(userAgent(cfg.Network))
+	apiClient.SetRetryConfig(cfg.Retry)
+	apiClient.SetSeed(cfg.Generation.Seed)
+	apiClient.SetKeyRotator(secrets)
+	if err := apiClient.ConfigureTLS(cfg.Network, cfg.Models); err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	if debugDump != "" {
+		if err := apiClient.SetDebugDumpDir(debugDump); err != nil {
+			return err
+		}
+		logger.Warn("Debug dump enabled - request and response bodies will be written to disk", "dir", debugDump)
+	}
 
 	// Set provider-level rate limits if configured
 	if len(cfg.ProviderRateLimits) > 0 {
 		apiClient.SetProviderRateLimits(cfg.ProviderRateLimits, cfg.ProviderBurstPercent)
 		logger.Info("Provider rate limits configured", "providers", cfg.ProviderRateLimits, "burst_percent", cfg.ProviderBurstPercent)
 	}
+	if cfg.ProviderAdaptiveRateLimit {
+		apiClient.SetAdaptiveRateLimit(true)
+		logger.Info("Adaptive rate limiting enabled - request rate drops after repeated 429s and recovers gradually")
+	}
+	if err := detectContextSizes(cfg, secrets, apiClient); err != nil {
+		return err
+	}
 
 	// Parse transform mode
 	var mode dataset.TransformMode
@@ -336,6 +825,22 @@ func runTransform(cmd *cobra.Command, args []string) error {
 			transformMode, dataset.TransformSFTToDPO, dataset.TransformRegenRejected)
 	}
 
+	// Flags override config; 0 falls back to the generation settings
+	if transformConcurrency < 0 || transformCheckpointInterval < 0 {
+		return fmt.Errorf("--concurrency and --checkpoint-interval must not be negative")
+	}
+	if transformConcurrency > config.MaxConcurrency && !cfg.Generation.DisableValidationLimits {
+		return fmt.Errorf("--concurrency must not exceed %d (got %d)", config.MaxConcurrency, transformConcurrency)
+	}
+	concurrency := cfg.Generation.Concurrency
+	if transformConcurrency > 0 {
+		concurrency = transformConcurrency
+	}
+	checkpointInterval := cfg.Generation.CheckpointInterval
+	if transformCheckpointInterval > 0 {
+		checkpointInterval = transformCheckpointInterval
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
@@ -344,10 +849,15 @@ func runTransform(cmd *cobra.Command, args []string) error {
 		OutputPath:          transformOutputPath,
 		InputReasoningPath:  transformInputReasoningPath,
 		OutputReasoningPath: transformOutputReasoningPath,
-		Concurrency:         cfg.Generation.Concurrency,
+		Concurrency:         concurrency,
 		CheckpointPath:      transformCheckpointPath,
 		Resume:              transformResume,
-		CheckpointInterval:  cfg.Generation.CheckpointInterval,
+		CheckpointInterval:  checkpointInterval,
+		ContinueOnError:     transformContinueOnError,
+		FailuresPath:        transformFailuresPath,
+		RetryFailures:       transformRetryFailures,
+		Force:               transformForce,
+		AllowOutputChange:   transformAllowOutputChange,
 	}
 
 	if err := dataset.Run(ctx, logger, mode, cfg, secrets, apiClient, opts); err != nil {
@@ -628,10 +1138,49 @@ func inspectCheckpoint(cmd *cobra.Command, args []string) error {
 		checkpoint.GetProgressPercentage(cp))
 	fmt.Println()
 
+	if topics := checkpoint.GetTopicProgress(cp); len(topics) > 1 {
+		fmt.Println("Topics:")
+		for _, topic := range topics {
+			fmt.Printf("  %s: %d / %d completed\n", topic.Topic, topic.Completed, topic.Total)
+		}
+		fmt.Println()
+	} else if len(cp.TopicSubtopics) > 0 && !cp.PromptsComplete {
+		fmt.Printf("Topics:              %d with subtopics, %d with prompts\n", len(cp.TopicSubtopics), len(cp.PromptTopicsDone))
+		fmt.Println()
+	}
+
 	fmt.Println("Statistics:")
 	fmt.Printf("  Total Prompts:     %d\n", cp.Stats.TotalPrompts)
 	fmt.Printf("  Successful:        %d\n", cp.Stats.SuccessCount)
 	fmt.Printf("  Failed:            %d\n", cp.Stats.FailureCount)
+	for _, class := range slices.Sorted(maps.Keys(cp.Stats.ErrorCounts)) {
+		fmt.Printf("    %-17s%d\n", class+":", cp.Stats.ErrorCounts[class])
+	}
+	if cp.Stats.JSONReformatAttempts > 0 {
+		fmt.Printf("  JSON Reformats:    %d / %d succeeded\n", cp.Stats.JSONReformatSuccesses, cp.Stats.JSONReformatAttempts)
+	}
+	if cp.Stats.TruncationRetries > 0 {
+		fmt.Printf("  Truncation Retry:  %d / %d recovered\n", cp.Stats.TruncationRecoveries, cp.Stats.TruncationRetries)
+	}
+	if cp.Stats.RetriedJobs > 0 {
+		fmt.Printf("  Retried Jobs:      %d\n", cp.Stats.RetriedJobs)
+	}
+	if cp.Stats.LengthRatioFiltered+cp.Stats.LengthRatioFlagged > 0 {
+		fmt.Printf("  Length Ratio:      %d dropped / %d flagged\n", cp.Stats.LengthRatioFiltered, cp.Stats.LengthRatioFlagged)
+	}
+	if cp.Stats.JudgeCacheHits+cp.Stats.JudgeCacheMisses > 0 {
+		fmt.Printf("  Judge Cache:       %d hits / %d misses\n", cp.Stats.JudgeCacheHits, cp.Stats.JudgeCacheMisses)
+	}
+	if cp.Stats.OutputCapped {
+		fmt.Println("  Output Capped:     max_output_bytes reached, remaining jobs skipped")
+	}
+	if cp.Stats.JudgeSuccesses+cp.Stats.JudgeFailures > 0 {
+		fmt.Printf("  Judge Successes:   %d\n", cp.Stats.JudgeSuccesses)
+		fmt.Printf("  Judge Failures:    %d\n", cp.Stats.JudgeFailures)
+	}
+	for _, model := range slices.Sorted(maps.Keys(cp.Stats.SystemFingerprints)) {
+		fmt.Printf("  Fingerprints:      %s: %s\n", model, strings.Join(cp.Stats.SystemFingerprints[model], ", "))
+	}
 	fmt.Printf("  Total Duration:    %s\n", cp.Stats.TotalDuration)
 	if cp.Stats.SuccessCount > 0 {
 		fmt.Printf("  Average Duration:  %s\n", cp.Stats.AverageDuration)
@@ -649,6 +1198,86 @@ func inspectCheckpoint(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// repairCheckpoint reconciles a session's checkpoint with the rows in its dataset files
+func repairCheckpoint(cmd *cobra.Command, args []string) error {
+	sessionDir := args[0]
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	sessionMgr, err := writer.NewSessionManager(logger, sessionDir)
+	if err != nil {
+		return err
+	}
+
+	// Rows per job depend on the session's dataset mode and num_rejected/kto_ratio
+	var gen config.GenerationConfig
+	backupPath := sessionMgr.GetConfigBackupPath()
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		logger.Warn("Session has no config backup, assuming one dataset row per job", "path", backupPath)
+	} else {
+		sessionCfg, _, err := config.Load(backupPath)
+		if err != nil {
+			return fmt.Errorf("failed to load session config: %w", err)
+		}
+		gen = sessionCfg.Generation
+	}
+
+	report, err := checkpoint.Repair(sessionMgr.GetSessionDir(), sessionMgr.GetDatasetPath(),
+		sessionMgr.GetReasoningDatasetPath(), gen, repairDryRun, logger)
+	if err != nil {
+		return fmt.Errorf("failed to repair checkpoint: %w", err)
+	}
+
+	fmt.Printf("Checkpoint Repair for: %s\n", sessionDir)
+	fmt.Println(strings.Repeat("=", 80))
+	fmt.Printf("Dataset rows:        %d\n", report.DatasetRows)
+	if report.UnparseableRows > 0 {
+		fmt.Printf("Unparseable rows:    %d (left in place)\n", report.UnparseableRows)
+	}
+	if report.UnmatchedRows > 0 {
+		fmt.Printf("Unmatched rows:      %d (prompt not in checkpoint, left in place)\n", report.UnmatchedRows)
+	}
+	for _, path := range slices.Sorted(maps.Keys(report.TrimmedFiles)) {
+		fmt.Printf("Partial row cut:     %s (%d bytes)\n", filepath.Base(path), report.TrimmedFiles[path])
+	}
+	for _, path := range report.NewlineAdded {
+		fmt.Printf("Newline added:       %s\n", filepath.Base(path))
+	}
+	fmt.Printf("Marked completed:    %d %s\n", len(report.MarkedComplete), jobIDList(report.MarkedComplete))
+	fmt.Printf("Marked pending:      %d %s\n", len(report.MarkedPending), jobIDList(report.MarkedPending))
+	if report.PhaseReopened {
+		fmt.Println("Phase:               complete -> pairs (missing jobs will be regenerated)")
+	}
+	fmt.Println()
+
+	switch {
+	case !report.Changed():
+		fmt.Println("Checkpoint and dataset are consistent, nothing to repair.")
+	case repairDryRun:
+		fmt.Println("Dry run: no files were changed. Re-run without --dry-run to apply.")
+	default:
+		fmt.Printf("Repaired. Previous checkpoint saved as %s\n", checkpoint.BackupFilename)
+		fmt.Printf("Resume with: vellumforge2 checkpoint resume %s\n", sessionDir)
+	}
+	return nil
+}
+
+// jobIDList formats up to 10 job IDs for the repair report
+func jobIDList(ids []int) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	const shown = 10
+	parts := make([]string, 0, shown)
+	for _, id := range ids[:min(len(ids), shown)] {
+		parts = append(parts, fmt.Sprint(id))
+	}
+	list := "(job IDs " + strings.Join(parts, ", ")
+	if len(ids) > shown {
+		list += fmt.Sprintf(", ... %d more", len(ids)-shown)
+	}
+	return list + ")"
+}
+
 // resumeFromCheckpoint resumes generation from a checkpoint
 func resumeFromCheckpoint(cmd *cobra.Command, args []string) error {
 	sessionDir := args[0]
@@ -688,6 +1317,15 @@ func resumeFromCheckpoint(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
+	if noCache {
+		cfg.Generation.EnablePromptCache = false
+	}
+	if noJudgeCache {
+		cfg.Generation.EnableJudgeCache = false
+	}
+	if keepFailed {
+		cfg.Generation.KeepFailed = true
+	}
 
 	// Validate checkpoint compatibility
 	if err := checkpoint.ValidateCheckpoint(cp, cfg); err != nil {
@@ -705,12 +1343,176 @@ func resumeFromCheckpoint(cmd *cobra.Command, args []string) error {
 	return runGenerationWithConfig(cfg, secrets)
 }
 
+// replaySession regenerates the jobs missing from a finished session and reports how many were recovered
+func replaySession(cmd *cobra.Command, args []string) error {
+	sessionDir := args[0]
+
+	if envFile != "" {
+		if err := loadEnvFile(envFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load env file: %v\n", err)
+		}
+	}
+
+	// SECURITY: Validate session path to prevent path traversal (CWE-22)
+	if err := writer.ValidateSessionPath(sessionDir); err != nil {
+		return fmt.Errorf("invalid session directory: %w", err)
+	}
+	fullPath := filepath.Join("output", sessionDir)
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		return fmt.Errorf("session directory not found: %s", sessionDir)
+	}
+
+	cfg, secrets, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	missing, err := checkpoint.PrepareReplay(fullPath, cfg, replayDryRun, logger)
+	if err != nil {
+		return fmt.Errorf("failed to prepare replay: %w", err)
+	}
+	if len(missing) == 0 {
+		fmt.Printf("Session %s has a row for every job, nothing to replay.\n", sessionDir)
+		return nil
+	}
+
+	ids := make([]int, len(missing))
+	for i, job := range missing {
+		ids[i] = job.ID
+	}
+	fmt.Printf("Replaying session: %s\n", sessionDir)
+	fmt.Printf("Missing jobs:        %d %s\n", len(missing), jobIDList(ids))
+	if replayDryRun {
+		fmt.Println("Dry run: no files were changed. Re-run without --dry-run to replay.")
+		return nil
+	}
+	fmt.Println()
+
+	if keepFailed {
+		cfg.Generation.KeepFailed = true
+	}
+	if cfg.Generation.KeepFailed {
+		// Like the failure counts, failures.jsonl only lists jobs that fail again
+		if err := os.Remove(filepath.Join(fullPath, writer.FailuresFile)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove previous failures file: %w", err)
+		}
+	}
+
+	cfg.Generation.ResumeFromSession = sessionDir
+	runErr := runGenerationWithConfig(cfg, secrets)
+
+	// Report from the checkpoint so an interrupted replay still shows its progress
+	cp, err := checkpoint.Load(fullPath, slog.Default())
+	if err != nil {
+		if runErr != nil {
+			return runErr
+		}
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	stillMissing := len(checkpoint.GetPendingJobs(cp))
+	fmt.Println()
+	fmt.Printf("Recovered rows:      %d / %d\n", len(missing)-stillMissing, len(missing))
+	switch {
+	case stillMissing == 0:
+	case cp.CurrentPhase == models.PhaseComplete:
+		fmt.Printf("Still missing:       %d (run replay again to retry)\n", stillMissing)
+	default:
+		fmt.Printf("Still missing:       %d (continue with: vellumforge2 checkpoint resume %s)\n", stillMissing, sessionDir)
+	}
+	return runErr
+}
+
+// logOptions combines the [logging] config with --log-format and --verbose
+func logOptions(cfg *config.Config) (writer.LogOptions, error) {
+	if err := config.ValidateLogFormat("--log-format", logFormat); err != nil {
+		return writer.LogOptions{}, err
+	}
+	opts := writer.LogOptions{
+		ConsoleFormat: cfg.Logging.Format,
+		ConsoleLevel:  config.ParseLogLevel(cfg.Logging.Level, slog.LevelInfo),
+		FileFormat:    cfg.Logging.FileFormat,
+	}
+	opts.FileLevel = config.ParseLogLevel(cfg.Logging.FileLevel, opts.ConsoleLevel)
+	if logFormat != "" {
+		opts.ConsoleFormat = logFormat
+	}
+	if verbose {
+		opts.ConsoleLevel = slog.LevelDebug
+		opts.FileLevel = slog.LevelDebug
+	}
+	return opts, nil
+}
+
+// userAgent returns network.user_agent, or VellumForge2/<version> of this build when it is unset
+func userAgent(netCfg config.NetworkConfig) string {
+	if netCfg.UserAgent != "" {
+		return netCfg.UserAgent
+	}
+	return api.DefaultUserAgent(Version)
+}
+
+// detectContextSizes fills in unset context_size values from the providers when
+// generation.autodetect_context is enabled
+func detectContextSizes(cfg *config.Config, secrets *config.Secrets, apiClient *api.Client) error {
+	if !cfg.Generation.AutodetectContext {
+		return nil
+	}
+	if err := apiClient.DetectContextSizes(context.Background(), cfg.Models, secrets.GetAPIKey); err != nil {
+		return fmt.Errorf("context size detection failed: %w", err)
+	}
+	return nil
+}
+
+// startMetricsExport wires a metrics collector into the client and orchestrator when --metrics-file
+// is set and starts rewriting the file; the returned stop function writes the final snapshot
+func startMetricsExport(apiClient *api.Client, orch *orchestrator.Orchestrator, logger *slog.Logger) (func(), error) {
+	if metricsFile == "" {
+		return func() {}, nil
+	}
+	if metricsInterval <= 0 {
+		return nil, fmt.Errorf("--metrics-interval must be positive (got %s)", metricsInterval)
+	}
+
+	collector := metrics.NewCollector(logger)
+	if err := collector.WriteFile(metricsFile); err != nil {
+		return nil, err
+	}
+	apiClient.SetMetrics(collector)
+	orch.SetMetrics(collector)
+	logger.Info("Prometheus metrics export enabled", "path", metricsFile, "interval", metricsInterval)
+	return collector.ExportToFile(metricsFile, metricsInterval), nil
+}
+
+// openFailureLog sends failed jobs to the session's failures.jsonl when generation.keep_failed is set
+// A resumed session appends to the file; the returned func closes it
+func openFailureLog(cfg *config.Config, sessionMgr *writer.SessionManager, resumeMode bool, orch *orchestrator.Orchestrator, logger *slog.Logger) (func(), error) {
+	if !cfg.Generation.KeepFailed {
+		return func() {}, nil
+	}
+
+	path := sessionMgr.GetFailuresPath()
+	failureLog, err := writer.NewFailureLog(path, resumeMode)
+	if err != nil {
+		return nil, err
+	}
+	orch.SetFailureLog(failureLog)
+	logger.Info("Failed jobs will be written to the session", "path", path)
+	return func() {
+		if err := failureLog.Close(); err != nil {
+			logger.Error("failed to close failures file", "error", err)
+		}
+	}, nil
+}
+
 // runGenerationWithConfig runs generation with provided config
 func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error {
-	// Determine log level
-	logLevel := slog.LevelInfo
-	if verbose {
-		logLevel = slog.LevelDebug
+	logOpts, err := logOptions(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkUploadConfig(cfg); err != nil {
+		return err
 	}
 
 	// Check for resume mode
@@ -723,7 +1525,7 @@ func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error
 	}
 
 	// Set up logger
-	logger, logFile, err := writer.SetupLogger(sessionMgr, logLevel)
+	logger, logFile, err := writer.SetupLogger(sessionMgr, logOpts)
 	if err != nil {
 		return fmt.Errorf("failed to setup logger: %w", err)
 	}
@@ -751,13 +1553,33 @@ func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error
 	}
 
 	// Create API client
-	apiClient := api.NewClient(logger)
+	apiClient := api.NewClientWithNetwork(logger, cfg.Network)
+	apiClient.SetUserAgent(userAgent(cfg.Network))
+	apiClient.SetRetryConfig(cfg.Retry)
+	apiClient.SetSeed(cfg.Generation.Seed)
+	apiClient.SetKeyRotator(secrets)
+	if err := apiClient.ConfigureTLS(cfg.Network, cfg.Models); err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	if debugDump != "" {
+		if err := apiClient.SetDebugDumpDir(debugDump); err != nil {
+			return err
+		}
+		logger.Warn("Debug dump enabled - request and response bodies will be written to disk", "dir", debugDump)
+	}
 
 	// Set provider-level rate limits if configured
 	if len(cfg.ProviderRateLimits) > 0 {
 		apiClient.SetProviderRateLimits(cfg.ProviderRateLimits, cfg.ProviderBurstPercent)
 		logger.Info("Provider rate limits configured", "providers", cfg.ProviderRateLimits, "burst_percent", cfg.ProviderBurstPercent)
 	}
+	if cfg.ProviderAdaptiveRateLimit {
+		apiClient.SetAdaptiveRateLimit(true)
+		logger.Info("Adaptive rate limiting enabled - request rate drops after repeated 429s and recovers gradually")
+	}
+	if err := detectContextSizes(cfg, secrets, apiClient); err != nil {
+		return err
+	}
 
 	// Set up checkpoint manager
 	var checkpointMgr *checkpoint.Manager
@@ -785,7 +1607,7 @@ func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error
 
 	// Create dataset writer
 	// Use dual dataset writer if reasoning capture is enabled
-	expectedRecords := cfg.Generation.NumSubtopics * cfg.Generation.NumPromptsPerSubtopic
+	expectedRecords := cfg.Generation.NumSubtopics * cfg.Generation.NumPromptsPerSubtopic * len(cfg.Generation.Topics())
 	var dataWriter writer.Writer
 	if cfg.Generation.EnableReasoningCapture {
 		dataWriter, err = writer.NewDualDatasetWriter(sessionMgr, logger, resumeMode, expectedRecords)
@@ -799,7 +1621,27 @@ func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error
 			return fmt.Errorf("failed to create dataset writer: %w", err)
 		}
 	}
+	if cfg.Generation.DatasetMode == models.DatasetModeMODPO && cfg.JudgeFiltering.MinPreferenceMargin > 0 {
+		dataWriter.SetMinPreferenceMargin(cfg.JudgeFiltering.MinPreferenceMargin)
+		logger.Info("Minimum preference margin filter enabled", "min_preference_margin", cfg.JudgeFiltering.MinPreferenceMargin)
+	}
+	if cfg.Generation.DatasetMode == models.DatasetModeMODPO && cfg.Generation.MaxBufferedRecords > 0 {
+		dataWriter.SetMaxBufferedRecords(cfg.Generation.MaxBufferedRecords)
+		logger.Info("MO-DPO record buffer capped, judged records are written early", "max_buffered_records", cfg.Generation.MaxBufferedRecords)
+	}
+	if cfg.Generation.IncludeID {
+		dataWriter.SetIncludeIDs(true)
+	}
+	if cfg.Generation.RecordFilterCommand != "" {
+		timeout := time.Duration(cfg.Generation.RecordFilterTimeoutSeconds) * time.Second
+		dataWriter = writer.NewFilteredWriter(dataWriter, writer.NewRecordFilter(cfg.Generation.RecordFilterCommand, timeout), logger)
+		logger.Info("Record filter command enabled", "command", cfg.Generation.RecordFilterCommand)
+	}
+	writerClosed := false
 	defer func() {
+		if writerClosed {
+			return
+		}
 		if err := dataWriter.Close(); err != nil {
 			logger.Error("failed to close data writer", "error", err)
 		}
@@ -807,17 +1649,28 @@ func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error
 
 	// Create orchestrator
 	orch := orchestrator.New(cfg, secrets, apiClient, dataWriter, checkpointMgr, resumeMode, logger)
+	stopMetrics, err := startMetricsExport(apiClient, orch, logger)
+	if err != nil {
+		return err
+	}
+	defer stopMetrics()
+	closeFailureLog, err := openFailureLog(cfg, sessionMgr, resumeMode, orch, logger)
+	if err != nil {
+		return err
+	}
+	defer closeFailureLog()
 
 	// Run with context
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	if err := orch.Run(ctx); err != nil {
-		if err == context.Canceled {
+		if err == context.Canceled || errors.Is(err, orchestrator.ErrMaxRuntimeExceeded) {
 			sessionDirName := filepath.Base(sessionMgr.GetSessionDir())
 			logger.Warn("Generation interrupted - resume from checkpoint",
+				"reason", interruptReason(err),
 				"session_dir", sessionDirName)
-			return fmt.Errorf("generation interrupted (resume by setting resume_from_session in config)")
+			return fmt.Errorf("generation %s (resume by setting resume_from_session in config)", interruptReason(err))
 		}
 		return fmt.Errorf("generation failed: %w", err)
 	}
@@ -828,8 +1681,27 @@ func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error
 		"total_prompts", stats.TotalPrompts,
 		"successful", stats.SuccessCount,
 		"failed", stats.FailureCount,
+		"error_breakdown", stats.ErrorCounts,
 		"duration", stats.TotalDuration,
 		"session_dir", sessionMgr.GetSessionDir())
+	logKeyPoolStats(logger, secrets)
+	if len(stats.SystemFingerprints) > 0 {
+		logger.Info("System fingerprints", "fingerprints", stats.SystemFingerprints)
+	}
+	if cfg.Generation.DatasetMode == models.DatasetModeMODPO && stats.JudgeSuccesses+stats.JudgeFailures > 0 {
+		logger.Info("Judge summary",
+			"judge_successes", stats.JudgeSuccesses,
+			"judge_failures", stats.JudgeFailures)
+	}
+
+	// Close the writer now so buffered MO-DPO rows are on disk for the report and upload
+	writerClosed = true
+	if err := dataWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close data writer: %w", err)
+	}
+	if _, err := writer.WriteQualityReport(sessionMgr, cfg.Generation.DatasetMode, logger); err != nil {
+		logger.Warn("Failed to write quality report", "error", err)
+	}
 
 	if err := maybeUploadToHuggingFace(cfg, secrets, sessionMgr, logger); err != nil {
 		return err
@@ -839,6 +1711,15 @@ func runGenerationWithConfig(cfg *config.Config, secrets *config.Secrets) error
 	return nil
 }
 
+// checkUploadConfig reports a broken commit message template before generation starts
+// rather than when the upload runs at the end
+func checkUploadConfig(cfg *config.Config) error {
+	if !uploadToHF {
+		return nil
+	}
+	return hfhub.CheckCommitTemplates(cfg.HuggingFace.CommitMessage, cfg.HuggingFace.CommitDescription)
+}
+
 func maybeUploadToHuggingFace(cfg *config.Config, secrets *config.Secrets, sessionMgr *writer.SessionManager, logger *slog.Logger) error {
 	if !uploadToHF {
 		return nil
@@ -854,20 +1735,150 @@ func maybeUploadToHuggingFace(cfg *config.Config, secrets *config.Secrets, sessi
 	}
 
 	if secrets.HuggingFaceToken == "" {
-		return fmt.Errorf("HUGGING_FACE_TOKEN environment variable must be set for uploads")
-	}
-
-	uploader := hfhub.NewUploader(secrets.HuggingFaceToken, logger)
-	if err := uploader.Upload(repoID, sessionMgr.GetSessionDir()); err != nil {
+		return fmt.Errorf("a Hugging Face token must be set for uploads (%s)", strings.Join(config.HuggingFaceTokenEnvVars, ", "))
+	}
+
+	opts := hfhub.UploadOptions{
+		Branch:               hfBranch,
+		Append:               hfAppend || cfg.HuggingFace.Append,
+		ReasoningDatasetPath: sessionMgr.GetReasoningDatasetPath(),
+		ReadyTimeout:         cfg.HuggingFace.ReadyTimeoutDuration(),
+		ReadyPollInterval:    cfg.HuggingFace.ReadyPollIntervalDuration(),
+		DatasetMode:          string(cfg.Generation.DatasetMode),
+		CommitMessage:        cfg.HuggingFace.CommitMessage,
+		CommitDescription:    cfg.HuggingFace.CommitDescription,
+		Force:                hfForce,
+		Card: hfhub.CardMetadata{
+			License:     cfg.Dataset.LicenseID(),
+			Source:      cfg.Dataset.Source,
+			Attribution: cfg.Dataset.Attribution,
+		},
+	}
+	if opts.Branch == "" {
+		opts.Branch = cfg.HuggingFace.Branch
+	}
+	if hfShard || cfg.HuggingFace.Shard {
+		opts.ShardSize = hfhub.DefaultShardSize
+	}
+
+	uploader := hfhub.NewUploader(secrets.HuggingFaceToken, logger, hfhub.UploaderOptions{
+		UploadTimeout: time.Duration(cfg.HuggingFace.UploadTimeoutSeconds) * time.Second,
+		CommitTimeout: time.Duration(cfg.HuggingFace.CommitTimeoutSeconds) * time.Second,
+		MaxRetries:    cfg.HuggingFace.MaxRetries,
+	})
+	if err := uploader.UploadWithOptions(repoID, sessionMgr.GetSessionDir(), opts); err != nil {
 		return fmt.Errorf("upload failed: %w", err)
 	}
 
 	return nil
 }
 
+// logKeyPoolStats logs per-key usage for providers configured with several API keys
+func logKeyPoolStats(logger *slog.Logger, secrets *config.Secrets) {
+	for provider, keys := range secrets.KeyPoolStats() {
+		for _, key := range keys {
+			logger.Info("API key usage",
+				"provider", provider,
+				"key", key.Key,
+				"requests", key.Requests,
+				"rate_limited", key.RateLimited)
+		}
+	}
+}
+
 func statusStr(complete bool) string {
 	if complete {
 		return "Complete"
 	}
 	return "Pending"
 }
+
+// interruptReason describes why a resumable run stopped early
+func interruptReason(err error) string {
+	if errors.Is(err, orchestrator.ErrMaxRuntimeExceeded) {
+		return "stopped by max_runtime budget"
+	}
+	return "interrupted"
+}
+
+// initConfig writes a starter config for --mode to stdout
+func initConfig(cmd *cobra.Command, args []string) error {
+	starter, err := config.StarterConfig(models.DatasetMode(initMode))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(cmd.OutOrStdout(), starter)
+	return err
+}
+
+// runProbe tests optional request features of models.<model> and prints recommended settings
+func runProbe(cmd *cobra.Command, args []string) error {
+	if envFile != "" {
+		if err := loadEnvFile(envFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load env file: %v\n", err)
+		}
+	}
+
+	cfg, secrets, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	modelKey := args[0]
+	modelCfg, ok := cfg.Models[modelKey]
+	if !ok {
+		return fmt.Errorf("model %q not found in config (available: %s)", modelKey, strings.Join(slices.Sorted(maps.Keys(cfg.Models)), ", "))
+	}
+
+	logLevel := slog.LevelWarn
+	if verbose {
+		logLevel = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+	apiClient := api.NewClientWithNetwork(logger, cfg.Network)
+	apiClient.SetUserAgent(userAgent(cfg.Network))
+	apiClient.SetRetryConfig(cfg.Retry)
+	apiClient.SetSeed(cfg.Generation.Seed)
+	if err := apiClient.ConfigureTLS(cfg.Network, map[string]config.ModelConfig{modelKey: modelCfg}); err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Probing models.%s (%s at %s)...\n", modelKey, modelCfg.ModelName, modelCfg.BaseURL)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := apiClient.Probe(ctx, modelCfg, secrets.GetAPIKey(modelCfg.BaseURL))
+	if err != nil {
+		return fmt.Errorf("probe failed: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "  Reasoning output:   %s\n", yesNo(result.Reasoning || result.StreamingReasoning))
+	fmt.Fprintf(os.Stderr, "  JSON mode:          %s\n", yesNo(result.JSONMode.Supported))
+	fmt.Fprintf(os.Stderr, "  Streaming:          %s\n", yesNo(result.Streaming.Supported))
+	fmt.Fprintf(os.Stderr, "  Streamed reasoning: %s\n", yesNo(result.StreamingReasoning))
+	fmt.Fprintf(os.Stderr, "  Seed honored:       %s\n\n", yesNo(result.SeedDeterministic))
+
+	fmt.Print(result.ConfigSnippet(modelKey))
+	return nil
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+// checkConfig validates --config and writes the resolved config to stdout
+func checkConfig(cmd *cobra.Command, args []string) error {
+	cfg, _, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	resolved, err := cfg.ResolvedTOML()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "# Resolved config for %s (defaults applied)\n%s", configPath, resolved)
+	return err
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"log/slog"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// adaptiveBurstThreshold is the number of 429s within adaptiveWindow that counts as overload
+	adaptiveBurstThreshold = 3
+	// adaptiveWindow is the period 429s are counted over; with many workers a single overload
+	// produces a burst of 429s, so the rate is cut at most once per window
+	adaptiveWindow = 10 * time.Second
+	// adaptiveDecreaseFactor is the multiplicative decrease applied on overload
+	adaptiveDecreaseFactor = 0.5
+	// adaptiveMinFraction is the lowest fraction of the configured rate the limiter drops to
+	adaptiveMinFraction = 0.1
+	// adaptiveIncreaseFraction is the share of the configured rate recovered per step
+	adaptiveIncreaseFraction = 0.05
+	// adaptiveRecoveryInterval is the minimum time between recovery steps
+	adaptiveRecoveryInterval = 5 * time.Second
+)
+
+// adaptiveState tracks the AIMD adjustment of one limiter (provider_adaptive_rate_limit)
+type adaptiveState struct {
+	name        string // Provider or model ID, for logging
+	baseLimit   rate.Limit
+	baseBurst   int
+	fraction    float64   // Current share of the configured rate (1 = unreduced)
+	windowStart time.Time // Start of the current 429 counting window
+	hits        int       // 429s in the current window
+	lastChange  time.Time // Last decrease or recovery step
+	lastHit     time.Time
+}
+
+// SetAdaptive enables AIMD adjustment of limiters from observed 429 responses
+func (p *RateLimiterPool) SetAdaptive(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.adaptive = enabled
+}
+
+// Observe records the outcome of a request sent through limiter: a burst of 429s halves its
+// rate, and successes after a quiet period recover it additively up to the configured rate
+// Rate changes are logged to logger; does nothing unless adaptive limiting is enabled
+func (p *RateLimiterPool) Observe(logger *slog.Logger, limiter *rate.Limiter, rateLimited bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.adaptive || limiter == nil {
+		return
+	}
+	now := p.now()
+	state, ok := p.adaptiveStates[limiter]
+	if !ok {
+		if !rateLimited {
+			return // Nothing to recover
+		}
+		state = &adaptiveState{name: p.limiterName(limiter), baseLimit: limiter.Limit(), baseBurst: limiter.Burst(), fraction: 1}
+		p.adaptiveStates[limiter] = state
+	}
+
+	if rateLimited {
+		state.lastHit = now
+		if now.Sub(state.windowStart) > adaptiveWindow {
+			state.windowStart = now
+			state.hits = 0
+		}
+		state.hits++
+		cooling := !state.lastChange.IsZero() && now.Sub(state.lastChange) < adaptiveWindow
+		if state.hits < adaptiveBurstThreshold || cooling || state.fraction <= adaptiveMinFraction {
+			return
+		}
+		state.fraction *= adaptiveDecreaseFactor
+		if state.fraction < adaptiveMinFraction {
+			state.fraction = adaptiveMinFraction
+		}
+		state.hits = 0
+		state.lastChange = now
+		p.applyAdaptive(limiter, state)
+		logger.Warn("Reducing request rate after repeated rate limit errors",
+			"limiter", state.name,
+			"rpm", float64(limiter.Limit())*60,
+			"configured_rpm", float64(state.baseLimit)*60,
+			"fraction", state.fraction)
+		return
+	}
+
+	if state.fraction >= 1 || now.Sub(state.lastHit) < adaptiveWindow || now.Sub(state.lastChange) < adaptiveRecoveryInterval {
+		return
+	}
+	state.fraction = min(1, state.fraction+adaptiveIncreaseFraction)
+	state.lastChange = now
+	p.applyAdaptive(limiter, state)
+	if state.fraction >= 1 {
+		logger.Info("Request rate recovered to configured limit", "limiter", state.name, "rpm", float64(state.baseLimit)*60)
+	} else {
+		logger.Debug("Recovering request rate", "limiter", state.name, "rpm", float64(limiter.Limit())*60, "fraction", state.fraction)
+	}
+}
+
+// applyAdaptive scales the limiter's rate and burst to the state's current fraction
+// The burst shrinks too so a refilled bucket does not immediately re-trigger 429s
+func (p *RateLimiterPool) applyAdaptive(limiter *rate.Limiter, state *adaptiveState) {
+	limiter.SetLimit(state.baseLimit * rate.Limit(state.fraction))
+	limiter.SetBurst(max(1, int(float64(state.baseBurst)*state.fraction)))
+}
+
+// limiterName returns the provider or model ID a limiter was created for (caller holds p.mu)
+func (p *RateLimiterPool) limiterName(limiter *rate.Limiter) string {
+	for name, l := range p.providerLimiters {
+		if l == limiter {
+			return name
+		}
+	}
+	for modelID, l := range p.limiters {
+		if l == limiter {
+			return modelID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRateLimiterPool_Observe(t *testing.T) {
+	clock := time.Now()
+	pool := NewRateLimiterPool()
+	pool.now = func() time.Time { return clock }
+	pool.SetAdaptive(true)
+
+	limiter := pool.GetOrCreateProvider("nvidia", 600, 15) // 10 rps, burst 90
+	base := limiter.Limit()
+
+	// Fewer 429s than the threshold leave the rate alone
+	for range adaptiveBurstThreshold - 1 {
+		pool.Observe(discardLogger, limiter, true)
+	}
+	if limiter.Limit() != base {
+		t.Fatalf("Expected rate unchanged below threshold, got %v", limiter.Limit())
+	}
+
+	// The threshold halves rate and burst
+	pool.Observe(discardLogger, limiter, true)
+	if limiter.Limit() != base/2 || limiter.Burst() != 45 {
+		t.Fatalf("Expected halved rate %v and burst 45, got %v and %d", base/2, limiter.Limit(), limiter.Burst())
+	}
+
+	// A concurrent burst of 429s within the same window does not cut again
+	for range 10 {
+		pool.Observe(discardLogger, limiter, true)
+	}
+	if limiter.Limit() != base/2 {
+		t.Errorf("Expected one decrease per window, got %v", limiter.Limit())
+	}
+
+	// Successes do not recover while 429s are recent
+	pool.Observe(discardLogger, limiter, false)
+	if limiter.Limit() != base/2 {
+		t.Errorf("Expected no recovery right after 429s, got %v", limiter.Limit())
+	}
+
+	// Quiet period: each recovery interval adds a step of the configured rate
+	clock = clock.Add(adaptiveWindow + time.Second)
+	pool.Observe(discardLogger, limiter, false)
+	want := base * rate.Limit(0.5+adaptiveIncreaseFraction)
+	if diff := limiter.Limit() - want; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected rate %v after one recovery step, got %v", want, limiter.Limit())
+	}
+	pool.Observe(discardLogger, limiter, false) // Same instant: no second step
+	if diff := limiter.Limit() - want; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected recovery to wait for the interval, got %v", limiter.Limit())
+	}
+
+	for range 20 {
+		clock = clock.Add(adaptiveRecoveryInterval)
+		pool.Observe(discardLogger, limiter, false)
+	}
+	if limiter.Limit() != base || limiter.Burst() != 90 {
+		t.Errorf("Expected full recovery to %v / 90, got %v / %d", base, limiter.Limit(), limiter.Burst())
+	}
+}
+
+func TestRateLimiterPool_ObserveLogsToLogger(t *testing.T) {
+	clock := time.Now()
+	pool := NewRateLimiterPool()
+	pool.now = func() time.Time { return clock }
+	pool.SetAdaptive(true)
+	limiter := pool.GetOrCreateProvider("nvidia", 600, 15)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	for range adaptiveBurstThreshold {
+		pool.Observe(logger, limiter, true)
+	}
+	if !strings.Contains(buf.String(), "Reducing request rate") || !strings.Contains(buf.String(), "limiter=nvidia") {
+		t.Errorf("Expected rate reduction logged to the given logger, got %q", buf.String())
+	}
+}
+
+func TestRateLimiterPool_ObserveFloor(t *testing.T) {
+	clock := time.Now()
+	pool := NewRateLimiterPool()
+	pool.now = func() time.Time { return clock }
+	pool.SetAdaptive(true)
+
+	limiter := pool.GetOrCreate("model", 600)
+	base := limiter.Limit()
+	for range 10 {
+		for range adaptiveBurstThreshold {
+			pool.Observe(discardLogger, limiter, true)
+		}
+		clock = clock.Add(adaptiveWindow + time.Second)
+	}
+	if want := base * adaptiveMinFraction; limiter.Limit() != want {
+		t.Errorf("Expected rate floored at %v, got %v", want, limiter.Limit())
+	}
+}
+
+func TestRateLimiterPool_ObserveDisabled(t *testing.T) {
+	pool := NewRateLimiterPool()
+	limiter := pool.GetOrCreate("model", 600)
+	base := limiter.Limit()
+	for range 10 {
+		pool.Observe(discardLogger, limiter, true)
+	}
+	if limiter.Limit() != base {
+		t.Errorf("Expected no adjustment when adaptive limiting is disabled, got %v", limiter.Limit())
+	}
+}
+
+func TestChatCompletion_AdaptiveRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error": {"message": "rate limited"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.maxRetries = 0
+	client.SetAdaptiveRateLimit(true)
+
+	modelCfg := tlsTestModel(server.URL)
+	modelCfg.RateLimitPerMinute = 6000
+	for range adaptiveBurstThreshold {
+		if _, err := client.ChatCompletion(context.Background(), modelCfg, "", []Message{{Role: "user", Content: "hi"}}); err == nil {
+			t.Fatalf("Expected rate limit error")
+		}
+	}
+
+	limiter := client.rateLimiterPool.GetOrCreate(server.URL+":test-model", 6000)
+	if limiter.Limit() != rate.Limit(50) {
+		t.Errorf("Expected rate halved to 50 rps after 429s, got %v", limiter.Limit())
+	}
+	if calls.Load() != adaptiveBurstThreshold {
+		t.Errorf("Expected %d requests, got %d", adaptiveBurstThreshold, calls.Load())
+	}
+}
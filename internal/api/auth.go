@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+const (
+	// DefaultAuthHeader carries the API key unless models.<name>.auth_header is set
+	DefaultAuthHeader = "Authorization"
+	// DefaultAuthScheme prefixes the key in the Authorization header
+	DefaultAuthScheme = "Bearer"
+	// authSchemeNone sends the bare key (auth_scheme = "none")
+	authSchemeNone = "none"
+)
+
+// setAuthHeader adds the API key to a request the way the model's endpoint expects:
+// "Authorization: Bearer <key>" by default, or "<auth_header>: <auth_scheme> <key>"
+// A custom header gets the bare key unless auth_scheme is set
+func setAuthHeader(header http.Header, modelCfg config.ModelConfig, apiKey string) {
+	name := modelCfg.AuthHeader
+	if name == "" {
+		name = DefaultAuthHeader
+	}
+	scheme := modelCfg.AuthScheme
+	if scheme == "" && strings.EqualFold(name, DefaultAuthHeader) {
+		scheme = DefaultAuthScheme
+	}
+
+	if scheme == "" || strings.EqualFold(scheme, authSchemeNone) {
+		header.Set(name, apiKey)
+		return
+	}
+	header.Set(name, scheme+" "+apiKey)
+}
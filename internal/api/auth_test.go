@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestSetAuthHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		scheme     string
+		wantHeader string
+		wantValue  string
+	}{
+		{"default", "", "", "Authorization", "Bearer key"},
+		{"custom scheme", "", "Token", "Authorization", "Token key"},
+		{"bare key in authorization", "", "none", "Authorization", "key"},
+		{"custom header", "Api-Key", "", "Api-Key", "key"},
+		{"custom header with scheme", "X-Gateway-Auth", "Key", "X-Gateway-Auth", "Key key"},
+		{"lowercase authorization keeps bearer", "authorization", "", "Authorization", "Bearer key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			setAuthHeader(header, config.ModelConfig{AuthHeader: tt.header, AuthScheme: tt.scheme}, "key")
+			if len(header) != 1 {
+				t.Errorf("Expected exactly one header, got %v", header)
+			}
+			if got := header.Get(tt.wantHeader); got != tt.wantValue {
+				t.Errorf("Expected %s: %q, got %q", tt.wantHeader, tt.wantValue, got)
+			}
+		})
+	}
+}
+
+func TestChatCompletion_CustomAuthHeader(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		name := "non-streaming"
+		if streaming {
+			name = "streaming"
+		}
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Api-Key"); got != "Token test-key" {
+					t.Errorf("Expected Api-Key header 'Token test-key', got %q", got)
+				}
+				if got := r.Header.Get("Authorization"); got != "" {
+					t.Errorf("Expected no Authorization header, got %q", got)
+				}
+				if streaming {
+					w.Header().Set("Content-Type", "text/event-stream")
+					_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+					return
+				}
+				_, _ = io.WriteString(w, `{"id":"1","model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+			}))
+			defer server.Close()
+
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			client := NewClient(logger)
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				Temperature:        0.7,
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 600,
+				HTTPTimeoutSeconds: 5,
+				AuthHeader:         "Api-Key",
+				AuthScheme:         "Token",
+			}
+			messages := []Message{{Role: "user", Content: "hi"}}
+
+			var err error
+			if streaming {
+				_, err = client.ChatCompletionStreaming(context.Background(), modelCfg, "test-key", messages)
+			} else {
+				_, err = client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRedactHeaders_CustomAuthHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Gateway-Token", "secret")
+	header.Set("Content-Type", "application/json")
+
+	redacted := redactHeaders(header)
+	if got := redacted["X-Gateway-Token"]; len(got) != 1 || got[0] != "[REDACTED]" {
+		t.Errorf("Expected custom auth header to be redacted, got %v", got)
+	}
+	if got := redacted["Content-Type"]; len(got) != 1 || got[0] != "application/json" {
+		t.Errorf("Expected Content-Type to be kept, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// BackoffStrategy scales the base retry delay for retry attempt n (1-based)
+// 429s keep growing by the rate limit multiplier whatever the strategy
+type BackoffStrategy interface {
+	Factor(attempt int) float64
+}
+
+// ExponentialBackoff doubles the delay per attempt: 1x, 2x, 4x, 8x (the default)
+type ExponentialBackoff struct{}
+
+func (ExponentialBackoff) Factor(attempt int) float64 {
+	return math.Pow(2, float64(attempt-1))
+}
+
+// LinearBackoff grows the delay by the base delay per attempt: 1x, 2x, 3x, 4x
+type LinearBackoff struct{}
+
+func (LinearBackoff) Factor(attempt int) float64 {
+	return float64(attempt)
+}
+
+// FibonacciBackoff follows the Fibonacci sequence: 1x, 1x, 2x, 3x, 5x, 8x
+type FibonacciBackoff struct{}
+
+func (FibonacciBackoff) Factor(attempt int) float64 {
+	prev, cur := 0.0, 1.0
+	for i := 1; i < attempt && !math.IsInf(cur, 1); i++ {
+		prev, cur = cur, prev+cur
+	}
+	return cur
+}
+
+// ConstantBackoff waits the base delay before every attempt
+type ConstantBackoff struct{}
+
+func (ConstantBackoff) Factor(int) float64 {
+	return 1
+}
+
+// NewBackoffStrategy returns the strategy for a retry.strategy name ("" = exponential)
+func NewBackoffStrategy(name string) (BackoffStrategy, error) {
+	switch name {
+	case "", config.RetryStrategyExponential:
+		return ExponentialBackoff{}, nil
+	case config.RetryStrategyLinear:
+		return LinearBackoff{}, nil
+	case config.RetryStrategyFibonacci:
+		return FibonacciBackoff{}, nil
+	case config.RetryStrategyConstant:
+		return ConstantBackoff{}, nil
+	default:
+		return nil, fmt.Errorf("unknown retry strategy %q", name)
+	}
+}
@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"math"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/metrics"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -20,12 +22,19 @@ const (
 	DefaultHTTPTimeout = 120 * time.Second
 	// DefaultMaxRetries is the default maximum number of retry attempts
 	DefaultMaxRetries = 3
-	// DefaultBaseRetryDelay is the base delay for exponential backoff
+	// DefaultBaseRetryDelay is the base delay scaled by the retry backoff strategy
 	DefaultBaseRetryDelay = 2 * time.Second
 	// RateLimitBackoffMultiplier is the multiplier for rate limit backoff (3^n)
 	RateLimitBackoffMultiplier = 3
 	// DefaultMaxBackoffDuration is the default maximum backoff duration
 	DefaultMaxBackoffDuration = 2 * time.Minute
+	// DefaultMaxIdleConns is the default number of idle connections kept across all hosts
+	DefaultMaxIdleConns = 100
+	// DefaultMaxIdleConnsPerHost is the default number of idle connections kept per host
+	// (Go's default of 2 forces new TLS handshakes under concurrent load)
+	DefaultMaxIdleConnsPerHost = 16
+	// DefaultIdleConnTimeout is how long idle keep-alive connections stay open
+	DefaultIdleConnTimeout = 90 * time.Second
 )
 
 // isLocalEndpoint checks if an endpoint is a local address
@@ -43,23 +52,75 @@ type Client struct {
 	logger               *slog.Logger
 	maxRetries           int
 	baseRetryDelay       time.Duration
-	providerRateLimits   map[string]int // Provider-level rate limits (requests per minute)
-	providerBurstPercent int            // Burst capacity as percentage for provider limiters
+	jitterFraction       float64                 // Random +/- share of each retry backoff
+	jitterSource         jitterSource            // Random source for retry jitter (seeded by SetSeed)
+	rateLimitMultiplier  float64                 // 429 backoff base (multiplier^attempt x baseRetryDelay)
+	backoffStrategy      BackoffStrategy         // Backoff growth for other retries (nil = exponential)
+	providerRateLimits   map[string]int          // Provider-level rate limits (requests per minute)
+	providerBurstPercent int                     // Burst capacity as percentage for provider limiters
+	debugDump            *debugDumper            // Optional request/response dump sink (nil = disabled)
+	tokenEstimator       TokenEstimator          // Prompt length guard estimator (nil = EstimateTokens)
+	extraBodyWarned      sync.Map                // Model names already warned about ignored extra_body keys
+	toolsRejected        sync.Map                // Models whose provider rejected tools (use_tool_calling falls back to JSON mode)
+	keyRotator           KeyRotator              // Optional API key rotation on 429 (nil = always retry with the same key)
+	timings              requestTimer            // Aggregate request/rate limiter time for benchmark reports
+	endpointClients      map[string]*http.Client // Per-base_url clients for models with their own TLS settings
+	metrics              *metrics.Collector      // Optional Prometheus metrics (nil = disabled)
+	fingerprints         fingerprintTracker      // system_fingerprint values observed per model
+	userAgent            string                  // User-Agent for models without their own user_agent
 }
 
-// NewClient creates a new API client
+// KeyRotator swaps a rate-limited API key for another key of the same provider
+// Implemented by config.Secrets when a provider has several keys (e.g. OPENAI_API_KEYS)
+type KeyRotator interface {
+	// RateLimited records a 429 for key and returns the key to retry with
+	RateLimited(key string) string
+}
+
+// NewClient creates a new API client with default connection pool settings
 func NewClient(logger *slog.Logger) *Client {
+	return NewClientWithNetwork(logger, config.NetworkConfig{})
+}
+
+// NewClientWithNetwork creates a new API client using the given connection pool settings
+// Zero values fall back to the package defaults
+func NewClientWithNetwork(logger *slog.Logger, netCfg config.NetworkConfig) *Client {
 	return &Client{
 		httpClient: &http.Client{
+			Transport: newTransport(netCfg),
 			// No timeout here - we use context timeouts instead for per-model control
 			Timeout: 0,
 		},
-		rateLimiterPool:    NewRateLimiterPool(),
-		logger:             logger,
-		maxRetries:         DefaultMaxRetries,
-		baseRetryDelay:     DefaultBaseRetryDelay,
-		providerRateLimits: make(map[string]int),
+		rateLimiterPool:     NewRateLimiterPool(),
+		logger:              logger,
+		maxRetries:          DefaultMaxRetries,
+		baseRetryDelay:      DefaultBaseRetryDelay,
+		jitterFraction:      DefaultJitterFraction,
+		rateLimitMultiplier: RateLimitBackoffMultiplier,
+		providerRateLimits:  make(map[string]int),
+		userAgent:           userAgentOrDefault(netCfg.UserAgent),
+	}
+}
+
+// newTransport builds an HTTP transport tuned for many concurrent requests to a few hosts
+func newTransport(netCfg config.NetworkConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.MaxIdleConns = DefaultMaxIdleConns
+	if netCfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = netCfg.MaxIdleConns
 	}
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if netCfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = netCfg.MaxIdleConnsPerHost
+	}
+	transport.MaxConnsPerHost = netCfg.MaxConnsPerHost // 0 = unlimited
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+	if netCfg.IdleConnTimeoutSeconds > 0 {
+		transport.IdleConnTimeout = time.Duration(netCfg.IdleConnTimeoutSeconds) * time.Second
+	}
+
+	return transport
 }
 
 // SetProviderRateLimits sets the global provider-level rate limits
@@ -71,6 +132,60 @@ func (c *Client) SetProviderRateLimits(limits map[string]int, burstPercent int)
 	}
 }
 
+// SetKeyRotator makes retries after a 429 switch to the next key from rotator
+func (c *Client) SetKeyRotator(rotator KeyRotator) {
+	c.keyRotator = rotator
+}
+
+// rotateKey returns the key to retry with after err (unchanged unless err is a 429)
+func (c *Client) rotateKey(modelCfg config.ModelConfig, apiKey string, err error) string {
+	if c.keyRotator == nil || apiKey == "" || !c.isRateLimitError(err) {
+		return apiKey
+	}
+	next := c.keyRotator.RateLimited(apiKey)
+	if next != apiKey {
+		c.logger.Debug("Rotating API key after rate limit", "model", modelCfg.ModelName)
+	}
+	return next
+}
+
+// SetAdaptiveRateLimit enables provider_adaptive_rate_limit: limiters slow down after a burst
+// of 429s and recover gradually as requests succeed again
+func (c *Client) SetAdaptiveRateLimit(enabled bool) {
+	c.rateLimiterPool.SetAdaptive(enabled)
+}
+
+// observeRateLimit feeds a request outcome to the adaptive limiter (only successes and 429s count)
+func (c *Client) observeRateLimit(limiter *rate.Limiter, err error) {
+	if err == nil || c.isRateLimitError(err) {
+		c.rateLimiterPool.Observe(c.logger, limiter, err != nil)
+	}
+}
+
+// SetMetrics records request durations, rate limiter waits, 429s and token usage to collector
+func (c *Client) SetMetrics(collector *metrics.Collector) {
+	c.metrics = collector
+}
+
+// recordAttempt records a successful attempt's system_fingerprint and reports the attempt
+// to the metrics collector (no-op when metrics are disabled)
+func (c *Client) recordAttempt(model string, duration time.Duration, resp *ChatCompletionResponse, err error) {
+	if err == nil {
+		c.observeFingerprint(model, resp)
+	}
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.RecordAPIRequest(model, duration, err == nil)
+	if err != nil {
+		if c.isRateLimitError(err) {
+			c.metrics.RecordRateLimited(model)
+		}
+		return
+	}
+	c.metrics.RecordTokens(model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+}
+
 // SetMaxRetries sets the maximum number of retry attempts
 func (c *Client) SetMaxRetries(maxRetries int) {
 	c.maxRetries = maxRetries
@@ -109,8 +224,20 @@ func (c *Client) ChatCompletion(
 	modelCfg config.ModelConfig,
 	apiKey string,
 	messages []Message,
+) (*ChatCompletionResponse, error) {
+	return c.chatCompletion(ctx, modelCfg, apiKey, messages, nil)
+}
+
+// chatCompletion sends a chat completion request, forcing a call to tool when it is set
+func (c *Client) chatCompletion(
+	ctx context.Context,
+	modelCfg config.ModelConfig,
+	apiKey string,
+	messages []Message,
+	tool *Tool,
 ) (*ChatCompletionResponse, error) {
 	requestStart := time.Now()
+	defer c.timings.finish(requestStart)
 
 	// Compute per-attempt HTTP timeout (applied inside the retry loop, not here).
 	// HTTPTimeoutSeconds should always be set (config loader defaults to 120s).
@@ -123,6 +250,12 @@ func (c *Client) ChatCompletion(
 			"timeout", httpTimeout)
 	}
 
+	// Fail (or truncate) locally instead of waiting for a context length 400 from the API
+	messages, err := c.guardPromptLength(modelCfg, messages)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate a unique model ID for rate limiting
 	modelID := fmt.Sprintf("%s:%s", modelCfg.BaseURL, modelCfg.ModelName)
 
@@ -137,10 +270,13 @@ func (c *Client) ChatCompletion(
 
 	// Wait for rate limiter (provider-level if configured, otherwise model-level)
 	rateLimitStart := time.Now()
-	if err := c.rateLimiterPool.Wait(ctx, modelID, modelCfg.RateLimitPerMinute, providerName, providerRPM, c.providerBurstPercent); err != nil {
+	limiter := c.rateLimiterPool.Limiter(modelID, modelCfg.RateLimitPerMinute, providerName, providerRPM, c.providerBurstPercent)
+	if err := limiter.Wait(ctx); err != nil {
 		return nil, fmt.Errorf("rate limiter wait failed: %w", err)
 	}
 	rateLimitWait := time.Since(rateLimitStart)
+	c.timings.addWait(rateLimitWait)
+	c.metrics.RecordRateLimiterWait(modelCfg.ModelName, rateLimitWait)
 
 	// Construct request
 	req := ChatCompletionRequest{
@@ -150,10 +286,18 @@ func (c *Client) ChatCompletion(
 		TopP:        modelCfg.TopP,
 		MaxTokens:   modelCfg.MaxOutputTokens,
 		N:           1,
+
+		ExtraBody:         modelCfg.ExtraBody,
+		ExtraBodyOverride: modelCfg.ExtraBodyOverride,
+		FieldMap:          modelCfg.FieldMap,
+		FieldOmit:         modelCfg.FieldOmit,
 	}
 
-	// Enable JSON mode if configured
-	if modelCfg.UseJSONMode {
+	// A forced tool call already constrains the output; otherwise enable JSON mode if configured
+	if tool != nil {
+		req.Tools = []Tool{*tool}
+		req.ToolChoice = &ToolChoice{Type: "function", Function: ToolChoiceFunction{Name: tool.Function.Name}}
+	} else if modelCfg.UseJSONMode {
 		req.ResponseFormat = &ResponseFormat{Type: "json_object"}
 	}
 
@@ -161,6 +305,7 @@ func (c *Client) ChatCompletion(
 	// Use model-specific maxRetries if configured (default is 3 from loader)
 	// Set to -1 for unlimited retries
 	var lastErr error
+	rotated := false // Retrying a 429 with a different key, so the long rate limit backoff is skipped
 	maxAttempts := modelCfg.MaxRetries
 	if maxAttempts == 0 {
 		maxAttempts = c.maxRetries // Fallback to client default
@@ -170,24 +315,7 @@ func (c *Client) ChatCompletion(
 	for attempt := 0; maxAttempts < 0 || attempt <= maxAttempts; attempt++ {
 		if attempt > 0 {
 			// Calculate backoff with jitter
-			backoff := time.Duration(math.Pow(2, float64(attempt-1))) * c.baseRetryDelay
-
-			// For rate limit errors, use longer delays (3^n: 6s, 18s, 54s)
-			if apiErr, ok := lastErr.(*APIError); ok && apiErr.StatusCode == http.StatusTooManyRequests {
-				backoff = time.Duration(math.Pow(RateLimitBackoffMultiplier, float64(attempt))) * c.baseRetryDelay
-			}
-
-			// Apply configurable backoff cap
-			maxBackoff := DefaultMaxBackoffDuration
-			if modelCfg.MaxBackoffSeconds > 0 {
-				maxBackoff = time.Duration(modelCfg.MaxBackoffSeconds) * time.Second
-			}
-			if backoff > maxBackoff {
-				backoff = maxBackoff
-			}
-
-			jitter := time.Duration(float64(backoff) * 0.1 * (2*float64(time.Now().UnixNano()%100)/100 - 1))
-			sleepDuration := backoff + jitter
+			sleepDuration := c.retryBackoff(attempt, lastErr, rotated, modelCfg)
 
 			c.logger.Warn("Retrying API request",
 				"attempt", attempt,
@@ -204,8 +332,11 @@ func (c *Client) ChatCompletion(
 		}
 
 		attemptCtx, attemptCancel := context.WithTimeout(ctx, httpTimeout)
-		resp, err := c.doRequest(attemptCtx, modelCfg.BaseURL, apiKey, req)
+		attemptStart := time.Now()
+		resp, err := c.doRequest(attemptCtx, modelCfg, apiKey, req)
 		attemptCancel()
+		c.observeRateLimit(limiter, err)
+		c.recordAttempt(modelCfg.ModelName, time.Since(attemptStart), resp, err)
 		if err == nil {
 			apiCallDuration := time.Since(apiCallStart)
 			totalDuration := time.Since(requestStart)
@@ -224,6 +355,7 @@ func (c *Client) ChatCompletion(
 					"max_tokens", modelCfg.MaxOutputTokens,
 					"finish_reason", resp.Choices[0].FinishReason)
 			}
+			resp.Attempts = attempt + 1
 			return resp, nil
 		}
 
@@ -233,6 +365,9 @@ func (c *Client) ChatCompletion(
 		if !c.isRetryable(err) {
 			return nil, err
 		}
+		nextKey := c.rotateKey(modelCfg, apiKey, err)
+		rotated = nextKey != apiKey
+		apiKey = nextKey
 	}
 
 	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
@@ -247,7 +382,12 @@ func (c *Client) ChatCompletionStructured(
 	apiKey string,
 	messages []Message,
 ) (*ChatCompletionResponse, error) {
-	// Use structure_temperature if set, otherwise use regular temperature
+	// Call regular ChatCompletion with modified config
+	return c.ChatCompletion(ctx, c.structuredConfig(modelCfg), apiKey, messages)
+}
+
+// structuredConfig returns modelCfg with structure_temperature (if set) as its temperature
+func (c *Client) structuredConfig(modelCfg config.ModelConfig) config.ModelConfig {
 	tempCfg := modelCfg
 	if modelCfg.StructureTemperature > 0 {
 		tempCfg.Temperature = modelCfg.StructureTemperature
@@ -256,14 +396,12 @@ func (c *Client) ChatCompletionStructured(
 			"original_temp", modelCfg.Temperature,
 			"actual_temp_used", tempCfg.Temperature)
 	}
-
-	// Call regular ChatCompletion with modified config
-	return c.ChatCompletion(ctx, tempCfg, apiKey, messages)
+	return tempCfg
 }
 
 func (c *Client) doRequest(
 	ctx context.Context,
-	baseURL string,
+	modelCfg config.ModelConfig,
 	apiKey string,
 	req ChatCompletionRequest,
 ) (*ChatCompletionResponse, error) {
@@ -272,16 +410,21 @@ func (c *Client) doRequest(
 	defer putBuffer(buf)
 
 	// Encode request directly to buffer (avoids intermediate allocation)
-	if err := json.NewEncoder(buf).Encode(req); err != nil {
+	// extra_body, field_map and field_omit need a map round-trip to merge, rename and drop keys
+	var payload interface{} = req
+	if len(req.ExtraBody) > 0 || len(req.FieldMap) > 0 || len(req.FieldOmit) > 0 {
+		body, err := c.requestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		payload = body
+	}
+	if err := json.NewEncoder(buf).Encode(payload); err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create HTTP request
-	endpoint := baseURL
-	if endpoint[len(endpoint)-1] != '/' {
-		endpoint += "/"
-	}
-	endpoint += "chat/completions"
+	endpoint := ChatEndpoint(modelCfg.BaseURL, modelCfg.ChatPath)
 
 	// Use bytes.NewReader with the buffered data
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(buf.Bytes()))
@@ -291,8 +434,9 @@ func (c *Client) doRequest(
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgentFor(modelCfg))
 	if apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		setAuthHeader(httpReq.Header, modelCfg, apiKey)
 		c.logger.Debug("API request", "endpoint", endpoint, "has_key", true, "key_length", len(apiKey))
 	} else {
 		// Only warn about missing API key for non-local endpoints
@@ -302,8 +446,9 @@ func (c *Client) doRequest(
 	}
 
 	// Send request
-	httpResp, err := c.httpClient.Do(httpReq)
+	httpResp, err := c.httpClientFor(modelCfg.BaseURL).Do(httpReq)
 	if err != nil {
+		c.debugDump.dump(httpReq, buf.Bytes(), 0, nil, err, false)
 		return nil, &APIError{
 			Message:    fmt.Sprintf("request failed: %v", err),
 			StatusCode: 0,
@@ -316,8 +461,12 @@ func (c *Client) doRequest(
 		}
 	}()
 
-	// Read response body
-	respBody, err := io.ReadAll(httpResp.Body)
+	// Read response body, capped by max_response_bytes
+	respBody, err := io.ReadAll(limitResponseBody(httpResp.Body, maxResponseBytes(modelCfg), modelCfg.ModelName))
+	c.debugDump.dump(httpReq, buf.Bytes(), httpResp.StatusCode, respBody, err, false)
+	if tooLarge, ok := responseTooLarge(err); ok {
+		return nil, tooLarge
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
@@ -344,6 +493,11 @@ func (c *Client) doRequest(
 		}
 	}
 
+	// Some gateways report failures as a 200 with an error object instead of an error status
+	if apiErr := c.errorInBody(respBody); apiErr != nil {
+		return nil, apiErr
+	}
+
 	// Parse response
 	var resp ChatCompletionResponse
 	if err := json.Unmarshal(respBody, &resp); err != nil {
@@ -354,6 +508,14 @@ func (c *Client) doRequest(
 		return nil, fmt.Errorf("no choices returned in response")
 	}
 
+	// Log reasoning detection (reasoning_content or reasoning field, see Message.UnmarshalJSON)
+	if reasoning := resp.Choices[0].Message.ReasoningContent; reasoning != "" {
+		c.logger.Debug("Reasoning content detected",
+			"model", resp.Model,
+			"reasoning_length", len(reasoning),
+			"content_length", len(resp.Choices[0].Message.Content))
+	}
+
 	return &resp, nil
 }
 
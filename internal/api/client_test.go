@@ -148,8 +148,7 @@ func TestChatCompletion_RetryOn500(t *testing.T) {
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	client := NewClient(logger)
-	client.maxRetries = 3
-	client.baseRetryDelay = 1 // 1ms for fast testing
+	client.SetRetryOptions(RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond}) // Fast for testing
 
 	modelCfg := config.ModelConfig{
 		BaseURL:            server.URL,
@@ -165,6 +164,9 @@ func TestChatCompletion_RetryOn500(t *testing.T) {
 	if attemptCount != 3 {
 		t.Errorf("Expected 3 attempts (2 retries), got %d", attemptCount)
 	}
+	if resp.Attempts != 3 {
+		t.Errorf("Expected the response to report 3 attempts, got %d", resp.Attempts)
+	}
 	if resp.Choices[0].Message.Content != "success" {
 		t.Errorf("Expected 'success', got '%s'", resp.Choices[0].Message.Content)
 	}
@@ -193,8 +195,7 @@ func TestChatCompletion_BackoffCap(t *testing.T) {
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	client := NewClient(logger)
-	client.maxRetries = 5
-	client.baseRetryDelay = 1 * time.Millisecond // Fast for testing
+	client.SetRetryOptions(RetryOptions{MaxAttempts: 5, BaseDelay: time.Millisecond}) // Fast for testing
 
 	// Set a very low backoff cap to test capping behavior
 	modelCfg := config.ModelConfig{
@@ -248,3 +249,89 @@ func TestChatCompletion_DefaultBackoffCap(t *testing.T) {
 	}
 	// The actual default is applied in the loader.go via applyDefaults()
 }
+
+func TestNewTransport_Defaults(t *testing.T) {
+	transport := newTransport(config.NetworkConfig{})
+
+	if transport.MaxIdleConns != DefaultMaxIdleConns {
+		t.Errorf("Expected MaxIdleConns %d, got %d", DefaultMaxIdleConns, transport.MaxIdleConns)
+	}
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost {
+		t.Errorf("Expected MaxIdleConnsPerHost %d, got %d", DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	}
+	if transport.MaxConnsPerHost != 0 {
+		t.Errorf("Expected unlimited MaxConnsPerHost, got %d", transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("Expected IdleConnTimeout %v, got %v", DefaultIdleConnTimeout, transport.IdleConnTimeout)
+	}
+}
+
+func TestNewTransport_Overrides(t *testing.T) {
+	transport := newTransport(config.NetworkConfig{
+		MaxIdleConns:           512,
+		MaxIdleConnsPerHost:    128,
+		MaxConnsPerHost:        256,
+		IdleConnTimeoutSeconds: 30,
+	})
+
+	if transport.MaxIdleConns != 512 {
+		t.Errorf("Expected MaxIdleConns 512, got %d", transport.MaxIdleConns)
+	}
+	if transport.MaxIdleConnsPerHost != 128 {
+		t.Errorf("Expected MaxIdleConnsPerHost 128, got %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport.MaxConnsPerHost != 256 {
+		t.Errorf("Expected MaxConnsPerHost 256, got %d", transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("Expected IdleConnTimeout 30s, got %v", transport.IdleConnTimeout)
+	}
+}
+
+func TestChatCompletion_NonStreamingReasoning(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "reasoning_content field",
+			body: `{"choices":[{"index":0,"message":{"role":"assistant","content":"Answer","reasoning_content":"Thinking"},"finish_reason":"stop"}]}`,
+		},
+		{
+			name: "reasoning field",
+			body: `{"choices":[{"index":0,"message":{"role":"assistant","content":"Answer","reasoning":"Thinking"},"finish_reason":"stop"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			client := NewClient(logger)
+
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				RateLimitPerMinute: 60,
+			}
+			resp, err := client.ChatCompletion(context.Background(), modelCfg, "test-key",
+				[]Message{{Role: "user", Content: "Test message"}})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if resp.Choices[0].Message.Content != "Answer" {
+				t.Errorf("Expected content 'Answer', got '%s'", resp.Choices[0].Message.Content)
+			}
+			if resp.Choices[0].Message.ReasoningContent != "Thinking" {
+				t.Errorf("Expected reasoning 'Thinking', got '%s'", resp.Choices[0].Message.ReasoningContent)
+			}
+		})
+	}
+}
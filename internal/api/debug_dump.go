@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// redactedHeaderParts mark credential headers replaced before request headers are written to a
+// debug dump: Authorization, X-Api-Key, and custom auth headers (models.<name>.auth_header)
+var redactedHeaderParts = []string{"auth", "key", "token", "secret"}
+
+// debugDumper writes raw request/response bodies to a directory for debugging providers
+type debugDumper struct {
+	dir    string
+	seq    atomic.Uint64
+	logger *slog.Logger
+}
+
+// debugExchange is the request side of a dump, written next to the raw response body
+type debugExchange struct {
+	Timestamp  time.Time           `json:"timestamp"`
+	Endpoint   string              `json:"endpoint"`
+	Streaming  bool                `json:"streaming"`
+	Headers    map[string][]string `json:"headers"`
+	StatusCode int                 `json:"status_code"` // 0 when the request failed before a response
+	Error      string              `json:"error,omitempty"`
+	Body       json.RawMessage     `json:"body"`
+}
+
+// SetDebugDumpDir enables writing every request body and raw response body to dir
+// Authorization headers are redacted; an empty dir disables dumping
+func (c *Client) SetDebugDumpDir(dir string) error {
+	if dir == "" {
+		c.debugDump = nil
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create debug dump directory: %w", err)
+	}
+	c.debugDump = &debugDumper{dir: dir, logger: c.logger}
+	return nil
+}
+
+// dump writes one request/response exchange as <timestamp>-<seq>.request.json and
+// <timestamp>-<seq>.response.{json,sse}; failures are logged and never affect the request
+func (d *debugDumper) dump(httpReq *http.Request, reqBody []byte, statusCode int, respBody []byte, reqErr error, streaming bool) {
+	if d == nil {
+		return
+	}
+
+	now := time.Now()
+	base := fmt.Sprintf("%s-%06d", now.UTC().Format("20060102T150405.000000Z"), d.seq.Add(1))
+
+	exchange := debugExchange{
+		Timestamp:  now,
+		Endpoint:   httpReq.URL.String(),
+		Streaming:  streaming,
+		Headers:    redactHeaders(httpReq.Header),
+		StatusCode: statusCode,
+		Body:       json.RawMessage(bytesOrNull(reqBody)),
+	}
+	if reqErr != nil {
+		exchange.Error = reqErr.Error()
+	}
+
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		d.logger.Warn("Failed to encode debug dump", "error", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(d.dir, base+".request.json"), data, 0o600); err != nil {
+		d.logger.Warn("Failed to write debug dump", "error", err)
+		return
+	}
+
+	if respBody == nil {
+		return
+	}
+	ext := ".response.json"
+	if streaming && statusCode == http.StatusOK {
+		ext = ".response.sse"
+	}
+	if err := os.WriteFile(filepath.Join(d.dir, base+ext), respBody, 0o600); err != nil {
+		d.logger.Warn("Failed to write debug dump", "error", err)
+	}
+}
+
+// redactHeaders copies headers with credentials replaced
+func redactHeaders(headers http.Header) map[string][]string {
+	redacted := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		redacted[name] = append([]string(nil), values...)
+	}
+	for name := range redacted {
+		lower := strings.ToLower(name)
+		for _, part := range redactedHeaderParts {
+			if strings.Contains(lower, part) {
+				redacted[name] = []string{"[REDACTED]"}
+				break
+			}
+		}
+	}
+	return redacted
+}
+
+// bytesOrNull keeps the envelope valid JSON when the request body is empty or not JSON
+func bytesOrNull(body []byte) []byte {
+	if len(body) == 0 || !json.Valid(body) {
+		quoted, _ := json.Marshal(string(body))
+		if len(body) == 0 {
+			return []byte("null")
+		}
+		return quoted
+	}
+	return body
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestDebugDump_RedactsAuthorization(t *testing.T) {
+	const rawResponse = `{"id":"test-123","choices":[{"index":0,"message":{"role":"assistant","content":"Test response"},"finish_reason":"stop"}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(rawResponse))
+	}))
+	defer server.Close()
+
+	dumpDir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := NewClient(logger)
+	if err := client.SetDebugDumpDir(dumpDir); err != nil {
+		t.Fatalf("Expected no error enabling debug dump, got: %v", err)
+	}
+
+	modelCfg := config.ModelConfig{
+		BaseURL:            server.URL,
+		ModelName:          "test-model",
+		Temperature:        0.7,
+		TopP:               1.0,
+		MaxOutputTokens:    100,
+		RateLimitPerMinute: 60,
+	}
+	if _, err := client.ChatCompletion(context.Background(), modelCfg, "secret-key",
+		[]Message{{Role: "user", Content: "Test message"}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	requests, _ := filepath.Glob(filepath.Join(dumpDir, "*.request.json"))
+	responses, _ := filepath.Glob(filepath.Join(dumpDir, "*.response.json"))
+	if len(requests) != 1 || len(responses) != 1 {
+		t.Fatalf("Expected 1 request and 1 response dump, got %d and %d", len(requests), len(responses))
+	}
+
+	requestData, err := os.ReadFile(requests[0])
+	if err != nil {
+		t.Fatalf("Failed to read request dump: %v", err)
+	}
+	if strings.Contains(string(requestData), "secret-key") {
+		t.Error("Expected API key to be redacted from request dump")
+	}
+
+	var exchange debugExchange
+	if err := json.Unmarshal(requestData, &exchange); err != nil {
+		t.Fatalf("Expected request dump to be valid JSON, got: %v", err)
+	}
+	if got := exchange.Headers["Authorization"]; len(got) != 1 || got[0] != "[REDACTED]" {
+		t.Errorf("Expected redacted Authorization header, got %v", got)
+	}
+	if exchange.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", exchange.StatusCode)
+	}
+	if !strings.Contains(string(exchange.Body), "Test message") {
+		t.Errorf("Expected request body in dump, got %s", exchange.Body)
+	}
+
+	responseData, err := os.ReadFile(responses[0])
+	if err != nil {
+		t.Fatalf("Failed to read response dump: %v", err)
+	}
+	if string(responseData) != rawResponse {
+		t.Errorf("Expected raw response body %q, got %q", rawResponse, responseData)
+	}
+}
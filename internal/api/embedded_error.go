@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// embeddedError is the {"error": {...}} object some gateways send with HTTP 200 instead of an
+// error status, either as the whole body or as an SSE chunk
+type embeddedError struct {
+	Error *struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"` // A string ("rate_limit_exceeded") or an HTTP status (429), by provider
+	} `json:"error"`
+}
+
+// errorInBody returns an APIError for an error object in a 200 response body or stream chunk,
+// or nil when the body carries none
+// Retryability follows the status the error stands for, so a rate limit or overloaded backend
+// is retried while an invalid request fails right away
+func (c *Client) errorInBody(body []byte) *APIError {
+	if !bytes.Contains(body, []byte(`"error"`)) {
+		return nil
+	}
+	var parsed embeddedError
+	if err := json.Unmarshal(body, &parsed); err != nil || parsed.Error == nil {
+		return nil
+	}
+
+	code := strings.Trim(string(parsed.Error.Code), `"`)
+	if code == "null" {
+		code = ""
+	}
+	message := parsed.Error.Message
+	if message == "" {
+		message = "unspecified error"
+	}
+	status := embeddedErrorStatus(parsed.Error.Type, code)
+	return &APIError{
+		Message:    message + " (error body returned with HTTP 200)",
+		StatusCode: status,
+		Type:       parsed.Error.Type,
+		Code:       code,
+		Retryable:  c.isStatusCodeRetryable(status),
+	}
+}
+
+// embeddedErrorStatus maps an error object's type and code to the HTTP status it stands for
+// A numeric code is taken as the status; unrecognized errors count as a bad request
+func embeddedErrorStatus(errType, code string) int {
+	if status, err := strconv.Atoi(code); err == nil && status >= 400 && status <= 599 {
+		return status
+	}
+
+	s := strings.ToLower(errType + " " + code)
+	switch {
+	case strings.Contains(s, "rate_limit") || strings.Contains(s, "too_many_requests"):
+		return http.StatusTooManyRequests
+	case strings.Contains(s, "auth") || strings.Contains(s, "api_key") || strings.Contains(s, "permission"):
+		return http.StatusUnauthorized
+	case strings.Contains(s, "timeout"):
+		return http.StatusGatewayTimeout
+	case strings.Contains(s, "overloaded") || strings.Contains(s, "unavailable"):
+		return http.StatusServiceUnavailable
+	case strings.Contains(s, "server_error") || strings.Contains(s, "internal"):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestErrorInBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int // 0 = no error detected
+		retryable  bool
+	}{
+		{"regular response", `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`, 0, false},
+		{"null error", `{"error":null,"choices":[]}`, 0, false},
+		{"content mentioning error", `{"choices":[{"message":{"content":"\"error\" is a word"}}]}`, 0, false},
+		{"rate limit type", `{"error":{"message":"slow down","type":"rate_limit_error"}}`, http.StatusTooManyRequests, true},
+		{"numeric code", `{"error":{"message":"upstream failed","code":502}}`, http.StatusBadGateway, true},
+		{"overloaded", `{"error":{"message":"busy","type":"overloaded_error"}}`, http.StatusServiceUnavailable, true},
+		{"invalid request", `{"error":{"message":"bad field","type":"invalid_request_error","code":"invalid_value"}}`, http.StatusBadRequest, false},
+		{"invalid key", `{"error":{"message":"no","code":"invalid_api_key"}}`, http.StatusUnauthorized, false},
+	}
+
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := client.errorInBody([]byte(tt.body))
+			if tt.wantStatus == 0 {
+				if apiErr != nil {
+					t.Errorf("Expected no error, got %v", apiErr)
+				}
+				return
+			}
+			if apiErr == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Retryable != tt.retryable {
+				t.Errorf("Expected status %d (retryable %v), got %d (retryable %v)",
+					tt.wantStatus, tt.retryable, apiErr.StatusCode, apiErr.Retryable)
+			}
+		})
+	}
+}
+
+func TestChatCompletion_ErrorBodyWith200(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		name := "non-streaming"
+		if streaming {
+			name = "streaming"
+		}
+		t.Run(name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body := `{"error":{"message":"Model is overloaded","type":"overloaded_error"}}`
+				if requests > 1 {
+					body = `{"error":{"message":"Unknown parameter","type":"invalid_request_error"}}`
+				}
+				if streaming {
+					w.Header().Set("Content-Type", "text/event-stream")
+					_, _ = io.WriteString(w, "data: "+body+"\n\n")
+					return
+				}
+				_, _ = io.WriteString(w, body)
+			}))
+			defer server.Close()
+
+			client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			client.SetRetryOptions(RetryOptions{MaxAttempts: 5, BaseDelay: time.Millisecond})
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 6000,
+				HTTPTimeoutSeconds: 5,
+			}
+			messages := []Message{{Role: "user", Content: "hi"}}
+
+			var err error
+			if streaming {
+				_, err = client.ChatCompletionStreaming(context.Background(), modelCfg, "test-key", messages)
+			} else {
+				_, err = client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+			}
+
+			// The overloaded error is retried; the invalid request is not
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Expected a 400 APIError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "Unknown parameter") {
+				t.Errorf("Expected the provider's message in the error, got %v", err)
+			}
+			if requests != 2 {
+				t.Errorf("Expected 2 requests (one retry), got %d", requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+)
+
+// chatCompletionsPath is the OpenAI-compatible chat endpoint relative to the API root
+const chatCompletionsPath = "chat/completions"
+
+// ChatEndpoint resolves the chat completions URL for a model
+// chatPath (models.<name>.chat_path) wins when set: a full URL is used as-is, a path is joined to base_url
+// Otherwise a base_url already ending in chat/completions is used as-is and anything else (a bare host,
+// /v1, /openai/v1) gets /chat/completions appended, as before chat_path existed; query strings are kept
+func ChatEndpoint(baseURL, chatPath string) string {
+	if strings.HasPrefix(chatPath, "http://") || strings.HasPrefix(chatPath, "https://") {
+		return chatPath
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		// Not a parseable absolute URL; keep the historical concatenation
+		return strings.TrimRight(baseURL, "/") + "/" + chatCompletionsPathOr(chatPath)
+	}
+
+	basePath := strings.TrimRight(u.Path, "/")
+	switch {
+	case chatPath != "":
+		u.Path = basePath + "/" + strings.TrimLeft(chatPath, "/")
+	case strings.HasSuffix(basePath, "/"+chatCompletionsPath):
+		u.Path = basePath
+	default:
+		u.Path = basePath + "/" + chatCompletionsPath
+	}
+	u.RawPath = ""
+	return u.String()
+}
+
+func chatCompletionsPathOr(chatPath string) string {
+	if chatPath != "" {
+		return strings.TrimLeft(chatPath, "/")
+	}
+	return chatCompletionsPath
+}
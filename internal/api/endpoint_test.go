@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChatEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		chatPath string
+		want     string
+	}{
+		{"openai", "https://api.openai.com/v1", "", "https://api.openai.com/v1/chat/completions"},
+		{"trailing slash", "https://api.openai.com/v1/", "", "https://api.openai.com/v1/chat/completions"},
+		{"together", "https://api.together.xyz/v1", "", "https://api.together.xyz/v1/chat/completions"},
+		{"nested path", "https://api.groq.com/openai/v1", "", "https://api.groq.com/openai/v1/chat/completions"},
+		{"local vllm", "http://localhost:8000/v1", "", "http://localhost:8000/v1/chat/completions"},
+		{"bare host keeps legacy path", "http://localhost:8000", "", "http://localhost:8000/chat/completions"},
+		{"bare host with slash", "https://api.openai.com/", "", "https://api.openai.com/chat/completions"},
+		{"full endpoint", "https://api.openai.com/v1/chat/completions", "", "https://api.openai.com/v1/chat/completions"},
+		{"full endpoint with slash", "http://localhost:8000/v1/chat/completions/", "", "http://localhost:8000/v1/chat/completions"},
+		{"query kept", "https://example.openai.azure.com/openai/deployments/gpt?api-version=2024-06-01", "",
+			"https://example.openai.azure.com/openai/deployments/gpt/chat/completions?api-version=2024-06-01"},
+		{"chat_path joined", "http://localhost:8080", "/chat/completions", "http://localhost:8080/chat/completions"},
+		{"chat_path relative", "https://example.com/api", "v2/chat", "https://example.com/api/v2/chat"},
+		{"chat_path full url", "https://api.openai.com/v1", "https://proxy.local/llm/chat", "https://proxy.local/llm/chat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChatEndpoint(tt.baseURL, tt.chatPath); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChatCompletion_ResolvedPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		suffix   string
+		chatPath string
+		want     string
+	}{
+		{"v1 base", "/v1", "", "/v1/chat/completions"},
+		{"bare host", "", "", "/chat/completions"},
+		{"chat_path override", "", "/api/chat", "/api/chat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			modelCfg := tlsTestModel(server.URL + tt.suffix)
+			modelCfg.ChatPath = tt.chatPath
+			if _, err := client.ChatCompletion(context.Background(), modelCfg, "", []Message{{Role: "user", Content: "hi"}}); err != nil {
+				t.Fatalf("ChatCompletion returned unexpected error: %v", err)
+			}
+			if gotPath != tt.want {
+				t.Errorf("Expected request to %s, got %s", tt.want, gotPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Error classes used for per-type failure statistics
+const (
+	ErrorClassRateLimit     = "rate_limit"
+	ErrorClassTimeout       = "timeout"
+	ErrorClassServerError   = "server_error"
+	ErrorClassAuth          = "auth"
+	ErrorClassClientError   = "client_error"
+	ErrorClassParseFailure  = "parse_failure"
+	ErrorClassEmptyContent  = "empty_content"
+	ErrorClassRefusal       = "refusal"
+	ErrorClassIncomplete    = "incomplete"
+	ErrorClassInvalidRecord = "invalid_record"
+	ErrorClassIdenticalPair = "identical_pair"
+	ErrorClassPromptLength  = "prompt_too_long"
+	ErrorClassResponseSize  = "response_too_large"
+	ErrorClassOther         = "other"
+)
+
+// ErrInvalidRecord is wrapped by every record validation error so callers can count
+// malformed rows separately from API and I/O failures
+var ErrInvalidRecord = errors.New("invalid record")
+
+// ErrIdenticalPair is wrapped when a job is dropped because its rejected response matches chosen
+var ErrIdenticalPair = errors.New("identical pair")
+
+// ClassifyError maps an API or job error to one of the ErrorClass* constants
+// Sentinel errors and APIErrors are matched first; other errors fall back to message matching
+// so wrapped job errors (refusals, parse failures, etc.) are still categorized
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, ErrInvalidRecord):
+		return ErrorClassInvalidRecord
+	case errors.Is(err, ErrIdenticalPair):
+		return ErrorClassIdenticalPair
+	case errors.Is(err, ErrPromptTooLong):
+		return ErrorClassPromptLength
+	}
+
+	var tooLarge *ResponseTooLargeError
+	if errors.As(err, &tooLarge) {
+		return ErrorClassResponseSize
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return ErrorClassRateLimit
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+			return ErrorClassAuth
+		case apiErr.StatusCode == http.StatusRequestTimeout || apiErr.StatusCode == http.StatusGatewayTimeout:
+			return ErrorClassTimeout
+		case apiErr.StatusCode >= 500:
+			return ErrorClassServerError
+		case apiErr.StatusCode >= 400:
+			return ErrorClassClientError
+		}
+		// StatusCode 0: transport-level failure, classify by message below
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "deadline exceeded") || strings.Contains(msg, "timeout"):
+		return ErrorClassTimeout
+	case strings.Contains(msg, "refusal"):
+		return ErrorClassRefusal
+	case strings.Contains(msg, "empty content") || strings.Contains(msg, "empty response") || strings.Contains(msg, "no choices"):
+		return ErrorClassEmptyContent
+	case strings.Contains(msg, "incomplete output") || strings.Contains(msg, "invalid completion") || strings.Contains(msg, "token exhaustion"):
+		return ErrorClassIncomplete
+	case strings.Contains(msg, "parse") || strings.Contains(msg, "unmarshal") || strings.Contains(msg, "json"):
+		return ErrorClassParseFailure
+	}
+
+	return ErrorClassOther
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"nil", nil, ""},
+		{"rate limit", &APIError{StatusCode: 429, Message: "slow down"}, ErrorClassRateLimit},
+		{"wrapped rate limit", fmt.Errorf("max retries exceeded: %w", &APIError{StatusCode: 429}), ErrorClassRateLimit},
+		{"unauthorized", &APIError{StatusCode: 401, Message: "bad key"}, ErrorClassAuth},
+		{"forbidden", &APIError{StatusCode: 403}, ErrorClassAuth},
+		{"server error", &APIError{StatusCode: 502}, ErrorClassServerError},
+		{"gateway timeout", &APIError{StatusCode: 504}, ErrorClassTimeout},
+		{"bad request", &APIError{StatusCode: 400, Message: "invalid model"}, ErrorClassClientError},
+		{"transport timeout", &APIError{Message: "request failed: context deadline exceeded"}, ErrorClassTimeout},
+		{"response too large", fmt.Errorf("failed to generate chosen response: %w", &ResponseTooLargeError{Model: "m", Limit: 10}), ErrorClassResponseSize},
+		{"context deadline", fmt.Errorf("failed to generate chosen response: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{"refusal", errors.New("chosen response contains refusal: as an ai"), ErrorClassRefusal},
+		{"empty content", errors.New("empty content returned from rejected model"), ErrorClassEmptyContent},
+		{"incomplete", errors.New("incomplete output detected: truncated mid-sentence"), ErrorClassIncomplete},
+		{"token exhaustion", errors.New("token exhaustion: model consumed 100 reasoning tokens"), ErrorClassIncomplete},
+		{"parse failure", errors.New("failed to parse judge response: bad input"), ErrorClassParseFailure},
+		{"invalid record", fmt.Errorf("failed to write KTO rejected record: %w: KTO record has empty completion", ErrInvalidRecord), ErrorClassInvalidRecord},
+		{"identical pair", fmt.Errorf("%w: rejected response matches chosen", ErrIdenticalPair), ErrorClassIdenticalPair},
+		{"invalid record text without sentinel", errors.New("model said: invalid record"), ErrorClassOther},
+		{"identical pair text without sentinel", errors.New("judge noted an identical pair"), ErrorClassOther},
+		{"prompt too long text without sentinel", errors.New("upstream: prompt too long"), ErrorClassOther},
+		{"prompt too long", fmt.Errorf("chosen generation failed: %w", ErrPromptTooLong), ErrorClassPromptLength},
+		{"other", errors.New("something unexpected"), ErrorClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// protectedRequestKeys are never replaced by extra_body, even with extra_body_override
+var protectedRequestKeys = map[string]bool{"model": true, "messages": true, "stream": true}
+
+// requestBody encodes a chat request as a map with the model's extra_body merged in, then
+// renames and drops fields per field_map and field_omit
+func (c *Client) requestBody(req ChatCompletionRequest) (map[string]interface{}, error) {
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	body := make(map[string]interface{})
+	if err := json.Unmarshal(reqBytes, &body); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal to map: %w", err)
+	}
+	c.applyExtraBody(body, req)
+	applyFieldMap(body, req)
+	return body, nil
+}
+
+// applyFieldMap drops req.FieldOmit keys and renames req.FieldMap keys in a request body map
+// Renames are applied together, so two fields can swap names
+func applyFieldMap(body map[string]interface{}, req ChatCompletionRequest) {
+	for _, key := range req.FieldOmit {
+		delete(body, key)
+	}
+	renamed := make(map[string]interface{}, len(req.FieldMap))
+	for from, to := range req.FieldMap {
+		if value, ok := body[from]; ok {
+			renamed[to] = value
+			delete(body, from)
+		}
+	}
+	for key, value := range renamed {
+		body[key] = value
+	}
+}
+
+// applyExtraBody merges req.ExtraBody into a request body map
+// Keys already set by the core request fields are kept unless ExtraBodyOverride is set;
+// model, messages, and stream are always kept. Ignored keys are warned about once per model
+func (c *Client) applyExtraBody(body map[string]interface{}, req ChatCompletionRequest) {
+	var ignored []string
+	for key, value := range req.ExtraBody {
+		if _, exists := body[key]; protectedRequestKeys[key] || (exists && !req.ExtraBodyOverride) {
+			ignored = append(ignored, key)
+			continue
+		}
+		body[key] = value
+	}
+
+	if len(ignored) > 0 {
+		if _, warned := c.extraBodyWarned.LoadOrStore(req.Model, true); !warned {
+			sort.Strings(ignored)
+			c.logger.Warn("extra_body keys conflict with core request fields and were ignored (set extra_body_override to replace them)",
+				"model", req.Model,
+				"keys", ignored)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestApplyExtraBody(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		override bool
+		want     map[string]interface{}
+	}{
+		{
+			name:     "core fields kept",
+			override: false,
+			want:     map[string]interface{}{"model": "test-model", "temperature": 0.7, "top_k": 40, "stream": true},
+		},
+		{
+			name:     "override replaces core fields except protected",
+			override: true,
+			want:     map[string]interface{}{"model": "test-model", "temperature": 1.2, "top_k": 40, "stream": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(logger)
+			body := map[string]interface{}{"model": "test-model", "temperature": 0.7, "stream": true}
+			client.applyExtraBody(body, ChatCompletionRequest{
+				Model: "test-model",
+				ExtraBody: map[string]interface{}{
+					"top_k":       40,
+					"temperature": 1.2,
+					"model":       "other-model",
+					"stream":      false,
+				},
+				ExtraBodyOverride: tt.override,
+			})
+
+			for key, want := range tt.want {
+				if body[key] != want {
+					t.Errorf("Expected %s = %v, got %v", key, want, body[key])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFieldMap(t *testing.T) {
+	body := map[string]interface{}{"model": "test-model", "temperature": 0.7, "top_p": 0.9, "max_tokens": 100, "n": 1}
+	applyFieldMap(body, ChatCompletionRequest{
+		FieldMap:  map[string]string{"model": "model_id", "temperature": "top_p", "top_p": "temperature", "seed": "random_seed"},
+		FieldOmit: []string{"n"},
+	})
+
+	want := map[string]interface{}{"model_id": "test-model", "temperature": 0.9, "top_p": 0.7, "max_tokens": 100}
+	if len(body) != len(want) {
+		t.Errorf("Expected %d fields, got %v", len(want), body)
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("Expected %s = %v, got %v", key, value, body[key])
+		}
+	}
+}
+
+func TestChatCompletion_ExtraBody(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		name := "non-streaming"
+		if streaming {
+			name = "streaming"
+		}
+		t.Run(name, func(t *testing.T) {
+			var received map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("Failed to decode request body: %v", err)
+				}
+				if streaming {
+					w.Header().Set("Content-Type", "text/event-stream")
+					_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+					return
+				}
+				_, _ = io.WriteString(w, `{"id":"1","model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+			}))
+			defer server.Close()
+
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			client := NewClient(logger)
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				Temperature:        0.7,
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 600,
+				HTTPTimeoutSeconds: 5,
+				ExtraBody: map[string]interface{}{
+					"top_k":              int64(40),
+					"repetition_penalty": 1.05,
+					"nvext":              map[string]interface{}{"guided_json": "{}"},
+					"temperature":        1.5,
+				},
+			}
+			messages := []Message{{Role: "user", Content: "hi"}}
+
+			var err error
+			if streaming {
+				_, err = client.ChatCompletionStreaming(context.Background(), modelCfg, "test-key", messages)
+			} else {
+				_, err = client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if received["top_k"] != float64(40) {
+				t.Errorf("Expected top_k 40, got %v", received["top_k"])
+			}
+			if received["repetition_penalty"] != 1.05 {
+				t.Errorf("Expected repetition_penalty 1.05, got %v", received["repetition_penalty"])
+			}
+			if nvext, ok := received["nvext"].(map[string]interface{}); !ok || nvext["guided_json"] != "{}" {
+				t.Errorf("Expected nested nvext to pass through, got %v", received["nvext"])
+			}
+			if received["temperature"] != 0.7 {
+				t.Errorf("Expected core temperature 0.7 to be kept, got %v", received["temperature"])
+			}
+			if streaming && received["stream"] != true {
+				t.Errorf("Expected stream=true, got %v", received["stream"])
+			}
+		})
+	}
+}
+
+func TestChatCompletion_FieldMap(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		name := "non-streaming"
+		if streaming {
+			name = "streaming"
+		}
+		t.Run(name, func(t *testing.T) {
+			var received map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("Failed to decode request body: %v", err)
+				}
+				if streaming {
+					w.Header().Set("Content-Type", "text/event-stream")
+					_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+					return
+				}
+				_, _ = io.WriteString(w, `{"id":"1","model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+			}))
+			defer server.Close()
+
+			client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				Temperature:        0.7,
+				TopP:               0.9,
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 600,
+				HTTPTimeoutSeconds: 5,
+				FieldMap:           map[string]string{"model": "model_id", "max_tokens": "max_completion_tokens"},
+				FieldOmit:          []string{"top_p"},
+			}
+			messages := []Message{{Role: "user", Content: "hi"}}
+
+			var err error
+			if streaming {
+				_, err = client.ChatCompletionStreaming(context.Background(), modelCfg, "test-key", messages)
+			} else {
+				_, err = client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if received["model_id"] != "test-model" {
+				t.Errorf("Expected model_id test-model, got %v", received["model_id"])
+			}
+			if received["max_completion_tokens"] != float64(100) {
+				t.Errorf("Expected max_completion_tokens 100, got %v", received["max_completion_tokens"])
+			}
+			for _, key := range []string{"model", "max_tokens", "top_p"} {
+				if _, ok := received[key]; ok {
+					t.Errorf("Expected %s to be absent, got %v", key, received[key])
+				}
+			}
+			if streaming && received["stream"] != true {
+				t.Errorf("Expected stream=true, got %v", received["stream"])
+			}
+		})
+	}
+}
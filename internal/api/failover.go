@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// Failover switches a model role to its fallback (models.<role>_fallback) once the primary has
+// failed generation.fallback_after_failures requests in a row; the switch lasts for the rest of the run
+// A nil *Failover has no fallback and always selects the primary. Safe for concurrent use
+type Failover struct {
+	mu          sync.Mutex
+	role        string
+	fallback    config.ModelConfig
+	threshold   int
+	consecutive int
+	active      bool
+	logger      *slog.Logger
+}
+
+// NewFailover creates a failover from role's primary model to fallback
+func NewFailover(role string, fallback config.ModelConfig, threshold int, logger *slog.Logger) *Failover {
+	return &Failover{
+		role:      role,
+		fallback:  fallback,
+		threshold: max(threshold, 1),
+		logger:    logger,
+	}
+}
+
+// Select returns the model for the next request: primary, or the fallback once the role has
+// failed over; usingFallback reports which one was picked and is passed back to Record
+func (f *Failover) Select(primary config.ModelConfig) (model config.ModelConfig, usingFallback bool) {
+	if f == nil {
+		return primary, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.active {
+		return f.fallback, true
+	}
+	return primary, false
+}
+
+// Record counts the outcome of a request made with the model Select returned
+// Only failures of the primary count towards the switch; cancelled requests are ignored
+func (f *Failover) Record(usingFallback bool, err error) {
+	if f == nil || usingFallback || errors.Is(err, context.Canceled) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		f.consecutive = 0
+		return
+	}
+	f.consecutive++
+	if f.active || f.consecutive < f.threshold {
+		return
+	}
+	f.active = true
+	f.logger.Warn("Primary model keeps failing, switching to fallback for the rest of the run",
+		"role", f.role,
+		"fallback_model", f.fallback.ModelName,
+		"consecutive_failures", f.consecutive,
+		"error", err)
+}
+
+// Active reports whether the role has switched to its fallback
+func (f *Failover) Active() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.active
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestFailover_SwitchesAfterConsecutiveFailures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	primary := config.ModelConfig{ModelName: "primary"}
+	f := NewFailover("main", config.ModelConfig{ModelName: "fallback"}, 3, logger)
+	failure := errors.New("503 service unavailable")
+
+	// A success resets the streak
+	f.Record(false, failure)
+	f.Record(false, failure)
+	f.Record(false, nil)
+	f.Record(false, failure)
+	f.Record(false, failure)
+	if model, onFallback := f.Select(primary); onFallback || model.ModelName != "primary" {
+		t.Fatalf("Expected primary before 3 consecutive failures, got %s (fallback=%v)", model.ModelName, onFallback)
+	}
+
+	// Cancelled requests don't count
+	f.Record(false, context.Canceled)
+	if f.Active() {
+		t.Fatal("Expected a cancelled request not to trigger the failover")
+	}
+
+	f.Record(false, failure)
+	model, onFallback := f.Select(primary)
+	if !onFallback || model.ModelName != "fallback" {
+		t.Fatalf("Expected fallback after 3 consecutive failures, got %s (fallback=%v)", model.ModelName, onFallback)
+	}
+
+	// The switch is permanent, even after the fallback succeeds
+	f.Record(true, nil)
+	if !f.Active() {
+		t.Error("Expected failover to stay active")
+	}
+}
+
+func TestFailover_NilSelectsPrimary(t *testing.T) {
+	var f *Failover
+	f.Record(false, errors.New("failed"))
+	if model, onFallback := f.Select(config.ModelConfig{ModelName: "primary"}); onFallback || model.ModelName != "primary" {
+		t.Errorf("Expected nil failover to select the primary, got %s (fallback=%v)", model.ModelName, onFallback)
+	}
+	if f.Active() {
+		t.Error("Expected nil failover to be inactive")
+	}
+}
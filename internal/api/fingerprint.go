@@ -0,0 +1,95 @@
+package api
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// FingerprintChangeFunc is called when a model returns a system_fingerprint it has not returned before
+type FingerprintChangeFunc func(model, previous, current string)
+
+// fingerprintTracker records the system_fingerprint values each model returned, in the order first seen
+type fingerprintTracker struct {
+	mu       sync.Mutex
+	seen     map[string][]string
+	onChange FingerprintChangeFunc
+}
+
+// observe records fp for model and reports whether it is a new value after an earlier one
+func (t *fingerprintTracker) observe(model, fp string) (previous string, changed bool) {
+	if fp == "" {
+		return "", false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	known := t.seen[model]
+	if slices.Contains(known, fp) {
+		return "", false
+	}
+	if t.seen == nil {
+		t.seen = make(map[string][]string)
+	}
+	t.seen[model] = append(known, fp)
+	if len(known) == 0 {
+		return "", false
+	}
+	return known[len(known)-1], true
+}
+
+// observeFingerprint records a response's system_fingerprint and warns when it changes mid-run
+// Backends without fingerprints (most non-OpenAI providers) leave it empty and are ignored
+func (c *Client) observeFingerprint(model string, resp *ChatCompletionResponse) {
+	if resp == nil {
+		return
+	}
+	previous, changed := c.fingerprints.observe(model, resp.SystemFingerprint)
+	if !changed {
+		return
+	}
+
+	c.logger.Warn("System fingerprint changed mid-run; the backend configuration behind this model changed",
+		slog.String("model", model),
+		slog.String("previous", previous),
+		slog.String("current", resp.SystemFingerprint))
+
+	c.fingerprints.mu.Lock()
+	onChange := c.fingerprints.onChange
+	c.fingerprints.mu.Unlock()
+	if onChange != nil {
+		onChange(model, previous, resp.SystemFingerprint)
+	}
+}
+
+// SetFingerprintChangeHandler sets a callback for system_fingerprint changes (nil = warn only)
+func (c *Client) SetFingerprintChangeHandler(fn FingerprintChangeFunc) {
+	c.fingerprints.mu.Lock()
+	defer c.fingerprints.mu.Unlock()
+	c.fingerprints.onChange = fn
+}
+
+// SystemFingerprints returns the system_fingerprint values observed per model, in the order first seen
+// Models that never returned a fingerprint are omitted; nil when none did
+func (c *Client) SystemFingerprints() map[string][]string {
+	c.fingerprints.mu.Lock()
+	defer c.fingerprints.mu.Unlock()
+	if len(c.fingerprints.seen) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(c.fingerprints.seen))
+	for model, fps := range c.fingerprints.seen {
+		out[model] = slices.Clone(fps)
+	}
+	return out
+}
+
+// RestoreSystemFingerprints seeds the fingerprints observed by an earlier run (resume),
+// so a fingerprint that differs from the interrupted run's is still reported as a change
+func (c *Client) RestoreSystemFingerprints(seen map[string][]string) {
+	for model, fps := range seen {
+		for _, fp := range fps {
+			c.fingerprints.observe(model, fp)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestObserveFingerprint(t *testing.T) {
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var changes []string
+	client.SetFingerprintChangeHandler(func(model, previous, current string) {
+		changes = append(changes, model+":"+previous+"->"+current)
+	})
+
+	for _, fp := range []string{"", "fp_a", "fp_a", "fp_b", "fp_a", ""} {
+		client.observeFingerprint("gpt", &ChatCompletionResponse{SystemFingerprint: fp})
+	}
+	client.observeFingerprint("other", &ChatCompletionResponse{SystemFingerprint: "fp_x"})
+
+	// Returning to an already seen fingerprint (load balanced backends) is not a new change
+	if len(changes) != 1 || changes[0] != "gpt:fp_a->fp_b" {
+		t.Errorf("Expected one change gpt:fp_a->fp_b, got %v", changes)
+	}
+
+	got := client.SystemFingerprints()
+	if len(got) != 2 || len(got["gpt"]) != 2 || got["gpt"][0] != "fp_a" || got["gpt"][1] != "fp_b" {
+		t.Errorf("Expected gpt fingerprints [fp_a fp_b], got %v", got)
+	}
+	if len(got["other"]) != 1 {
+		t.Errorf("Expected one fingerprint for other, got %v", got["other"])
+	}
+}
+
+func TestRestoreSystemFingerprints(t *testing.T) {
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if got := client.SystemFingerprints(); got != nil {
+		t.Errorf("Expected no fingerprints before any response, got %v", got)
+	}
+
+	changed := 0
+	client.SetFingerprintChangeHandler(func(string, string, string) { changed++ })
+	client.RestoreSystemFingerprints(map[string][]string{"gpt": {"fp_a", "fp_b"}})
+	if changed != 0 {
+		t.Errorf("Expected restoring fingerprints not to report changes, got %d", changed)
+	}
+
+	client.observeFingerprint("gpt", &ChatCompletionResponse{SystemFingerprint: "fp_b"})
+	if changed != 0 {
+		t.Errorf("Expected a restored fingerprint not to count as a change, got %d", changed)
+	}
+	client.observeFingerprint("gpt", &ChatCompletionResponse{SystemFingerprint: "fp_c"})
+	if changed != 1 {
+		t.Errorf("Expected a new fingerprint after resume to count as a change, got %d", changed)
+	}
+}
+
+func TestChatCompletion_CapturesSystemFingerprint(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		name := "non-streaming"
+		if streaming {
+			name = "streaming"
+		}
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if streaming {
+					w.Header().Set("Content-Type", "text/event-stream")
+					_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"o\"}}]}\n\n"+
+						"data: {\"id\":\"1\",\"model\":\"test-model\",\"system_fingerprint\":\"fp_123\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"k\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+					return
+				}
+				_, _ = io.WriteString(w, `{"id":"1","model":"test-model","system_fingerprint":"fp_123","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+			}))
+			defer server.Close()
+
+			client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				Temperature:        0.7,
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 600,
+				HTTPTimeoutSeconds: 5,
+			}
+			messages := []Message{{Role: "user", Content: "hi"}}
+
+			var resp *ChatCompletionResponse
+			var err error
+			if streaming {
+				resp, err = client.ChatCompletionStreaming(context.Background(), modelCfg, "test-key", messages)
+			} else {
+				resp, err = client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp.SystemFingerprint != "fp_123" {
+				t.Errorf("Expected system_fingerprint fp_123, got %q", resp.SystemFingerprint)
+			}
+			if got := client.SystemFingerprints()["test-model"]; len(got) != 1 || got[0] != "fp_123" {
+				t.Errorf("Expected fp_123 recorded for test-model, got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// stubRotator hands out the next key after a 429 and records which keys were rate-limited
+type stubRotator struct {
+	next    map[string]string
+	limited []string
+}
+
+func (r *stubRotator) RateLimited(key string) string {
+	r.limited = append(r.limited, key)
+	if next, ok := r.next[key]; ok {
+		return next
+	}
+	return key
+}
+
+func TestChatCompletion_RotatesKeyOnRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Authorization"))
+		mu.Unlock()
+		if r.Header.Get("Authorization") == "Bearer key-a" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error": {"message": "rate limited"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := NewClient(logger)
+	client.baseRetryDelay = time.Millisecond
+	rotator := &stubRotator{next: map[string]string{"key-a": "key-b"}}
+	client.SetKeyRotator(rotator)
+
+	modelCfg := config.ModelConfig{
+		BaseURL:            server.URL,
+		ModelName:          "test-model",
+		RateLimitPerMinute: 1000,
+		MaxRetries:         2,
+	}
+	resp, err := client.ChatCompletion(context.Background(), modelCfg, "key-a", []Message{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Expected success after rotating key, got %v", err)
+	}
+	if resp.Choices[0].Message.Content != "ok" {
+		t.Errorf("Expected 'ok', got %q", resp.Choices[0].Message.Content)
+	}
+	if want := []string{"Bearer key-a", "Bearer key-b"}; !slices.Equal(seen, want) {
+		t.Errorf("Expected requests with %v, got %v", want, seen)
+	}
+	if !slices.Equal(rotator.limited, []string{"key-a"}) {
+		t.Errorf("Expected key-a reported as rate-limited, got %v", rotator.limited)
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// modelsPath is the OpenAI-compatible model list endpoint relative to the API root
+const modelsPath = "models"
+
+// modelInfoTimeout bounds a model list request at startup
+const modelInfoTimeout = 30 * time.Second
+
+// maxModelListBytes caps the model list body (OpenRouter lists hundreds of models)
+const maxModelListBytes = 8 << 20
+
+// maxModelErrorBytes caps how much of a failed response is quoted in the error
+const maxModelErrorBytes = 512
+
+type modelList struct {
+	Data []modelInfo `json:"data"`
+}
+
+// modelInfo is a /models entry; providers report the context window under different names
+type modelInfo struct {
+	ID            string `json:"id"`
+	MaxModelLen   int    `json:"max_model_len"`  // vLLM
+	ContextLength int    `json:"context_length"` // OpenRouter, LM Studio
+	ContextWindow int    `json:"context_window"` // Groq
+}
+
+func (m modelInfo) contextSize() int {
+	switch {
+	case m.MaxModelLen > 0:
+		return m.MaxModelLen
+	case m.ContextLength > 0:
+		return m.ContextLength
+	default:
+		return m.ContextWindow
+	}
+}
+
+// ModelsEndpoint resolves the model list URL for a base_url the way chat endpoints are resolved:
+// a bare host gets /v1, a URL ending in chat/completions is cut back to the API root, and
+// /models is appended; query strings are kept
+func ModelsEndpoint(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return strings.TrimRight(baseURL, "/") + "/" + modelsPath
+	}
+
+	basePath := strings.TrimRight(u.Path, "/")
+	basePath = strings.TrimSuffix(basePath, "/"+chatCompletionsPath)
+	if basePath == "" {
+		basePath = "/v1"
+	}
+	u.Path = basePath + "/" + modelsPath
+	u.RawPath = ""
+	return u.String()
+}
+
+// DetectContextSizes fills in context_size from the provider's model list for every model that
+// left it unset (generation.autodetect_context), updating modelCfgs in place
+// A failed lookup keeps the default with a warning; a detected size below max_output_tokens is
+// an error, since every request would overflow the context
+func (c *Client) DetectContextSizes(ctx context.Context, modelCfgs map[string]config.ModelConfig, apiKey func(baseURL string) string) error {
+	lists := make(map[string][]modelInfo) // Model lists by endpoint, fetched once
+
+	for _, name := range slices.Sorted(maps.Keys(modelCfgs)) {
+		mc := modelCfgs[name]
+		if !mc.ContextSizeUnset || (name == "judge" && !mc.Enabled) {
+			continue
+		}
+
+		endpoint := ModelsEndpoint(mc.BaseURL)
+		list, ok := lists[endpoint]
+		if !ok {
+			var err error
+			list, err = c.listModels(ctx, mc, apiKey(mc.BaseURL), endpoint)
+			if err != nil {
+				c.logger.Warn("Context size detection failed, keeping the default",
+					"model", name,
+					"endpoint", endpoint,
+					"context_size", mc.ContextSize,
+					"error", err)
+				continue
+			}
+			lists[endpoint] = list
+		}
+
+		size := detectedContextSize(list, mc.ModelName)
+		if size == 0 {
+			c.logger.Warn("Provider did not report a context size, keeping the default",
+				"model", name,
+				"model_name", mc.ModelName,
+				"context_size", mc.ContextSize)
+			continue
+		}
+		if mc.MaxOutputTokens > size {
+			return fmt.Errorf("models.%s.max_output_tokens (%d) exceeds the detected context size of %s (%d)",
+				name, mc.MaxOutputTokens, mc.ModelName, size)
+		}
+
+		c.logger.Info("Detected context size", "model", name, "model_name", mc.ModelName, "context_size", size)
+		mc.ContextSize = size
+		mc.ContextSizeUnset = false
+		modelCfgs[name] = mc
+	}
+	return nil
+}
+
+// detectedContextSize returns the context size listed for modelName (0 = not reported)
+// A server listing a single model (vLLM, llama.cpp) is matched regardless of its id, since
+// those often serve under a path or alias that differs from model_name
+func detectedContextSize(list []modelInfo, modelName string) int {
+	for _, m := range list {
+		if m.ID == modelName {
+			return m.contextSize()
+		}
+	}
+	if len(list) == 1 {
+		return list[0].contextSize()
+	}
+	return 0
+}
+
+// listModels fetches the model list of a provider
+func (c *Client) listModels(ctx context.Context, modelCfg config.ModelConfig, apiKey, endpoint string) ([]modelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, modelInfoTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgentFor(modelCfg))
+	if apiKey != "" {
+		setAuthHeader(req.Header, modelCfg, apiKey)
+	}
+
+	resp, err := c.httpClientFor(modelCfg.BaseURL).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxModelListBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), maxModelErrorBytes)])))
+	}
+
+	var list modelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+	return list.Data, nil
+}
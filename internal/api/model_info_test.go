@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestModelsEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+	}{
+		{"openai", "https://api.openai.com/v1", "https://api.openai.com/v1/models"},
+		{"trailing slash", "https://api.openai.com/v1/", "https://api.openai.com/v1/models"},
+		{"bare host gets v1", "http://localhost:8000", "http://localhost:8000/v1/models"},
+		{"full chat endpoint", "http://localhost:8000/v1/chat/completions", "http://localhost:8000/v1/models"},
+		{"nested path", "https://api.groq.com/openai/v1", "https://api.groq.com/openai/v1/models"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ModelsEndpoint(tt.baseURL); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectContextSizes(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": [
+			{"id": "vllm-model", "max_model_len": 32768},
+			{"id": "router-model", "context_length": 131072},
+			{"id": "small-model", "context_window": 2048},
+			{"id": "unknown-model"}
+		]}`))
+	}))
+	defer server.Close()
+
+	base := config.ModelConfig{BaseURL: server.URL + "/v1", MaxOutputTokens: 4096, ContextSize: 16384, ContextSizeUnset: true}
+	model := func(name string) config.ModelConfig {
+		mc := base
+		mc.ModelName = name
+		return mc
+	}
+	explicit := model("vllm-model")
+	explicit.ContextSize = 8192
+	explicit.ContextSizeUnset = false
+	judgeModel := model("unknown-model")
+	judgeModel.Enabled = true
+
+	modelCfgs := map[string]config.ModelConfig{
+		"main":     model("vllm-model"),
+		"rejected": model("router-model"),
+		"judge":    judgeModel,
+		"explicit": explicit,
+	}
+
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	err := client.DetectContextSizes(context.Background(), modelCfgs, func(string) string { return "key" })
+	if err != nil {
+		t.Fatalf("DetectContextSizes returned unexpected error: %v", err)
+	}
+
+	want := map[string]int{"main": 32768, "rejected": 131072, "judge": 16384, "explicit": 8192}
+	for name, size := range want {
+		if got := modelCfgs[name].ContextSize; got != size {
+			t.Errorf("%s: expected context_size %d, got %d", name, size, got)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected the model list to be fetched once per endpoint, got %d requests", requests)
+	}
+
+	// A detected window smaller than max_output_tokens is an error
+	tooSmall := map[string]config.ModelConfig{"main": model("small-model")}
+	err = client.DetectContextSizes(context.Background(), tooSmall, func(string) string { return "key" })
+	if err == nil || !strings.Contains(err.Error(), "exceeds the detected context size") {
+		t.Errorf("Expected a max_output_tokens error, got %v", err)
+	}
+}
+
+func TestDetectContextSizesKeepsDefaultOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	modelCfgs := map[string]config.ModelConfig{"main": {
+		BaseURL: server.URL, ModelName: "m", MaxOutputTokens: 4096, ContextSize: 16384, ContextSizeUnset: true,
+	}}
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := client.DetectContextSizes(context.Background(), modelCfgs, func(string) string { return "" }); err != nil {
+		t.Fatalf("Expected a failed lookup to fall back, got error: %v", err)
+	}
+	if got := modelCfgs["main"].ContextSize; got != 16384 {
+		t.Errorf("Expected the default context_size to be kept, got %d", got)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// maxErrorSnippetBytes caps how much of a failed PostJSON response is quoted in the error
+const maxErrorSnippetBytes = 512
+
+// PostJSON sends body as a JSON POST to an endpoint outside the chat protocol (e.g. a reward model)
+// Requests use the client's transport, so [network] TLS settings apply, and network errors and
+// retryable statuses are retried with the client's backoff. maxRetries 0 uses the client default
+// (-1 = unlimited); timeout bounds each attempt (0 = none). Returns the body of the 200 response
+func (c *Client) PostJSON(ctx context.Context, endpoint, apiKey string, body []byte, maxRetries int, timeout time.Duration) ([]byte, error) {
+	if maxRetries == 0 {
+		maxRetries = c.maxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; maxRetries < 0 || attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			sleepDuration := c.retryBackoff(attempt, lastErr, false, config.ModelConfig{})
+			c.logger.Warn("Retrying request",
+				"attempt", attempt,
+				"max_retries", maxRetries,
+				"backoff", sleepDuration,
+				"endpoint", endpoint,
+				"error", lastErr)
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(sleepDuration):
+			}
+		}
+
+		attemptCtx, attemptCancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, attemptCancel = context.WithTimeout(ctx, timeout)
+		}
+		respBody, err := c.postJSONOnce(attemptCtx, endpoint, apiKey, body)
+		attemptCancel()
+		if err == nil {
+			return respBody, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		lastErr = err
+		if !c.isRetryable(err) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
+}
+
+// postJSONOnce makes a single PostJSON attempt
+func (c *Client) postJSONOnce(ctx context.Context, endpoint, apiKey string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := c.httpClientFor(endpoint).Do(req)
+	if err != nil {
+		return nil, &APIError{Message: fmt.Sprintf("request failed: %v", err), Retryable: true}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorSnippetBytes))
+		return nil, &APIError{
+			Message:    fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet))),
+			StatusCode: resp.StatusCode,
+			Retryable:  c.isStatusCodeRetryable(resp.StatusCode),
+		}
+	}
+
+	respBody, err := io.ReadAll(limitResponseBody(resp.Body, DefaultMaxResponseBytes, endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return respBody, nil
+}
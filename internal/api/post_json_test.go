@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostJSON_RetriesRetryableStatus(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Expected bearer auth, got %q", got)
+		}
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.SetRetryOptions(RetryOptions{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	got, err := client.PostJSON(context.Background(), server.URL+"/score", "key", []byte(`{"ok":true}`), 0, time.Second)
+	if err != nil {
+		t.Fatalf("PostJSON returned unexpected error: %v", err)
+	}
+	if string(got) != `{"ok":true}` || requests != 2 {
+		t.Errorf("Expected the echoed body after 2 requests, got %q after %d", got, requests)
+	}
+}
+
+func TestPostJSON_DoesNotRetryClientErrors(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, "bad input")
+	}))
+	defer server.Close()
+
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.SetRetryOptions(RetryOptions{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	_, err := client.PostJSON(context.Background(), server.URL, "", []byte(`{}`), 0, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "status 400: bad input") {
+		t.Errorf("Expected a status 400 error, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected no retries for a 400, got %d requests", requests)
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+const (
+	// probeMaxTokens caps probe completions (reasoning models need room to think before answering)
+	probeMaxTokens = 512
+	// probeSeed is the seed sent by the seed probe and recommended in the config snippet
+	probeSeed = 42
+)
+
+// ProbeCheck is the outcome of probing one optional request feature
+type ProbeCheck struct {
+	Supported bool
+	Detail    string // Error or unexpected output when unsupported
+}
+
+// ProbeResult reports which optional request features a model endpoint supports
+type ProbeResult struct {
+	ModelName          string
+	Reasoning          bool       // Non-streamed response carried reasoning_content or <think> tags
+	EmbeddedThink      bool       // Reasoning arrived as <think> tags in content rather than reasoning_content
+	JSONMode           ProbeCheck // response_format json_object accepted and returned valid JSON
+	Streaming          ProbeCheck // SSE streaming returned a response
+	StreamingReasoning bool       // Streamed response carried reasoning deltas
+	Seed               ProbeCheck // A seed in the request body was accepted
+	SeedDeterministic  bool       // Two seeded requests returned identical output
+}
+
+// Probe sends a few small requests to detect JSON mode, streaming (with reasoning) and seed support
+// Only a failing baseline request is an error; unsupported features are reported in the result
+func (c *Client) Probe(ctx context.Context, modelCfg config.ModelConfig, apiKey string) (*ProbeResult, error) {
+	base := modelCfg
+	base.UseJSONMode = false
+	base.UseStreaming = false
+	base.MaxRetries = 1 // Rejected features should fail fast, not back off
+	if base.MaxOutputTokens <= 0 || base.MaxOutputTokens > probeMaxTokens {
+		base.MaxOutputTokens = probeMaxTokens
+	}
+	result := &ProbeResult{ModelName: modelCfg.ModelName}
+
+	ready := []Message{{Role: "user", Content: "Reply with the single word: ready"}}
+	resp, err := c.ChatCompletion(ctx, base, apiKey, ready)
+	if err != nil {
+		return nil, fmt.Errorf("baseline request failed: %w", err)
+	}
+	if msg, ok := firstMessage(resp); ok {
+		result.EmbeddedThink = strings.Contains(msg.Content, "<think>")
+		result.Reasoning = msg.ReasoningContent != "" || result.EmbeddedThink
+	}
+
+	jsonCfg := base
+	jsonCfg.UseJSONMode = true
+	resp, err = c.ChatCompletion(ctx, jsonCfg, apiKey, []Message{
+		{Role: "user", Content: `Return a JSON object with a single key "ok" set to true.`},
+	})
+	switch msg, ok := firstMessage(resp); {
+	case err != nil:
+		result.JSONMode.Detail = err.Error()
+	case !ok || !json.Valid([]byte(strings.TrimSpace(msg.Content))):
+		result.JSONMode.Detail = "response was not valid JSON"
+	default:
+		result.JSONMode.Supported = true
+	}
+
+	resp, err = c.ChatCompletionStreaming(ctx, base, apiKey, ready)
+	switch msg, ok := firstMessage(resp); {
+	case err != nil:
+		result.Streaming.Detail = err.Error()
+	case !ok || (msg.Content == "" && msg.ReasoningContent == ""):
+		result.Streaming.Detail = "stream returned no content"
+	default:
+		result.Streaming.Supported = true
+		result.StreamingReasoning = msg.ReasoningContent != ""
+	}
+
+	// Sampled at temperature 1 so identical outputs mean the seed is honored, not greedy decoding
+	seedCfg := base
+	seedCfg.Temperature = 1
+	seedCfg.ExtraBody = maps.Clone(base.ExtraBody)
+	if seedCfg.ExtraBody == nil {
+		seedCfg.ExtraBody = make(map[string]interface{})
+	}
+	seedCfg.ExtraBody["seed"] = probeSeed
+	story := []Message{{Role: "user", Content: "Write one short sentence about a random animal."}}
+	var outputs []string
+	for range 2 {
+		resp, err := c.ChatCompletion(ctx, seedCfg, apiKey, story)
+		if err != nil {
+			result.Seed.Detail = err.Error()
+			break
+		}
+		msg, _ := firstMessage(resp)
+		outputs = append(outputs, msg.Content)
+	}
+	if len(outputs) == 2 {
+		result.Seed.Supported = true
+		result.SeedDeterministic = outputs[0] == outputs[1]
+		if !result.SeedDeterministic {
+			result.Seed.Detail = "seed accepted but outputs differed"
+		}
+	}
+
+	return result, nil
+}
+
+func firstMessage(resp *ChatCompletionResponse) (Message, bool) {
+	if resp == nil || len(resp.Choices) == 0 {
+		return Message{}, false
+	}
+	return resp.Choices[0].Message, true
+}
+
+// ConfigSnippet renders the recommended settings as TOML for models.<modelKey>
+func (r *ProbeResult) ConfigSnippet(modelKey string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[models.%s]\n", modelKey)
+	fmt.Fprintf(&b, "use_json_mode = %t%s\n", r.JSONMode.Supported, probeComment(r.JSONMode, "JSON mode returned valid JSON"))
+
+	streamNote := "streaming works"
+	if r.StreamingReasoning {
+		streamNote = "streaming works, reasoning_content is streamed"
+	}
+	fmt.Fprintf(&b, "use_streaming = %t%s\n", r.Streaming.Supported, probeComment(r.Streaming, streamNote))
+
+	switch {
+	case r.SeedDeterministic:
+		fmt.Fprintf(&b, "extra_body = { seed = %d }  # seed is honored (merge into an existing extra_body)\n", probeSeed)
+	case r.Seed.Supported:
+		fmt.Fprintf(&b, "# extra_body = { seed = %d }  # seed accepted but not deterministic\n", probeSeed)
+	default:
+		fmt.Fprintf(&b, "# seed unsupported: %s\n", probeDetail(r.Seed.Detail))
+	}
+
+	if r.Reasoning || r.StreamingReasoning {
+		b.WriteString("\n[generation]\n")
+		b.WriteString("enable_reasoning_capture = true  # model returns reasoning\n")
+		if r.EmbeddedThink {
+			b.WriteString("strip_think_from_content = true  # reasoning is embedded in content as <think> tags\n")
+		}
+	}
+	return b.String()
+}
+
+func probeComment(check ProbeCheck, supportedNote string) string {
+	if check.Supported {
+		return "  # " + supportedNote
+	}
+	return "  # unsupported: " + probeDetail(check.Detail)
+}
+
+// probeDetail keeps error details on one short line
+func probeDetail(detail string) string {
+	detail = strings.Join(strings.Fields(detail), " ")
+	if len(detail) > 120 {
+		detail = detail[:117] + "..."
+	}
+	return detail
+}
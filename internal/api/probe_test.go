@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newProbeServer fakes an endpoint; rejectJSON and rejectSeed make it answer 400 to those request fields
+func newProbeServer(t *testing.T, rejectJSON, rejectSeed, randomSeed bool) *httptest.Server {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		n := calls.Add(1)
+
+		_, hasJSON := body["response_format"]
+		_, hasSeed := body["seed"]
+		if (rejectJSON && hasJSON) || (rejectSeed && hasSeed) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"message": "unsupported parameter"}}`))
+			return
+		}
+
+		if body["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"reasoning_content\":\"thinking\"}}]}\n\n"+
+				"data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ready\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+
+		content := "ready"
+		switch {
+		case hasJSON:
+			content = `{"ok": true}`
+		case hasSeed && randomSeed:
+			content = "A fox " + strings.Repeat("!", int(n))
+		case hasSeed:
+			content = "A fox naps."
+		}
+		resp, _ := json.Marshal(ChatCompletionResponse{Choices: []Choice{{Message: Message{Role: "assistant", Content: content}, FinishReason: "stop"}}})
+		_, _ = w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name          string
+		rejectJSON    bool
+		rejectSeed    bool
+		randomSeed    bool
+		wantJSON      bool
+		wantSeed      bool
+		wantSeedFixed bool
+		wantSnippet   []string
+	}{
+		{
+			name:          "all features",
+			wantJSON:      true,
+			wantSeed:      true,
+			wantSeedFixed: true,
+			wantSnippet:   []string{"use_json_mode = true", "use_streaming = true", "extra_body = { seed = 42 }", "enable_reasoning_capture = true"},
+		},
+		{
+			name:        "rejected parameters",
+			rejectJSON:  true,
+			rejectSeed:  true,
+			wantSnippet: []string{"use_json_mode = false  # unsupported: API error (status 400)", "# seed unsupported"},
+		},
+		{
+			name:        "seed ignored",
+			randomSeed:  true,
+			wantJSON:    true,
+			wantSeed:    true,
+			wantSnippet: []string{"# extra_body = { seed = 42 }  # seed accepted but not deterministic"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newProbeServer(t, tt.rejectJSON, tt.rejectSeed, tt.randomSeed)
+			client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			result, err := client.Probe(context.Background(), tlsTestModel(server.URL), "")
+			if err != nil {
+				t.Fatalf("Probe returned unexpected error: %v", err)
+			}
+			if result.JSONMode.Supported != tt.wantJSON {
+				t.Errorf("Expected JSON mode supported=%t, got %+v", tt.wantJSON, result.JSONMode)
+			}
+			if !result.Streaming.Supported || !result.StreamingReasoning {
+				t.Errorf("Expected streaming with reasoning, got %+v (reasoning %t)", result.Streaming, result.StreamingReasoning)
+			}
+			if result.Seed.Supported != tt.wantSeed || result.SeedDeterministic != tt.wantSeedFixed {
+				t.Errorf("Expected seed supported=%t deterministic=%t, got %+v / %t",
+					tt.wantSeed, tt.wantSeedFixed, result.Seed, result.SeedDeterministic)
+			}
+
+			snippet := result.ConfigSnippet("main")
+			if !strings.HasPrefix(snippet, "[models.main]\n") {
+				t.Errorf("Expected snippet for models.main, got:\n%s", snippet)
+			}
+			for _, want := range tt.wantSnippet {
+				if !strings.Contains(snippet, want) {
+					t.Errorf("Expected snippet to contain %q, got:\n%s", want, snippet)
+				}
+			}
+		})
+	}
+}
+
+func TestProbe_BaselineFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": {"message": "invalid api key"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := client.Probe(context.Background(), tlsTestModel(server.URL), ""); err == nil || !strings.Contains(err.Error(), "baseline") {
+		t.Errorf("Expected baseline request error, got %v", err)
+	}
+}
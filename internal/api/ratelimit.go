@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"sync"
+	"time"
 
 	"golang.org/x/time/rate"
 )
@@ -15,6 +16,10 @@ type RateLimiterPool struct {
 	providerLimiters map[string]*rate.Limiter
 	providerRates    map[string]int
 	mu               sync.RWMutex
+
+	adaptive       bool                             // provider_adaptive_rate_limit: adjust limiters from observed 429s
+	adaptiveStates map[*rate.Limiter]*adaptiveState // Only limiters that have seen a 429
+	now            func() time.Time
 }
 
 // NewRateLimiterPool creates a new rate limiter pool
@@ -24,6 +29,8 @@ func NewRateLimiterPool() *RateLimiterPool {
 		rates:            make(map[string]int),
 		providerLimiters: make(map[string]*rate.Limiter),
 		providerRates:    make(map[string]int),
+		adaptiveStates:   make(map[*rate.Limiter]*adaptiveState),
+		now:              time.Now,
 	}
 }
 
@@ -113,15 +120,16 @@ func (p *RateLimiterPool) GetOrCreateProvider(providerName string, requestsPerMi
 // Wait blocks until the rate limiter allows the next request
 // If providerName is not empty and providerRPM > 0, uses provider-level rate limiting
 func (p *RateLimiterPool) Wait(ctx context.Context, modelID string, requestsPerMinute int, providerName string, providerRPM int, burstPercent int) error {
-	// Use provider-level rate limiting if configured
+	return p.Limiter(modelID, requestsPerMinute, providerName, providerRPM, burstPercent).Wait(ctx)
+}
+
+// Limiter returns the limiter Wait uses for a request
+// (the provider limiter if providerName is not empty and providerRPM > 0, otherwise the model limiter)
+func (p *RateLimiterPool) Limiter(modelID string, requestsPerMinute int, providerName string, providerRPM int, burstPercent int) *rate.Limiter {
 	if providerName != "" && providerRPM > 0 {
-		limiter := p.GetOrCreateProvider(providerName, providerRPM, burstPercent)
-		return limiter.Wait(ctx)
+		return p.GetOrCreateProvider(providerName, providerRPM, burstPercent)
 	}
-
-	// Fall back to model-level rate limiting
-	limiter := p.GetOrCreate(modelID, requestsPerMinute)
-	return limiter.Wait(ctx)
+	return p.GetOrCreate(modelID, requestsPerMinute)
 }
 
 func max(a, b int) int {
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// DefaultMaxResponseBytes caps a response body when models.<name>.max_response_bytes is unset
+const DefaultMaxResponseBytes = 64 << 20
+
+// ResponseTooLargeError is returned when a response body exceeds max_response_bytes
+// It is not retried: an endpoint that sent one runaway response is likely to send another
+type ResponseTooLargeError struct {
+	Model string
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response from %s exceeded max_response_bytes (%d bytes); raise models.<name>.max_response_bytes or set it to -1 to disable the limit",
+		e.Model, e.Limit)
+}
+
+// maxResponseBytes returns the body cap for a model (0 = unlimited)
+func maxResponseBytes(modelCfg config.ModelConfig) int64 {
+	switch {
+	case modelCfg.MaxResponseBytes < 0:
+		return 0
+	case modelCfg.MaxResponseBytes == 0:
+		return DefaultMaxResponseBytes
+	default:
+		return modelCfg.MaxResponseBytes
+	}
+}
+
+// limitResponseBody wraps body so reading past limit fails with a ResponseTooLargeError
+// instead of buffering an unbounded response; limit 0 returns body unchanged
+func limitResponseBody(body io.Reader, limit int64, model string) io.Reader {
+	if limit <= 0 {
+		return body
+	}
+	// One extra byte tells a body of exactly limit bytes apart from a longer one
+	return &cappedReader{r: io.LimitReader(body, limit+1), limit: limit, model: model}
+}
+
+// cappedReader fails once more than limit bytes were read
+type cappedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+	model string
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read > c.limit {
+		return n - int(c.read-c.limit), &ResponseTooLargeError{Model: c.model, Limit: c.limit}
+	}
+	return n, err
+}
+
+// responseTooLarge returns the ResponseTooLargeError wrapped in err, if any
+func responseTooLarge(err error) (*ResponseTooLargeError, bool) {
+	var tooLarge *ResponseTooLargeError
+	if errors.As(err, &tooLarge) {
+		return tooLarge, true
+	}
+	return nil, false
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestLimitResponseBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		wantErr bool
+	}{
+		{"under limit", "hello", 10, false},
+		{"exactly limit", "hello", 5, false},
+		{"over limit", "hello!", 5, true},
+		{"unlimited", strings.Repeat("x", 1000), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := io.ReadAll(limitResponseBody(strings.NewReader(tt.body), tt.limit, "m"))
+			var tooLarge *ResponseTooLargeError
+			if got := errors.As(err, &tooLarge); got != tt.wantErr {
+				t.Fatalf("Expected too large error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && int64(len(data)) != tt.limit {
+				t.Errorf("Expected %d bytes read before the error, got %d", tt.limit, len(data))
+			}
+			if !tt.wantErr && string(data) != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, data)
+			}
+		})
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	if got := maxResponseBytes(config.ModelConfig{}); got != DefaultMaxResponseBytes {
+		t.Errorf("Expected default %d, got %d", DefaultMaxResponseBytes, got)
+	}
+	if got := maxResponseBytes(config.ModelConfig{MaxResponseBytes: -1}); got != 0 {
+		t.Errorf("Expected -1 to disable the limit, got %d", got)
+	}
+	if got := maxResponseBytes(config.ModelConfig{MaxResponseBytes: 2048}); got != 2048 {
+		t.Errorf("Expected 2048, got %d", got)
+	}
+}
+
+func TestChatCompletion_MaxResponseBytes(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		name := "non-streaming"
+		if streaming {
+			name = "streaming"
+		}
+		t.Run(name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				content := strings.Repeat("x", 4096)
+				if streaming {
+					w.Header().Set("Content-Type", "text/event-stream")
+					_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\""+content+"\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+					return
+				}
+				_, _ = io.WriteString(w, `{"id":"1","model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"`+content+`"},"finish_reason":"stop"}]}`)
+			}))
+			defer server.Close()
+
+			client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				Temperature:        0.7,
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 600,
+				HTTPTimeoutSeconds: 5,
+				MaxRetries:         2,
+				MaxResponseBytes:   1024,
+			}
+			messages := []Message{{Role: "user", Content: "hi"}}
+
+			var err error
+			if streaming {
+				_, err = client.ChatCompletionStreaming(context.Background(), modelCfg, "test-key", messages)
+			} else {
+				_, err = client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+			}
+			var tooLarge *ResponseTooLargeError
+			if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+				t.Fatalf("Expected a ResponseTooLargeError with limit 1024, got %v", err)
+			}
+			if requests != 1 {
+				t.Errorf("Expected the oversized response not to be retried, got %d requests", requests)
+			}
+		})
+	}
+}
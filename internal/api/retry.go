@@ -0,0 +1,126 @@
+package api
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// DefaultJitterFraction is the random +/- share applied to each retry backoff
+const DefaultJitterFraction = 0.1
+
+// RetryOptions tunes the client's retry backoff; zero fields keep the defaults
+type RetryOptions struct {
+	BaseDelay           time.Duration   // First retry delay, scaled per attempt by Strategy (default: DefaultBaseRetryDelay)
+	MaxAttempts         int             // Retries for models without max_retries (default: DefaultMaxRetries, -1 = unlimited)
+	JitterFraction      float64         // Random +/- share of each backoff (default: DefaultJitterFraction, negative = no jitter)
+	RateLimitMultiplier float64         // 429 backoff is multiplier^attempt x BaseDelay (default: RateLimitBackoffMultiplier)
+	Strategy            BackoffStrategy // Backoff growth for other retryable errors (default: ExponentialBackoff)
+}
+
+// SetRetryOptions replaces the retry backoff settings; zero fields reset to the defaults
+func (c *Client) SetRetryOptions(opts RetryOptions) {
+	c.baseRetryDelay = DefaultBaseRetryDelay
+	if opts.BaseDelay > 0 {
+		c.baseRetryDelay = opts.BaseDelay
+	}
+	c.maxRetries = DefaultMaxRetries
+	if opts.MaxAttempts != 0 {
+		c.maxRetries = opts.MaxAttempts
+	}
+	c.jitterFraction = DefaultJitterFraction
+	switch {
+	case opts.JitterFraction < 0:
+		c.jitterFraction = 0
+	case opts.JitterFraction > 0:
+		c.jitterFraction = opts.JitterFraction
+	}
+	c.rateLimitMultiplier = RateLimitBackoffMultiplier
+	if opts.RateLimitMultiplier > 0 {
+		c.rateLimitMultiplier = opts.RateLimitMultiplier
+	}
+	c.backoffStrategy = opts.Strategy
+	if c.backoffStrategy == nil {
+		c.backoffStrategy = ExponentialBackoff{}
+	}
+}
+
+// jitterSource draws retry jitter, from a seeded generator once SetSeed is called
+// Shared by all workers, so it is locked; the zero value uses the runtime's random source
+type jitterSource struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// float64 returns a uniform value in [0, 1)
+func (s *jitterSource) float64() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rng == nil {
+		return rand.Float64()
+	}
+	return s.rng.Float64()
+}
+
+// SetSeed seeds retry jitter from generation.seed so the sequence of jitter values is reproducible
+// (0 = unseeded). Which request draws which value still depends on worker scheduling
+func (c *Client) SetSeed(seed int64) {
+	c.jitterSource.mu.Lock()
+	defer c.jitterSource.mu.Unlock()
+
+	c.jitterSource.rng = nil
+	if seed != 0 {
+		c.jitterSource.rng = rand.New(rand.NewPCG(uint64(seed), 0))
+	}
+}
+
+// SetRetryConfig applies the [retry] config block
+// Unknown strategies are rejected by Validate, so they fall back to exponential here
+func (c *Client) SetRetryConfig(retryCfg config.RetryConfig) {
+	strategy, err := NewBackoffStrategy(retryCfg.Strategy)
+	if err != nil {
+		strategy = ExponentialBackoff{}
+	}
+	c.SetRetryOptions(RetryOptions{
+		BaseDelay:           retryCfg.BaseDelayDuration(),
+		MaxAttempts:         retryCfg.MaxAttempts,
+		JitterFraction:      retryCfg.JitterFraction,
+		RateLimitMultiplier: retryCfg.RateLimitMultiplier,
+		Strategy:            strategy,
+	})
+}
+
+// retryBackoff returns how long to sleep before retry attempt (1-based) after lastErr
+// Backoff grows per attempt by the retry strategy (doubling by default), 429s grow by the rate
+// limit multiplier instead (unless the key was rotated), the result is capped at the model's
+// max_backoff_seconds and then jittered
+func (c *Client) retryBackoff(attempt int, lastErr error, rotated bool, modelCfg config.ModelConfig) time.Duration {
+	strategy := c.backoffStrategy
+	if strategy == nil {
+		strategy = ExponentialBackoff{}
+	}
+	factor := strategy.Factor(attempt)
+
+	// For rate limit errors, use longer delays (3^n by default: 6s, 18s, 54s)
+	if apiErr, ok := lastErr.(*APIError); ok && apiErr.StatusCode == http.StatusTooManyRequests && !rotated {
+		factor = math.Pow(c.rateLimitMultiplier, float64(attempt))
+	}
+
+	// Apply configurable backoff cap (in float64 so huge attempt counts cannot overflow)
+	maxBackoff := DefaultMaxBackoffDuration
+	if modelCfg.MaxBackoffSeconds > 0 {
+		maxBackoff = time.Duration(modelCfg.MaxBackoffSeconds) * time.Second
+	}
+	backoff := maxBackoff
+	if scaled := factor * float64(c.baseRetryDelay); scaled >= 0 && scaled < float64(maxBackoff) {
+		backoff = time.Duration(scaled)
+	}
+
+	jitter := time.Duration(float64(backoff) * c.jitterFraction * (2*c.jitterSource.float64() - 1))
+	return backoff + jitter
+}
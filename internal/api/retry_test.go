@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestSetRetryOptions_Defaults(t *testing.T) {
+	client := NewClient(slog.Default())
+	client.SetRetryOptions(RetryOptions{BaseDelay: time.Second, MaxAttempts: 7, JitterFraction: 0.5, RateLimitMultiplier: 2})
+	client.SetRetryOptions(RetryOptions{})
+
+	if client.baseRetryDelay != DefaultBaseRetryDelay {
+		t.Errorf("Expected base delay %v, got %v", DefaultBaseRetryDelay, client.baseRetryDelay)
+	}
+	if client.maxRetries != DefaultMaxRetries {
+		t.Errorf("Expected max retries %d, got %d", DefaultMaxRetries, client.maxRetries)
+	}
+	if client.jitterFraction != DefaultJitterFraction {
+		t.Errorf("Expected jitter fraction %.2f, got %.2f", DefaultJitterFraction, client.jitterFraction)
+	}
+	if client.rateLimitMultiplier != RateLimitBackoffMultiplier {
+		t.Errorf("Expected rate limit multiplier %d, got %.2f", RateLimitBackoffMultiplier, client.rateLimitMultiplier)
+	}
+}
+
+func TestSetRetryConfig(t *testing.T) {
+	client := NewClient(slog.Default())
+	client.SetRetryConfig(config.RetryConfig{BaseDelay: "500ms", MaxAttempts: -1, JitterFraction: -1, RateLimitMultiplier: 2})
+
+	if client.baseRetryDelay != 500*time.Millisecond {
+		t.Errorf("Expected base delay 500ms, got %v", client.baseRetryDelay)
+	}
+	if client.maxRetries != -1 {
+		t.Errorf("Expected unlimited retries, got %d", client.maxRetries)
+	}
+	if client.jitterFraction != 0 {
+		t.Errorf("Expected no jitter, got %.2f", client.jitterFraction)
+	}
+	if client.rateLimitMultiplier != 2 {
+		t.Errorf("Expected rate limit multiplier 2, got %.2f", client.rateLimitMultiplier)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	client := NewClient(slog.Default())
+	client.SetRetryOptions(RetryOptions{BaseDelay: time.Second, JitterFraction: -1, RateLimitMultiplier: 2})
+
+	rateLimited := &APIError{StatusCode: http.StatusTooManyRequests}
+	serverErr := &APIError{StatusCode: http.StatusInternalServerError}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		err      error
+		rotated  bool
+		modelCfg config.ModelConfig
+		want     time.Duration
+	}{
+		{"first retry", 1, serverErr, false, config.ModelConfig{}, time.Second},
+		{"second retry", 2, serverErr, false, config.ModelConfig{}, 2 * time.Second},
+		{"third retry", 3, errors.New("network"), false, config.ModelConfig{}, 4 * time.Second},
+		{"rate limited", 3, rateLimited, false, config.ModelConfig{}, 8 * time.Second},
+		{"rate limited after key rotation", 3, rateLimited, true, config.ModelConfig{}, 4 * time.Second},
+		{"capped by max_backoff_seconds", 5, serverErr, false, config.ModelConfig{MaxBackoffSeconds: 10}, 10 * time.Second},
+		{"capped by default", 30, serverErr, false, config.ModelConfig{}, DefaultMaxBackoffDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := client.retryBackoff(tt.attempt, tt.err, tt.rotated, tt.modelCfg)
+			if got != tt.want {
+				t.Errorf("Expected backoff %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff_JitterBounds(t *testing.T) {
+	client := NewClient(slog.Default())
+	client.SetRetryOptions(RetryOptions{BaseDelay: time.Second, JitterFraction: 0.5})
+
+	for i := 0; i < 20; i++ {
+		got := client.retryBackoff(2, nil, false, config.ModelConfig{})
+		if got < time.Second || got > 3*time.Second {
+			t.Fatalf("Expected backoff within 2s +/- 50%%, got %v", got)
+		}
+	}
+}
+
+func TestRetryBackoff_SeededJitter(t *testing.T) {
+	backoffs := func(seed int64) []time.Duration {
+		client := NewClient(slog.Default())
+		client.SetRetryOptions(RetryOptions{BaseDelay: time.Second, JitterFraction: 0.5})
+		client.SetSeed(seed)
+		var got []time.Duration
+		for i := 0; i < 5; i++ {
+			got = append(got, client.retryBackoff(1, nil, false, config.ModelConfig{}))
+		}
+		return got
+	}
+
+	first, second, other := backoffs(7), backoffs(7), backoffs(8)
+	if !slices.Equal(first, second) {
+		t.Errorf("Expected the same seed to give the same backoffs, got %v and %v", first, second)
+	}
+	if slices.Equal(first, other) {
+		t.Errorf("Expected different seeds to give different backoffs, got %v for both", first)
+	}
+}
+
+func TestBackoffStrategies(t *testing.T) {
+	tests := []struct {
+		name string
+		want []float64
+	}{
+		{"", []float64{1, 2, 4, 8, 16}},
+		{config.RetryStrategyExponential, []float64{1, 2, 4, 8, 16}},
+		{config.RetryStrategyLinear, []float64{1, 2, 3, 4, 5}},
+		{config.RetryStrategyFibonacci, []float64{1, 1, 2, 3, 5}},
+		{config.RetryStrategyConstant, []float64{1, 1, 1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := NewBackoffStrategy(tt.name)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			for i, want := range tt.want {
+				if got := strategy.Factor(i + 1); got != want {
+					t.Errorf("Attempt %d: expected factor %v, got %v", i+1, want, got)
+				}
+			}
+		})
+	}
+
+	if _, err := NewBackoffStrategy("random"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestRetryBackoff_Strategy(t *testing.T) {
+	client := NewClient(slog.Default())
+	client.SetRetryConfig(config.RetryConfig{Strategy: config.RetryStrategyLinear, BaseDelay: "1s", JitterFraction: -1, RateLimitMultiplier: 2})
+
+	serverErr := &APIError{StatusCode: http.StatusInternalServerError}
+	if got := client.retryBackoff(3, serverErr, false, config.ModelConfig{}); got != 3*time.Second {
+		t.Errorf("Expected linear backoff 3s, got %v", got)
+	}
+	rateLimited := &APIError{StatusCode: http.StatusTooManyRequests}
+	if got := client.retryBackoff(3, rateLimited, false, config.ModelConfig{}); got != 8*time.Second {
+		t.Errorf("Expected 429 backoff to keep the rate limit multiplier (8s), got %v", got)
+	}
+	if got := client.retryBackoff(200, serverErr, false, config.ModelConfig{MaxBackoffSeconds: 30}); got != 30*time.Second {
+		t.Errorf("Expected backoff capped at 30s, got %v", got)
+	}
+
+	client.SetRetryConfig(config.RetryConfig{Strategy: config.RetryStrategyFibonacci, JitterFraction: -1})
+	if got := client.retryBackoff(5000, serverErr, false, config.ModelConfig{}); got != DefaultMaxBackoffDuration {
+		t.Errorf("Expected a huge fibonacci factor capped at %v, got %v", DefaultMaxBackoffDuration, got)
+	}
+}
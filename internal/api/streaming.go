@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 	"os"
 	"strings"
@@ -21,6 +20,7 @@ type StreamDelta struct {
 	Role             string `json:"role,omitempty"`
 	Content          string `json:"content,omitempty"`
 	ReasoningContent string `json:"reasoning_content,omitempty"` // For reasoning models
+	Reasoning        string `json:"reasoning,omitempty"`         // Alternate field name used by some providers
 }
 
 // StreamChoice represents a choice in a streaming response chunk
@@ -37,10 +37,12 @@ type StreamResponse struct {
 	Created int64          `json:"created"`
 	Model   string         `json:"model"`
 	Choices []StreamChoice `json:"choices"`
+	// Some providers only send the fingerprint on later chunks
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
 }
 
 // ChatCompletionStreaming sends a chat completion request with streaming enabled
-// This is required for reasoning models to expose the reasoning_content field
+// Bypasses gateway timeouts for long responses; reasoning is captured in both streaming and non-streaming modes
 func (c *Client) ChatCompletionStreaming(
 	ctx context.Context,
 	modelCfg config.ModelConfig,
@@ -48,6 +50,7 @@ func (c *Client) ChatCompletionStreaming(
 	messages []Message,
 ) (*ChatCompletionResponse, error) {
 	requestStart := time.Now()
+	defer c.timings.finish(requestStart)
 
 	// Apply per-model HTTP timeout
 	// HTTPTimeoutSeconds should always be set (config loader defaults to 120s)
@@ -64,6 +67,12 @@ func (c *Client) ChatCompletionStreaming(
 	ctx, cancel = context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	// Fail (or truncate) locally instead of waiting for a context length 400 from the API
+	messages, err := c.guardPromptLength(modelCfg, messages)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generate a unique model ID for rate limiting
 	modelID := fmt.Sprintf("%s:%s", modelCfg.BaseURL, modelCfg.ModelName)
 
@@ -78,10 +87,13 @@ func (c *Client) ChatCompletionStreaming(
 
 	// Wait for rate limiter
 	rateLimitStart := time.Now()
-	if err := c.rateLimiterPool.Wait(ctx, modelID, modelCfg.RateLimitPerMinute, providerName, providerRPM, c.providerBurstPercent); err != nil {
+	limiter := c.rateLimiterPool.Limiter(modelID, modelCfg.RateLimitPerMinute, providerName, providerRPM, c.providerBurstPercent)
+	if err := limiter.Wait(ctx); err != nil {
 		return nil, fmt.Errorf("rate limiter wait failed: %w", err)
 	}
 	rateLimitWait := time.Since(rateLimitStart)
+	c.timings.addWait(rateLimitWait)
+	c.metrics.RecordRateLimiterWait(modelCfg.ModelName, rateLimitWait)
 
 	// Construct request with streaming enabled
 	req := ChatCompletionRequest{
@@ -91,6 +103,11 @@ func (c *Client) ChatCompletionStreaming(
 		TopP:        modelCfg.TopP,
 		MaxTokens:   modelCfg.MaxOutputTokens,
 		N:           1,
+
+		ExtraBody:         modelCfg.ExtraBody,
+		ExtraBodyOverride: modelCfg.ExtraBodyOverride,
+		FieldMap:          modelCfg.FieldMap,
+		FieldOmit:         modelCfg.FieldOmit,
 	}
 
 	// Enable JSON mode if configured
@@ -98,19 +115,16 @@ func (c *Client) ChatCompletionStreaming(
 		req.ResponseFormat = &ResponseFormat{Type: "json_object"}
 	}
 
-	// Add stream parameter (not part of ChatCompletionRequest struct)
-	reqMap := make(map[string]interface{})
-	reqBytes, err := json.Marshal(req)
+	// Add stream parameter (not part of ChatCompletionRequest struct) after merging extra_body
+	reqMap, err := c.requestBody(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-	if err := json.Unmarshal(reqBytes, &reqMap); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal to map: %w", err)
+		return nil, err
 	}
 	reqMap["stream"] = true
 
 	// Retry with exponential backoff
 	var lastErr error
+	rotated := false // Retrying a 429 with a different key, so the long rate limit backoff is skipped
 	maxAttempts := modelCfg.MaxRetries
 	if maxAttempts == 0 {
 		maxAttempts = c.maxRetries
@@ -120,21 +134,7 @@ func (c *Client) ChatCompletionStreaming(
 	for attempt := 0; maxAttempts < 0 || attempt <= maxAttempts; attempt++ {
 		if attempt > 0 {
 			// Calculate backoff
-			backoff := time.Duration(math.Pow(2, float64(attempt-1))) * c.baseRetryDelay
-			if apiErr, ok := lastErr.(*APIError); ok && apiErr.StatusCode == http.StatusTooManyRequests {
-				backoff = time.Duration(math.Pow(RateLimitBackoffMultiplier, float64(attempt))) * c.baseRetryDelay
-			}
-
-			maxBackoff := DefaultMaxBackoffDuration
-			if modelCfg.MaxBackoffSeconds > 0 {
-				maxBackoff = time.Duration(modelCfg.MaxBackoffSeconds) * time.Second
-			}
-			if backoff > maxBackoff {
-				backoff = maxBackoff
-			}
-
-			jitter := time.Duration(float64(backoff) * 0.1 * (2*float64(time.Now().UnixNano()%100)/100 - 1))
-			sleepDuration := backoff + jitter
+			sleepDuration := c.retryBackoff(attempt, lastErr, rotated, modelCfg)
 
 			c.logger.Warn("Retrying streaming API request",
 				"attempt", attempt,
@@ -149,7 +149,10 @@ func (c *Client) ChatCompletionStreaming(
 			}
 		}
 
-		resp, err := c.doStreamingRequest(ctx, modelCfg.BaseURL, apiKey, reqMap)
+		attemptStart := time.Now()
+		resp, err := c.doStreamingRequest(ctx, modelCfg, apiKey, reqMap)
+		c.observeRateLimit(limiter, err)
+		c.recordAttempt(modelCfg.ModelName, time.Since(attemptStart), resp, err)
 		if err == nil {
 			apiCallDuration := time.Since(apiCallStart)
 			totalDuration := time.Since(requestStart)
@@ -160,6 +163,7 @@ func (c *Client) ChatCompletionStreaming(
 				"api_duration_ms", apiCallDuration.Milliseconds(),
 				"total_ms", totalDuration.Milliseconds())
 
+			resp.Attempts = attempt + 1
 			return resp, nil
 		}
 
@@ -168,6 +172,9 @@ func (c *Client) ChatCompletionStreaming(
 		if !c.isRetryable(err) {
 			return nil, err
 		}
+		nextKey := c.rotateKey(modelCfg, apiKey, err)
+		rotated = nextKey != apiKey
+		apiKey = nextKey
 	}
 
 	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
@@ -175,7 +182,7 @@ func (c *Client) ChatCompletionStreaming(
 
 func (c *Client) doStreamingRequest(
 	ctx context.Context,
-	baseURL string,
+	modelCfg config.ModelConfig,
 	apiKey string,
 	reqMap map[string]interface{},
 ) (*ChatCompletionResponse, error) {
@@ -188,11 +195,7 @@ func (c *Client) doStreamingRequest(
 	}
 
 	// Create HTTP request
-	endpoint := baseURL
-	if endpoint[len(endpoint)-1] != '/' {
-		endpoint += "/"
-	}
-	endpoint += "chat/completions"
+	endpoint := ChatEndpoint(modelCfg.BaseURL, modelCfg.ChatPath)
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(buf.Bytes()))
 	if err != nil {
@@ -201,14 +204,16 @@ func (c *Client) doStreamingRequest(
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgentFor(modelCfg))
 	httpReq.Header.Set("Accept", "text/event-stream")
 	if apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		setAuthHeader(httpReq.Header, modelCfg, apiKey)
 	}
 
 	// Send request
-	httpResp, err := c.httpClient.Do(httpReq)
+	httpResp, err := c.httpClientFor(modelCfg.BaseURL).Do(httpReq)
 	if err != nil {
+		c.debugDump.dump(httpReq, buf.Bytes(), 0, nil, err, true)
 		return nil, &APIError{
 			Message:    fmt.Sprintf("request failed: %v", err),
 			StatusCode: 0,
@@ -216,10 +221,13 @@ func (c *Client) doStreamingRequest(
 		}
 	}
 	defer func() { _ = httpResp.Body.Close() }()
+	// Capped by max_response_bytes, counting SSE framing; reading past it aborts the request
+	var body io.Reader = limitResponseBody(httpResp.Body, maxResponseBytes(modelCfg), modelCfg.ModelName)
 
 	// Check status code
 	if httpResp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(httpResp.Body)
+		bodyBytes, _ := io.ReadAll(body)
+		c.debugDump.dump(httpReq, buf.Bytes(), httpResp.StatusCode, bodyBytes, nil, true)
 		var errResp ErrorResponse
 		if err := json.Unmarshal(bodyBytes, &errResp); err == nil && errResp.Error.Message != "" {
 			return nil, &APIError{
@@ -247,9 +255,19 @@ func (c *Client) doStreamingRequest(
 	var responseID string
 	var responseModel string
 	var responseCreated int64
+	var systemFingerprint string
 	var finishReason string
 
-	scanner := bufio.NewScanner(httpResp.Body)
+	// Capture the raw SSE stream when debug dumps are enabled
+	if c.debugDump != nil {
+		var rawStream bytes.Buffer
+		body = io.TeeReader(body, &rawStream)
+		defer func() {
+			c.debugDump.dump(httpReq, buf.Bytes(), httpResp.StatusCode, rawStream.Bytes(), nil, true)
+		}()
+	}
+
+	scanner := bufio.NewScanner(body)
 	// Increase scanner buffer to handle large SSE data: lines from some providers
 	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
 	for scanner.Scan() {
@@ -268,7 +286,7 @@ func (c *Client) doStreamingRequest(
 			if data == "[DONE]" {
 				if debugStreaming {
 					c.logger.Debug("Streaming SSE received DONE marker",
-						"endpoint", baseURL,
+						"endpoint", modelCfg.BaseURL,
 						"response_id", responseID,
 						"model", responseModel,
 					)
@@ -276,6 +294,11 @@ func (c *Client) doStreamingRequest(
 				break
 			}
 
+			// A mid-stream failure arrives as an error chunk; the status is already 200
+			if apiErr := c.errorInBody([]byte(data)); apiErr != nil {
+				return nil, apiErr
+			}
+
 			// Parse JSON chunk
 			var chunk StreamResponse
 			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
@@ -289,6 +312,9 @@ func (c *Client) doStreamingRequest(
 				responseModel = chunk.Model
 				responseCreated = chunk.Created
 			}
+			if systemFingerprint == "" {
+				systemFingerprint = chunk.SystemFingerprint
+			}
 
 			// Extract content from delta
 			if len(chunk.Choices) > 0 {
@@ -303,6 +329,8 @@ func (c *Client) doStreamingRequest(
 				// Append reasoning content (for reasoning models)
 				if delta.ReasoningContent != "" {
 					reasoningContent.WriteString(delta.ReasoningContent)
+				} else if delta.Reasoning != "" {
+					reasoningContent.WriteString(delta.Reasoning)
 				}
 
 				// Check finish reason
@@ -317,7 +345,7 @@ func (c *Client) doStreamingRequest(
 					}
 
 					c.logger.Debug("Streaming SSE chunk",
-						"endpoint", baseURL,
+						"endpoint", modelCfg.BaseURL,
 						"chunk_id", chunk.ID,
 						"model", chunk.Model,
 						"created", chunk.Created,
@@ -331,6 +359,9 @@ func (c *Client) doStreamingRequest(
 	}
 
 	if err := scanner.Err(); err != nil {
+		if tooLarge, ok := responseTooLarge(err); ok {
+			return nil, tooLarge
+		}
 		return nil, fmt.Errorf("stream reading error: %w", err)
 	}
 
@@ -360,6 +391,7 @@ func (c *Client) doStreamingRequest(
 			PromptTokens:     0,
 			TotalTokens:      0,
 		},
+		SystemFingerprint: systemFingerprint,
 	}
 
 	// Log reasoning detection
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RequestTimings aggregates time spent in chat completion requests (used by run --benchmark)
+type RequestTimings struct {
+	Requests      int64
+	RateLimitWait time.Duration // Time spent blocked on the rate limiter
+	Total         time.Duration // Wall-clock time of whole requests, including waits and retries
+}
+
+// WaitFraction returns the share of request time spent waiting on the rate limiter
+func (t RequestTimings) WaitFraction() float64 {
+	if t.Total <= 0 {
+		return 0
+	}
+	return float64(t.RateLimitWait) / float64(t.Total)
+}
+
+// requestTimer accumulates RequestTimings across concurrent requests
+type requestTimer struct {
+	requests      atomic.Int64
+	rateLimitWait atomic.Int64 // Nanoseconds
+	total         atomic.Int64 // Nanoseconds
+}
+
+func (t *requestTimer) addWait(d time.Duration) {
+	t.rateLimitWait.Add(int64(d))
+}
+
+func (t *requestTimer) finish(start time.Time) {
+	t.requests.Add(1)
+	t.total.Add(int64(time.Since(start)))
+}
+
+// RequestTimings returns the accumulated request timings for this client
+func (c *Client) RequestTimings() RequestTimings {
+	return RequestTimings{
+		Requests:      c.timings.requests.Load(),
+		RateLimitWait: time.Duration(c.timings.rateLimitWait.Load()),
+		Total:         time.Duration(c.timings.total.Load()),
+	}
+}
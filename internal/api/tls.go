@@ -0,0 +1,121 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// tlsSettings is the effective TLS verification setup for one endpoint
+type tlsSettings struct {
+	caBundle string
+	insecure bool
+}
+
+// modelTLS returns the settings for a model: its own ca_bundle replaces network.ca_bundle,
+// and insecure_skip_verify on either level disables verification
+func modelTLS(netCfg config.NetworkConfig, modelCfg config.ModelConfig) tlsSettings {
+	settings := tlsSettings{caBundle: netCfg.CABundle, insecure: netCfg.InsecureSkipVerify || modelCfg.InsecureSkipVerify}
+	if modelCfg.CABundle != "" {
+		settings.caBundle = modelCfg.CABundle
+	}
+	return settings
+}
+
+// newTLSConfig builds a TLS config trusting the system roots plus caBundle
+// Returns nil when neither option is set so Go's defaults apply
+func newTLSConfig(settings tlsSettings) (*tls.Config, error) {
+	if settings == (tlsSettings{}) {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: settings.insecure, // Opt-in for self-signed local servers, warned at startup
+	}
+	if settings.caBundle != "" {
+		pem, err := os.ReadFile(settings.caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_bundle %s contains no PEM certificates", settings.caBundle)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg, nil
+}
+
+// ConfigureTLS applies network.ca_bundle / insecure_skip_verify to all requests and gives
+// models with their own TLS settings a dedicated HTTP client for their base_url
+func (c *Client) ConfigureTLS(netCfg config.NetworkConfig, modelCfgs map[string]config.ModelConfig) error {
+	global := tlsSettings{caBundle: netCfg.CABundle, insecure: netCfg.InsecureSkipVerify}
+	tlsCfg, err := newTLSConfig(global)
+	if err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+	if tlsCfg != nil {
+		transport := newTransport(netCfg)
+		transport.TLSClientConfig = tlsCfg
+		c.httpClient = &http.Client{Transport: transport}
+	}
+	if global.insecure {
+		c.logger.Warn("TLS certificate verification is DISABLED for all endpoints (network.insecure_skip_verify)")
+	}
+
+	// Clients are keyed by base_url, so models sharing an endpoint must agree on TLS settings
+	byURL := make(map[string]tlsSettings)
+	owner := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(modelCfgs)) {
+		modelCfg := modelCfgs[name]
+		settings := modelTLS(netCfg, modelCfg)
+		if prev, ok := byURL[modelCfg.BaseURL]; ok && prev != settings {
+			return fmt.Errorf("models.%s and models.%s share base_url %s but use different TLS settings",
+				owner[modelCfg.BaseURL], name, modelCfg.BaseURL)
+		}
+		byURL[modelCfg.BaseURL] = settings
+		owner[modelCfg.BaseURL] = name
+	}
+
+	for _, baseURL := range slices.Sorted(maps.Keys(byURL)) {
+		settings := byURL[baseURL]
+		if settings == global {
+			continue
+		}
+		tlsCfg, err := newTLSConfig(settings)
+		if err != nil {
+			return fmt.Errorf("models.%s: %w", owner[baseURL], err)
+		}
+		transport := newTransport(netCfg)
+		transport.TLSClientConfig = tlsCfg
+		if c.endpointClients == nil {
+			c.endpointClients = make(map[string]*http.Client)
+		}
+		c.endpointClients[baseURL] = &http.Client{Transport: transport}
+
+		if settings.insecure && !global.insecure {
+			c.logger.Warn("TLS certificate verification is DISABLED for model endpoint",
+				"model", owner[baseURL],
+				"base_url", baseURL)
+		}
+	}
+	return nil
+}
+
+// httpClientFor returns the HTTP client for a base URL (a per-model TLS client when configured)
+func (c *Client) httpClientFor(baseURL string) *http.Client {
+	if client, ok := c.endpointClients[baseURL]; ok {
+		return client
+	}
+	return c.httpClient
+}
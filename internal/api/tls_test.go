@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func newTLSTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // Expected handshake failures
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func tlsTestModel(baseURL string) config.ModelConfig {
+	return config.ModelConfig{
+		BaseURL:            baseURL,
+		ModelName:          "test-model",
+		RateLimitPerMinute: 1000,
+	}
+}
+
+func TestConfigureTLS(t *testing.T) {
+	server := newTLSTestServer(t)
+
+	// PEM bundle with the test server's self-signed certificate
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certPEM, 0o644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		netCfg  config.NetworkConfig
+		model   func(config.ModelConfig) config.ModelConfig
+		wantErr bool
+	}{
+		{
+			name:    "default verification rejects self-signed",
+			wantErr: true,
+		},
+		{
+			name:   "global ca_bundle",
+			netCfg: config.NetworkConfig{CABundle: bundle},
+		},
+		{
+			name:   "global insecure_skip_verify",
+			netCfg: config.NetworkConfig{InsecureSkipVerify: true},
+		},
+		{
+			name: "per-model ca_bundle",
+			model: func(m config.ModelConfig) config.ModelConfig {
+				m.CABundle = bundle
+				return m
+			},
+		},
+		{
+			name: "per-model insecure_skip_verify",
+			model: func(m config.ModelConfig) config.ModelConfig {
+				m.InsecureSkipVerify = true
+				return m
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			client := NewClient(logger)
+			client.maxRetries = 0 // Fail on the first certificate error
+
+			modelCfg := tlsTestModel(server.URL)
+			if tt.model != nil {
+				modelCfg = tt.model(modelCfg)
+			}
+			if err := client.ConfigureTLS(tt.netCfg, map[string]config.ModelConfig{"main": modelCfg}); err != nil {
+				t.Fatalf("ConfigureTLS returned unexpected error: %v", err)
+			}
+
+			_, err := client.ChatCompletion(context.Background(), modelCfg, "", []Message{{Role: "user", Content: "hi"}})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "certificate") {
+					t.Errorf("Expected a certificate error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected request to succeed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigureTLS_Errors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	notPEM := filepath.Join(t.TempDir(), "bundle.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+	if err := NewClient(logger).ConfigureTLS(config.NetworkConfig{CABundle: notPEM}, nil); err == nil {
+		t.Errorf("Expected error for a bundle without PEM certificates")
+	}
+	if err := NewClient(logger).ConfigureTLS(config.NetworkConfig{CABundle: filepath.Join(t.TempDir(), "missing.pem")}, nil); err == nil {
+		t.Errorf("Expected error for a missing bundle")
+	}
+
+	shared := tlsTestModel("https://localhost:8443/v1")
+	insecure := shared
+	insecure.InsecureSkipVerify = true
+	err := NewClient(logger).ConfigureTLS(config.NetworkConfig{}, map[string]config.ModelConfig{"main": shared, "rejected": insecure})
+	if err == nil || !strings.Contains(err.Error(), "different TLS settings") {
+		t.Errorf("Expected conflicting TLS settings error, got %v", err)
+	}
+}
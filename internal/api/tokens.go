@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+const (
+	// charsPerToken is the rough characters-per-token ratio of BPE tokenizers on English text
+	charsPerToken = 4
+	// messageTokenOverhead approximates the role/separator tokens chat templates add per message
+	messageTokenOverhead = 4
+)
+
+// ErrPromptTooLong is returned before sending when the estimated prompt does not fit in
+// context_size - max_output_tokens (classified as prompt_too_long)
+var ErrPromptTooLong = errors.New("prompt too long")
+
+// TokenEstimator estimates the number of tokens in a piece of text
+type TokenEstimator func(text string) int
+
+// EstimateTokens is the default TokenEstimator: about 4 characters per token, rounded up
+// It is a heuristic, not a tokenizer; use SetTokenEstimator for exact counts
+func EstimateTokens(text string) int {
+	runes := len([]rune(text))
+	return (runes + charsPerToken - 1) / charsPerToken
+}
+
+// SetTokenEstimator replaces the estimator used by the prompt length guard (nil restores the default)
+func (c *Client) SetTokenEstimator(estimator TokenEstimator) {
+	c.tokenEstimator = estimator
+}
+
+func (c *Client) estimateTokens(text string) int {
+	if c.tokenEstimator != nil {
+		return c.tokenEstimator(text)
+	}
+	return EstimateTokens(text)
+}
+
+// EstimatePromptTokens estimates the prompt size of a chat request with the client's estimator
+func (c *Client) EstimatePromptTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += c.estimateTokens(msg.Content) + messageTokenOverhead
+	}
+	return total
+}
+
+// guardPromptLength checks the estimated prompt against the model's input budget
+// (context_size - max_output_tokens) and applies models.<name>.prompt_overflow:
+// warn (default) logs and sends the prompt anyway, error fails locally, truncate shortens the longest
+// message to fit, off skips the check
+// The returned messages are a copy when truncated; the caller's slice is never modified
+func (c *Client) guardPromptLength(modelCfg config.ModelConfig, messages []Message) ([]Message, error) {
+	if modelCfg.PromptOverflow == config.PromptOverflowOff || modelCfg.ContextSize <= 0 {
+		return messages, nil
+	}
+
+	budget := modelCfg.ContextSize - modelCfg.MaxOutputTokens
+	estimated := c.EstimatePromptTokens(messages)
+	if estimated <= budget {
+		return messages, nil
+	}
+
+	switch modelCfg.PromptOverflow {
+	case "", config.PromptOverflowWarn:
+		// The estimate is only ~4 characters/token, so let the server decide
+		c.logger.Warn("Prompt may exceed model input budget, sending anyway (set prompt_overflow = \"error\" to fail locally)",
+			"model", modelCfg.ModelName,
+			"estimated_tokens", estimated,
+			"budget", budget)
+		return messages, nil
+	case config.PromptOverflowError:
+		return nil, fmt.Errorf("%w: ~%d estimated tokens exceeds the %d token input budget of %s (context_size %d - max_output_tokens %d); shorten the template or raise context_size",
+			ErrPromptTooLong, estimated, budget, modelCfg.ModelName, modelCfg.ContextSize, modelCfg.MaxOutputTokens)
+	}
+
+	// Truncate the longest message, which is almost always the rendered template
+	longest := 0
+	for i, msg := range messages {
+		if len(msg.Content) > len(messages[longest].Content) {
+			longest = i
+		}
+	}
+	excess := estimated - budget
+	content := []rune(messages[longest].Content)
+	target := c.estimateTokens(string(content)) - excess
+	if target <= 0 {
+		return nil, fmt.Errorf("%w: ~%d estimated tokens exceeds the %d token input budget of %s and cannot be truncated to fit",
+			ErrPromptTooLong, estimated, budget, modelCfg.ModelName)
+	}
+
+	// Binary search for the longest prefix that fits (works with any estimator)
+	lo, hi := 0, len(content)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if c.estimateTokens(string(content[:mid])) <= target {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	c.logger.Warn("Prompt exceeds model input budget, truncating",
+		"model", modelCfg.ModelName,
+		"estimated_tokens", estimated,
+		"budget", budget,
+		"message_index", longest,
+		"role", messages[longest].Role,
+		"kept_chars", lo,
+		"dropped_chars", len(content)-lo)
+
+	truncated := make([]Message, len(messages))
+	copy(truncated, messages)
+	truncated[longest].Content = string(content[:lo])
+	return truncated, nil
+}
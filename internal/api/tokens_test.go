@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"日本語です", 2}, // Counted by rune, not byte
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q): expected %d, got %d", tt.text, tt.want, got)
+		}
+	}
+}
+
+func TestGuardPromptLength(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := NewClient(logger)
+
+	// Budget: 100 - 60 = 40 tokens
+	modelCfg := config.ModelConfig{ModelName: "test-model", ContextSize: 100, MaxOutputTokens: 60}
+	short := []Message{{Role: "user", Content: strings.Repeat("a", 40)}}
+	long := []Message{
+		{Role: "system", Content: "You are a writer."},
+		{Role: "user", Content: strings.Repeat("a", 400)},
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		got, err := client.guardPromptLength(modelCfg, short)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got[0].Content != short[0].Content {
+			t.Errorf("Expected prompt unchanged")
+		}
+	})
+
+	t.Run("warn by default", func(t *testing.T) {
+		got, err := client.guardPromptLength(modelCfg, long)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got[1].Content != long[1].Content {
+			t.Errorf("Expected the prompt to be sent unchanged")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		cfg := modelCfg
+		cfg.PromptOverflow = config.PromptOverflowError
+		_, err := client.guardPromptLength(cfg, long)
+		if !errors.Is(err, ErrPromptTooLong) {
+			t.Fatalf("Expected ErrPromptTooLong, got %v", err)
+		}
+		if ClassifyError(err) != ErrorClassPromptLength {
+			t.Errorf("Expected class %s, got %s", ErrorClassPromptLength, ClassifyError(err))
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		cfg := modelCfg
+		cfg.PromptOverflow = config.PromptOverflowTruncate
+		got, err := client.guardPromptLength(cfg, long)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if estimated := client.EstimatePromptTokens(got); estimated > 40 {
+			t.Errorf("Expected truncated prompt within 40 tokens, got %d", estimated)
+		}
+		if got[0].Content != long[0].Content {
+			t.Errorf("Expected system message unchanged, got %q", got[0].Content)
+		}
+		if len(got[1].Content) == 0 || len(got[1].Content) >= len(long[1].Content) {
+			t.Errorf("Expected user message to be shortened, got %d chars", len(got[1].Content))
+		}
+		if len(long[1].Content) != 400 {
+			t.Errorf("Expected caller's messages to be left untouched")
+		}
+	})
+
+	t.Run("off", func(t *testing.T) {
+		cfg := modelCfg
+		cfg.PromptOverflow = config.PromptOverflowOff
+		if _, err := client.guardPromptLength(cfg, long); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("custom estimator", func(t *testing.T) {
+		custom := NewClient(logger)
+		custom.SetTokenEstimator(func(text string) int { return len(text) }) // 1 char per token
+		cfg := modelCfg
+		cfg.PromptOverflow = config.PromptOverflowError
+		if _, err := custom.guardPromptLength(cfg, short); !errors.Is(err, ErrPromptTooLong) {
+			t.Errorf("Expected ErrPromptTooLong with custom estimator, got %v", err)
+		}
+	})
+}
+
+func TestChatCompletion_PromptTooLongNotSent(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := NewClient(logger)
+	modelCfg := config.ModelConfig{
+		BaseURL:            server.URL,
+		ModelName:          "test-model",
+		MaxOutputTokens:    60,
+		ContextSize:        100,
+		RateLimitPerMinute: 60,
+		HTTPTimeoutSeconds: 5,
+		PromptOverflow:     config.PromptOverflowError,
+	}
+	messages := []Message{{Role: "user", Content: strings.Repeat("a", 400)}}
+
+	_, err := client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+	if !errors.Is(err, ErrPromptTooLong) {
+		t.Fatalf("Expected ErrPromptTooLong, got %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected no request to be sent, got %d", n)
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// ItemsToolName is the function planning requests force the model to call with use_tool_calling
+const ItemsToolName = "return_items"
+
+// ItemsTool returns a strict function definition whose arguments are {"items": [...]},
+// each item matching itemSchema
+func ItemsTool(description string, itemSchema map[string]interface{}) Tool {
+	return Tool{
+		Type: "function",
+		Function: ToolFunction{
+			Name:        ItemsToolName,
+			Description: description,
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"items": map[string]interface{}{
+						"type":  "array",
+						"items": itemSchema,
+					},
+				},
+				"required":             []string{"items"},
+				"additionalProperties": false,
+			},
+			Strict: true,
+		},
+	}
+}
+
+// ToolArguments returns the arguments of the first tool call in the first choice
+// ("" when the model answered with plain content)
+func (r *ChatCompletionResponse) ToolArguments() string {
+	if len(r.Choices) == 0 || len(r.Choices[0].Message.ToolCalls) == 0 {
+		return ""
+	}
+	return r.Choices[0].Message.ToolCalls[0].Function.Arguments
+}
+
+// ChatCompletionWithTool is ChatCompletionStructured with the model forced to call tool
+// When the provider rejects the request (400 or 422), it is repeated in JSON mode and the
+// model is remembered so later requests go straight to JSON mode
+func (c *Client) ChatCompletionWithTool(
+	ctx context.Context,
+	modelCfg config.ModelConfig,
+	apiKey string,
+	messages []Message,
+	tool Tool,
+) (*ChatCompletionResponse, error) {
+	modelID := fmt.Sprintf("%s:%s", modelCfg.BaseURL, modelCfg.ModelName)
+	structured := c.structuredConfig(modelCfg)
+	structured.UseJSONMode = true
+
+	if _, rejected := c.toolsRejected.Load(modelID); !rejected {
+		resp, err := c.chatCompletion(ctx, structured, apiKey, messages, &tool)
+		if err == nil || !isToolRejection(err) {
+			return resp, err
+		}
+		if _, seen := c.toolsRejected.LoadOrStore(modelID, true); !seen {
+			c.logger.Warn("Provider rejected tool calling, falling back to JSON mode",
+				"model", modelCfg.ModelName,
+				"error", err)
+		}
+	}
+	return c.chatCompletion(ctx, structured, apiKey, messages, nil)
+}
+
+// isToolRejection reports whether err is the provider refusing the request itself,
+// which for a request that otherwise works in JSON mode means tools are unsupported
+func isToolRejection(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity
+}
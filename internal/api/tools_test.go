@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestChatCompletionWithTool(t *testing.T) {
+	tests := []struct {
+		name          string
+		rejectTools   bool
+		wantArguments string
+		wantContent   string
+		wantToolsSent []bool // tools present in each request, in order
+	}{
+		{
+			name:          "forced call",
+			wantArguments: `{"items":["a","b"]}`,
+			wantToolsSent: []bool{true, true},
+		},
+		{
+			name:          "tools rejected falls back to JSON mode",
+			rejectTools:   true,
+			wantContent:   `["a","b"]`,
+			wantToolsSent: []bool{true, false, false}, // The second call skips tools
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var toolsSent []bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req ChatCompletionRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("Failed to decode request: %v", err)
+				}
+				toolsSent = append(toolsSent, len(req.Tools) > 0)
+
+				if len(req.Tools) == 0 {
+					if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+						t.Errorf("Expected JSON mode without tools, got %+v", req.ResponseFormat)
+					}
+					_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"[\"a\",\"b\"]"}}]}`)
+					return
+				}
+				if tt.rejectTools {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = io.WriteString(w, `{"error":{"message":"tools are not supported","type":"invalid_request_error"}}`)
+					return
+				}
+				if req.ToolChoice == nil || req.ToolChoice.Function.Name != ItemsToolName || req.ResponseFormat != nil {
+					t.Errorf("Expected a forced %s call without response_format, got %+v / %+v", ItemsToolName, req.ToolChoice, req.ResponseFormat)
+				}
+				_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[`+
+					`{"id":"call_1","type":"function","function":{"name":"return_items","arguments":"{\"items\":[\"a\",\"b\"]}"}}]},`+
+					`"finish_reason":"tool_calls"}]}`)
+			}))
+			defer server.Close()
+
+			client := NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			client.SetRetryOptions(RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 6000,
+				HTTPTimeoutSeconds: 5,
+			}
+			messages := []Message{{Role: "user", Content: "List two things"}}
+			tool := ItemsTool("Return the items", map[string]interface{}{"type": "string"})
+
+			for i := 0; i < 2; i++ {
+				resp, err := client.ChatCompletionWithTool(context.Background(), modelCfg, "test-key", messages, tool)
+				if err != nil {
+					t.Fatalf("Call %d: ChatCompletionWithTool returned error: %v", i, err)
+				}
+				if got := resp.ToolArguments(); got != tt.wantArguments {
+					t.Errorf("Call %d: expected tool arguments %q, got %q", i, tt.wantArguments, got)
+				}
+				if got := resp.Choices[0].Message.Content; got != tt.wantContent {
+					t.Errorf("Call %d: expected content %q, got %q", i, tt.wantContent, got)
+				}
+			}
+
+			if len(toolsSent) != len(tt.wantToolsSent) {
+				t.Fatalf("Expected %d requests, got %d", len(tt.wantToolsSent), len(toolsSent))
+			}
+			for i, want := range tt.wantToolsSent {
+				if toolsSent[i] != want {
+					t.Errorf("Request %d: expected tools sent %v, got %v", i, want, toolsSent[i])
+				}
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package api
 
+import "encoding/json"
+
 // ChatCompletionRequest represents an OpenAI-compatible chat completion request
 type ChatCompletionRequest struct {
 	Model          string          `json:"model"`
@@ -10,6 +12,13 @@ type ChatCompletionRequest struct {
 	N              int             `json:"n,omitempty"`
 	Stop           []string        `json:"stop,omitempty"`
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     *ToolChoice     `json:"tool_choice,omitempty"`
+
+	ExtraBody         map[string]interface{} `json:"-"` // Provider-specific fields merged into the JSON body (models.<name>.extra_body)
+	ExtraBodyOverride bool                   `json:"-"` // Let ExtraBody replace core fields (except model, messages, stream)
+	FieldMap          map[string]string      `json:"-"` // Top-level fields renamed before sending (models.<name>.field_map)
+	FieldOmit         []string               `json:"-"` // Top-level fields dropped before sending (models.<name>.field_omit)
 }
 
 // ResponseFormat specifies the format of the model's output
@@ -17,11 +26,65 @@ type ResponseFormat struct {
 	Type string `json:"type"` // "text" or "json_object"
 }
 
+// Tool is a function the model can call (OpenAI function calling)
+type Tool struct {
+	Type     string       `json:"type"` // "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and the JSON schema of its arguments
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+	Strict      bool                   `json:"strict,omitempty"` // Arguments must match Parameters exactly
+}
+
+// ToolChoice forces the model to call one specific function
+type ToolChoice struct {
+	Type     string             `json:"type"` // "function"
+	Function ToolChoiceFunction `json:"function"`
+}
+
+// ToolChoiceFunction names the function a ToolChoice forces
+type ToolChoiceFunction struct {
+	Name string `json:"name"`
+}
+
+// ToolCall is a function call made by the model in an assistant message
+type ToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON-encoded arguments
+	} `json:"function"`
+}
+
 // Message represents a single message in the chat
 type Message struct {
-	Role             string `json:"role"`
-	Content          string `json:"content"`
-	ReasoningContent string `json:"reasoning_content,omitempty"` // For reasoning models (e.g., Kimi-K2-Thinking)
+	Role             string     `json:"role"`
+	Content          string     `json:"content"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"` // For reasoning models (e.g., Kimi-K2-Thinking)
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// UnmarshalJSON decodes a message and fills ReasoningContent from the "reasoning" field
+// some providers (OpenRouter, newer vLLM) use instead of "reasoning_content"
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message // avoids recursing into this method
+	var raw struct {
+		message
+		Reasoning string `json:"reasoning"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.message)
+	if m.ReasoningContent == "" {
+		m.ReasoningContent = raw.Reasoning
+	}
+	return nil
 }
 
 // ChatCompletionResponse represents an OpenAI-compatible chat completion response
@@ -32,6 +95,10 @@ type ChatCompletionResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+	// Backend configuration identifier (OpenAI); changes when the provider updates the model deployment
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	// HTTP requests the client made for this response, set by ChatCompletion (1 = no retries)
+	Attempts int `json:"-"`
 }
 
 // Choice represents a single completion choice
@@ -0,0 +1,36 @@
+package api
+
+import "github.com/lamim/vellumforge2/internal/config"
+
+// userAgentProduct is the product token of the default User-Agent
+const userAgentProduct = "VellumForge2"
+
+// DefaultUserAgent returns the User-Agent sent when network.user_agent is unset
+func DefaultUserAgent(version string) string {
+	if version == "" {
+		version = "dev"
+	}
+	return userAgentProduct + "/" + version
+}
+
+// userAgentOrDefault returns ua, or the default User-Agent of a dev build when it is empty
+func userAgentOrDefault(ua string) string {
+	if ua == "" {
+		return DefaultUserAgent("")
+	}
+	return ua
+}
+
+// SetUserAgent sets the User-Agent for models without their own user_agent
+// Empty falls back to DefaultUserAgent of a dev build
+func (c *Client) SetUserAgent(ua string) {
+	c.userAgent = userAgentOrDefault(ua)
+}
+
+// userAgentFor returns the User-Agent to send to a model's provider
+func (c *Client) userAgentFor(modelCfg config.ModelConfig) string {
+	if modelCfg.UserAgent != "" {
+		return modelCfg.UserAgent
+	}
+	return c.userAgent
+}
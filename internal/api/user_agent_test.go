@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestDefaultUserAgent(t *testing.T) {
+	if got := DefaultUserAgent("1.2.3"); got != "VellumForge2/1.2.3" {
+		t.Errorf("Expected VellumForge2/1.2.3, got %q", got)
+	}
+	if got := DefaultUserAgent(""); got != "VellumForge2/dev" {
+		t.Errorf("Expected VellumForge2/dev, got %q", got)
+	}
+}
+
+func TestChatCompletion_UserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		network   string
+		model     string
+		streaming bool
+		want      string
+	}{
+		{"default", "", "", false, "VellumForge2/dev"},
+		{"network override", "my-pipeline/2.0", "", false, "my-pipeline/2.0"},
+		{"model override", "my-pipeline/2.0", "gateway-client/1", false, "gateway-client/1"},
+		{"streaming", "my-pipeline/2.0", "", true, "my-pipeline/2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("User-Agent"); got != tt.want {
+					t.Errorf("Expected User-Agent %q, got %q", tt.want, got)
+				}
+				if tt.streaming {
+					w.Header().Set("Content-Type", "text/event-stream")
+					_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"model\":\"test-model\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+					return
+				}
+				_, _ = io.WriteString(w, `{"id":"1","model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+			}))
+			defer server.Close()
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			client := NewClientWithNetwork(logger, config.NetworkConfig{UserAgent: tt.network})
+			modelCfg := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				Temperature:        0.7,
+				MaxOutputTokens:    100,
+				ContextSize:        1000,
+				RateLimitPerMinute: 600,
+				HTTPTimeoutSeconds: 5,
+				UserAgent:          tt.model,
+			}
+			messages := []Message{{Role: "user", Content: "hi"}}
+
+			var err error
+			if tt.streaming {
+				_, err = client.ChatCompletionStreaming(context.Background(), modelCfg, "test-key", messages)
+			} else {
+				_, err = client.ChatCompletion(context.Background(), modelCfg, "test-key", messages)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
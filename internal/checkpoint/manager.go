@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -115,6 +118,16 @@ func (m *Manager) writeCheckpointToDisk(cp *models.Checkpoint) error {
 	m.writeMu.Lock()
 	defer m.writeMu.Unlock()
 
+	if err := writeCheckpointFile(m.sessionDir, cp); err != nil {
+		return err
+	}
+
+	m.logger.Debug("Checkpoint saved", "path", filepath.Join(m.sessionDir, CheckpointFilename), "phase", cp.CurrentPhase)
+	return nil
+}
+
+// writeCheckpointFile atomically replaces the checkpoint in sessionDir
+func writeCheckpointFile(sessionDir string, cp *models.Checkpoint) error {
 	// Marshal to JSON
 	data, err := json.MarshalIndent(cp, "", "  ")
 	if err != nil {
@@ -122,7 +135,7 @@ func (m *Manager) writeCheckpointToDisk(cp *models.Checkpoint) error {
 	}
 
 	// Atomic write: write to temp file, then rename
-	checkpointPath := filepath.Join(m.sessionDir, CheckpointFilename)
+	checkpointPath := filepath.Join(sessionDir, CheckpointFilename)
 	tempPath := checkpointPath + ".tmp"
 
 	if err := os.WriteFile(tempPath, data, 0644); err != nil {
@@ -132,8 +145,6 @@ func (m *Manager) writeCheckpointToDisk(cp *models.Checkpoint) error {
 	if err := os.Rename(tempPath, checkpointPath); err != nil {
 		return fmt.Errorf("failed to rename checkpoint: %w", err)
 	}
-
-	m.logger.Debug("Checkpoint saved", "path", checkpointPath, "phase", cp.CurrentPhase)
 	return nil
 }
 
@@ -183,10 +194,13 @@ func (m *Manager) copyCheckpoint() *models.Checkpoint {
 		CurrentPhase:      m.checkpoint.CurrentPhase,
 		SubtopicsComplete: m.checkpoint.SubtopicsComplete,
 		Subtopics:         append([]string{}, m.checkpoint.Subtopics...),
+		SubtopicWeights:   maps.Clone(m.checkpoint.SubtopicWeights),
 		PromptsComplete:   m.checkpoint.PromptsComplete,
 		Prompts:           append([]models.GenerationJob{}, m.checkpoint.Prompts...),
+		TopicSubtopics:    maps.Clone(m.checkpoint.TopicSubtopics),
+		PromptTopicsDone:  slices.Clone(m.checkpoint.PromptTopicsDone),
 		CompletedJobIDs:   make(map[int]bool, len(m.checkpoint.CompletedJobIDs)),
-		Stats:             m.checkpoint.Stats,
+		Stats:             copyStats(&m.checkpoint.Stats),
 		ConfigHash:        m.checkpoint.ConfigHash,
 	}
 	for k, v := range m.checkpoint.CompletedJobIDs {
@@ -195,6 +209,15 @@ func (m *Manager) copyCheckpoint() *models.Checkpoint {
 	return cp
 }
 
+// copyStats copies session stats, including the error breakdown map, so the
+// checkpoint never shares the map the orchestrator keeps updating
+func copyStats(stats *models.SessionStats) models.SessionStats {
+	statsCopy := *stats
+	statsCopy.ErrorCounts = maps.Clone(stats.ErrorCounts)
+	statsCopy.SystemFingerprints = maps.Clone(stats.SystemFingerprints)
+	return statsCopy
+}
+
 // Load reads checkpoint from disk
 func Load(sessionDir string, logger *slog.Logger) (*models.Checkpoint, error) {
 	checkpointPath := filepath.Join(sessionDir, CheckpointFilename)
@@ -218,16 +241,48 @@ func Load(sessionDir string, logger *slog.Logger) (*models.Checkpoint, error) {
 }
 
 // MarkSubtopicsComplete saves subtopics phase completion
-func (m *Manager) MarkSubtopicsComplete(subtopics []string) error {
+// weights holds the prompt weights of weighted subtopics (nil = all weight 1)
+func (m *Manager) MarkSubtopicsComplete(subtopics []string, weights map[string]int) error {
 	m.mu.Lock()
 	m.checkpoint.SubtopicsComplete = true
 	m.checkpoint.Subtopics = subtopics
+	m.checkpoint.SubtopicWeights = weights
 	m.checkpoint.CurrentPhase = models.PhasePrompts
 	m.mu.Unlock()
 
 	return m.SaveSync() // Use sync for phase transitions
 }
 
+// MarkTopicSubtopicsComplete saves the subtopics of one main topic (generation.main_topics)
+// so a resumed run only generates subtopics for the remaining topics
+func (m *Manager) MarkTopicSubtopicsComplete(topic string, subtopics []string, weights map[string]int) error {
+	m.mu.Lock()
+	if m.checkpoint.TopicSubtopics == nil {
+		m.checkpoint.TopicSubtopics = make(map[string][]string)
+	}
+	m.checkpoint.TopicSubtopics[topic] = subtopics
+	for key, weight := range weights {
+		if m.checkpoint.SubtopicWeights == nil {
+			m.checkpoint.SubtopicWeights = make(map[string]int)
+		}
+		m.checkpoint.SubtopicWeights[key] = weight
+	}
+	m.mu.Unlock()
+
+	return m.SaveSync()
+}
+
+// MarkTopicPromptsComplete appends the jobs of one main topic (generation.main_topics)
+// so a resumed run only generates prompts for the remaining topics
+func (m *Manager) MarkTopicPromptsComplete(topic string, jobs []models.GenerationJob) error {
+	m.mu.Lock()
+	m.checkpoint.Prompts = append(m.checkpoint.Prompts, jobs...)
+	m.checkpoint.PromptTopicsDone = append(m.checkpoint.PromptTopicsDone, topic)
+	m.mu.Unlock()
+
+	return m.SaveSync()
+}
+
 // MarkPromptsComplete saves prompts phase completion
 func (m *Manager) MarkPromptsComplete(jobs []models.GenerationJob) error {
 	m.mu.Lock()
@@ -247,7 +302,7 @@ func (m *Manager) MarkJobComplete(jobID int, stats *models.SessionStats) error {
 
 	m.mu.Lock()
 	m.checkpoint.CompletedJobIDs[jobID] = true
-	m.checkpoint.Stats = *stats
+	m.checkpoint.Stats = copyStats(stats)
 	m.jobCounter++
 	shouldSave := m.jobCounter >= m.interval
 	if shouldSave {
@@ -265,7 +320,7 @@ func (m *Manager) MarkJobComplete(jobID int, stats *models.SessionStats) error {
 func (m *Manager) MarkComplete(stats *models.SessionStats) error {
 	m.mu.Lock()
 	m.checkpoint.CurrentPhase = models.PhaseComplete
-	m.checkpoint.Stats = *stats
+	m.checkpoint.Stats = copyStats(stats)
 	m.mu.Unlock()
 
 	return m.SaveSync() // Use sync for final checkpoint
@@ -296,8 +351,9 @@ func (m *Manager) Close() error {
 
 func computeConfigHash(cfg *config.Config) string {
 	// Hash critical config fields that affect generation
+	// Single-topic sessions hash main_topic alone so their existing checkpoints still match
 	data := fmt.Sprintf("%s:%d:%d",
-		cfg.Generation.MainTopic,
+		strings.Join(cfg.Generation.Topics(), "\x00"),
 		cfg.Generation.NumSubtopics,
 		cfg.Generation.NumPromptsPerSubtopic)
 	hash := sha256.Sum256([]byte(data))
@@ -71,7 +71,7 @@ func TestSaveAndLoad(t *testing.T) {
 
 	// Mark subtopics complete
 	subtopics := []string{"topic1", "topic2", "topic3"}
-	if err := mgr.MarkSubtopicsComplete(subtopics); err != nil {
+	if err := mgr.MarkSubtopicsComplete(subtopics, nil); err != nil {
 		t.Fatalf("MarkSubtopicsComplete failed: %v", err)
 	}
 
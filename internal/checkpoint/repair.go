@@ -0,0 +1,276 @@
+package checkpoint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// BackupFilename is where Repair keeps the checkpoint it replaced
+const BackupFilename = CheckpointFilename + ".bak"
+
+// RepairReport describes what Repair found in a session and what it changed
+type RepairReport struct {
+	DatasetRows     int              // Complete rows in the main dataset
+	UnparseableRows int              // Lines that are not JSON or carry no prompt (left in place)
+	UnmatchedRows   int              // Rows whose prompt matches no job in the checkpoint, or extra rows beyond what the jobs sharing it write
+	TrimmedFiles    map[string]int64 // Bytes of a partial trailing line cut from each dataset file
+	NewlineAdded    []string         // Dataset files whose last complete row lacked a trailing newline
+	MarkedComplete  []int            // Jobs found in the dataset but missing from the checkpoint
+	MarkedPending   []int            // Jobs the checkpoint counted whose rows never reached the dataset
+	PhaseReopened   bool             // Checkpoint said complete but jobs were missing, so it can be resumed again
+}
+
+// Changed reports whether the session needed any repair
+func (r *RepairReport) Changed() bool {
+	return len(r.TrimmedFiles) > 0 || len(r.NewlineAdded) > 0 ||
+		len(r.MarkedComplete) > 0 || len(r.MarkedPending) > 0 || r.PhaseReopened
+}
+
+// Repair reconciles a session's checkpoint with the rows actually on disk after a hard kill
+// Rows are matched to jobs by prompt text, compared after Unicode normalization so that
+// generation.normalize_unicode does not hide rows, and each job accounts for the rows gen says it
+// writes (two or more in KTO and multi-rejected DPO): jobs with rows become completed, completed jobs
+// without rows (e.g. a lost MO-DPO buffer) become pending again, and a partial last line left by an
+// interrupted write is cut so resumed appends start on a clean line
+// datasetPath is the main dataset; reasoningPath (optional) only has its tail repaired
+// gen is the session's generation config; the replaced checkpoint is kept as checkpoint.json.bak
+// With dryRun nothing is written
+func Repair(sessionDir, datasetPath, reasoningPath string, gen config.GenerationConfig, dryRun bool, logger *slog.Logger) (*RepairReport, error) {
+	cp, err := Load(sessionDir, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RepairReport{TrimmedFiles: make(map[string]int64)}
+	scan, err := scanDataset(datasetPath, dryRun, report)
+	if err != nil {
+		return nil, err
+	}
+	report.DatasetRows = scan.rows
+	report.UnparseableRows = scan.unparseable
+	if reasoningPath != "" {
+		if _, err := scanDataset(reasoningPath, dryRun, report); err != nil {
+			return nil, err
+		}
+	}
+
+	reconcileJobs(cp, scan.prompts, gen.RowsPerJob, report)
+	if !report.Changed() || dryRun {
+		return report, nil
+	}
+
+	if len(report.MarkedComplete) > 0 || len(report.MarkedPending) > 0 || report.PhaseReopened {
+		checkpointPath := filepath.Join(sessionDir, CheckpointFilename)
+		original, err := os.ReadFile(checkpointPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint for backup: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(sessionDir, BackupFilename), original, 0644); err != nil {
+			return nil, fmt.Errorf("failed to back up checkpoint: %w", err)
+		}
+		if err := writeCheckpointFile(sessionDir, cp); err != nil {
+			return nil, err
+		}
+		logger.Info("Repaired checkpoint written",
+			"path", checkpointPath,
+			"backup", BackupFilename,
+			"completed_jobs", len(cp.CompletedJobIDs))
+	}
+
+	return report, nil
+}
+
+// promptMatchKey is the form prompts are compared in: NFKC, which both normalize_unicode
+// forms (and unnormalized text) map to
+func promptMatchKey(prompt string) string {
+	return norm.NFKC.String(prompt)
+}
+
+// reconcileJobs rewrites cp.CompletedJobIDs to the jobs whose prompts have rows on disk
+// Rows are a multiset: a job claims up to rowsPerJob of the rows with its prompt (fewer when the
+// record filter skipped some), so when several jobs share a prompt only as many count as completed
+// as the rows cover, and jobs the checkpoint already counted claim the rows first
+func reconcileJobs(cp *models.Checkpoint, prompts map[string]int, rowsPerJob func(jobID int) int, report *RepairReport) {
+	if cp.CompletedJobIDs == nil {
+		cp.CompletedJobIDs = make(map[int]bool)
+	}
+
+	remaining := make(map[string]int, len(prompts))
+	for prompt, rows := range prompts {
+		remaining[promptMatchKey(prompt)] += rows
+	}
+	claim := func(job models.GenerationJob) bool {
+		key := promptMatchKey(job.Prompt)
+		if remaining[key] == 0 {
+			return false
+		}
+		remaining[key] -= min(remaining[key], max(rowsPerJob(job.ID), 1))
+		return true
+	}
+
+	for _, job := range cp.Prompts {
+		if cp.CompletedJobIDs[job.ID] && !claim(job) {
+			delete(cp.CompletedJobIDs, job.ID)
+			report.MarkedPending = append(report.MarkedPending, job.ID)
+		}
+	}
+	for _, job := range cp.Prompts {
+		if !cp.CompletedJobIDs[job.ID] && claim(job) {
+			cp.CompletedJobIDs[job.ID] = true
+			report.MarkedComplete = append(report.MarkedComplete, job.ID)
+		}
+	}
+	for _, rows := range remaining {
+		report.UnmatchedRows += rows
+	}
+	slices.Sort(report.MarkedComplete)
+	slices.Sort(report.MarkedPending)
+
+	if len(report.MarkedComplete) > 0 || len(report.MarkedPending) > 0 {
+		cp.Stats.SuccessCount = len(cp.CompletedJobIDs)
+	}
+	if cp.CurrentPhase == models.PhaseComplete && len(cp.CompletedJobIDs) < len(cp.Prompts) {
+		cp.CurrentPhase = models.PhasePairs
+		report.PhaseReopened = true
+	}
+}
+
+// datasetScan is the row count of a dataset file, per prompt and in total
+type datasetScan struct {
+	prompts     map[string]int
+	rows        int
+	unparseable int
+}
+
+// scanDataset counts rows per prompt in a JSONL dataset and repairs its tail unless dryRun is set:
+// an unparseable last line without a newline is truncated, a valid one gets its newline
+// Tail fixes are recorded in report; a missing file is treated as empty (nothing was flushed before the kill)
+func scanDataset(path string, dryRun bool, report *RepairReport) (*datasetScan, error) {
+	scan := &datasetScan{prompts: make(map[string]int)}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return scan, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			complete := line[len(line)-1] == '\n'
+			prompt, ok := rowPrompt(bytes.TrimSpace(line))
+			switch {
+			case !complete && !ok:
+				// Partial write cut off by the kill
+				report.TrimmedFiles[path] = int64(len(line))
+				if !dryRun {
+					if err := os.Truncate(path, offset); err != nil {
+						return nil, fmt.Errorf("failed to truncate partial row: %w", err)
+					}
+				}
+			case !ok:
+				if len(bytes.TrimSpace(line)) > 0 {
+					scan.unparseable++
+				}
+			default:
+				scan.rows++
+				scan.prompts[prompt]++
+				if !complete {
+					report.NewlineAdded = append(report.NewlineAdded, path)
+					if !dryRun {
+						if err := appendNewline(path); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+			offset += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dataset: %w", err)
+		}
+	}
+	return scan, nil
+}
+
+func appendNewline(path string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dataset for append: %w", err)
+	}
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to terminate last row: %w", err)
+	}
+	return file.Close()
+}
+
+// datasetRow holds the prompt-bearing fields of every dataset format the pipeline writes
+type datasetRow struct {
+	Prompt        json.RawMessage          `json:"prompt"`        // DPO, KTO, MO-DPO (string or message list)
+	Instruction   string                   `json:"instruction"`   // SFT alpaca
+	Conversations []models.ShareGPTMessage `json:"conversations"` // SFT sharegpt
+	Messages      []models.OpenAIMessage   `json:"messages"`      // SFT openai
+}
+
+// rowPrompt extracts the job prompt written into a dataset row
+func rowPrompt(line []byte) (string, bool) {
+	if len(line) == 0 {
+		return "", false
+	}
+	var row datasetRow
+	if err := json.Unmarshal(line, &row); err != nil {
+		return "", false
+	}
+
+	if len(row.Prompt) > 0 {
+		var prompt string
+		if err := json.Unmarshal(row.Prompt, &prompt); err == nil {
+			return prompt, prompt != ""
+		}
+		var messages []models.OpenAIMessage
+		if err := json.Unmarshal(row.Prompt, &messages); err == nil {
+			return lastUserMessage(messages)
+		}
+		return "", false
+	}
+	if row.Instruction != "" {
+		return row.Instruction, true
+	}
+	for _, msg := range row.Conversations {
+		if from := strings.ToLower(msg.From); from == "human" || from == "user" {
+			return msg.Value, true
+		}
+	}
+	return lastUserMessage(row.Messages)
+}
+
+func lastUserMessage(messages []models.OpenAIMessage) (string, bool) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if strings.ToLower(messages[i].Role) == "user" {
+			return messages[i].Content, true
+		}
+	}
+	return "", false
+}
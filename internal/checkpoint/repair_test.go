@@ -0,0 +1,283 @@
+package checkpoint
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// dpoSession is the generation config of a single-rejected DPO session (one row per job)
+var dpoSession = config.GenerationConfig{DatasetMode: models.DatasetModeDPO, NumRejected: 1}
+
+func writeRepairSession(t *testing.T, cp *models.Checkpoint, dataset string) (string, string) {
+	t.Helper()
+	sessionDir := t.TempDir()
+	if err := writeCheckpointFile(sessionDir, cp); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+	datasetPath := filepath.Join(sessionDir, "dataset.jsonl")
+	if err := os.WriteFile(datasetPath, []byte(dataset), 0644); err != nil {
+		t.Fatalf("Failed to write dataset: %v", err)
+	}
+	return sessionDir, datasetPath
+}
+
+func repairCheckpointFixture(phase models.CheckpointPhase, completed ...int) *models.Checkpoint {
+	cp := &models.Checkpoint{
+		SessionID:       "test",
+		CurrentPhase:    phase,
+		PromptsComplete: true,
+		Prompts: []models.GenerationJob{
+			{ID: 0, Prompt: "A dragon"},
+			{ID: 1, Prompt: "A knight"},
+			{ID: 2, Prompt: "A castle"},
+		},
+		CompletedJobIDs: make(map[int]bool),
+	}
+	for _, id := range completed {
+		cp.CompletedJobIDs[id] = true
+	}
+	cp.Stats.SuccessCount = len(completed)
+	return cp
+}
+
+func TestRepair(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Job 0 in the checkpoint and file, job 1 in the file only, job 2 in the checkpoint only,
+	// plus a partial row cut off mid-write
+	cp := repairCheckpointFixture(models.PhaseComplete, 0, 2)
+	dataset := `{"prompt":"A dragon","chosen":"c","rejected":"r"}` + "\n" +
+		`{"prompt":"A knight","chosen":"c","rejected":"r"}` + "\n" +
+		`{"prompt":"A cas`
+	sessionDir, datasetPath := writeRepairSession(t, cp, dataset)
+
+	report, err := Repair(sessionDir, datasetPath, "", dpoSession, false, logger)
+	if err != nil {
+		t.Fatalf("Repair returned unexpected error: %v", err)
+	}
+
+	if report.DatasetRows != 2 {
+		t.Errorf("Expected 2 dataset rows, got %d", report.DatasetRows)
+	}
+	if len(report.MarkedComplete) != 1 || report.MarkedComplete[0] != 1 {
+		t.Errorf("Expected job 1 marked completed, got %v", report.MarkedComplete)
+	}
+	if len(report.MarkedPending) != 1 || report.MarkedPending[0] != 2 {
+		t.Errorf("Expected job 2 marked pending, got %v", report.MarkedPending)
+	}
+	if report.TrimmedFiles[datasetPath] != int64(len(`{"prompt":"A cas`)) {
+		t.Errorf("Expected partial row trimmed, got %v", report.TrimmedFiles)
+	}
+	if !report.PhaseReopened {
+		t.Error("Expected complete phase to be reopened")
+	}
+
+	repaired, err := Load(sessionDir, logger)
+	if err != nil {
+		t.Fatalf("Failed to load repaired checkpoint: %v", err)
+	}
+	if !repaired.CompletedJobIDs[0] || !repaired.CompletedJobIDs[1] || repaired.CompletedJobIDs[2] {
+		t.Errorf("Expected jobs 0 and 1 completed, got %v", repaired.CompletedJobIDs)
+	}
+	if repaired.CurrentPhase != models.PhasePairs || repaired.Stats.SuccessCount != 2 {
+		t.Errorf("Expected phase pairs with 2 successes, got %s / %d", repaired.CurrentPhase, repaired.Stats.SuccessCount)
+	}
+	if pending := GetPendingJobs(repaired); len(pending) != 1 || pending[0].ID != 2 {
+		t.Errorf("Expected only job 2 pending, got %v", pending)
+	}
+
+	data, err := os.ReadFile(datasetPath)
+	if err != nil {
+		t.Fatalf("Failed to read dataset: %v", err)
+	}
+	if want := dataset[:len(dataset)-len(`{"prompt":"A cas`)]; string(data) != want {
+		t.Errorf("Expected dataset truncated to %q, got %q", want, string(data))
+	}
+	if _, err := os.Stat(filepath.Join(sessionDir, BackupFilename)); err != nil {
+		t.Errorf("Expected checkpoint backup, got %v", err)
+	}
+
+	// A second pass finds nothing to do
+	again, err := Repair(sessionDir, datasetPath, "", dpoSession, false, logger)
+	if err != nil {
+		t.Fatalf("Second Repair returned unexpected error: %v", err)
+	}
+	if again.Changed() {
+		t.Errorf("Expected repaired session to be consistent, got %+v", again)
+	}
+}
+
+func TestRepair_NormalizedAndRepeatedPrompts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Job 0's prompt was written NFC-normalized (normalize_unicode = "nfc"); jobs 1-3 share a
+	// prompt but only two of their rows reached the dataset
+	cp := &models.Checkpoint{
+		SessionID:       "test",
+		CurrentPhase:    models.PhasePairs,
+		PromptsComplete: true,
+		Prompts: []models.GenerationJob{
+			{ID: 0, Prompt: "Un cafe\u0301 au dragon"},
+			{ID: 1, Prompt: "A knight"},
+			{ID: 2, Prompt: "A knight"},
+			{ID: 3, Prompt: "A knight"},
+		},
+		CompletedJobIDs: map[int]bool{0: true, 1: true, 3: true},
+	}
+	dataset := `{"prompt":"Un caf\u00e9 au dragon","chosen":"c","rejected":"r"}` + "\n" +
+		`{"prompt":"A knight","chosen":"c","rejected":"r"}` + "\n" +
+		`{"prompt":"A knight","chosen":"c","rejected":"r"}` + "\n"
+	sessionDir, datasetPath := writeRepairSession(t, cp, dataset)
+
+	report, err := Repair(sessionDir, datasetPath, "", dpoSession, true, logger)
+	if err != nil {
+		t.Fatalf("Repair returned unexpected error: %v", err)
+	}
+	if report.Changed() || report.UnmatchedRows != 0 {
+		t.Errorf("Expected the checkpoint to match the dataset, got %+v", report)
+	}
+
+	// A third completed job sharing the prompt has no row left to claim
+	cp.CompletedJobIDs[2] = true
+	sessionDir, datasetPath = writeRepairSession(t, cp, dataset)
+	report, err = Repair(sessionDir, datasetPath, "", dpoSession, true, logger)
+	if err != nil {
+		t.Fatalf("Repair returned unexpected error: %v", err)
+	}
+	if len(report.MarkedPending) != 1 || len(report.MarkedComplete) != 0 {
+		t.Errorf("Expected one of the shared-prompt jobs marked pending, got %+v", report)
+	}
+}
+
+func TestRepair_SeveralRowsPerJob(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		name string
+		gen  config.GenerationConfig
+		row  string
+	}{
+		{"kto", config.GenerationConfig{DatasetMode: models.DatasetModeKTO, NumRejected: 1}, `{"prompt":%q,"completion":"c","label":true}`},
+		{"kto ratio", config.GenerationConfig{DatasetMode: models.DatasetModeKTO, KTORatio: 2}, `{"prompt":%q,"completion":"c","label":false}`},
+		{"multi-rejected rows", config.GenerationConfig{DatasetMode: models.DatasetModeDPO, NumRejected: 3, RejectedOutputShape: models.RejectedShapeRows}, `{"prompt":%q,"chosen":"c","rejected":"r"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Jobs 0 and 1 share a prompt but only job 0 finished; job 2 finished after the last checkpoint save
+			cp := repairCheckpointFixture(models.PhasePairs, 0)
+			cp.Prompts[1].Prompt = cp.Prompts[0].Prompt
+			var dataset strings.Builder
+			for _, job := range []models.GenerationJob{cp.Prompts[0], cp.Prompts[2]} {
+				for range tt.gen.RowsPerJob(job.ID) {
+					dataset.WriteString(fmt.Sprintf(tt.row, job.Prompt) + "\n")
+				}
+			}
+			sessionDir, datasetPath := writeRepairSession(t, cp, dataset.String())
+
+			report, err := Repair(sessionDir, datasetPath, "", tt.gen, true, logger)
+			if err != nil {
+				t.Fatalf("Repair returned unexpected error: %v", err)
+			}
+			if len(report.MarkedComplete) != 1 || report.MarkedComplete[0] != 2 {
+				t.Errorf("Expected only job 2 marked completed, got %v", report.MarkedComplete)
+			}
+			if len(report.MarkedPending) != 0 {
+				t.Errorf("Expected no jobs marked pending, got %v", report.MarkedPending)
+			}
+			if report.UnmatchedRows != 0 {
+				t.Errorf("Expected every row matched to a job, got %d unmatched", report.UnmatchedRows)
+			}
+
+			// Read with one row per job, job 0's extra rows would complete job 1
+			report, err = Repair(sessionDir, datasetPath, "", dpoSession, true, logger)
+			if err != nil {
+				t.Fatalf("Repair returned unexpected error: %v", err)
+			}
+			if !slices.Contains(report.MarkedComplete, 1) {
+				t.Errorf("Expected the one-row-per-job reading to claim job 1, got %v", report.MarkedComplete)
+			}
+		})
+	}
+}
+
+func TestRepair_DryRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cp := repairCheckpointFixture(models.PhasePairs, 0, 1, 2)
+	dataset := `{"prompt":"A dragon","completion":"c","label":true}` + "\n" + `{"prompt":"A kn`
+	sessionDir, datasetPath := writeRepairSession(t, cp, dataset)
+
+	report, err := Repair(sessionDir, datasetPath, "", dpoSession, true, logger)
+	if err != nil {
+		t.Fatalf("Repair returned unexpected error: %v", err)
+	}
+	if len(report.MarkedPending) != 2 || len(report.TrimmedFiles) != 1 {
+		t.Errorf("Expected 2 pending jobs and a trimmed file, got %+v", report)
+	}
+
+	data, _ := os.ReadFile(datasetPath)
+	if string(data) != dataset {
+		t.Error("Expected dry run to leave the dataset untouched")
+	}
+	unchanged, err := Load(sessionDir, logger)
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if len(unchanged.CompletedJobIDs) != 3 {
+		t.Errorf("Expected dry run to leave the checkpoint untouched, got %v", unchanged.CompletedJobIDs)
+	}
+	if _, err := os.Stat(filepath.Join(sessionDir, BackupFilename)); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup on dry run, got %v", err)
+	}
+}
+
+func TestRepair_MissingDatasetNewline(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cp := repairCheckpointFixture(models.PhasePairs, 0)
+	dataset := `{"prompt":"A dragon","chosen":"c","rejected":"r"}`
+	sessionDir, datasetPath := writeRepairSession(t, cp, dataset)
+
+	report, err := Repair(sessionDir, datasetPath, "", dpoSession, false, logger)
+	if err != nil {
+		t.Fatalf("Repair returned unexpected error: %v", err)
+	}
+	if len(report.NewlineAdded) != 1 || len(report.MarkedComplete)+len(report.MarkedPending) != 0 {
+		t.Errorf("Expected only a newline fix, got %+v", report)
+	}
+	data, _ := os.ReadFile(datasetPath)
+	if string(data) != dataset+"\n" {
+		t.Errorf("Expected newline appended, got %q", string(data))
+	}
+}
+
+func TestRowPrompt(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+		ok   bool
+	}{
+		{"dpo string", `{"prompt":"A dragon","chosen":"c","rejected":"r"}`, "A dragon", true},
+		{"conversational dpo", `{"prompt":[{"role":"system","content":"s"},{"role":"user","content":"A dragon"}]}`, "A dragon", true},
+		{"alpaca", `{"instruction":"A dragon","input":"","output":"o"}`, "A dragon", true},
+		{"sharegpt", `{"conversations":[{"from":"system","value":"s"},{"from":"human","value":"A dragon"},{"from":"gpt","value":"o"}]}`, "A dragon", true},
+		{"openai", `{"messages":[{"role":"user","content":"A dragon"},{"role":"assistant","content":"o"}]}`, "A dragon", true},
+		{"no prompt", `{"chosen":"c"}`, "", false},
+		{"not json", `{"prompt":`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rowPrompt([]byte(tt.line))
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Expected (%q, %t), got (%q, %t)", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package checkpoint
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// PrepareReplay reopens a finished session so only its missing jobs (failed or filtered) are
+// regenerated, returning those jobs. Failure counts are reset since the failed jobs are retried.
+// Nothing is written when no jobs are missing or with dryRun
+func PrepareReplay(sessionDir string, cfg *config.Config, dryRun bool, logger *slog.Logger) ([]models.GenerationJob, error) {
+	cp, err := Load(sessionDir, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	missing, err := replayJobs(cp, cfg)
+	if err != nil || len(missing) == 0 || dryRun {
+		return missing, err
+	}
+
+	cp.CurrentPhase = models.PhasePairs
+	cp.Stats.FailureCount = 0
+	cp.Stats.ErrorCounts = nil
+	if err := writeCheckpointFile(sessionDir, cp); err != nil {
+		return nil, err
+	}
+	logger.Info("Checkpoint reopened for replay", "missing_jobs", len(missing), "completed_jobs", len(cp.CompletedJobIDs))
+	return missing, nil
+}
+
+// replayJobs checks cp can be replayed with cfg and returns the jobs without a completed row
+func replayJobs(cp *models.Checkpoint, cfg *config.Config) ([]models.GenerationJob, error) {
+	if expectedHash := computeConfigHash(cfg); cp.ConfigHash != expectedHash {
+		return nil, fmt.Errorf("checkpoint config mismatch: checkpoint was created with different topic/counts (hash: %s vs %s)", cp.ConfigHash, expectedHash)
+	}
+	if cp.CurrentPhase != models.PhaseComplete || !cp.PromptsComplete {
+		return nil, fmt.Errorf("session has not finished (phase: %s), use checkpoint resume to continue it", cp.CurrentPhase)
+	}
+	return GetPendingJobs(cp), nil
+}
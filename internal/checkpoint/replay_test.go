@@ -0,0 +1,91 @@
+package checkpoint
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func replayTestCheckpoint(cfg *config.Config) *models.Checkpoint {
+	return &models.Checkpoint{
+		ConfigHash:      computeConfigHash(cfg),
+		CurrentPhase:    models.PhaseComplete,
+		PromptsComplete: true,
+		Prompts: []models.GenerationJob{
+			{ID: 0, Prompt: "p0"},
+			{ID: 1, Prompt: "p1"},
+			{ID: 2, Prompt: "p2"},
+		},
+		CompletedJobIDs: map[int]bool{0: true, 2: true},
+		Stats: models.SessionStats{
+			SuccessCount: 2,
+			FailureCount: 1,
+			ErrorCounts:  map[string]int{"timeout": 1},
+		},
+	}
+}
+
+func TestPrepareReplay(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{Generation: config.GenerationConfig{MainTopic: "Test", NumSubtopics: 1, NumPromptsPerSubtopic: 3}}
+	dir := t.TempDir()
+	if err := writeCheckpointFile(dir, replayTestCheckpoint(cfg)); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+
+	missing, err := PrepareReplay(dir, cfg, true, logger)
+	if err != nil {
+		t.Fatalf("PrepareReplay dry run returned unexpected error: %v", err)
+	}
+	if len(missing) != 1 || missing[0].ID != 1 {
+		t.Fatalf("Expected job 1 missing, got %v", missing)
+	}
+	if cp, _ := Load(dir, logger); cp.CurrentPhase != models.PhaseComplete {
+		t.Errorf("Expected dry run to leave the phase complete, got %s", cp.CurrentPhase)
+	}
+
+	if _, err := PrepareReplay(dir, cfg, false, logger); err != nil {
+		t.Fatalf("PrepareReplay returned unexpected error: %v", err)
+	}
+	cp, err := Load(dir, logger)
+	if err != nil {
+		t.Fatalf("Failed to reload checkpoint: %v", err)
+	}
+	if cp.CurrentPhase != models.PhasePairs {
+		t.Errorf("Expected phase %s, got %s", models.PhasePairs, cp.CurrentPhase)
+	}
+	if cp.Stats.FailureCount != 0 || len(cp.Stats.ErrorCounts) != 0 {
+		t.Errorf("Expected failure counts reset, got %d %v", cp.Stats.FailureCount, cp.Stats.ErrorCounts)
+	}
+	if cp.Stats.SuccessCount != 2 || len(cp.CompletedJobIDs) != 2 {
+		t.Errorf("Expected completed jobs kept, got %d successes and %v", cp.Stats.SuccessCount, cp.CompletedJobIDs)
+	}
+	if err := ValidateCheckpoint(cp, cfg); err != nil {
+		t.Errorf("Expected the reopened checkpoint to be resumable, got %v", err)
+	}
+}
+
+func TestReplayJobs_Errors(t *testing.T) {
+	cfg := &config.Config{Generation: config.GenerationConfig{MainTopic: "Test", NumSubtopics: 1, NumPromptsPerSubtopic: 3}}
+
+	running := replayTestCheckpoint(cfg)
+	running.CurrentPhase = models.PhasePairs
+	if _, err := replayJobs(running, cfg); err == nil || !strings.Contains(err.Error(), "checkpoint resume") {
+		t.Errorf("Expected unfinished session to point at checkpoint resume, got %v", err)
+	}
+
+	other := &config.Config{Generation: config.GenerationConfig{MainTopic: "Other", NumSubtopics: 1, NumPromptsPerSubtopic: 3}}
+	if _, err := replayJobs(replayTestCheckpoint(cfg), other); err == nil || !strings.Contains(err.Error(), "config mismatch") {
+		t.Errorf("Expected config mismatch error, got %v", err)
+	}
+
+	complete := replayTestCheckpoint(cfg)
+	complete.CompletedJobIDs[1] = true
+	if missing, err := replayJobs(complete, cfg); err != nil || len(missing) != 0 {
+		t.Errorf("Expected nothing to replay, got %v (error: %v)", missing, err)
+	}
+}
@@ -47,6 +47,32 @@ func GetTotalCount(cp *models.Checkpoint) int {
 	return len(cp.Prompts)
 }
 
+// TopicProgress is the preference pairs progress of one main topic
+type TopicProgress struct {
+	Topic     string
+	Completed int
+	Total     int
+}
+
+// GetTopicProgress returns the job progress of each main topic in job order
+func GetTopicProgress(cp *models.Checkpoint) []TopicProgress {
+	var progress []TopicProgress
+	index := make(map[string]int)
+	for _, job := range cp.Prompts {
+		i, ok := index[job.MainTopic]
+		if !ok {
+			i = len(progress)
+			index[job.MainTopic] = i
+			progress = append(progress, TopicProgress{Topic: job.MainTopic})
+		}
+		progress[i].Total++
+		if cp.CompletedJobIDs[job.ID] {
+			progress[i].Completed++
+		}
+	}
+	return progress
+}
+
 // GetProgressPercentage returns completion percentage
 func GetProgressPercentage(cp *models.Checkpoint) float64 {
 	total := GetTotalCount(cp)
@@ -150,3 +150,41 @@ func TestGetCompletedAndTotalCount(t *testing.T) {
 		t.Errorf("Expected completed 3, got %d", completed)
 	}
 }
+
+func TestGetTopicProgress(t *testing.T) {
+	cp := &models.Checkpoint{
+		Prompts: []models.GenerationJob{
+			{ID: 0, MainTopic: "Fantasy"},
+			{ID: 1, MainTopic: "Fantasy"},
+			{ID: 2, MainTopic: "Sci-fi"},
+			{ID: 3, MainTopic: "Sci-fi"},
+			{ID: 4, MainTopic: "Sci-fi"},
+		},
+		CompletedJobIDs: map[int]bool{1: true, 2: true, 4: true},
+	}
+
+	got := GetTopicProgress(cp)
+	want := []TopicProgress{{"Fantasy", 1, 2}, {"Sci-fi", 2, 3}}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d topics, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], got[i])
+		}
+	}
+}
+
+func TestConfigHashMainTopics(t *testing.T) {
+	single := &config.Config{Generation: config.GenerationConfig{MainTopic: "Fantasy", NumSubtopics: 2, NumPromptsPerSubtopic: 2}}
+	listed := &config.Config{Generation: config.GenerationConfig{MainTopics: []string{"Fantasy"}, NumSubtopics: 2, NumPromptsPerSubtopic: 2}}
+	reordered := &config.Config{Generation: config.GenerationConfig{MainTopics: []string{"Sci-fi", "Fantasy"}, NumSubtopics: 2, NumPromptsPerSubtopic: 2}}
+	multi := &config.Config{Generation: config.GenerationConfig{MainTopics: []string{"Fantasy", "Sci-fi"}, NumSubtopics: 2, NumPromptsPerSubtopic: 2}}
+
+	if computeConfigHash(single) != computeConfigHash(listed) {
+		t.Error("Expected a one-topic main_topics list to hash like main_topic")
+	}
+	if computeConfigHash(multi) == computeConfigHash(reordered) {
+		t.Error("Expected reordering main_topics to change the config hash")
+	}
+}
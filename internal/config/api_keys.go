@@ -0,0 +1,162 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyRateLimitCooldown is how long a key is skipped after it returned a 429
+const KeyRateLimitCooldown = 30 * time.Second
+
+// apiKeyEnvVars maps providers to their key variable; "<VAR>S" holds a comma-separated
+// list of keys that are rotated per request (e.g. OPENAI_API_KEYS)
+var apiKeyEnvVars = []struct {
+	provider string
+	envVar   string
+}{
+	{"generic", "API_KEY"}, // Provider-agnostic fallback
+	{"openai", "OPENAI_API_KEY"},
+	{"nvidia", "NVIDIA_API_KEY"},
+	{"anthropic", "ANTHROPIC_API_KEY"},
+	{"together", "TOGETHER_API_KEY"},
+	{"chutes", "CHUTES_API_KEY"},
+	{"nahcrof", "NAHCROF_API_KEY"},
+}
+
+// KeyStats reports how a pooled key has been used
+type KeyStats struct {
+	Key         string // Masked key (last 4 characters)
+	Requests    int
+	RateLimited int // 429 responses
+}
+
+// KeyPool rotates requests round-robin across several API keys of one provider
+// Keys that recently returned a 429 are skipped until KeyRateLimitCooldown has passed
+type KeyPool struct {
+	mu          sync.Mutex
+	keys        []string
+	next        int
+	coolUntil   map[string]time.Time
+	requests    map[string]int
+	rateLimited map[string]int
+	now         func() time.Time
+}
+
+// NewKeyPool creates a pool over keys (in rotation order)
+func NewKeyPool(keys []string) *KeyPool {
+	return &KeyPool{
+		keys:        keys,
+		coolUntil:   make(map[string]time.Time),
+		requests:    make(map[string]int),
+		rateLimited: make(map[string]int),
+		now:         time.Now,
+	}
+}
+
+// Next returns the next key that is not cooling down
+// If every key is cooling down, the one that recovers first is returned
+func (p *KeyPool) Next() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	best := -1
+	for i := range p.keys {
+		idx := (p.next + i) % len(p.keys)
+		key := p.keys[idx]
+		if !now.Before(p.coolUntil[key]) {
+			best = idx
+			break
+		}
+		if best < 0 || p.coolUntil[key].Before(p.coolUntil[p.keys[best]]) {
+			best = idx
+		}
+	}
+
+	p.next = (best + 1) % len(p.keys)
+	key := p.keys[best]
+	p.requests[key]++
+	return key
+}
+
+// MarkRateLimited records a 429 for key and skips it for KeyRateLimitCooldown
+// Reports whether key belongs to the pool
+func (p *KeyPool) MarkRateLimited(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !slices.Contains(p.keys, key) {
+		return false
+	}
+	p.rateLimited[key]++
+	p.coolUntil[key] = p.now().Add(KeyRateLimitCooldown)
+	return true
+}
+
+// Stats returns per-key request and 429 counts in rotation order
+func (p *KeyPool) Stats() []KeyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]KeyStats, len(p.keys))
+	for i, key := range p.keys {
+		stats[i] = KeyStats{Key: maskKey(key), Requests: p.requests[key], RateLimited: p.rateLimited[key]}
+	}
+	return stats
+}
+
+// maskKey hides all but the last 4 characters of a key for logging
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return "..." + key[len(key)-4:]
+}
+
+// loadProviderKeys reads "<VAR>S" (comma-separated) and "<VAR>" for one provider
+// The single key comes first when it isn't already in the list; duplicates are dropped
+func loadProviderKeys(getenv func(string) string, envVar string) []string {
+	var keys []string
+	add := func(key string) {
+		key = strings.TrimSpace(key)
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	add(getenv(envVar))
+	for _, key := range strings.Split(getenv(envVar+"S"), ",") {
+		add(key)
+	}
+	return keys
+}
+
+// providerKey returns the key for a provider, rotating when several are configured
+func (s *Secrets) providerKey(provider string) string {
+	if pool := s.keyPools[provider]; pool != nil {
+		return pool.Next()
+	}
+	return s.APIKeys[provider]
+}
+
+// RateLimited records a 429 for key and returns the key to retry with: the next key of the
+// same provider pool, or key itself when it isn't pooled
+func (s *Secrets) RateLimited(key string) string {
+	for _, pool := range s.keyPools {
+		if pool.MarkRateLimited(key) {
+			return pool.Next()
+		}
+	}
+	return key
+}
+
+// KeyPoolStats returns per-key usage for every provider with more than one key
+func (s *Secrets) KeyPoolStats() map[string][]KeyStats {
+	stats := make(map[string][]KeyStats, len(s.keyPools))
+	for provider, pool := range s.keyPools {
+		stats[provider] = pool.Stats()
+	}
+	return stats
+}
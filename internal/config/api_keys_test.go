@@ -0,0 +1,97 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestKeyPoolRotation(t *testing.T) {
+	pool := NewKeyPool([]string{"key-a", "key-b", "key-c"})
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	var got []string
+	for range 4 {
+		got = append(got, pool.Next())
+	}
+	if want := []string{"key-a", "key-b", "key-c", "key-a"}; !slices.Equal(got, want) {
+		t.Errorf("Expected round-robin %v, got %v", want, got)
+	}
+
+	// A rate-limited key is skipped until its cooldown ends
+	if !pool.MarkRateLimited("key-b") {
+		t.Fatalf("Expected key-b to belong to the pool")
+	}
+	if pool.MarkRateLimited("other-key") {
+		t.Errorf("Expected unknown key not to belong to the pool")
+	}
+	got = got[:0]
+	for range 3 {
+		got = append(got, pool.Next())
+	}
+	if want := []string{"key-c", "key-a", "key-c"}; !slices.Equal(got, want) {
+		t.Errorf("Expected key-b skipped during cooldown %v, got %v", want, got)
+	}
+
+	now = now.Add(KeyRateLimitCooldown)
+	if key := pool.Next(); key != "key-a" {
+		t.Errorf("Expected rotation to continue with key-a, got %s", key)
+	}
+	if key := pool.Next(); key != "key-b" {
+		t.Errorf("Expected key-b back after cooldown, got %s", key)
+	}
+
+	stats := pool.Stats()
+	if stats[1].RateLimited != 1 || stats[1].Key != "...ey-b" {
+		t.Errorf("Unexpected stats for key-b: %+v", stats[1])
+	}
+}
+
+func TestKeyPoolAllCoolingDown(t *testing.T) {
+	pool := NewKeyPool([]string{"key-a", "key-b"})
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	pool.MarkRateLimited("key-a")
+	now = now.Add(time.Second)
+	pool.MarkRateLimited("key-b")
+
+	// Both are cooling down; key-a recovers first
+	if key := pool.Next(); key != "key-a" {
+		t.Errorf("Expected key-a (earliest recovery), got %s", key)
+	}
+}
+
+func TestLoadSecretsKeyList(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-single")
+	t.Setenv("OPENAI_API_KEYS", "sk-one, sk-two,,sk-single")
+	t.Setenv("NVIDIA_API_KEY", "nv-only")
+	t.Setenv("NVIDIA_API_KEYS", "")
+
+	secrets, err := LoadSecrets()
+	if err != nil {
+		t.Fatalf("LoadSecrets() error = %v", err)
+	}
+
+	if secrets.APIKeys["openai"] != "sk-single" {
+		t.Errorf("Expected first OpenAI key to be the single key, got %s", secrets.APIKeys["openai"])
+	}
+	var got []string
+	for range 4 {
+		got = append(got, secrets.GetAPIKey("https://api.openai.com/v1"))
+	}
+	if want := []string{"sk-single", "sk-one", "sk-two", "sk-single"}; !slices.Equal(got, want) {
+		t.Errorf("Expected rotation %v, got %v", want, got)
+	}
+
+	if next := secrets.RateLimited("sk-single"); next != "sk-one" {
+		t.Errorf("Expected rate-limited key to rotate to sk-one, got %s", next)
+	}
+	if next := secrets.RateLimited("nv-only"); next != "nv-only" {
+		t.Errorf("Expected unpooled key to be returned unchanged, got %s", next)
+	}
+	if _, ok := secrets.KeyPoolStats()["nvidia"]; ok {
+		t.Errorf("Expected no pool for a provider with one key")
+	}
+}
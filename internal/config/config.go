@@ -2,8 +2,13 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/lamim/vellumforge2/pkg/models"
 )
@@ -14,83 +19,698 @@ type JudgeFilteringConfig struct {
 	UseExplanations  bool    `toml:"use_explanations"`   // Include reasoning in judge responses (false = scores only)
 	MinChosenScore   float64 `toml:"min_chosen_score"`   // Minimum average score for chosen responses (1.0-5.0)
 	MaxRejectedScore float64 `toml:"max_rejected_score"` // Maximum average score for rejected responses (1.0-5.0)
+
+	MinPreferenceMargin float64 `toml:"min_preference_margin"` // MO-DPO only: drop records whose chosen-rejected margin is below this (0 = disabled)
+
+	ScoreMin           *int   `toml:"score_min"`            // Lowest valid per-criterion judge score (default: 1)
+	ScoreMax           *int   `toml:"score_max"`            // Highest valid per-criterion judge score (default: 5)
+	InvalidScoreAction string `toml:"invalid_score_action"` // Out-of-range or fractional criterion scores: clamp (default), retry (re-ask the judge), or drop
+}
+
+// Default judge score scale when judge_filtering.score_min or score_max is unset
+const (
+	DefaultScoreMin = 1
+	DefaultScoreMax = 5
+)
+
+// ScoreScale returns the valid per-criterion score range; an unset bound takes its default
+// on its own, so setting only score_max = 10 gives 1-10
+func (c JudgeFilteringConfig) ScoreScale() (int, int) {
+	lo, hi := DefaultScoreMin, DefaultScoreMax
+	if c.ScoreMin != nil {
+		lo = *c.ScoreMin
+	}
+	if c.ScoreMax != nil {
+		hi = *c.ScoreMax
+	}
+	return lo, hi
+}
+
+// NetworkConfig holds HTTP connection pool settings shared by all API requests
+type NetworkConfig struct {
+	MaxIdleConns           int `toml:"max_idle_conns"`            // Idle connections kept across all hosts (default: 4x concurrency, min 100)
+	MaxIdleConnsPerHost    int `toml:"max_idle_conns_per_host"`   // Idle connections kept per host (default: 2x concurrency, min 16)
+	MaxConnsPerHost        int `toml:"max_conns_per_host"`        // Max connections per host including active (0 = unlimited)
+	IdleConnTimeoutSeconds int `toml:"idle_conn_timeout_seconds"` // How long idle connections stay open (default: 90)
+
+	CABundle           string `toml:"ca_bundle"`            // PEM file of extra CA certificates to trust (e.g. a self-signed local server)
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify"` // Disable TLS certificate verification for all endpoints (unsafe, default: false)
+	UserAgent          string `toml:"user_agent"`           // User-Agent header sent with API requests (default: VellumForge2/<version>)
+}
+
+// Retry backoff strategies for retry.strategy
+const (
+	RetryStrategyExponential = "exponential"
+	RetryStrategyLinear      = "linear"
+	RetryStrategyFibonacci   = "fibonacci"
+	RetryStrategyConstant    = "constant"
+)
+
+// RetryConfig tunes API retry backoff for all models; zero values keep the defaults
+type RetryConfig struct {
+	Strategy            string  `toml:"strategy"`              // Backoff growth: exponential (default), linear, fibonacci or constant
+	BaseDelay           string  `toml:"base_delay"`            // First retry delay, scaled per attempt by strategy, e.g. "2s" or "500ms" (default: 2s)
+	MaxAttempts         int     `toml:"max_attempts"`          // Retries for models without max_retries (default: 3, -1 = unlimited)
+	JitterFraction      float64 `toml:"jitter_fraction"`       // Random +/- share of each backoff, 0.0-1.0 (default: 0.1, -1 = no jitter)
+	RateLimitMultiplier float64 `toml:"rate_limit_multiplier"` // 429 backoff is multiplier^attempt x base_delay, 1.0-10.0 (default: 3)
+}
+
+// BaseDelayDuration returns the parsed base_delay (0 = default)
+// Invalid values are rejected by Validate, so they also fall back to 0 here
+func (r RetryConfig) BaseDelayDuration() time.Duration {
+	if r.BaseDelay == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(r.BaseDelay)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// maxRetryBaseDelay caps retry.base_delay so a typo cannot stall a run for hours per retry
+const maxRetryBaseDelay = 5 * time.Minute
+
+func (r RetryConfig) validate() error {
+	switch r.Strategy {
+	case "", RetryStrategyExponential, RetryStrategyLinear, RetryStrategyFibonacci, RetryStrategyConstant:
+	default:
+		return fmt.Errorf("retry.strategy must be 'exponential', 'linear', 'fibonacci' or 'constant' (got %s)", r.Strategy)
+	}
+	if r.BaseDelay != "" {
+		d, err := time.ParseDuration(r.BaseDelay)
+		if err != nil {
+			return fmt.Errorf("retry.base_delay must be a duration such as \"2s\" or \"500ms\" (got %q)", r.BaseDelay)
+		}
+		if d <= 0 || d > maxRetryBaseDelay {
+			return fmt.Errorf("retry.base_delay must be positive and at most %s (got %s)", maxRetryBaseDelay, r.BaseDelay)
+		}
+	}
+	if r.MaxAttempts < -1 {
+		return fmt.Errorf("retry.max_attempts must be -1 (unlimited) or at least 1 (got %d)", r.MaxAttempts)
+	}
+	if r.JitterFraction != -1 && (r.JitterFraction < 0 || r.JitterFraction > 1.0) {
+		return fmt.Errorf("retry.jitter_fraction must be between 0.0 and 1.0, or -1 for no jitter (got %.2f)", r.JitterFraction)
+	}
+	if r.RateLimitMultiplier != 0 && (r.RateLimitMultiplier < 1.0 || r.RateLimitMultiplier > 10.0) {
+		return fmt.Errorf("retry.rate_limit_multiplier must be between 1.0 and 10.0 (got %.2f)", r.RateLimitMultiplier)
+	}
+	return nil
+}
+
+// Defaults for [embeddings]
+const (
+	DefaultEmbeddingSimilarity     = 0.9
+	DefaultEmbeddingBatchSize      = 64
+	DefaultEmbeddingTimeoutSeconds = 60
+	maxEmbeddingBatchSize          = 2048
+)
+
+// EmbeddingsConfig enables dropping near-duplicate prompts by cosine similarity of their embeddings
+type EmbeddingsConfig struct {
+	Enabled             bool    `toml:"enabled"`              // Embed generated prompts and drop near-duplicates (default: false)
+	BaseURL             string  `toml:"base_url"`             // OpenAI-compatible API root, e.g. "https://api.openai.com/v1" (key looked up like a model's)
+	ModelName           string  `toml:"model_name"`           // Embedding model, e.g. "text-embedding-3-small"
+	SimilarityThreshold float64 `toml:"similarity_threshold"` // Drop prompts at least this similar to a kept prompt (0.0-1.0, default: 0.9)
+	BatchSize           int     `toml:"batch_size"`           // Prompts per embeddings request (default: 64, max 2048)
+	TimeoutSeconds      int     `toml:"timeout_seconds"`      // Timeout per embeddings request (default: 60, -1 = no timeout)
+	MaxRetries          int     `toml:"max_retries"`          // Retries of a failed embeddings request (default: retry.max_attempts, -1 = unlimited)
+}
+
+// Threshold returns the similarity at or above which a prompt is dropped
+func (e EmbeddingsConfig) Threshold() float64 {
+	if e.SimilarityThreshold == 0 {
+		return DefaultEmbeddingSimilarity
+	}
+	return e.SimilarityThreshold
+}
+
+// Batch returns how many prompts are embedded per request
+func (e EmbeddingsConfig) Batch() int {
+	if e.BatchSize == 0 {
+		return DefaultEmbeddingBatchSize
+	}
+	return e.BatchSize
+}
+
+// Timeout returns the per-request timeout (0 = none)
+func (e EmbeddingsConfig) Timeout() time.Duration {
+	switch {
+	case e.TimeoutSeconds < 0:
+		return 0
+	case e.TimeoutSeconds == 0:
+		return DefaultEmbeddingTimeoutSeconds * time.Second
+	default:
+		return time.Duration(e.TimeoutSeconds) * time.Second
+	}
+}
+
+func (e EmbeddingsConfig) validate() error {
+	if !e.Enabled {
+		return nil
+	}
+	if e.BaseURL == "" {
+		return fmt.Errorf("embeddings.base_url is required when embeddings.enabled=true")
+	}
+	if e.ModelName == "" {
+		return fmt.Errorf("embeddings.model_name is required when embeddings.enabled=true")
+	}
+	if e.SimilarityThreshold < 0 || e.SimilarityThreshold > 1.0 {
+		return fmt.Errorf("embeddings.similarity_threshold must be between 0.0 and 1.0 (got %.2f)", e.SimilarityThreshold)
+	}
+	if e.BatchSize < 0 || e.BatchSize > maxEmbeddingBatchSize {
+		return fmt.Errorf("embeddings.batch_size must be between 1 and %d (got %d)", maxEmbeddingBatchSize, e.BatchSize)
+	}
+	if e.TimeoutSeconds < -1 {
+		return fmt.Errorf("embeddings.timeout_seconds must be -1 (no timeout) or positive (got %d)", e.TimeoutSeconds)
+	}
+	if e.MaxRetries < -1 {
+		return fmt.Errorf("embeddings.max_retries must be -1 (unlimited) or at least 0 (got %d)", e.MaxRetries)
+	}
+	return nil
+}
+
+// DefaultRewardModelTimeoutSeconds is used when reward_model.timeout_seconds is unset
+const DefaultRewardModelTimeoutSeconds = 60
+
+// RewardModelConfig filters records by the scalar scores of a reward model endpoint, as an
+// alternative to judge_filtering. Thresholds are in the reward model's own units; unset ones are not applied
+type RewardModelConfig struct {
+	Enabled          bool     `toml:"enabled"`            // Score chosen/rejected responses with the reward model and filter on the thresholds (default: false)
+	BaseURL          string   `toml:"base_url"`           // Full scoring endpoint URL, e.g. "http://localhost:8000/score" (key looked up like a model's)
+	ModelName        string   `toml:"model_name"`         // Sent as "model" (optional for single-model servers)
+	MinChosenScore   *float64 `toml:"min_chosen_score"`   // Drop records whose chosen response scores below this
+	MaxRejectedScore *float64 `toml:"max_rejected_score"` // Drop records whose rejected response scores above this
+	MinMargin        *float64 `toml:"min_margin"`         // Drop records whose chosen score beats rejected by less than this
+	TimeoutSeconds   int      `toml:"timeout_seconds"`    // Timeout per scoring request (default: 60, -1 = no timeout)
+	MaxRetries       int      `toml:"max_retries"`        // Retries of a failed scoring request (default: retry.max_attempts, -1 = unlimited)
+	OnError          string   `toml:"on_error"`           // Records whose scoring still fails after retries: drop (default, fail closed) or keep (fail open)
+}
+
+const (
+	// RewardErrorDrop filters records the reward model could not score
+	RewardErrorDrop = "drop"
+	// RewardErrorKeep writes records the reward model could not score unfiltered
+	RewardErrorKeep = "keep"
+)
+
+// Timeout returns the per-request timeout (0 = none)
+func (r RewardModelConfig) Timeout() time.Duration {
+	switch {
+	case r.TimeoutSeconds < 0:
+		return 0
+	case r.TimeoutSeconds == 0:
+		return DefaultRewardModelTimeoutSeconds * time.Second
+	default:
+		return time.Duration(r.TimeoutSeconds) * time.Second
+	}
+}
+
+func (r *RewardModelConfig) validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.BaseURL == "" {
+		return fmt.Errorf("reward_model.base_url is required when reward_model.enabled=true")
+	}
+	if r.MinChosenScore == nil && r.MaxRejectedScore == nil && r.MinMargin == nil {
+		return fmt.Errorf("reward_model.enabled=true requires min_chosen_score, max_rejected_score, or min_margin")
+	}
+	if r.MinMargin != nil && *r.MinMargin < 0 {
+		return fmt.Errorf("reward_model.min_margin must not be negative (got %.2f)", *r.MinMargin)
+	}
+	if r.TimeoutSeconds < -1 {
+		return fmt.Errorf("reward_model.timeout_seconds must be -1 (no timeout) or positive (got %d)", r.TimeoutSeconds)
+	}
+	if r.MaxRetries < -1 {
+		return fmt.Errorf("reward_model.max_retries must be -1 (unlimited) or at least 0 (got %d)", r.MaxRetries)
+	}
+	switch r.OnError {
+	case "":
+		r.OnError = RewardErrorDrop
+	case RewardErrorDrop, RewardErrorKeep:
+	default:
+		return fmt.Errorf("reward_model.on_error must be 'drop' or 'keep' (got %s)", r.OnError)
+	}
+	return nil
+}
+
+// Log formats for logging.format and logging.file_format (and --log-format)
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// JSON layouts for generation.json_indent
+const (
+	JSONIndentCompact = "compact"
+	JSONIndentPretty  = "pretty"
+)
+
+// LoggingConfig selects the format and level of the console and session log file independently
+type LoggingConfig struct {
+	Format     string `toml:"format"`      // Console format: text (default) or json (--log-format overrides it)
+	Level      string `toml:"level"`       // Console level: debug, info (default), warn or error (--verbose forces debug)
+	FileFormat string `toml:"file_format"` // Session log file format: json (default) or text
+	FileLevel  string `toml:"file_level"`  // Session log file level (default: same as level)
+}
+
+// ParseLogLevel parses a logging level name, returning fallback when it is empty
+// Invalid names are rejected by Validate, so they also fall back here
+func ParseLogLevel(name string, fallback slog.Level) slog.Level {
+	if name == "" {
+		return fallback
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return fallback
+	}
+	return level
+}
+
+// ValidateLogFormat checks a console or file log format ("" keeps the default)
+func ValidateLogFormat(key, format string) error {
+	switch format {
+	case "", LogFormatText, LogFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("%s must be 'text' or 'json' (got %s)", key, format)
+	}
+}
+
+func (l LoggingConfig) validate() error {
+	if err := ValidateLogFormat("logging.format", l.Format); err != nil {
+		return err
+	}
+	if err := ValidateLogFormat("logging.file_format", l.FileFormat); err != nil {
+		return err
+	}
+	levels := []struct{ key, name string }{{"logging.level", l.Level}, {"logging.file_level", l.FileLevel}}
+	for _, lv := range levels {
+		var level slog.Level
+		if lv.name != "" && level.UnmarshalText([]byte(lv.name)) != nil {
+			return fmt.Errorf("%s must be debug, info, warn or error (got %s)", lv.key, lv.name)
+		}
+	}
+	return nil
 }
 
 // Config represents the complete application configuration
 type Config struct {
-	Generation           GenerationConfig       `toml:"generation"`
-	Models               map[string]ModelConfig `toml:"models"`
-	PromptTemplates      PromptTemplates        `toml:"prompt_templates"`
-	HuggingFace          HuggingFaceConfig      `toml:"huggingface"`
-	ProviderRateLimits   map[string]int         `toml:"provider_rate_limits"`   // Global rate limits per provider (requests per minute)
-	ProviderBurstPercent int                    `toml:"provider_burst_percent"` // Burst capacity as percentage (1-50, default: 15)
-	JudgeFiltering       JudgeFilteringConfig   `toml:"judge_filtering"`        // Optional judge-based quality filtering
+	Generation                GenerationConfig       `toml:"generation"`
+	Models                    map[string]ModelConfig `toml:"models"`
+	PromptTemplates           PromptTemplates        `toml:"prompt_templates"`
+	HuggingFace               HuggingFaceConfig      `toml:"huggingface"`
+	ProviderRateLimits        map[string]int         `toml:"provider_rate_limits"`         // Global rate limits per provider (requests per minute)
+	ProviderBurstPercent      int                    `toml:"provider_burst_percent"`       // Burst capacity as percentage (1-50, default: 15)
+	ProviderAdaptiveRateLimit bool                   `toml:"provider_adaptive_rate_limit"` // Cut the request rate after a burst of 429s and recover it gradually (default: false)
+	JudgeFiltering            JudgeFilteringConfig   `toml:"judge_filtering"`              // Optional judge-based quality filtering
+	RewardModel               RewardModelConfig      `toml:"reward_model"`                 // Optional reward-model quality filtering (alternative to judge_filtering)
+	Network                   NetworkConfig          `toml:"network"`                      // HTTP connection pool / keep-alive tuning
+	Retry                     RetryConfig            `toml:"retry"`                        // API retry backoff tuning for all models
+	Logging                   LoggingConfig          `toml:"logging"`                      // Console and session log file format and level
+	Embeddings                EmbeddingsConfig       `toml:"embeddings"`                   // Optional embedding-based prompt diversity filtering
+	Dataset                   DatasetConfig          `toml:"dataset"`                      // License, source and attribution for the published dataset card
 }
 
 // GenerationConfig holds generation-specific settings
 type GenerationConfig struct {
-	MainTopic                string             `toml:"main_topic"`
-	NumSubtopics             int                `toml:"num_subtopics"`
-	SubtopicChunkSize        int                `toml:"subtopic_chunk_size"` // Request subtopics in chunks (0=all at once, default: 30)
-	NumPromptsPerSubtopic    int                `toml:"num_prompts_per_subtopic"`
-	Concurrency              int                `toml:"concurrency"`
-	OverGenerationBuffer     float64            `toml:"over_generation_buffer"`     // Buffer percentage (0.0-1.0, default 0.15)
-	MaxExclusionListSize     int                `toml:"max_exclusion_list_size"`    // Max items in exclusion list (default 50)
-	MinSuccessRate           float64            `toml:"min_success_rate"`           // Minimum success rate for prompt generation (0.0-1.0, default 0.90)
-	PromptRetryAttempts      int                `toml:"prompt_retry_attempts"`      // Number of retry attempts for failed subtopics (default 2)
-	DisableValidationLimits  bool               `toml:"disable_validation_limits"`  // Disable upper bound validation (use with caution)
-	EnableCheckpointing      bool               `toml:"enable_checkpointing"`       // Enable checkpoint/resume support
-	CheckpointInterval       int                `toml:"checkpoint_interval"`        // Save checkpoint every N completed jobs (default: 10)
-	ResumeFromSession        string             `toml:"resume_from_session"`        // Session directory to resume from (e.g., "session_2025-10-27T12-34-56")
-	DatasetMode              models.DatasetMode `toml:"dataset_mode"`               // Dataset format: sft, dpo, kto, mo-dpo (default: mo-dpo)
-	SFTFormat                models.SFTFormat   `toml:"sft_format"`                 // SFT output format (alpaca/sharegpt)
-	IncludeTopicColumns      bool               `toml:"include_topic_columns"`      // For SFT mode: include main_topic/sub_topic columns (default: true)
-	EnableReasoningCapture   bool               `toml:"enable_reasoning_capture"`   // Capture reasoning from reasoning models (creates dual datasets)
-	ReasoningCaptureRejected bool               `toml:"reasoning_capture_rejected"` // Also capture reasoning for rejected responses (default: false)
+	MainTopic                  string               `toml:"main_topic"`
+	MainTopics                 []string             `toml:"main_topics"` // Several main topics for one combined dataset; num_subtopics applies per topic (excludes main_topic)
+	NumSubtopics               int                  `toml:"num_subtopics"`
+	SubtopicChunkSize          int                  `toml:"subtopic_chunk_size"` // Request subtopics in chunks (0=all at once, default: 30)
+	AdaptiveChunking           bool                 `toml:"adaptive_chunking"`   // Resize subtopic chunks from recent yield and request until enough are received (default: false)
+	NumPromptsPerSubtopic      int                  `toml:"num_prompts_per_subtopic"`
+	WeightedSubtopics          bool                 `toml:"weighted_subtopics"`  // Accept subtopics as {"topic": "...", "weight": N} and give each num_prompts_per_subtopic x N prompts (default: false)
+	MaxSubtopicWeight          int                  `toml:"max_subtopic_weight"` // Weighted subtopics: larger weights are clamped to this (default: 5, max 100)
+	Concurrency                int                  `toml:"concurrency"`
+	PromptConcurrency          int                  `toml:"prompt_concurrency"`            // Workers for the prompt generation phase (0 = use concurrency, default)
+	OverGenerationBuffer       float64              `toml:"over_generation_buffer"`        // Buffer percentage (0.0-1.0, default 0.15)
+	MaxExclusionListSize       int                  `toml:"max_exclusion_list_size"`       // Max items in exclusion list (default 50)
+	SubtopicDedupSimilarity    float64              `toml:"subtopic_dedup_similarity"`     // Drop subtopics at least this similar to an earlier one (0.0-1.0, 0 = exact match only)
+	MinSuccessRate             float64              `toml:"min_success_rate"`              // Minimum success rate for prompt generation (0.0-1.0, default 0.90)
+	PromptRetryAttempts        int                  `toml:"prompt_retry_attempts"`         // Number of retry attempts for failed subtopics (default 2)
+	JSONReformatRetry          bool                 `toml:"json_reformat_retry"`           // Ask the model once to reformat unparseable subtopic/prompt JSON (extra request, default: false)
+	UndershootRetry            bool                 `toml:"undershoot_retry"`              // Re-ask (up to 2x) when a subtopic/prompt request returns under half the requested count (default: false)
+	ListMinItems               int                  `toml:"list_min_items"`                // Reject subtopic/prompt lists with fewer items (default: 1)
+	ListMaxRatio               float64              `toml:"list_max_ratio"`                // Reject subtopic/prompt lists longer than this multiple of the requested count (>= 1.0, 0 = no limit)
+	PromptSamples              int                  `toml:"prompt_samples"`                // Independent prompt requests per subtopic, merged and deduplicated (default: 1)
+	PromptSampleTemperature    float64              `toml:"prompt_sample_temperature"`     // Main model temperature for prompt samples when prompt_samples > 1 (0 = model temperature)
+	RetryOnTruncation          bool                 `toml:"retry_on_truncation"`           // Retry a chosen response cut off at max_output_tokens once with a higher limit (default: false)
+	TruncationRetryFactor      float64              `toml:"truncation_retry_factor"`       // max_output_tokens multiplier for the truncation retry, capped by context_size (default: 2.0)
+	DisableValidationLimits    bool                 `toml:"disable_validation_limits"`     // Disable upper bound validation (use with caution)
+	EnableCheckpointing        bool                 `toml:"enable_checkpointing"`          // Enable checkpoint/resume support
+	CheckpointInterval         int                  `toml:"checkpoint_interval"`           // Save checkpoint every N completed jobs (default: 10)
+	ResumeFromSession          string               `toml:"resume_from_session"`           // Session directory to resume from (e.g., "session_2025-10-27T12-34-56")
+	OrderedOutput              bool                 `toml:"ordered_output"`                // Write records in job order regardless of concurrency (default: false)
+	OrderedOutputBuffer        int                  `toml:"ordered_output_buffer"`         // Results held waiting for a slow job before writing past it (default: 10x concurrency)
+	DatasetMode                models.DatasetMode   `toml:"dataset_mode"`                  // Dataset format: sft, dpo, kto, mo-dpo (default: mo-dpo)
+	SFTFormat                  models.SFTFormat     `toml:"sft_format"`                    // SFT output format (alpaca/sharegpt/openai)
+	DPOFormat                  models.DPOFormat     `toml:"dpo_format"`                    // DPO output format: standard (strings) or conversational (message lists, default: standard)
+	IncludeTopicColumns        bool                 `toml:"include_topic_columns"`         // For SFT mode: include main_topic/sub_topic columns (default: true)
+	IncludeID                  bool                 `toml:"include_id"`                    // Write an id column (SHA-256 of the record content) to every record, all modes (default: false)
+	IncludeMetadata            bool                 `toml:"include_metadata"`              // Write a _meta column with per-record generation details such as retry attempts, all modes (default: false)
+	JSONIndent                 string               `toml:"json_indent"`                   // Dataset record layout: compact (default); pretty is rejected, as JSONL needs one record per line
+	AutodetectContext          bool                 `toml:"autodetect_context"`            // Query each model's /models endpoint at startup to fill in an unset context_size (default: false)
+	IncludeSystemPrompt        bool                 `toml:"include_system_prompt"`         // DPO/KTO: write chosen_system_prompt as a system column (conversational DPO always includes it)
+	RecordFilterCommand        string               `toml:"record_filter_command"`         // Shell command each record's JSON is piped through before writing; empty stdout skips the record
+	RecordFilterTimeoutSeconds int                  `toml:"record_filter_timeout_seconds"` // Seconds a single record_filter_command run may take (default: 30)
+	EnableReasoningCapture     bool                 `toml:"enable_reasoning_capture"`      // Capture reasoning from reasoning models (creates dual datasets)
+	ReasoningCaptureRejected   bool                 `toml:"reasoning_capture_rejected"`    // Also capture reasoning for rejected responses (default: false)
+	StripThinkFromContent      bool                 `toml:"strip_think_from_content"`      // Move <think> blocks embedded in content to the reasoning fields (default: false)
+	NumRejected                int                  `toml:"num_rejected"`                  // Rejected responses generated per prompt (default: 1, DPO/KTO only)
+	RejectedOutputShape        models.RejectedShape `toml:"rejected_output_shape"`         // DPO output for num_rejected > 1: rows (one row per rejected) or array (default: rows)
+	KTORatio                   float64              `toml:"kto_ratio"`                     // KTO: undesirable rows per desirable row, e.g. 2.0 = 1:2, 0.5 = 2:1 (0 = use num_rejected)
+	PreservePromptReasoning    bool                 `toml:"preserve_prompt_reasoning"`     // Keep reasoning tags found in prompt fields (default: false = strip them)
+	RejectedFromMain           bool                 `toml:"rejected_from_main"`            // Without models.rejected, generate rejected responses with the main model at a higher temperature (default: false)
+	RejectedTemperatureOffset  float64              `toml:"rejected_temperature_offset"`   // Added to the main model's temperature for rejected_from_main, clamped to [0, 2] (default: 0.5)
+	SwapProbability            float64              `toml:"swap_probability"`              // Probability (0.0-1.0) of swapping main/rejected models per job for hard negatives (default: 0)
+	RejectedSeesChosen         bool                 `toml:"rejected_sees_chosen"`          // Expose the chosen response to rejected_generation as {{.Chosen}} for contrastive rejections (default: false)
+	Seed                       int64                `toml:"seed"`                          // Run-wide seed: default for swap_seed and temperature_jitter_seed, and seeds retry jitter (0 = unseeded)
+	SwapSeed                   int64                `toml:"swap_seed"`                     // Seed for swap decisions (same seed + job ID = same assignment, default: seed)
+	RecordModelAssignment      bool                 `toml:"record_model_assignment"`       // Write chosen_model/rejected_model columns to preference records
+	TemperatureJitter          float64              `toml:"temperature_jitter"`            // Max random +/- offset applied per job to chosen/rejected temperature (0.0-1.0, default: 0)
+	TemperatureJitterSeed      int64                `toml:"temperature_jitter_seed"`       // Seed for temperature jitter (same seed + job ID = same temperatures, default: seed)
+	EnablePromptCache          bool                 `toml:"enable_prompt_cache"`           // Reuse prompts generated for the same subtopic + prompt template (disable per run with --no-cache)
+	PromptCacheDir             string               `toml:"prompt_cache_dir"`              // Prompt cache directory (default: output/prompt_cache)
+	PromptCacheTTLHours        int                  `toml:"prompt_cache_ttl_hours"`        // Expire cached prompts after N hours (0 = never)
+	EnableJudgeCache           bool                 `toml:"enable_judge_cache"`            // Reuse judge scores for the same judge model, rubric, prompt and response (disable per run with --no-judge-cache)
+	JudgeCacheDir              string               `toml:"judge_cache_dir"`               // Judge cache directory (default: output/judge_cache)
+	JudgeFailureThreshold      int                  `toml:"judge_failure_threshold"`       // MO-DPO: consecutive judge failures before the circuit breaker trips (default: 10, -1 = disabled)
+	FallbackAfterFailures      int                  `toml:"fallback_after_failures"`       // Consecutive failures on a main/rejected/judge model before switching to models.<role>_fallback (default: 3)
+	JudgeFailureAction         string               `toml:"judge_failure_action"`          // MO-DPO: what to do when the breaker trips: abort (default) or flag
+	MaxBufferedRecords         int                  `toml:"max_buffered_records"`          // MO-DPO: records held in memory before judged ones are written early (0 = unbounded, default: 0)
+	OnIdenticalPair            string               `toml:"on_identical_pair"`             // When rejected matches chosen: keep (default, logged), regen (retry rejected once), or drop
+	LengthRatioMin             float64              `toml:"length_ratio_min"`              // Lowest allowed chosen/rejected length ratio in characters (0 = no minimum, default: 0)
+	LengthRatioMax             float64              `toml:"length_ratio_max"`              // Highest allowed chosen/rejected length ratio in characters (0 = no maximum, default: 0)
+	LengthRatioAction          string               `toml:"length_ratio_action"`           // Pairs outside the length ratio range: drop (default) or flag (write them marked in _meta and count them)
+	MaxFailureRate             float64              `toml:"max_failure_rate"`              // Abort when the job failure rate exceeds this (0.0-1.0, 0 = disabled, default: 0)
+	FailureRateMinSamples      int                  `toml:"failure_rate_min_samples"`      // Jobs to observe before max_failure_rate is checked (default: 20)
+	KeepFailed                 bool                 `toml:"keep_failed"`                   // Write each failed job's prompt and error to failures.jsonl in the session directory (default: false)
+	MaxRuntime                 string               `toml:"max_runtime"`                   // Wall-clock budget for the whole run, e.g. "2h" or "90m" (empty = no limit, resumable when hit)
+	MaxOutputBytes             int64                `toml:"max_output_bytes"`              // Stop dispatching jobs once the dataset (file(s) plus buffered MO-DPO records) reaches this size and finalize the run as complete (0 = no limit, default)
+	OnFingerprintChange        string               `toml:"on_fingerprint_change"`         // When a model's system_fingerprint changes mid-run: warn (default) or abort
+	NormalizeUnicode           string               `toml:"normalize_unicode"`             // Unicode normalization applied to prompts and responses before writing: nfc or nfkc (empty = off, default)
+	ShutdownGraceSeconds       int                  `toml:"shutdown_grace_seconds"`        // Seconds in-flight jobs may keep running after Ctrl+C or another stop so finished ones are written (default: 10, -1 = stop immediately)
+	WarmupSeconds              int                  `toml:"warmup_seconds"`                // Stagger worker starts over this many seconds instead of starting all at once (0 = no warmup, default)
+}
+
+// DefaultRejectedTemperatureOffset is used when generation.rejected_temperature_offset is unset
+const DefaultRejectedTemperatureOffset = 0.5
+
+// RejectedOffset returns the temperature offset of rejected_from_main responses
+func (g GenerationConfig) RejectedOffset() float64 {
+	if g.RejectedTemperatureOffset == 0 {
+		return DefaultRejectedTemperatureOffset
+	}
+	return g.RejectedTemperatureOffset
+}
+
+// SwapDecisionSeed returns the seed for model swap decisions: swap_seed, or seed when unset
+func (g GenerationConfig) SwapDecisionSeed() int64 {
+	if g.SwapSeed == 0 {
+		return g.Seed
+	}
+	return g.SwapSeed
+}
+
+// JitterSeed returns the seed for temperature jitter: temperature_jitter_seed, or seed when unset
+func (g GenerationConfig) JitterSeed() int64 {
+	if g.TemperatureJitterSeed == 0 {
+		return g.Seed
+	}
+	return g.TemperatureJitterSeed
+}
+
+// validateLengthRatio checks length_ratio_min/max and defaults length_ratio_action
+func (g *GenerationConfig) validateLengthRatio() error {
+	if g.LengthRatioMin < 0 || g.LengthRatioMax < 0 {
+		return fmt.Errorf("generation.length_ratio_min and length_ratio_max must not be negative (got %.2f and %.2f)", g.LengthRatioMin, g.LengthRatioMax)
+	}
+	if g.LengthRatioMin > 0 && g.LengthRatioMax > 0 && g.LengthRatioMin > g.LengthRatioMax {
+		return fmt.Errorf("generation.length_ratio_min must not exceed length_ratio_max (got %.2f and %.2f)", g.LengthRatioMin, g.LengthRatioMax)
+	}
+	switch g.LengthRatioAction {
+	case "":
+		g.LengthRatioAction = LengthRatioDrop
+	case LengthRatioDrop, LengthRatioFlag:
+	default:
+		return fmt.Errorf("generation.length_ratio_action must be 'drop' or 'flag' (got %s)", g.LengthRatioAction)
+	}
+	if (g.LengthRatioMin > 0 || g.LengthRatioMax > 0) && g.DatasetMode == models.DatasetModeSFT {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.length_ratio_min/max only apply to modes with rejected responses and will be ignored\n")
+	}
+	return nil
+}
+
+// RejectedModel returns the model that generates rejected responses: models.rejected, or with
+// generation.rejected_from_main the main model at its temperature plus rejected_temperature_offset
+func (c *Config) RejectedModel() (ModelConfig, bool) {
+	if rejected, ok := c.Models["rejected"]; ok {
+		return rejected, true
+	}
+	mainModel, ok := c.Models["main"]
+	if !ok || !c.Generation.RejectedFromMain {
+		return ModelConfig{}, false
+	}
+	mainModel.Temperature = min(max(mainModel.Temperature+c.Generation.RejectedOffset(), 0), 2)
+	return mainModel, true
+}
+
+// FallbackModel returns the fallback of a model role (main, rejected or judge): models.<role>_fallback,
+// or for rejected_from_main the main fallback at its temperature plus rejected_temperature_offset
+func (c *Config) FallbackModel(role string) (ModelConfig, bool) {
+	if role != "rejected" {
+		fallback, ok := c.Models[role+"_fallback"]
+		return fallback, ok
+	}
+	if _, ok := c.Models["rejected"]; ok {
+		fallback, ok := c.Models["rejected_fallback"]
+		return fallback, ok
+	}
+	fallback, ok := c.Models["main_fallback"]
+	if !ok || !c.Generation.RejectedFromMain {
+		return ModelConfig{}, false
+	}
+	fallback.Temperature = min(max(fallback.Temperature+c.Generation.RejectedOffset(), 0), 2)
+	return fallback, true
+}
+
+// PromptWorkers returns the prompt generation worker pool size: prompt_concurrency, or
+// concurrency when unset
+func (g GenerationConfig) PromptWorkers() int {
+	if g.PromptConcurrency > 0 {
+		return g.PromptConcurrency
+	}
+	return g.Concurrency
+}
+
+// MaxWarmupSeconds caps generation.warmup_seconds
+const MaxWarmupSeconds = 3600
+
+// Warmup returns the window worker starts are staggered over (0 = start all at once)
+func (g GenerationConfig) Warmup() time.Duration {
+	if g.WarmupSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(g.WarmupSeconds) * time.Second
+}
+
+// DefaultShutdownGraceSeconds is used when generation.shutdown_grace_seconds is unset
+const DefaultShutdownGraceSeconds = 10
+
+// ShutdownGrace returns how long in-flight jobs may finish after the run is stopped (0 = not at all)
+// Call after Validate, which applies the default
+func (g GenerationConfig) ShutdownGrace() time.Duration {
+	if g.ShutdownGraceSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(g.ShutdownGraceSeconds) * time.Second
+}
+
+// MaxRuntimeDuration returns the parsed max_runtime budget (0 = no limit)
+// Call after Validate; an unparseable value is treated as no limit
+func (g GenerationConfig) MaxRuntimeDuration() time.Duration {
+	if g.MaxRuntime == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(g.MaxRuntime)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Topics returns the main topics of a run: main_topics, or main_topic alone
+func (g GenerationConfig) Topics() []string {
+	if len(g.MainTopics) > 0 {
+		return g.MainTopics
+	}
+	return []string{g.MainTopic}
+}
+
+// MultiTopic reports whether the run covers several main topics (generation.main_topics)
+func (g GenerationConfig) MultiTopic() bool {
+	return len(g.MainTopics) > 0
+}
+
+func (g GenerationConfig) validateMainTopics(disableLimits bool) error {
+	if len(g.MainTopics) == 0 {
+		if g.MainTopic == "" {
+			return fmt.Errorf("generation.main_topic (or main_topics) is required")
+		}
+		return nil
+	}
+	if g.MainTopic != "" {
+		return fmt.Errorf("generation.main_topic and generation.main_topics are mutually exclusive")
+	}
+	if !disableLimits && len(g.MainTopics) > MaxMainTopics {
+		return fmt.Errorf("generation.main_topics must not exceed %d topics (got %d)", MaxMainTopics, len(g.MainTopics))
+	}
+	seen := make(map[string]bool, len(g.MainTopics))
+	for i, topic := range g.MainTopics {
+		if strings.TrimSpace(topic) == "" {
+			return fmt.Errorf("generation.main_topics[%d] is empty", i)
+		}
+		key := strings.ToLower(strings.TrimSpace(topic))
+		if seen[key] {
+			return fmt.Errorf("generation.main_topics lists %q more than once", topic)
+		}
+		seen[key] = true
+	}
+	return nil
 }
 
 // ModelConfig represents configuration for a single model endpoint
 type ModelConfig struct {
 	BaseURL              string  `toml:"base_url"`
+	ChatPath             string  `toml:"chat_path"`   // Optional: chat completions path joined to base_url, or a full URL (default: derived from base_url)
+	AuthHeader           string  `toml:"auth_header"` // Optional: header carrying the API key, e.g. "Api-Key" (default: Authorization)
+	AuthScheme           string  `toml:"auth_scheme"` // Optional: prefix before the key, e.g. "Token", or "none" (default: Bearer for Authorization, none otherwise)
+	UserAgent            string  `toml:"user_agent"`  // Optional: User-Agent for this model's provider (default: network.user_agent)
 	ModelName            string  `toml:"model_name"`
 	Temperature          float64 `toml:"temperature"`
 	StructureTemperature float64 `toml:"structure_temperature"` // Temperature for JSON generation (optional, defaults to temperature)
 	TopP                 float64 `toml:"top_p"`
 	MaxOutputTokens      int     `toml:"max_output_tokens"`
 	ContextSize          int     `toml:"context_size"`
+	ContextSizeUnset     bool    `toml:"-"` // context_size was left out and holds the default (generation.autodetect_context may replace it)
 	RateLimitPerMinute   int     `toml:"rate_limit_per_minute"`
 	MaxBackoffSeconds    int     `toml:"max_backoff_seconds"`             // Optional: max backoff duration (default 120)
 	MaxRetries           int     `toml:"max_retries"`                     // Optional: max retry attempts (default 3, 0 = unlimited)
 	HTTPTimeoutSeconds   int     `toml:"http_timeout_seconds"`            // Optional: HTTP request timeout (default 120, 0 = no timeout)
+	MaxResponseBytes     int64   `toml:"max_response_bytes"`              // Optional: abort a response whose body exceeds this many bytes (default 64MB, -1 = unlimited)
 	JudgeTimeoutSeconds  int     `toml:"judge_timeout_seconds,omitempty"` // Timeout for judge API calls (default: 100s)
 	UseJSONMode          bool    `toml:"use_json_mode"`                   // Enable structured JSON output mode (optional)
+	UseToolCalling       bool    `toml:"use_tool_calling"`                // Main model: return subtopics and prompts through a forced function call (falls back to JSON mode if the provider rejects tools)
 	UseStreaming         bool    `toml:"use_streaming"`                   // Enable streaming mode (bypasses gateway timeouts, default: false)
+	PromptOverflow       string  `toml:"prompt_overflow"`                 // Prompt over context_size - max_output_tokens: warn (default), error, truncate, or off
 	Enabled              bool    `toml:"enabled"`                         // Only used for judge model
+	CABundle             string  `toml:"ca_bundle"`                       // Optional: PEM CA bundle for this model's endpoint (replaces network.ca_bundle)
+	InsecureSkipVerify   bool    `toml:"insecure_skip_verify"`            // Optional: disable TLS certificate verification for this model (unsafe)
+
+	ExtraBody         map[string]interface{} `toml:"extra_body"`          // Provider-specific request fields merged into the JSON body (e.g. top_k, repetition_penalty)
+	ExtraBodyOverride bool                   `toml:"extra_body_override"` // Let extra_body replace core fields like temperature (model, messages, stream are never replaced)
+	FieldMap          map[string]string      `toml:"field_map"`           // Rename top-level request fields before sending, e.g. { model = "model_id" }
+	FieldOmit         []string               `toml:"field_omit"`          // Drop top-level request fields before sending, e.g. ["top_p"]
 }
 
 // PromptTemplates holds all customizable prompt templates
 type PromptTemplates struct {
-	SubtopicGeneration   string `toml:"subtopic_generation"`
-	PromptGeneration     string `toml:"prompt_generation"`
-	ChosenGeneration     string `toml:"chosen_generation"`
-	RejectedGeneration   string `toml:"rejected_generation"`
-	JudgeRubric          string `toml:"judge_rubric"`
-	ChosenSystemPrompt   string `toml:"chosen_system_prompt"`   // Optional system prompt for chosen generation
-	RejectedSystemPrompt string `toml:"rejected_system_prompt"` // Optional system prompt for rejected generation
-	SubtopicSystemPrompt string `toml:"subtopic_system_prompt"` // Optional system prompt for subtopic generation
-	PromptSystemPrompt   string `toml:"prompt_system_prompt"`   // Optional system prompt for prompt generation
-	JudgeSystemPrompt    string `toml:"judge_system_prompt"`    // Optional system prompt for judge evaluation
+	SubtopicGeneration   string `toml:"subtopic_generation,multiline"`
+	PromptGeneration     string `toml:"prompt_generation,multiline"`
+	ChosenGeneration     string `toml:"chosen_generation,multiline"`
+	RejectedGeneration   string `toml:"rejected_generation,multiline"`
+	JudgeRubric          string `toml:"judge_rubric,multiline"`
+	ChosenSystemPrompt   string `toml:"chosen_system_prompt,multiline"`   // Optional system prompt for chosen generation
+	RejectedSystemPrompt string `toml:"rejected_system_prompt,multiline"` // Optional system prompt for rejected generation
+	SubtopicSystemPrompt string `toml:"subtopic_system_prompt,multiline"` // Optional system prompt for subtopic generation
+	PromptSystemPrompt   string `toml:"prompt_system_prompt,multiline"`   // Optional system prompt for prompt generation
+	JudgeSystemPrompt    string `toml:"judge_system_prompt,multiline"`    // Optional system prompt for judge evaluation
+
+	ChosenExamples []FewShotExample `toml:"chosen_examples"` // Optional few-shot examples exposed to chosen_generation as {{.Examples}}
+}
+
+// FewShotExample is an example input/output pair rendered into few-shot prompt blocks
+type FewShotExample struct {
+	Input  string `toml:"input,multiline"`
+	Output string `toml:"output,multiline"`
 }
 
 // HuggingFaceConfig holds Hugging Face Hub settings
 type HuggingFaceConfig struct {
 	RepoID string `toml:"repo_id"`
+	Branch string `toml:"branch"` // Branch to commit to (default: main, created if missing)
+	Append bool   `toml:"append"` // Append rows to the existing remote dataset instead of replacing it
+	Shard  bool   `toml:"shard"`  // Split dataset files above HF's 10MB text limit into dataset-00001.jsonl, ... shards
+
+	ReadyTimeout      string `toml:"ready_timeout"`       // Max wait for a newly created repo to become available, e.g. "2m" (default: 60s)
+	ReadyPollInterval string `toml:"ready_poll_interval"` // How often that wait checks the repo, e.g. "500ms" (default: 1s)
+
+	UploadTimeoutSeconds int `toml:"upload_timeout_seconds"` // Timeout of each LFS file upload (default: 600)
+	CommitTimeoutSeconds int `toml:"commit_timeout_seconds"` // Timeout of each commit request (default: 300)
+	MaxRetries           int `toml:"max_retries"`            // Retries of failed preupload, upload and commit requests (default: 3, -1 = no retries)
+
+	CommitMessage     string `toml:"commit_message"`     // Commit summary template, e.g. "{{.DatasetMode}}: {{.Rows}} rows from {{.Session}}" (default: "Upload dataset from VellumForge2 session {{.Session}}")
+	CommitDescription string `toml:"commit_description"` // Commit description template; the upload metadata (rows, mode, config hash) is always appended
+}
+
+// ReadyTimeoutDuration returns the parsed ready_timeout (0 = default)
+func (h HuggingFaceConfig) ReadyTimeoutDuration() time.Duration {
+	return parseOptionalDuration(h.ReadyTimeout)
+}
+
+// ReadyPollIntervalDuration returns the parsed ready_poll_interval (0 = default)
+func (h HuggingFaceConfig) ReadyPollIntervalDuration() time.Duration {
+	return parseOptionalDuration(h.ReadyPollInterval)
+}
+
+// parseOptionalDuration parses an optional duration setting, returning 0 when it is empty
+// Invalid values are rejected by Validate, so they also fall back to 0 here
+func parseOptionalDuration(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+func (h HuggingFaceConfig) validate() error {
+	settings := []struct{ key, value string }{
+		{"huggingface.ready_timeout", h.ReadyTimeout},
+		{"huggingface.ready_poll_interval", h.ReadyPollInterval},
+	}
+	for _, s := range settings {
+		if s.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(s.value); err != nil || d <= 0 {
+			return fmt.Errorf("%s must be a positive duration such as \"30s\" (got %q)", s.key, s.value)
+		}
+	}
+	if timeout, interval := h.ReadyTimeoutDuration(), h.ReadyPollIntervalDuration(); timeout > 0 && interval > timeout {
+		return fmt.Errorf("huggingface.ready_poll_interval (%s) must not exceed huggingface.ready_timeout (%s)", h.ReadyPollInterval, h.ReadyTimeout)
+	}
+	if h.UploadTimeoutSeconds < 0 {
+		return fmt.Errorf("huggingface.upload_timeout_seconds must not be negative (got %d)", h.UploadTimeoutSeconds)
+	}
+	if h.CommitTimeoutSeconds < 0 {
+		return fmt.Errorf("huggingface.commit_timeout_seconds must not be negative (got %d)", h.CommitTimeoutSeconds)
+	}
+	if h.MaxRetries < -1 {
+		return fmt.Errorf("huggingface.max_retries must be at least -1 (-1 = no retries, got %d)", h.MaxRetries)
+	}
+	return nil
 }
 
 // Secrets holds sensitive credentials loaded from environment variables
 type Secrets struct {
 	APIKeys          map[string]string
 	HuggingFaceToken string
+	keyPools         map[string]*KeyPool // Providers with several keys (<VAR>S), rotated per request
 }
 
 const (
@@ -100,6 +720,71 @@ const (
 	MaxNumSubtopics = 10000
 	// MaxNumPromptsPerSubtopic is the maximum prompts per subtopic
 	MaxNumPromptsPerSubtopic = 10000
+	// MaxPromptSamples is the maximum independent prompt requests per subtopic
+	MaxPromptSamples = 20
+	// MaxNumRejected is the maximum rejected responses per prompt
+	MaxNumRejected = 16
+	// MaxSubtopicWeight is the highest generation.max_subtopic_weight allowed
+	MaxSubtopicWeight = 100
+	// MaxMainTopics is the maximum number of generation.main_topics
+	MaxMainTopics = 1000
+)
+
+const (
+	// JudgeFailureActionAbort stops the run when the judge circuit breaker trips
+	JudgeFailureActionAbort = "abort"
+	// JudgeFailureActionFlag keeps generating and writes unscored records flagged judge_failed
+	JudgeFailureActionFlag = "flag"
+)
+
+const (
+	// FingerprintChangeWarn logs a warning and keeps generating when a model's system_fingerprint changes
+	FingerprintChangeWarn = "warn"
+	// FingerprintChangeAbort stops the run so a dataset is never built across two backend configurations
+	FingerprintChangeAbort = "abort"
+)
+
+const (
+	// UnicodeNFC composes characters (e + combining accent -> é) without changing how text looks
+	UnicodeNFC = "nfc"
+	// UnicodeNFKC also folds compatibility characters (non-breaking space, ligatures, full-width forms)
+	UnicodeNFKC = "nfkc"
+)
+
+const (
+	// InvalidScoreClamp rounds fractional scores and clamps them into [score_min, score_max]
+	InvalidScoreClamp = "clamp"
+	// InvalidScoreRetry discards the evaluation and asks the judge again
+	InvalidScoreRetry = "retry"
+	// InvalidScoreDrop leaves the offending criterion out of the average
+	InvalidScoreDrop = "drop"
+)
+
+const (
+	// IdenticalPairDrop fails the job (counted as identical_pair, and toward max_failure_rate) when rejected matches chosen
+	IdenticalPairDrop = "drop"
+	// IdenticalPairRegen regenerates a matching rejected response once, then drops the job if it still matches
+	IdenticalPairRegen = "regen"
+	// IdenticalPairKeep writes identical pairs unchanged and logs a warning (default)
+	IdenticalPairKeep = "keep"
+)
+
+const (
+	// LengthRatioDrop filters pairs whose chosen/rejected length ratio is out of range
+	LengthRatioDrop = "drop"
+	// LengthRatioFlag writes out-of-range pairs anyway and counts them
+	LengthRatioFlag = "flag"
+)
+
+const (
+	// PromptOverflowWarn logs a warning and sends the prompt unchanged (default); the estimate is rough
+	PromptOverflowWarn = "warn"
+	// PromptOverflowError fails a request whose estimated prompt exceeds context_size - max_output_tokens
+	PromptOverflowError = "error"
+	// PromptOverflowTruncate shortens the longest message to fit and logs a warning
+	PromptOverflowTruncate = "truncate"
+	// PromptOverflowOff sends prompts without checking their length
+	PromptOverflowOff = "off"
 )
 
 // Validate checks if the configuration is valid
@@ -113,6 +798,50 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("provider_burst_percent must be between 1 and 50 (got %d)", c.ProviderBurstPercent)
 	}
 
+	// Validate network settings (defaults applied by loader)
+	if c.Network.MaxIdleConns < 0 || c.Network.MaxIdleConnsPerHost < 0 || c.Network.MaxConnsPerHost < 0 || c.Network.IdleConnTimeoutSeconds < 0 {
+		return fmt.Errorf("network settings must not be negative")
+	}
+	if c.Network.MaxConnsPerHost > 0 && c.Network.MaxIdleConnsPerHost > c.Network.MaxConnsPerHost {
+		return fmt.Errorf("network.max_idle_conns_per_host (%d) must not exceed network.max_conns_per_host (%d)",
+			c.Network.MaxIdleConnsPerHost, c.Network.MaxConnsPerHost)
+	}
+	if strings.ContainsAny(c.Network.UserAgent, "\r\n") {
+		return fmt.Errorf("network.user_agent must be a single line")
+	}
+	if err := c.Retry.validate(); err != nil {
+		return err
+	}
+	if err := c.Logging.validate(); err != nil {
+		return err
+	}
+	if err := c.HuggingFace.validate(); err != nil {
+		return err
+	}
+	if err := c.Embeddings.validate(); err != nil {
+		return err
+	}
+	if err := c.RewardModel.validate(); err != nil {
+		return err
+	}
+	if c.RewardModel.Enabled && c.JudgeFiltering.Enabled && c.Generation.DatasetMode != models.DatasetModeMODPO {
+		return fmt.Errorf("reward_model.enabled and judge_filtering.enabled are alternatives; enable only one")
+	}
+	if err := c.Dataset.validate(); err != nil {
+		return err
+	}
+	if c.Network.InsecureSkipVerify {
+		fmt.Fprintf(os.Stderr, "WARNING: network.insecure_skip_verify=true disables TLS certificate verification for ALL endpoints - API keys and data can be intercepted\n")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Models)) {
+		if c.Models[name].InsecureSkipVerify && !c.Network.InsecureSkipVerify {
+			fmt.Fprintf(os.Stderr, "WARNING: models.%s.insecure_skip_verify=true disables TLS certificate verification for %s\n", name, c.Models[name].BaseURL)
+		}
+		if c.Models[name].UseToolCalling && name != "main" {
+			fmt.Fprintf(os.Stderr, "WARNING: models.%s.use_tool_calling has no effect - only the main model generates subtopics and prompts\n", name)
+		}
+	}
+
 	// Set default dataset mode if not specified
 	if c.Generation.DatasetMode == "" {
 		c.Generation.DatasetMode = models.DatasetModeMODPO // Default to current behavior
@@ -133,18 +862,51 @@ func (c *Config) Validate() error {
 	// Validate SFT format selection
 	if c.Generation.DatasetMode == models.DatasetModeSFT {
 		switch c.Generation.SFTFormat {
-		case "", models.SFTFormatAlpaca, models.SFTFormatShareGPT:
+		case "", models.SFTFormatAlpaca, models.SFTFormatShareGPT, models.SFTFormatOpenAI:
 			if c.Generation.SFTFormat == "" {
 				c.Generation.SFTFormat = models.SFTFormatShareGPT
 			}
 		default:
-			return fmt.Errorf("generation.sft_format must be 'alpaca' or 'sharegpt' (got %s)", c.Generation.SFTFormat)
+			return fmt.Errorf("generation.sft_format must be 'alpaca', 'sharegpt', or 'openai' (got %s)", c.Generation.SFTFormat)
+		}
+	}
+
+	// Validate dataset JSON layout
+	switch c.Generation.JSONIndent {
+	case "":
+		c.Generation.JSONIndent = JSONIndentCompact
+	case JSONIndentCompact:
+	case JSONIndentPretty:
+		return fmt.Errorf("generation.json_indent = 'pretty' is not supported: dataset files are JSONL with one record per line, which indented JSON would break (use 'compact')")
+	default:
+		return fmt.Errorf("generation.json_indent must be 'compact' (got %s)", c.Generation.JSONIndent)
+	}
+
+	// Validate DPO format selection
+	switch c.Generation.DPOFormat {
+	case "":
+		c.Generation.DPOFormat = models.DPOFormatStandard
+	case models.DPOFormatStandard, models.DPOFormatConversational:
+	default:
+		return fmt.Errorf("generation.dpo_format must be 'standard' or 'conversational' (got %s)", c.Generation.DPOFormat)
+	}
+	if c.Generation.DPOFormat == models.DPOFormatConversational && c.Generation.DatasetMode != models.DatasetModeDPO {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.dpo_format = 'conversational' only applies to dataset_mode = 'dpo'\n")
+	}
+	if c.Generation.IncludeSystemPrompt {
+		switch {
+		case c.Generation.DatasetMode != models.DatasetModeDPO && c.Generation.DatasetMode != models.DatasetModeKTO:
+			fmt.Fprintf(os.Stderr, "WARNING: generation.include_system_prompt only applies to dataset_mode = 'dpo' or 'kto'\n")
+		case c.Generation.DatasetMode == models.DatasetModeDPO && c.Generation.DPOFormat == models.DPOFormatConversational:
+			fmt.Fprintf(os.Stderr, "WARNING: generation.include_system_prompt has no effect with dpo_format = 'conversational' (the prompt already starts with the system message)\n")
+		case c.PromptTemplates.ChosenSystemPrompt == "":
+			fmt.Fprintf(os.Stderr, "WARNING: generation.include_system_prompt has no effect without prompt_templates.chosen_system_prompt\n")
 		}
 	}
 
 	// Validate generation config
-	if c.Generation.MainTopic == "" {
-		return fmt.Errorf("generation.main_topic is required")
+	if err := c.Generation.validateMainTopics(c.Generation.DisableValidationLimits); err != nil {
+		return err
 	}
 	if c.Generation.NumSubtopics < 1 {
 		return fmt.Errorf("generation.num_subtopics must be at least 1")
@@ -164,14 +926,32 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("generation.num_prompts_per_subtopic must not exceed %d (got %d)", MaxNumPromptsPerSubtopic, c.Generation.NumPromptsPerSubtopic)
 		}
 	}
+	if c.Generation.MaxSubtopicWeight < 0 || c.Generation.MaxSubtopicWeight > MaxSubtopicWeight {
+		return fmt.Errorf("generation.max_subtopic_weight must be between 1 and %d (got %d)", MaxSubtopicWeight, c.Generation.MaxSubtopicWeight)
+	}
+	if c.Generation.WeightedSubtopics && !c.Generation.DisableValidationLimits {
+		// The heaviest subtopic must still fit the per-subtopic prompt limit
+		if heaviest := c.Generation.NumPromptsPerSubtopic * max(1, c.Generation.MaxSubtopicWeight); heaviest > MaxNumPromptsPerSubtopic {
+			return fmt.Errorf("generation.num_prompts_per_subtopic x max_subtopic_weight must not exceed %d (got %d)", MaxNumPromptsPerSubtopic, heaviest)
+		}
+	}
+	if !c.Generation.WeightedSubtopics && c.Generation.MaxSubtopicWeight > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.max_subtopic_weight has no effect unless generation.weighted_subtopics = true\n")
+	}
 	if c.Generation.Concurrency < 1 {
 		return fmt.Errorf("generation.concurrency must be at least 1")
 	}
+	if c.Generation.PromptConcurrency < 0 {
+		return fmt.Errorf("generation.prompt_concurrency must be 0 (use concurrency) or at least 1 (got %d)", c.Generation.PromptConcurrency)
+	}
 	// Skip upper bound validation if disabled
 	if !c.Generation.DisableValidationLimits {
 		if c.Generation.Concurrency > MaxConcurrency {
 			return fmt.Errorf("generation.concurrency must not exceed %d (got %d)", MaxConcurrency, c.Generation.Concurrency)
 		}
+		if c.Generation.PromptConcurrency > MaxConcurrency {
+			return fmt.Errorf("generation.prompt_concurrency must not exceed %d (got %d)", MaxConcurrency, c.Generation.PromptConcurrency)
+		}
 	}
 	if c.Generation.OverGenerationBuffer < 0 || c.Generation.OverGenerationBuffer > 1.0 {
 		return fmt.Errorf("generation.over_generation_buffer must be between 0.0 and 1.0 (got %.2f)", c.Generation.OverGenerationBuffer)
@@ -179,9 +959,175 @@ func (c *Config) Validate() error {
 	if c.Generation.MinSuccessRate < 0 || c.Generation.MinSuccessRate > 1.0 {
 		return fmt.Errorf("generation.min_success_rate must be between 0.0 and 1.0 (got %.2f)", c.Generation.MinSuccessRate)
 	}
+	if c.Generation.ListMinItems < 0 {
+		return fmt.Errorf("generation.list_min_items must be non-negative (got %d)", c.Generation.ListMinItems)
+	}
+	if c.Generation.ListMinItems == 0 {
+		c.Generation.ListMinItems = 1
+	}
+	if c.Generation.ListMaxRatio < 0 || (c.Generation.ListMaxRatio > 0 && c.Generation.ListMaxRatio < 1.0) {
+		return fmt.Errorf("generation.list_max_ratio must be 0 (no limit) or at least 1.0 (got %.2f)", c.Generation.ListMaxRatio)
+	}
 	if c.Generation.PromptRetryAttempts < 0 || c.Generation.PromptRetryAttempts > 5 {
 		return fmt.Errorf("generation.prompt_retry_attempts must be between 0 and 5 (got %d)", c.Generation.PromptRetryAttempts)
 	}
+	if c.Generation.PromptSamples == 0 {
+		c.Generation.PromptSamples = 1
+	}
+	if c.Generation.PromptSamples < 1 || c.Generation.PromptSamples > MaxPromptSamples {
+		return fmt.Errorf("generation.prompt_samples must be between 1 and %d (got %d)", MaxPromptSamples, c.Generation.PromptSamples)
+	}
+	if c.Generation.PromptSampleTemperature < 0 || c.Generation.PromptSampleTemperature > 2 {
+		return fmt.Errorf("generation.prompt_sample_temperature must be between 0.0 and 2.0 (got %.2f)", c.Generation.PromptSampleTemperature)
+	}
+	if c.Generation.PromptSamples > c.Generation.NumPromptsPerSubtopic {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.prompt_samples (%d) exceeds num_prompts_per_subtopic (%d); each sample still asks for at least one prompt\n",
+			c.Generation.PromptSamples, c.Generation.NumPromptsPerSubtopic)
+	}
+	if c.Generation.SwapProbability < 0 || c.Generation.SwapProbability > 1.0 {
+		return fmt.Errorf("generation.swap_probability must be between 0.0 and 1.0 (got %.2f)", c.Generation.SwapProbability)
+	}
+	if c.Generation.SwapProbability > 0 && c.Generation.DatasetMode == models.DatasetModeSFT {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.swap_probability has no effect in SFT mode (no rejected responses)\n")
+	}
+	if c.Generation.RecordFilterTimeoutSeconds < 0 {
+		return fmt.Errorf("generation.record_filter_timeout_seconds must be non-negative (got %d)", c.Generation.RecordFilterTimeoutSeconds)
+	}
+	if c.Generation.TemperatureJitter < 0 || c.Generation.TemperatureJitter > 1.0 {
+		return fmt.Errorf("generation.temperature_jitter must be between 0.0 and 1.0 (got %.2f)", c.Generation.TemperatureJitter)
+	}
+	if c.Generation.NumRejected == 0 {
+		c.Generation.NumRejected = 1
+	}
+	if c.Generation.RejectedOutputShape == "" {
+		c.Generation.RejectedOutputShape = models.RejectedShapeRows
+	}
+	if c.Generation.NumRejected < 1 || c.Generation.NumRejected > MaxNumRejected {
+		return fmt.Errorf("generation.num_rejected must be between 1 and %d (got %d)", MaxNumRejected, c.Generation.NumRejected)
+	}
+	switch c.Generation.RejectedOutputShape {
+	case models.RejectedShapeRows, models.RejectedShapeArray:
+	default:
+		return fmt.Errorf("generation.rejected_output_shape must be 'rows' or 'array' (got %s)", c.Generation.RejectedOutputShape)
+	}
+	if c.Generation.NumRejected > 1 && c.Generation.RejectedOutputShape == models.RejectedShapeArray &&
+		c.Generation.DatasetMode == models.DatasetModeDPO && c.Generation.DPOFormat == models.DPOFormatConversational {
+		return fmt.Errorf("generation.rejected_output_shape = 'array' is not supported with dpo_format = 'conversational' (use 'rows')")
+	}
+	if c.Generation.NumRejected > 1 {
+		switch c.Generation.DatasetMode {
+		case models.DatasetModeMODPO:
+			return fmt.Errorf("generation.num_rejected > 1 is not supported in mo-dpo mode (judge scores a single pair)")
+		case models.DatasetModeSFT:
+			fmt.Fprintf(os.Stderr, "WARNING: generation.num_rejected has no effect in SFT mode (no rejected responses)\n")
+		case models.DatasetModeKTO:
+			if c.Generation.RejectedOutputShape == models.RejectedShapeArray {
+				fmt.Fprintf(os.Stderr, "WARNING: generation.rejected_output_shape = 'array' has no effect in KTO mode (one row per completion)\n")
+			}
+		}
+	}
+	if c.Generation.KTORatio != 0 {
+		if c.Generation.KTORatio < 0 || c.Generation.KTORatio > MaxNumRejected {
+			return fmt.Errorf("generation.kto_ratio must be between 0.0 and %d (got %.2f)", MaxNumRejected, c.Generation.KTORatio)
+		}
+		if c.Generation.DatasetMode != models.DatasetModeKTO {
+			fmt.Fprintf(os.Stderr, "WARNING: generation.kto_ratio only applies to KTO mode and will be ignored\n")
+		} else if c.Generation.NumRejected > 1 {
+			return fmt.Errorf("generation.kto_ratio and generation.num_rejected > 1 both set the rejected count in KTO mode (use one)")
+		}
+	}
+	if c.Generation.JudgeFailureThreshold == 0 {
+		c.Generation.JudgeFailureThreshold = 10
+	}
+	if c.Generation.JudgeFailureThreshold < -1 {
+		return fmt.Errorf("generation.judge_failure_threshold must be -1 (disabled) or at least 1 (got %d)", c.Generation.JudgeFailureThreshold)
+	}
+	if c.Generation.FallbackAfterFailures == 0 {
+		c.Generation.FallbackAfterFailures = 3
+	}
+	if c.Generation.FallbackAfterFailures < 1 {
+		return fmt.Errorf("generation.fallback_after_failures must be at least 1 (got %d)", c.Generation.FallbackAfterFailures)
+	}
+	switch c.Generation.JudgeFailureAction {
+	case "":
+		c.Generation.JudgeFailureAction = JudgeFailureActionAbort
+	case JudgeFailureActionAbort, JudgeFailureActionFlag:
+	default:
+		return fmt.Errorf("generation.judge_failure_action must be 'abort' or 'flag' (got %s)", c.Generation.JudgeFailureAction)
+	}
+	if c.Generation.ShutdownGraceSeconds == 0 {
+		c.Generation.ShutdownGraceSeconds = DefaultShutdownGraceSeconds
+	}
+	if c.Generation.ShutdownGraceSeconds < -1 {
+		return fmt.Errorf("generation.shutdown_grace_seconds must be -1 (stop immediately) or positive (got %d)", c.Generation.ShutdownGraceSeconds)
+	}
+	if c.Generation.WarmupSeconds < 0 || c.Generation.WarmupSeconds > MaxWarmupSeconds {
+		return fmt.Errorf("generation.warmup_seconds must be between 0 and %d (got %d)", MaxWarmupSeconds, c.Generation.WarmupSeconds)
+	}
+	switch c.Generation.NormalizeUnicode {
+	case "", UnicodeNFC, UnicodeNFKC:
+	default:
+		return fmt.Errorf("generation.normalize_unicode must be 'nfc', 'nfkc' or empty (got %s)", c.Generation.NormalizeUnicode)
+	}
+	switch c.Generation.OnFingerprintChange {
+	case "":
+		c.Generation.OnFingerprintChange = FingerprintChangeWarn
+	case FingerprintChangeWarn, FingerprintChangeAbort:
+	default:
+		return fmt.Errorf("generation.on_fingerprint_change must be 'warn' or 'abort' (got %s)", c.Generation.OnFingerprintChange)
+	}
+	if c.Generation.MaxBufferedRecords < 0 {
+		return fmt.Errorf("generation.max_buffered_records must be 0 (unbounded) or positive (got %d)", c.Generation.MaxBufferedRecords)
+	}
+	if c.Generation.SubtopicDedupSimilarity < 0 || c.Generation.SubtopicDedupSimilarity > 1.0 {
+		return fmt.Errorf("generation.subtopic_dedup_similarity must be between 0.0 and 1.0 (got %.2f)", c.Generation.SubtopicDedupSimilarity)
+	}
+	if c.Generation.MaxFailureRate < 0 || c.Generation.MaxFailureRate > 1.0 {
+		return fmt.Errorf("generation.max_failure_rate must be between 0.0 and 1.0 (got %.2f)", c.Generation.MaxFailureRate)
+	}
+	if c.Generation.FailureRateMinSamples == 0 {
+		c.Generation.FailureRateMinSamples = 20
+	}
+	if c.Generation.FailureRateMinSamples < 1 {
+		return fmt.Errorf("generation.failure_rate_min_samples must be at least 1 (got %d)", c.Generation.FailureRateMinSamples)
+	}
+	if c.Generation.OrderedOutputBuffer == 0 {
+		c.Generation.OrderedOutputBuffer = max(c.Generation.Concurrency, 1) * 10
+	}
+	if c.Generation.OrderedOutputBuffer < 1 {
+		return fmt.Errorf("generation.ordered_output_buffer must be at least 1 (got %d)", c.Generation.OrderedOutputBuffer)
+	}
+	if c.Generation.TruncationRetryFactor == 0 {
+		c.Generation.TruncationRetryFactor = 2.0
+	}
+	if c.Generation.TruncationRetryFactor <= 1.0 {
+		return fmt.Errorf("generation.truncation_retry_factor must be greater than 1.0 (got %.2f)", c.Generation.TruncationRetryFactor)
+	}
+	if c.Generation.MaxRuntime != "" {
+		d, err := time.ParseDuration(c.Generation.MaxRuntime)
+		if err != nil {
+			return fmt.Errorf("generation.max_runtime must be a duration such as \"2h\" or \"90m\" (got %q)", c.Generation.MaxRuntime)
+		}
+		if d <= 0 {
+			return fmt.Errorf("generation.max_runtime must be positive (got %s)", c.Generation.MaxRuntime)
+		}
+	}
+	if c.Generation.MaxOutputBytes < 0 {
+		return fmt.Errorf("generation.max_output_bytes must be 0 (no limit) or positive (got %d)", c.Generation.MaxOutputBytes)
+	}
+	switch c.Generation.OnIdenticalPair {
+	case "":
+		c.Generation.OnIdenticalPair = IdenticalPairKeep
+	case IdenticalPairDrop, IdenticalPairRegen, IdenticalPairKeep:
+	default:
+		return fmt.Errorf("generation.on_identical_pair must be 'drop', 'regen', or 'keep' (got %s)", c.Generation.OnIdenticalPair)
+	}
+	if err := c.Generation.validateLengthRatio(); err != nil {
+		return err
+	}
+	if c.Generation.PromptCacheTTLHours < 0 {
+		return fmt.Errorf("generation.prompt_cache_ttl_hours must not be negative (got %d)", c.Generation.PromptCacheTTLHours)
+	}
 	if c.Generation.CheckpointInterval < 1 {
 		// Set default if not specified
 		c.Generation.CheckpointInterval = 10
@@ -192,22 +1138,47 @@ func (c *Config) Validate() error {
 	if !ok {
 		return fmt.Errorf("models.main is required")
 	}
+
+	// Jittered temperatures are clamped to [0, 2]; warn when that narrows the range
+	if jitter := c.Generation.TemperatureJitter; jitter > 0 {
+		sides := map[string]ModelConfig{"main": mainModel}
+		if rejectedModel, ok := c.RejectedModel(); ok {
+			sides["rejected"] = rejectedModel
+		}
+		for _, name := range []string{"main", "rejected"} {
+			if mc, ok := sides[name]; ok && (mc.Temperature-jitter < 0 || mc.Temperature+jitter > 2.0) {
+				fmt.Fprintf(os.Stderr, "WARNING: models.%s.temperature %.2f +/- temperature_jitter %.2f exceeds [0.0, 2.0] and will be clamped\n",
+					name, mc.Temperature, jitter)
+			}
+		}
+	}
 	if err := validateModelConfig("main", mainModel); err != nil {
 		return err
 	}
 
-	// Validate rejected model exists (unless SFT mode)
+	// Validate rejected model exists (unless SFT mode or rejected_from_main)
+	if c.Generation.RejectedTemperatureOffset < -2 || c.Generation.RejectedTemperatureOffset > 2 {
+		return fmt.Errorf("generation.rejected_temperature_offset must be between -2.0 and 2.0 (got %.2f)", c.Generation.RejectedTemperatureOffset)
+	}
 	rejectedModel, ok := c.Models["rejected"]
-	if !ok {
-		if c.Generation.DatasetMode != models.DatasetModeSFT {
-			return fmt.Errorf("models.rejected is required for dataset_mode=%s", c.Generation.DatasetMode)
-		}
-		// Warn if missing in SFT mode
-		fmt.Fprintf(os.Stderr, "WARNING: models.rejected not configured for SFT mode - only chosen responses will be generated\n")
-	} else {
+	switch {
+	case ok:
 		if err := validateModelConfig("rejected", rejectedModel); err != nil {
 			return err
 		}
+		if c.Generation.RejectedFromMain {
+			fmt.Fprintf(os.Stderr, "WARNING: generation.rejected_from_main is ignored because models.rejected is configured\n")
+		}
+	case c.Generation.RejectedFromMain:
+		if temperature := mainModel.Temperature + c.Generation.RejectedOffset(); temperature < 0 || temperature > 2 {
+			fmt.Fprintf(os.Stderr, "WARNING: models.main.temperature %.2f + rejected_temperature_offset %.2f exceeds [0.0, 2.0] and will be clamped\n",
+				mainModel.Temperature, c.Generation.RejectedOffset())
+		}
+	case c.Generation.DatasetMode != models.DatasetModeSFT:
+		return fmt.Errorf("models.rejected is required for dataset_mode=%s (or set generation.rejected_from_main = true)", c.Generation.DatasetMode)
+	default:
+		// Warn if missing in SFT mode
+		fmt.Fprintf(os.Stderr, "WARNING: models.rejected not configured for SFT mode - only chosen responses will be generated\n")
 	}
 
 	// Validate judge model if enabled
@@ -221,6 +1192,29 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate fallback models (switched to after repeated failures of the primary)
+	for _, role := range []string{"main", "rejected", "judge"} {
+		name := role + "_fallback"
+		fallback, ok := c.Models[name]
+		if !ok {
+			continue
+		}
+		if err := validateModelConfig(name, fallback); err != nil {
+			return err
+		}
+		primary, ok := c.Models[role]
+		switch {
+		case role == "judge" && !primary.Enabled:
+			fmt.Fprintf(os.Stderr, "WARNING: models.judge_fallback has no effect unless models.judge is enabled\n")
+		case !ok:
+			fmt.Fprintf(os.Stderr, "WARNING: models.%s has no effect without models.%s\n", name, role)
+		}
+	}
+
+	if c.Generation.EnableJudgeCache && (!judgeExists || !judgeModel.Enabled) {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.enable_judge_cache has no effect unless models.judge is enabled\n")
+	}
+
 	// MO-DPO mode requires judge
 	if c.Generation.DatasetMode == models.DatasetModeMODPO {
 		if !judgeExists || !judgeModel.Enabled {
@@ -228,16 +1222,32 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate judge score scale (applies to both filtering and MO-DPO scoring)
+	lo, hi := c.JudgeFiltering.ScoreScale()
+	if lo >= hi {
+		return fmt.Errorf("judge_filtering.score_min must be less than score_max (got %d and %d)", lo, hi)
+	}
+	c.JudgeFiltering.ScoreMin, c.JudgeFiltering.ScoreMax = &lo, &hi
+	switch c.JudgeFiltering.InvalidScoreAction {
+	case "":
+		c.JudgeFiltering.InvalidScoreAction = InvalidScoreClamp
+	case InvalidScoreClamp, InvalidScoreRetry, InvalidScoreDrop:
+	default:
+		return fmt.Errorf("judge_filtering.invalid_score_action must be 'clamp', 'retry', or 'drop' (got %s)", c.JudgeFiltering.InvalidScoreAction)
+	}
+	scoreMin := float64(lo)
+	scoreMax := float64(hi)
+
 	// Validate judge filtering config
 	if c.JudgeFiltering.Enabled {
 		if !judgeExists || !judgeModel.Enabled {
 			return fmt.Errorf("judge_filtering.enabled=true requires models.judge with enabled=true")
 		}
-		if c.JudgeFiltering.MinChosenScore < 1.0 || c.JudgeFiltering.MinChosenScore > 5.0 {
-			return fmt.Errorf("judge_filtering.min_chosen_score must be between 1.0 and 5.0 (got %.2f)", c.JudgeFiltering.MinChosenScore)
+		if c.JudgeFiltering.MinChosenScore < scoreMin || c.JudgeFiltering.MinChosenScore > scoreMax {
+			return fmt.Errorf("judge_filtering.min_chosen_score must be between %.1f and %.1f (got %.2f)", scoreMin, scoreMax, c.JudgeFiltering.MinChosenScore)
 		}
-		if c.JudgeFiltering.MaxRejectedScore < 1.0 || c.JudgeFiltering.MaxRejectedScore > 5.0 {
-			return fmt.Errorf("judge_filtering.max_rejected_score must be between 1.0 and 5.0 (got %.2f)", c.JudgeFiltering.MaxRejectedScore)
+		if c.JudgeFiltering.MaxRejectedScore < scoreMin || c.JudgeFiltering.MaxRejectedScore > scoreMax {
+			return fmt.Errorf("judge_filtering.max_rejected_score must be between %.1f and %.1f (got %.2f)", scoreMin, scoreMax, c.JudgeFiltering.MaxRejectedScore)
 		}
 		// Set default thresholds if not specified
 		if c.JudgeFiltering.MinChosenScore == 0 {
@@ -248,6 +1258,17 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate preference margin filter (applied to MO-DPO records after async judging)
+	if c.JudgeFiltering.MinPreferenceMargin < 0 || c.JudgeFiltering.MinPreferenceMargin > scoreMax-scoreMin {
+		return fmt.Errorf("judge_filtering.min_preference_margin must be between 0.0 and %.1f (got %.2f)", scoreMax-scoreMin, c.JudgeFiltering.MinPreferenceMargin)
+	}
+	if c.JudgeFiltering.MinPreferenceMargin > 0 && c.Generation.DatasetMode != models.DatasetModeMODPO {
+		fmt.Fprintf(os.Stderr, "WARNING: judge_filtering.min_preference_margin only applies in mo-dpo mode and will be ignored\n")
+	}
+
+	if c.Generation.MaxBufferedRecords > 0 && c.Generation.DatasetMode != models.DatasetModeMODPO {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.max_buffered_records only applies in mo-dpo mode and will be ignored\n")
+	}
 	// Warn if judge filtering is enabled in MO-DPO mode (redundant)
 	if c.Generation.DatasetMode == models.DatasetModeMODPO && c.JudgeFiltering.Enabled {
 		fmt.Fprintf(os.Stderr, "WARNING: judge_filtering is redundant in mo-dpo mode (judge scoring is always included)\n")
@@ -266,10 +1287,20 @@ func (c *Config) Validate() error {
 	if c.PromptTemplates.RejectedGeneration == "" {
 		return fmt.Errorf("prompt_templates.rejected_generation is required")
 	}
+	usesChosen := chosenPlaceholder.MatchString(c.PromptTemplates.RejectedGeneration)
+	if usesChosen && !c.Generation.RejectedSeesChosen {
+		return fmt.Errorf("prompt_templates.rejected_generation uses {{.Chosen}}, which requires generation.rejected_sees_chosen = true")
+	}
+	if c.Generation.RejectedSeesChosen && !usesChosen {
+		fmt.Fprintf(os.Stderr, "WARNING: generation.rejected_sees_chosen has no effect unless prompt_templates.rejected_generation uses {{.Chosen}}\n")
+	}
 
 	return nil
 }
 
+// chosenPlaceholder matches a {{.Chosen}} reference in a template action
+var chosenPlaceholder = regexp.MustCompile(`\{\{[^}]*\.Chosen\b`)
+
 func validateModelConfig(name string, mc ModelConfig) error {
 	if mc.BaseURL == "" {
 		return fmt.Errorf("models.%s.base_url is required", name)
@@ -295,46 +1326,121 @@ func validateModelConfig(name string, mc ModelConfig) error {
 	if mc.RateLimitPerMinute < 1 {
 		return fmt.Errorf("models.%s.rate_limit_per_minute must be at least 1", name)
 	}
+	switch mc.PromptOverflow {
+	case "", PromptOverflowWarn, PromptOverflowError, PromptOverflowTruncate, PromptOverflowOff:
+	default:
+		return fmt.Errorf("models.%s.prompt_overflow must be 'warn', 'error', 'truncate', or 'off' (got %s)", name, mc.PromptOverflow)
+	}
 	if mc.MaxOutputTokens > mc.ContextSize {
 		return fmt.Errorf("models.%s.max_output_tokens (%d) must not exceed context_size (%d)", name, mc.MaxOutputTokens, mc.ContextSize)
 	}
+	if mc.MaxResponseBytes < -1 {
+		return fmt.Errorf("models.%s.max_response_bytes must be -1 (unlimited), 0 (default) or positive (got %d)", name, mc.MaxResponseBytes)
+	}
+	if mc.AuthHeader != "" && !isHeaderName(mc.AuthHeader) {
+		return fmt.Errorf("models.%s.auth_header must be a valid HTTP header name (got %q)", name, mc.AuthHeader)
+	}
+	if strings.ContainsAny(mc.UserAgent, "\r\n") {
+		return fmt.Errorf("models.%s.user_agent must be a single line", name)
+	}
+	if strings.ContainsAny(mc.AuthScheme, " \t\r\n") {
+		return fmt.Errorf("models.%s.auth_scheme must be a single word such as \"Bearer\" or \"Token\" (got %q)", name, mc.AuthScheme)
+	}
+	return validateFieldMap(name, mc)
+}
+
+// coreRequestFields are the top-level fields the API client may send in a chat request
+var coreRequestFields = map[string]bool{
+	"model": true, "messages": true, "temperature": true, "top_p": true, "max_tokens": true, "n": true,
+	"stop": true, "response_format": true, "tools": true, "tool_choice": true, "stream": true,
+}
+
+// validateFieldMap checks that field_map and field_omit describe one unambiguous rewrite of the
+// request body: no field is both renamed and dropped, and no rename overwrites another field
+func validateFieldMap(name string, mc ModelConfig) error {
+	omitted := make(map[string]bool, len(mc.FieldOmit))
+	for _, field := range mc.FieldOmit {
+		switch field {
+		case "":
+			return fmt.Errorf("models.%s.field_omit must not contain empty field names", name)
+		case "messages", "stream":
+			return fmt.Errorf("models.%s.field_omit cannot drop %s", name, field)
+		}
+		omitted[field] = true
+	}
+
+	renamedFrom := make(map[string]string, len(mc.FieldMap))
+	for _, from := range slices.Sorted(maps.Keys(mc.FieldMap)) {
+		to := mc.FieldMap[from]
+		switch {
+		case from == "" || to == "":
+			return fmt.Errorf("models.%s.field_map must not contain empty field names", name)
+		case from == "stream" || to == "stream":
+			return fmt.Errorf("models.%s.field_map cannot rename stream", name)
+		case from == to:
+			return fmt.Errorf("models.%s.field_map renames %s to itself", name, from)
+		case omitted[from]:
+			return fmt.Errorf("models.%s.field_map renames %s, which field_omit drops", name, from)
+		}
+		if other, ok := renamedFrom[to]; ok {
+			return fmt.Errorf("models.%s.field_map renames both %s and %s to %s", name, other, from, to)
+		}
+		renamedFrom[to] = from
+
+		// The new name must not overwrite a field that is still sent under its own name
+		_, movedAway := mc.FieldMap[to]
+		_, inExtraBody := mc.ExtraBody[to]
+		if (coreRequestFields[to] || inExtraBody) && !movedAway && !omitted[to] {
+			return fmt.Errorf("models.%s.field_map renames %s to %s, which the request already sends (rename or omit %s as well)", name, from, to, to)
+		}
+	}
 	return nil
 }
 
+// isHeaderName reports whether name is a valid HTTP header field name (an RFC 7230 token)
+func isHeaderName(name string) bool {
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// HuggingFaceTokenEnvVars lists the environment variables checked for the Hugging Face token,
+// in order of precedence
+var HuggingFaceTokenEnvVars = []string{"HUGGING_FACE_TOKEN", "HUGGINGFACE_TOKEN", "HF_TOKEN"}
+
 // LoadSecrets loads sensitive credentials from environment variables
 func LoadSecrets() (*Secrets, error) {
 	secrets := &Secrets{
 		APIKeys: make(map[string]string),
 	}
 
-	// Load generic API key (provider-agnostic)
-	if key := os.Getenv("API_KEY"); key != "" {
-		secrets.APIKeys["generic"] = key
+	// Load API keys; provider-specific keys override the generic API_KEY
+	// A comma-separated <VAR>S list (e.g. OPENAI_API_KEYS) is rotated per request
+	for _, v := range apiKeyEnvVars {
+		keys := loadProviderKeys(os.Getenv, v.envVar)
+		if len(keys) == 0 {
+			continue
+		}
+		secrets.APIKeys[v.provider] = keys[0]
+		if len(keys) > 1 {
+			if secrets.keyPools == nil {
+				secrets.keyPools = make(map[string]*KeyPool)
+			}
+			secrets.keyPools[v.provider] = NewKeyPool(keys)
+		}
 	}
 
-	// Load provider-specific API keys (optional, override generic)
-	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
-		secrets.APIKeys["openai"] = key
-	}
-	if key := os.Getenv("NVIDIA_API_KEY"); key != "" {
-		secrets.APIKeys["nvidia"] = key
-	}
-	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
-		secrets.APIKeys["anthropic"] = key
-	}
-	if key := os.Getenv("TOGETHER_API_KEY"); key != "" {
-		secrets.APIKeys["together"] = key
-	}
-	if key := os.Getenv("CHUTES_API_KEY"); key != "" {
-		secrets.APIKeys["chutes"] = key
-	}
-	if key := os.Getenv("NAHCROF_API_KEY"); key != "" {
-		secrets.APIKeys["nahcrof"] = key
+	// Load Hugging Face token (first non-empty variable wins)
+	for _, name := range HuggingFaceTokenEnvVars {
+		if token := os.Getenv(name); token != "" {
+			secrets.HuggingFaceToken = token
+			break
+		}
 	}
 
-	// Load Hugging Face token
-	secrets.HuggingFaceToken = os.Getenv("HUGGING_FACE_TOKEN")
-
 	return secrets, nil
 }
 
@@ -342,38 +1448,38 @@ func LoadSecrets() (*Secrets, error) {
 func (s *Secrets) GetAPIKey(baseURL string) string {
 	// Try to match common provider domains (provider-specific keys)
 	if contains(baseURL, "openai.com") {
-		if key := s.APIKeys["openai"]; key != "" {
+		if key := s.providerKey("openai"); key != "" {
 			return key
 		}
 	}
 	if contains(baseURL, "nvidia.com") {
-		if key := s.APIKeys["nvidia"]; key != "" {
+		if key := s.providerKey("nvidia"); key != "" {
 			return key
 		}
 	}
 	if contains(baseURL, "anthropic.com") {
-		if key := s.APIKeys["anthropic"]; key != "" {
+		if key := s.providerKey("anthropic"); key != "" {
 			return key
 		}
 	}
 	if contains(baseURL, "together.xyz") || contains(baseURL, "together.ai") {
-		if key := s.APIKeys["together"]; key != "" {
+		if key := s.providerKey("together"); key != "" {
 			return key
 		}
 	}
 	if contains(baseURL, "llm.chutes.ai") {
-		if key := s.APIKeys["chutes"]; key != "" {
+		if key := s.providerKey("chutes"); key != "" {
 			return key
 		}
 	}
 	if contains(baseURL, "ai.nahcrof.com") {
-		if key := s.APIKeys["nahcrof"]; key != "" {
+		if key := s.providerKey("nahcrof"); key != "" {
 			return key
 		}
 	}
 
 	// Fall back to generic API_KEY for any OpenAI-compatible provider
-	if key := s.APIKeys["generic"]; key != "" {
+	if key := s.providerKey("generic"); key != "" {
 		return key
 	}
 
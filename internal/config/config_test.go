@@ -1,8 +1,14 @@
 package config
 
 import (
+	"log/slog"
+	"math"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
 
 	"github.com/lamim/vellumforge2/pkg/models"
 )
@@ -87,6 +93,896 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestMaxRuntimeDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"2h", 2 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"not-a-duration", 0},
+	}
+
+	for _, tt := range tests {
+		g := GenerationConfig{MaxRuntime: tt.value}
+		if got := g.MaxRuntimeDuration(); got != tt.want {
+			t.Errorf("MaxRuntimeDuration(%q): expected %v, got %v", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestRunSeedDefaults(t *testing.T) {
+	tests := []struct {
+		name       string
+		g          GenerationConfig
+		wantSwap   int64
+		wantJitter int64
+	}{
+		{"unseeded", GenerationConfig{}, 0, 0},
+		{"run seed", GenerationConfig{Seed: 42}, 42, 42},
+		{"explicit seeds win", GenerationConfig{Seed: 42, SwapSeed: 1, TemperatureJitterSeed: 2}, 1, 2},
+	}
+
+	for _, tt := range tests {
+		if got := tt.g.SwapDecisionSeed(); got != tt.wantSwap {
+			t.Errorf("%s: expected swap seed %d, got %d", tt.name, tt.wantSwap, got)
+		}
+		if got := tt.g.JitterSeed(); got != tt.wantJitter {
+			t.Errorf("%s: expected jitter seed %d, got %d", tt.name, tt.wantJitter, got)
+		}
+	}
+}
+
+func TestValidateLengthRatio(t *testing.T) {
+	tests := []struct {
+		name       string
+		g          GenerationConfig
+		wantErr    bool
+		wantAction string
+	}{
+		{"unset", GenerationConfig{}, false, LengthRatioDrop},
+		{"range", GenerationConfig{LengthRatioMin: 0.5, LengthRatioMax: 2}, false, LengthRatioDrop},
+		{"flag", GenerationConfig{LengthRatioMax: 2, LengthRatioAction: LengthRatioFlag}, false, LengthRatioFlag},
+		{"negative", GenerationConfig{LengthRatioMin: -1}, true, ""},
+		{"min above max", GenerationConfig{LengthRatioMin: 3, LengthRatioMax: 2}, true, ""},
+		{"unknown action", GenerationConfig{LengthRatioMin: 0.5, LengthRatioAction: "truncate"}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.g
+			err := g.validateLengthRatio()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && g.LengthRatioAction != tt.wantAction {
+				t.Errorf("Expected action %q, got %q", tt.wantAction, g.LengthRatioAction)
+			}
+		})
+	}
+}
+
+func TestValidateSubtopicWeights(t *testing.T) {
+	tests := []struct {
+		name   string
+		gen    GenerationConfig
+		errMsg string
+	}{
+		{"weight cap too high", GenerationConfig{MaxSubtopicWeight: 101, WeightedSubtopics: true}, "max_subtopic_weight must be between"},
+		{"negative weight cap", GenerationConfig{MaxSubtopicWeight: -1}, "max_subtopic_weight must be between"},
+		{"heaviest subtopic over prompt limit", GenerationConfig{NumPromptsPerSubtopic: 2001, MaxSubtopicWeight: 5, WeightedSubtopics: true}, "num_prompts_per_subtopic x max_subtopic_weight"},
+		{"limit ignored when unweighted", GenerationConfig{NumPromptsPerSubtopic: 2001}, ""},
+		{"limit ignored with validation disabled", GenerationConfig{NumPromptsPerSubtopic: 2001, MaxSubtopicWeight: 5, WeightedSubtopics: true, DisableValidationLimits: true}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.gen.MainTopic = "Test"
+			tt.gen.NumSubtopics = 2
+			tt.gen.NumPromptsPerSubtopic = max(2, tt.gen.NumPromptsPerSubtopic)
+			// Concurrency 0 makes Validate fail right after the weight checks
+			err := (&Config{Generation: tt.gen}).Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if tt.errMsg == "" && strings.Contains(err.Error(), "subtopic_weight") {
+				t.Errorf("Expected no subtopic weight error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateMainTopics(t *testing.T) {
+	tests := []struct {
+		name   string
+		gen    GenerationConfig
+		errMsg string
+	}{
+		{"neither set", GenerationConfig{}, "main_topic (or main_topics) is required"},
+		{"both set", GenerationConfig{MainTopic: "Fantasy", MainTopics: []string{"Sci-fi"}}, "mutually exclusive"},
+		{"empty entry", GenerationConfig{MainTopics: []string{"Fantasy", " "}}, "main_topics[1] is empty"},
+		{"duplicate entry", GenerationConfig{MainTopics: []string{"Fantasy", "fantasy "}}, "more than once"},
+		{"list of topics", GenerationConfig{MainTopics: []string{"Fantasy", "Sci-fi"}}, ""},
+		{"single topic", GenerationConfig{MainTopic: "Fantasy"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.gen.NumSubtopics = 2
+			tt.gen.NumPromptsPerSubtopic = 2
+			// Concurrency 0 makes Validate fail after the topic checks
+			err := (&Config{Generation: tt.gen}).Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if tt.errMsg == "" && strings.Contains(err.Error(), "main_topic") {
+				t.Errorf("Expected no main topic error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerationConfigTopics(t *testing.T) {
+	single := GenerationConfig{MainTopic: "Fantasy"}
+	if got := single.Topics(); len(got) != 1 || got[0] != "Fantasy" || single.MultiTopic() {
+		t.Errorf("Expected [Fantasy] without multi-topic, got %v", got)
+	}
+	multi := GenerationConfig{MainTopics: []string{"Fantasy", "Sci-fi"}}
+	if got := multi.Topics(); len(got) != 2 || got[1] != "Sci-fi" || !multi.MultiTopic() {
+		t.Errorf("Expected [Fantasy Sci-fi] with multi-topic, got %v", got)
+	}
+}
+
+func TestValidatePromptSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples int
+		temp    float64
+		errMsg  string
+	}{
+		{"default", 0, 0, ""},
+		{"several samples at higher temperature", 4, 1.2, ""},
+		{"too many samples", 21, 0, "prompt_samples must be between"},
+		{"negative samples", -1, 0, "prompt_samples must be between"},
+		{"temperature too high", 2, 2.5, "prompt_sample_temperature must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:               "Test",
+				NumSubtopics:            2,
+				NumPromptsPerSubtopic:   8,
+				Concurrency:             4,
+				PromptSamples:           tt.samples,
+				PromptSampleTemperature: tt.temp,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if tt.errMsg == "" {
+				if strings.Contains(err.Error(), "prompt_sample") {
+					t.Errorf("Expected no prompt sample error, got %v", err)
+				}
+				if cfg.Generation.PromptSamples < 1 {
+					t.Errorf("Expected prompt_samples to default to at least 1, got %d", cfg.Generation.PromptSamples)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateListLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		minItems int
+		maxRatio float64
+		errMsg   string
+	}{
+		{"defaults", 0, 0, ""},
+		{"min items and ratio", 3, 2.5, ""},
+		{"ratio of one", 1, 1.0, ""},
+		{"negative min items", -1, 0, "list_min_items must be non-negative"},
+		{"ratio below one", 1, 0.5, "list_max_ratio must be 0"},
+		{"negative ratio", 1, -2, "list_max_ratio must be 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 8,
+				Concurrency:           4,
+				ListMinItems:          tt.minItems,
+				ListMaxRatio:          tt.maxRatio,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" && !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if tt.errMsg == "" {
+				if strings.Contains(err.Error(), "list_m") {
+					t.Errorf("Expected no list limit error, got %v", err)
+				}
+				if cfg.Generation.ListMinItems < 1 {
+					t.Errorf("Expected list_min_items to default to at least 1, got %d", cfg.Generation.ListMinItems)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePromptConcurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		want    int
+		errMsg  string
+	}{
+		{"default uses concurrency", 0, 4, ""},
+		{"custom", 16, 16, ""},
+		{"negative", -1, 0, "prompt_concurrency must be"},
+		{"above limit", MaxConcurrency + 1, 0, "prompt_concurrency must not exceed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           4,
+				PromptConcurrency:     tt.workers,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" {
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if strings.Contains(err.Error(), "prompt_concurrency") {
+				t.Fatalf("Expected prompt_concurrency to be valid, got %v", err)
+			}
+			if got := cfg.Generation.PromptWorkers(); got != tt.want {
+				t.Errorf("Expected %d prompt workers, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateShutdownGrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds int
+		want    time.Duration
+		errMsg  string
+	}{
+		{"default", 0, DefaultShutdownGraceSeconds * time.Second, ""},
+		{"custom", 30, 30 * time.Second, ""},
+		{"disabled", -1, 0, ""},
+		{"invalid", -5, 0, "shutdown_grace_seconds must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           4,
+				ShutdownGraceSeconds:  tt.seconds,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" {
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if got := cfg.Generation.ShutdownGrace(); got != tt.want {
+				t.Errorf("Expected grace %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateWarmup(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds int
+		want    time.Duration
+		errMsg  string
+	}{
+		{"unset starts workers at once", 0, 0, ""},
+		{"warmup window", 30, 30 * time.Second, ""},
+		{"negative", -1, 0, "warmup_seconds must be between"},
+		{"too long", MaxWarmupSeconds + 1, 0, "warmup_seconds must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           4,
+				WarmupSeconds:         tt.seconds,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" {
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if strings.Contains(err.Error(), "warmup_seconds") {
+				t.Errorf("Expected no warmup error, got %v", err)
+			}
+			if got := cfg.Generation.Warmup(); got != tt.want {
+				t.Errorf("Expected warmup %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateJSONIndent(t *testing.T) {
+	tests := []struct {
+		name   string
+		indent string
+		errMsg string
+	}{
+		{"unset defaults to compact", "", ""},
+		{"compact", JSONIndentCompact, ""},
+		{"pretty breaks JSONL", JSONIndentPretty, "one record per line"},
+		{"unknown", "tabs", "json_indent must be 'compact'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           4,
+				JSONIndent:            tt.indent,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" {
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if strings.Contains(err.Error(), "json_indent") {
+				t.Errorf("Expected no json_indent error, got %v", err)
+			}
+			if cfg.Generation.JSONIndent != JSONIndentCompact {
+				t.Errorf("Expected json_indent %q, got %q", JSONIndentCompact, cfg.Generation.JSONIndent)
+			}
+		})
+	}
+}
+
+func TestValidateNormalizeUnicode(t *testing.T) {
+	for _, form := range []string{"", UnicodeNFC, UnicodeNFKC, "NFD"} {
+		t.Run(form, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           4,
+				NormalizeUnicode:      form,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			wantErr := form == "NFD"
+			if gotErr := strings.Contains(err.Error(), "normalize_unicode"); gotErr != wantErr {
+				t.Errorf("Expected normalize_unicode error %v for %q, got %v", wantErr, form, err)
+			}
+		})
+	}
+}
+
+func TestValidateOnFingerprintChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		want   string
+		errMsg string
+	}{
+		{"default", "", FingerprintChangeWarn, ""},
+		{"warn", "warn", FingerprintChangeWarn, ""},
+		{"abort", "abort", FingerprintChangeAbort, ""},
+		{"invalid", "ignore", "", "on_fingerprint_change must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           4,
+				OnFingerprintChange:   tt.action,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" {
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if strings.Contains(err.Error(), "on_fingerprint_change") {
+				t.Errorf("Expected no on_fingerprint_change error, got %v", err)
+			}
+			if cfg.Generation.OnFingerprintChange != tt.want {
+				t.Errorf("Expected on_fingerprint_change %q, got %q", tt.want, cfg.Generation.OnFingerprintChange)
+			}
+		})
+	}
+}
+
+func TestValidateOnIdenticalPair(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		want   string
+		errMsg string
+	}{
+		{"default", "", IdenticalPairKeep, ""},
+		{"drop", "drop", IdenticalPairDrop, ""},
+		{"regen", "regen", IdenticalPairRegen, ""},
+		{"invalid", "warn", "", "on_identical_pair must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Generation: GenerationConfig{
+				MainTopic:             "Test",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           4,
+				OnIdenticalPair:       tt.action,
+			}}
+			// No models are configured, so Validate fails after the generation checks
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if tt.errMsg != "" {
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if strings.Contains(err.Error(), "on_identical_pair") {
+				t.Errorf("Expected no on_identical_pair error, got %v", err)
+			}
+			if cfg.Generation.OnIdenticalPair != tt.want {
+				t.Errorf("Expected on_identical_pair %q, got %q", tt.want, cfg.Generation.OnIdenticalPair)
+			}
+		})
+	}
+}
+
+func TestJudgeFilteringScoreScale(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		wantLo int
+		wantHi int
+	}{
+		{"unset", "", 1, 5},
+		{"only score_max", "score_max = 10", 1, 10},
+		{"only score_min", "score_min = 0", 0, 5},
+		{"both", "score_min = 0\nscore_max = 10", 0, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			if err := toml.Unmarshal([]byte("[judge_filtering]\n"+tt.data), &cfg); err != nil {
+				t.Fatalf("Failed to parse config: %v", err)
+			}
+			lo, hi := cfg.JudgeFiltering.ScoreScale()
+			if lo != tt.wantLo || hi != tt.wantHi {
+				t.Errorf("Expected scale %d-%d, got %d-%d", tt.wantLo, tt.wantHi, lo, hi)
+			}
+		})
+	}
+}
+
+func TestValidateModelConfigAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		scheme  string
+		wantErr bool
+	}{
+		{"defaults", "", "", false},
+		{"api key header", "Api-Key", "", false},
+		{"token scheme", "", "Token", false},
+		{"header with space", "Api Key", "", true},
+		{"header with colon", "Api-Key:", "", true},
+		{"scheme with space", "", "Bearer token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := ModelConfig{
+				BaseURL:            "https://api.example.com/v1",
+				ModelName:          "test-model",
+				MaxOutputTokens:    1024,
+				ContextSize:        2048,
+				RateLimitPerMinute: 60,
+				AuthHeader:         tt.header,
+				AuthScheme:         tt.scheme,
+			}
+			err := validateModelConfig("main", mc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateFieldMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldMap  map[string]string
+		fieldOmit []string
+		extraBody map[string]interface{}
+		wantErr   bool
+	}{
+		{"none", nil, nil, nil, false},
+		{"rename and omit", map[string]string{"model": "model_id", "max_tokens": "max_completion_tokens"}, []string{"top_p"}, nil, false},
+		{"swap", map[string]string{"temperature": "top_p", "top_p": "temperature"}, nil, nil, false},
+		{"target freed by omit", map[string]string{"temperature": "top_p"}, []string{"top_p"}, nil, false},
+		{"empty source", map[string]string{"": "model_id"}, nil, nil, true},
+		{"empty target", map[string]string{"model": ""}, nil, nil, true},
+		{"rename to itself", map[string]string{"model": "model"}, nil, nil, true},
+		{"renamed and omitted", map[string]string{"top_p": "nucleus"}, []string{"top_p"}, nil, true},
+		{"duplicate target", map[string]string{"temperature": "temp", "top_p": "temp"}, nil, nil, true},
+		{"target collides with core field", map[string]string{"temperature": "top_p"}, nil, nil, true},
+		{"target collides with extra_body", map[string]string{"temperature": "temp"}, nil, map[string]interface{}{"temp": 1}, true},
+		{"rename stream", map[string]string{"stream": "streaming"}, nil, nil, true},
+		{"omit messages", nil, []string{"messages"}, nil, true},
+		{"empty omit", nil, []string{""}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc := ModelConfig{
+				BaseURL:            "https://api.example.com/v1",
+				ModelName:          "test-model",
+				MaxOutputTokens:    1024,
+				ContextSize:        2048,
+				RateLimitPerMinute: 60,
+				FieldMap:           tt.fieldMap,
+				FieldOmit:          tt.fieldOmit,
+				ExtraBody:          tt.extraBody,
+			}
+			err := validateModelConfig("main", mc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRetryConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RetryConfig
+		wantErr bool
+	}{
+		{"defaults", RetryConfig{}, false},
+		{"all set", RetryConfig{BaseDelay: "500ms", MaxAttempts: 5, JitterFraction: 0.2, RateLimitMultiplier: 2}, false},
+		{"unlimited attempts and no jitter", RetryConfig{MaxAttempts: -1, JitterFraction: -1}, false},
+		{"unparseable base delay", RetryConfig{BaseDelay: "soon"}, true},
+		{"negative base delay", RetryConfig{BaseDelay: "-1s"}, true},
+		{"base delay too long", RetryConfig{BaseDelay: "10m"}, true},
+		{"negative attempts", RetryConfig{MaxAttempts: -2}, true},
+		{"jitter too high", RetryConfig{JitterFraction: 1.5}, true},
+		{"negative jitter", RetryConfig{JitterFraction: -0.5}, true},
+		{"multiplier below one", RetryConfig{RateLimitMultiplier: 0.5}, true},
+		{"multiplier too high", RetryConfig{RateLimitMultiplier: 20}, true},
+		{"linear strategy", RetryConfig{Strategy: RetryStrategyLinear}, false},
+		{"constant strategy", RetryConfig{Strategy: RetryStrategyConstant}, false},
+		{"unknown strategy", RetryConfig{Strategy: "random"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoggingConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LoggingConfig
+		wantErr bool
+	}{
+		{"defaults", LoggingConfig{}, false},
+		{"all set", LoggingConfig{Format: "json", Level: "warn", FileFormat: "text", FileLevel: "debug"}, false},
+		{"level is case insensitive", LoggingConfig{Level: "DEBUG"}, false},
+		{"unknown format", LoggingConfig{Format: "yaml"}, true},
+		{"unknown file format", LoggingConfig{FileFormat: "logfmt"}, true},
+		{"unknown level", LoggingConfig{Level: "verbose"}, true},
+		{"unknown file level", LoggingConfig{FileLevel: "trace"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHuggingFaceConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     HuggingFaceConfig
+		wantErr bool
+	}{
+		{"defaults", HuggingFaceConfig{}, false},
+		{"all set", HuggingFaceConfig{ReadyTimeout: "2m", ReadyPollInterval: "500ms"}, false},
+		{"unparseable timeout", HuggingFaceConfig{ReadyTimeout: "soon"}, true},
+		{"zero interval", HuggingFaceConfig{ReadyPollInterval: "0s"}, true},
+		{"negative timeout", HuggingFaceConfig{ReadyTimeout: "-5s"}, true},
+		{"interval above timeout", HuggingFaceConfig{ReadyTimeout: "5s", ReadyPollInterval: "10s"}, true},
+		{"upload settings", HuggingFaceConfig{UploadTimeoutSeconds: 3600, CommitTimeoutSeconds: 900, MaxRetries: 10}, false},
+		{"no retries", HuggingFaceConfig{MaxRetries: -1}, false},
+		{"negative upload timeout", HuggingFaceConfig{UploadTimeoutSeconds: -1}, true},
+		{"negative commit timeout", HuggingFaceConfig{CommitTimeoutSeconds: -1}, true},
+		{"invalid max retries", HuggingFaceConfig{MaxRetries: -2}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg := HuggingFaceConfig{ReadyTimeout: "2m"}
+	if got := cfg.ReadyTimeoutDuration(); got != 2*time.Minute {
+		t.Errorf("Expected ready timeout 2m, got %v", got)
+	}
+	if got := cfg.ReadyPollIntervalDuration(); got != 0 {
+		t.Errorf("Expected unset poll interval to be 0, got %v", got)
+	}
+}
+
+func TestDatasetConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     DatasetConfig
+		license string
+		errMsg  string
+	}{
+		{"unset", DatasetConfig{}, "", ""},
+		{"spdx identifier", DatasetConfig{License: "cc-by-4.0"}, "cc-by-4.0", ""},
+		{"spdx casing is normalized", DatasetConfig{License: " Apache-2.0 "}, "apache-2.0", ""},
+		{"unknown license", DatasetConfig{License: "cc-by-5.0"}, "cc-by-5.0", "is not an SPDX identifier"},
+		{"other with attribution", DatasetConfig{License: "other", Attribution: "Custom terms: ..."}, "other", ""},
+		{"other without attribution", DatasetConfig{License: "other"}, "other", "dataset.attribution must describe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+			if got := tt.cfg.LicenseID(); got != tt.license {
+				t.Errorf("Expected license ID %q, got %q", tt.license, got)
+			}
+		})
+	}
+}
+
+func TestEmbeddingsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    EmbeddingsConfig
+		errMsg string
+	}{
+		{"disabled ignores settings", EmbeddingsConfig{SimilarityThreshold: 5}, ""},
+		{"enabled", EmbeddingsConfig{Enabled: true, BaseURL: "https://api.openai.com/v1", ModelName: "text-embedding-3-small"}, ""},
+		{"missing base_url", EmbeddingsConfig{Enabled: true, ModelName: "m"}, "embeddings.base_url is required"},
+		{"missing model", EmbeddingsConfig{Enabled: true, BaseURL: "http://localhost:8080"}, "embeddings.model_name is required"},
+		{"threshold too high", EmbeddingsConfig{Enabled: true, BaseURL: "http://localhost:8080", ModelName: "m", SimilarityThreshold: 1.5}, "similarity_threshold must be between"},
+		{"batch too large", EmbeddingsConfig{Enabled: true, BaseURL: "http://localhost:8080", ModelName: "m", BatchSize: 5000}, "batch_size must be between"},
+		{"bad timeout", EmbeddingsConfig{Enabled: true, BaseURL: "http://localhost:8080", ModelName: "m", TimeoutSeconds: -2}, "timeout_seconds must be"},
+		{"bad max retries", EmbeddingsConfig{Enabled: true, BaseURL: "http://localhost:8080", ModelName: "m", MaxRetries: -2}, "max_retries must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestRewardModelConfigValidate(t *testing.T) {
+	score := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		cfg     RewardModelConfig
+		wantErr bool
+	}{
+		{"disabled", RewardModelConfig{}, false},
+		{"min chosen", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinChosenScore: score(-1)}, false},
+		{"zero margin", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinMargin: score(0)}, false},
+		{"missing base url", RewardModelConfig{Enabled: true, MinChosenScore: score(0)}, true},
+		{"no thresholds", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score"}, true},
+		{"negative margin", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinMargin: score(-0.5)}, true},
+		{"bad timeout", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinMargin: score(1), TimeoutSeconds: -2}, true},
+		{"bad max retries", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinMargin: score(1), MaxRetries: -2}, true},
+		{"keep on error", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinMargin: score(1), OnError: RewardErrorKeep}, false},
+		{"bad on error", RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinMargin: score(1), OnError: "ignore"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	cfg := RewardModelConfig{Enabled: true, BaseURL: "http://localhost:8000/score", MinMargin: score(1)}
+	if err := cfg.validate(); err != nil || cfg.OnError != RewardErrorDrop {
+		t.Errorf("Expected on_error to default to %q, got %q (err %v)", RewardErrorDrop, cfg.OnError, err)
+	}
+}
+
+func TestEmbeddingsConfigDefaults(t *testing.T) {
+	var cfg EmbeddingsConfig
+	if cfg.Threshold() != DefaultEmbeddingSimilarity || cfg.Batch() != DefaultEmbeddingBatchSize ||
+		cfg.Timeout() != DefaultEmbeddingTimeoutSeconds*time.Second {
+		t.Errorf("Expected defaults, got threshold=%v batch=%d timeout=%v", cfg.Threshold(), cfg.Batch(), cfg.Timeout())
+	}
+	if got := (EmbeddingsConfig{TimeoutSeconds: -1}).Timeout(); got != 0 {
+		t.Errorf("Expected no timeout for -1, got %v", got)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		want slog.Level
+	}{
+		{"", slog.LevelWarn},
+		{"debug", slog.LevelDebug},
+		{"error", slog.LevelError},
+		{"bogus", slog.LevelWarn},
+	}
+
+	for _, tt := range tests {
+		if got := ParseLogLevel(tt.name, slog.LevelWarn); got != tt.want {
+			t.Errorf("ParseLogLevel(%q): Expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRetryBaseDelayDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"2s", 2 * time.Second},
+		{"500ms", 500 * time.Millisecond},
+		{"not-a-duration", 0},
+	}
+
+	for _, tt := range tests {
+		r := RetryConfig{BaseDelay: tt.value}
+		if got := r.BaseDelayDuration(); got != tt.want {
+			t.Errorf("BaseDelayDuration(%q): expected %v, got %v", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestModelConfigExtraBody(t *testing.T) {
+	data := `
+[models.main]
+model_name = "test-model"
+extra_body_override = true
+
+[models.main.extra_body]
+top_k = 40
+repetition_penalty = 1.05
+
+[models.main.extra_body.nvext]
+guided_json = "{}"
+`
+	var cfg Config
+	if err := toml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	mainModel := cfg.Models["main"]
+	if !mainModel.ExtraBodyOverride {
+		t.Error("Expected extra_body_override to be true")
+	}
+	if mainModel.ExtraBody["top_k"] != int64(40) {
+		t.Errorf("Expected top_k 40, got %v", mainModel.ExtraBody["top_k"])
+	}
+	if mainModel.ExtraBody["repetition_penalty"] != 1.05 {
+		t.Errorf("Expected repetition_penalty 1.05, got %v", mainModel.ExtraBody["repetition_penalty"])
+	}
+	if nvext, ok := mainModel.ExtraBody["nvext"].(map[string]interface{}); !ok || nvext["guided_json"] != "{}" {
+		t.Errorf("Expected nested nvext table, got %v", mainModel.ExtraBody["nvext"])
+	}
+}
+
 func TestLoadSecrets(t *testing.T) {
 	// Set test environment variables
 	if err := os.Setenv("OPENAI_API_KEY", "test-key-123"); err != nil {
@@ -114,6 +1010,55 @@ func TestLoadSecrets(t *testing.T) {
 	}
 }
 
+func TestLoadSecretsHuggingFaceTokenPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "none set",
+			env:  map[string]string{},
+			want: "",
+		},
+		{
+			name: "HF_TOKEN only",
+			env:  map[string]string{"HF_TOKEN": "hf-short"},
+			want: "hf-short",
+		},
+		{
+			name: "HUGGINGFACE_TOKEN over HF_TOKEN",
+			env:  map[string]string{"HUGGINGFACE_TOKEN": "hf-joined", "HF_TOKEN": "hf-short"},
+			want: "hf-joined",
+		},
+		{
+			name: "HUGGING_FACE_TOKEN over all",
+			env: map[string]string{
+				"HUGGING_FACE_TOKEN": "hf-underscored",
+				"HUGGINGFACE_TOKEN":  "hf-joined",
+				"HF_TOKEN":           "hf-short",
+			},
+			want: "hf-underscored",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range HuggingFaceTokenEnvVars {
+				t.Setenv(name, tt.env[name])
+			}
+
+			secrets, err := LoadSecrets()
+			if err != nil {
+				t.Fatalf("LoadSecrets() error = %v", err)
+			}
+			if secrets.HuggingFaceToken != tt.want {
+				t.Errorf("Expected token %q, got %q", tt.want, secrets.HuggingFaceToken)
+			}
+		})
+	}
+}
+
 func TestGetAPIKey(t *testing.T) {
 	t.Run("provider_specific_keys", func(t *testing.T) {
 		secrets := &Secrets{
@@ -204,3 +1149,246 @@ func TestGetAPIKey(t *testing.T) {
 		}
 	})
 }
+
+func TestApplyDefaultsContextSizeUnset(t *testing.T) {
+	cfg := &Config{Models: map[string]ModelConfig{
+		"main":     {ModelName: "m"},
+		"rejected": {ModelName: "r", ContextSize: 8192},
+	}}
+	applyDefaults(cfg)
+
+	if mc := cfg.Models["main"]; mc.ContextSize != 16384 || !mc.ContextSizeUnset {
+		t.Errorf("Expected an unset context_size to default to 16384 and be marked unset, got %d (unset %v)", mc.ContextSize, mc.ContextSizeUnset)
+	}
+	if mc := cfg.Models["rejected"]; mc.ContextSize != 8192 || mc.ContextSizeUnset {
+		t.Errorf("Expected an explicit context_size to be kept, got %d (unset %v)", mc.ContextSize, mc.ContextSizeUnset)
+	}
+}
+
+func TestRejectedFromMain(t *testing.T) {
+	mainModel := ModelConfig{
+		BaseURL:            "https://api.example.com/v1",
+		ModelName:          "test-model",
+		Temperature:        0.7,
+		TopP:               1.0,
+		MaxOutputTokens:    1024,
+		ContextSize:        2048,
+		RateLimitPerMinute: 60,
+	}
+	newConfig := func(fromMain bool, offset float64) *Config {
+		return &Config{
+			Generation: GenerationConfig{
+				MainTopic:                 "Test",
+				NumSubtopics:              2,
+				NumPromptsPerSubtopic:     2,
+				Concurrency:               4,
+				DatasetMode:               models.DatasetModeDPO,
+				RejectedFromMain:          fromMain,
+				RejectedTemperatureOffset: offset,
+			},
+			Models: map[string]ModelConfig{"main": mainModel},
+			PromptTemplates: PromptTemplates{
+				SubtopicGeneration: "template1",
+				PromptGeneration:   "template2",
+				ChosenGeneration:   "template3",
+				RejectedGeneration: "template4",
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		fromMain bool
+		offset   float64
+		wantTemp float64
+		errMsg   string
+	}{
+		{"missing rejected model", false, 0, 0, "models.rejected is required"},
+		{"default offset", true, 0, 1.2, ""},
+		{"custom offset", true, 0.3, 1.0, ""},
+		{"clamped to 2", true, 1.8, 2.0, ""},
+		{"offset out of range", true, 2.5, 0, "rejected_temperature_offset must be between"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newConfig(tt.fromMain, tt.offset)
+			err := cfg.Validate()
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate returned unexpected error: %v", err)
+			}
+
+			rejected, ok := cfg.RejectedModel()
+			if !ok {
+				t.Fatal("Expected a rejected model")
+			}
+			if rejected.ModelName != mainModel.ModelName || math.Abs(rejected.Temperature-tt.wantTemp) > 1e-9 {
+				t.Errorf("Expected %s at temperature %.2f, got %s at %.2f",
+					mainModel.ModelName, tt.wantTemp, rejected.ModelName, rejected.Temperature)
+			}
+			if cfg.Models["main"].Temperature != mainModel.Temperature {
+				t.Error("Expected the main model's temperature to be left untouched")
+			}
+		})
+	}
+
+	// A configured rejected model wins over rejected_from_main
+	cfg := newConfig(true, 0)
+	cfg.Models["rejected"] = ModelConfig{ModelName: "weak-model", Temperature: 0.9}
+	if rejected, _ := cfg.RejectedModel(); rejected.ModelName != "weak-model" || rejected.Temperature != 0.9 {
+		t.Errorf("Expected models.rejected to take precedence, got %+v", rejected)
+	}
+}
+
+func TestRejectedSeesChosen(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		enabled  bool
+		errMsg   string
+	}{
+		{"placeholder with flag", "Make this worse: {{.Chosen}}", true, ""},
+		{"placeholder in a pipeline", "{{printf \"%.200s\" .Chosen}} {{.Prompt}}", true, ""},
+		{"placeholder without flag", "Make this worse: {{.Chosen}}", false, "requires generation.rejected_sees_chosen"},
+		{"similar name without flag", "{{.Prompt}} {{.ChosenStyle}}", false, ""},
+		{"flag without placeholder", "Write badly: {{.Prompt}}", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Generation: GenerationConfig{
+					MainTopic:             "Test",
+					NumSubtopics:          2,
+					NumPromptsPerSubtopic: 2,
+					Concurrency:           4,
+					DatasetMode:           models.DatasetModeDPO,
+					RejectedFromMain:      true,
+					RejectedSeesChosen:    tt.enabled,
+				},
+				Models: map[string]ModelConfig{"main": {
+					BaseURL:            "https://api.example.com/v1",
+					ModelName:          "test-model",
+					Temperature:        0.7,
+					TopP:               1.0,
+					MaxOutputTokens:    1024,
+					ContextSize:        2048,
+					RateLimitPerMinute: 60,
+				}},
+				PromptTemplates: PromptTemplates{
+					SubtopicGeneration: "template1",
+					PromptGeneration:   "template2",
+					ChosenGeneration:   "template3",
+					RejectedGeneration: tt.template,
+				},
+			}
+			err := cfg.Validate()
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Validate returned unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFallbackModel(t *testing.T) {
+	cfg := &Config{
+		Generation: GenerationConfig{RejectedFromMain: true, RejectedTemperatureOffset: 0.5},
+		Models: map[string]ModelConfig{
+			"main":           {ModelName: "main"},
+			"main_fallback":  {ModelName: "main-backup", Temperature: 0.7},
+			"judge_fallback": {ModelName: "judge-backup"},
+		},
+	}
+
+	if fallback, ok := cfg.FallbackModel("main"); !ok || fallback.ModelName != "main-backup" {
+		t.Errorf("Expected main fallback main-backup, got %s (ok=%v)", fallback.ModelName, ok)
+	}
+	if fallback, ok := cfg.FallbackModel("judge"); !ok || fallback.ModelName != "judge-backup" {
+		t.Errorf("Expected judge fallback judge-backup, got %s (ok=%v)", fallback.ModelName, ok)
+	}
+
+	// rejected_from_main falls back to the main fallback at the rejected temperature
+	fallback, ok := cfg.FallbackModel("rejected")
+	if !ok || fallback.ModelName != "main-backup" || fallback.Temperature != 1.2 {
+		t.Errorf("Expected rejected fallback main-backup at 1.2, got %s at %.2f (ok=%v)", fallback.ModelName, fallback.Temperature, ok)
+	}
+
+	// A dedicated rejected model only falls back to rejected_fallback
+	cfg.Models["rejected"] = ModelConfig{ModelName: "rejected"}
+	if _, ok := cfg.FallbackModel("rejected"); ok {
+		t.Error("Expected no rejected fallback without models.rejected_fallback")
+	}
+}
+
+func TestValidateFallbackAfterFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    int
+		fallback *ModelConfig
+		want     int
+		errMsg   string
+	}{
+		{"default", 0, nil, 3, ""},
+		{"custom", 5, nil, 5, ""},
+		{"negative", -1, nil, 0, "fallback_after_failures must be at least 1"},
+		{"invalid fallback model", 0, &ModelConfig{BaseURL: "https://backup.example.com/v1"}, 3, "models.main_fallback.model_name is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Generation: GenerationConfig{
+					MainTopic:             "Test",
+					NumSubtopics:          2,
+					NumPromptsPerSubtopic: 2,
+					Concurrency:           4,
+					DatasetMode:           models.DatasetModeSFT,
+					FallbackAfterFailures: tt.value,
+				},
+				Models: map[string]ModelConfig{"main": {
+					BaseURL:            "https://api.example.com/v1",
+					ModelName:          "test-model",
+					Temperature:        0.7,
+					TopP:               1.0,
+					MaxOutputTokens:    1024,
+					ContextSize:        2048,
+					RateLimitPerMinute: 60,
+				}},
+				PromptTemplates: PromptTemplates{
+					SubtopicGeneration: "template1",
+					PromptGeneration:   "template2",
+					ChosenGeneration:   "template3",
+					RejectedGeneration: "template4",
+				},
+			}
+			if tt.fallback != nil {
+				cfg.Models["main_fallback"] = *tt.fallback
+			}
+			err := cfg.Validate()
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate returned unexpected error: %v", err)
+			}
+			if cfg.Generation.FallbackAfterFailures != tt.want {
+				t.Errorf("Expected fallback_after_failures %d, got %d", tt.want, cfg.Generation.FallbackAfterFailures)
+			}
+		})
+	}
+}
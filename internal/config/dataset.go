@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LicenseOther is the license value for terms without an SPDX identifier; describe them in
+// dataset.attribution
+const LicenseOther = "other"
+
+// spdxLicenses are the SPDX identifiers the Hugging Face Hub recognizes as dataset licenses,
+// lowercased as the Hub writes them
+var spdxLicenses = map[string]bool{
+	"0bsd": true, "afl-3.0": true, "agpl-3.0": true, "apache-2.0": true, "artistic-2.0": true,
+	"bsd-2-clause": true, "bsd-3-clause": true, "bsd-3-clause-clear": true, "bsl-1.0": true,
+	"c-uda": true, "cc0-1.0": true,
+	"cc-by-2.0": true, "cc-by-2.5": true, "cc-by-3.0": true, "cc-by-4.0": true,
+	"cc-by-sa-3.0": true, "cc-by-sa-4.0": true,
+	"cc-by-nc-2.0": true, "cc-by-nc-3.0": true, "cc-by-nc-4.0": true,
+	"cc-by-nd-4.0": true, "cc-by-nc-nd-3.0": true, "cc-by-nc-nd-4.0": true,
+	"cc-by-nc-sa-2.0": true, "cc-by-nc-sa-3.0": true, "cc-by-nc-sa-4.0": true,
+	"cdla-permissive-1.0": true, "cdla-permissive-2.0": true, "cdla-sharing-1.0": true,
+	"ecl-2.0": true, "epl-1.0": true, "epl-2.0": true, "eupl-1.1": true, "eupl-1.2": true,
+	"gfdl": true, "gpl-2.0": true, "gpl-3.0": true, "isc": true,
+	"lgpl-2.1": true, "lgpl-3.0": true, "mit": true, "mpl-2.0": true, "ms-pl": true,
+	"ncsa": true, "odbl": true, "odc-by": true, "osl-3.0": true, "pddl": true,
+	"postgresql": true, "unlicense": true, "wtfpl": true, "zlib": true,
+}
+
+// DatasetConfig holds the licensing and attribution metadata of the published dataset
+// It is written to the dataset card created on Hugging Face upload
+type DatasetConfig struct {
+	License     string `toml:"license"`     // SPDX license identifier, e.g. "cc-by-4.0" or "apache-2.0" ("other" for custom terms)
+	Source      string `toml:"source"`      // Where the data comes from, e.g. the generating models or a URL
+	Attribution string `toml:"attribution"` // Credit or notice to reproduce with the dataset (required with license = "other")
+}
+
+// LicenseID returns the license in the lowercase form the Hub uses ("" if unset)
+func (d DatasetConfig) LicenseID() string {
+	return strings.ToLower(strings.TrimSpace(d.License))
+}
+
+func (d DatasetConfig) validate() error {
+	license := d.LicenseID()
+	switch {
+	case license == "":
+		if d.Attribution != "" || d.Source != "" {
+			fmt.Fprintf(os.Stderr, "WARNING: dataset.source/attribution are set without dataset.license - the dataset card will have no license tag\n")
+		}
+		return nil
+	case license == LicenseOther:
+		if strings.TrimSpace(d.Attribution) == "" {
+			return fmt.Errorf("dataset.attribution must describe the license terms when dataset.license = %q", LicenseOther)
+		}
+		return nil
+	case !spdxLicenses[license]:
+		return fmt.Errorf("dataset.license %q is not an SPDX identifier the Hugging Face Hub recognizes (e.g. cc-by-4.0, apache-2.0, mit; use %q for custom terms)", d.License, LicenseOther)
+	}
+	return nil
+}
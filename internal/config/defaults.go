@@ -9,9 +9,13 @@ Each subtopic should be:
 - Unique from the others in the list
 - Rich with potential for creative exploration
 - Grounded in the fantasy genre
+{{if .Weighted}}
+Give each subtopic a weight from 1 to {{.MaxWeight}}: how many times more story prompts it deserves than a typical one. Use 1 for most subtopics and higher weights only for unusually rich ones.
 
+Return ONLY a valid JSON array of objects (no markdown, no additional text):
+[{"topic": "Subtopic 1", "weight": 1}, {"topic": "Subtopic 2", "weight": 3}, ...]{{else}}
 Return ONLY a valid JSON array of strings (no markdown, no additional text):
-["Subtopic 1", "Subtopic 2", ...]`
+["Subtopic 1", "Subtopic 2", ...]{{end}}`
 }
 
 // GetDefaultPromptTemplate returns the default template for prompt generation
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"strings"
 	"unicode"
 )
 
@@ -15,6 +16,9 @@ const (
 
 	// MaxTemplateSize is the maximum allowed size for template content
 	MaxTemplateSize = 50 * 1024 // 50KB
+
+	// MaxChosenExamples is the maximum number of few-shot examples for chosen generation
+	MaxChosenExamples = 20
 )
 
 // ValidateInputs performs additional security validation on user-controllable fields.
@@ -24,6 +28,11 @@ func (c *Config) ValidateInputs() error {
 	if err := validateMainTopic(c.Generation.MainTopic); err != nil {
 		return fmt.Errorf("invalid main_topic: %w", err)
 	}
+	for i, topic := range c.Generation.MainTopics {
+		if err := validateMainTopic(topic); err != nil {
+			return fmt.Errorf("invalid main_topics[%d]: %w", i, err)
+		}
+	}
 
 	// Validate model configurations
 	for name, mc := range c.Models {
@@ -36,11 +45,26 @@ func (c *Config) ValidateInputs() error {
 		}
 	}
 
+	// The embeddings endpoint gets the same checks as a model
+	if c.Embeddings.Enabled {
+		if err := validateModelName(c.Embeddings.ModelName, "embeddings"); err != nil {
+			return err
+		}
+		if err := validateBaseURL(c.Embeddings.BaseURL, "embeddings"); err != nil {
+			return err
+		}
+	}
+
 	// Validate template sizes
 	if err := c.validateTemplateSizes(); err != nil {
 		return err
 	}
 
+	// Validate few-shot examples
+	if err := c.validateChosenExamples(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -125,6 +149,33 @@ func (c *Config) validateTemplateSizes() error {
 	return nil
 }
 
+// validateChosenExamples checks the few-shot example count and that chosen_generation
+// plus all examples stays within the template size limit
+func (c *Config) validateChosenExamples() error {
+	examples := c.PromptTemplates.ChosenExamples
+	if len(examples) == 0 {
+		return nil
+	}
+	if len(examples) > MaxChosenExamples {
+		return fmt.Errorf("prompt_templates.chosen_examples must not exceed %d examples (got %d)",
+			MaxChosenExamples, len(examples))
+	}
+
+	totalSize := len(c.PromptTemplates.ChosenGeneration)
+	for i, example := range examples {
+		if strings.TrimSpace(example.Output) == "" {
+			return fmt.Errorf("prompt_templates.chosen_examples[%d] has an empty output", i)
+		}
+		totalSize += len(example.Input) + len(example.Output)
+	}
+	if totalSize > MaxTemplateSize {
+		return fmt.Errorf("template 'chosen_generation' with chosen_examples exceeds maximum size of %d bytes (got %d)",
+			MaxTemplateSize, totalSize)
+	}
+
+	return nil
+}
+
 // containsControlChars checks if a string contains control characters
 // (excluding newlines, tabs, and carriage returns which are acceptable)
 func containsControlChars(s string) bool {
@@ -283,3 +283,61 @@ func TestValidateInputs_Integration(t *testing.T) {
 		t.Error("ValidateInputs() with invalid URL expected error, got nil")
 	}
 }
+
+func TestValidateChosenExamples(t *testing.T) {
+	tests := []struct {
+		name     string
+		examples []FewShotExample
+		template string
+		errMsg   string
+	}{
+		{
+			name:     "no examples",
+			template: "Write {{.Prompt}}",
+		},
+		{
+			name:     "valid examples",
+			template: "{{range .Examples}}{{.Input}} => {{.Output}}\n{{end}}Write {{.Prompt}}",
+			examples: []FewShotExample{{Input: "a", Output: "b"}, {Input: "c", Output: "d"}},
+		},
+		{
+			name:     "too many examples",
+			template: "Write {{.Prompt}}",
+			examples: make([]FewShotExample, MaxChosenExamples+1),
+			errMsg:   "must not exceed",
+		},
+		{
+			name:     "empty output",
+			template: "Write {{.Prompt}}",
+			examples: []FewShotExample{{Input: "a", Output: " "}},
+			errMsg:   "chosen_examples[0] has an empty output",
+		},
+		{
+			name:     "combined size too large",
+			template: strings.Repeat("x", MaxTemplateSize/2),
+			examples: []FewShotExample{{Input: "a", Output: strings.Repeat("y", MaxTemplateSize/2)}},
+			errMsg:   "exceeds maximum size",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				PromptTemplates: PromptTemplates{
+					ChosenGeneration: tt.template,
+					ChosenExamples:   tt.examples,
+				},
+			}
+			err := cfg.validateChosenExamples()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"math"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// ktoRatioEpsilon absorbs float error so ratios like 0.1 * 10 land on whole numbers
+const ktoRatioEpsilon = 1e-9
+
+// ktoNegatives returns the undesirable completions job jobID gets under generation.kto_ratio
+// A fractional ratio is spread over consecutive job IDs (1.5 -> 1, 2, 1, 2, ...; 0.5 -> 0, 1, ...)
+// so any run of jobs stays within one row of the target and resumed runs make the same choices
+func ktoNegatives(ratio float64, jobID int) int {
+	upTo := func(id int) float64 { return math.Floor(ratio*float64(id) + ktoRatioEpsilon) }
+	return int(upTo(jobID+1) - upTo(jobID))
+}
+
+// RejectedCount returns how many rejected responses are generated for a job
+// KTO with kto_ratio set may return 0 (desirable row only); otherwise num_rejected applies
+func (g GenerationConfig) RejectedCount(jobID int) int {
+	if g.DatasetMode == models.DatasetModeKTO && g.KTORatio > 0 {
+		return ktoNegatives(g.KTORatio, jobID)
+	}
+	return max(g.NumRejected, 1)
+}
+
+// RowsPerJob returns how many dataset rows a completed job writes: one desirable plus one
+// undesirable row per rejected response in KTO, one row per rejected response in DPO with
+// rejected_output_shape = "rows", and a single row otherwise
+func (g GenerationConfig) RowsPerJob(jobID int) int {
+	switch g.DatasetMode {
+	case models.DatasetModeKTO:
+		return 1 + g.RejectedCount(jobID)
+	case models.DatasetModeDPO:
+		if g.RejectedOutputShape != models.RejectedShapeArray {
+			return g.RejectedCount(jobID)
+		}
+	}
+	return 1
+}
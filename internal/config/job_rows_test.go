@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestKTONegatives(t *testing.T) {
+	tests := []struct {
+		ratio float64
+		want  []int // Negatives for job IDs 0..len-1
+	}{
+		{1.0, []int{1, 1, 1, 1}},
+		{2.0, []int{2, 2, 2, 2}},
+		{0.5, []int{0, 1, 0, 1}},
+		{1.5, []int{1, 2, 1, 2}},
+		{0.1, []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		for id, want := range tt.want {
+			if got := ktoNegatives(tt.ratio, id); got != want {
+				t.Errorf("ktoNegatives(%.1f, %d): expected %d, got %d", tt.ratio, id, want, got)
+			}
+		}
+	}
+
+	// Totals track the ratio over any number of jobs
+	total := 0
+	for id := range 1000 {
+		total += ktoNegatives(0.3, id)
+	}
+	if total != 300 {
+		t.Errorf("Expected 300 negatives for 1000 jobs at ratio 0.3, got %d", total)
+	}
+}
+
+func TestRowsPerJob(t *testing.T) {
+	tests := []struct {
+		name string
+		gen  GenerationConfig
+		want []int // Rows for job IDs 0..len-1
+	}{
+		{"sft", GenerationConfig{DatasetMode: models.DatasetModeSFT, NumRejected: 1}, []int{1, 1}},
+		{"dpo", GenerationConfig{DatasetMode: models.DatasetModeDPO, NumRejected: 1}, []int{1, 1}},
+		{"dpo rows", GenerationConfig{DatasetMode: models.DatasetModeDPO, NumRejected: 3, RejectedOutputShape: models.RejectedShapeRows}, []int{3, 3}},
+		{"dpo array", GenerationConfig{DatasetMode: models.DatasetModeDPO, NumRejected: 3, RejectedOutputShape: models.RejectedShapeArray}, []int{1, 1}},
+		{"mo-dpo", GenerationConfig{DatasetMode: models.DatasetModeMODPO, NumRejected: 1}, []int{1, 1}},
+		{"kto", GenerationConfig{DatasetMode: models.DatasetModeKTO, NumRejected: 1}, []int{2, 2}},
+		{"kto num_rejected", GenerationConfig{DatasetMode: models.DatasetModeKTO, NumRejected: 2}, []int{3, 3}},
+		{"kto ratio", GenerationConfig{DatasetMode: models.DatasetModeKTO, KTORatio: 0.5}, []int{1, 2, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for id, want := range tt.want {
+				if got := tt.gen.RowsPerJob(id); got != want {
+					t.Errorf("Job %d: expected %d rows, got %d", id, want, got)
+				}
+			}
+		})
+	}
+}
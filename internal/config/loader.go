@@ -2,29 +2,19 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"path/filepath"
 
 	"github.com/pelletier/go-toml/v2"
 
 	"github.com/lamim/vellumforge2/pkg/models"
 )
 
-// Load reads and parses the configuration file and environment variables
+// Load reads and parses the configuration and environment variables
+// configPath is a file path, "-" to read from stdin, or an http(s) URL (see ReadSource)
 func Load(configPath string) (*Config, *Secrets, error) {
-	// Check file size before reading (prevent OOM attacks)
-	const maxConfigSize = 10 * 1024 * 1024 // 10MB
-	fileInfo, err := os.Stat(configPath)
+	data, err := ReadSource(configPath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to stat config file: %w", err)
-	}
-	if fileInfo.Size() > maxConfigSize {
-		return nil, nil, fmt.Errorf("config file too large: %d bytes (max %d bytes)", fileInfo.Size(), maxConfigSize)
-	}
-
-	// Read config file
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, nil, err
 	}
 
 	// Parse TOML
@@ -76,6 +66,27 @@ func applyDefaults(cfg *Config) {
 	if cfg.Generation.SFTFormat == "" {
 		cfg.Generation.SFTFormat = models.SFTFormatShareGPT
 	}
+	if cfg.Generation.PromptCacheDir == "" {
+		cfg.Generation.PromptCacheDir = filepath.Join("output", "prompt_cache")
+	}
+	if cfg.Generation.JudgeCacheDir == "" {
+		cfg.Generation.JudgeCacheDir = filepath.Join("output", "judge_cache")
+	}
+
+	// Network defaults scale with concurrency so workers (and async judges) reuse
+	// keep-alive connections instead of paying a TLS handshake per request
+	if cfg.Network.MaxIdleConnsPerHost == 0 {
+		cfg.Network.MaxIdleConnsPerHost = max(cfg.Generation.Concurrency*2, 16)
+		if cfg.Network.MaxConnsPerHost > 0 {
+			cfg.Network.MaxIdleConnsPerHost = min(cfg.Network.MaxIdleConnsPerHost, cfg.Network.MaxConnsPerHost)
+		}
+	}
+	if cfg.Network.MaxIdleConns == 0 {
+		cfg.Network.MaxIdleConns = max(cfg.Generation.Concurrency*4, 100)
+	}
+	if cfg.Network.IdleConnTimeoutSeconds == 0 {
+		cfg.Network.IdleConnTimeoutSeconds = 90
+	}
 
 	// Apply defaults for each model
 	for name, model := range cfg.Models {
@@ -91,6 +102,7 @@ func applyDefaults(cfg *Config) {
 		}
 		if model.ContextSize == 0 {
 			model.ContextSize = 16384
+			model.ContextSizeUnset = true
 		}
 		if model.RateLimitPerMinute == 0 {
 			model.RateLimitPerMinute = 60
@@ -112,6 +124,9 @@ func applyDefaults(cfg *Config) {
 		// - Any positive number → use that value
 		if model.MaxRetries == 0 {
 			model.MaxRetries = 3 // Default to 3 retries
+			if cfg.Retry.MaxAttempts != 0 {
+				model.MaxRetries = cfg.Retry.MaxAttempts // [retry] max_attempts overrides the default
+			}
 		}
 		// If structure_temperature not set, it will use regular temperature (0 = unset)
 
@@ -127,6 +142,9 @@ func applyDefaults(cfg *Config) {
 	if cfg.Generation.SubtopicChunkSize == 0 {
 		cfg.Generation.SubtopicChunkSize = 30 // Default chunk size
 	}
+	if cfg.Generation.WeightedSubtopics && cfg.Generation.MaxSubtopicWeight == 0 {
+		cfg.Generation.MaxSubtopicWeight = 5
+	}
 
 	// Apply default templates if not provided
 	if cfg.PromptTemplates.SubtopicGeneration == "" {
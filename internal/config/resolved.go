@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// redactedValue replaces credentials in the resolved config output
+const redactedValue = "REDACTED"
+
+// sensitiveKeyParts mark URL query parameters and extra_body keys that hold credentials
+// Matched against the lowercased name with "_" and "-" removed; bare "token" is not listed so
+// fields like max_tokens stay visible
+var sensitiveKeyParts = []string{"apikey", "accesstoken", "authtoken", "secret", "password", "authorization"}
+
+// sensitiveKeys are exact (normalized) names that hold credentials
+var sensitiveKeys = []string{"key", "token", "auth"}
+
+// ResolvedTOML renders the config as TOML after defaults and validation have been applied,
+// so users can see the values actually in effect (config check)
+// Credentials embedded in any base_url or extra_body are redacted; API keys are never part of Config
+func (c *Config) ResolvedTOML() (string, error) {
+	redacted := *c
+	redacted.Models = make(map[string]ModelConfig, len(c.Models))
+	for name, model := range c.Models {
+		model.BaseURL = redactURL(model.BaseURL)
+		model.ExtraBody = redactMap(model.ExtraBody)
+		redacted.Models[name] = model
+	}
+	redacted.RewardModel.BaseURL = redactURL(c.RewardModel.BaseURL)
+	redacted.Embeddings.BaseURL = redactURL(c.Embeddings.BaseURL)
+
+	data, err := toml.Marshal(redacted)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal resolved config: %w", err)
+	}
+	return string(data), nil
+}
+
+// isSensitiveKey reports whether a parameter or field name looks like a credential
+func isSensitiveKey(name string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	if slices.Contains(sensitiveKeys, normalized) {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL hides the userinfo password and credential-like query parameters of a URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if isSensitiveKey(name) {
+				query.Set(name, redactedValue)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// redactMap copies m with credential-like keys redacted, recursing into nested tables
+func redactMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	redacted := maps.Clone(m)
+	for key, value := range redacted {
+		if isSensitiveKey(key) {
+			redacted[key] = redactedValue
+		} else if nested, ok := value.(map[string]interface{}); ok {
+			redacted[key] = redactMap(nested)
+		}
+	}
+	return redacted
+}
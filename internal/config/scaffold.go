@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// starterChosenTemplate and starterRejectedTemplate are the placeholder generation templates
+// written by StarterConfig (the loader has no defaults for these two)
+const (
+	starterChosenTemplate = `You are a talented writer. Write a compelling short story (400-600 words) based on this prompt:
+
+{{.Prompt}}
+
+Requirements:
+- Clear beginning, middle, and end
+- Vivid descriptions and engaging characters
+- Strong narrative voice
+
+Write the story now:`
+
+	starterRejectedTemplate = `Write a short story based on this prompt:
+
+{{.Prompt}}
+
+Write 200-300 words.`
+)
+
+// starterConfigTemplate renders a commented starter config from a defaulted Config
+// Values come from the Config so the output tracks the loader and Validate defaults
+// Uses << >> delimiters so {{.Prompt}} style placeholders can be written literally
+var starterConfigTemplate = template.Must(template.New("starter").Delims("<<", ">>").Funcs(template.FuncMap{
+	"str":     strconv.Quote,
+	"literal": func(s string) string { return "'''" + s + "'''" },
+	"float":   func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) },
+}).Parse(`# VellumForge2 starter config (dataset_mode = <<str (print .Mode)>>)
+# Generated by "vellumforge2 config init". Replace the placeholder models, then run:
+#   vellumforge2 run --config config.toml
+# API keys are read from the environment (.env): API_KEY, or OPENAI_API_KEY, NVIDIA_API_KEY, ...
+# See configs/config.example.toml for every available option.
+
+[generation]
+main_topic = <<str .Cfg.Generation.MainTopic>>
+num_subtopics = <<.Cfg.Generation.NumSubtopics>>
+num_prompts_per_subtopic = <<.Cfg.Generation.NumPromptsPerSubtopic>>
+concurrency = <<.Cfg.Generation.Concurrency>>           # Parallel requests
+dataset_mode = <<str (print .Cfg.Generation.DatasetMode)>>  # sft, dpo, kto, or mo-dpo
+<<- if eq .Mode "sft">>
+sft_format = <<str (print .Cfg.Generation.SFTFormat)>>  # alpaca, sharegpt, or openai
+<<- end>>
+<<- if eq .Mode "dpo">>
+dpo_format = <<str (print .Cfg.Generation.DPOFormat)>>  # standard (strings) or conversational (message lists)
+<<- end>>
+<<- if eq .Mode "kto">>
+# KTO writes one desirable (chosen) and one undesirable (rejected) row per prompt
+<<- end>>
+over_generation_buffer = <<float .Cfg.Generation.OverGenerationBuffer>>  # Request extra prompts to cover failures
+max_exclusion_list_size = <<.Cfg.Generation.MaxExclusionListSize>>
+min_success_rate = <<float .Cfg.Generation.MinSuccessRate>>
+prompt_retry_attempts = <<.Cfg.Generation.PromptRetryAttempts>>
+subtopic_chunk_size = <<.Cfg.Generation.SubtopicChunkSize>>
+
+# Checkpointing lets interrupted runs resume with "vellumforge2 checkpoint resume"
+enable_checkpointing = true
+checkpoint_interval = <<.Cfg.Generation.CheckpointInterval>>
+
+# Main model - generates chosen responses (and subtopics/prompts)
+[models.main]
+base_url = <<str .Main.BaseURL>>
+model_name = <<str .Main.ModelName>>
+temperature = <<float .Main.Temperature>>
+structure_temperature = <<float .Main.StructureTemperature>>  # Used for subtopic/prompt JSON generation
+top_p = <<float .Main.TopP>>
+max_output_tokens = <<.Main.MaxOutputTokens>>
+context_size = <<.Main.ContextSize>>
+rate_limit_per_minute = <<.Main.RateLimitPerMinute>>
+http_timeout_seconds = <<.Main.HTTPTimeoutSeconds>>
+max_retries = <<.Main.MaxRetries>>
+<<- with .Rejected>>
+
+# Rejected model - generates the weaker side of each pair
+[models.rejected]
+base_url = <<str .BaseURL>>
+model_name = <<str .ModelName>>
+temperature = <<float .Temperature>>
+top_p = <<float .TopP>>
+max_output_tokens = <<.MaxOutputTokens>>
+context_size = <<.ContextSize>>
+rate_limit_per_minute = <<.RateLimitPerMinute>>
+http_timeout_seconds = <<.HTTPTimeoutSeconds>>
+max_retries = <<.MaxRetries>>
+<<- end>>
+<<- with .Judge>>
+
+# Judge model - scores chosen and rejected responses
+[models.judge]
+enabled = true
+base_url = <<str .BaseURL>>
+model_name = <<str .ModelName>>
+temperature = <<float .Temperature>>
+top_p = <<float .TopP>>
+max_output_tokens = <<.MaxOutputTokens>>
+context_size = <<.ContextSize>>
+rate_limit_per_minute = <<.RateLimitPerMinute>>
+http_timeout_seconds = <<.HTTPTimeoutSeconds>>
+judge_timeout_seconds = <<.JudgeTimeoutSeconds>>
+max_retries = <<.MaxRetries>>
+<<- end>>
+
+# Templates use Go text/template syntax. Available variables:
+#   subtopic_generation: {{.MainTopic}}, {{.NumSubtopics}}, {{.IsRetry}}, {{.ExcludeSubtopics}}
+#   prompt_generation: {{.SubTopic}}, {{.NumPrompts}}, {{.MainTopic}}
+#   chosen_generation / rejected_generation: {{.Prompt}}, {{.MainTopic}}, {{.SubTopic}}
+<<- if .Judge>>
+#   judge_rubric: {{.Prompt}}, {{.StoryText}}
+<<- end>>
+[prompt_templates]
+subtopic_generation = <<literal .Cfg.PromptTemplates.SubtopicGeneration>>
+
+prompt_generation = <<literal .Cfg.PromptTemplates.PromptGeneration>>
+
+chosen_generation = <<literal .Cfg.PromptTemplates.ChosenGeneration>>
+
+rejected_generation = <<literal .Cfg.PromptTemplates.RejectedGeneration>>
+<<- if .Judge>>
+
+judge_rubric = <<literal .Cfg.PromptTemplates.JudgeRubric>>
+<<- end>>
+
+[huggingface]
+# Upload with: vellumforge2 run --upload-to-hf (requires HUGGING_FACE_TOKEN)
+repo_id = ""
+`))
+
+// placeholderModel returns a model entry with a placeholder endpoint (defaults are applied later)
+func placeholderModel(modelName string) ModelConfig {
+	return ModelConfig{
+		BaseURL:   "https://api.openai.com/v1",
+		ModelName: modelName,
+	}
+}
+
+// StarterConfig renders a commented, validatable starter config for the given dataset mode
+// Models and templates are placeholders; every other value is the default the loader applies
+func StarterConfig(mode models.DatasetMode) (string, error) {
+	switch mode {
+	case models.DatasetModeSFT, models.DatasetModeDPO, models.DatasetModeKTO, models.DatasetModeMODPO:
+	default:
+		return "", fmt.Errorf("unknown dataset mode %q (must be one of: sft, dpo, kto, mo-dpo)", mode)
+	}
+
+	cfg := Config{
+		Generation: GenerationConfig{
+			MainTopic:             "Fantasy Fiction",
+			NumSubtopics:          10,
+			NumPromptsPerSubtopic: 5,
+			DatasetMode:           mode,
+			CheckpointInterval:    10,
+		},
+		Models: map[string]ModelConfig{"main": placeholderModel("your-main-model")},
+		PromptTemplates: PromptTemplates{
+			ChosenGeneration:   starterChosenTemplate,
+			RejectedGeneration: starterRejectedTemplate,
+		},
+	}
+	if mode != models.DatasetModeSFT {
+		cfg.Models["rejected"] = placeholderModel("your-rejected-model")
+	}
+	if mode == models.DatasetModeMODPO {
+		judge := placeholderModel("your-judge-model")
+		judge.Enabled = true
+		judge.Temperature = 0.4
+		cfg.Models["judge"] = judge
+	}
+	if mode == models.DatasetModeDPO {
+		cfg.Generation.DPOFormat = models.DPOFormatStandard
+	}
+
+	applyDefaults(&cfg)
+	mainModel := cfg.Models["main"]
+	mainModel.StructureTemperature = 0.4
+	cfg.Models["main"] = mainModel
+
+	data := struct {
+		Mode     models.DatasetMode
+		Cfg      Config
+		Main     ModelConfig
+		Rejected *ModelConfig
+		Judge    *ModelConfig
+	}{Mode: mode, Cfg: cfg, Main: mainModel}
+	if rejected, ok := cfg.Models["rejected"]; ok {
+		data.Rejected = &rejected
+	}
+	if judge, ok := cfg.Models["judge"]; ok {
+		data.Judge = &judge
+	}
+
+	var b strings.Builder
+	if err := starterConfigTemplate.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render starter config: %w", err)
+	}
+	return b.String(), nil
+}
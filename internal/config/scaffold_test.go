@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestStarterConfig(t *testing.T) {
+	for _, mode := range []models.DatasetMode{
+		models.DatasetModeSFT,
+		models.DatasetModeDPO,
+		models.DatasetModeKTO,
+		models.DatasetModeMODPO,
+	} {
+		t.Run(string(mode), func(t *testing.T) {
+			out, err := StarterConfig(mode)
+			if err != nil {
+				t.Fatalf("StarterConfig(%s) error = %v", mode, err)
+			}
+
+			// Every key must map to a Config field
+			var cfg Config
+			decoder := toml.NewDecoder(strings.NewReader(out))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&cfg); err != nil {
+				t.Fatalf("Starter config does not decode strictly: %v\n%s", err, out)
+			}
+
+			applyDefaults(&cfg)
+			if err := cfg.Validate(); err != nil {
+				t.Fatalf("Starter config failed validation: %v", err)
+			}
+			if err := cfg.ValidateInputs(); err != nil {
+				t.Fatalf("Starter config failed input validation: %v", err)
+			}
+
+			if cfg.Generation.DatasetMode != mode {
+				t.Errorf("Expected dataset_mode %s, got %s", mode, cfg.Generation.DatasetMode)
+			}
+			if !strings.Contains(cfg.PromptTemplates.ChosenGeneration, "{{.Prompt}}") {
+				t.Errorf("Expected chosen_generation to reference {{.Prompt}}, got %q", cfg.PromptTemplates.ChosenGeneration)
+			}
+			_, hasRejected := cfg.Models["rejected"]
+			if hasRejected != (mode != models.DatasetModeSFT) {
+				t.Errorf("Unexpected models.rejected presence for %s: %v", mode, hasRejected)
+			}
+			judge, hasJudge := cfg.Models["judge"]
+			if (hasJudge && judge.Enabled) != (mode == models.DatasetModeMODPO) {
+				t.Errorf("Unexpected models.judge for %s: present=%v enabled=%v", mode, hasJudge, judge.Enabled)
+			}
+		})
+	}
+
+	if _, err := StarterConfig("ppo"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// StdinSource is the --config value that reads the TOML from stdin
+	StdinSource = "-"
+	// MaxConfigSize caps the config read from any source (prevents OOM attacks)
+	MaxConfigSize = 10 * 1024 * 1024 // 10MB
+	// ConfigFetchTimeout bounds fetching a config from a URL
+	ConfigFetchTimeout = 30 * time.Second
+)
+
+// sourceClient fetches configs given as URLs
+var sourceClient = &http.Client{Timeout: ConfigFetchTimeout}
+
+// streamSources caches stdin and URL configs, which can only be read once (stdin) or may change
+// between reads (URL), so the session backup holds exactly what was loaded
+var streamSources sync.Map // source -> []byte
+
+// IsRemoteSource reports whether source is an http(s) URL rather than a file path
+func IsRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// ReadSource reads raw config TOML from a file path, "-" (stdin), or an http(s) URL
+// Stdin and URL sources are read once per process; later calls return the same bytes
+func ReadSource(source string) ([]byte, error) {
+	if source != StdinSource && !IsRemoteSource(source) {
+		return readConfigFile(source)
+	}
+
+	if data, ok := streamSources.Load(source); ok {
+		return data.([]byte), nil
+	}
+
+	var data []byte
+	var err error
+	if source == StdinSource {
+		data, err = readLimited(os.Stdin, "stdin")
+	} else {
+		data, err = fetchConfig(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := streamSources.LoadOrStore(source, data)
+	return actual.([]byte), nil
+}
+
+// readConfigFile reads a config file after checking its size
+func readConfigFile(path string) ([]byte, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat config file: %w", err)
+	}
+	if fileInfo.Size() > MaxConfigSize {
+		return nil, fmt.Errorf("config file too large: %d bytes (max %d bytes)", fileInfo.Size(), MaxConfigSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return data, nil
+}
+
+// fetchConfig downloads a config from an http(s) URL
+func fetchConfig(rawURL string) ([]byte, error) {
+	if err := validateConfigURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	resp, err := sourceClient.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch config: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch config: status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > MaxConfigSize {
+		return nil, fmt.Errorf("config too large: %d bytes (max %d bytes)", resp.ContentLength, MaxConfigSize)
+	}
+	return readLimited(resp.Body, "URL")
+}
+
+// readLimited reads r up to MaxConfigSize, failing rather than truncating larger input
+func readLimited(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config from %s: %w", name, err)
+	}
+	if len(data) > MaxConfigSize {
+		return nil, fmt.Errorf("config from %s too large (max %d bytes)", name, MaxConfigSize)
+	}
+	return data, nil
+}
+
+// validateConfigURL accepts http(s) URLs with a host, no embedded credentials (they would end
+// up in logs and the checkpoint), and no ".." path segments
+func validateConfigURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid config URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("config URL must use http or https scheme (got %s)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("config URL must have a host")
+	}
+	if u.User != nil {
+		return fmt.Errorf("config URL must not contain credentials")
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == ".." {
+			return fmt.Errorf("config URL must not contain '..' path segments")
+		}
+	}
+	return nil
+}
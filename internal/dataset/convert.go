@@ -0,0 +1,447 @@
+package dataset
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lamim/vellumforge2/internal/writer"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// ConvertOptions controls an offline dataset format conversion (no models are called).
+type ConvertOptions struct {
+	From       models.DatasetMode
+	To         models.DatasetMode
+	SFTFormat  models.SFTFormat // Output format when To is sft (default: sharegpt)
+	InputPath  string
+	OutputPath string
+	// Force replaces an existing output file.
+	Force bool
+}
+
+// ConvertStats summarizes a conversion.
+type ConvertStats struct {
+	Read       int // Input rows
+	Written    int // Output rows
+	Skipped    int // Input rows with nothing to write (e.g. undesirable KTO rows for SFT)
+	Duplicates int // Output rows dropped because an earlier row was identical (DPO rows shape repeats chosen)
+}
+
+// ConversionLoss checks that a conversion is possible and describes what it drops.
+// MO-DPO input is read as DPO with its judge scores and topic columns dropped. DPO input may
+// also be in TRL's conversational format, with a single-turn prompt message list.
+func ConversionLoss(from, to models.DatasetMode) (string, error) {
+	if from == to {
+		return "", fmt.Errorf("input and output formats are both %s", from)
+	}
+	switch from {
+	case models.DatasetModeSFT:
+		return "", fmt.Errorf("cannot convert sft to %s: SFT rows have no rejected responses (use transform --mode sft-to-dpo to generate them)", to)
+	case models.DatasetModeDPO, models.DatasetModeKTO, models.DatasetModeMODPO:
+	default:
+		return "", fmt.Errorf("unknown input format %q (expected dpo, kto or mo-dpo)", from)
+	}
+
+	var notes []string
+	if from == models.DatasetModeMODPO {
+		notes = append(notes, "judge scores and topic columns are dropped")
+	}
+	switch to {
+	case models.DatasetModeDPO:
+		if from == models.DatasetModeKTO {
+			notes = append(notes, "completions are paired by prompt, so prompts without both a desirable and an undesirable completion are skipped; the input is grouped in memory")
+		}
+	case models.DatasetModeKTO:
+		if from != models.DatasetModeKTO {
+			notes = append(notes, "each pair becomes a desirable and an undesirable row, and the pairing itself is not kept")
+		}
+	case models.DatasetModeSFT:
+		if from == models.DatasetModeKTO {
+			notes = append(notes, "undesirable completions are dropped")
+		} else {
+			notes = append(notes, "rejected responses are dropped")
+		}
+	case models.DatasetModeMODPO:
+		return "", fmt.Errorf("cannot convert %s to mo-dpo: MO-DPO rows need judge scores", from)
+	default:
+		return "", fmt.Errorf("unknown output format %q (expected dpo, kto or sft)", to)
+	}
+	return strings.Join(notes, "; "), nil
+}
+
+// Convert streams a JSONL dataset from one format into another.
+// Row IDs are not carried over since the record content changes.
+func Convert(opts ConvertOptions) (ConvertStats, error) {
+	var stats ConvertStats
+	if _, err := ConversionLoss(opts.From, opts.To); err != nil {
+		return stats, err
+	}
+	switch opts.SFTFormat {
+	case "":
+		opts.SFTFormat = models.SFTFormatShareGPT
+	case models.SFTFormatAlpaca, models.SFTFormatShareGPT, models.SFTFormatOpenAI:
+	default:
+		return stats, fmt.Errorf("unknown SFT format %q (expected alpaca, sharegpt or openai)", opts.SFTFormat)
+	}
+	if err := checkConvertPaths(opts); err != nil {
+		return stats, err
+	}
+
+	in, err := os.Open(opts.InputPath)
+	if err != nil {
+		return stats, fmt.Errorf("failed to open input dataset: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	// Write to a temp file so a failed conversion never leaves a partial output behind
+	tmpPath := opts.OutputPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return stats, fmt.Errorf("failed to create output dataset: %w", err)
+	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	c := &converter{opts: opts, out: bufio.NewWriter(out), seen: make(map[[sha256.Size]byte]bool)}
+	if opts.From == models.DatasetModeKTO {
+		err = c.convertKTO(in, &stats)
+	} else {
+		err = c.convertDPO(in, &stats)
+	}
+	if err == nil {
+		err = c.out.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	if err := os.Rename(tmpPath, opts.OutputPath); err != nil {
+		return stats, fmt.Errorf("failed to move output dataset into place: %w", err)
+	}
+	return stats, nil
+}
+
+// checkConvertPaths refuses to convert a file onto itself or over an existing file without Force
+func checkConvertPaths(opts ConvertOptions) error {
+	inAbs, err := filepath.Abs(opts.InputPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve input path: %w", err)
+	}
+	outAbs, err := filepath.Abs(opts.OutputPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %w", err)
+	}
+	if inAbs == outAbs {
+		return fmt.Errorf("output path must differ from the input path")
+	}
+	if _, err := os.Stat(opts.OutputPath); err == nil && !opts.Force {
+		return fmt.Errorf("output file %s already exists (use --force to replace it)", opts.OutputPath)
+	}
+	return nil
+}
+
+// convertDPORecord reads DPO and MO-DPO rows; rejected may be a string or, for
+// rejected_output_shape = "array", a list. In conversational rows (dpo_format = "conversational")
+// prompt is a message list and chosen and rejected are single assistant messages.
+type convertDPORecord struct {
+	System         string          `json:"system"`
+	Prompt         json.RawMessage `json:"prompt"`
+	Chosen         json.RawMessage `json:"chosen"`
+	Rejected       json.RawMessage `json:"rejected"`
+	ChosenModel    string          `json:"chosen_model"`
+	RejectedModel  string          `json:"rejected_model"`
+	RejectedModels []string        `json:"rejected_models"`
+}
+
+// promptText returns the system prompt and user prompt of a row. A conversational prompt may hold
+// one optional system message followed by one user message; multi-turn prompts cannot be flattened
+// into the single prompt column of the other formats.
+func (r convertDPORecord) promptText() (system, prompt string, err error) {
+	if len(r.Prompt) == 0 || json.Unmarshal(r.Prompt, &prompt) == nil {
+		return r.System, prompt, nil
+	}
+	var messages []models.OpenAIMessage
+	if err := json.Unmarshal(r.Prompt, &messages); err != nil {
+		return "", "", fmt.Errorf("prompt must be a string or a list of messages")
+	}
+	system = r.System
+	for i, message := range messages {
+		switch {
+		case message.Role == "system" && i == 0:
+			if system == "" {
+				system = message.Content
+			}
+		case message.Role == "user" && prompt == "":
+			prompt = message.Content
+		default:
+			return "", "", fmt.Errorf("conversational prompt must be an optional system message and one user message (multi-turn prompts are not supported)")
+		}
+	}
+	return system, prompt, nil
+}
+
+// responseText decodes a response written as a string or as a single assistant message list
+func responseText(raw json.RawMessage, field string) (string, error) {
+	var text string
+	if len(raw) == 0 || json.Unmarshal(raw, &text) == nil {
+		return text, nil
+	}
+	var messages []models.OpenAIMessage
+	if err := json.Unmarshal(raw, &messages); err != nil || len(messages) != 1 || messages[0].Role != "assistant" {
+		return "", fmt.Errorf("%s must be a string or a single assistant message", field)
+	}
+	return messages[0].Content, nil
+}
+
+// rejectedList returns the rejected responses of a row with their models
+func (r convertDPORecord) rejectedList() ([]models.RejectedResponse, error) {
+	var single string
+	if err := json.Unmarshal(r.Rejected, &single); err == nil {
+		return []models.RejectedResponse{{Content: single, Model: r.RejectedModel}}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(r.Rejected, &list); err != nil {
+		content, msgErr := responseText(r.Rejected, "rejected")
+		if msgErr != nil {
+			return nil, fmt.Errorf("rejected must be a string, a list of strings or a single assistant message")
+		}
+		return []models.RejectedResponse{{Content: content, Model: r.RejectedModel}}, nil
+	}
+	rejected := make([]models.RejectedResponse, len(list))
+	for i, content := range list {
+		rejected[i].Content = content
+		if i < len(r.RejectedModels) {
+			rejected[i].Model = r.RejectedModels[i]
+		}
+	}
+	return rejected, nil
+}
+
+// ktoCompletions groups the KTO rows of one prompt for pairing
+type ktoCompletions struct {
+	system      string
+	prompt      string
+	desirable   []models.KTORecord
+	undesirable []models.KTORecord
+}
+
+type converter struct {
+	opts ConvertOptions
+	out  *bufio.Writer
+	seen map[[sha256.Size]byte]bool // Hashes of written rows that input duplicates often repeat
+}
+
+// convertDPO streams DPO or MO-DPO rows into KTO, SFT or DPO rows
+func (c *converter) convertDPO(in io.Reader, stats *ConvertStats) error {
+	return scanJSONL(in, func(lineNum int, line []byte) error {
+		var record convertDPORecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("line %d: failed to parse %s record: %w", lineNum, c.opts.From, err)
+		}
+		system, prompt, err := record.promptText()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		chosenText, err := responseText(record.Chosen, "chosen")
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if strings.TrimSpace(prompt) == "" || strings.TrimSpace(chosenText) == "" {
+			return fmt.Errorf("line %d: %s record is missing prompt or chosen", lineNum, c.opts.From)
+		}
+		rejected, err := record.rejectedList()
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		stats.Read++
+
+		switch c.opts.To {
+		case models.DatasetModeKTO:
+			chosen := models.KTORecord{System: system, Prompt: prompt, Completion: chosenText, Label: true, Model: record.ChosenModel}
+			// Rows-shape DPO repeats the chosen row once per rejected candidate
+			if err := c.writeUnique(stats, chosen, system, prompt, chosenText); err != nil {
+				return err
+			}
+			for _, r := range rejected {
+				if err := c.write(stats, models.KTORecord{System: system, Prompt: prompt, Completion: r.Content, Label: false, Model: r.Model}); err != nil {
+					return err
+				}
+			}
+		case models.DatasetModeSFT:
+			if err := c.writeUnique(stats, c.sftRecord(system, prompt, chosenText), system, prompt, chosenText); err != nil {
+				return err
+			}
+		case models.DatasetModeDPO:
+			for _, r := range rejected {
+				pair := models.DPORecord{System: system, Prompt: prompt, Chosen: chosenText, Rejected: r.Content, ChosenModel: record.ChosenModel, RejectedModel: r.Model}
+				if err := c.write(stats, pair); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// convertKTO reads KTO rows into SFT rows (streamed) or DPO pairs (grouped by prompt)
+func (c *converter) convertKTO(in io.Reader, stats *ConvertStats) error {
+	var order []string
+	groups := make(map[string]*ktoCompletions)
+
+	err := scanJSONL(in, func(lineNum int, line []byte) error {
+		var record models.KTORecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("line %d: failed to parse KTO record: %w", lineNum, err)
+		}
+		if strings.TrimSpace(record.Prompt) == "" || strings.TrimSpace(record.Completion) == "" {
+			return fmt.Errorf("line %d: KTO record is missing prompt or completion", lineNum)
+		}
+		stats.Read++
+
+		if c.opts.To == models.DatasetModeSFT {
+			if !record.Label {
+				stats.Skipped++
+				return nil
+			}
+			return c.writeUnique(stats, c.sftRecord(record.System, record.Prompt, record.Completion), record.System, record.Prompt, record.Completion)
+		}
+
+		key := record.System + "\x00" + record.Prompt
+		group, ok := groups[key]
+		if !ok {
+			group = &ktoCompletions{system: record.System, prompt: record.Prompt}
+			groups[key] = group
+			order = append(order, key)
+		}
+		if record.Label {
+			group.desirable = append(group.desirable, record)
+		} else {
+			group.undesirable = append(group.undesirable, record)
+		}
+		return nil
+	})
+	if err != nil || c.opts.To == models.DatasetModeSFT {
+		return err
+	}
+
+	// Pair completions in input order, reusing the shorter side so every row of
+	// a paired prompt is written (kto_ratio datasets have unequal sides)
+	for _, key := range order {
+		group := groups[key]
+		if len(group.desirable) == 0 || len(group.undesirable) == 0 {
+			stats.Skipped += len(group.desirable) + len(group.undesirable)
+			continue
+		}
+		for i := range max(len(group.desirable), len(group.undesirable)) {
+			chosen := group.desirable[i%len(group.desirable)]
+			rejected := group.undesirable[i%len(group.undesirable)]
+			pair := models.DPORecord{
+				System:        group.system,
+				Prompt:        group.prompt,
+				Chosen:        chosen.Completion,
+				Rejected:      rejected.Completion,
+				ChosenModel:   chosen.Model,
+				RejectedModel: rejected.Model,
+			}
+			if err := c.write(stats, pair); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sftRecord builds an SFT row in the configured output format
+func (c *converter) sftRecord(system, prompt, output string) models.SFTRecord {
+	switch c.opts.SFTFormat {
+	case models.SFTFormatAlpaca:
+		return models.SFTRecord{System: system, Instruction: prompt, Output: output}
+	case models.SFTFormatOpenAI:
+		var messages []models.OpenAIMessage
+		if system != "" {
+			messages = append(messages, models.OpenAIMessage{Role: "system", Content: system})
+		}
+		messages = append(messages,
+			models.OpenAIMessage{Role: "user", Content: prompt},
+			models.OpenAIMessage{Role: "assistant", Content: output},
+		)
+		return models.SFTRecord{Messages: messages}
+	default:
+		var turns []models.ShareGPTMessage
+		if system != "" {
+			turns = append(turns, models.ShareGPTMessage{From: "system", Value: system})
+		}
+		turns = append(turns,
+			models.ShareGPTMessage{From: "human", Value: prompt},
+			models.ShareGPTMessage{From: "gpt", Value: output},
+		)
+		return models.SFTRecord{Conversations: turns}
+	}
+}
+
+// writeUnique writes record unless a row with the same system, prompt and response was already written
+func (c *converter) writeUnique(stats *ConvertStats, record any, system, prompt, response string) error {
+	key := sha256.Sum256([]byte(system + "\x00" + prompt + "\x00" + response))
+	if c.seen[key] {
+		stats.Duplicates++
+		return nil
+	}
+	c.seen[key] = true
+	return c.write(stats, record)
+}
+
+// write validates and appends one output row
+func (c *converter) write(stats *ConvertStats, record any) error {
+	var err error
+	switch r := record.(type) {
+	case models.DPORecord:
+		err = writer.ValidateDPORecord(r)
+	case models.KTORecord:
+		err = writer.ValidateKTORecord(r)
+	case models.SFTRecord:
+		err = writer.ValidateSFTRecord(r)
+	}
+	if err != nil {
+		return fmt.Errorf("output row %d: %w", stats.Written+1, err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s record: %w", c.opts.To, err)
+	}
+	if _, err := c.out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write output dataset: %w", err)
+	}
+	stats.Written++
+	return nil
+}
+
+// scanJSONL calls fn for every non-blank line of a JSONL stream with its 1-based line number
+func scanJSONL(in io.Reader, fn func(lineNum int, line []byte) error) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(lineNum, []byte(line)); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed while reading input dataset: %w", err)
+	}
+	return nil
+}
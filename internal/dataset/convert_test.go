@@ -0,0 +1,201 @@
+package dataset
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// runConvert writes input lines to a temp dataset, converts it and returns the output lines
+func runConvert(t *testing.T, opts ConvertOptions, input []string) ([]string, ConvertStats, error) {
+	t.Helper()
+	dir := t.TempDir()
+	opts.InputPath = filepath.Join(dir, "in.jsonl")
+	opts.OutputPath = filepath.Join(dir, "out.jsonl")
+	if err := os.WriteFile(opts.InputPath, []byte(strings.Join(input, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	stats, err := Convert(opts)
+	if err != nil {
+		return nil, stats, err
+	}
+	data, err := os.ReadFile(opts.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), stats, nil
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      ConvertOptions
+		input     []string
+		want      []string
+		wantStats ConvertStats
+	}{
+		{
+			name: "dpo to sft sharegpt",
+			opts: ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeSFT},
+			input: []string{
+				`{"system": "s", "prompt": "p", "chosen": "c", "rejected": "r1"}`,
+				`{"system": "s", "prompt": "p", "chosen": "c", "rejected": "r2"}`,
+			},
+			want: []string{
+				`{"conversations":[{"from":"system","value":"s"},{"from":"human","value":"p"},{"from":"gpt","value":"c"}]}`,
+			},
+			wantStats: ConvertStats{Read: 2, Written: 1, Duplicates: 1},
+		},
+		{
+			name:      "dpo to sft alpaca",
+			opts:      ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeSFT, SFTFormat: models.SFTFormatAlpaca},
+			input:     []string{`{"prompt": "p", "chosen": "c", "rejected": "r"}`},
+			want:      []string{`{"instruction":"p","output":"c"}`},
+			wantStats: ConvertStats{Read: 1, Written: 1},
+		},
+		{
+			name: "dpo to kto",
+			opts: ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeKTO},
+			input: []string{
+				`{"prompt": "p", "chosen": "c", "rejected": "r1", "chosen_model": "a", "rejected_model": "b"}`,
+				`{"prompt": "p", "chosen": "c", "rejected": "r2"}`,
+			},
+			want: []string{
+				`{"prompt":"p","completion":"c","label":true,"model":"a"}`,
+				`{"prompt":"p","completion":"r1","label":false,"model":"b"}`,
+				`{"prompt":"p","completion":"r2","label":false}`,
+			},
+			wantStats: ConvertStats{Read: 2, Written: 3, Duplicates: 1},
+		},
+		{
+			name:  "dpo array shape to kto",
+			opts:  ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeKTO},
+			input: []string{`{"prompt": "p", "chosen": "c", "rejected": ["r1", "r2"], "rejected_models": ["x"]}`},
+			want: []string{
+				`{"prompt":"p","completion":"c","label":true}`,
+				`{"prompt":"p","completion":"r1","label":false,"model":"x"}`,
+				`{"prompt":"p","completion":"r2","label":false}`,
+			},
+			wantStats: ConvertStats{Read: 1, Written: 3},
+		},
+		{
+			name: "kto to dpo pairs by prompt",
+			opts: ConvertOptions{From: models.DatasetModeKTO, To: models.DatasetModeDPO},
+			input: []string{
+				`{"prompt": "p1", "completion": "good1", "label": true}`,
+				`{"prompt": "lonely", "completion": "only good", "label": true}`,
+				`{"prompt": "p1", "completion": "bad1", "label": false}`,
+				`{"prompt": "p1", "completion": "good2", "label": true}`,
+				`{"prompt": "bad only", "completion": "b", "label": false}`,
+				`{"system": "s", "prompt": "p1", "completion": "other system", "label": false}`,
+			},
+			want: []string{
+				`{"prompt":"p1","chosen":"good1","rejected":"bad1"}`,
+				`{"prompt":"p1","chosen":"good2","rejected":"bad1"}`,
+			},
+			wantStats: ConvertStats{Read: 6, Written: 2, Skipped: 3},
+		},
+		{
+			name: "kto to sft drops undesirable",
+			opts: ConvertOptions{From: models.DatasetModeKTO, To: models.DatasetModeSFT, SFTFormat: models.SFTFormatAlpaca},
+			input: []string{
+				`{"prompt": "p", "completion": "good", "label": true}`,
+				`{"prompt": "p", "completion": "bad", "label": false}`,
+			},
+			want:      []string{`{"instruction":"p","output":"good"}`},
+			wantStats: ConvertStats{Read: 2, Written: 1, Skipped: 1},
+		},
+		{
+			name: "mo-dpo to dpo drops scores and topics",
+			opts: ConvertOptions{From: models.DatasetModeMODPO, To: models.DatasetModeDPO},
+			input: []string{
+				`{"main_topic": "Fantasy", "sub_topic": "Elves", "prompt": "p", "chosen": "c", "rejected": "r", "chosen_scores": {"plot": {"score": 5, "reasoning": "ok"}}, "preference_margin": 2}`,
+			},
+			want:      []string{`{"prompt":"p","chosen":"c","rejected":"r"}`},
+			wantStats: ConvertStats{Read: 1, Written: 1},
+		},
+		{
+			name: "conversational dpo to kto",
+			opts: ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeKTO},
+			input: []string{
+				`{"prompt": [{"role": "system", "content": "s"}, {"role": "user", "content": "p"}], "chosen": [{"role": "assistant", "content": "c"}], "rejected": [{"role": "assistant", "content": "r"}]}`,
+			},
+			want: []string{
+				`{"system":"s","prompt":"p","completion":"c","label":true}`,
+				`{"system":"s","prompt":"p","completion":"r","label":false}`,
+			},
+			wantStats: ConvertStats{Read: 1, Written: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats, err := runConvert(t, tt.opts, tt.input)
+			if err != nil {
+				t.Fatalf("Convert returned unexpected error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+			if stats != tt.wantStats {
+				t.Errorf("Expected stats %+v, got %+v", tt.wantStats, stats)
+			}
+		})
+	}
+}
+
+func TestConvert_RejectsBadRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   ConvertOptions
+		input  string
+		errMsg string
+	}{
+		{"multi-turn conversational prompt", ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeSFT},
+			`{"prompt": [{"role": "user", "content": "a"}, {"role": "assistant", "content": "b"}, {"role": "user", "content": "c"}], "chosen": "x", "rejected": "y"}`,
+			"line 1: conversational prompt must be an optional system message and one user message"},
+		{"several chosen messages", ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeKTO},
+			`{"prompt": "p", "chosen": [{"role": "assistant", "content": "a"}, {"role": "assistant", "content": "b"}], "rejected": "r"}`,
+			"chosen must be a string or a single assistant message"},
+		{"missing chosen", ConvertOptions{From: models.DatasetModeDPO, To: models.DatasetModeKTO},
+			`{"prompt": "p", "rejected": "r"}`, "missing prompt or chosen"},
+		{"kto missing completion", ConvertOptions{From: models.DatasetModeKTO, To: models.DatasetModeDPO},
+			`{"prompt": "p", "label": true}`, "missing prompt or completion"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := runConvert(t, tt.opts, []string{tt.input})
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestConvert_LeavesNoOutputOnError(t *testing.T) {
+	dir := t.TempDir()
+	opts := ConvertOptions{
+		From:       models.DatasetModeDPO,
+		To:         models.DatasetModeKTO,
+		InputPath:  filepath.Join(dir, "in.jsonl"),
+		OutputPath: filepath.Join(dir, "out.jsonl"),
+	}
+	input := `{"prompt": "p", "chosen": "c", "rejected": "r"}` + "\n" + `not json` + "\n"
+	if err := os.WriteFile(opts.InputPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	if _, err := Convert(opts); err == nil {
+		t.Fatal("Expected an error for a malformed line, got nil")
+	}
+	for _, path := range []string{opts.OutputPath, opts.OutputPath + ".tmp"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to exist, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+)
+
+// transformFailure is a single line in the failures file written when ContinueOnError is set.
+type transformFailure struct {
+	JobID      int       `json:"job_id"`
+	LineNumber int       `json:"line_number"`
+	Prompt     string    `json:"prompt"`
+	ErrorClass string    `json:"error_class"`
+	Error      string    `json:"error"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// failureLog appends skipped jobs to a JSONL file.
+// The file is opened lazily so that clean runs don't leave an empty failures file behind.
+type failureLog struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+// newFailureLog creates a failure log at path. When resume is false any
+// previous failures file is removed so it only reflects the current run.
+func newFailureLog(path string, resume bool) (*failureLog, error) {
+	if !resume && path != "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale failures file: %w", err)
+		}
+	}
+	return &failureLog{path: path}, nil
+}
+
+// Record appends a failed job to the failures file.
+func (f *failureLog) Record(jobID, lineNumber int, prompt, errorClass string, jobErr error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open failures file: %w", err)
+		}
+		f.file = file
+	}
+
+	data, err := json.Marshal(transformFailure{
+		JobID:      jobID,
+		LineNumber: lineNumber,
+		Prompt:     prompt,
+		ErrorClass: errorClass,
+		Error:      jobErr.Error(),
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal failure record: %w", err)
+	}
+	if _, err := f.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write failure record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file if it was opened.
+func (f *failureLog) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// takeRetryJobs returns the set of previously skipped job IDs that should be re-run.
+// When retry is false nothing is returned. Retried jobs stay recorded as skipped
+// until they succeed, so an interrupted retry run loses nothing.
+func (cp *transformCheckpoint) takeRetryJobs(retry bool) map[int]bool {
+	retryIDs := make(map[int]bool)
+	if !retry {
+		return retryIDs
+	}
+	for _, id := range cp.SkippedJobIDs {
+		if id >= 0 && id < cp.TotalJobs {
+			retryIDs[id] = true
+		}
+	}
+	return retryIDs
+}
+
+// markSkipped records a job as skipped in the checkpoint.
+func (cp *transformCheckpoint) markSkipped(jobID int) {
+	for _, id := range cp.SkippedJobIDs {
+		if id == jobID {
+			return
+		}
+	}
+	cp.SkippedJobIDs = append(cp.SkippedJobIDs, jobID)
+	sort.Ints(cp.SkippedJobIDs)
+}
+
+// clearSkipped removes a job from the checkpoint's skipped list after a successful retry.
+func (cp *transformCheckpoint) clearSkipped(jobID int) {
+	kept := cp.SkippedJobIDs[:0]
+	for _, id := range cp.SkippedJobIDs {
+		if id != jobID {
+			kept = append(kept, id)
+		}
+	}
+	cp.SkippedJobIDs = kept
+}
+
+// recordError counts a failed job by error class and returns the class.
+func (cp *transformCheckpoint) recordError(err error) string {
+	errorClass := api.ClassifyError(err)
+	if cp.ErrorCounts == nil {
+		cp.ErrorCounts = make(map[string]int)
+	}
+	cp.ErrorCounts[errorClass]++
+	return errorClass
+}
+
+// sortedJobIDs returns the keys of a job ID set in ascending order.
+func sortedJobIDs(ids map[int]bool) []int {
+	sorted := make([]int, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
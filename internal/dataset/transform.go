@@ -3,8 +3,11 @@ package dataset
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -17,6 +20,7 @@ import (
 	"github.com/lamim/vellumforge2/internal/api"
 	"github.com/lamim/vellumforge2/internal/config"
 	"github.com/lamim/vellumforge2/internal/util"
+	"github.com/lamim/vellumforge2/internal/writer"
 	"github.com/lamim/vellumforge2/pkg/models"
 )
 
@@ -30,6 +34,10 @@ const (
 	TransformRegenRejected TransformMode = "regen-rejected"
 )
 
+// StdioPath as Options.InputPath or Options.OutputPath reads records from stdin or writes them to stdout.
+// Streamed transforms keep no checkpoint, since stdin cannot be read again on resume.
+const StdioPath = "-"
+
 // Options controls dataset transformation behaviour.
 type Options struct {
 	InputPath           string
@@ -46,18 +54,42 @@ type Options struct {
 	Resume bool
 	// CheckpointInterval controls how often (in completed jobs) we persist progress.
 	CheckpointInterval int
+
+	// ContinueOnError logs and skips jobs that exhaust their retries instead of aborting the run.
+	// Skipped jobs are appended to FailuresPath and recorded in the checkpoint.
+	ContinueOnError bool
+	// FailuresPath is where skipped jobs are written (defaults to <output>.failures.jsonl).
+	FailuresPath string
+	// RetryFailures re-runs jobs that were skipped in a previous run when resuming.
+	// Their rows are appended to the output in job order, ahead of any jobs not yet run.
+	RetryFailures bool
+	// Force resumes even when the input files changed since the checkpoint was created.
+	Force bool
+	// AllowOutputChange resumes into different output paths than the checkpoint recorded.
+	// Output already written to the old paths is not copied over, so the result is split across files.
+	AllowOutputChange bool
 }
 
 // transformCheckpoint tracks progress for long-running transforms so they can be resumed.
 type transformCheckpoint struct {
-	Mode                TransformMode `json:"mode"`
-	InputPath           string        `json:"input_path"`
-	OutputPath          string        `json:"output_path"`
-	InputReasoningPath  string        `json:"input_reasoning_path,omitempty"`
-	OutputReasoningPath string        `json:"output_reasoning_path,omitempty"`
-	TotalJobs           int           `json:"total_jobs"`
-	CompletedJobs       int           `json:"completed_jobs"`
-	LastUpdated         time.Time     `json:"last_updated"`
+	Mode                 TransformMode  `json:"mode"`
+	InputPath            string         `json:"input_path"`
+	OutputPath           string         `json:"output_path"`
+	InputReasoningPath   string         `json:"input_reasoning_path,omitempty"`
+	OutputReasoningPath  string         `json:"output_reasoning_path,omitempty"`
+	InputSHA256          string         `json:"input_sha256,omitempty"`           // Content hash of InputPath
+	InputReasoningSHA256 string         `json:"input_reasoning_sha256,omitempty"` // Content hash of InputReasoningPath
+	TotalJobs            int            `json:"total_jobs"`
+	CompletedJobs        int            `json:"completed_jobs"`
+	SkippedJobIDs        []int          `json:"skipped_job_ids,omitempty"` // Jobs skipped via ContinueOnError
+	ErrorCounts          map[string]int `json:"error_counts,omitempty"`    // Failed jobs by error class
+	RelativePaths        bool           `json:"relative_paths,omitempty"`  // Paths are relative to the checkpoint's directory
+	LastUpdated          time.Time      `json:"last_updated"`
+}
+
+// paths returns the file paths the checkpoint records.
+func (cp *transformCheckpoint) paths() []*string {
+	return []*string{&cp.InputPath, &cp.OutputPath, &cp.InputReasoningPath, &cp.OutputReasoningPath}
 }
 
 // Run performs a dataset transformation using the provided config and API client.
@@ -83,6 +115,9 @@ func Run(
 		if opts.OutputPath == "" {
 			return fmt.Errorf("output path is required for sft-to-dpo mode")
 		}
+		if opts.InputReasoningPath != "" || opts.OutputReasoningPath != "" {
+			return fmt.Errorf("input_reasoning and output_reasoning are only supported in regen-rejected mode")
+		}
 	case TransformRegenRejected:
 		if opts.InputPath == "" && opts.InputReasoningPath == "" {
 			return fmt.Errorf("at least one of input or input_reasoning path is required for regen-rejected mode")
@@ -96,10 +131,21 @@ func Run(
 	default:
 		return fmt.Errorf("unsupported transform mode: %s", mode)
 	}
+	if opts.InputReasoningPath == StdioPath || opts.OutputReasoningPath == StdioPath {
+		return fmt.Errorf("input_reasoning and output_reasoning must be file paths; only input and output can be streamed with %q", StdioPath)
+	}
+	if opts.streaming() {
+		if opts.Resume || opts.CheckpointPath != "" {
+			return fmt.Errorf("checkpointing is not supported when input or output is %q (stdin/stdout); drop resume and checkpoint or use file paths", StdioPath)
+		}
+		if opts.OutputPath == StdioPath && opts.ContinueOnError && opts.FailuresPath == "" {
+			return fmt.Errorf("continue on error requires an explicit failures path when writing to stdout")
+		}
+	}
 	// Ensure rejected model is configured; both modes rely on it.
-	rejectedModel, ok := cfg.Models["rejected"]
+	rejectedModel, ok := cfg.RejectedModel()
 	if !ok {
-		return fmt.Errorf("config is missing 'rejected' model; it is required for dataset transforms")
+		return fmt.Errorf("config is missing 'rejected' model (or generation.rejected_from_main); it is required for dataset transforms")
 	}
 
 	if cfg.PromptTemplates.RejectedGeneration == "" {
@@ -120,13 +166,35 @@ func Run(
 			opts.CheckpointInterval = 10
 		}
 	}
-	if opts.CheckpointPath == "" {
+	if opts.AllowOutputChange {
+		if !opts.Resume {
+			return fmt.Errorf("allow output change requires resuming from an existing checkpoint")
+		}
+		// The default checkpoint path derives from the output path, so it would not find the original.
+		if opts.CheckpointPath == "" {
+			return fmt.Errorf("allow output change requires an explicit checkpoint path (the original checkpoint)")
+		}
+	}
+	if opts.CheckpointPath == "" && !opts.streaming() {
 		base := opts.OutputPath
 		if base == "" {
 			base = opts.OutputReasoningPath
 		}
 		opts.CheckpointPath = base + ".checkpoint.json"
 	}
+	if opts.FailuresPath == "" && opts.OutputPath != StdioPath {
+		base := opts.OutputPath
+		if base == "" {
+			base = opts.OutputReasoningPath
+		}
+		opts.FailuresPath = base + ".failures.jsonl"
+	}
+	if opts.RetryFailures && !opts.Resume {
+		return fmt.Errorf("retry failures requires resuming from an existing checkpoint")
+	}
+	if opts.Force && !opts.Resume {
+		return fmt.Errorf("force requires resuming from an existing checkpoint")
+	}
 
 	// Ensure output directories exist (mirrors behaviour of main pipeline).
 	if opts.OutputPath != "" {
@@ -165,7 +233,12 @@ func runSFTToDPO(
 	rejectedModel config.ModelConfig,
 	opts Options,
 ) error {
-	jobs, err := buildSFTJobs(opts.InputPath, cfg.Generation.SFTFormat)
+	input, err := openInput(opts.InputPath)
+	if err != nil {
+		return err
+	}
+	jobs, err := buildSFTJobs(input, cfg.Generation.SFTFormat)
+	_ = input.Close()
 	if err != nil {
 		return err
 	}
@@ -174,11 +247,12 @@ func runSFTToDPO(
 		return nil
 	}
 
-	cp, err := initTransformCheckpoint(opts, TransformSFTToDPO, len(jobs))
+	cp, err := initTransformCheckpoint(logger, opts, TransformSFTToDPO, len(jobs))
 	if err != nil {
 		return err
 	}
-	if cp.CompletedJobs >= cp.TotalJobs {
+	retryOrder := sortedJobIDs(cp.takeRetryJobs(opts.RetryFailures))
+	if cp.CompletedJobs >= cp.TotalJobs && len(retryOrder) == 0 {
 		logger.Info("Transform already complete", "input", opts.InputPath, "output", opts.OutputPath)
 		return nil
 	}
@@ -192,14 +266,14 @@ func runSFTToDPO(
 	}
 
 	// Open output file (append on resume, create otherwise).
-	var outputFile *os.File
+	var outputFile io.WriteCloser
 	if opts.Resume {
-		outputFile, err = os.OpenFile(opts.OutputPath, os.O_APPEND|os.O_WRONLY, 0o644)
+		outputFile, err = os.OpenFile(opts.OutputPath, resumeOpenFlags(opts), 0o644)
 		if err != nil {
 			return fmt.Errorf("failed to open output dataset for resume (file must exist): %w", err)
 		}
 	} else {
-		outputFile, err = os.Create(opts.OutputPath)
+		outputFile, err = createOutput(opts.OutputPath)
 		if err != nil {
 			return fmt.Errorf("failed to create output dataset: %w", err)
 		}
@@ -212,6 +286,12 @@ func runSFTToDPO(
 		logger.Warn("No API key found for rejected model base URL", "base_url", rejectedModel.BaseURL)
 	}
 
+	failures, err := newFailureLog(opts.FailuresPath, opts.Resume)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = failures.Close() }()
+
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 
@@ -233,7 +313,15 @@ func runSFTToDPO(
 						return
 					}
 
-					rejected, err := generateRejected(ctx, logger, cfg, client, rejectedModel, apiKey, job.Prompt)
+					rejected, err := generateRejected(ctx, logger, cfg, client, rejectedModel, apiKey, job.Prompt, job.Chosen)
+					if err == nil {
+						// Catch malformed rows (e.g. an empty chosen in the input) before they reach the output.
+						err = writer.ValidateDPORecord(models.DPORecord{
+							Prompt:   sanitizePrompt(cfg, job.Prompt),
+							Chosen:   job.Chosen,
+							Rejected: rejected,
+						})
+					}
 					res := sftResult{Job: job, Rejected: rejected, Err: err}
 
 					select {
@@ -246,9 +334,16 @@ func runSFTToDPO(
 		}(i)
 	}
 
-	// Feed jobs starting from the next incomplete index.
+	// Feed previously skipped jobs first (when retrying), then jobs starting from the next incomplete index.
 	go func() {
 		defer close(jobCh)
+		for _, id := range retryOrder {
+			select {
+			case <-ctx.Done():
+				return
+			case jobCh <- jobs[id]:
+			}
+		}
 		for i := cp.CompletedJobs; i < len(jobs); i++ {
 			select {
 			case <-ctx.Done():
@@ -265,30 +360,81 @@ func runSFTToDPO(
 	}()
 
 	nextID := cp.CompletedJobs
+	retryIdx := 0
 	pending := make(map[int]sftResult)
 
+	// writeResult writes a successful result or records a skipped job.
+	writeResult := func(res sftResult) error {
+		if res.Err != nil {
+			cp.markSkipped(res.Job.ID)
+			return nil
+		}
+		record := models.DPORecord{
+			System:   res.Job.System,
+			Prompt:   sanitizePrompt(cfg, res.Job.Prompt),
+			Chosen:   res.Job.Chosen,
+			Rejected: res.Rejected,
+		}
+		if err := encoder.Encode(&record); err != nil {
+			return fmt.Errorf("failed to write DPO record for job %d (line %d): %w", res.Job.ID, res.Job.LineNumber, err)
+		}
+		return nil
+	}
+
 	for res := range resultCh {
 		if res.Err != nil {
-			cancel()
-			return fmt.Errorf("job %d (line %d) failed: %w", res.Job.ID, res.Job.LineNumber, res.Err)
+			errorClass := cp.recordError(res.Err)
+			if !opts.ContinueOnError {
+				cancel()
+				return fmt.Errorf("job %d (line %d) failed (%s): %w", res.Job.ID, res.Job.LineNumber, errorClass, res.Err)
+			}
+			logger.Error("Job failed, skipping",
+				"job_id", res.Job.ID,
+				"line", res.Job.LineNumber,
+				"error_class", errorClass,
+				"error", res.Err)
+			if err := failures.Record(res.Job.ID, res.Job.LineNumber, res.Job.Prompt, errorClass, res.Err); err != nil {
+				logger.Warn("Failed to record skipped job", "job_id", res.Job.ID, "error", err)
+			}
 		}
 
 		pending[res.Job.ID] = res
 
+		// Retried jobs were already counted as processed. They are appended in job order ahead of
+		// new jobs, saving the checkpoint with each write so a crash cannot re-run (and duplicate) them.
+		// Jobs that fail again simply stay recorded as skipped.
+		for retryIdx < len(retryOrder) {
+			retryRes, ok := pending[retryOrder[retryIdx]]
+			if !ok {
+				break
+			}
+			delete(pending, retryRes.Job.ID)
+			retryIdx++
+			if retryRes.Err != nil {
+				continue
+			}
+			if err := writeResult(retryRes); err != nil {
+				cancel()
+				return err
+			}
+			cp.clearSkipped(retryRes.Job.ID)
+			if err := saveTransformCheckpoint(opts.CheckpointPath, cp); err != nil {
+				logger.Warn("Failed to save transform checkpoint", "error", err)
+			}
+		}
+		if retryIdx < len(retryOrder) {
+			continue // New jobs wait until every retried job is written
+		}
+
 		for {
 			nextRes, ok := pending[nextID]
 			if !ok {
 				break
 			}
 
-			record := models.DPORecord{
-				Prompt:   nextRes.Job.Prompt,
-				Chosen:   nextRes.Job.Chosen,
-				Rejected: nextRes.Rejected,
-			}
-			if err := encoder.Encode(&record); err != nil {
+			if err := writeResult(nextRes); err != nil {
 				cancel()
-				return fmt.Errorf("failed to write DPO record for job %d (line %d): %w", nextRes.Job.ID, nextRes.Job.LineNumber, err)
+				return err
 			}
 
 			delete(pending, nextID)
@@ -318,7 +464,15 @@ func runSFTToDPO(
 		"input", opts.InputPath,
 		"output", opts.OutputPath,
 		"total_jobs", cp.TotalJobs,
-		"completed_jobs", cp.CompletedJobs)
+		"completed_jobs", cp.CompletedJobs,
+		"skipped_jobs", len(cp.SkippedJobIDs),
+		"error_breakdown", cp.ErrorCounts)
+	if len(cp.SkippedJobIDs) > 0 {
+		logger.Warn("Some jobs were skipped after exhausting retries",
+			"skipped_jobs", len(cp.SkippedJobIDs),
+			"failures_file", opts.FailuresPath,
+			"retry_hint", "resume with --resume --retry-failures to re-run them")
+	}
 
 	return nil
 }
@@ -351,6 +505,15 @@ func runRegenRejected(
 			logger.Info("No DPO records found in reasoning input dataset", "input_reasoning", opts.InputReasoningPath)
 			return nil
 		}
+		if opts.InputPath != "" {
+			logger.Warn("Both input and input_reasoning are set; prompts and chosen are read from input_reasoning only",
+				"input", opts.InputPath,
+				"input_reasoning", opts.InputReasoningPath)
+		}
+		if opts.OutputPath != "" {
+			logger.Info("Reconstructing non-reasoning chosen responses from reasoning chosen (think blocks removed)",
+				"output", opts.OutputPath)
+		}
 
 		jobs = make([]dpoJob, len(reasoningRecs))
 		for i, rr := range reasoningRecs {
@@ -371,13 +534,19 @@ func runRegenRejected(
 			jobs[i] = dpoJob{
 				ID:         i,
 				LineNumber: rr.LineNumber,
+				System:     rr.Record.System,
 				Prompt:     prompt,
 				Chosen:     chosen,
 			}
 		}
 	} else {
 		// Fallback: use non-reasoning dataset only
-		jobs, err = buildDPOJobs(opts.InputPath)
+		input, err := openInput(opts.InputPath)
+		if err != nil {
+			return err
+		}
+		jobs, err = buildDPOJobs(input)
+		_ = input.Close()
 		if err != nil {
 			return err
 		}
@@ -387,11 +556,12 @@ func runRegenRejected(
 		}
 	}
 
-	cp, err := initTransformCheckpoint(opts, TransformRegenRejected, len(jobs))
+	cp, err := initTransformCheckpoint(logger, opts, TransformRegenRejected, len(jobs))
 	if err != nil {
 		return err
 	}
-	if cp.CompletedJobs >= cp.TotalJobs {
+	retryOrder := sortedJobIDs(cp.takeRetryJobs(opts.RetryFailures))
+	if cp.CompletedJobs >= cp.TotalJobs && len(retryOrder) == 0 {
 		logger.Info("Transform already complete", "input", opts.InputPath, "output", opts.OutputPath)
 		return nil
 	}
@@ -405,17 +575,17 @@ func runRegenRejected(
 	}
 
 	// Open output files (append on resume, create otherwise).
-	var outputFile *os.File
+	var outputFile io.WriteCloser
 	var reasoningFile *os.File
 
 	if opts.OutputPath != "" {
 		if opts.Resume {
-			outputFile, err = os.OpenFile(opts.OutputPath, os.O_APPEND|os.O_WRONLY, 0o644)
+			outputFile, err = os.OpenFile(opts.OutputPath, resumeOpenFlags(opts), 0o644)
 			if err != nil {
 				return fmt.Errorf("failed to open output dataset for resume (file must exist): %w", err)
 			}
 		} else {
-			outputFile, err = os.Create(opts.OutputPath)
+			outputFile, err = createOutput(opts.OutputPath)
 			if err != nil {
 				return fmt.Errorf("failed to create output dataset: %w", err)
 			}
@@ -425,7 +595,7 @@ func runRegenRejected(
 
 	if opts.OutputReasoningPath != "" {
 		if opts.Resume {
-			reasoningFile, err = os.OpenFile(opts.OutputReasoningPath, os.O_APPEND|os.O_WRONLY, 0o644)
+			reasoningFile, err = os.OpenFile(opts.OutputReasoningPath, resumeOpenFlags(opts), 0o644)
 			if err != nil {
 				return fmt.Errorf("failed to open reasoning output dataset for resume (file must exist): %w", err)
 			}
@@ -451,6 +621,12 @@ func runRegenRejected(
 		logger.Warn("No API key found for rejected model base URL", "base_url", rejectedModel.BaseURL)
 	}
 
+	failures, err := newFailureLog(opts.FailuresPath, opts.Resume)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = failures.Close() }()
+
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 
@@ -472,7 +648,15 @@ func runRegenRejected(
 						return
 					}
 
-					rejected, err := generateRejected(ctx, logger, cfg, client, rejectedModel, apiKey, job.Prompt)
+					rejected, err := generateRejected(ctx, logger, cfg, client, rejectedModel, apiKey, job.Prompt, job.Chosen)
+					if err == nil {
+						// Catch malformed rows (e.g. an empty chosen in the input) before they reach the output.
+						err = writer.ValidateDPORecord(models.DPORecord{
+							Prompt:   sanitizePrompt(cfg, job.Prompt),
+							Chosen:   job.Chosen,
+							Rejected: rejected,
+						})
+					}
 					res := dpoResult{Job: job, Rejected: rejected, Err: err}
 
 					select {
@@ -485,9 +669,16 @@ func runRegenRejected(
 		}(i)
 	}
 
-	// Feed jobs starting from the next incomplete index.
+	// Feed previously skipped jobs first (when retrying), then jobs starting from the next incomplete index.
 	go func() {
 		defer close(jobCh)
+		for _, id := range retryOrder {
+			select {
+			case <-ctx.Done():
+				return
+			case jobCh <- jobs[id]:
+			}
+		}
 		for i := cp.CompletedJobs; i < len(jobs); i++ {
 			select {
 			case <-ctx.Done():
@@ -504,61 +695,112 @@ func runRegenRejected(
 	}()
 
 	nextID := cp.CompletedJobs
+	retryIdx := 0
 	pending := make(map[int]dpoResult)
 
+	// writeResult writes a successful result to the requested outputs or records a skipped job.
+	writeResult := func(res dpoResult) error {
+		if res.Err != nil {
+			cp.markSkipped(res.Job.ID)
+			return nil
+		}
+
+		// Write non-reasoning dataset if requested
+		if encoder != nil {
+			record := models.DPORecord{
+				System:   res.Job.System,
+				Prompt:   sanitizePrompt(cfg, res.Job.Prompt),
+				Chosen:   res.Job.Chosen,
+				Rejected: res.Rejected,
+			}
+			if err := encoder.Encode(&record); err != nil {
+				return fmt.Errorf("failed to write updated DPO record for job %d (line %d): %w", res.Job.ID, res.Job.LineNumber, err)
+			}
+		}
+
+		// Write reasoning dataset if requested
+		if reasoningEncoder != nil && len(reasoningRecs) > 0 {
+			if res.Job.ID < 0 || res.Job.ID >= len(reasoningRecs) {
+				return fmt.Errorf("invalid reasoning record index %d for job %d", res.Job.ID, res.Job.ID)
+			}
+			base := reasoningRecs[res.Job.ID].Record
+
+			// Preserve existing reasoning (if any) while updating the rejected content
+			combinedRejected := res.Rejected
+			if base.Rejected != "" && util.ContainsThinkTags(base.Rejected) {
+				think, _ := util.SplitThinkAndAnswer(base.Rejected)
+				if strings.TrimSpace(think) != "" {
+					combinedRejected = util.CombineReasoningAndContent(think, res.Rejected)
+				}
+			}
+
+			reasoningRecord := models.DPORecord{
+				System:   base.System,
+				Prompt:   sanitizePrompt(cfg, base.Prompt),
+				Chosen:   base.Chosen,
+				Rejected: combinedRejected,
+			}
+			if err := reasoningEncoder.Encode(&reasoningRecord); err != nil {
+				return fmt.Errorf("failed to write reasoning DPO record for job %d (line %d): %w", res.Job.ID, res.Job.LineNumber, err)
+			}
+		}
+		return nil
+	}
+
 	for res := range resultCh {
 		if res.Err != nil {
-			cancel()
-			return fmt.Errorf("job %d (line %d) failed: %w", res.Job.ID, res.Job.LineNumber, res.Err)
+			errorClass := cp.recordError(res.Err)
+			if !opts.ContinueOnError {
+				cancel()
+				return fmt.Errorf("job %d (line %d) failed (%s): %w", res.Job.ID, res.Job.LineNumber, errorClass, res.Err)
+			}
+			logger.Error("Job failed, skipping",
+				"job_id", res.Job.ID,
+				"line", res.Job.LineNumber,
+				"error_class", errorClass,
+				"error", res.Err)
+			if err := failures.Record(res.Job.ID, res.Job.LineNumber, res.Job.Prompt, errorClass, res.Err); err != nil {
+				logger.Warn("Failed to record skipped job", "job_id", res.Job.ID, "error", err)
+			}
 		}
 
 		pending[res.Job.ID] = res
 
-		for {
-			nextRes, ok := pending[nextID]
+		// Retried jobs were already counted as processed. They are appended in job order ahead of
+		// new jobs, saving the checkpoint with each write so a crash cannot re-run (and duplicate) them.
+		// Jobs that fail again simply stay recorded as skipped.
+		for retryIdx < len(retryOrder) {
+			retryRes, ok := pending[retryOrder[retryIdx]]
 			if !ok {
 				break
 			}
-
-			// Write non-reasoning dataset if requested
-			if encoder != nil {
-				record := models.DPORecord{
-					Prompt:   nextRes.Job.Prompt,
-					Chosen:   nextRes.Job.Chosen,
-					Rejected: nextRes.Rejected,
-				}
-				if err := encoder.Encode(&record); err != nil {
-					cancel()
-					return fmt.Errorf("failed to write updated DPO record for job %d (line %d): %w", nextRes.Job.ID, nextRes.Job.LineNumber, err)
-				}
+			delete(pending, retryRes.Job.ID)
+			retryIdx++
+			if retryRes.Err != nil {
+				continue
+			}
+			if err := writeResult(retryRes); err != nil {
+				cancel()
+				return err
+			}
+			cp.clearSkipped(retryRes.Job.ID)
+			if err := saveTransformCheckpoint(opts.CheckpointPath, cp); err != nil {
+				logger.Warn("Failed to save transform checkpoint", "error", err)
 			}
+		}
+		if retryIdx < len(retryOrder) {
+			continue // New jobs wait until every retried job is written
+		}
 
-			// Write reasoning dataset if requested
-			if reasoningEncoder != nil && len(reasoningRecs) > 0 {
-				if nextRes.Job.ID < 0 || nextRes.Job.ID >= len(reasoningRecs) {
-					cancel()
-					return fmt.Errorf("invalid reasoning record index %d for job %d", nextRes.Job.ID, nextRes.Job.ID)
-				}
-				base := reasoningRecs[nextRes.Job.ID].Record
-
-				// Preserve existing reasoning (if any) while updating the rejected content
-				combinedRejected := nextRes.Rejected
-				if base.Rejected != "" && util.ContainsThinkTags(base.Rejected) {
-					think, _ := util.SplitThinkAndAnswer(base.Rejected)
-					if strings.TrimSpace(think) != "" {
-						combinedRejected = util.CombineReasoningAndContent(think, nextRes.Rejected)
-					}
-				}
+		for {
+			nextRes, ok := pending[nextID]
+			if !ok {
+				break
+			}
 
-				reasoningRecord := models.DPORecord{
-					Prompt:   base.Prompt,
-					Chosen:   base.Chosen,
-					Rejected: combinedRejected,
-				}
-				if err := reasoningEncoder.Encode(&reasoningRecord); err != nil {
-					cancel()
-					return fmt.Errorf("failed to write reasoning DPO record for job %d (line %d): %w", nextRes.Job.ID, nextRes.Job.LineNumber, err)
-				}
+			if err := writeResult(nextRes); err != nil {
+				cancel()
+				return err
 			}
 
 			delete(pending, nextID)
@@ -588,11 +830,28 @@ func runRegenRejected(
 		"input", opts.InputPath,
 		"output", opts.OutputPath,
 		"total_jobs", cp.TotalJobs,
-		"completed_jobs", cp.CompletedJobs)
+		"completed_jobs", cp.CompletedJobs,
+		"skipped_jobs", len(cp.SkippedJobIDs),
+		"error_breakdown", cp.ErrorCounts)
+	if len(cp.SkippedJobIDs) > 0 {
+		logger.Warn("Some jobs were skipped after exhausting retries",
+			"skipped_jobs", len(cp.SkippedJobIDs),
+			"failures_file", opts.FailuresPath,
+			"retry_hint", "resume with --resume --retry-failures to re-run them")
+	}
 
 	return nil
 }
 
+// sanitizePrompt strips reasoning tags from a prompt unless the config preserves them.
+// Reasoning in chosen/rejected is left untouched.
+func sanitizePrompt(cfg *config.Config, prompt string) string {
+	if cfg.Generation.PreservePromptReasoning {
+		return prompt
+	}
+	return util.StripReasoningFromPrompt(prompt)
+}
+
 // extractPromptAndChosen derives the prompt and chosen text from an SFT record
 // using the configured SFT format.
 func extractPromptAndChosen(record *models.SFTRecord, format models.SFTFormat) (string, string, error) {
@@ -641,15 +900,62 @@ func extractPromptAndChosen(record *models.SFTRecord, format models.SFTFormat) (
 		}
 		return prompt, chosen, nil
 
+	case models.SFTFormatOpenAI:
+		var prompt string
+		var chosen string
+
+		for _, msg := range record.Messages {
+			if prompt == "" && strings.ToLower(strings.TrimSpace(msg.Role)) == "user" {
+				prompt = strings.TrimSpace(msg.Content)
+			}
+		}
+
+		for i := len(record.Messages) - 1; i >= 0; i-- {
+			if strings.ToLower(strings.TrimSpace(record.Messages[i].Role)) == "assistant" {
+				chosen = record.Messages[i].Content
+				break
+			}
+		}
+
+		if prompt == "" {
+			return "", "", fmt.Errorf("openai SFT record missing user message")
+		}
+		if strings.TrimSpace(chosen) == "" {
+			return "", "", fmt.Errorf("openai SFT record missing assistant message")
+		}
+		return prompt, chosen, nil
+
 	default:
 		return "", "", fmt.Errorf("unsupported SFT format for transform: %s", format)
 	}
 }
 
+// extractSystemPrompt returns the system prompt of an OpenAI-format SFT record so it can be
+// carried into the DPO output. Other formats have no system prompt to carry and return "".
+// A system message is only accepted as the first message, and only once.
+func extractSystemPrompt(record *models.SFTRecord, format models.SFTFormat) (string, error) {
+	if format != models.SFTFormatOpenAI {
+		return "", nil
+	}
+
+	var system string
+	for i, msg := range record.Messages {
+		if strings.ToLower(strings.TrimSpace(msg.Role)) != "system" {
+			continue
+		}
+		if i != 0 {
+			return "", fmt.Errorf("openai SFT record has a system message at position %d (must be first)", i)
+		}
+		system = msg.Content
+	}
+	return system, nil
+}
+
 // sftJob represents a single SFT example to be converted to a DPO record.
 type sftJob struct {
 	ID         int
 	LineNumber int
+	System     string // System prompt from OpenAI-format input (empty for other formats)
 	Prompt     string
 	Chosen     string
 }
@@ -664,6 +970,7 @@ type sftResult struct {
 type dpoJob struct {
 	ID         int
 	LineNumber int
+	System     string // System prompt carried in the input record (if any)
 	Prompt     string
 	Chosen     string
 }
@@ -680,15 +987,9 @@ type reasoningDPORecord struct {
 	LineNumber int
 }
 
-// buildSFTJobs parses an input SFT JSONL file into a list of jobs.
-func buildSFTJobs(inputPath string, format models.SFTFormat) ([]sftJob, error) {
-	file, err := os.Open(inputPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open input dataset: %w", err)
-	}
-	defer func() { _ = file.Close() }()
-
-	scanner := bufio.NewScanner(file)
+// buildSFTJobs parses SFT JSONL records into a list of jobs.
+func buildSFTJobs(input io.Reader, format models.SFTFormat) ([]sftJob, error) {
+	scanner := bufio.NewScanner(input)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
 
 	var jobs []sftJob
@@ -710,11 +1011,16 @@ func buildSFTJobs(inputPath string, format models.SFTFormat) ([]sftJob, error) {
 		if err != nil {
 			return nil, fmt.Errorf("line %d: %w", lineNum, err)
 		}
+		system, err := extractSystemPrompt(&sft, format)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
 
 		id := len(jobs)
 		jobs = append(jobs, sftJob{
 			ID:         id,
 			LineNumber: lineNum,
+			System:     system,
 			Prompt:     prompt,
 			Chosen:     chosen,
 		})
@@ -727,15 +1033,9 @@ func buildSFTJobs(inputPath string, format models.SFTFormat) ([]sftJob, error) {
 	return jobs, nil
 }
 
-// buildDPOJobs parses an input DPO JSONL file into a list of jobs.
-func buildDPOJobs(inputPath string) ([]dpoJob, error) {
-	file, err := os.Open(inputPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open input dataset: %w", err)
-	}
-	defer func() { _ = file.Close() }()
-
-	scanner := bufio.NewScanner(file)
+// buildDPOJobs parses DPO JSONL records into a list of jobs.
+func buildDPOJobs(input io.Reader) ([]dpoJob, error) {
+	scanner := bufio.NewScanner(input)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
 
 	var jobs []dpoJob
@@ -764,6 +1064,7 @@ func buildDPOJobs(inputPath string) ([]dpoJob, error) {
 		jobs = append(jobs, dpoJob{
 			ID:         id,
 			LineNumber: lineNum,
+			System:     dpo.System,
 			Prompt:     dpo.Prompt,
 			Chosen:     dpo.Chosen,
 		})
@@ -816,6 +1117,7 @@ func loadReasoningDPO(inputPath string) ([]reasoningDPORecord, error) {
 }
 
 // generateRejected calls the rejected model using the configured rejected_generation template.
+// chosen is exposed to the template as {{.Chosen}} with generation.rejected_sees_chosen.
 func generateRejected(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -824,10 +1126,15 @@ func generateRejected(
 	rejectedModel config.ModelConfig,
 	apiKey string,
 	prompt string,
+	chosen string,
 ) (string, error) {
-	renderedPrompt, err := util.RenderTemplate(cfg.PromptTemplates.RejectedGeneration, map[string]interface{}{
+	templateData := map[string]interface{}{
 		"Prompt": prompt,
-	})
+	}
+	if cfg.Generation.RejectedSeesChosen {
+		templateData["Chosen"] = chosen
+	}
+	renderedPrompt, err := util.RenderTemplate(cfg.PromptTemplates.RejectedGeneration, templateData)
 	if err != nil {
 		return "", fmt.Errorf("failed to render rejected template: %w", err)
 	}
@@ -870,8 +1177,104 @@ func generateRejected(
 	return content, nil
 }
 
+// ioMismatches describes each input/output path that differs between a checkpoint and the current run.
+// With allowOutputChange a moved output path is accepted, but adding or removing an output is not.
+func ioMismatches(cp *transformCheckpoint, opts Options, allowOutputChange bool) []string {
+	paths := []struct {
+		flag       string
+		checkpoint string
+		current    string
+		output     bool
+	}{
+		{"--input", cp.InputPath, opts.InputPath, false},
+		{"--output", cp.OutputPath, opts.OutputPath, true},
+		{"--input-reasoning", cp.InputReasoningPath, opts.InputReasoningPath, false},
+		{"--output-reasoning", cp.OutputReasoningPath, opts.OutputReasoningPath, true},
+	}
+
+	var mismatches []string
+	for _, p := range paths {
+		if p.output && allowOutputChange && (p.checkpoint == "") == (p.current == "") {
+			continue
+		}
+		if absPath(p.checkpoint) != absPath(p.current) {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %s but the checkpoint has %s", p.flag, pathOrNone(absPath(p.current)), pathOrNone(absPath(p.checkpoint))))
+		}
+	}
+	return mismatches
+}
+
+// streaming reports whether the transform reads stdin or writes stdout.
+func (opts Options) streaming() bool {
+	return opts.InputPath == StdioPath || opts.OutputPath == StdioPath
+}
+
+// openInput opens an input dataset, or stdin for StdioPath.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == StdioPath {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input dataset: %w", err)
+	}
+	return file, nil
+}
+
+// createOutput creates an output dataset, or returns stdout (left open on Close) for StdioPath.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == StdioPath {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// nopWriteCloser is an io.WriteCloser whose Close does nothing.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// resumeOpenFlags opens outputs for appending on resume; a changed output path may not exist yet.
+func resumeOpenFlags(opts Options) int {
+	if opts.AllowOutputChange {
+		return os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	}
+	return os.O_APPEND | os.O_WRONLY
+}
+
+func pathOrNone(path string) string {
+	if path == "" {
+		return "(not set)"
+	}
+	return path
+}
+
 // initTransformCheckpoint loads or creates a checkpoint for a transform run.
-func initTransformCheckpoint(opts Options, mode TransformMode, totalJobs int) (*transformCheckpoint, error) {
+// Streamed transforms get a checkpoint that only tracks progress in memory.
+func initTransformCheckpoint(logger *slog.Logger, opts Options, mode TransformMode, totalJobs int) (*transformCheckpoint, error) {
+	if opts.streaming() {
+		return &transformCheckpoint{
+			Mode:                mode,
+			InputPath:           opts.InputPath,
+			OutputPath:          opts.OutputPath,
+			InputReasoningPath:  opts.InputReasoningPath,
+			OutputReasoningPath: opts.OutputReasoningPath,
+			TotalJobs:           totalJobs,
+			LastUpdated:         time.Now(),
+		}, nil
+	}
+
+	inputHash, err := hashInputFile(opts.InputPath)
+	if err != nil {
+		return nil, err
+	}
+	reasoningHash, err := hashInputFile(opts.InputReasoningPath)
+	if err != nil {
+		return nil, err
+	}
+
 	if opts.Resume {
 		cp, err := loadTransformCheckpoint(opts.CheckpointPath)
 		if err != nil {
@@ -880,11 +1283,33 @@ func initTransformCheckpoint(opts Options, mode TransformMode, totalJobs int) (*
 		if cp.Mode != mode {
 			return nil, fmt.Errorf("checkpoint mode mismatch: expected %s, got %s", mode, cp.Mode)
 		}
-		if cp.InputPath != opts.InputPath || cp.OutputPath != opts.OutputPath ||
-			cp.InputReasoningPath != opts.InputReasoningPath || cp.OutputReasoningPath != opts.OutputReasoningPath {
-			return nil, fmt.Errorf("checkpoint I/O mismatch: checkpoint was created for input=%s, output=%s, input_reasoning=%s, output_reasoning=%s",
-				cp.InputPath, cp.OutputPath, cp.InputReasoningPath, cp.OutputReasoningPath)
+		if mismatches := ioMismatches(cp, opts, opts.AllowOutputChange); len(mismatches) > 0 {
+			hint := "resume with the same paths the checkpoint was created with"
+			if !opts.AllowOutputChange {
+				hint += ", or pass --allow-output-change to write to new output paths"
+			}
+			return nil, fmt.Errorf("checkpoint I/O mismatch: %s (%s)", strings.Join(mismatches, "; "), hint)
+		}
+		if moved := ioMismatches(cp, opts, false); len(moved) > 0 {
+			logger.Warn("Resuming into new output paths (--allow-output-change); rows written before this run stay in the old files",
+				"changes", moved,
+				"completed_jobs", cp.CompletedJobs)
+			cp.OutputPath = opts.OutputPath
+			cp.OutputReasoningPath = opts.OutputReasoningPath
+		}
+		// Completed job IDs index into the input, so a changed input would silently misalign them.
+		// Checkpoints written before hashes were recorded have none and are trusted.
+		changed := inputChanged(cp.InputSHA256, inputHash, opts.InputPath)
+		changed = append(changed, inputChanged(cp.InputReasoningSHA256, reasoningHash, opts.InputReasoningPath)...)
+		if len(changed) > 0 {
+			if !opts.Force {
+				return nil, fmt.Errorf("input changed since the checkpoint was created (%s); resuming would misalign completed jobs - restore the original input, start without --resume, or pass --force to resume anyway",
+					strings.Join(changed, ", "))
+			}
+			logger.Warn("Input changed since the checkpoint was created - resuming anyway (--force)", "files", changed)
 		}
+		cp.InputSHA256 = inputHash
+		cp.InputReasoningSHA256 = reasoningHash
 		// If totalJobs changed (e.g. edited dataset), prefer the current scan but keep completed count bounded.
 		cp.TotalJobs = totalJobs
 		if cp.CompletedJobs > cp.TotalJobs {
@@ -894,14 +1319,16 @@ func initTransformCheckpoint(opts Options, mode TransformMode, totalJobs int) (*
 	}
 
 	cp := &transformCheckpoint{
-		Mode:                mode,
-		InputPath:           opts.InputPath,
-		OutputPath:          opts.OutputPath,
-		InputReasoningPath:  opts.InputReasoningPath,
-		OutputReasoningPath: opts.OutputReasoningPath,
-		TotalJobs:           totalJobs,
-		CompletedJobs:       0,
-		LastUpdated:         time.Now(),
+		Mode:                 mode,
+		InputPath:            opts.InputPath,
+		OutputPath:           opts.OutputPath,
+		InputReasoningPath:   opts.InputReasoningPath,
+		OutputReasoningPath:  opts.OutputReasoningPath,
+		InputSHA256:          inputHash,
+		InputReasoningSHA256: reasoningHash,
+		TotalJobs:            totalJobs,
+		CompletedJobs:        0,
+		LastUpdated:          time.Now(),
 	}
 	if err := saveTransformCheckpoint(opts.CheckpointPath, cp); err != nil {
 		return nil, err
@@ -909,6 +1336,35 @@ func initTransformCheckpoint(opts Options, mode TransformMode, totalJobs int) (*
 	return cp, nil
 }
 
+// hashInputFile returns the hex SHA-256 of a transform input, or "" when path is unset.
+func hashInputFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open input for hashing: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash input %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// inputChanged reports path when the checkpoint recorded a hash that no longer matches.
+func inputChanged(recorded, current, path string) []string {
+	if recorded == "" || recorded == current {
+		return nil
+	}
+	return []string{path}
+}
+
+// loadTransformCheckpoint reads a checkpoint and resolves its paths to absolute ones.
+// Checkpoints written before paths were stored relative to the checkpoint resolve them
+// against the working directory, as they were recorded.
 func loadTransformCheckpoint(path string) (*transformCheckpoint, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -918,12 +1374,35 @@ func loadTransformCheckpoint(path string) (*transformCheckpoint, error) {
 	if err := json.Unmarshal(data, &cp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal transform checkpoint: %w", err)
 	}
+
+	base := absPath(filepath.Dir(path))
+	for _, p := range cp.paths() {
+		if *p == "" {
+			continue
+		}
+		if cp.RelativePaths && !filepath.IsAbs(*p) {
+			*p = filepath.Join(base, filepath.FromSlash(*p))
+		}
+		*p = absPath(*p)
+	}
 	return &cp, nil
 }
 
+// saveTransformCheckpoint writes cp with its paths relative to the checkpoint's directory,
+// so the checkpoint and the datasets next to it can be moved or copied to another machine
+// together and still be resumed. An empty path (streamed transforms) saves nothing.
 func saveTransformCheckpoint(path string, cp *transformCheckpoint) error {
 	cp.LastUpdated = time.Now()
-	data, err := json.MarshalIndent(cp, "", "  ")
+	if path == "" {
+		return nil
+	}
+	stored := *cp
+	stored.RelativePaths = true
+	base := absPath(filepath.Dir(path))
+	for _, p := range stored.paths() {
+		*p = relativePath(base, *p)
+	}
+	data, err := json.MarshalIndent(&stored, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal transform checkpoint: %w", err)
 	}
@@ -933,6 +1412,30 @@ func saveTransformCheckpoint(path string, cp *transformCheckpoint) error {
 	return nil
 }
 
+// absPath returns the cleaned absolute form of path ("" stays "").
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// relativePath returns path relative to base with forward slashes, or the absolute path
+// when there is no relative one (e.g. another drive on Windows).
+func relativePath(base, path string) string {
+	if path == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(base, absPath(path))
+	if err != nil {
+		return absPath(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
 // isLocalEndpoint mirrors the internal api helper for determining if a base URL is local.
 func isLocalEndpoint(endpoint string) bool {
 	return strings.Contains(endpoint, "://127.0.0.1") ||
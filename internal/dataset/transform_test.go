@@ -0,0 +1,346 @@
+package dataset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// writeFile creates path with content, failing the test on error
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestTransformCheckpoint_RoundTripRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	cp := &transformCheckpoint{
+		Mode:          TransformSFTToDPO,
+		InputPath:     "data/sft.jsonl",
+		OutputPath:    "out/dpo.jsonl",
+		TotalJobs:     4,
+		CompletedJobs: 2,
+		SkippedJobIDs: []int{1},
+	}
+	cpPath := filepath.Join("out", "dpo.checkpoint.json")
+	if err := os.Mkdir("out", 0o755); err != nil {
+		t.Fatalf("Failed to create output dir: %v", err)
+	}
+	if err := saveTransformCheckpoint(cpPath, cp); err != nil {
+		t.Fatalf("saveTransformCheckpoint failed: %v", err)
+	}
+	if cp.OutputPath != "out/dpo.jsonl" {
+		t.Errorf("Expected save to leave the caller's paths alone, got %s", cp.OutputPath)
+	}
+
+	var stored transformCheckpoint
+	data, err := os.ReadFile(cpPath)
+	if err != nil {
+		t.Fatalf("Failed to read checkpoint: %v", err)
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("Failed to parse checkpoint: %v", err)
+	}
+	if !stored.RelativePaths || stored.OutputPath != "dpo.jsonl" || stored.InputPath != "../data/sft.jsonl" {
+		t.Errorf("Expected paths relative to the checkpoint, got input=%s output=%s relative=%v", stored.InputPath, stored.OutputPath, stored.RelativePaths)
+	}
+
+	loaded, err := loadTransformCheckpoint(cpPath)
+	if err != nil {
+		t.Fatalf("loadTransformCheckpoint failed: %v", err)
+	}
+	if loaded.OutputPath != filepath.Join(dir, "out", "dpo.jsonl") || loaded.InputPath != filepath.Join(dir, "data", "sft.jsonl") {
+		t.Errorf("Expected absolute paths, got input=%s output=%s", loaded.InputPath, loaded.OutputPath)
+	}
+	if loaded.CompletedJobs != 2 || loaded.TotalJobs != 4 || len(loaded.SkippedJobIDs) != 1 || loaded.Mode != TransformSFTToDPO {
+		t.Errorf("Expected progress to round-trip, got %+v", loaded)
+	}
+}
+
+func TestTransformCheckpoint_LegacyPathsResolveAgainstWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	cpPath := filepath.Join("checkpoints", "old.checkpoint.json")
+	writeFile(t, cpPath, `{"mode": "sft-to-dpo", "input_path": "in.jsonl", "output_path": "out.jsonl", "total_jobs": 1}`)
+
+	loaded, err := loadTransformCheckpoint(cpPath)
+	if err != nil {
+		t.Fatalf("loadTransformCheckpoint failed: %v", err)
+	}
+	if loaded.InputPath != filepath.Join(dir, "in.jsonl") || loaded.OutputPath != filepath.Join(dir, "out.jsonl") {
+		t.Errorf("Expected paths relative to the working directory, got input=%s output=%s", loaded.InputPath, loaded.OutputPath)
+	}
+}
+
+func TestInitTransformCheckpoint_ResumeAfterMovingDir(t *testing.T) {
+	oldDir := filepath.Join(t.TempDir(), "run")
+	input := "{\"instruction\": \"p\", \"output\": \"c\"}\n"
+	writeFile(t, filepath.Join(oldDir, "sft.jsonl"), input)
+
+	opts := Options{
+		InputPath:      filepath.Join(oldDir, "sft.jsonl"),
+		OutputPath:     filepath.Join(oldDir, "dpo.jsonl"),
+		CheckpointPath: filepath.Join(oldDir, "dpo.checkpoint.json"),
+	}
+	cp, err := initTransformCheckpoint(testLogger(), opts, TransformSFTToDPO, 1)
+	if err != nil {
+		t.Fatalf("initTransformCheckpoint failed: %v", err)
+	}
+	cp.CompletedJobs = 1
+	if err := saveTransformCheckpoint(opts.CheckpointPath, cp); err != nil {
+		t.Fatalf("saveTransformCheckpoint failed: %v", err)
+	}
+
+	newDir := filepath.Join(t.TempDir(), "moved")
+	if err := os.Rename(oldDir, newDir); err != nil {
+		t.Fatalf("Failed to move run directory: %v", err)
+	}
+
+	resume := Options{
+		InputPath:      filepath.Join(newDir, "sft.jsonl"),
+		OutputPath:     filepath.Join(newDir, "dpo.jsonl"),
+		CheckpointPath: filepath.Join(newDir, "dpo.checkpoint.json"),
+		Resume:         true,
+	}
+	resumed, err := initTransformCheckpoint(testLogger(), resume, TransformSFTToDPO, 1)
+	if err != nil {
+		t.Fatalf("Expected resume from the moved directory to succeed, got %v", err)
+	}
+	if resumed.CompletedJobs != 1 || resumed.OutputPath != resume.OutputPath {
+		t.Errorf("Expected 1 completed job writing to %s, got %d writing to %s", resume.OutputPath, resumed.CompletedJobs, resumed.OutputPath)
+	}
+}
+
+func TestIOMismatches(t *testing.T) {
+	cp := &transformCheckpoint{
+		InputPath:           "/data/dpo.jsonl",
+		OutputPath:          "/data/dpo.regen.jsonl",
+		InputReasoningPath:  "/data/dpo.reasoning.jsonl",
+		OutputReasoningPath: "/data/dpo.reasoning.regen.jsonl",
+	}
+	same := Options{
+		InputPath:           cp.InputPath,
+		OutputPath:          cp.OutputPath,
+		InputReasoningPath:  cp.InputReasoningPath,
+		OutputReasoningPath: cp.OutputReasoningPath,
+	}
+
+	tests := []struct {
+		name              string
+		modify            func(opts *Options)
+		allowOutputChange bool
+		want              []string
+	}{
+		{"same paths", func(*Options) {}, false, nil},
+		{
+			name:   "moved input reasoning",
+			modify: func(opts *Options) { opts.InputReasoningPath = "/other/dpo.reasoning.jsonl" },
+			want:   []string{"--input-reasoning is /other/dpo.reasoning.jsonl but the checkpoint has /data/dpo.reasoning.jsonl"},
+		},
+		{
+			name:   "dropped output reasoning",
+			modify: func(opts *Options) { opts.OutputReasoningPath = "" },
+			want:   []string{"--output-reasoning is (not set) but the checkpoint has /data/dpo.reasoning.regen.jsonl"},
+		},
+		{
+			name: "moved outputs",
+			modify: func(opts *Options) {
+				opts.OutputPath = "/new/dpo.regen.jsonl"
+				opts.OutputReasoningPath = "/new/dpo.reasoning.regen.jsonl"
+			},
+			want: []string{
+				"--output is /new/dpo.regen.jsonl but the checkpoint has /data/dpo.regen.jsonl",
+				"--output-reasoning is /new/dpo.reasoning.regen.jsonl but the checkpoint has /data/dpo.reasoning.regen.jsonl",
+			},
+		},
+		{
+			name: "moved outputs allowed",
+			modify: func(opts *Options) {
+				opts.OutputPath = "/new/dpo.regen.jsonl"
+				opts.OutputReasoningPath = "/new/dpo.reasoning.regen.jsonl"
+			},
+			allowOutputChange: true,
+		},
+		{
+			name:              "dropped output not allowed by output change",
+			modify:            func(opts *Options) { opts.OutputReasoningPath = "" },
+			allowOutputChange: true,
+			want:              []string{"--output-reasoning is (not set) but the checkpoint has /data/dpo.reasoning.regen.jsonl"},
+		},
+		{
+			name:              "moved input not allowed by output change",
+			modify:            func(opts *Options) { opts.InputPath = "/other/dpo.jsonl" },
+			allowOutputChange: true,
+			want:              []string{"--input is /other/dpo.jsonl but the checkpoint has /data/dpo.jsonl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := same
+			tt.modify(&opts)
+			got := ioMismatches(cp, opts, tt.allowOutputChange)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected mismatches %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInitTransformCheckpoint_ResumeMismatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, dir string, opts *Options)
+		errMsg string
+	}{
+		{
+			name: "different input path",
+			modify: func(t *testing.T, dir string, opts *Options) {
+				opts.InputPath = filepath.Join(dir, "other.jsonl")
+				writeFile(t, opts.InputPath, "{\"instruction\": \"p\", \"output\": \"c\"}\n")
+			},
+			errMsg: "checkpoint I/O mismatch: --input is",
+		},
+		{
+			name: "different output path",
+			modify: func(t *testing.T, dir string, opts *Options) {
+				opts.OutputPath = filepath.Join(dir, "elsewhere.jsonl")
+			},
+			errMsg: "--allow-output-change",
+		},
+		{
+			name: "changed input content",
+			modify: func(t *testing.T, dir string, opts *Options) {
+				writeFile(t, opts.InputPath, "{\"instruction\": \"edited\", \"output\": \"c\"}\n")
+			},
+			errMsg: "input changed since the checkpoint was created",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := Options{
+				InputPath:      filepath.Join(dir, "sft.jsonl"),
+				OutputPath:     filepath.Join(dir, "dpo.jsonl"),
+				CheckpointPath: filepath.Join(dir, "dpo.checkpoint.json"),
+			}
+			writeFile(t, opts.InputPath, "{\"instruction\": \"p\", \"output\": \"c\"}\n")
+			if _, err := initTransformCheckpoint(testLogger(), opts, TransformSFTToDPO, 1); err != nil {
+				t.Fatalf("initTransformCheckpoint failed: %v", err)
+			}
+
+			tt.modify(t, dir, &opts)
+			opts.Resume = true
+			_, err := initTransformCheckpoint(testLogger(), opts, TransformSFTToDPO, 1)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestRun_RetryFailuresWritesInJobOrder(t *testing.T) {
+	// The retried job 0 answers last, so writing as results arrive would put it after job 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content
+		if prompt == "p0" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		_, _ = fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"weak %s"},"finish_reason":"stop"}]}`, prompt)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	opts := Options{
+		InputPath:      filepath.Join(dir, "dpo.jsonl"),
+		OutputPath:     filepath.Join(dir, "dpo.regen.jsonl"),
+		CheckpointPath: filepath.Join(dir, "dpo.regen.checkpoint.json"),
+		Concurrency:    4,
+	}
+	var input strings.Builder
+	for i := range 5 {
+		fmt.Fprintf(&input, "{\"prompt\": \"p%d\", \"chosen\": \"c%d\", \"rejected\": \"r%d\"}\n", i, i, i)
+	}
+	writeFile(t, opts.InputPath, input.String())
+	writeFile(t, opts.OutputPath, "{\"prompt\":\"p1\",\"chosen\":\"c1\",\"rejected\":\"weak p1\"}\n")
+
+	// A previous run completed jobs 0-2 but skipped 0 and 2
+	cp, err := initTransformCheckpoint(testLogger(), opts, TransformRegenRejected, 5)
+	if err != nil {
+		t.Fatalf("initTransformCheckpoint failed: %v", err)
+	}
+	cp.CompletedJobs = 3
+	cp.SkippedJobIDs = []int{0, 2}
+	if err := saveTransformCheckpoint(opts.CheckpointPath, cp); err != nil {
+		t.Fatalf("saveTransformCheckpoint failed: %v", err)
+	}
+
+	cfg := &config.Config{
+		Models: map[string]config.ModelConfig{
+			"rejected": {BaseURL: server.URL, ModelName: "test-model", MaxOutputTokens: 100, RateLimitPerMinute: 6000},
+		},
+		PromptTemplates: config.PromptTemplates{RejectedGeneration: "{{.Prompt}}"},
+	}
+	opts.Resume = true
+	opts.RetryFailures = true
+	opts.ContinueOnError = true
+	if err := Run(context.Background(), testLogger(), TransformRegenRejected, cfg, &config.Secrets{}, api.NewClient(testLogger()), opts); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(opts.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	var prompts []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record models.DPORecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to parse output line %q: %v", line, err)
+		}
+		prompts = append(prompts, record.Prompt)
+	}
+	if got, want := strings.Join(prompts, ","), "p1,p0,p2,p3,p4"; got != want {
+		t.Errorf("Expected rows %s, got %s", want, got)
+	}
+
+	loaded, err := loadTransformCheckpoint(opts.CheckpointPath)
+	if err != nil {
+		t.Fatalf("loadTransformCheckpoint failed: %v", err)
+	}
+	if loaded.CompletedJobs != 5 || len(loaded.SkippedJobIDs) != 0 {
+		t.Errorf("Expected 5 completed jobs and none skipped, got %d completed, skipped %v", loaded.CompletedJobs, loaded.SkippedJobIDs)
+	}
+}
@@ -0,0 +1,123 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// embeddingsPath is the OpenAI-compatible embeddings endpoint relative to the API root
+const embeddingsPath = "embeddings"
+
+// Client requests embeddings from an OpenAI-compatible /embeddings endpoint
+// Requests go through the shared API client, so [network] TLS settings and [retry] backoff apply
+type Client struct {
+	apiClient  *api.Client
+	endpoint   string
+	model      string
+	apiKey     string
+	batchSize  int
+	maxRetries int
+	timeout    time.Duration
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// New creates a client for the [embeddings] endpoint that sends requests through apiClient
+func New(cfg config.EmbeddingsConfig, apiClient *api.Client, apiKey string) *Client {
+	return &Client{
+		apiClient:  apiClient,
+		endpoint:   Endpoint(cfg.BaseURL),
+		model:      cfg.ModelName,
+		apiKey:     apiKey,
+		batchSize:  cfg.Batch(),
+		maxRetries: cfg.MaxRetries,
+		timeout:    cfg.Timeout(),
+	}
+}
+
+// Endpoint resolves the embeddings URL for a base_url the way chat endpoints are resolved:
+// a URL already ending in /embeddings is used as-is, a bare host gets /v1 added, and any
+// other path gets /embeddings appended
+func Endpoint(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return strings.TrimRight(baseURL, "/") + "/" + embeddingsPath
+	}
+
+	basePath := strings.TrimRight(u.Path, "/")
+	switch {
+	case strings.HasSuffix(basePath, "/"+embeddingsPath):
+		u.Path = basePath
+	case basePath == "":
+		u.Path = "/v1/" + embeddingsPath
+	default:
+		u.Path = basePath + "/" + embeddingsPath
+	}
+	u.RawPath = ""
+	return u.String()
+}
+
+// Embed returns one embedding per text, in order, requesting them in batches
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += c.batchSize {
+		end := min(start+c.batchSize, len(texts))
+		batch, err := c.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("embeddings batch %d-%d: %w", start, end, err)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedBatch makes a single embeddings request, retrying transient failures
+func (c *Client) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: c.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	respBody, err := c.apiClient.PostJSON(ctx, c.endpoint, c.apiKey, body, c.maxRetries, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed embeddingResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Data))
+	}
+
+	// Providers may return the items out of order; index says which input each belongs to
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) || vectors[item.Index] != nil {
+			return nil, fmt.Errorf("invalid or repeated embedding index %d", item.Index)
+		}
+		if len(item.Embedding) == 0 {
+			return nil, fmt.Errorf("empty embedding for input %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
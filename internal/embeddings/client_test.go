@@ -0,0 +1,188 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// testAPIClient returns an API client that retries once without a real backoff
+func testAPIClient() *api.Client {
+	client := api.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	return client
+}
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://api.openai.com/v1", "https://api.openai.com/v1/embeddings"},
+		{"https://api.openai.com/v1/", "https://api.openai.com/v1/embeddings"},
+		{"http://localhost:8080", "http://localhost:8080/v1/embeddings"},
+		{"http://localhost:8080/v1/embeddings", "http://localhost:8080/v1/embeddings"},
+		{"https://example.com/openai/v1?api-version=1", "https://example.com/openai/v1/embeddings?api-version=1"},
+	}
+
+	for _, tt := range tests {
+		if got := Endpoint(tt.baseURL); got != tt.want {
+			t.Errorf("Endpoint(%q): expected %q, got %q", tt.baseURL, tt.want, got)
+		}
+	}
+}
+
+func TestEmbedBatchesAndOrders(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Expected bearer auth, got %q", got)
+		}
+		var req embeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Model != "embed-small" {
+			t.Errorf("Expected model embed-small, got %q", req.Model)
+		}
+
+		// Answer in reverse order; the client must place items by index
+		var resp embeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{i, []float32{float32(len(req.Input[i]))}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := New(config.EmbeddingsConfig{BaseURL: server.URL + "/v1", ModelName: "embed-small", BatchSize: 2}, testAPIClient(), "key")
+	vectors, err := client.Embed(context.Background(), []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("Embed returned unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 batched requests, got %d", requests)
+	}
+	for i, want := range []float32{1, 2, 3} {
+		if len(vectors[i]) != 1 || vectors[i][0] != want {
+			t.Errorf("Vector %d: expected [%v], got %v", i, want, vectors[i])
+		}
+	}
+}
+
+func TestEmbedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		errMsg string
+	}{
+		{"http error", http.StatusUnauthorized, `{"error": "bad key"}`, "status 401"},
+		{"missing items", http.StatusOK, `{"data": [{"index": 0, "embedding": [1]}]}`, "expected 2 embeddings"},
+		{"repeated index", http.StatusOK, `{"data": [{"index": 0, "embedding": [1]}, {"index": 0, "embedding": [1]}]}`, "repeated embedding index"},
+		{"empty embedding", http.StatusOK, `{"data": [{"index": 0, "embedding": [1]}, {"index": 1, "embedding": []}]}`, "empty embedding"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := New(config.EmbeddingsConfig{BaseURL: server.URL, ModelName: "m"}, testAPIClient(), "")
+			_, err := client.Embed(context.Background(), []string{"a", "b"})
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestEmbedRetriesTransientErrors(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"data": [{"index": 0, "embedding": [0.5]}]}`)
+	}))
+	defer server.Close()
+
+	client := New(config.EmbeddingsConfig{BaseURL: server.URL, ModelName: "m"}, testAPIClient(), "")
+	vectors, err := client.Embed(context.Background(), []string{"a"})
+	if err != nil {
+		t.Fatalf("Embed returned unexpected error: %v", err)
+	}
+	if len(vectors) != 1 || vectors[0][0] != 0.5 || requests != 2 {
+		t.Errorf("Expected one vector after a retry, got %v after %d requests", vectors, requests)
+	}
+}
+
+func TestEmbedUsesNetworkTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"data": [{"index": 0, "embedding": [1]}]}`)
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // Expected handshake failures
+	server.StartTLS()
+	defer server.Close()
+
+	// PEM bundle with the test server's self-signed certificate
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certPEM, 0o644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		netCfg  config.NetworkConfig
+		wantErr bool
+	}{
+		{"default verification rejects self-signed", config.NetworkConfig{}, true},
+		{"ca_bundle", config.NetworkConfig{CABundle: bundle}, false},
+		{"insecure_skip_verify", config.NetworkConfig{InsecureSkipVerify: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiClient := testAPIClient()
+			if err := apiClient.ConfigureTLS(tt.netCfg, nil); err != nil {
+				t.Fatalf("ConfigureTLS returned unexpected error: %v", err)
+			}
+
+			client := New(config.EmbeddingsConfig{BaseURL: server.URL, ModelName: "m"}, apiClient, "")
+			_, err := client.Embed(context.Background(), []string{"a"})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "certificate") {
+					t.Errorf("Expected a certificate error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected request to succeed, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package embeddings
+
+import "math"
+
+// CosineSimilarity returns the cosine similarity of two vectors (0 when either is all zeros
+// or their lengths differ)
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Diverse greedily keeps each vector whose similarity to every vector kept so far is below
+// threshold, so of a group of near-duplicates only the first survives
+// It returns one keep flag per vector
+func Diverse(vectors [][]float32, threshold float64) []bool {
+	keep := make([]bool, len(vectors))
+	kept := make([][]float32, 0, len(vectors))
+	for i, v := range vectors {
+		unit := normalize(v)
+		duplicate := false
+		for _, k := range kept {
+			if dot(unit, k) >= threshold {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			keep[i] = true
+			kept = append(kept, unit)
+		}
+	}
+	return keep
+}
+
+// normalize returns v scaled to unit length, so a dot product is the cosine similarity
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	unit := make([]float32, len(v))
+	if norm == 0 {
+		return unit
+	}
+	scale := 1 / math.Sqrt(norm)
+	for i, x := range v {
+		unit[i] = float32(float64(x) * scale)
+	}
+	return unit
+}
+
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,52 @@
+package embeddings
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 1},
+		{"scaled", []float32{1, 2}, []float32{2, 4}, 1},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"opposite", []float32{1, 0}, []float32{-1, 0}, -1},
+		{"zero vector", []float32{0, 0}, []float32{1, 0}, 0},
+		{"length mismatch", []float32{1}, []float32{1, 0}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDiverse(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0},      // kept
+		{0.99, 0.1}, // near-duplicate of 0
+		{0, 1},      // kept
+		{0.7, 0.7},  // ~0.71 to both, kept at 0.9
+		{0, 2},      // same direction as 2
+	}
+
+	got := Diverse(vectors, 0.9)
+	want := []bool{true, false, true, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Vector %d: expected keep=%v, got %v", i, want[i], got[i])
+		}
+	}
+
+	// A threshold of 1 only drops exact duplicates in direction
+	if kept := Diverse(vectors, 1.0); !kept[1] || kept[4] {
+		t.Errorf("Expected threshold 1.0 to keep near-duplicates and drop exact ones, got %v", kept)
+	}
+}
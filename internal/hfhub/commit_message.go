@@ -0,0 +1,157 @@
+package hfhub
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lamim/vellumforge2/internal/util"
+)
+
+const (
+	// DefaultCommitMessage is the commit summary template used when none is configured
+	DefaultCommitMessage = "Upload dataset from VellumForge2 session {{.Session}}"
+	// DefaultAppendCommitMessage is the default commit summary template in append mode
+	DefaultAppendCommitMessage = "Append dataset rows from VellumForge2 session {{.Session}}"
+	// configHashLength is how many hex digits of the config hash {{.ConfigHash}} shows
+	configHashLength = 12
+)
+
+// CommitInfo describes an upload for the commit message templates and the metadata
+// block appended to the commit description
+type CommitInfo struct {
+	Session     string // Session directory name
+	DatasetMode string // generation.dataset_mode of the session
+	Rows        int    // Rows in the session's dataset file (the appended rows in append mode)
+	ConfigHash  string // SHA-256 of the session config uploaded as vf2.toml (empty if missing)
+	Branch      string
+	Append      bool
+}
+
+// templateData returns the values available to commit_message and commit_description
+func (i CommitInfo) templateData() map[string]interface{} {
+	shortHash := i.ConfigHash
+	if len(shortHash) > configHashLength {
+		shortHash = shortHash[:configHashLength]
+	}
+	return map[string]interface{}{
+		"Session":     i.Session,
+		"DatasetMode": i.DatasetMode,
+		"Rows":        i.Rows,
+		"ConfigHash":  shortHash,
+		"Branch":      i.Branch,
+		"Append":      i.Append,
+	}
+}
+
+// metadata returns the structured block appended to every commit description
+func (i CommitInfo) metadata() string {
+	var b strings.Builder
+	b.WriteString("VellumForge2 upload metadata:\n")
+	fmt.Fprintf(&b, "- session: %s\n", i.Session)
+	if i.DatasetMode != "" {
+		fmt.Fprintf(&b, "- dataset_mode: %s\n", i.DatasetMode)
+	}
+	fmt.Fprintf(&b, "- rows: %d\n", i.Rows)
+	if i.ConfigHash != "" {
+		fmt.Fprintf(&b, "- config_sha256: %s\n", i.ConfigHash)
+	}
+	fmt.Fprintf(&b, "- branch: %s\n", i.Branch)
+	fmt.Fprintf(&b, "- append: %t", i.Append)
+	return b.String()
+}
+
+// commitText renders the commit summary and description for an upload
+// An empty message template selects the default summary; the description is the rendered
+// description template (if any) followed by the metadata block
+func commitText(messageTmpl, descriptionTmpl string, info CommitInfo) (summary, description string, err error) {
+	if messageTmpl == "" {
+		messageTmpl = DefaultCommitMessage
+		if info.Append {
+			messageTmpl = DefaultAppendCommitMessage
+		}
+	}
+	data := info.templateData()
+
+	summary, err = util.RenderTemplate(messageTmpl, data)
+	if err != nil {
+		return "", "", fmt.Errorf("huggingface.commit_message: %w", err)
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", "", fmt.Errorf("huggingface.commit_message renders to an empty summary")
+	}
+	if strings.ContainsAny(summary, "\r\n") {
+		return "", "", fmt.Errorf("huggingface.commit_message must render to a single line (use commit_description for details)")
+	}
+
+	description = info.metadata()
+	if descriptionTmpl != "" {
+		text, err := util.RenderTemplate(descriptionTmpl, data)
+		if err != nil {
+			return "", "", fmt.Errorf("huggingface.commit_description: %w", err)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			description = text + "\n\n" + description
+		}
+	}
+	return summary, description, nil
+}
+
+// CheckCommitTemplates renders the commit templates with sample values, so a broken template
+// is reported before a long generation run instead of when the upload starts
+func CheckCommitTemplates(messageTmpl, descriptionTmpl string) error {
+	sample := CommitInfo{Session: "session_sample", DatasetMode: "dpo", Rows: 1, ConfigHash: strings.Repeat("0", 64), Branch: DefaultBranch}
+	_, _, err := commitText(messageTmpl, descriptionTmpl, sample)
+	return err
+}
+
+// countRows returns the number of non-empty lines in a JSONL file (0 if it doesn't exist)
+func countRows(path string) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = file.Close() }()
+
+	rows := 0
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			rows++
+		}
+		if readErr == io.EOF {
+			return rows, nil
+		}
+		if readErr != nil {
+			return 0, readErr
+		}
+	}
+}
+
+// fileSHA256 returns the hex SHA-256 of a file ("" if it doesn't exist)
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,180 @@
+package hfhub
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// datasetFile is the regular (no reasoning) dataset, exposed as the "default" HF config
+	datasetFile = "dataset.jsonl"
+	// reasoningDatasetFile is the reasoning dataset, exposed as the "reasoning" HF config
+	reasoningDatasetFile = "dataset_reasoning.jsonl"
+	// datasetCardFile is the dataset card whose YAML front matter declares the configs
+	datasetCardFile = "README.md"
+	// reasoningConfigName is the HF config name of the reasoning dataset
+	reasoningConfigName = "reasoning"
+)
+
+// datasetConfigsYAML declares the regular and reasoning data files (dataset.jsonl and
+// dataset_reasoning.jsonl, or their shard patterns) as separate HF configs,
+// so the viewer and load_dataset don't merge rows with and without reasoning into one split
+func datasetConfigsYAML(defaultPath, reasoningPath string) string {
+	return `configs:
+- config_name: default
+  data_files:
+  - split: train
+    path: ` + defaultPath + `
+- config_name: ` + reasoningConfigName + `
+  data_files:
+  - split: train
+    path: ` + reasoningPath + `
+`
+}
+
+// CardMetadata is the licensing information written to a new dataset card ([dataset] config)
+type CardMetadata struct {
+	License     string // Lowercase SPDX identifier for the license: front matter tag ("" = none)
+	Source      string
+	Attribution string
+}
+
+// empty reports whether there is nothing to put on the card
+func (m CardMetadata) empty() bool {
+	return m == CardMetadata{}
+}
+
+// licenseYAML is the front matter line carrying the license tag
+func licenseYAML(license string) string {
+	return "license: " + license + "\n"
+}
+
+// renderDatasetCard builds a minimal dataset card with the license and configs front matter
+// The configs are only declared when splitConfigs is set (a reasoning dataset was uploaded)
+func renderDatasetCard(repoID, defaultPath, reasoningPath string, splitConfigs bool, meta CardMetadata) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	if meta.License != "" {
+		b.WriteString(licenseYAML(meta.License))
+	}
+	if splitConfigs {
+		b.WriteString(datasetConfigsYAML(defaultPath, reasoningPath))
+	}
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %s\n\n", repoID)
+	b.WriteString("Generated with VellumForge2.\n\n")
+	if splitConfigs {
+		b.WriteString("## Configs\n\n")
+		fmt.Fprintf(&b, "- `default` (`%s`): responses without reasoning\n", defaultPath)
+		fmt.Fprintf(&b, "- `%s` (`%s`): responses with reasoning wrapped in `<think>` tags\n\n", reasoningConfigName, reasoningPath)
+	}
+	b.WriteString("```python\n")
+	b.WriteString("from datasets import load_dataset\n\n")
+	fmt.Fprintf(&b, "ds = load_dataset(%q)\n", repoID)
+	if splitConfigs {
+		fmt.Fprintf(&b, "reasoning = load_dataset(%q, %q)\n", repoID, reasoningConfigName)
+	}
+	b.WriteString("```\n")
+	if meta.License != "" || meta.Source != "" || meta.Attribution != "" {
+		b.WriteString("\n## License and Attribution\n\n")
+		if meta.License != "" {
+			fmt.Fprintf(&b, "- License: `%s`\n", meta.License)
+		}
+		if meta.Source != "" {
+			fmt.Fprintf(&b, "- Source: %s\n", meta.Source)
+		}
+		if meta.Attribution != "" {
+			fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(meta.Attribution))
+		}
+	}
+	return b.String()
+}
+
+// createDatasetCardOperation returns a README.md operation with the license front matter and,
+// with splitConfigs, the regular and reasoning datasets as separate configs, or nil when the
+// remote card shouldn't be touched
+// An existing card is never overwritten; if it lacks the license tag, the reasoning config or
+// the uploaded data file paths (e.g. after switching to shards), the YAML to add is logged
+func (u *Uploader) createDatasetCardOperation(repoID, branch, defaultPath, reasoningPath string, splitConfigs bool, meta CardMetadata) (*CommitOperation, error) {
+	existing, found, err := u.fetchRemoteFile(repoID, branch, datasetCardFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check remote %s: %w", datasetCardFile, err)
+	}
+
+	if found {
+		if meta.License != "" && !strings.Contains(existing, strings.TrimSpace(licenseYAML(meta.License))) {
+			u.logger.Warn("Remote dataset card has no matching license tag; leaving it unchanged. Add this to its YAML front matter",
+				"file", datasetCardFile,
+				"yaml", licenseYAML(meta.License))
+		}
+		if !splitConfigs {
+			return nil, nil
+		}
+		if !strings.Contains(existing, "config_name: "+reasoningConfigName) {
+			u.logger.Warn("Remote dataset card has no reasoning config; leaving it unchanged. Add this to its YAML front matter to split the configs",
+				"file", datasetCardFile,
+				"yaml", datasetConfigsYAML(defaultPath, reasoningPath))
+			return nil, nil
+		}
+		if !strings.Contains(existing, "path: "+defaultPath) || !strings.Contains(existing, "path: "+reasoningPath) {
+			u.logger.Warn("Remote dataset card points at other data files; leaving it unchanged. Update its YAML front matter to load the uploaded files",
+				"file", datasetCardFile,
+				"yaml", datasetConfigsYAML(defaultPath, reasoningPath))
+			return nil, nil
+		}
+		u.logger.Debug("Dataset card already declares the reasoning config", "file", datasetCardFile)
+		return nil, nil
+	}
+
+	if splitConfigs {
+		u.logger.Info("Created dataset card with separate configs",
+			"file", datasetCardFile,
+			"configs", "default, "+reasoningConfigName,
+			"license", meta.License)
+	} else {
+		u.logger.Info("Created dataset card", "file", datasetCardFile, "license", meta.License)
+	}
+
+	return &CommitOperation{
+		Operation: "add",
+		Path:      datasetCardFile,
+		Content:   base64.StdEncoding.EncodeToString([]byte(renderDatasetCard(repoID, defaultPath, reasoningPath, splitConfigs, meta))),
+		Encoding:  "base64",
+	}, nil
+}
+
+// fetchRemoteFile downloads a small file from the dataset repo; found is false on 404
+func (u *Uploader) fetchRemoteFile(repoID, branch, pathInRepo string) (content string, found bool, err error) {
+	resolveURL := fmt.Sprintf("https://huggingface.co/datasets/%s/resolve/%s/%s", repoID, url.PathEscape(branch), pathInRepo)
+	req, err := http.NewRequest("GET", resolveURL, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to download remote file: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			u.logger.Warn("Failed to close response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read remote file: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return string(bodyBytes), true, nil
+}
@@ -25,6 +25,12 @@ type LFSUploadInfo struct {
 	Size      int64             `json:"size"`
 	UploadURL string            `json:"-"` // Populated from actions.upload.href
 	Header    map[string]string `json:"-"` // Populated from actions.upload.header
+
+	VerifyURL    string            `json:"-"` // Populated from actions.verify.href (empty if the server sent no verify action)
+	VerifyHeader map[string]string `json:"-"` // Populated from actions.verify.header
+
+	PartStatePath string          `json:"-"` // Optional sidecar file recording completed multipart parts
+	partState     *multipartState // Completed parts from earlier attempts of this upload
 }
 
 // LFSBatchObject represents an object in the LFS batch request/response
@@ -136,6 +142,10 @@ func (u *Uploader) PreuploadLFS(repoID, branch string, files []LFSPointer) (map[
 			info.UploadURL = obj.Actions.Upload.Href
 			info.Header = obj.Actions.Upload.Header
 		}
+		if obj.Actions != nil && obj.Actions.Verify != nil {
+			info.VerifyURL = obj.Actions.Verify.Href
+			info.VerifyHeader = obj.Actions.Verify.Header
+		}
 
 		uploadMap[obj.OID] = info
 	}
@@ -153,14 +163,94 @@ func (u *Uploader) UploadLFSFile(uploadInfo *LFSUploadInfo, filePath string) err
 	}
 
 	// Check if this is multipart upload by looking for chunk_size in header
-	chunkSizeStr, hasChunkSize := uploadInfo.Header["chunk_size"]
-	if hasChunkSize {
+	var err error
+	if chunkSizeStr, hasChunkSize := uploadInfo.Header["chunk_size"]; hasChunkSize {
 		// Multipart upload
-		return u.uploadLFSFileMultipart(uploadInfo, filePath, chunkSizeStr)
+		err = u.uploadLFSFileMultipart(uploadInfo, filePath, chunkSizeStr)
+	} else {
+		// Basic single-part upload
+		err = u.uploadLFSFileBasic(uploadInfo, filePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Confirm the server stored the object with the expected OID/size before the commit references it
+	return u.verifyLFSFileWithRetry(uploadInfo, u.maxRetries)
+}
+
+// verifyLFSFile calls the LFS verify action for an uploaded object
+func (u *Uploader) verifyLFSFile(uploadInfo *LFSUploadInfo) error {
+	payload, err := json.Marshal(LFSBatchObject{OID: uploadInfo.OID, Size: uploadInfo.Size})
+	if err != nil {
+		return fmt.Errorf("failed to marshal verify payload: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", uploadInfo.VerifyURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create verify request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+u.token)
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	for key, value := range uploadInfo.VerifyHeader {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := u.preuploadClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send verify request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("LFS verify failed with status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Basic single-part upload
-	return u.uploadLFSFileBasic(uploadInfo, filePath)
+	u.logger.Debug("LFS file verified", "oid", uploadInfo.OID, "size", uploadInfo.Size)
+	return nil
+}
+
+// verifyLFSFileWithRetry verifies an uploaded object with retry logic
+// Objects without a verify action are accepted as-is
+func (u *Uploader) verifyLFSFileWithRetry(uploadInfo *LFSUploadInfo, maxRetries int) error {
+	if uploadInfo.VerifyURL == "" {
+		u.logger.Debug("No LFS verify action for object, skipping verification", "oid", uploadInfo.OID)
+		return nil
+	}
+
+	var lastErr error
+	backoff := 2 * time.Second
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			u.logger.Warn("Retrying LFS verify",
+				"oid", uploadInfo.OID,
+				"attempt", attempt,
+				"max_retries", maxRetries,
+				"backoff", backoff)
+			time.Sleep(backoff)
+			backoff *= 2 // Exponential backoff
+		}
+
+		err := u.verifyLFSFile(uploadInfo)
+		if err == nil {
+			if attempt > 0 {
+				u.logger.Info("LFS verify succeeded after retry", "oid", uploadInfo.OID, "attempt", attempt)
+			}
+			return nil
+		}
+
+		lastErr = err
+		u.logger.Warn("LFS verify failed",
+			"oid", uploadInfo.OID,
+			"attempt", attempt,
+			"error", err)
+	}
+
+	return fmt.Errorf("verify failed after %d attempts: %w", maxRetries+1, lastErr)
 }
 
 // uploadLFSFileBasic uploads a file using basic LFS protocol (single PUT)
@@ -238,14 +328,29 @@ func (u *Uploader) uploadLFSFileMultipart(uploadInfo *LFSUploadInfo, filePath st
 		"chunk_size", chunkSize,
 		"parts", len(partURLs))
 
-	// Upload each part and collect ETags
-	type partResult struct {
-		partNumber int
-		etag       string
+	// Parts uploaded by a previous attempt of this same upload are skipped
+	state := u.loadMultipartState(uploadInfo, chunkSize)
+	if len(state.Parts) > 0 {
+		u.logger.Info("Resuming multipart LFS upload",
+			"oid", uploadInfo.OID,
+			"completed_parts", len(state.Parts),
+			"parts", len(partURLs))
+	}
+
+	// Upload parts in ascending order, recording each ETag as it succeeds
+	partNums := make([]int, 0, len(partURLs))
+	for partNum := range partURLs {
+		partNums = append(partNums, partNum)
 	}
-	results := make([]partResult, 0, len(partURLs))
+	sort.Ints(partNums)
+
+	for _, partNum := range partNums {
+		partURL := partURLs[partNum]
+		if etag, done := state.Parts[partNum]; done && etag != "" {
+			u.logger.Debug("Skipping already uploaded part", "part", partNum, "etag", etag)
+			continue
+		}
 
-	for partNum, partURL := range partURLs {
 		// Calculate offset and length for this part
 		offset := int64(partNum-1) * chunkSize
 		length := chunkSize
@@ -287,24 +392,21 @@ func (u *Uploader) uploadLFSFileMultipart(uploadInfo *LFSUploadInfo, filePath st
 			return fmt.Errorf("no ETag returned for part %d", partNum)
 		}
 
-		results = append(results, partResult{partNumber: partNum, etag: etag})
+		state.Parts[partNum] = etag
+		u.saveMultipartState(uploadInfo, state)
 		u.logger.Debug("Uploaded part", "part", partNum, "etag", etag)
 	}
 
-	// Sort results by part number (S3 requires ascending order)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].partNumber < results[j].partNumber
-	})
-
-	// Send completion request
+	// Send completion request with every part in ascending order (S3 requirement),
+	// including parts uploaded by earlier attempts
 	completionPayload := map[string]interface{}{
 		"oid": uploadInfo.OID,
 		"parts": func() []map[string]interface{} {
-			parts := make([]map[string]interface{}, len(results))
-			for i, r := range results {
+			parts := make([]map[string]interface{}, len(partNums))
+			for i, partNum := range partNums {
 				parts[i] = map[string]interface{}{
-					"partNumber": r.partNumber,
-					"etag":       r.etag,
+					"partNumber": partNum,
+					"etag":       state.Parts[partNum],
 				}
 			}
 			return parts
@@ -332,9 +434,12 @@ func (u *Uploader) uploadLFSFileMultipart(uploadInfo *LFSUploadInfo, filePath st
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
+		// The recorded ETags may be what the server rejected, so the next attempt starts over
+		u.clearMultipartState(uploadInfo)
 		return fmt.Errorf("completion request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
+	u.clearMultipartState(uploadInfo)
 	u.logger.Info("LFS file uploaded (multipart)", "oid", uploadInfo.OID, "size", fileInfo.Size(), "parts", len(partURLs))
 	return nil
 }
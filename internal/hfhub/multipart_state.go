@@ -0,0 +1,94 @@
+package hfhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// multipartState records the parts of a multipart LFS upload that already succeeded so a
+// retry, or a later run, can skip them. The presigned URLs change on every preupload call, so
+// the state is keyed by what fixes the part layout: object OID, size, and chunk size
+type multipartState struct {
+	OID       string         `json:"oid"`
+	Size      int64          `json:"size"`
+	ChunkSize int64          `json:"chunk_size"`
+	Parts     map[int]string `json:"parts"` // Part number -> ETag
+}
+
+// multipartStatePath returns the sidecar path for an object's multipart state in dir
+func multipartStatePath(dir, oid string) string {
+	return filepath.Join(dir, fmt.Sprintf(".lfs_upload_%s.json", oid))
+}
+
+// loadMultipartState returns the recorded parts for this upload, or a fresh state when
+// nothing was recorded or the recorded state belongs to a different upload
+func (u *Uploader) loadMultipartState(uploadInfo *LFSUploadInfo, chunkSize int64) *multipartState {
+	fresh := &multipartState{
+		OID:       uploadInfo.OID,
+		Size:      uploadInfo.Size,
+		ChunkSize: chunkSize,
+		Parts:     make(map[int]string),
+	}
+	matches := func(state *multipartState) bool {
+		return state != nil && state.OID == fresh.OID && state.Size == fresh.Size && state.ChunkSize == fresh.ChunkSize
+	}
+
+	// In-process retries reuse the state from the previous attempt
+	if matches(uploadInfo.partState) {
+		return uploadInfo.partState
+	}
+	if uploadInfo.PartStatePath == "" {
+		return fresh
+	}
+
+	data, err := os.ReadFile(uploadInfo.PartStatePath)
+	if err != nil {
+		return fresh
+	}
+	var state multipartState
+	if err := json.Unmarshal(data, &state); err != nil {
+		u.logger.Warn("Ignoring unreadable multipart upload state", "path", uploadInfo.PartStatePath, "error", err)
+		return fresh
+	}
+	if !matches(&state) || state.Parts == nil {
+		u.logger.Debug("Multipart upload state belongs to a different upload, starting fresh", "oid", uploadInfo.OID)
+		return fresh
+	}
+	return &state
+}
+
+// saveMultipartState records completed parts in memory and, when a state path is set, on disk
+// Disk failures are logged and never fail the upload
+func (u *Uploader) saveMultipartState(uploadInfo *LFSUploadInfo, state *multipartState) {
+	uploadInfo.partState = state
+	if uploadInfo.PartStatePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		u.logger.Warn("Failed to encode multipart upload state", "error", err)
+		return
+	}
+	tmpPath := uploadInfo.PartStatePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		u.logger.Warn("Failed to write multipart upload state", "path", tmpPath, "error", err)
+		return
+	}
+	if err := os.Rename(tmpPath, uploadInfo.PartStatePath); err != nil {
+		u.logger.Warn("Failed to save multipart upload state", "path", uploadInfo.PartStatePath, "error", err)
+	}
+}
+
+// clearMultipartState drops recorded parts once the upload completed (or was rejected)
+func (u *Uploader) clearMultipartState(uploadInfo *LFSUploadInfo) {
+	uploadInfo.partState = nil
+	if uploadInfo.PartStatePath == "" {
+		return
+	}
+	if err := os.Remove(uploadInfo.PartStatePath); err != nil && !os.IsNotExist(err) {
+		u.logger.Warn("Failed to remove multipart upload state", "path", uploadInfo.PartStatePath, "error", err)
+	}
+}
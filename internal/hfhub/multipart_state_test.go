@@ -0,0 +1,114 @@
+package hfhub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUploadLFSFileMultipart_ResumesWithNewURLs(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "dataset.jsonl")
+	if err := os.WriteFile(filePath, []byte("0123456789"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var mu sync.Mutex
+	puts := map[string]int{}
+	failPart := "/run1/part/2"
+	var completed []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			_, _ = io.Copy(io.Discard, r.Body)
+			puts[r.URL.Path]++
+			if r.URL.Path == failPart {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			part := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			w.Header().Set("ETag", `"etag-`+part+`"`)
+			return
+		}
+		var payload struct {
+			Parts []map[string]interface{} `json:"parts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode completion request: %v", err)
+		}
+		completed = payload.Parts
+	}))
+	defer server.Close()
+
+	uploadInfo := func(run string) *LFSUploadInfo {
+		header := map[string]string{"chunk_size": "4"}
+		for part := 1; part <= 3; part++ {
+			header[fmt.Sprint(part)] = fmt.Sprintf("%s/%s/part/%d?X-Amz-Signature=%s", server.URL, run, part, run)
+		}
+		return &LFSUploadInfo{
+			OID:           "abc123",
+			Size:          10,
+			UploadURL:     server.URL + "/" + run + "/complete?sig=" + run,
+			Header:        header,
+			PartStatePath: multipartStatePath(dir, "abc123"),
+		}
+	}
+
+	u := NewUploader("token", slog.New(slog.NewTextHandler(io.Discard, nil)), UploaderOptions{})
+	if err := u.uploadLFSFileMultipart(uploadInfo("run1"), filePath, "4"); err == nil {
+		t.Fatal("Expected the first run to fail on part 2")
+	}
+
+	// A later run gets fresh presigned URLs from a new preupload call
+	if err := u.uploadLFSFileMultipart(uploadInfo("run2"), filePath, "4"); err != nil {
+		t.Fatalf("Resumed upload returned error: %v", err)
+	}
+	if puts["/run2/part/1"] != 0 {
+		t.Errorf("Expected part 1 to be skipped on resume, got %d uploads", puts["/run2/part/1"])
+	}
+	if puts["/run2/part/2"] != 1 || puts["/run2/part/3"] != 1 {
+		t.Errorf("Expected parts 2 and 3 uploaded once on resume, got %v", puts)
+	}
+	if len(completed) != 3 || completed[0]["etag"] != `"etag-1"` {
+		t.Errorf("Expected completion with all 3 parts and part 1's earlier ETag, got %v", completed)
+	}
+	if _, err := os.Stat(multipartStatePath(dir, "abc123")); !os.IsNotExist(err) {
+		t.Errorf("Expected the state file to be removed after completion, got %v", err)
+	}
+}
+
+func TestLoadMultipartState_LayoutMismatch(t *testing.T) {
+	dir := t.TempDir()
+	u := NewUploader("token", slog.New(slog.NewTextHandler(io.Discard, nil)), UploaderOptions{})
+	saved := &LFSUploadInfo{OID: "abc123", Size: 10, PartStatePath: multipartStatePath(dir, "abc123")}
+	u.saveMultipartState(saved, &multipartState{OID: "abc123", Size: 10, ChunkSize: 4, Parts: map[int]string{1: "etag-1"}})
+
+	tests := []struct {
+		name      string
+		size      int64
+		chunkSize int64
+		wantParts int
+	}{
+		{"same layout", 10, 4, 1},
+		{"different size", 12, 4, 0},
+		{"different chunk size", 10, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &LFSUploadInfo{OID: "abc123", Size: tt.size, UploadURL: "https://example.com/new", PartStatePath: saved.PartStatePath}
+			if got := len(u.loadMultipartState(info, tt.chunkSize).Parts); got != tt.wantParts {
+				t.Errorf("Expected %d recorded parts, got %d", tt.wantParts, got)
+			}
+		})
+	}
+}
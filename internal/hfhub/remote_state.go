@@ -0,0 +1,194 @@
+package hfhub
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// remotePathInfo is one entry of the Hub's paths-info response
+type remotePathInfo struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	OID  string `json:"oid"` // Git blob SHA-1
+	Size int64  `json:"size"`
+	LFS  *struct {
+		OID  string `json:"oid"` // SHA-256 of the content
+		Size int64  `json:"size"`
+	} `json:"lfs,omitempty"`
+}
+
+// fetchPathsInfo returns the remote files among paths on branch, keyed by path
+// A missing repo or branch yields an empty map: nothing has been uploaded there yet
+func (u *Uploader) fetchPathsInfo(repoID, branch string, paths []string) (map[string]remotePathInfo, error) {
+	infoURL := fmt.Sprintf("https://huggingface.co/api/datasets/%s/paths-info/%s", repoID, url.PathEscape(branch))
+	form := url.Values{"paths": paths}
+	req, err := http.NewRequest("POST", infoURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote file info: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			u.logger.Warn("Failed to close response body", "error", err)
+		}
+	}()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote file info: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]remotePathInfo{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("paths-info failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var entries []remotePathInfo
+	if err := json.Unmarshal(bodyBytes, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse remote file info: %w", err)
+	}
+	infos := make(map[string]remotePathInfo, len(entries))
+	for _, entry := range entries {
+		if entry.Type == "file" {
+			infos[entry.Path] = entry
+		}
+	}
+	return infos, nil
+}
+
+// listRemoteFiles returns the paths of the files at the root of branch, following the tree
+// API's pagination. A missing repo or branch yields none
+func (u *Uploader) listRemoteFiles(repoID, branch string) ([]string, error) {
+	next := fmt.Sprintf("https://huggingface.co/api/datasets/%s/tree/%s", repoID, url.PathEscape(branch))
+	var files []string
+	for next != "" {
+		req, err := http.NewRequest("GET", next, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+u.token)
+
+		resp, err := u.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list remote files: %w", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read remote file list: %w", err)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return files, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("tree listing failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		}
+
+		var entries []remotePathInfo
+		if err := json.Unmarshal(bodyBytes, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse remote file list: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.Type == "file" {
+				files = append(files, entry.Path)
+			}
+		}
+		next = nextPageURL(resp.Header.Get("Link"))
+	}
+	return files, nil
+}
+
+// nextPageURL returns the rel="next" target of a Link header, or "" on the last page
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, found := strings.Cut(part, ";")
+		if found && strings.Contains(params, `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+	return ""
+}
+
+// localFileHashes returns the SHA-256 (the LFS OID) and git blob SHA-1 of a file
+func localFileHashes(path string) (sha256Hex, blobSHA1Hex string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", "", err
+	}
+	contentHash := sha256.New()
+	blobHash := sha1.New()
+	fmt.Fprintf(blobHash, "blob %d\x00", info.Size())
+	if _, err := io.Copy(io.MultiWriter(contentHash, blobHash), file); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(contentHash.Sum(nil)), hex.EncodeToString(blobHash.Sum(nil)), nil
+}
+
+// matchesRemote reports whether the local file has the same content as the remote one
+func matchesRemote(localPath string, remote remotePathInfo) (bool, error) {
+	sha256Hex, blobSHA1Hex, err := localFileHashes(localPath)
+	if err != nil {
+		return false, err
+	}
+	if remote.LFS != nil {
+		return remote.LFS.OID == sha256Hex, nil
+	}
+	return remote.OID == blobSHA1Hex, nil
+}
+
+// alreadyUploaded reports whether every local file in files is on branch with identical content,
+// in which case uploading the session again would only produce an empty commit
+func (u *Uploader) alreadyUploaded(repoID, branch string, files []uploadFile) (bool, error) {
+	var local []uploadFile
+	paths := []string{}
+	for _, file := range files {
+		if _, err := os.Stat(file.localPath); err == nil {
+			local = append(local, file)
+			paths = append(paths, file.pathInRepo)
+		}
+	}
+	if len(local) == 0 {
+		return false, nil
+	}
+
+	remote, err := u.fetchPathsInfo(repoID, branch, paths)
+	if err != nil {
+		return false, err
+	}
+	for _, file := range local {
+		info, found := remote[file.pathInRepo]
+		if !found {
+			return false, nil
+		}
+		same, err := matchesRemote(file.localPath, info)
+		if err != nil {
+			return false, fmt.Errorf("failed to hash %s: %w", file.localPath, err)
+		}
+		if !same {
+			u.logger.Debug("Remote file differs from the session", "file", file.pathInRepo)
+			return false, nil
+		}
+	}
+	return true, nil
+}
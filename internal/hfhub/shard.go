@@ -0,0 +1,134 @@
+package hfhub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultShardSize is the shard size used by --hf-shard; it stays below LFSThreshold so
+// every shard is committed as plain text the dataset viewer can render
+const DefaultShardSize = 9 * 1024 * 1024
+
+// shardName returns the repo path of the n-th (1-based) shard of a JSONL file
+func shardName(pathInRepo string, n int) string {
+	return fmt.Sprintf("%s-%05d.jsonl", strings.TrimSuffix(pathInRepo, ".jsonl"), n)
+}
+
+// shardGlob returns the data_files pattern matching every shard of a JSONL file
+func shardGlob(pathInRepo string) string {
+	return strings.TrimSuffix(pathInRepo, ".jsonl") + "-*.jsonl"
+}
+
+// isShardOf reports whether path is a shard of pathInRepo as named by shardName
+func isShardOf(path, pathInRepo string) bool {
+	number, ok := strings.CutPrefix(path, strings.TrimSuffix(pathInRepo, ".jsonl")+"-")
+	if !ok {
+		return false
+	}
+	number, ok = strings.CutSuffix(number, ".jsonl")
+	if !ok || len(number) != 5 {
+		return false
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// staleShards returns the remote shards of pathInRepo that this upload doesn't replace, e.g.
+// dataset-00003.jsonl when the dataset now fits in two shards or is no longer sharded.
+// Left in place they would still match the shard glob and duplicate rows
+func staleShards(remoteFiles []string, pathInRepo string, uploading map[string]bool) []string {
+	var stale []string
+	for _, path := range remoteFiles {
+		if isShardOf(path, pathInRepo) && !uploading[path] {
+			stale = append(stale, path)
+		}
+	}
+	return stale
+}
+
+// staleShardOperations returns delete operations for the stale remote shards of datasets
+func (u *Uploader) staleShardOperations(repoID, branch string, datasets []string, uploading map[string]bool) ([]CommitOperation, error) {
+	remoteFiles, err := u.listRemoteFiles(repoID, branch)
+	if err != nil {
+		return nil, err
+	}
+	var operations []CommitOperation
+	for _, dataset := range datasets {
+		for _, path := range staleShards(remoteFiles, dataset, uploading) {
+			u.logger.Info("Deleting remote shard that is no longer part of the dataset", "file", path)
+			operations = append(operations, CommitOperation{Operation: "delete", Path: path})
+		}
+	}
+	return operations, nil
+}
+
+// splitIntoShards splits localPath at line boundaries into files of at most shardSize bytes
+// in dir, returning them in order. A single line longer than shardSize gets a shard of its own
+func splitIntoShards(localPath, pathInRepo, dir string, shardSize int64) ([]uploadFile, error) {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = src.Close() }()
+
+	var shards []uploadFile
+	var current *os.File
+	var written int64
+	closeCurrent := func() error {
+		if current == nil {
+			return nil
+		}
+		err := current.Close()
+		current = nil
+		return err
+	}
+
+	reader := bufio.NewReader(src)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			_ = closeCurrent()
+			return nil, readErr
+		}
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			if current != nil && written+int64(len(line)) > shardSize {
+				if err := closeCurrent(); err != nil {
+					return nil, err
+				}
+			}
+			if current == nil {
+				name := shardName(pathInRepo, len(shards)+1)
+				current, err = os.Create(filepath.Join(dir, filepath.Base(name)))
+				if err != nil {
+					return nil, err
+				}
+				shards = append(shards, uploadFile{localPath: current.Name(), pathInRepo: name})
+				written = 0
+			}
+			if _, err := current.Write(line); err != nil {
+				_ = closeCurrent()
+				return nil, err
+			}
+			written += int64(len(line))
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := closeCurrent(); err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
@@ -0,0 +1,153 @@
+package hfhub
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitIntoShards(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		shardSize int64
+		want      []string
+	}{
+		{"splits at line boundaries", "aaa\nbbb\nccc\n", 8, []string{"aaa\nbbb\n", "ccc\n"}},
+		{"fits in one shard", "aaa\nbbb\n", 100, []string{"aaa\nbbb\n"}},
+		{"long line gets its own shard", "a\nbbbbbbbbbbbb\nc\n", 4, []string{"a\n", "bbbbbbbbbbbb\n", "c\n"}},
+		{"missing trailing newline", "aaa\nbbb", 4, []string{"aaa\n", "bbb\n"}},
+		{"empty file", "", 4, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			localPath := filepath.Join(dir, "dataset.jsonl")
+			if err := os.WriteFile(localPath, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			shardDir := filepath.Join(dir, "shards")
+			if err := os.Mkdir(shardDir, 0o755); err != nil {
+				t.Fatalf("Failed to create shard dir: %v", err)
+			}
+
+			shards, err := splitIntoShards(localPath, "dataset.jsonl", shardDir, tt.shardSize)
+			if err != nil {
+				t.Fatalf("splitIntoShards returned unexpected error: %v", err)
+			}
+			if len(shards) != len(tt.want) {
+				t.Fatalf("Expected %d shards, got %d", len(tt.want), len(shards))
+			}
+			for i, shard := range shards {
+				if want := shardName("dataset.jsonl", i+1); shard.pathInRepo != want {
+					t.Errorf("Expected shard %d at %s, got %s", i+1, want, shard.pathInRepo)
+				}
+				data, err := os.ReadFile(shard.localPath)
+				if err != nil {
+					t.Fatalf("Failed to read shard: %v", err)
+				}
+				if string(data) != tt.want[i] {
+					t.Errorf("Expected shard %d to hold %q, got %q", i+1, tt.want[i], data)
+				}
+			}
+		})
+	}
+}
+
+func TestStaleShards(t *testing.T) {
+	remote := []string{
+		"dataset.jsonl",
+		"dataset-00001.jsonl",
+		"dataset-00002.jsonl",
+		"dataset-00003.jsonl",
+		"dataset-00004.jsonl",
+		"dataset-extra.jsonl",
+		"dataset_reasoning-00001.jsonl",
+		"README.md",
+	}
+
+	uploading := map[string]bool{"dataset-00001.jsonl": true, "dataset-00002.jsonl": true}
+	if got, want := staleShards(remote, "dataset.jsonl", uploading), []string{"dataset-00003.jsonl", "dataset-00004.jsonl"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	unsharded := map[string]bool{"dataset.jsonl": true}
+	if got := staleShards(remote, "dataset.jsonl", unsharded); len(got) != 4 {
+		t.Errorf("Expected all 4 shards to be stale after an unsharded upload, got %v", got)
+	}
+}
+
+// hubRedirect sends every request to server, keeping the path and query
+type hubRedirect struct {
+	server *httptest.Server
+}
+
+func (h hubRedirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, _ := url.Parse(h.server.URL)
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestStaleShardOperations_CommitsDeletes(t *testing.T) {
+	var commitBody string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/tree/main") && r.URL.Query().Get("cursor") == "":
+			w.Header().Set("Link", `<`+server.URL+r.URL.Path+`?cursor=2>; rel="next"`)
+			_, _ = io.WriteString(w, `[{"type": "file", "path": "dataset-00001.jsonl"}, {"type": "directory", "path": "data"}]`)
+		case strings.HasSuffix(r.URL.Path, "/tree/main"):
+			_, _ = io.WriteString(w, `[{"type": "file", "path": "dataset-00002.jsonl"}, {"type": "file", "path": "dataset-00003.jsonl"}]`)
+		case strings.HasSuffix(r.URL.Path, "/commit/main"):
+			data, _ := io.ReadAll(r.Body)
+			commitBody = string(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	u := NewUploader("token", slog.New(slog.NewTextHandler(io.Discard, nil)), UploaderOptions{})
+	u.httpClient = &http.Client{Transport: hubRedirect{server}}
+	u.commitClient = &http.Client{Transport: hubRedirect{server}}
+
+	uploading := map[string]bool{"dataset-00001.jsonl": true}
+	operations, err := u.staleShardOperations("user/repo", "main", []string{"dataset.jsonl"}, uploading)
+	if err != nil {
+		t.Fatalf("staleShardOperations returned unexpected error: %v", err)
+	}
+	if len(operations) != 2 || operations[0].Path != "dataset-00002.jsonl" || operations[1].Path != "dataset-00003.jsonl" {
+		t.Fatalf("Expected deletes for shards 2 and 3 across both pages, got %+v", operations)
+	}
+
+	if err := u.createCommit("user/repo", "main", operations, "msg", ""); err != nil {
+		t.Fatalf("createCommit returned unexpected error: %v", err)
+	}
+	want := `{"key":"deletedFile","value":{"path":"dataset-00003.jsonl"}}`
+	if !strings.Contains(commitBody, want) {
+		t.Errorf("Expected commit payload to contain %s, got:\n%s", want, commitBody)
+	}
+}
+
+func TestListRemoteFiles_MissingBranch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	u := NewUploader("token", slog.New(slog.NewTextHandler(io.Discard, nil)), UploaderOptions{})
+	u.httpClient = &http.Client{Transport: hubRedirect{server}}
+	files, err := u.listRemoteFiles("user/repo", "new-branch")
+	if err != nil || len(files) != 0 {
+		t.Errorf("Expected no files and no error for a missing branch, got %v, %v", files, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,10 +26,56 @@ const (
 	CommitTimeout = 300 * time.Second
 	// LogPreviewLength is the maximum length for log previews
 	LogPreviewLength = 500
-	// MaxRetries is the maximum number of retries for failed operations
+	// MaxRetries is the default number of retries for failed operations
 	MaxRetries = 3
+	// DefaultBranch is the branch used when no branch is specified
+	DefaultBranch = "main"
+	// DefaultRepoReadyTimeout is how long a newly created repo may take to become available
+	DefaultRepoReadyTimeout = 60 * time.Second
+	// DefaultRepoReadyPollInterval is how often a newly created repo is checked while waiting
+	DefaultRepoReadyPollInterval = time.Second
 )
 
+// appendableFiles are the dataset files that are concatenated with the remote copy in append mode
+var appendableFiles = map[string]bool{
+	datasetFile:          true,
+	reasoningDatasetFile: true,
+}
+
+// UploadOptions controls where and how a session is uploaded
+type UploadOptions struct {
+	Branch string // Target branch (default: main); created from main if it doesn't exist
+	Append bool   // Append rows to the existing remote dataset files instead of replacing them
+	// ReasoningDatasetPath is the local reasoning dataset (default: <session>/dataset_reasoning.jsonl)
+	// When present it is uploaded as its own "reasoning" config next to the default dataset
+	ReasoningDatasetPath string
+	// ShardSize splits dataset files above LFSThreshold into <name>-00001.jsonl, ... shards of at
+	// most this many bytes (0: upload them whole and only warn that the viewer may not render them)
+	ShardSize int64
+	// ReadyTimeout and ReadyPollInterval bound the wait for a newly created repo to become
+	// available before committing (0: DefaultRepoReadyTimeout and DefaultRepoReadyPollInterval)
+	ReadyTimeout      time.Duration
+	ReadyPollInterval time.Duration
+	// DatasetMode is recorded in the commit description metadata
+	DatasetMode string
+	// CommitMessage and CommitDescription are text/template strings for the commit summary
+	// and description, rendered with CommitInfo (empty: DefaultCommitMessage, no description
+	// text). The description always ends with the upload metadata block
+	CommitMessage     string
+	CommitDescription string
+	// Card is the license, source and attribution written to a newly created dataset card
+	Card CardMetadata
+	// Force uploads even when the remote files already match the session (otherwise such an
+	// upload is skipped; append and shard uploads are never skipped)
+	Force bool
+}
+
+// uploadFile maps a local file to its path in the dataset repo
+type uploadFile struct {
+	localPath  string
+	pathInRepo string
+}
+
 // Uploader handles uploading datasets to Hugging Face Hub
 type Uploader struct {
 	token           string
@@ -36,11 +83,35 @@ type Uploader struct {
 	preuploadClient *http.Client // For LFS preupload
 	lfsClient       *http.Client // For LFS file uploads
 	commitClient    *http.Client // For commit operations
+	maxRetries      int          // Retries of failed preupload, LFS upload, verify and commit requests
 	logger          *slog.Logger
 }
 
+// UploaderOptions overrides the uploader's timeouts and retries (zero values use the defaults)
+type UploaderOptions struct {
+	UploadTimeout time.Duration // Timeout of each LFS file upload (default: LFSUploadTimeout)
+	CommitTimeout time.Duration // Timeout of each commit request (default: CommitTimeout)
+	MaxRetries    int           // Retries of failed requests (default: MaxRetries, -1 = no retries)
+}
+
 // NewUploader creates a new Hugging Face Hub uploader
-func NewUploader(token string, logger *slog.Logger) *Uploader {
+func NewUploader(token string, logger *slog.Logger, opts UploaderOptions) *Uploader {
+	uploadTimeout := opts.UploadTimeout
+	if uploadTimeout <= 0 {
+		uploadTimeout = LFSUploadTimeout
+	}
+	commitTimeout := opts.CommitTimeout
+	if commitTimeout <= 0 {
+		commitTimeout = CommitTimeout
+	}
+	maxRetries := opts.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = MaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+
 	return &Uploader{
 		token: token,
 		httpClient: &http.Client{
@@ -50,12 +121,13 @@ func NewUploader(token string, logger *slog.Logger) *Uploader {
 			Timeout: PreuploadTimeout,
 		},
 		lfsClient: &http.Client{
-			Timeout: LFSUploadTimeout,
+			Timeout: uploadTimeout,
 		},
 		commitClient: &http.Client{
-			Timeout: CommitTimeout,
+			Timeout: commitTimeout,
 		},
-		logger: logger.With("component", "hf_uploader"),
+		maxRetries: maxRetries,
+		logger:     logger.With("component", "hf_uploader"),
 	}
 }
 
@@ -66,24 +138,85 @@ type RepoInfo struct {
 	Private bool   `json:"private"`
 }
 
-// Upload uploads a session directory to Hugging Face Hub using the commit API
+// Upload uploads a session directory to the main branch, replacing existing dataset files
 func (u *Uploader) Upload(repoID, sessionDir string) error {
-	u.logger.Info("Starting upload to Hugging Face Hub", "repo_id", repoID)
+	return u.UploadWithOptions(repoID, sessionDir, UploadOptions{})
+}
+
+// UploadWithOptions uploads a session directory to Hugging Face Hub using the commit API
+func (u *Uploader) UploadWithOptions(repoID, sessionDir string, opts UploadOptions) error {
+	branch := opts.Branch
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	u.logger.Info("Starting upload to Hugging Face Hub", "repo_id", repoID, "branch", branch, "append", opts.Append)
+
+	// Appending concatenates with a single remote file, which shards can't be merged into
+	if opts.Append && opts.ShardSize > 0 {
+		return fmt.Errorf("sharding cannot be combined with append mode")
+	}
+
+	// Render the commit text first so a template error doesn't leave a half-done upload
+	info, err := u.commitInfo(sessionDir, branch, opts)
+	if err != nil {
+		return err
+	}
+	commitMsg, commitDescription, err := commitText(opts.CommitMessage, opts.CommitDescription, info)
+	if err != nil {
+		return err
+	}
 
-	// Create repository if it doesn't exist
-	if err := u.createRepo(repoID); err != nil {
+	reasoningPath := opts.ReasoningDatasetPath
+	if reasoningPath == "" {
+		reasoningPath = filepath.Join(sessionDir, reasoningDatasetFile)
+	}
+
+	// Prepare files for upload (datasets + config as vf2.toml)
+	filesToUpload := []uploadFile{
+		{localPath: filepath.Join(sessionDir, datasetFile), pathInRepo: datasetFile},
+		{localPath: reasoningPath, pathInRepo: reasoningDatasetFile},
+		{localPath: filepath.Join(sessionDir, "config.toml.bak"), pathInRepo: "vf2.toml"}, // Rename for clarity on HF Hub
+	}
+
+	// Re-uploading an unchanged session would only recreate the same files
+	if !opts.Force && !opts.Append && opts.ShardSize <= 0 {
+		same, err := u.alreadyUploaded(repoID, branch, filesToUpload)
+		if err != nil {
+			u.logger.Warn("Could not compare the session with the remote files, uploading anyway", "error", err)
+		} else if same {
+			u.logger.Info("Session is already uploaded (remote files match the local SHA-256), skipping upload; use --hf-force to upload anyway",
+				"repo_id", repoID,
+				"branch", branch,
+				"url", fmt.Sprintf("https://huggingface.co/datasets/%s", repoID))
+			return nil
+		}
+	}
+
+	// Create repository if it doesn't exist (existing repos are reused, never deleted)
+	if err := u.createRepo(repoID, opts); err != nil {
 		return fmt.Errorf("failed to create repository: %w", err)
 	}
 
-	// Prepare files for upload (dataset + config as vf2.toml)
-	filesToUpload := map[string]string{
-		"dataset.jsonl":           "dataset.jsonl",
-		"dataset_reasoning.jsonl": "dataset_reasoning.jsonl",
-		"config.toml.bak":         "vf2.toml", // Rename for clarity on HF Hub
+	if branch != DefaultBranch {
+		if err := u.createBranch(repoID, branch); err != nil {
+			return fmt.Errorf("failed to create branch %s: %w", branch, err)
+		}
 	}
+	uploaded := make(map[string]bool, len(filesToUpload))
+	dataFiles := map[string]string{datasetFile: datasetFile, reasoningDatasetFile: reasoningDatasetFile} // data_files path per dataset
 	operations := []CommitOperation{}
 	lfsFiles := []LFSPointer{}
+	// Repo paths written by this commit (shards included), and the dataset files among them
+	// whose stale remote shards get deleted
+	uploading := map[string]bool{}
+	var datasets []string
 	filePaths := make(map[string]string) // oid -> filePath
+	shardDir := ""                       // temp dir holding shards, created on first use
+	defer func() {
+		if shardDir != "" {
+			_ = os.RemoveAll(shardDir)
+		}
+	}()
 
 	// Add .gitattributes to ensure proper text rendering
 	// This prevents HuggingFace from adding dataset.jsonl to LFS with -text flag
@@ -95,8 +228,9 @@ func (u *Uploader) Upload(repoID, sessionDir string) error {
 		u.logger.Debug("Added .gitattributes to operations")
 	}
 
-	for localFilename, hfFilename := range filesToUpload {
-		localPath := filepath.Join(sessionDir, localFilename)
+	for _, file := range filesToUpload {
+		localPath, hfFilename := file.localPath, file.pathInRepo
+		localFilename := filepath.Base(localPath)
 
 		// Check if file exists
 		if _, err := os.Stat(localPath); os.IsNotExist(err) {
@@ -104,33 +238,89 @@ func (u *Uploader) Upload(repoID, sessionDir string) error {
 			continue
 		}
 
-		// Prepare commit operation with HF filename
-		op, err := PrepareFileOperation(localPath, hfFilename)
-		if err != nil {
-			return fmt.Errorf("failed to prepare %s: %w", localFilename, err)
+		// In append mode, upload remote rows + new rows as a single file
+		if opts.Append && appendableFiles[hfFilename] {
+			mergedPath, err := u.mergeWithRemote(repoID, branch, hfFilename, localPath)
+			if err != nil {
+				return fmt.Errorf("failed to append to remote %s: %w", hfFilename, err)
+			}
+			if mergedPath != localPath {
+				defer func() { _ = os.Remove(mergedPath) }()
+				localPath = mergedPath
+			}
 		}
 
-		operations = append(operations, *op)
+		parts := []uploadFile{{localPath: localPath, pathInRepo: hfFilename}}
+		if appendableFiles[hfFilename] {
+			parts, err = u.shardIfLarge(localPath, hfFilename, opts.ShardSize, &shardDir)
+			if err != nil {
+				return fmt.Errorf("failed to shard %s: %w", localFilename, err)
+			}
+			if len(parts) > 1 {
+				dataFiles[hfFilename] = shardGlob(hfFilename)
+			}
+		}
 
-		// Track LFS files for upload
-		if op.LFSFile != nil {
-			// Generate sample preview (first 200 bytes as base64)
-			sample, err := generateFileSample(localPath, 200)
+		for _, part := range parts {
+			uploading[part.pathInRepo] = true
+			// Prepare commit operation with HF filename
+			op, err := PrepareFileOperation(part.localPath, part.pathInRepo)
 			if err != nil {
-				u.logger.Warn("Failed to generate sample", "file", localFilename, "error", err)
-				sample = "" // Continue without sample
+				return fmt.Errorf("failed to prepare %s: %w", localFilename, err)
 			}
 
-			lfsFiles = append(lfsFiles, LFSPointer{
-				OID:    op.LFSFile.SHA256,
-				Size:   op.LFSFile.Size,
-				Path:   hfFilename,
-				Sample: sample,
-			})
-			filePaths[op.LFSFile.SHA256] = localPath
-			u.logger.Debug("File will use LFS", "file", hfFilename, "size", op.LFSFile.Size)
-		} else {
-			u.logger.Debug("File will be embedded", "file", hfFilename)
+			operations = append(operations, *op)
+
+			// Track LFS files for upload
+			if op.LFSFile != nil {
+				// Generate sample preview (first 200 bytes as base64)
+				sample, err := generateFileSample(part.localPath, 200)
+				if err != nil {
+					u.logger.Warn("Failed to generate sample", "file", localFilename, "error", err)
+					sample = "" // Continue without sample
+				}
+
+				lfsFiles = append(lfsFiles, LFSPointer{
+					OID:    op.LFSFile.SHA256,
+					Size:   op.LFSFile.Size,
+					Path:   part.pathInRepo,
+					Sample: sample,
+				})
+				filePaths[op.LFSFile.SHA256] = part.localPath
+				u.logger.Debug("File will use LFS", "file", part.pathInRepo, "size", op.LFSFile.Size)
+			} else {
+				u.logger.Debug("File will be embedded", "file", part.pathInRepo)
+			}
+		}
+		uploaded[hfFilename] = true
+		if appendableFiles[hfFilename] {
+			datasets = append(datasets, hfFilename)
+		}
+	}
+
+	// A re-upload with fewer shards (or none) deletes the remote shards it doesn't replace.
+	// Append mode never shards and merges with the single remote file, so it keeps them
+	if !opts.Append && len(datasets) > 0 {
+		deletes, err := u.staleShardOperations(repoID, branch, datasets, uploading)
+		switch {
+		case err == nil:
+			operations = append(operations, deletes...)
+		case opts.ShardSize > 0:
+			// Stale shards would match the new shard glob, so don't commit without checking
+			return fmt.Errorf("failed to check for stale remote shards: %w", err)
+		default:
+			u.logger.Warn("Could not check for stale remote shards, leaving any in place", "error", err)
+		}
+	}
+
+	// The dataset card carries the license and splits regular and reasoning rows into separate HF configs
+	splitConfigs := uploaded[datasetFile] && uploaded[reasoningDatasetFile]
+	if uploaded[datasetFile] && (splitConfigs || !opts.Card.empty()) {
+		cardOp, err := u.createDatasetCardOperation(repoID, branch, dataFiles[datasetFile], dataFiles[reasoningDatasetFile], splitConfigs, opts.Card)
+		if err != nil {
+			u.logger.Warn("Failed to create dataset card, continuing without it", "error", err)
+		} else if cardOp != nil {
+			operations = append(operations, *cardOp)
 		}
 	}
 
@@ -142,7 +332,7 @@ func (u *Uploader) Upload(repoID, sessionDir string) error {
 	if len(lfsFiles) > 0 {
 		u.logger.Info("Uploading LFS files", "count", len(lfsFiles))
 
-		uploadMap, err := u.PreuploadLFSWithRetry(repoID, "main", lfsFiles, MaxRetries)
+		uploadMap, err := u.PreuploadLFSWithRetry(repoID, branch, lfsFiles, u.maxRetries)
 		if err != nil {
 			return fmt.Errorf("failed to preupload LFS: %w", err)
 		}
@@ -158,83 +348,83 @@ func (u *Uploader) Upload(repoID, sessionDir string) error {
 				continue // Skip upload, file exists
 			}
 
-			// Upload the file to S3/storage
-			if err := u.UploadLFSFileWithRetry(uploadInfo, localPath, MaxRetries); err != nil {
+			// Upload the file to S3/storage; multipart progress is tracked in the session dir
+			uploadInfo.PartStatePath = multipartStatePath(sessionDir, oid)
+			if err := u.UploadLFSFileWithRetry(uploadInfo, localPath, u.maxRetries); err != nil {
 				return fmt.Errorf("failed to upload LFS file %s: %w", localPath, err)
 			}
 		}
 	}
 
 	// Create commit with retry logic
-	sessionName := filepath.Base(sessionDir)
-	commitMsg := fmt.Sprintf("Upload dataset from VellumForge2 session %s", sessionName)
-
-	if err := u.createCommitWithRetry(repoID, "main", operations, commitMsg, MaxRetries); err != nil {
+	if err := u.createCommitWithRetry(repoID, branch, operations, commitMsg, commitDescription, u.maxRetries); err != nil {
 		return fmt.Errorf("failed to create commit: %w", err)
 	}
 
 	u.logger.Info("Upload completed successfully",
 		"repo_id", repoID,
+		"branch", branch,
 		"url", fmt.Sprintf("https://huggingface.co/datasets/%s", repoID))
 
 	return nil
 }
 
-func (u *Uploader) deleteRepo(repoID string) error {
-	url := fmt.Sprintf("https://huggingface.co/api/datasets/%s", repoID)
-	req, err := http.NewRequest("DELETE", url, nil)
+// shardIfLarge returns the files to upload for a dataset file: itself when it is below
+// LFSThreshold, its shards when shardSize is set, or itself with a warning otherwise
+// HF stores text files above LFSThreshold in LFS regardless of .gitattributes, and the
+// dataset viewer may not render them
+func (u *Uploader) shardIfLarge(localPath, pathInRepo string, shardSize int64, shardDir *string) ([]uploadFile, error) {
+	whole := []uploadFile{{localPath: localPath, pathInRepo: pathInRepo}}
+	info, err := os.Stat(localPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if info.Size() < LFSThreshold {
+		return whole, nil
 	}
-	req.Header.Set("Authorization", "Bearer "+u.token)
 
-	u.logger.Debug("Deleting repository", "repo_id", repoID, "url", url)
+	if shardSize <= 0 {
+		u.logger.Warn("Dataset file exceeds HF's text file limit and will be stored in LFS; the dataset viewer may not render it. Use --hf-shard to split it",
+			"file", pathInRepo,
+			"size_mb", info.Size()/(1024*1024),
+			"limit_mb", LFSThreshold/(1024*1024))
+		return whole, nil
+	}
 
-	resp, err := u.httpClient.Do(req)
+	if *shardDir == "" {
+		dir, err := os.MkdirTemp("", "vf2-shards-*")
+		if err != nil {
+			return nil, err
+		}
+		*shardDir = dir
+	}
+	shards, err := splitIntoShards(localPath, pathInRepo, *shardDir, shardSize)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			u.logger.Warn("Failed to close response body", "error", err)
-		}
-	}()
 
-	// 200/204 = success, 404 = already deleted (OK)
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("delete repo failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	u.logger.Info("Split dataset file into shards",
+		"file", pathInRepo,
+		"size_mb", info.Size()/(1024*1024),
+		"shards", len(shards),
+		"pattern", shardGlob(pathInRepo))
+	if shardSize >= LFSThreshold {
+		u.logger.Warn("Shard size is not below HF's text file limit; shards may still be stored in LFS",
+			"shard_size", shardSize,
+			"limit", LFSThreshold)
 	}
-
-	u.logger.Info("Repository deleted", "repo_id", repoID)
-	return nil
+	return shards, nil
 }
 
-func (u *Uploader) createRepo(repoID string) error {
+func (u *Uploader) createRepo(repoID string, opts UploadOptions) error {
 	// Check if repo exists first
-	checkURL := fmt.Sprintf("https://huggingface.co/api/datasets/%s", repoID)
-	req, err := http.NewRequest("GET", checkURL, nil)
+	exists, err := u.repoExists(repoID)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+u.token)
-
-	resp, err := u.httpClient.Do(req)
-	if err == nil && resp.StatusCode == http.StatusOK {
-		_ = resp.Body.Close()
-		u.logger.Warn("Repository already exists - deleting to ensure clean state", "repo_id", repoID)
-
-		// Delete existing repo to avoid LFS cache issues
-		if err := u.deleteRepo(repoID); err != nil {
-			return fmt.Errorf("failed to delete existing repo: %w", err)
-		}
-
-		// Wait for HF to propagate deletion and clear LFS cache
-		// This is necessary because HF's LFS storage is global and cached
-		u.logger.Info("Waiting for HF to propagate deletion", "seconds", 10)
-		time.Sleep(10 * time.Second)
-	} else if resp != nil {
-		_ = resp.Body.Close()
+	if exists {
+		u.logger.Info("Repository already exists, committing on top of it", "repo_id", repoID)
+		return nil
 	}
 
 	// Create repository
@@ -257,7 +447,7 @@ func (u *Uploader) createRepo(repoID string) error {
 		return err
 	}
 
-	req, err = http.NewRequest("POST", createURL, bytes.NewReader(body))
+	req, err := http.NewRequest("POST", createURL, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -267,7 +457,7 @@ func (u *Uploader) createRepo(repoID string) error {
 
 	u.logger.Debug("Creating repository", "url", createURL, "name", repoName)
 
-	resp, err = u.httpClient.Do(req)
+	resp, err := u.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -283,16 +473,224 @@ func (u *Uploader) createRepo(repoID string) error {
 	}
 
 	u.logger.Info("Repository created", "repo_id", repoID)
+	return u.waitForRepo(repoID, opts.ReadyTimeout, opts.ReadyPollInterval)
+}
+
+// repoExists reports whether the dataset repo is visible to the API
+// Network errors count as "not yet" so creation is attempted and reports the real failure
+func (u *Uploader) repoExists(repoID string) (bool, error) {
+	checkURL := fmt.Sprintf("https://huggingface.co/api/datasets/%s", repoID)
+	req, err := http.NewRequest("GET", checkURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		u.logger.Debug("Repository check failed", "repo_id", repoID, "error", err)
+		return false, nil
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// waitForRepo polls a newly created repo until the API serves it, so the first commit
+// doesn't race its propagation; returns as soon as it is visible
+func (u *Uploader) waitForRepo(repoID string, timeout, interval time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultRepoReadyTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultRepoReadyPollInterval
+	}
+	interval = min(interval, timeout)
+
+	start := time.Now()
+	for {
+		exists, err := u.repoExists(repoID)
+		if err != nil {
+			return err
+		}
+		waited := time.Since(start).Round(time.Millisecond)
+		if exists {
+			u.logger.Info("Repository ready", "repo_id", repoID, "waited", waited)
+			return nil
+		}
+		if waited >= timeout {
+			return fmt.Errorf("repository %s was not available %s after creation (raise huggingface.ready_timeout)", repoID, timeout)
+		}
+		u.logger.Debug("Waiting for repository to become available", "repo_id", repoID, "waited", waited)
+		time.Sleep(min(interval, timeout-waited))
+	}
+}
+
+// createBranch creates branch from main if it doesn't already exist
+func (u *Uploader) createBranch(repoID, branch string) error {
+	branchURL := fmt.Sprintf("https://huggingface.co/api/datasets/%s/branch/%s", repoID, url.PathEscape(branch))
+	req, err := http.NewRequest("POST", branchURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	u.logger.Debug("Creating branch", "repo_id", repoID, "branch", branch)
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			u.logger.Warn("Failed to close response body", "error", err)
+		}
+	}()
+
+	// 409 = branch already exists (OK)
+	if resp.StatusCode == http.StatusConflict {
+		u.logger.Debug("Branch already exists", "branch", branch)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("create branch failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	u.logger.Info("Branch created", "repo_id", repoID, "branch", branch)
 	return nil
 }
 
-func (u *Uploader) createCommit(repoID, branch string, operations []CommitOperation, message string) error {
-	url := fmt.Sprintf("https://huggingface.co/api/datasets/%s/commit/%s", repoID, branch)
+// mergeWithRemote downloads pathInRepo from branch and writes it, followed by the rows in
+// localPath, to a temp file. Returns localPath unchanged if the remote file doesn't exist.
+// The caller is responsible for removing the returned temp file.
+func (u *Uploader) mergeWithRemote(repoID, branch, pathInRepo, localPath string) (string, error) {
+	resolveURL := fmt.Sprintf("https://huggingface.co/datasets/%s/resolve/%s/%s", repoID, url.PathEscape(branch), pathInRepo)
+	req, err := http.NewRequest("GET", resolveURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	resp, err := u.lfsClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download remote file: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			u.logger.Warn("Failed to close response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		u.logger.Info("No remote file to append to, uploading new rows only", "file", pathInRepo)
+		return localPath, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	merged, err := os.CreateTemp("", "vf2-append-*.jsonl")
+	if err != nil {
+		return "", err
+	}
+	mergedPath := merged.Name()
+	cleanup := func() {
+		_ = merged.Close()
+		_ = os.Remove(mergedPath)
+	}
+
+	remoteBytes, err := copyEnsuringTrailingNewline(merged, resp.Body)
+	if err != nil {
+		cleanup()
+		return "", fmt.Errorf("failed to write remote rows: %w", err)
+	}
+
+	local, err := os.Open(localPath)
+	if err != nil {
+		cleanup()
+		return "", err
+	}
+	localBytes, err := io.Copy(merged, local)
+	_ = local.Close()
+	if err != nil {
+		cleanup()
+		return "", fmt.Errorf("failed to write new rows: %w", err)
+	}
+
+	if err := merged.Close(); err != nil {
+		_ = os.Remove(mergedPath)
+		return "", err
+	}
+
+	u.logger.Info("Appending to remote file",
+		"file", pathInRepo,
+		"remote_bytes", remoteBytes,
+		"new_bytes", localBytes)
+	return mergedPath, nil
+}
+
+// copyEnsuringTrailingNewline copies src to dst and adds a newline if src
+// is non-empty and doesn't end with one, so appended JSONL rows stay on their own lines
+func copyEnsuringTrailingNewline(dst io.Writer, src io.Reader) (int64, error) {
+	buf := make([]byte, 32*1024)
+	var written int64
+	var last byte
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return written, err
+			}
+			written += int64(n)
+			last = buf[n-1]
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+
+	if written > 0 && last != '\n' {
+		if _, err := dst.Write([]byte{'\n'}); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// commitInfo collects the session details for the commit message and description
+func (u *Uploader) commitInfo(sessionDir, branch string, opts UploadOptions) (CommitInfo, error) {
+	rows, err := countRows(filepath.Join(sessionDir, datasetFile))
+	if err != nil {
+		return CommitInfo{}, fmt.Errorf("failed to count dataset rows: %w", err)
+	}
+	configHash, err := fileSHA256(filepath.Join(sessionDir, "config.toml.bak"))
+	if err != nil {
+		return CommitInfo{}, fmt.Errorf("failed to hash session config: %w", err)
+	}
+	return CommitInfo{
+		Session:     filepath.Base(sessionDir),
+		DatasetMode: opts.DatasetMode,
+		Rows:        rows,
+		ConfigHash:  configHash,
+		Branch:      branch,
+		Append:      opts.Append,
+	}, nil
+}
+
+func (u *Uploader) createCommit(repoID, branch string, operations []CommitOperation, message, description string) error {
+	revision := url.PathEscape(branch)
+	url := fmt.Sprintf("https://huggingface.co/api/datasets/%s/commit/%s", repoID, revision)
 
 	// Build NDJSON payload (newline-delimited JSON)
 	// Format:
-	// {"key": "header", "value": {"summary": "...", "description": ""}}
+	// {"key": "header", "value": {"summary": "...", "description": "..."}}
 	// {"key": "file", "value": {"content": "...", "path": "...", "encoding": "base64"}}
+	// {"key": "deletedFile", "value": {"path": "..."}}
 
 	var ndjsonLines []string
 
@@ -301,7 +699,7 @@ func (u *Uploader) createCommit(repoID, branch string, operations []CommitOperat
 		"key": "header",
 		"value": map[string]string{
 			"summary":     message,
-			"description": "",
+			"description": description,
 		},
 	}
 	headerJSON, err := json.Marshal(header)
@@ -312,7 +710,17 @@ func (u *Uploader) createCommit(repoID, branch string, operations []CommitOperat
 
 	// File lines
 	for _, op := range operations {
-		if op.LFSFile != nil {
+		if op.Operation == "delete" {
+			fileLine := map[string]interface{}{
+				"key":   "deletedFile",
+				"value": map[string]interface{}{"path": op.Path},
+			}
+			fileJSON, err := json.Marshal(fileLine)
+			if err != nil {
+				return fmt.Errorf("failed to marshal deleted file %s: %w", op.Path, err)
+			}
+			ndjsonLines = append(ndjsonLines, string(fileJSON))
+		} else if op.LFSFile != nil {
 			// LFS file
 			fileLine := map[string]interface{}{
 				"key": "lfsFile",
@@ -396,7 +804,7 @@ func (u *Uploader) createCommit(repoID, branch string, operations []CommitOperat
 }
 
 // createCommitWithRetry attempts to create a commit with retry logic
-func (u *Uploader) createCommitWithRetry(repoID, branch string, operations []CommitOperation, message string, maxRetries int) error {
+func (u *Uploader) createCommitWithRetry(repoID, branch string, operations []CommitOperation, message, description string, maxRetries int) error {
 	var lastErr error
 	backoff := 2 * time.Second
 
@@ -412,7 +820,7 @@ func (u *Uploader) createCommitWithRetry(repoID, branch string, operations []Com
 			backoff *= 2 // Exponential backoff
 		}
 
-		err := u.createCommit(repoID, branch, operations, message)
+		err := u.createCommit(repoID, branch, operations, message, description)
 		if err == nil {
 			if attempt > 0 {
 				u.logger.Info("Commit creation succeeded after retry",
@@ -0,0 +1,120 @@
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// newEvaluateJudge returns a judge whose model is served by handler
+func newEvaluateJudge(t *testing.T, handler http.HandlerFunc) *Judge {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := api.NewClient(logger)
+	client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	cfg := &config.Config{
+		Models: map[string]config.ModelConfig{
+			"judge": {
+				BaseURL:             server.URL,
+				ModelName:           "judge-model",
+				MaxOutputTokens:     100,
+				ContextSize:         10000,
+				RateLimitPerMinute:  6000,
+				HTTPTimeoutSeconds:  5,
+				JudgeTimeoutSeconds: 5,
+			},
+		},
+		PromptTemplates: config.PromptTemplates{JudgeRubric: "Score this story: {{.StoryText}}"},
+	}
+	return New(cfg, &config.Secrets{}, client, logger)
+}
+
+// requestStory returns the story a judge request is scoring
+func requestStory(t *testing.T, r *http.Request) string {
+	var req api.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		t.Errorf("Failed to decode request: %v", err)
+		return ""
+	}
+	return req.Messages[len(req.Messages)-1].Content
+}
+
+func scoresResponse(score int) string {
+	content := fmt.Sprintf(`{"plot": {"score": %d, "reasoning": "ok"}}`, score)
+	data, _ := json.Marshal(content)
+	return `{"choices":[{"message":{"role":"assistant","content":` + string(data) + `}}]}`
+}
+
+func TestEvaluate_ScoresConcurrently(t *testing.T) {
+	// Each request waits until both are in flight, so a sequential Evaluate would time out
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	j := newEvaluateJudge(t, func(w http.ResponseWriter, r *http.Request) {
+		story := requestStory(t, r)
+		arrived.Done()
+		waited := make(chan struct{})
+		go func() { arrived.Wait(); close(waited) }()
+		select {
+		case <-waited:
+		case <-time.After(2 * time.Second):
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"message":"requests were not concurrent"}}`)
+			return
+		}
+		score := 2
+		if strings.Contains(story, "chosen") {
+			score = 4
+		}
+		_, _ = io.WriteString(w, scoresResponse(score))
+	})
+
+	result, err := j.Evaluate(context.Background(), "prompt", "the chosen story", "the rejected story")
+	if err != nil {
+		t.Fatalf("Evaluate returned error: %v", err)
+	}
+	if result.ChosenScoreTotal != 4 || result.RejectedScoreTotal != 2 {
+		t.Errorf("Expected chosen 4 and rejected 2, got %.1f and %.1f", result.ChosenScoreTotal, result.RejectedScoreTotal)
+	}
+	if result.PreferenceMargin != 2 {
+		t.Errorf("Expected preference margin 2, got %.1f", result.PreferenceMargin)
+	}
+}
+
+func TestEvaluate_FailureCancelsOtherEvaluation(t *testing.T) {
+	j := newEvaluateJudge(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(requestStory(t, r), "rejected") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"message":"bad request"}}`)
+			return
+		}
+		// The chosen evaluation only ends when Evaluate cancels it
+		select {
+		case <-r.Context().Done():
+		case <-time.After(3 * time.Second):
+			_, _ = io.WriteString(w, scoresResponse(4))
+		}
+	})
+
+	start := time.Now()
+	_, err := j.Evaluate(context.Background(), "prompt", "the chosen story", "the rejected story")
+	if err == nil || !strings.Contains(err.Error(), "failed to evaluate rejected response") {
+		t.Fatalf("Expected the rejected evaluation's error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the chosen evaluation to be canceled, Evaluate took %v", elapsed)
+	}
+}
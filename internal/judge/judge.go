@@ -3,9 +3,11 @@ package judge
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/lamim/vellumforge2/internal/api"
@@ -25,31 +27,83 @@ type Judge struct {
 	cfg       *config.Config
 	secrets   *config.Secrets
 	apiClient *api.Client
+	failover  *api.Failover // Switches to models.judge_fallback after repeated failures (nil = no fallback)
+	cache     *judgeCache   // Optional on-disk score cache (generation.enable_judge_cache, nil = disabled)
 	logger    *slog.Logger
+
+	// Judge cache counters, including those restored from a previous run
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
 }
 
 // New creates a new judge
 func New(cfg *config.Config, secrets *config.Secrets, apiClient *api.Client, logger *slog.Logger) *Judge {
-	return &Judge{
+	j := &Judge{
 		cfg:       cfg,
 		secrets:   secrets,
 		apiClient: apiClient,
 		logger:    logger.With("component", "judge"),
 	}
+	if fallback, ok := cfg.FallbackModel("judge"); ok {
+		j.failover = api.NewFailover("judge", fallback, cfg.Generation.FallbackAfterFailures, j.logger)
+	}
+	// Failing to set up the cache is not fatal; every evaluation then calls the judge
+	if cfg.Generation.EnableJudgeCache {
+		cache, err := newJudgeCache(cfg.Generation.JudgeCacheDir, j.logger)
+		if err != nil {
+			j.logger.Warn("Judge cache disabled", "error", err)
+		} else {
+			j.cache = cache
+			j.logger.Info("Judge cache enabled", "dir", cfg.Generation.JudgeCacheDir)
+		}
+	}
+	return j
+}
+
+// CacheStats returns the judge cache hits and misses
+func (j *Judge) CacheStats() (hits, misses int) {
+	return int(j.cacheHits.Load()), int(j.cacheMisses.Load())
+}
+
+// RestoreCacheStats seeds the judge cache counters with those of a previous run (resume)
+func (j *Judge) RestoreCacheStats(hits, misses int) {
+	j.cacheHits.Add(int64(hits))
+	j.cacheMisses.Add(int64(misses))
 }
 
 // Evaluate sends a story to the judge model for evaluation (full mode with explanations)
+// Chosen and rejected are scored concurrently; the record fails if either evaluation fails
 func (j *Judge) Evaluate(ctx context.Context, prompt, chosen, rejected string) (*models.JudgeResult, error) {
-	// Evaluate chosen response
-	chosenScores, err := j.evaluateSingle(ctx, prompt, chosen, true)
-	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate chosen response: %w", err)
+	// The first failure cancels the other request instead of waiting for a result we'd discard
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var rejectedScores map[string]models.CriteriaScore
+	var rejectedErr error
+	rejectedDone := make(chan struct{})
+	go func() {
+		defer close(rejectedDone)
+		rejectedScores, rejectedErr = j.evaluateSingle(ctx, prompt, rejected, true)
+		if rejectedErr != nil {
+			cancel()
+		}
+	}()
+
+	chosenScores, chosenErr := j.evaluateSingle(ctx, prompt, chosen, true)
+	if chosenErr != nil {
+		cancel()
 	}
+	<-rejectedDone
 
-	// Evaluate rejected response
-	rejectedScores, err := j.evaluateSingle(ctx, prompt, rejected, true)
-	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate rejected response: %w", err)
+	// Report the evaluation that failed, not the one it canceled
+	if chosenErr != nil && rejectedErr != nil && errors.Is(chosenErr, context.Canceled) && !errors.Is(rejectedErr, context.Canceled) {
+		chosenErr = nil
+	}
+	if chosenErr != nil {
+		return nil, fmt.Errorf("failed to evaluate chosen response: %w", chosenErr)
+	}
+	if rejectedErr != nil {
+		return nil, fmt.Errorf("failed to evaluate rejected response: %w", rejectedErr)
 	}
 
 	// Calculate total scores (average across all criteria)
@@ -99,13 +153,6 @@ func (j *Judge) evaluateSingle(ctx context.Context, prompt, story string, includ
 	}
 
 	judgeModel := j.cfg.Models["judge"]
-	apiKey := j.secrets.GetAPIKey(judgeModel.BaseURL)
-
-	// Create timeout context for judge API call
-	// Use configured timeout (default: 100s, generous for slower models)
-	timeoutDuration := time.Duration(judgeModel.JudgeTimeoutSeconds) * time.Second
-	timeoutCtx, cancel := context.WithTimeout(ctx, timeoutDuration)
-	defer cancel()
 
 	// Build messages with optional system prompt
 	messages := []api.Message{}
@@ -120,9 +167,58 @@ func (j *Judge) evaluateSingle(ctx context.Context, prompt, story string, includ
 		Content: judgePrompt,
 	})
 
+	// Identical prompt + response + rubric reuse the scores of an earlier run
+	var cacheKey string
+	if j.cache != nil {
+		scoreMin, scoreMax := j.scoreScale()
+		cacheKey = judgeCacheKey(judgeModel.ModelName, scoreMin, scoreMax, messages)
+		if scores, ok := j.cache.Get(cacheKey); ok {
+			j.cacheHits.Add(1)
+			j.logger.Debug("Using cached judge scores", "key", cacheKey[:12])
+			return scores, nil
+		}
+		j.cacheMisses.Add(1)
+	}
+
+	for attempt := 0; ; attempt++ {
+		scores, err := j.requestScores(ctx, judgeModel, messages)
+		if err != nil {
+			return nil, err
+		}
+		scores, err = j.validateScores(scores)
+		if err == nil {
+			if j.cache != nil {
+				if err := j.cache.Put(cacheKey, scores); err != nil {
+					j.logger.Warn("Failed to cache judge scores", "error", err)
+				}
+			}
+			return scores, nil
+		}
+		if !errors.Is(err, errInvalidScore) || attempt >= maxInvalidScoreRetries {
+			return nil, err
+		}
+		j.logger.Warn("Asking the judge again after invalid scores",
+			"attempt", attempt+1,
+			"max_retries", maxInvalidScoreRetries,
+			"error", err)
+	}
+}
+
+// requestScores makes one judge API call and parses the per-criterion scores
+func (j *Judge) requestScores(ctx context.Context, judgeModel config.ModelConfig, messages []api.Message) (map[string]models.CriteriaScore, error) {
+	judgeModel, onFallback := j.failover.Select(judgeModel)
+	apiKey := j.secrets.GetAPIKey(judgeModel.BaseURL)
+
+	// Create timeout context for judge API call
+	// Use configured timeout (default: 100s, generous for slower models)
+	timeoutDuration := time.Duration(judgeModel.JudgeTimeoutSeconds) * time.Second
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeoutDuration)
+	defer cancel()
+
 	// Call judge model ONCE
 	// API-level retries are handled by the API client for network errors, timeouts, etc.
 	resp, err := j.apiClient.ChatCompletion(timeoutCtx, judgeModel, apiKey, messages)
+	j.failover.Record(onFallback, err)
 	if err != nil {
 		// API call failed - network error, timeout, rate limit, etc.
 		// The API client has already retried these errors appropriately
@@ -328,16 +424,16 @@ PROMPT:
 STORY:
 {{.StoryText}}
 
-Evaluate the story and provide ONLY scores (1-5) for each criterion. Do NOT provide reasoning or explanations.
+Evaluate the story and provide ONLY scores ({{.ScoreMin}}-{{.ScoreMax}}) for each criterion. Do NOT provide reasoning or explanations.
 
 Return ONLY valid JSON in this exact format:
 {
-  "criterion1": {"score": 1-5},
-  "criterion2": {"score": 1-5},
-  "criterion3": {"score": 1-5}
+  "criterion1": {"score": {{.ScoreMin}}-{{.ScoreMax}}},
+  "criterion2": {"score": {{.ScoreMin}}-{{.ScoreMax}}},
+  "criterion3": {"score": {{.ScoreMin}}-{{.ScoreMax}}}
 }
 
-Criteria to evaluate (score 1-5 for each):
+Criteria to evaluate (integer score {{.ScoreMin}}-{{.ScoreMax}} for each):
 1. plot_and_structural_integrity
 2. character_and_dialogue
 3. world_building_and_immersion
@@ -346,9 +442,12 @@ Criteria to evaluate (score 1-5 for each):
 
 Return ONLY the JSON object, no markdown formatting.`
 
+	scoreMin, scoreMax := j.scoreScale()
 	return util.RenderTemplate(template, map[string]interface{}{
 		"Prompt":    prompt,
 		"StoryText": story,
+		"ScoreMin":  scoreMin,
+		"ScoreMax":  scoreMax,
 	})
 }
 
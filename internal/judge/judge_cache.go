@@ -0,0 +1,106 @@
+package judge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// judgeCacheEntry is the on-disk format of cached judge scores
+type judgeCacheEntry struct {
+	Key       string                          `json:"key"`
+	CreatedAt time.Time                       `json:"created_at"`
+	Scores    map[string]models.CriteriaScore `json:"scores"`
+}
+
+// judgeCache stores validated judge scores on disk (generation.enable_judge_cache)
+// Entries are keyed by a hash of the judge model, score scale, and the rendered judge messages,
+// which contain the rubric, prompt and response, so editing the rubric never reuses stale scores
+type judgeCache struct {
+	dir    string
+	logger *slog.Logger
+}
+
+// newJudgeCache creates the cache directory and returns a cache stored in it
+func newJudgeCache(dir string, logger *slog.Logger) (*judgeCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create judge cache directory: %w", err)
+	}
+	return &judgeCache{dir: dir, logger: logger}, nil
+}
+
+// judgeCacheKey hashes everything that determines a judge's scores for a request
+func judgeCacheKey(modelName string, scoreMin, scoreMax int, messages []api.Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\x00%d\x00%d", modelName, scoreMin, scoreMax)
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "\x00%s\x00%s", msg.Role, msg.Content)
+	}
+	hash := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(hash[:])
+}
+
+// path returns the cache file path for a key
+func (c *judgeCache) path(key string) string {
+	return filepath.Join(c.dir, key[:32]+".json")
+}
+
+// Get returns cached scores for key, or false if missing or unreadable
+func (c *judgeCache) Get(key string) (map[string]models.CriteriaScore, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry judgeCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.logger.Warn("Ignoring corrupt judge cache entry", "key", key, "error", err)
+		return nil, false
+	}
+	if entry.Key != key || len(entry.Scores) == 0 {
+		return nil, false
+	}
+
+	return entry.Scores, true
+}
+
+// Put stores scores for key (written atomically via temp file + rename)
+func (c *judgeCache) Put(key string, scores map[string]models.CriteriaScore) error {
+	data, err := json.Marshal(judgeCacheEntry{
+		Key:       key,
+		CreatedAt: time.Now(),
+		Scores:    scores,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal judge cache entry: %w", err)
+	}
+
+	// Chosen and rejected are judged concurrently, so identical responses may write the same key
+	tmp, err := os.CreateTemp(c.dir, key[:32]+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write judge cache entry: %w", err)
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write judge cache entry: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path(key)); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to save judge cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package judge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEvaluateSingle_UsesJudgeCache(t *testing.T) {
+	var requests atomic.Int32
+	j := newEvaluateJudge(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = io.WriteString(w, scoresResponse(4))
+	})
+	cache, err := newJudgeCache(t.TempDir(), j.logger)
+	if err != nil {
+		t.Fatalf("newJudgeCache returned unexpected error: %v", err)
+	}
+	j.cache = cache
+
+	for i := 0; i < 2; i++ {
+		scores, err := j.evaluateSingle(context.Background(), "prompt", "the story", true)
+		if err != nil {
+			t.Fatalf("evaluateSingle returned unexpected error: %v", err)
+		}
+		if scores["plot"].Score != 4 {
+			t.Errorf("Expected plot score 4, got %d", scores["plot"].Score)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected 1 judge request for a repeated evaluation, got %d", got)
+	}
+
+	// A different response and an edited rubric both miss the cache
+	if _, err := j.evaluateSingle(context.Background(), "prompt", "another story", true); err != nil {
+		t.Fatalf("evaluateSingle returned unexpected error: %v", err)
+	}
+	j.cfg.PromptTemplates.JudgeRubric = "Score this story strictly: {{.StoryText}}"
+	if _, err := j.evaluateSingle(context.Background(), "prompt", "the story", true); err != nil {
+		t.Fatalf("evaluateSingle returned unexpected error: %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("Expected 3 judge requests, got %d", got)
+	}
+
+	hits, misses := j.CacheStats()
+	if hits != 1 || misses != 3 {
+		t.Errorf("Expected 1 hit and 3 misses, got %d and %d", hits, misses)
+	}
+}
@@ -230,6 +230,30 @@ func TestTruncateString(t *testing.T) {
 // instead of checking error types to determine retryability.
 
 // Helper function to create a test judge instance
+func TestParseJudgeResponse_RationaleAliases(t *testing.T) {
+	j := setupTestJudge()
+
+	response := `{
+		"plot": {"score": 4, "rationale": "Tight pacing"},
+		"prose": {"score": 3, "explanation": "Some clichés"},
+		"voice": {"score": 5, "reasoning": "Distinct", "rationale": "ignored"}
+	}`
+	scores, err := j.parseJudgeResponse(response)
+	if err != nil {
+		t.Fatalf("parseJudgeResponse returned unexpected error: %v", err)
+	}
+
+	want := map[string]string{"plot": "Tight pacing", "prose": "Some clichés", "voice": "Distinct"}
+	for criterion, reasoning := range want {
+		if scores[criterion].Reasoning != reasoning {
+			t.Errorf("Expected %s reasoning %q, got %q", criterion, reasoning, scores[criterion].Reasoning)
+		}
+	}
+	if scores["prose"].Score != 3 {
+		t.Errorf("Expected prose score 3, got %d", scores["prose"].Score)
+	}
+}
+
 func setupTestJudge() *Judge {
 	return &Judge{
 		cfg:     &config.Config{},
@@ -0,0 +1,79 @@
+package judge
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+const (
+	// maxInvalidScoreRetries is how many extra judge calls invalid_score_action=retry makes
+	maxInvalidScoreRetries = 2
+)
+
+var (
+	// errInvalidScore marks an evaluation rejected by invalid_score_action=retry
+	errInvalidScore = errors.New("judge returned invalid scores")
+	// errNoValidScores means every criterion was dropped by invalid_score_action=drop
+	errNoValidScores = errors.New("judge returned no valid scores")
+)
+
+// scoreScale returns the valid per-criterion score range (judge_filtering.score_min/score_max)
+func (j *Judge) scoreScale() (int, int) {
+	lo, hi := j.cfg.JudgeFiltering.ScoreScale()
+	if lo >= hi {
+		return config.DefaultScoreMin, config.DefaultScoreMax
+	}
+	return lo, hi
+}
+
+// validateScores checks every criterion score against the configured scale
+// Out-of-range and fractional scores are logged and then clamped, rejected or dropped
+// according to judge_filtering.invalid_score_action
+func (j *Judge) validateScores(scores map[string]models.CriteriaScore) (map[string]models.CriteriaScore, error) {
+	lo, hi := j.scoreScale()
+	action := j.cfg.JudgeFiltering.InvalidScoreAction
+	if action == "" {
+		action = config.InvalidScoreClamp
+	}
+
+	var invalid []string
+	valid := make(map[string]models.CriteriaScore, len(scores))
+	for criterion, score := range scores {
+		outOfRange := score.Score < lo || score.Score > hi
+		if !outOfRange && !score.Rounded {
+			valid[criterion] = score
+			continue
+		}
+
+		j.logger.Warn("Judge returned an invalid criterion score",
+			"criterion", criterion,
+			"score", score.Score,
+			"fractional", score.Rounded,
+			"min", lo,
+			"max", hi,
+			"action", action)
+		invalid = append(invalid, criterion)
+
+		switch action {
+		case config.InvalidScoreClamp:
+			score.Score = max(lo, min(score.Score, hi))
+			valid[criterion] = score
+		case config.InvalidScoreDrop:
+			// Leave the criterion out of the average
+		}
+	}
+
+	if len(invalid) == 0 {
+		return scores, nil
+	}
+	if action == config.InvalidScoreRetry {
+		return nil, fmt.Errorf("%w: %v outside %d-%d or fractional", errInvalidScore, invalid, lo, hi)
+	}
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("%w: all %d criteria were invalid", errNoValidScores, len(scores))
+	}
+	return valid, nil
+}
@@ -0,0 +1,142 @@
+package judge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestParseJudgeResponse_FractionalScore(t *testing.T) {
+	j := setupTestJudge()
+
+	scores, err := j.parseJudgeResponse(`{"plot": {"score": 4.5}, "prose": {"score": 3.0}, "voice": {"score": 2.4}}`)
+	if err != nil {
+		t.Fatalf("parseJudgeResponse returned unexpected error: %v", err)
+	}
+
+	want := map[string]struct {
+		score   int
+		rounded bool
+	}{
+		"plot":  {5, true},
+		"prose": {3, false},
+		"voice": {2, true},
+	}
+	for criterion, w := range want {
+		got := scores[criterion]
+		if got.Score != w.score || got.Rounded != w.rounded {
+			t.Errorf("Criterion %q: expected score %d (rounded=%v), got %d (rounded=%v)",
+				criterion, w.score, w.rounded, got.Score, got.Rounded)
+		}
+	}
+}
+
+func TestValidateScores(t *testing.T) {
+	scores := func() map[string]models.CriteriaScore {
+		return map[string]models.CriteriaScore{
+			"plot":  {Score: 7},
+			"prose": {Score: -2},
+			"voice": {Score: 4, Rounded: true},
+			"world": {Score: 3},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		action  string
+		want    map[string]int
+		wantErr error
+	}{
+		{
+			name:   "clamp",
+			action: config.InvalidScoreClamp,
+			want:   map[string]int{"plot": 5, "prose": 1, "voice": 4, "world": 3},
+		},
+		{
+			name:   "default_is_clamp",
+			action: "",
+			want:   map[string]int{"plot": 5, "prose": 1, "voice": 4, "world": 3},
+		},
+		{
+			name:   "drop",
+			action: config.InvalidScoreDrop,
+			want:   map[string]int{"world": 3},
+		},
+		{
+			name:    "retry",
+			action:  config.InvalidScoreRetry,
+			wantErr: errInvalidScore,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := setupTestJudge()
+			j.cfg.JudgeFiltering.InvalidScoreAction = tt.action
+
+			got, err := j.validateScores(scores())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateScores returned unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d criteria, got %d: %v", len(tt.want), len(got), got)
+			}
+			for criterion, want := range tt.want {
+				if got[criterion].Score != want {
+					t.Errorf("Criterion %q: expected score %d, got %d", criterion, want, got[criterion].Score)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateScores_CustomScale(t *testing.T) {
+	j := setupTestJudge()
+	lo, hi := 0, 10
+	j.cfg.JudgeFiltering.ScoreMin = &lo
+	j.cfg.JudgeFiltering.ScoreMax = &hi
+
+	got, err := j.validateScores(map[string]models.CriteriaScore{"plot": {Score: 8}, "prose": {Score: 12}})
+	if err != nil {
+		t.Fatalf("validateScores returned unexpected error: %v", err)
+	}
+	if got["plot"].Score != 8 || got["prose"].Score != 10 {
+		t.Errorf("Expected plot=8 prose=10, got plot=%d prose=%d", got["plot"].Score, got["prose"].Score)
+	}
+	if avg := calculateAverageScore(got); avg != 9.0 {
+		t.Errorf("Expected average 9.0 after clamping, got %f", avg)
+	}
+}
+
+func TestValidateScores_AllDropped(t *testing.T) {
+	j := setupTestJudge()
+	j.cfg.JudgeFiltering.InvalidScoreAction = config.InvalidScoreDrop
+
+	_, err := j.validateScores(map[string]models.CriteriaScore{"plot": {Score: 9}, "prose": {Score: 0}})
+	if !errors.Is(err, errNoValidScores) {
+		t.Errorf("Expected errNoValidScores, got %v", err)
+	}
+}
+
+func TestFilteringPromptUsesScale(t *testing.T) {
+	j := setupTestJudge()
+	hi := 10
+	j.cfg.JudgeFiltering.ScoreMax = &hi // score_min keeps its default of 1
+
+	prompt, err := j.getFilteringPrompt("a prompt", "a story")
+	if err != nil {
+		t.Fatalf("getFilteringPrompt returned unexpected error: %v", err)
+	}
+	if !strings.Contains(prompt, `{"score": 1-10},`) {
+		t.Errorf("Expected filtering prompt to use the 1-10 scale, got:\n%s", prompt)
+	}
+}
@@ -1,7 +1,9 @@
 package metrics
 
 import (
+	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -9,8 +11,13 @@ import (
 )
 
 var (
+	// registry holds only VellumForge metrics (no Go runtime or process collectors),
+	// so the exported file stays small and cheap to write
+	registry = prometheus.NewRegistry()
+	factory  = promauto.With(registry)
+
 	// API metrics
-	apiRequestDuration = promauto.NewHistogramVec(
+	apiRequestDuration = factory.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "vellumforge_api_request_duration_seconds",
 			Help:    "API request duration in seconds by model",
@@ -19,7 +26,7 @@ var (
 		[]string{"model", "status"},
 	)
 
-	rateLimiterWaitDuration = promauto.NewHistogramVec(
+	rateLimiterWaitDuration = factory.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "vellumforge_rate_limiter_wait_duration_seconds",
 			Help:    "Rate limiter wait duration in seconds by model",
@@ -29,7 +36,7 @@ var (
 	)
 
 	// Worker metrics
-	workerQueueDepth = promauto.NewGaugeVec(
+	workerQueueDepth = factory.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "vellumforge_worker_queue_depth",
 			Help: "Current depth of worker job queue",
@@ -37,7 +44,7 @@ var (
 		[]string{"phase"}, // "prompts" or "pairs"
 	)
 
-	jobProcessingDuration = promauto.NewHistogramVec(
+	jobProcessingDuration = factory.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "vellumforge_job_processing_duration_seconds",
 			Help:    "Job processing duration breakdown by stage",
@@ -47,7 +54,7 @@ var (
 	)
 
 	// Generation metrics
-	generationThroughput = promauto.NewCounterVec(
+	generationThroughput = factory.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "vellumforge_generation_total",
 			Help: "Total number of generations completed",
@@ -55,16 +62,56 @@ var (
 		[]string{"stage", "status"}, // stage: "subtopics"/"prompts"/"pairs", status: "success"/"error"
 	)
 
-	activeWorkers = promauto.NewGaugeVec(
+	activeWorkers = factory.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "vellumforge_active_workers",
 			Help: "Number of active workers by phase",
 		},
 		[]string{"phase"},
 	)
+
+	rateLimitedResponses = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vellumforge_rate_limited_responses_total",
+			Help: "API responses rejected with HTTP 429 by model",
+		},
+		[]string{"model"},
+	)
+
+	tokensUsed = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vellumforge_tokens_total",
+			Help: "Tokens reported by the API usage field by model",
+		},
+		[]string{"model", "type"}, // type: "prompt" or "completion"
+	)
+
+	// Dataset metrics
+	rowsWritten = factory.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vellumforge_rows_written_total",
+			Help: "Jobs written to the dataset",
+		},
+	)
+
+	rowsFiltered = factory.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vellumforge_rows_filtered_total",
+			Help: "Jobs dropped by judge filtering",
+		},
+	)
+
+	jobFailures = factory.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vellumforge_job_failures_total",
+			Help: "Failed jobs by error class",
+		},
+		[]string{"class"}, // api.ClassifyError classes: rate_limit, timeout, auth, ...
+	)
 )
 
 // Collector provides convenience methods for recording metrics
+// A nil *Collector is valid and records nothing, so callers need no enabled checks
 type Collector struct {
 	logger *slog.Logger
 }
@@ -78,6 +125,9 @@ func NewCollector(logger *slog.Logger) *Collector {
 
 // RecordAPIRequest records an API request duration
 func (c *Collector) RecordAPIRequest(model string, duration time.Duration, success bool) {
+	if c == nil {
+		return
+	}
 	status := "success"
 	if !success {
 		status = "error"
@@ -87,21 +137,33 @@ func (c *Collector) RecordAPIRequest(model string, duration time.Duration, succe
 
 // RecordRateLimiterWait records rate limiter wait time
 func (c *Collector) RecordRateLimiterWait(model string, duration time.Duration) {
+	if c == nil {
+		return
+	}
 	rateLimiterWaitDuration.WithLabelValues(model).Observe(duration.Seconds())
 }
 
 // SetWorkerQueueDepth sets the current queue depth
 func (c *Collector) SetWorkerQueueDepth(phase string, depth int) {
+	if c == nil {
+		return
+	}
 	workerQueueDepth.WithLabelValues(phase).Set(float64(depth))
 }
 
 // RecordJobProcessing records job processing duration by stage
 func (c *Collector) RecordJobProcessing(stage string, duration time.Duration) {
+	if c == nil {
+		return
+	}
 	jobProcessingDuration.WithLabelValues(stage).Observe(duration.Seconds())
 }
 
 // IncrementGeneration increments generation counter
 func (c *Collector) IncrementGeneration(stage string, success bool) {
+	if c == nil {
+		return
+	}
 	status := "success"
 	if !success {
 		status = "error"
@@ -111,9 +173,102 @@ func (c *Collector) IncrementGeneration(stage string, success bool) {
 
 // SetActiveWorkers sets the number of active workers
 func (c *Collector) SetActiveWorkers(phase string, count int) {
+	if c == nil {
+		return
+	}
 	activeWorkers.WithLabelValues(phase).Set(float64(count))
 }
 
+// RecordRateLimited counts a 429 response
+func (c *Collector) RecordRateLimited(model string) {
+	if c == nil {
+		return
+	}
+	rateLimitedResponses.WithLabelValues(model).Inc()
+}
+
+// RecordTokens adds the prompt and completion tokens reported by a response
+func (c *Collector) RecordTokens(model string, promptTokens, completionTokens int) {
+	if c == nil {
+		return
+	}
+	if promptTokens > 0 {
+		tokensUsed.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		tokensUsed.WithLabelValues(model, "completion").Add(float64(completionTokens))
+	}
+}
+
+// RecordRowWritten counts a job written to the dataset
+func (c *Collector) RecordRowWritten() {
+	if c == nil {
+		return
+	}
+	rowsWritten.Inc()
+}
+
+// RecordRowFiltered counts a job dropped by judge filtering
+func (c *Collector) RecordRowFiltered() {
+	if c == nil {
+		return
+	}
+	rowsFiltered.Inc()
+}
+
+// RecordJobFailure counts a failed job by error class
+func (c *Collector) RecordJobFailure(errorClass string) {
+	if c == nil {
+		return
+	}
+	jobFailures.WithLabelValues(errorClass).Inc()
+}
+
+// WriteFile writes a snapshot of all metrics to path in the Prometheus text format
+// The file is replaced atomically, so node_exporter's textfile collector never reads a partial file
+func (c *Collector) WriteFile(path string) error {
+	if err := prometheus.WriteToTextfile(path, registry); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
+
+// ExportToFile rewrites the metrics file every interval until the returned stop function is called
+// stop writes a final snapshot and waits for the exporter to exit; it is safe to call more than once
+func (c *Collector) ExportToFile(path string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	write := func() {
+		if err := c.WriteFile(path); err != nil {
+			c.logger.Warn("Metrics export failed", "path", path, "error", err)
+		}
+	}
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				write()
+			case <-done:
+				write()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}
+}
+
 // GetMetricsSummary returns a human-readable summary of current metrics
 func (c *Collector) GetMetricsSummary() string {
 	// This is a placeholder - in practice you'd gather from prometheus registry
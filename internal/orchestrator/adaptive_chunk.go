@@ -0,0 +1,116 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// minAdaptiveChunk is the smallest subtopic chunk adaptive_chunking will request
+	minAdaptiveChunk = 5
+	// adaptiveLowYield is the received/requested ratio below which the chunk shrinks
+	adaptiveLowYield = 0.8
+	// maxAdaptiveFailures stops chunking after this many consecutive failed chunks
+	maxAdaptiveFailures = 3
+	// maxAdaptiveStale stops chunking after this many consecutive chunks with no new subtopics
+	maxAdaptiveStale = 2
+)
+
+// adaptiveChunker sizes subtopic chunk requests from the outcome of recent chunks
+// (generation.adaptive_chunking)
+// A short chunk shrinks the size to what the model actually returned, a failed chunk halves it,
+// and a full chunk grows it by 25% up to twice the configured subtopic_chunk_size
+type adaptiveChunker struct {
+	size    int
+	minSize int
+	maxSize int
+}
+
+func newAdaptiveChunker(initial int) *adaptiveChunker {
+	minSize := min(minAdaptiveChunk, initial)
+	return &adaptiveChunker{
+		size:    initial,
+		minSize: minSize,
+		maxSize: max(initial*2, minSize),
+	}
+}
+
+// Next returns the size of the next chunk request
+func (a *adaptiveChunker) Next(remaining int) int {
+	return min(a.size, remaining)
+}
+
+// Observe adjusts the chunk size after a request for requested items returned received
+// ok is false when the request or its JSON parsing failed
+func (a *adaptiveChunker) Observe(requested, received int, ok bool) {
+	switch {
+	case !ok || received == 0:
+		a.size = max(a.minSize, a.size/2)
+	case float64(received) < float64(requested)*adaptiveLowYield:
+		a.size = max(a.minSize, received)
+	case received >= requested && requested == a.size:
+		// Only grow on chunks that were not capped by the remaining count
+		a.size = min(a.maxSize, a.size+max(1, a.size/4))
+	}
+}
+
+// requestSubtopicsAdaptive collects subtopics in adaptively sized chunks until requestCount
+// distinct subtopics were received, the model stops producing new ones, or chunks keep failing
+// Unlike fixed chunking, progress counts what the model returned rather than what was asked for
+func (o *Orchestrator) requestSubtopicsAdaptive(ctx context.Context, mainTopic string, requestCount, chunkSize int) ([]string, error) {
+	chunker := newAdaptiveChunker(chunkSize)
+	var allSubtopics []string
+	distinct, failures, stale := 0, 0, 0
+
+	for distinct < requestCount {
+		requested := chunker.Next(requestCount - distinct)
+		chunkSubtopics, err := o.requestSubtopics(ctx, mainTopic, requested, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			failures++
+			chunker.Observe(requested, 0, false)
+			if failures < maxAdaptiveFailures {
+				o.logger.Warn("Subtopic chunk failed, retrying with a smaller chunk",
+					"error", err,
+					"requested", requested,
+					"next_chunk_size", chunker.size)
+				continue
+			}
+			if len(allSubtopics) > 0 {
+				o.logger.Warn("Chunk requests keep failing, continuing with partial results",
+					"error", err,
+					"collected_so_far", len(allSubtopics))
+				break
+			}
+			return nil, fmt.Errorf("initial subtopic generation failed: %w", err)
+		}
+		failures = 0
+
+		allSubtopics = append(allSubtopics, chunkSubtopics...)
+		newDistinct := len(deduplicateStrings(allSubtopics))
+		chunker.Observe(requested, len(chunkSubtopics), true)
+		o.logger.Debug("Adaptive subtopic chunk",
+			"requested", requested,
+			"received", len(chunkSubtopics),
+			"new_distinct", newDistinct-distinct,
+			"collected", newDistinct,
+			"next_chunk_size", chunker.size)
+
+		if newDistinct == distinct {
+			stale++
+			if stale >= maxAdaptiveStale {
+				o.logger.Warn("Model stopped returning new subtopics, continuing with partial results",
+					"collected", newDistinct,
+					"requested", requestCount)
+				break
+			}
+		} else {
+			stale = 0
+		}
+		distinct = newDistinct
+	}
+
+	return allSubtopics, nil
+}
@@ -0,0 +1,114 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestAdaptiveChunker(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested int
+		received  int
+		ok        bool
+		want      int
+	}{
+		{name: "short chunk shrinks to yield", requested: 30, received: 20, ok: true, want: 20},
+		{name: "near-full chunk keeps size", requested: 30, received: 27, ok: true, want: 30},
+		{name: "full chunk grows", requested: 30, received: 30, ok: true, want: 37},
+		{name: "capped chunk does not grow", requested: 10, received: 10, ok: true, want: 30},
+		{name: "failure halves", requested: 30, ok: false, want: 15},
+		{name: "empty chunk halves", requested: 30, received: 0, ok: true, want: 15},
+		{name: "shrink floors at minimum", requested: 30, received: 2, ok: true, want: minAdaptiveChunk},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunker := newAdaptiveChunker(30)
+			chunker.Observe(tt.requested, tt.received, tt.ok)
+			if chunker.size != tt.want {
+				t.Errorf("Expected chunk size %d, got %d", tt.want, chunker.size)
+			}
+		})
+	}
+
+	// Growth is capped at twice the configured chunk size
+	chunker := newAdaptiveChunker(30)
+	for range 10 {
+		chunker.Observe(chunker.size, chunker.size, true)
+	}
+	if chunker.size != 60 {
+		t.Errorf("Expected growth capped at 60, got %d", chunker.size)
+	}
+}
+
+func TestRequestSubtopicsAdaptive(t *testing.T) {
+	// The model returns 20 new subtopics no matter how many are requested
+	countPattern := regexp.MustCompile(`Give (\d+) subtopics`)
+	var mu sync.Mutex
+	var requested []int
+	next := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if m := countPattern.FindStringSubmatch(string(body)); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			requested = append(requested, n)
+		}
+		items := make([]string, 20)
+		for i := range items {
+			items[i] = fmt.Sprintf("Subtopic %d", next)
+			next++
+		}
+		content, _ := json.Marshal(items)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` +
+			jsonQuote(string(content)) + `},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{MainTopic: "Fantasy"},
+			Models: map[string]config.ModelConfig{"main": {
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				RateLimitPerMinute: 6000,
+			}},
+			PromptTemplates: config.PromptTemplates{SubtopicGeneration: "Give {{.NumSubtopics}} subtopics about {{.MainTopic}}"},
+		},
+		secrets:   &config.Secrets{},
+		apiClient: api.NewClient(logger),
+		logger:    logger,
+		stats:     &models.SessionStats{},
+	}
+
+	subtopics, err := orch.requestSubtopicsAdaptive(context.Background(), "Fantasy", 50, 30)
+	if err != nil {
+		t.Fatalf("requestSubtopicsAdaptive returned unexpected error: %v", err)
+	}
+	if len(subtopics) < 50 {
+		t.Errorf("Expected at least 50 subtopics, got %d", len(subtopics))
+	}
+	// 30 (short, shrink to 20) -> 20 (full) -> remaining 10
+	if want := []int{30, 20, 10}; !slices.Equal(requested, want) {
+		t.Errorf("Expected chunk requests %v, got %v", want, requested)
+	}
+}
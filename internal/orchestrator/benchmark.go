@@ -0,0 +1,105 @@
+package orchestrator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// phaseTimer aggregates per-phase timings for throughput reports (run --benchmark)
+// Phase durations are set by Run; job timings are added concurrently by workers
+type phaseTimer struct {
+	subtopics     time.Duration
+	prompts       time.Duration
+	pairsStart    time.Time
+	pairsEnd      time.Time
+	rowsBaseline  int // SuccessCount when the pairs phase started (resumed sessions start above 0)
+	doneBaseline  int // Jobs already completed by earlier runs of this session
+	totalJobs     int // Jobs in the full configured run
+	jobsProcessed atomic.Int64
+
+	// Successful jobs only, summed in nanoseconds
+	timedJobs     atomic.Int64
+	timedRejected atomic.Int64 // Jobs that generated rejected responses
+	chosenNanos   atomic.Int64
+	rejectedNanos atomic.Int64
+	jobNanos      atomic.Int64
+}
+
+// recordJob adds the timing breakdown of one successful job
+func (t *phaseTimer) recordJob(chosen, rejected, total time.Duration, hasRejected bool) {
+	t.timedJobs.Add(1)
+	t.chosenNanos.Add(int64(chosen))
+	t.jobNanos.Add(int64(total))
+	if hasRejected {
+		t.timedRejected.Add(1)
+		t.rejectedNanos.Add(int64(rejected))
+	}
+}
+
+// ThroughputReport summarizes achieved throughput for capacity planning
+type ThroughputReport struct {
+	SubtopicsDuration time.Duration
+	PromptsDuration   time.Duration
+	PairsDuration     time.Duration // 0 when the pairs phase was never reached
+
+	TotalJobs     int // Jobs in the full configured run
+	CompletedJobs int // Jobs finished so far, including earlier runs of a resumed session
+	JobsProcessed int // Jobs finished in this run (successes and failures)
+	RowsWritten   int // Successful jobs written in this run
+	RowsPerMinute float64
+
+	AvgChosen   time.Duration // Average chosen generation latency per successful job
+	AvgRejected time.Duration // Average rejected generation latency (jobs with rejected responses)
+	AvgJob      time.Duration // Average total latency per successful job (judge runs async, not included)
+
+	APIRequests           int64
+	RateLimitWaitFraction float64 // Share of API request time spent blocked on the rate limiter
+
+	ProjectedRemaining time.Duration // Estimated time to finish the remaining jobs (0 = unknown)
+	ProjectedTotal     time.Duration // Estimated wall-clock time of the full run from scratch (0 = unknown)
+}
+
+// ThroughputReport returns timing and throughput for the run so far
+// Intended for after Run returns, e.g. when run --benchmark stops at its time budget
+func (o *Orchestrator) ThroughputReport() ThroughputReport {
+	t := &o.timer
+	report := ThroughputReport{
+		SubtopicsDuration: t.subtopics,
+		PromptsDuration:   t.prompts,
+		TotalJobs:         t.totalJobs,
+		JobsProcessed:     int(t.jobsProcessed.Load()),
+		RowsWritten:       o.stats.SuccessCount - t.rowsBaseline,
+	}
+	report.CompletedJobs = min(t.doneBaseline+report.JobsProcessed, t.totalJobs)
+
+	if !t.pairsStart.IsZero() {
+		end := t.pairsEnd
+		if end.IsZero() {
+			end = time.Now()
+		}
+		report.PairsDuration = end.Sub(t.pairsStart)
+	}
+
+	if n := t.timedJobs.Load(); n > 0 {
+		report.AvgChosen = time.Duration(t.chosenNanos.Load() / n)
+		report.AvgJob = time.Duration(t.jobNanos.Load() / n)
+	}
+	if n := t.timedRejected.Load(); n > 0 {
+		report.AvgRejected = time.Duration(t.rejectedNanos.Load() / n)
+	}
+
+	if o.apiClient != nil {
+		timings := o.apiClient.RequestTimings()
+		report.APIRequests = timings.Requests
+		report.RateLimitWaitFraction = timings.WaitFraction()
+	}
+
+	minutes := report.PairsDuration.Minutes()
+	if minutes > 0 && report.JobsProcessed > 0 {
+		report.RowsPerMinute = float64(report.RowsWritten) / minutes
+		perJob := report.PairsDuration / time.Duration(report.JobsProcessed)
+		report.ProjectedRemaining = perJob * time.Duration(report.TotalJobs-report.CompletedJobs)
+		report.ProjectedTotal = report.SubtopicsDuration + report.PromptsDuration + perJob*time.Duration(report.TotalJobs)
+	}
+	return report
+}
@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestThroughputReport(t *testing.T) {
+	start := time.Now()
+	o := &Orchestrator{stats: &models.SessionStats{SuccessCount: 25}}
+	o.timer.subtopics = 10 * time.Second
+	o.timer.prompts = 50 * time.Second
+	o.timer.totalJobs = 100
+	o.timer.doneBaseline = 10 // Resumed session: 10 jobs done by an earlier run
+	o.timer.rowsBaseline = 10
+	o.timer.pairsStart = start
+	o.timer.pairsEnd = start.Add(2 * time.Minute)
+	o.timer.jobsProcessed.Store(20)
+	for range 15 {
+		o.timer.recordJob(4*time.Second, 2*time.Second, 6*time.Second, true)
+	}
+	o.timer.recordJob(4*time.Second, 0, 4*time.Second, false)
+
+	r := o.ThroughputReport()
+	if r.RowsWritten != 15 {
+		t.Errorf("Expected 15 rows written this run, got %d", r.RowsWritten)
+	}
+	if r.RowsPerMinute != 7.5 {
+		t.Errorf("Expected 7.5 rows/min, got %f", r.RowsPerMinute)
+	}
+	if r.CompletedJobs != 30 {
+		t.Errorf("Expected 30 completed jobs, got %d", r.CompletedJobs)
+	}
+	if r.AvgChosen != 4*time.Second || r.AvgRejected != 2*time.Second {
+		t.Errorf("Expected chosen 4s / rejected 2s, got %s / %s", r.AvgChosen, r.AvgRejected)
+	}
+	if want := (6*15 + 4) * time.Second / 16; r.AvgJob != want {
+		t.Errorf("Expected average job %s, got %s", want, r.AvgJob)
+	}
+
+	// 20 jobs in 2 minutes = 6s per job
+	if r.ProjectedRemaining != 70*6*time.Second {
+		t.Errorf("Expected 7m remaining, got %s", r.ProjectedRemaining)
+	}
+	if want := time.Minute + 100*6*time.Second; r.ProjectedTotal != want {
+		t.Errorf("Expected full run %s, got %s", want, r.ProjectedTotal)
+	}
+}
+
+func TestThroughputReport_PairsNotReached(t *testing.T) {
+	o := &Orchestrator{stats: &models.SessionStats{}}
+	o.timer.subtopics = 30 * time.Second
+
+	r := o.ThroughputReport()
+	if r.PairsDuration != 0 || r.RowsPerMinute != 0 || r.ProjectedTotal != 0 {
+		t.Errorf("Expected no throughput or projection before the pairs phase, got %+v", r)
+	}
+}
@@ -0,0 +1,54 @@
+package orchestrator
+
+import "errors"
+
+// errFailureRateExceeded is the cancellation cause when generation.max_failure_rate aborts a run
+var errFailureRateExceeded = errors.New("job failure rate exceeded generation.max_failure_rate")
+
+// failureRateMonitor tracks job outcomes for this run and trips once the failure rate exceeds
+// maxRate after at least minSamples jobs. Only used by the collector goroutine.
+type failureRateMonitor struct {
+	maxRate    float64 // Failure rate (0.0-1.0) that trips the monitor (<= 0 disables it)
+	minSamples int     // Jobs to observe before the rate is checked
+	successes  int
+	failures   int
+	tripped    bool
+}
+
+// newFailureRateMonitor creates a monitor; counts start at zero so resumed runs are judged
+// only on their own jobs
+func newFailureRateMonitor(maxRate float64, minSamples int) *failureRateMonitor {
+	return &failureRateMonitor{maxRate: maxRate, minSamples: minSamples}
+}
+
+// Record counts a job outcome and reports whether this outcome tripped the monitor
+// Only the call that trips the monitor returns true
+func (m *failureRateMonitor) Record(failed bool) bool {
+	if failed {
+		m.failures++
+	} else {
+		m.successes++
+	}
+	if m.tripped || m.maxRate <= 0 || m.successes+m.failures < m.minSamples {
+		return false
+	}
+	if m.Rate() <= m.maxRate {
+		return false
+	}
+	m.tripped = true
+	return true
+}
+
+// Rate returns the failure rate observed so far
+func (m *failureRateMonitor) Rate() float64 {
+	total := m.successes + m.failures
+	if total == 0 {
+		return 0
+	}
+	return float64(m.failures) / float64(total)
+}
+
+// Samples returns the number of jobs observed so far
+func (m *failureRateMonitor) Samples() int {
+	return m.successes + m.failures
+}
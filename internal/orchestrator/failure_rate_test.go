@@ -0,0 +1,70 @@
+package orchestrator
+
+import "testing"
+
+func TestFailureRateMonitor(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRate    float64
+		minSamples int
+		outcomes   []bool // true = failed
+		tripAt     int    // index of the outcome that trips the monitor (-1 = never)
+	}{
+		{
+			name:       "disabled",
+			maxRate:    0,
+			minSamples: 1,
+			outcomes:   []bool{true, true, true, true},
+			tripAt:     -1,
+		},
+		{
+			name:       "waits for min samples",
+			maxRate:    0.5,
+			minSamples: 4,
+			outcomes:   []bool{true, true, true, true},
+			tripAt:     3,
+		},
+		{
+			name:       "rate at threshold does not trip",
+			maxRate:    0.5,
+			minSamples: 2,
+			outcomes:   []bool{true, false, false, true},
+			tripAt:     -1,
+		},
+		{
+			name:       "trips once rate exceeds threshold",
+			maxRate:    0.5,
+			minSamples: 2,
+			outcomes:   []bool{false, false, true, true, true, true},
+			tripAt:     4,
+		},
+		{
+			name:       "successes keep rate below threshold",
+			maxRate:    0.25,
+			minSamples: 4,
+			outcomes:   []bool{false, false, false, true, false, false},
+			tripAt:     -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := newFailureRateMonitor(tt.maxRate, tt.minSamples)
+			tripped := -1
+			for i, failed := range tt.outcomes {
+				if monitor.Record(failed) {
+					if tripped != -1 {
+						t.Fatalf("Monitor tripped twice (at %d and %d)", tripped, i)
+					}
+					tripped = i
+				}
+			}
+			if tripped != tt.tripAt {
+				t.Errorf("Expected trip at %d, got %d (rate %.2f)", tt.tripAt, tripped, monitor.Rate())
+			}
+			if monitor.Samples() != len(tt.outcomes) {
+				t.Errorf("Expected %d samples, got %d", len(tt.outcomes), monitor.Samples())
+			}
+		})
+	}
+}
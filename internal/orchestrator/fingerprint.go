@@ -0,0 +1,37 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// errFingerprintChanged is the cancellation cause when generation.on_fingerprint_change = "abort" stops a run
+var errFingerprintChanged = errors.New("model system_fingerprint changed mid-run")
+
+// watchFingerprints seeds the client with the fingerprints of the interrupted run (resume)
+// and, with on_fingerprint_change = "abort", cancels the run on the first change
+func (o *Orchestrator) watchFingerprints() {
+	if o.apiClient == nil {
+		return
+	}
+	o.apiClient.RestoreSystemFingerprints(o.stats.SystemFingerprints)
+	if o.cfg.Generation.OnFingerprintChange != config.FingerprintChangeAbort {
+		return
+	}
+	o.apiClient.SetFingerprintChangeHandler(func(model, previous, current string) {
+		if o.cancelRun != nil {
+			o.cancelRun(fmt.Errorf("%w: %s went from %s to %s; set generation.on_fingerprint_change = \"warn\" to continue",
+				errFingerprintChanged, model, previous, current))
+		}
+	})
+}
+
+// syncFingerprintStats copies the client's observed fingerprints into the session stats
+func (o *Orchestrator) syncFingerprintStats() {
+	if o.apiClient == nil {
+		return
+	}
+	o.stats.SystemFingerprints = o.apiClient.SystemFingerprints()
+}
@@ -0,0 +1,69 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// errIdenticalPair drops a job whose rejected response matches chosen (classified as identical_pair)
+var errIdenticalPair = fmt.Errorf("%w: rejected response matches chosen", api.ErrIdenticalPair)
+
+// isIdenticalPair compares chosen and rejected ignoring case and whitespace differences
+func isIdenticalPair(chosen, rejected string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(chosen), " "), strings.Join(strings.Fields(rejected), " "))
+}
+
+// resolveIdenticalPairs applies generation.on_identical_pair to rejected candidates that match chosen
+// In regen mode each matching candidate is regenerated once; any candidate that still matches
+// fails the job so it is never written as a degenerate pair
+func (o *Orchestrator) resolveIdenticalPairs(
+	ctx context.Context,
+	logger *slog.Logger,
+	job models.GenerationJob,
+	chosen string,
+	model config.ModelConfig,
+	rejections []models.RejectedResponse,
+) error {
+	action := o.cfg.Generation.OnIdenticalPair
+	for i := range rejections {
+		if !isIdenticalPair(chosen, rejections[i].Content) {
+			continue
+		}
+
+		if action == config.IdenticalPairKeep || action == "" {
+			logger.Warn("Keeping job whose rejected response matches chosen",
+				"job_id", job.ID,
+				"candidate", i+1)
+			continue
+		}
+
+		if action == config.IdenticalPairRegen {
+			logger.Warn("Rejected response matches chosen, regenerating once",
+				"job_id", job.ID,
+				"candidate", i+1)
+			rejection, err := o.generateRejectedResponse(ctx, logger, job, chosen, model)
+			if err != nil {
+				return fmt.Errorf("failed to regenerate identical rejected response: %w", err)
+			}
+			rejection.Attempts = rejections[i].Attempts.Add(rejection.Attempts)
+			rejection.Attempts.Content++
+			rejections[i] = rejection
+			if !isIdenticalPair(chosen, rejection.Content) {
+				continue
+			}
+		}
+
+		logger.Warn("Dropping job: rejected response matches chosen",
+			"job_id", job.ID,
+			"candidate", i+1,
+			"on_identical_pair", action)
+		return errIdenticalPair
+	}
+	return nil
+}
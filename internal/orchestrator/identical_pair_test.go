@@ -0,0 +1,95 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestIsIdenticalPair(t *testing.T) {
+	tests := []struct {
+		chosen, rejected string
+		want             bool
+	}{
+		{"The answer.", "The answer.", true},
+		{"The  answer.\n", "the answer.", true},
+		{"The answer.", "A different answer.", false},
+	}
+
+	for _, tt := range tests {
+		if got := isIdenticalPair(tt.chosen, tt.rejected); got != tt.want {
+			t.Errorf("isIdenticalPair(%q, %q): expected %v, got %v", tt.chosen, tt.rejected, tt.want, got)
+		}
+	}
+}
+
+func TestResolveIdenticalPairs(t *testing.T) {
+	tests := []struct {
+		name         string
+		action       string
+		regenReply   string
+		wantErr      bool
+		wantRejected string
+	}{
+		{name: "keep", action: config.IdenticalPairKeep, wantRejected: "same text"},
+		{name: "drop", action: config.IdenticalPairDrop, wantErr: true},
+		{name: "regen succeeds", action: config.IdenticalPairRegen, regenReply: "different text", wantRejected: "different text"},
+		{name: "regen still identical", action: config.IdenticalPairRegen, regenReply: "Same text", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` +
+					jsonQuote(tt.regenReply) + `},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			orch := &Orchestrator{
+				cfg:       &config.Config{Generation: config.GenerationConfig{OnIdenticalPair: tt.action}},
+				secrets:   &config.Secrets{},
+				apiClient: api.NewClient(logger),
+				logger:    logger,
+			}
+			model := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "weak-model",
+				MaxOutputTokens:    100,
+				RateLimitPerMinute: 60,
+			}
+			rejections := []models.RejectedResponse{{Content: "same text", Model: "weak-model"}}
+
+			err := orch.resolveIdenticalPairs(context.Background(), logger, models.GenerationJob{ID: 1}, "same text", model, rejections)
+
+			if tt.wantErr {
+				if !errors.Is(err, errIdenticalPair) {
+					t.Fatalf("Expected errIdenticalPair, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			} else if rejections[0].Content != tt.wantRejected {
+				t.Errorf("Expected rejected %q, got %q", tt.wantRejected, rejections[0].Content)
+			}
+
+			wantRequests := 0
+			if tt.action == config.IdenticalPairRegen {
+				wantRequests = 1
+			}
+			if requests != wantRequests {
+				t.Errorf("Expected %d regeneration requests, got %d", wantRequests, requests)
+			}
+		})
+	}
+}
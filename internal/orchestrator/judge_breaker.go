@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"errors"
+	"sync"
+)
+
+// errJudgeUnavailable is the cancellation cause when the judge circuit breaker aborts a run
+var errJudgeUnavailable = errors.New("judge model unavailable")
+
+// judgeBreaker tracks async judge outcomes and trips after too many consecutive failures
+// Safe for concurrent use by judge goroutines
+type judgeBreaker struct {
+	mu          sync.Mutex
+	threshold   int // Consecutive failures before tripping (<= 0 disables the breaker)
+	consecutive int
+	successes   int
+	failures    int
+	tripped     bool
+}
+
+// newJudgeBreaker creates a breaker seeded with counts from a previous run (resume)
+func newJudgeBreaker(threshold, successes, failures int) *judgeBreaker {
+	return &judgeBreaker{
+		threshold: threshold,
+		successes: successes,
+		failures:  failures,
+	}
+}
+
+// RecordSuccess counts a successful evaluation and resets the consecutive failure streak
+func (b *judgeBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.successes++
+	b.consecutive = 0
+}
+
+// RecordFailure counts a failed evaluation and reports whether this failure tripped the breaker
+// Only the call that trips the breaker returns true
+func (b *judgeBreaker) RecordFailure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.consecutive++
+	if b.tripped || b.threshold <= 0 || b.consecutive < b.threshold {
+		return false
+	}
+	b.tripped = true
+	return true
+}
+
+// RecordSkipped counts a record that was not sent to the judge because the breaker is open
+func (b *judgeBreaker) RecordSkipped() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+}
+
+// Tripped reports whether the breaker has tripped
+func (b *judgeBreaker) Tripped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.tripped
+}
+
+// Counts returns the total successful and failed evaluations
+func (b *judgeBreaker) Counts() (successes, failures int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.successes, b.failures
+}
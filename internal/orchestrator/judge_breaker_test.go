@@ -0,0 +1,46 @@
+package orchestrator
+
+import "testing"
+
+func TestJudgeBreakerTripsAfterConsecutiveFailures(t *testing.T) {
+	breaker := newJudgeBreaker(3, 0, 0)
+
+	if breaker.RecordFailure() || breaker.RecordFailure() {
+		t.Fatal("Expected breaker to stay closed below the threshold")
+	}
+	breaker.RecordSuccess() // resets the streak
+
+	if breaker.RecordFailure() || breaker.RecordFailure() {
+		t.Fatal("Expected success to reset the consecutive failure count")
+	}
+	if !breaker.RecordFailure() {
+		t.Fatal("Expected breaker to trip on the third consecutive failure")
+	}
+	if breaker.RecordFailure() {
+		t.Error("Expected only the tripping call to return true")
+	}
+	if !breaker.Tripped() {
+		t.Error("Expected breaker to report tripped")
+	}
+
+	breaker.RecordSkipped()
+	successes, failures := breaker.Counts()
+	if successes != 1 || failures != 7 {
+		t.Errorf("Expected 1 success and 7 failures, got %d and %d", successes, failures)
+	}
+}
+
+func TestJudgeBreakerDisabled(t *testing.T) {
+	breaker := newJudgeBreaker(-1, 5, 2)
+
+	for i := 0; i < 100; i++ {
+		if breaker.RecordFailure() {
+			t.Fatalf("Expected disabled breaker never to trip (tripped at failure %d)", i+1)
+		}
+	}
+
+	successes, failures := breaker.Counts()
+	if successes != 5 || failures != 102 {
+		t.Errorf("Expected counts to continue from resumed stats (5, 102), got (%d, %d)", successes, failures)
+	}
+}
@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/judge"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestApplyJudgeFiltering_JudgesEveryRejectedCandidate(t *testing.T) {
+	// The fake judge scores a story by the "scoreN" tag in its text
+	tag := regexp.MustCompile(`score(\d)`)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var req api.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		score := tag.FindStringSubmatch(req.Messages[len(req.Messages)-1].Content)[1]
+		content, _ := json.Marshal(fmt.Sprintf(`{"plot": {"score": %s}}`, score))
+		_, _ = fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s}}]}`, content)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		rejected     []string
+		want         bool
+		wantRequests int32
+	}{
+		{"single rejected", []string{"score1 weak"}, false, 2},
+		{"every candidate weak", []string{"score1 weak", "score2 weak", "score1 weak"}, false, 4},
+		{"last candidate too strong", []string{"score1 weak", "score2 weak", "score5 strong"}, true, 4},
+		{"first candidate too strong", []string{"score5 strong", "score1 weak", "score1 weak"}, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			client := api.NewClient(logger)
+			client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+			cfg := &config.Config{
+				Models: map[string]config.ModelConfig{
+					"judge": {
+						BaseURL:             server.URL,
+						ModelName:           "judge-model",
+						MaxOutputTokens:     100,
+						RateLimitPerMinute:  6000,
+						HTTPTimeoutSeconds:  5,
+						JudgeTimeoutSeconds: 5,
+					},
+				},
+				JudgeFiltering: config.JudgeFilteringConfig{Enabled: true, MinChosenScore: 3, MaxRejectedScore: 3},
+			}
+			orch := &Orchestrator{
+				cfg:         cfg,
+				judgeModule: judge.New(cfg, &config.Secrets{}, client, logger),
+				logger:      logger,
+			}
+
+			result := models.GenerationResult{Job: models.GenerationJob{Prompt: "A dragon"}, Chosen: "score5 chosen", Rejected: tt.rejected[0]}
+			if len(tt.rejected) > 1 {
+				for _, content := range tt.rejected {
+					result.RejectedList = append(result.RejectedList, models.RejectedResponse{Content: content})
+				}
+			}
+
+			requests.Store(0)
+			if got := orch.applyJudgeFiltering(result); got != tt.want {
+				t.Errorf("Expected filtered=%v, got %v", tt.want, got)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("Expected %d judge requests, got %d", tt.wantRequests, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/writer"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestHandleResultKeepsFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), writer.FailuresFile)
+	failureLog, err := writer.NewFailureLog(path, false)
+	if err != nil {
+		t.Fatalf("NewFailureLog returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	orch := &Orchestrator{
+		cfg:        &config.Config{},
+		stats:      &models.SessionStats{},
+		logger:     slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
+		ctx:        ctx,
+		failureLog: failureLog,
+	}
+	monitor := newFailureRateMonitor(0, 1)
+
+	failed := models.GenerationResult{
+		Job:   models.GenerationJob{ID: 7, SubTopic: "Dragons", Prompt: "Write about a dragon"},
+		Error: errors.New("chosen generation failed"),
+	}
+	orch.handleResult(failed, monitor)
+
+	// Jobs failing because the run is stopping are not worth keeping
+	cancel()
+	failed.Job.ID = 8
+	orch.handleResult(failed, monitor)
+	_ = failureLog.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read failures file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 kept failure, got %d: %s", len(lines), data)
+	}
+	if !strings.Contains(lines[0], `"job_id":7`) || !strings.Contains(lines[0], "Write about a dragon") {
+		t.Errorf("Expected job 7 and its prompt, got %s", lines[0])
+	}
+	if orch.stats.FailureCount != 2 {
+		t.Errorf("Expected both failures counted in stats, got %d", orch.stats.FailureCount)
+	}
+}
@@ -0,0 +1,7 @@
+package orchestrator
+
+// rejectedCount returns how many rejected responses to generate for a job
+// KTO with kto_ratio set may return 0 (desirable row only); otherwise num_rejected applies
+func (o *Orchestrator) rejectedCount(jobID int) int {
+	return o.cfg.Generation.RejectedCount(jobID)
+}
@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestRejectedCount(t *testing.T) {
+	tests := []struct {
+		name string
+		gen  config.GenerationConfig
+		want int
+	}{
+		{"default", config.GenerationConfig{DatasetMode: models.DatasetModeDPO}, 1},
+		{"num_rejected", config.GenerationConfig{DatasetMode: models.DatasetModeKTO, NumRejected: 3}, 3},
+		{"kto_ratio", config.GenerationConfig{DatasetMode: models.DatasetModeKTO, KTORatio: 2.0}, 2},
+		{"kto_ratio ignored outside KTO", config.GenerationConfig{DatasetMode: models.DatasetModeDPO, KTORatio: 2.0}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &Orchestrator{cfg: &config.Config{Generation: tt.gen}}
+			if got := orch.rejectedCount(0); got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteKTORecordNoNegatives(t *testing.T) {
+	writer := &preferenceWriter{}
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				DatasetMode: models.DatasetModeKTO,
+				KTORatio:    0.5,
+			},
+		},
+		dataWriter: writer,
+	}
+
+	// Job 0 gets no undesirable rows at ratio 0.5
+	result := models.GenerationResult{
+		Job:    models.GenerationJob{ID: 0, Prompt: "Write a story"},
+		Chosen: "A fine story",
+	}
+	if err := orch.writeKTORecord(result); err != nil {
+		t.Fatalf("writeKTORecord returned error: %v", err)
+	}
+
+	if len(writer.ktoRecords) != 1 || !writer.ktoRecords[0].Label {
+		t.Fatalf("Expected only the desirable row, got %+v", writer.ktoRecords)
+	}
+}
@@ -0,0 +1,35 @@
+package orchestrator
+
+import (
+	"unicode/utf8"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// lengthRatioOutOfRange returns the first chosen/rejected length ratio (in characters) outside
+// generation.length_ratio_min/max; with several rejected candidates every one is checked
+// Results without a rejected response are never out of range
+func (o *Orchestrator) lengthRatioOutOfRange(result models.GenerationResult) (float64, bool) {
+	minRatio, maxRatio := o.cfg.Generation.LengthRatioMin, o.cfg.Generation.LengthRatioMax
+	if (minRatio <= 0 && maxRatio <= 0) || result.Rejected == "" {
+		return 0, false
+	}
+
+	rejected := []string{result.Rejected}
+	if len(result.RejectedList) > 1 {
+		rejected = rejected[:0]
+		for _, candidate := range result.RejectedList {
+			rejected = append(rejected, candidate.Content)
+		}
+	}
+
+	chosenLen := float64(utf8.RuneCountInString(result.Chosen))
+	for _, content := range rejected {
+		// An empty candidate gives +Inf, which only a maximum rejects
+		ratio := chosenLen / float64(utf8.RuneCountInString(content))
+		if (minRatio > 0 && ratio < minRatio) || (maxRatio > 0 && ratio > maxRatio) {
+			return ratio, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,107 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestLengthRatioOutOfRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		min       float64
+		max       float64
+		chosen    int
+		rejected  []int
+		wantRatio float64
+		want      bool
+	}{
+		{"disabled", 0, 0, 10, []int{100}, 0, false},
+		{"within range", 0.5, 2, 100, []int{80}, 0, false},
+		{"rejected much longer", 0.5, 2, 100, []int{300}, 100.0 / 300, true},
+		{"chosen much longer", 0.5, 2, 300, []int{100}, 3, true},
+		{"only a minimum", 0.5, 0, 1000, []int{10}, 0, false},
+		{"any candidate counts", 0.5, 2, 100, []int{90, 400}, 0.25, true},
+		{"no rejected response", 0.5, 2, 100, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &Orchestrator{cfg: &config.Config{Generation: config.GenerationConfig{LengthRatioMin: tt.min, LengthRatioMax: tt.max}}}
+			result := models.GenerationResult{Chosen: strings.Repeat("é", tt.chosen)}
+			for _, n := range tt.rejected {
+				result.RejectedList = append(result.RejectedList, models.RejectedResponse{Content: strings.Repeat("x", n)})
+			}
+			if len(result.RejectedList) > 0 {
+				result.Rejected = result.RejectedList[0].Content
+			}
+
+			ratio, got := orch.lengthRatioOutOfRange(result)
+			if got != tt.want || ratio != tt.wantRatio {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tt.wantRatio, tt.want, ratio, got)
+			}
+		})
+	}
+}
+
+// dpoCountingWriter counts DPO records instead of writing them, keeping the last one
+type dpoCountingWriter struct {
+	stubWriter
+	dpoRecords int
+	last       models.DPORecord
+}
+
+func (w *dpoCountingWriter) WriteDPORecord(record models.DPORecord, _, _ string) error {
+	w.dpoRecords++
+	w.last = record
+	return nil
+}
+
+func TestHandleResultLengthRatio(t *testing.T) {
+	for _, action := range []string{config.LengthRatioDrop, config.LengthRatioFlag} {
+		t.Run(action, func(t *testing.T) {
+			dw := &dpoCountingWriter{}
+			orch := &Orchestrator{
+				cfg: &config.Config{Generation: config.GenerationConfig{
+					DatasetMode:       models.DatasetModeDPO,
+					LengthRatioMin:    0.5,
+					LengthRatioMax:    2,
+					LengthRatioAction: action,
+				}},
+				dataWriter: dw,
+				stats:      &models.SessionStats{},
+				logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			monitor := newFailureRateMonitor(0, 1)
+
+			orch.handleResult(models.GenerationResult{
+				Job:      models.GenerationJob{ID: 1, Prompt: "Write about a dragon"},
+				Chosen:   strings.Repeat("A short tale. ", 10),
+				Rejected: strings.Repeat("A rambling tale that goes on. ", 40),
+			}, monitor)
+
+			if action == config.LengthRatioDrop {
+				if dw.dpoRecords != 0 || orch.stats.LengthRatioFiltered != 1 || orch.stats.FilteredCount != 1 {
+					t.Errorf("Expected the pair to be dropped and counted, got %d written, stats %+v", dw.dpoRecords, orch.stats)
+				}
+				return
+			}
+			if dw.dpoRecords != 1 || orch.stats.LengthRatioFlagged != 1 || orch.stats.FilteredCount != 0 {
+				t.Errorf("Expected the pair to be written and flagged, got %d written, stats %+v", dw.dpoRecords, orch.stats)
+			}
+			meta := dw.last.Meta
+			if meta == nil || !meta.LengthRatioFlagged || meta.LengthRatio <= 0 || meta.LengthRatio >= 0.5 {
+				t.Fatalf("Expected _meta to mark the row flagged with its ratio, got %+v", meta)
+			}
+			data, err := json.Marshal(meta)
+			if err != nil || strings.Contains(string(data), "attempts") {
+				t.Errorf("Expected only the length fields without include_metadata, got %s (err %v)", data, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestMaxRuntimeErr(t *testing.T) {
+	orch := &Orchestrator{
+		cfg:    &config.Config{Generation: config.GenerationConfig{MaxRuntime: "1ms"}},
+		stats:  &models.SessionStats{},
+		logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
+	}
+
+	budgetCtx, cancelBudget := context.WithTimeoutCause(context.Background(), time.Millisecond, ErrMaxRuntimeExceeded)
+	defer cancelBudget()
+	runCtx, cancelRun := context.WithCancelCause(budgetCtx)
+	defer cancelRun(nil)
+	<-runCtx.Done()
+
+	if err := orch.maxRuntimeErr(runCtx); !errors.Is(err, ErrMaxRuntimeExceeded) {
+		t.Errorf("Expected ErrMaxRuntimeExceeded after the budget expired, got %v", err)
+	}
+
+	interruptedCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := orch.maxRuntimeErr(interruptedCtx); err != nil {
+		t.Errorf("Expected nil for a signal-style cancellation, got %v", err)
+	}
+}
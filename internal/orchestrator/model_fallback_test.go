@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestProcessJob_SwitchesToMainFallback(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, `{"error":{"message":"service unavailable"}}`)
+	}))
+	defer primary.Close()
+	reply := strings.Repeat("The dragon slept beneath the mountain for a thousand years. ", 3)
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":`+jsonQuote(reply)+`},"finish_reason":"stop"}]}`)
+	}))
+	defer fallback.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := api.NewClient(logger)
+	client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	model := func(url, name string) config.ModelConfig {
+		return config.ModelConfig{BaseURL: url, ModelName: name, MaxOutputTokens: 100, RateLimitPerMinute: 6000, HTTPTimeoutSeconds: 5}
+	}
+	cfg := &config.Config{
+		Generation: config.GenerationConfig{DatasetMode: models.DatasetModeSFT, FallbackAfterFailures: 2},
+		Models: map[string]config.ModelConfig{
+			"main":          model(primary.URL, "primary-model"),
+			"main_fallback": model(fallback.URL, "fallback-model"),
+		},
+		PromptTemplates: config.PromptTemplates{ChosenGeneration: "{{.Prompt}}"},
+	}
+	fallbackModel, _ := cfg.FallbackModel("main")
+	orch := &Orchestrator{
+		cfg:          cfg,
+		secrets:      &config.Secrets{},
+		apiClient:    client,
+		logger:       logger,
+		stats:        &models.SessionStats{},
+		mainFailover: api.NewFailover("main", fallbackModel, cfg.Generation.FallbackAfterFailures, logger),
+	}
+
+	job := models.GenerationJob{ID: 1, Prompt: "Write about a dragon"}
+	for i := 0; i < 2; i++ {
+		if result := orch.processJob(context.Background(), logger, job); result.Error == nil {
+			t.Fatalf("Expected job %d against the failing primary to fail", i+1)
+		}
+	}
+	result := orch.processJob(context.Background(), logger, job)
+	if result.Error != nil {
+		t.Fatalf("Expected the fallback model to succeed, got %v", result.Error)
+	}
+	if result.ChosenModel != "fallback-model" {
+		t.Errorf("Expected chosen model fallback-model, got %s", result.ChosenModel)
+	}
+}
@@ -0,0 +1,320 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/writer"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// preferenceWriter records DPO/KTO writes; other methods fall through to stubWriter
+type preferenceWriter struct {
+	stubWriter
+	dpoRecords        []models.DPORecord
+	multiRecords      []models.MultiRejectedDPORecord
+	multiReasoning    [][]string
+	convRecords       []models.ConversationalDPORecord
+	ktoRecords        []models.KTORecord
+	rejectedReasoning []string
+}
+
+func (p *preferenceWriter) WriteDPORecord(record models.DPORecord, _, rejectedReasoning string) error {
+	p.dpoRecords = append(p.dpoRecords, record)
+	p.rejectedReasoning = append(p.rejectedReasoning, rejectedReasoning)
+	return nil
+}
+
+func (p *preferenceWriter) WriteMultiRejectedDPORecord(record models.MultiRejectedDPORecord, _ string, rejectedReasoning []string) error {
+	p.multiRecords = append(p.multiRecords, record)
+	p.multiReasoning = append(p.multiReasoning, rejectedReasoning)
+	return nil
+}
+
+func (p *preferenceWriter) WriteConversationalDPORecord(record models.ConversationalDPORecord, _, rejectedReasoning string) error {
+	p.convRecords = append(p.convRecords, record)
+	p.rejectedReasoning = append(p.rejectedReasoning, rejectedReasoning)
+	return nil
+}
+
+func (p *preferenceWriter) WriteKTORecord(record models.KTORecord, _ string) error {
+	p.ktoRecords = append(p.ktoRecords, record)
+	return nil
+}
+
+func multiRejectedResult() models.GenerationResult {
+	return models.GenerationResult{
+		Job:               models.GenerationJob{Prompt: "prompt"},
+		Chosen:            "chosen",
+		ChosenModel:       "main-model",
+		Rejected:          "rejected one",
+		RejectedReasoning: "thinking one",
+		RejectedModel:     "weak-model",
+		RejectedList: []models.RejectedResponse{
+			{Content: "rejected one", Reasoning: "thinking one", Model: "weak-model"},
+			{Content: "rejected two", Model: "weak-model"},
+			{Content: "rejected three", Model: "weak-model"},
+		},
+	}
+}
+
+func TestWriteDPORecordMultiRejectedRows(t *testing.T) {
+	writer := &preferenceWriter{}
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				DatasetMode:           models.DatasetModeDPO,
+				NumRejected:           3,
+				RejectedOutputShape:   models.RejectedShapeRows,
+				RecordModelAssignment: true,
+			},
+		},
+		dataWriter: writer,
+	}
+
+	if err := orch.writeDPORecord(multiRejectedResult()); err != nil {
+		t.Fatalf("writeDPORecord returned error: %v", err)
+	}
+
+	if len(writer.dpoRecords) != 3 {
+		t.Fatalf("Expected 3 DPO rows, got %d", len(writer.dpoRecords))
+	}
+	for i, want := range []string{"rejected one", "rejected two", "rejected three"} {
+		record := writer.dpoRecords[i]
+		if record.Prompt != "prompt" || record.Chosen != "chosen" {
+			t.Errorf("Row %d: expected shared prompt/chosen, got %+v", i, record)
+		}
+		if record.Rejected != want {
+			t.Errorf("Row %d: expected rejected %q, got %q", i, want, record.Rejected)
+		}
+		if record.RejectedModel != "weak-model" {
+			t.Errorf("Row %d: expected rejected_model weak-model, got %q", i, record.RejectedModel)
+		}
+	}
+	if writer.rejectedReasoning[0] != "thinking one" || writer.rejectedReasoning[1] != "" {
+		t.Errorf("Expected per-candidate reasoning, got %v", writer.rejectedReasoning)
+	}
+	if len(writer.multiRecords) != 0 {
+		t.Errorf("Expected no array records in rows mode, got %d", len(writer.multiRecords))
+	}
+}
+
+func TestWriteDPORecordMultiRejectedArray(t *testing.T) {
+	writer := &preferenceWriter{}
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				DatasetMode:         models.DatasetModeDPO,
+				NumRejected:         3,
+				RejectedOutputShape: models.RejectedShapeArray,
+			},
+		},
+		dataWriter: writer,
+	}
+
+	if err := orch.writeDPORecord(multiRejectedResult()); err != nil {
+		t.Fatalf("writeDPORecord returned error: %v", err)
+	}
+
+	if len(writer.multiRecords) != 1 || len(writer.dpoRecords) != 0 {
+		t.Fatalf("Expected 1 array record and 0 rows, got %d and %d", len(writer.multiRecords), len(writer.dpoRecords))
+	}
+	record := writer.multiRecords[0]
+	if len(record.Rejected) != 3 || record.Rejected[2] != "rejected three" {
+		t.Errorf("Expected 3 rejected entries in order, got %v", record.Rejected)
+	}
+	if len(record.RejectedModels) != 0 {
+		t.Errorf("Expected no rejected_models without record_model_assignment, got %v", record.RejectedModels)
+	}
+	if len(writer.multiReasoning[0]) != 3 || writer.multiReasoning[0][0] != "thinking one" {
+		t.Errorf("Expected reasoning aligned with rejected entries, got %v", writer.multiReasoning[0])
+	}
+}
+
+func TestWriteKTORecordMultiRejected(t *testing.T) {
+	writer := &preferenceWriter{}
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				DatasetMode: models.DatasetModeKTO,
+				NumRejected: 3,
+			},
+		},
+		dataWriter: writer,
+	}
+
+	if err := orch.writeKTORecord(multiRejectedResult()); err != nil {
+		t.Fatalf("writeKTORecord returned error: %v", err)
+	}
+
+	if len(writer.ktoRecords) != 4 {
+		t.Fatalf("Expected 4 KTO rows (1 chosen + 3 rejected), got %d", len(writer.ktoRecords))
+	}
+	if !writer.ktoRecords[0].Label {
+		t.Errorf("Expected first KTO row to be the chosen completion")
+	}
+	for i, record := range writer.ktoRecords[1:] {
+		if record.Label {
+			t.Errorf("Expected rejected row %d to have label false", i)
+		}
+	}
+}
+
+func TestWriteMultiRowJob_InvalidRowWritesNothing(t *testing.T) {
+	for _, mode := range []models.DatasetMode{models.DatasetModeKTO, models.DatasetModeDPO} {
+		t.Run(string(mode), func(t *testing.T) {
+			writer := &preferenceWriter{}
+			orch := &Orchestrator{
+				cfg: &config.Config{
+					Generation: config.GenerationConfig{
+						DatasetMode: mode,
+						NumRejected: 3,
+					},
+				},
+				dataWriter: writer,
+			}
+
+			result := multiRejectedResult()
+			result.RejectedList[2].Content = "   "
+
+			var err error
+			if mode == models.DatasetModeKTO {
+				err = orch.writeKTORecord(result)
+			} else {
+				err = orch.writeDPORecord(result)
+			}
+			if !errors.Is(err, api.ErrInvalidRecord) {
+				t.Fatalf("Expected ErrInvalidRecord, got %v", err)
+			}
+			if got := len(writer.ktoRecords) + len(writer.dpoRecords); got != 0 {
+				t.Errorf("Expected no rows written for a job with an invalid row, got %d", got)
+			}
+		})
+	}
+}
+
+func TestWriteDPORecordConversational(t *testing.T) {
+	writer := &preferenceWriter{}
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				DatasetMode:           models.DatasetModeDPO,
+				DPOFormat:             models.DPOFormatConversational,
+				NumRejected:           3,
+				RejectedOutputShape:   models.RejectedShapeRows,
+				RecordModelAssignment: true,
+			},
+			PromptTemplates: config.PromptTemplates{ChosenSystemPrompt: "be helpful"},
+		},
+		dataWriter: writer,
+	}
+
+	if err := orch.writeDPORecord(multiRejectedResult()); err != nil {
+		t.Fatalf("writeDPORecord returned error: %v", err)
+	}
+
+	if len(writer.convRecords) != 3 || len(writer.dpoRecords) != 0 {
+		t.Fatalf("Expected 3 conversational rows and no plain rows, got %d and %d", len(writer.convRecords), len(writer.dpoRecords))
+	}
+	record := writer.convRecords[1]
+	if len(record.Prompt) != 2 || record.Prompt[0].Role != "system" || record.Prompt[0].Content != "be helpful" ||
+		record.Prompt[1].Role != "user" || record.Prompt[1].Content != "prompt" {
+		t.Errorf("Expected system + user prompt messages, got %+v", record.Prompt)
+	}
+	if len(record.Chosen) != 1 || record.Chosen[0].Role != "assistant" || record.Chosen[0].Content != "chosen" {
+		t.Errorf("Expected a single assistant chosen message, got %+v", record.Chosen)
+	}
+	if len(record.Rejected) != 1 || record.Rejected[0].Content != "rejected two" {
+		t.Errorf("Expected rejected candidate two as an assistant message, got %+v", record.Rejected)
+	}
+	if record.ChosenModel != "main-model" || record.RejectedModel != "weak-model" {
+		t.Errorf("Expected model assignment columns, got %q and %q", record.ChosenModel, record.RejectedModel)
+	}
+	if writer.rejectedReasoning[0] != "thinking one" {
+		t.Errorf("Expected per-candidate reasoning, got %v", writer.rejectedReasoning)
+	}
+}
+
+func TestWriteRecordSystemPrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    models.DatasetMode
+		shape   models.RejectedShape
+		include bool
+		want    string
+	}{
+		{"dpo rows", models.DatasetModeDPO, models.RejectedShapeRows, true, "be helpful"},
+		{"dpo array", models.DatasetModeDPO, models.RejectedShapeArray, true, "be helpful"},
+		{"kto", models.DatasetModeKTO, models.RejectedShapeRows, true, "be helpful"},
+		{"off by default", models.DatasetModeDPO, models.RejectedShapeRows, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &preferenceWriter{}
+			orch := &Orchestrator{
+				cfg: &config.Config{
+					Generation: config.GenerationConfig{
+						DatasetMode:         tt.mode,
+						NumRejected:         3,
+						RejectedOutputShape: tt.shape,
+						IncludeSystemPrompt: tt.include,
+					},
+					PromptTemplates: config.PromptTemplates{ChosenSystemPrompt: "be helpful"},
+				},
+				dataWriter: writer,
+			}
+
+			if err := orch.writeRecordByMode(multiRejectedResult()); err != nil {
+				t.Fatalf("writeRecordByMode returned error: %v", err)
+			}
+
+			var systems []string
+			for _, record := range writer.dpoRecords {
+				systems = append(systems, record.System)
+			}
+			for _, record := range writer.multiRecords {
+				systems = append(systems, record.System)
+			}
+			for _, record := range writer.ktoRecords {
+				systems = append(systems, record.System)
+			}
+			if len(systems) == 0 {
+				t.Fatal("Expected records to be written")
+			}
+			for i, system := range systems {
+				if system != tt.want {
+					t.Errorf("Expected row %d system %q, got %q", i, tt.want, system)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteJobRows(t *testing.T) {
+	skip := func() error { return writer.ErrRecordSkipped }
+	ok := func() error { return nil }
+	failed := errors.New("disk full")
+	fail := func() error { return failed }
+
+	tests := []struct {
+		name string
+		rows []func() error
+		want error
+	}{
+		{"all written", []func() error{ok, ok}, nil},
+		{"some skipped", []func() error{skip, ok}, nil},
+		{"all skipped", []func() error{skip, skip}, writer.ErrRecordSkipped},
+		{"failure after skip", []func() error{skip, fail, ok}, failed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := writeJobRows(tt.rows); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
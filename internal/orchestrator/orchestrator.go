@@ -3,10 +3,13 @@ package orchestrator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
@@ -14,7 +17,10 @@ import (
 	"github.com/lamim/vellumforge2/internal/api"
 	"github.com/lamim/vellumforge2/internal/checkpoint"
 	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/embeddings"
 	"github.com/lamim/vellumforge2/internal/judge"
+	"github.com/lamim/vellumforge2/internal/metrics"
+	"github.com/lamim/vellumforge2/internal/reward"
 	"github.com/lamim/vellumforge2/internal/util"
 	"github.com/lamim/vellumforge2/internal/writer"
 	"github.com/lamim/vellumforge2/pkg/models"
@@ -27,10 +33,15 @@ const (
 	judgeUpdateBufferSize = 100
 )
 
+// ErrMaxRuntimeExceeded is returned by Run when generation.max_runtime stops the run
+// The checkpoint is flushed first, so the session can be resumed like an interrupted run
+var ErrMaxRuntimeExceeded = errors.New("max_runtime budget exceeded")
+
 // judgeUpdate represents an async judge result update
 type judgeUpdate struct {
 	recordIndex int
 	judgeResult *models.JudgeResult
+	failed      bool // Judge evaluation failed; flag the record instead of scoring it
 }
 
 // subtopicTask represents a subtopic prompt generation task
@@ -58,11 +69,35 @@ type Orchestrator struct {
 	stats         *models.SessionStats
 	checkpointMgr *checkpoint.Manager
 	resumeMode    bool
-	ctx           context.Context // Main context for cancellation propagation
+	ctx           context.Context         // Main context for cancellation propagation
+	cancelRun     context.CancelCauseFunc // Aborts the run with a cause (judge circuit breaker)
+	stopDispatch  context.CancelFunc      // Stops workers taking new jobs while in-flight ones finish (max_output_bytes)
+	outputCapped  bool                    // max_output_bytes was reached in this run (collector goroutine only)
 	// Non-blocking judge support
-	judgeUpdates   chan judgeUpdate
-	pendingJudges  sync.WaitGroup
-	judgeSemaphore chan struct{} // Limit concurrent judge goroutines
+	judgeUpdates     chan judgeUpdate
+	pendingJudges    sync.WaitGroup
+	judgeSemaphore   chan struct{}      // Limit concurrent judge goroutines
+	judgeBreaker     *judgeBreaker      // Trips after consecutive judge failures (MO-DPO)
+	promptCache      *promptCache       // Optional on-disk prompt cache (nil = disabled)
+	embedder         *embeddings.Client // Optional prompt diversity filter ([embeddings], nil = disabled)
+	rewardModel      *reward.Client     // Optional reward model filter ([reward_model], nil = disabled)
+	subtopicWeights  map[string]int     // Prompt weight per lowercased subtopic (weighted_subtopics, missing = 1)
+	reasoningWarned  sync.Map           // Model names already warned about missing reasoning content
+	mainFailover     *api.Failover      // Switches chosen generation to models.main_fallback (nil = no fallback)
+	rejectedFailover *api.Failover      // Switches rejected generation to its fallback (nil = no fallback)
+
+	// JSON reformat retry counters (updated concurrently by prompt workers, synced into stats)
+	reformatAttempts  atomic.Int64
+	reformatSuccesses atomic.Int64
+
+	// Truncation retry counters (updated concurrently by job workers, synced into stats)
+	truncationRetries    atomic.Int64
+	truncationRecoveries atomic.Int64
+
+	timer phaseTimer // Per-phase timings for throughput reports
+
+	metrics    *metrics.Collector // Optional Prometheus metrics (nil = disabled)
+	failureLog *writer.FailureLog // Optional failed-job log (generation.keep_failed, nil = disabled)
 }
 
 // New creates a new orchestrator
@@ -104,20 +139,89 @@ func New(
 		resumeMode:    resumeMode,
 	}
 
+	if fallback, ok := cfg.FallbackModel("main"); ok {
+		o.mainFailover = api.NewFailover("main", fallback, cfg.Generation.FallbackAfterFailures, logger)
+	}
+	if fallback, ok := cfg.FallbackModel("rejected"); ok {
+		o.rejectedFailover = api.NewFailover("rejected", fallback, cfg.Generation.FallbackAfterFailures, logger)
+	}
+
+	if judgeModule != nil {
+		judgeModule.RestoreCacheStats(stats.JudgeCacheHits, stats.JudgeCacheMisses)
+	}
+	o.reformatAttempts.Store(int64(stats.JSONReformatAttempts))
+	o.reformatSuccesses.Store(int64(stats.JSONReformatSuccesses))
+	o.truncationRetries.Store(int64(stats.TruncationRetries))
+	o.truncationRecoveries.Store(int64(stats.TruncationRecoveries))
+
+	// Initialize optional prompt cache (failure to set it up is not fatal)
+	if cfg.Generation.EnablePromptCache {
+		ttl := time.Duration(cfg.Generation.PromptCacheTTLHours) * time.Hour
+		cache, err := newPromptCache(cfg.Generation.PromptCacheDir, ttl,
+			cfg.PromptTemplates.PromptGeneration, cfg.PromptTemplates.PromptSystemPrompt,
+			cfg.Generation.NumPromptsPerSubtopic, logger)
+		if err != nil {
+			logger.Warn("Prompt cache disabled", "error", err)
+		} else {
+			o.promptCache = cache
+			logger.Info("Prompt cache enabled", "dir", cfg.Generation.PromptCacheDir, "ttl", ttl)
+		}
+	}
+
+	// Optional embedding-based prompt diversity filtering
+	if cfg.Embeddings.Enabled {
+		o.embedder = embeddings.New(cfg.Embeddings, apiClient, secrets.GetAPIKey(cfg.Embeddings.BaseURL))
+		logger.Info("Prompt diversity filtering enabled",
+			"model", cfg.Embeddings.ModelName,
+			"similarity_threshold", cfg.Embeddings.Threshold())
+	}
+
+	// Optional reward model filtering (alternative to judge filtering)
+	if cfg.RewardModel.Enabled {
+		o.rewardModel = reward.New(cfg.RewardModel, apiClient, secrets.GetAPIKey(cfg.RewardModel.BaseURL))
+		logger.Info("Reward model filtering enabled",
+			"endpoint", cfg.RewardModel.BaseURL,
+			"on_error", cfg.RewardModel.OnError)
+	}
+
 	// Initialize non-blocking judge support if judge is enabled
 	if judgeModule != nil {
 		o.judgeUpdates = make(chan judgeUpdate, judgeUpdateBufferSize)
 		// Judge semaphore scales with main concurrency to prevent bottleneck
 		o.judgeSemaphore = make(chan struct{}, cfg.Generation.Concurrency)
+		o.judgeBreaker = newJudgeBreaker(cfg.Generation.JudgeFailureThreshold, stats.JudgeSuccesses, stats.JudgeFailures)
 	}
 
 	return o
 }
 
+// SetMetrics records written, filtered and failed rows to collector
+func (o *Orchestrator) SetMetrics(collector *metrics.Collector) {
+	o.metrics = collector
+}
+
+// SetFailureLog writes every failed job's prompt and error to log
+func (o *Orchestrator) SetFailureLog(log *writer.FailureLog) {
+	o.failureLog = log
+}
+
 // Run executes the complete generation pipeline
 func (o *Orchestrator) Run(ctx context.Context) error {
+	// Apply the wall-clock budget on top of the caller's (signal-aware) context
+	if maxRuntime := o.cfg.Generation.MaxRuntimeDuration(); maxRuntime > 0 {
+		var cancelBudget context.CancelFunc
+		ctx, cancelBudget = context.WithTimeoutCause(ctx, maxRuntime, ErrMaxRuntimeExceeded)
+		defer cancelBudget()
+		o.logger.Info("Run time budget enabled", "max_runtime", maxRuntime)
+	}
+
 	// Store context for judge goroutines to respect cancellation
+	// The judge circuit breaker, failure rate monitor and fingerprint watch cancel it with their own cause
+	ctx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
 	o.ctx = ctx
+	o.cancelRun = cancelRun
+	o.watchFingerprints()
 
 	var checkpointCloseErr error
 
@@ -140,90 +244,56 @@ func (o *Orchestrator) Run(ctx context.Context) error {
 		}
 	}()
 
+	topicAttr := slog.String("main_topic", o.cfg.Generation.MainTopic)
+	if o.cfg.Generation.MultiTopic() {
+		topicAttr = slog.Any("main_topics", o.cfg.Generation.MainTopics)
+	}
 	o.logger.Info("Starting generation pipeline",
-		"main_topic", o.cfg.Generation.MainTopic,
+		topicAttr,
 		"num_subtopics", o.cfg.Generation.NumSubtopics,
 		"prompts_per_subtopic", o.cfg.Generation.NumPromptsPerSubtopic,
 		"resume_mode", o.resumeMode)
 
 	// Phase 1: Generate subtopics
-	var subtopics []string
-	var err error
-
-	if o.resumeMode && o.checkpointMgr != nil {
-		cp := o.checkpointMgr.GetCheckpoint()
-		if cp.SubtopicsComplete {
-			subtopics = cp.Subtopics
-			o.logger.Info("Resuming from checkpoint: subtopics phase complete", "count", len(subtopics))
-		} else {
-			subtopics, err = o.generateSubtopics(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to generate subtopics: %w", err)
-			}
-			if o.checkpointMgr != nil {
-				if err := o.checkpointMgr.MarkSubtopicsComplete(subtopics); err != nil {
-					o.logger.Warn("Failed to save subtopics checkpoint", "error", err)
-				}
-			}
-		}
-	} else {
-		subtopics, err = o.generateSubtopics(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to generate subtopics: %w", err)
-		}
-		if o.checkpointMgr != nil {
-			if err := o.checkpointMgr.MarkSubtopicsComplete(subtopics); err != nil {
-				o.logger.Warn("Failed to save subtopics checkpoint", "error", err)
-			}
+	phaseStart := time.Now()
+	topicGroups, err := o.runSubtopicPhase(ctx)
+	if err != nil {
+		if budgetErr := o.maxRuntimeErr(ctx); budgetErr != nil {
+			return budgetErr
 		}
+		return fmt.Errorf("failed to generate subtopics: %w", err)
 	}
+	subtopics := flattenSubtopics(topicGroups)
 
+	o.timer.subtopics = time.Since(phaseStart)
 	o.logger.Info("Generated subtopics", "count", len(subtopics))
 
 	// Validate subtopic count
-	if len(subtopics) != o.cfg.Generation.NumSubtopics {
+	expectedSubtopics := o.cfg.Generation.NumSubtopics * len(topicGroups)
+	if len(subtopics) != expectedSubtopics {
 		o.logger.Warn("Subtopic count mismatch",
-			"expected", o.cfg.Generation.NumSubtopics,
+			"expected", expectedSubtopics,
 			"actual", len(subtopics),
-			"difference", len(subtopics)-o.cfg.Generation.NumSubtopics)
+			"difference", len(subtopics)-expectedSubtopics)
 	}
 
 	// Phase 2: Generate prompts for each subtopic
-	var prompts []models.GenerationJob
-
-	if o.resumeMode && o.checkpointMgr != nil {
-		cp := o.checkpointMgr.GetCheckpoint()
-		if cp.PromptsComplete {
-			prompts = cp.Prompts
-			o.logger.Info("Resuming from checkpoint: prompts phase complete", "count", len(prompts))
-		} else {
-			prompts, err = o.generatePrompts(ctx, subtopics)
-			if err != nil {
-				return fmt.Errorf("failed to generate prompts: %w", err)
-			}
-			if o.checkpointMgr != nil {
-				if err := o.checkpointMgr.MarkPromptsComplete(prompts); err != nil {
-					o.logger.Warn("Failed to save prompts checkpoint", "error", err)
-				}
-			}
-		}
-	} else {
-		prompts, err = o.generatePrompts(ctx, subtopics)
-		if err != nil {
-			return fmt.Errorf("failed to generate prompts: %w", err)
-		}
-		if o.checkpointMgr != nil {
-			if err := o.checkpointMgr.MarkPromptsComplete(prompts); err != nil {
-				o.logger.Warn("Failed to save prompts checkpoint", "error", err)
-			}
+	phaseStart = time.Now()
+	prompts, err := o.runPromptPhase(ctx, topicGroups)
+	if err != nil {
+		if budgetErr := o.maxRuntimeErr(ctx); budgetErr != nil {
+			return budgetErr
 		}
+		return fmt.Errorf("failed to generate prompts: %w", err)
 	}
 
+	o.timer.prompts = time.Since(phaseStart)
+	o.syncReformatStats()
 	o.logger.Info("Generated prompts", "count", len(prompts))
 	o.stats.TotalPrompts = len(prompts)
 
 	// Validate prompt count
-	expectedPrompts := len(subtopics) * o.cfg.Generation.NumPromptsPerSubtopic
+	expectedPrompts := o.expectedPromptCount(subtopics)
 	if len(prompts) != expectedPrompts {
 		o.logger.Warn("Prompt count mismatch",
 			"expected", expectedPrompts,
@@ -253,7 +323,13 @@ func (o *Orchestrator) Run(ctx context.Context) error {
 		go o.judgeUpdater(updaterCtx)
 	}
 
-	if err := o.generatePreferencePairs(ctx, pendingJobs, initialProgress); err != nil {
+	o.timer.totalJobs = len(prompts)
+	o.timer.doneBaseline = len(prompts) - len(pendingJobs)
+	o.timer.rowsBaseline = o.stats.SuccessCount
+	o.timer.pairsStart = time.Now()
+	err = o.generatePreferencePairs(ctx, pendingJobs, initialProgress)
+	o.timer.pairsEnd = time.Now()
+	if err != nil {
 		return fmt.Errorf("failed to generate preference pairs: %w", err)
 	}
 
@@ -263,6 +339,36 @@ func (o *Orchestrator) Run(ctx context.Context) error {
 		o.pendingJudges.Wait()
 		close(o.judgeUpdates)
 		o.logger.Info("All background judge evaluations complete")
+		o.syncJudgeStats()
+	}
+
+	// Judge circuit breaker aborted the run
+	if cause := context.Cause(ctx); errors.Is(cause, errJudgeUnavailable) {
+		o.logger.Error("Generation aborted: judge model unavailable",
+			"judge_successes", o.stats.JudgeSuccesses,
+			"judge_failures", o.stats.JudgeFailures)
+		return cause
+	}
+
+	// A model's backend configuration changed with on_fingerprint_change = "abort"
+	if cause := context.Cause(ctx); errors.Is(cause, errFingerprintChanged) {
+		o.syncFingerprintStats()
+		o.logger.Error("Generation aborted: system fingerprint changed",
+			"fingerprints", o.stats.SystemFingerprints)
+		return cause
+	}
+
+	// Failure rate monitor aborted the run; likely a broken config or provider
+	if cause := context.Cause(ctx); errors.Is(cause, errFailureRateExceeded) {
+		o.logger.Error("Generation aborted: job failure rate too high",
+			"failure_count", o.stats.FailureCount,
+			"max_failure_rate", o.cfg.Generation.MaxFailureRate)
+		return cause
+	}
+
+	// Time budget ran out; in-flight jobs were abandoned and stay pending in the checkpoint
+	if budgetErr := o.maxRuntimeErr(ctx); budgetErr != nil {
+		return budgetErr
 	}
 
 	// Check if context was canceled during generation
@@ -271,7 +377,19 @@ func (o *Orchestrator) Run(ctx context.Context) error {
 		return context.Canceled
 	}
 
+	// Size cap reached: the remaining jobs are skipped and the run finalizes as complete
+	if o.outputCapped {
+		o.logger.Info("Generation capped by max_output_bytes",
+			"max_output_bytes", o.cfg.Generation.MaxOutputBytes,
+			"bytes_written", o.dataWriter.BytesWritten(),
+			"completed", o.stats.SuccessCount,
+			"total_prompts", o.stats.TotalPrompts)
+	}
+
 	// Finalize stats
+	o.syncReformatStats()
+	o.syncTruncationStats()
+	o.syncFingerprintStats()
 	o.stats.EndTime = time.Now()
 	o.stats.TotalDuration = o.stats.EndTime.Sub(o.stats.StartTime)
 	if o.stats.SuccessCount > 0 {
@@ -297,7 +415,35 @@ func (o *Orchestrator) Run(ctx context.Context) error {
 		failureRate := float64(o.stats.FailureCount) / float64(o.stats.TotalPrompts) * 100
 		o.logger.Warn("Generation completed with failures",
 			"failure_rate", fmt.Sprintf("%.2f%%", failureRate),
-			"lost_rows", o.stats.FailureCount)
+			"lost_rows", o.stats.FailureCount,
+			"error_breakdown", o.stats.ErrorCounts)
+	}
+	if o.stats.JSONReformatAttempts > 0 {
+		o.logger.Info("JSON reformat retries",
+			"attempts", o.stats.JSONReformatAttempts,
+			"succeeded", o.stats.JSONReformatSuccesses)
+	}
+	if o.stats.TruncationRetries > 0 {
+		o.logger.Info("Truncation retries",
+			"attempts", o.stats.TruncationRetries,
+			"recovered", o.stats.TruncationRecoveries)
+	}
+	if o.stats.LengthRatioFiltered+o.stats.LengthRatioFlagged > 0 {
+		o.logger.Warn("Records with a chosen/rejected length ratio out of range",
+			"dropped", o.stats.LengthRatioFiltered,
+			"flagged", o.stats.LengthRatioFlagged,
+			"length_ratio_min", o.cfg.Generation.LengthRatioMin,
+			"length_ratio_max", o.cfg.Generation.LengthRatioMax)
+	}
+	if o.stats.JudgeCacheHits+o.stats.JudgeCacheMisses > 0 {
+		o.logger.Info("Judge cache",
+			"hits", o.stats.JudgeCacheHits,
+			"misses", o.stats.JudgeCacheMisses)
+	}
+	if o.judgeBreaker != nil && o.stats.JudgeFailures > 0 {
+		o.logger.Warn("Some records have no judge scores (flagged judge_failed)",
+			"judge_successes", o.stats.JudgeSuccesses,
+			"judge_failures", o.stats.JudgeFailures)
 	}
 
 	// Check for checkpoint save/close errors before returning
@@ -308,7 +454,8 @@ func (o *Orchestrator) Run(ctx context.Context) error {
 	return nil
 }
 
-func (o *Orchestrator) generateSubtopics(ctx context.Context) ([]string, error) {
+// generateSubtopics generates generation.num_subtopics subtopics of mainTopic
+func (o *Orchestrator) generateSubtopics(ctx context.Context, mainTopic string) ([]string, error) {
 	targetCount := o.cfg.Generation.NumSubtopics
 
 	// STRATEGY: Request extra based on configurable buffer to account for LLM undershoot and duplicates
@@ -324,7 +471,13 @@ func (o *Orchestrator) generateSubtopics(ctx context.Context) ([]string, error)
 	}
 
 	// Log strategy
-	if chunkSize < requestCount {
+	if o.cfg.Generation.AdaptiveChunking {
+		o.logger.Info("Generating subtopics with adaptive chunking and over-generation strategy",
+			"target", targetCount,
+			"requesting", requestCount,
+			"initial_chunk_size", chunkSize,
+			"buffer_percent", bufferPercent)
+	} else if chunkSize < requestCount {
 		o.logger.Info("Generating subtopics with chunking and over-generation strategy",
 			"target", targetCount,
 			"requesting", requestCount,
@@ -341,6 +494,14 @@ func (o *Orchestrator) generateSubtopics(ctx context.Context) ([]string, error)
 	// Request subtopics in chunks
 	var allSubtopics []string
 	remaining := requestCount
+	if o.cfg.Generation.AdaptiveChunking {
+		var err error
+		allSubtopics, err = o.requestSubtopicsAdaptive(ctx, mainTopic, requestCount, chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		remaining = 0
+	}
 
 	for remaining > 0 {
 		currentChunk := chunkSize
@@ -353,7 +514,7 @@ func (o *Orchestrator) generateSubtopics(ctx context.Context) ([]string, error)
 			"remaining", remaining,
 			"collected", len(allSubtopics))
 
-		chunkSubtopics, err := o.requestSubtopics(ctx, currentChunk, nil)
+		chunkSubtopics, err := o.requestSubtopics(ctx, mainTopic, currentChunk, nil)
 		if err != nil {
 			if len(allSubtopics) > 0 {
 				// Partial success - log warning and continue with what we have
@@ -372,7 +533,7 @@ func (o *Orchestrator) generateSubtopics(ctx context.Context) ([]string, error)
 	subtopics := allSubtopics
 
 	// Deduplicate
-	uniqueSubtopics := deduplicateStrings(subtopics)
+	uniqueSubtopics := o.deduplicateSubtopics(subtopics)
 
 	o.logger.Info("Initial subtopic generation complete",
 		"requested", requestCount,
@@ -396,7 +557,7 @@ func (o *Orchestrator) generateSubtopics(ctx context.Context) ([]string, error)
 		"shortage", shortage)
 
 	// Retry with exclusion list (but simpler prompt)
-	retrySubtopics, retryErr := o.requestSubtopics(ctx, shortage, uniqueSubtopics)
+	retrySubtopics, retryErr := o.requestSubtopics(ctx, mainTopic, shortage, uniqueSubtopics)
 	if retryErr != nil {
 		o.logger.Warn("Retry failed, proceeding with partial results", "error", retryErr)
 		return uniqueSubtopics, nil // Return what we have
@@ -404,7 +565,7 @@ func (o *Orchestrator) generateSubtopics(ctx context.Context) ([]string, error)
 
 	// Merge and deduplicate again
 	allSubtopics = append(uniqueSubtopics, retrySubtopics...)
-	finalUnique := deduplicateStrings(allSubtopics)
+	finalUnique := o.deduplicateSubtopics(allSubtopics)
 
 	o.logger.Info("Subtopic generation complete after retry",
 		"final_count", len(finalUnique),
@@ -429,14 +590,24 @@ func truncateExclusionList(items []string, maxSize int) ([]string, bool) {
 	return items[len(items)-maxSize:], true
 }
 
-// requestSubtopics makes a single API call for subtopics
+// requestSubtopics requests subtopics, re-asking when the model returns far fewer than count
+// (generation.undershoot_retry)
 // exclusionList is optional (nil on first call, populated on retry)
-func (o *Orchestrator) requestSubtopics(ctx context.Context, count int, exclusionList []string) ([]string, error) {
+func (o *Orchestrator) requestSubtopics(ctx context.Context, mainTopic string, count int, exclusionList []string) ([]string, error) {
+	return o.retryOnUndershoot(ctx, "subtopics", count, func() ([]string, error) {
+		return o.requestSubtopicsOnce(ctx, mainTopic, count, exclusionList)
+	})
+}
+
+// requestSubtopicsOnce makes a single API call for subtopics
+func (o *Orchestrator) requestSubtopicsOnce(ctx context.Context, mainTopic string, count int, exclusionList []string) ([]string, error) {
 	// Build template data
 	templateData := map[string]interface{}{
-		"MainTopic":    o.cfg.Generation.MainTopic,
+		"MainTopic":    mainTopic,
 		"NumSubtopics": count,
 		"IsRetry":      false, // Default to false
+		"Weighted":     o.cfg.Generation.WeightedSubtopics,
+		"MaxWeight":    o.cfg.Generation.MaxSubtopicWeight,
 	}
 
 	// Add exclusion list if present (for retry)
@@ -483,15 +654,18 @@ func (o *Orchestrator) requestSubtopics(ctx context.Context, count int, exclusio
 		Content: prompt,
 	})
 
-	resp, err := o.apiClient.ChatCompletionStructured(ctx, mainModel, apiKey, messages)
-	if err != nil {
-		return nil, err
+	// Weighted subtopics come back as [{"topic": "...", "weight": 3}]
+	weightKey := ""
+	itemSchema := stringItemSchema
+	if o.cfg.Generation.WeightedSubtopics {
+		weightKey = subtopicWeightKey
+		itemSchema = weightedSubtopicSchema
 	}
 
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("API returned empty response")
+	content, err := o.requestItems(ctx, mainModel, apiKey, messages, "Return the generated subtopics", itemSchema)
+	if err != nil {
+		return nil, err
 	}
-	content := resp.Choices[0].Message.Content
 	o.logger.Debug("Received subtopics response", "length", len(content))
 
 	// Extract and repair JSON
@@ -512,7 +686,11 @@ func (o *Orchestrator) requestSubtopics(ctx context.Context, count int, exclusio
 	}
 
 	// Attempt unmarshal with validation (with fallback to basic unmarshal)
-	subtopics, actualCount, err := ValidateStringArray(jsonStr, 1)
+	listOpts := o.listOptions(count, weightKey) // Coerces objects: models sometimes return [{"subtopic": "..."}]
+	subtopics, actualCount, err := ValidateStringArrayWithOptions(jsonStr, listOpts)
+	if err == nil && weightKey != "" {
+		o.recordSubtopicWeights(jsonStr)
+	}
 	if err != nil {
 		// Fallback: try basic unmarshal (old behavior)
 		o.logger.Warn("ValidateStringArray failed, trying basic unmarshal", "error", err)
@@ -523,7 +701,21 @@ func (o *Orchestrator) requestSubtopics(ctx context.Context, count int, exclusio
 				"unmarshal_error", unmarshalErr,
 				"extracted_json", util.TruncateString(jsonStr, 200),
 				"original_response", util.TruncateString(content, 200))
-			return nil, fmt.Errorf("failed to parse subtopics: %w (unmarshal also failed: %v)", err, unmarshalErr)
+			if !o.cfg.Generation.JSONReformatRetry {
+				return nil, fmt.Errorf("failed to parse subtopics: %w (unmarshal also failed: %v)", err, unmarshalErr)
+			}
+
+			// One-shot reformat retry: ask the model to fix its own output
+			o.logger.Info("Requesting JSON reformat for subtopics")
+			reformatted, reformatErr := o.reformatStringArray(ctx, mainModel, apiKey, content, listOpts)
+			if reformatErr != nil {
+				return nil, fmt.Errorf("failed to parse subtopics: %w (unmarshal also failed: %v; reformat retry failed: %v)", err, unmarshalErr, reformatErr)
+			}
+			o.logger.Info("JSON reformat succeeded for subtopics", "count", len(reformatted))
+			basicSubtopics = reformatted
+		}
+		if countErr := listOpts.checkCount(len(basicSubtopics)); countErr != nil {
+			return nil, fmt.Errorf("failed to parse subtopics: %w", countErr)
 		}
 		subtopics = basicSubtopics
 		actualCount = len(basicSubtopics)
@@ -537,15 +729,35 @@ func (o *Orchestrator) requestSubtopics(ctx context.Context, count int, exclusio
 	return subtopics, nil
 }
 
-func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string) ([]models.GenerationJob, error) {
+// listOptions returns the subtopic/prompt list limits for a request of requested items:
+// at least generation.list_min_items (capped at requested) and, with list_max_ratio set,
+// at most that multiple of requested
+func (o *Orchestrator) listOptions(requested int, weightKey string) StringArrayOptions {
+	minCount := max(o.cfg.Generation.ListMinItems, 1)
+	if requested > 0 {
+		minCount = max(min(minCount, requested), 1)
+	}
+	opts := StringArrayOptions{
+		MinCount:      minCount,
+		CoerceObjects: true,
+		WeightKey:     weightKey,
+	}
+	if ratio := o.cfg.Generation.ListMaxRatio; ratio > 0 && requested > 0 {
+		opts.MaxCount = max(int(math.Ceil(float64(requested)*ratio)), opts.MinCount)
+	}
+	return opts
+}
+
+// generatePrompts generates the prompts of mainTopic's subtopics, numbering jobs from firstJobID
+func (o *Orchestrator) generatePrompts(ctx context.Context, mainTopic string, subtopics []string, firstJobID int) ([]models.GenerationJob, error) {
 	// Calculate optimal worker count for prompt generation phase
 	// Cap workers to avoid overwhelming rate limits with too many concurrent requests
 	mainModel := o.cfg.Models["main"]
 	effectiveRPM, burstCapacity, usingProviderLimit := o.apiClient.GetEffectiveRateLimit(mainModel)
 
 	// Cap workers at burst capacity to prevent rate limit exhaustion
-	// Use min3(subtopics, burstCapacity, concurrency) for optimal scaling
-	promptWorkers := min3(len(subtopics), burstCapacity, o.cfg.Generation.Concurrency)
+	// Use min3(subtopics, burstCapacity, prompt_concurrency) for optimal scaling
+	promptWorkers := min3(len(subtopics), burstCapacity, o.cfg.Generation.PromptWorkers())
 
 	// Log worker calculation reasoning
 	limitType := "model"
@@ -554,6 +766,8 @@ func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string)
 	}
 	o.logger.Info("Calculating optimal workers for prompt generation",
 		"total_subtopics", len(subtopics),
+		"prompt_samples", max(1, o.cfg.Generation.PromptSamples),
+		"prompt_concurrency", o.cfg.Generation.PromptWorkers(),
 		"phase3_concurrency", o.cfg.Generation.Concurrency,
 		"effective_rate_limit", effectiveRPM,
 		"burst_capacity", burstCapacity,
@@ -564,14 +778,14 @@ func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string)
 	tasksChan := make(chan subtopicTask, len(subtopics))
 	resultsChan := make(chan promptResult, len(subtopics))
 
-	// Start workers with calculated optimal count
+	// Start workers with calculated optimal count, staggered over generation.warmup_seconds
 	var wg sync.WaitGroup
 	wg.Add(promptWorkers)
-	for i := 0; i < promptWorkers; i++ {
-		go func(workerID int) {
+	o.startStaggered(ctx, promptWorkers, o.cfg.Generation.Warmup(), func(int) {
+		go func() {
 			defer wg.Done()
 			for task := range tasksChan {
-				prompts, err := o.generatePromptsForSubtopic(ctx, task.subtopic)
+				prompts, err := o.generatePromptsForSubtopic(ctx, mainTopic, task.subtopic)
 				resultsChan <- promptResult{
 					index:    task.index,
 					subtopic: task.subtopic,
@@ -579,8 +793,8 @@ func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string)
 					err:      err,
 				}
 			}
-		}(i)
-	}
+		}()
+	})
 
 	// Send tasks
 	for i, subtopic := range subtopics {
@@ -643,7 +857,7 @@ func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string)
 			}
 
 			// Retry failed subtopics
-			retryResults := o.retryFailedSubtopics(ctx, failedSubtopics, failedIndices, promptWorkers)
+			retryResults := o.retryFailedSubtopics(ctx, mainTopic, failedSubtopics, failedIndices, promptWorkers)
 
 			// Update results with successful retries
 			var stillFailing []string
@@ -692,7 +906,6 @@ func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string)
 	// Build jobs from successful results (skip failed subtopics)
 	var allJobs []models.GenerationJob
 	var skippedSubtopics []string
-	jobID := 0
 
 	for i := 0; i < len(subtopics); i++ {
 		result, ok := results[i]
@@ -704,15 +917,19 @@ func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string)
 
 		for _, p := range result.prompts {
 			allJobs = append(allJobs, models.GenerationJob{
-				ID:        jobID,
-				MainTopic: o.cfg.Generation.MainTopic,
+				MainTopic: mainTopic,
 				SubTopic:  result.subtopic,
-				Prompt:    p,
+				Prompt:    o.normalizeText(p),
 			})
-			jobID++
 		}
 	}
 
+	// Job IDs are assigned once near-duplicates are gone so they stay contiguous
+	allJobs = o.filterSimilarPrompts(ctx, allJobs)
+	for i := range allJobs {
+		allJobs[i].ID = firstJobID + i
+	}
+
 	// Save partial progress to checkpoint with failure tracking
 	if o.checkpointMgr != nil && len(skippedSubtopics) > 0 {
 		cp := o.checkpointMgr.GetCheckpoint()
@@ -731,7 +948,7 @@ func (o *Orchestrator) generatePrompts(ctx context.Context, subtopics []string)
 }
 
 // retryFailedSubtopics retries prompt generation for failed subtopics
-func (o *Orchestrator) retryFailedSubtopics(ctx context.Context, failedSubtopics []string, failedIndices map[int]string, workers int) map[int]promptResult {
+func (o *Orchestrator) retryFailedSubtopics(ctx context.Context, mainTopic string, failedSubtopics []string, failedIndices map[int]string, workers int) map[int]promptResult {
 	tasksChan := make(chan subtopicTask, len(failedSubtopics))
 	resultsChan := make(chan promptResult, len(failedSubtopics))
 
@@ -742,7 +959,7 @@ func (o *Orchestrator) retryFailedSubtopics(ctx context.Context, failedSubtopics
 		go func(workerID int) {
 			defer wg.Done()
 			for task := range tasksChan {
-				prompts, err := o.generatePromptsForSubtopic(ctx, task.subtopic)
+				prompts, err := o.generatePromptsForSubtopic(ctx, mainTopic, task.subtopic)
 				resultsChan <- promptResult{
 					index:    task.index,
 					subtopic: task.subtopic,
@@ -779,19 +996,71 @@ func (o *Orchestrator) retryFailedSubtopics(ctx context.Context, failedSubtopics
 	return retryResults
 }
 
+// sanitizePrompts strips reasoning tags from generated prompts and drops prompts left empty
+func sanitizePrompts(prompts []string, subtopic string, logger *slog.Logger) []string {
+	sanitized := prompts[:0]
+	stripped := 0
+	for _, prompt := range prompts {
+		clean := util.StripReasoningFromPrompt(prompt)
+		if clean != prompt {
+			stripped++
+		}
+		if clean != "" {
+			sanitized = append(sanitized, clean)
+		}
+	}
+	if stripped > 0 {
+		logger.Warn("Stripped reasoning tags from generated prompts",
+			"subtopic", subtopic,
+			"stripped", stripped,
+			"dropped", len(prompts)-len(sanitized))
+	}
+	return sanitized
+}
+
 // generatePromptsForSubtopic generates prompts for a single subtopic
-func (o *Orchestrator) generatePromptsForSubtopic(ctx context.Context, subtopic string) ([]string, error) {
+func (o *Orchestrator) generatePromptsForSubtopic(ctx context.Context, mainTopic, subtopic string) ([]string, error) {
+	// Reuse cached prompts for this subtopic + template if available
+	cacheKey := o.promptCacheKey(mainTopic, subtopic)
+	if o.promptCache != nil {
+		if prompts, ok := o.promptCache.Get(cacheKey); ok {
+			o.logger.Debug("Using cached prompts", "subtopic", subtopic, "count", len(prompts))
+			return prompts, nil
+		}
+	}
+
+	prompts, err := o.generateSubtopicPrompts(ctx, mainTopic, subtopic, o.promptsForSubtopic(subtopic))
+	if err != nil {
+		return nil, err
+	}
+
+	// Prompts should never carry reasoning; strip any the model echoed back
+	if !o.cfg.Generation.PreservePromptReasoning {
+		prompts = sanitizePrompts(prompts, subtopic, o.logger)
+	}
+
+	if o.promptCache != nil && len(prompts) > 0 {
+		if err := o.promptCache.Put(cacheKey, prompts); err != nil {
+			o.logger.Warn("Failed to cache prompts", "subtopic", subtopic, "error", err)
+		}
+	}
+
+	return prompts, nil
+}
+
+// requestPrompts makes a single API call to mainModel for count prompts for a subtopic
+func (o *Orchestrator) requestPrompts(ctx context.Context, mainModel config.ModelConfig, mainTopic, subtopic string, count int) ([]string, error) {
 	// Render template
 	prompt, err := util.RenderTemplate(o.cfg.PromptTemplates.PromptGeneration, map[string]interface{}{
+		"MainTopic":  mainTopic,
 		"SubTopic":   subtopic,
-		"NumPrompts": o.cfg.Generation.NumPromptsPerSubtopic,
+		"NumPrompts": count,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to render prompt template: %w", err)
 	}
 
 	// Call API
-	mainModel := o.cfg.Models["main"]
 	apiKey := o.secrets.GetAPIKey(mainModel.BaseURL)
 
 	// Build messages with optional system prompt
@@ -807,17 +1076,11 @@ func (o *Orchestrator) generatePromptsForSubtopic(ctx context.Context, subtopic
 		Content: prompt,
 	})
 
-	resp, err := o.apiClient.ChatCompletionStructured(ctx, mainModel, apiKey, messages)
+	content, err := o.requestItems(ctx, mainModel, apiKey, messages, "Return the generated prompts", stringItemSchema)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("API returned empty response")
-	}
-	// Parse JSON response
-	content := resp.Choices[0].Message.Content
-
 	o.logger.Debug("Received prompts response", "subtopic", subtopic, "length", len(content))
 
 	// Extract JSON from potential markdown code blocks
@@ -841,7 +1104,8 @@ func (o *Orchestrator) generatePromptsForSubtopic(ctx context.Context, subtopic
 	}
 
 	// Attempt unmarshal with validation (with fallback to basic unmarshal)
-	prompts, actualCount, err := ValidateStringArray(jsonStr, 1)
+	listOpts := o.listOptions(count, "") // Coerces objects: models sometimes return [{"prompt": "..."}]
+	prompts, actualCount, err := ValidateStringArrayWithOptions(jsonStr, listOpts)
 	if err != nil {
 		// Fallback: try basic unmarshal (old behavior)
 		o.logger.Warn("ValidateStringArray failed for prompts, trying basic unmarshal",
@@ -855,7 +1119,21 @@ func (o *Orchestrator) generatePromptsForSubtopic(ctx context.Context, subtopic
 				"subtopic", subtopic,
 				"extracted_json", util.TruncateString(jsonStr, 200),
 				"original_response", util.TruncateString(content, 200))
-			return nil, fmt.Errorf("failed to parse prompts for subtopic %q: %w (unmarshal also failed: %v)", subtopic, err, unmarshalErr)
+			if !o.cfg.Generation.JSONReformatRetry {
+				return nil, fmt.Errorf("failed to parse prompts for subtopic %q: %w (unmarshal also failed: %v)", subtopic, err, unmarshalErr)
+			}
+
+			// One-shot reformat retry: ask the model to fix its own output
+			o.logger.Info("Requesting JSON reformat for prompts", "subtopic", subtopic)
+			reformatted, reformatErr := o.reformatStringArray(ctx, mainModel, apiKey, content, listOpts)
+			if reformatErr != nil {
+				return nil, fmt.Errorf("failed to parse prompts for subtopic %q: %w (unmarshal also failed: %v; reformat retry failed: %v)", subtopic, err, unmarshalErr, reformatErr)
+			}
+			o.logger.Info("JSON reformat succeeded for prompts", "subtopic", subtopic, "count", len(reformatted))
+			basicPrompts = reformatted
+		}
+		if countErr := listOpts.checkCount(len(basicPrompts)); countErr != nil {
+			return nil, fmt.Errorf("failed to parse prompts for subtopic %q: %w", subtopic, countErr)
 		}
 		prompts = basicPrompts
 		actualCount = len(basicPrompts)
@@ -874,12 +1152,17 @@ func (o *Orchestrator) generatePreferencePairs(ctx context.Context, jobs []model
 	jobsChan := make(chan models.GenerationJob, len(jobs))
 	resultsChan := make(chan models.GenerationResult, len(jobs))
 
-	// Start workers
+	// Start workers; jobs already running when ctx is done get a short grace period to finish
+	// Reaching max_output_bytes only stops dispatch: in-flight jobs keep running on jobCtx
+	jobCtx, releaseJobs := o.jobContext(ctx, o.cfg.Generation.ShutdownGrace())
+	dispatchCtx, stopDispatch := context.WithCancel(ctx)
+	defer stopDispatch()
+	o.stopDispatch = stopDispatch
 	var wg sync.WaitGroup
 	wg.Add(o.cfg.Generation.Concurrency) // Add all workers before starting goroutines
-	for i := 0; i < o.cfg.Generation.Concurrency; i++ {
-		go o.worker(ctx, i, jobsChan, resultsChan, &wg)
-	}
+	o.startStaggered(dispatchCtx, o.cfg.Generation.Concurrency, o.cfg.Generation.Warmup(), func(i int) {
+		go o.worker(dispatchCtx, jobCtx, i, jobsChan, resultsChan, &wg)
+	})
 
 	// Send jobs
 	for _, job := range jobs {
@@ -887,13 +1170,18 @@ func (o *Orchestrator) generatePreferencePairs(ctx context.Context, jobs []model
 	}
 	close(jobsChan)
 
-	// Start result collector
+	// Start result collector (optionally reordering results by dispatch order)
+	var reorder *resultReorderer
+	if o.cfg.Generation.OrderedOutput {
+		reorder = newResultReorderer(jobs, o.cfg.Generation.OrderedOutputBuffer)
+	}
 	var collectorWg sync.WaitGroup
 	collectorWg.Add(1)
-	go o.collectResults(resultsChan, &collectorWg, initialProgress)
+	go o.collectResults(resultsChan, &collectorWg, initialProgress, reorder)
 
 	// Wait for workers to finish
 	wg.Wait()
+	releaseJobs()
 	close(resultsChan)
 
 	// Wait for collector to finish
@@ -902,6 +1190,19 @@ func (o *Orchestrator) generatePreferencePairs(ctx context.Context, jobs []model
 	return nil
 }
 
+// maxRuntimeErr returns ErrMaxRuntimeExceeded (and logs why the run stopped) once the
+// generation.max_runtime budget has expired, nil otherwise
+func (o *Orchestrator) maxRuntimeErr(ctx context.Context) error {
+	if !errors.Is(context.Cause(ctx), ErrMaxRuntimeExceeded) {
+		return nil
+	}
+	o.logger.Warn("Run stopped: max_runtime budget reached - checkpoint will be saved for resume",
+		"max_runtime", o.cfg.Generation.MaxRuntime,
+		"completed", o.stats.SuccessCount,
+		"total_prompts", o.stats.TotalPrompts)
+	return ErrMaxRuntimeExceeded
+}
+
 // GetStats returns the session statistics
 func (o *Orchestrator) GetStats() *models.SessionStats {
 	return o.stats
@@ -1,8 +1,12 @@
 package orchestrator
 
 import (
+	"io"
+	"log/slog"
 	"strings"
 	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
 )
 
 func TestValidateJSONArray(t *testing.T) {
@@ -172,6 +176,152 @@ func TestValidateStringArray(t *testing.T) {
 	}
 }
 
+func TestValidateStringArrayWithOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		opts      StringArrayOptions
+		wantItems []string
+		wantErr   string
+	}{
+		{
+			name:      "array of strings",
+			input:     `["a", "b", "c"]`,
+			opts:      StringArrayOptions{MinCount: 1, CoerceObjects: true},
+			wantItems: []string{"a", "b", "c"},
+		},
+		{
+			name:      "array of single-key objects",
+			input:     `[{"subtopic": "Dragons"}, {"subtopic": " Elves "}]`,
+			opts:      StringArrayOptions{MinCount: 1, CoerceObjects: true},
+			wantItems: []string{"Dragons", "Elves"},
+		},
+		{
+			name:      "mixed strings and objects",
+			input:     `["Dragons", {"prompt": "Write about elves"}, "", {"x": ""}]`,
+			opts:      StringArrayOptions{MinCount: 1, CoerceObjects: true},
+			wantItems: []string{"Dragons", "Write about elves"},
+		},
+		{
+			name:    "objects rejected without coercion",
+			input:   `[{"subtopic": "Dragons"}]`,
+			opts:    StringArrayOptions{MinCount: 1},
+			wantErr: "failed to unmarshal strings",
+		},
+		{
+			name:    "multi-key object",
+			input:   `[{"title": "Dragons", "summary": "Fire"}]`,
+			opts:    StringArrayOptions{CoerceObjects: true},
+			wantErr: "expected exactly 1",
+		},
+		{
+			name:    "object with non-string value",
+			input:   `[{"count": 3}]`,
+			opts:    StringArrayOptions{CoerceObjects: true},
+			wantErr: "does not hold a string value",
+		},
+		{
+			name:    "number element",
+			input:   `["a", 42]`,
+			opts:    StringArrayOptions{CoerceObjects: true},
+			wantErr: "element 1 is neither a string nor an object",
+		},
+		{
+			name:      "within max count",
+			input:     `["a", "b"]`,
+			opts:      StringArrayOptions{MinCount: 1, MaxCount: 2},
+			wantItems: []string{"a", "b"},
+		},
+		{
+			name:    "exceeds max count",
+			input:   `["a", "b", "c"]`,
+			opts:    StringArrayOptions{MaxCount: 2},
+			wantErr: "too many elements: got 3, expected at most 2",
+		},
+		{
+			name:      "weighted objects",
+			input:     `[{"topic": "Dragons", "weight": 3}, "Elves", {"weight": 2, "subtopic": "Orcs"}]`,
+			opts:      StringArrayOptions{MinCount: 1, CoerceObjects: true, WeightKey: "weight"},
+			wantItems: []string{"Dragons", "Elves", "Orcs"},
+		},
+		{
+			name:    "weighted objects without weight key",
+			input:   `[{"topic": "Dragons", "weight": 3}]`,
+			opts:    StringArrayOptions{CoerceObjects: true},
+			wantErr: "expected exactly 1",
+		},
+		{
+			name:    "below min count after coercion",
+			input:   `[{"subtopic": ""}, "a"]`,
+			opts:    StringArrayOptions{MinCount: 2, CoerceObjects: true},
+			wantErr: "insufficient elements: got 1, expected at least 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, count, err := ValidateStringArrayWithOptions(tt.input, tt.opts)
+
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if count != len(tt.wantItems) {
+				t.Errorf("Expected count %d, got %d", len(tt.wantItems), count)
+			}
+			if strings.Join(items, "|") != strings.Join(tt.wantItems, "|") {
+				t.Errorf("Expected items %v, got %v", tt.wantItems, items)
+			}
+		})
+	}
+}
+
+func TestListOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		minItems  int
+		maxRatio  float64
+		requested int
+		wantMin   int
+		wantMax   int
+	}{
+		{"defaults", 0, 0, 10, 1, 0},
+		{"min items", 3, 0, 10, 3, 0},
+		{"min items capped at requested", 5, 0, 2, 2, 0},
+		{"max ratio", 1, 1.5, 10, 1, 15},
+		{"max ratio rounds up", 1, 1.5, 3, 1, 5},
+		{"max never below min", 4, 1.0, 3, 3, 3},
+		{"unknown requested count", 2, 2.0, 0, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Orchestrator{cfg: &config.Config{Generation: config.GenerationConfig{
+				ListMinItems: tt.minItems,
+				ListMaxRatio: tt.maxRatio,
+			}}}
+			opts := o.listOptions(tt.requested, "weight")
+			if opts.MinCount != tt.wantMin {
+				t.Errorf("Expected MinCount %d, got %d", tt.wantMin, opts.MinCount)
+			}
+			if opts.MaxCount != tt.wantMax {
+				t.Errorf("Expected MaxCount %d, got %d", tt.wantMax, opts.MaxCount)
+			}
+			if !opts.CoerceObjects || opts.WeightKey != "weight" {
+				t.Errorf("Expected object coercion with weight key, got %+v", opts)
+			}
+		})
+	}
+}
+
 func TestDeduplicateStrings(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -268,3 +418,22 @@ func TestDeduplicateStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizePrompts(t *testing.T) {
+	input := []string{
+		"Write a story about dragons",
+		"<think>echoed reasoning</think>Write a poem about elves",
+		"<think>only reasoning</think>",
+		"leaked reasoning</think>Describe a castle",
+	}
+	want := []string{
+		"Write a story about dragons",
+		"Write a poem about elves",
+		"Describe a castle",
+	}
+
+	got := sanitizePrompts(input, "fantasy", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
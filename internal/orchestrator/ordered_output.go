@@ -0,0 +1,79 @@
+package orchestrator
+
+import "github.com/lamim/vellumforge2/pkg/models"
+
+// resultReorderer releases generation results in dispatch order (generation.ordered_output)
+// Out-of-order results wait in a bounded buffer; when a straggler fills it, the reorderer
+// spills past that job, which is then released as soon as it arrives
+type resultReorderer struct {
+	order   []int // Job IDs in dispatch order
+	next    int   // Index into order of the next result to release
+	pending map[int]models.GenerationResult
+	spilled map[int]bool // Jobs skipped past by a full buffer, released on arrival
+	limit   int
+}
+
+func newResultReorderer(jobs []models.GenerationJob, limit int) *resultReorderer {
+	order := make([]int, len(jobs))
+	for i, job := range jobs {
+		order[i] = job.ID
+	}
+	return &resultReorderer{
+		order:   order,
+		pending: make(map[int]models.GenerationResult),
+		spilled: make(map[int]bool),
+		limit:   max(limit, 1),
+	}
+}
+
+// Add buffers a result and returns the results that are now ready, in order, plus the job IDs
+// of stragglers that were spilled past because the buffer exceeded its limit
+func (r *resultReorderer) Add(result models.GenerationResult) (ready []models.GenerationResult, spilled []int) {
+	if r.spilled[result.Job.ID] {
+		delete(r.spilled, result.Job.ID)
+		return []models.GenerationResult{result}, nil
+	}
+
+	r.pending[result.Job.ID] = result
+	ready = r.release(ready)
+	for len(r.pending) > r.limit && r.next < len(r.order) {
+		straggler := r.order[r.next]
+		r.spilled[straggler] = true
+		spilled = append(spilled, straggler)
+		r.next++
+		ready = r.release(ready)
+	}
+	return ready, spilled
+}
+
+// release appends consecutive buffered results starting at next
+func (r *resultReorderer) release(ready []models.GenerationResult) []models.GenerationResult {
+	for r.next < len(r.order) {
+		result, ok := r.pending[r.order[r.next]]
+		if !ok {
+			break
+		}
+		ready = append(ready, result)
+		delete(r.pending, r.order[r.next])
+		r.next++
+	}
+	return ready
+}
+
+// Drain returns the remaining buffered results in dispatch order
+// Used when the run stops early and some earlier jobs will never report
+func (r *resultReorderer) Drain() []models.GenerationResult {
+	var ready []models.GenerationResult
+	for ; r.next < len(r.order) && len(r.pending) > 0; r.next++ {
+		if result, ok := r.pending[r.order[r.next]]; ok {
+			ready = append(ready, result)
+			delete(r.pending, r.order[r.next])
+		}
+	}
+	return ready
+}
+
+// Buffered returns the number of results waiting for an earlier job
+func (r *resultReorderer) Buffered() int {
+	return len(r.pending)
+}
@@ -0,0 +1,86 @@
+package orchestrator
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func reorderJobs(ids ...int) []models.GenerationJob {
+	jobs := make([]models.GenerationJob, len(ids))
+	for i, id := range ids {
+		jobs[i] = models.GenerationJob{ID: id}
+	}
+	return jobs
+}
+
+func resultIDs(results []models.GenerationResult) []int {
+	ids := make([]int, len(results))
+	for i, r := range results {
+		ids[i] = r.Job.ID
+	}
+	return ids
+}
+
+func TestResultReorderer(t *testing.T) {
+	// Resumed runs dispatch a subset of job IDs, so order follows dispatch, not 0..n
+	r := newResultReorderer(reorderJobs(2, 5, 7, 8), 10)
+
+	var released []int
+	for _, id := range []int{7, 5, 8, 2} {
+		ready, spilled := r.Add(models.GenerationResult{Job: models.GenerationJob{ID: id}})
+		if len(spilled) != 0 {
+			t.Errorf("Expected no spills, got %v", spilled)
+		}
+		released = append(released, resultIDs(ready)...)
+	}
+
+	if want := []int{2, 5, 7, 8}; !slices.Equal(released, want) {
+		t.Errorf("Expected release order %v, got %v", want, released)
+	}
+	if r.Buffered() != 0 {
+		t.Errorf("Expected empty buffer, got %d", r.Buffered())
+	}
+}
+
+func TestResultReordererSpill(t *testing.T) {
+	r := newResultReorderer(reorderJobs(0, 1, 2, 3, 4), 2)
+
+	var released []int
+	add := func(id int) []int {
+		ready, spilled := r.Add(models.GenerationResult{Job: models.GenerationJob{ID: id}})
+		released = append(released, resultIDs(ready)...)
+		return spilled
+	}
+
+	add(1)
+	add(2)
+	// Job 0 stalls; a third buffered result exceeds the limit and spills past it
+	if spilled := add(3); !slices.Equal(spilled, []int{0}) {
+		t.Fatalf("Expected job 0 to be spilled, got %v", spilled)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(released, want) {
+		t.Errorf("Expected %v released after spill, got %v", want, released)
+	}
+
+	// The straggler is written as soon as it arrives
+	add(0)
+	add(4)
+	if want := []int{1, 2, 3, 0, 4}; !slices.Equal(released, want) {
+		t.Errorf("Expected %v, got %v", want, released)
+	}
+}
+
+func TestResultReordererDrain(t *testing.T) {
+	r := newResultReorderer(reorderJobs(0, 1, 2, 3), 10)
+	r.Add(models.GenerationResult{Job: models.GenerationJob{ID: 3}})
+	r.Add(models.GenerationResult{Job: models.GenerationJob{ID: 2}})
+
+	if got := resultIDs(r.Drain()); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("Expected drain order [2 3], got %v", got)
+	}
+	if r.Buffered() != 0 {
+		t.Errorf("Expected empty buffer after drain, got %d", r.Buffered())
+	}
+}
@@ -0,0 +1,150 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/writer"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// sizedWriter counts SFT rows as rowBytes each and closes capped once BytesWritten reaches limit
+type sizedWriter struct {
+	stubWriter
+	mu       sync.Mutex
+	rows     int
+	rowBytes int64
+	limit    int64
+	capped   chan struct{}
+	closeCap sync.Once
+}
+
+func (w *sizedWriter) WriteSFTRecord(models.SFTRecord, string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rows++
+	return nil
+}
+
+func (w *sizedWriter) BytesWritten() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	written := int64(w.rows) * w.rowBytes
+	if written >= w.limit {
+		w.closeCap.Do(func() { close(w.capped) })
+	}
+	return written
+}
+
+func TestGeneratePreferencePairs_StopsAtMaxOutputBytes(t *testing.T) {
+	dw := &sizedWriter{rowBytes: 100, limit: 200, capped: make(chan struct{})}
+	reply := strings.Repeat("The dragon slept beneath the mountain for a thousand years. ", 3)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests after the second wait until the collector has seen the cap
+		if requests.Add(1) > 2 {
+			select {
+			case <-dw.capped:
+			case <-time.After(2 * time.Second):
+			}
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":`+jsonQuote(reply)+`},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := api.NewClient(logger)
+	client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				DatasetMode:          models.DatasetModeSFT,
+				Concurrency:          1,
+				MaxOutputBytes:       dw.limit,
+				ShutdownGraceSeconds: -1,
+			},
+			Models: map[string]config.ModelConfig{
+				"main": {
+					BaseURL:            server.URL,
+					ModelName:          "main-model",
+					MaxOutputTokens:    100,
+					RateLimitPerMinute: 6000,
+					HTTPTimeoutSeconds: 5,
+				},
+			},
+			PromptTemplates: config.PromptTemplates{ChosenGeneration: "{{.Prompt}}"},
+		},
+		secrets:    &config.Secrets{},
+		apiClient:  client,
+		dataWriter: dw,
+		logger:     logger,
+		stats:      &models.SessionStats{TotalPrompts: 6},
+		ctx:        context.Background(),
+	}
+
+	jobs := make([]models.GenerationJob, 6)
+	for i := range jobs {
+		jobs[i] = models.GenerationJob{ID: i, SubTopic: "Dragons", Prompt: "Write about a dragon"}
+	}
+	if err := orch.generatePreferencePairs(context.Background(), jobs, 0); err != nil {
+		t.Fatalf("generatePreferencePairs returned error: %v", err)
+	}
+
+	// The job in flight when the cap was reached may still be written; nothing after it starts
+	if dw.rows < 2 || dw.rows > 3 {
+		t.Errorf("Expected 2-3 rows written before dispatch stopped, got %d", dw.rows)
+	}
+	if !orch.stats.OutputCapped {
+		t.Error("Expected stats to record the output cap")
+	}
+	if orch.stats.FailureCount != 0 {
+		t.Errorf("Expected no failures from the cap, got %d", orch.stats.FailureCount)
+	}
+}
+
+func TestCheckOutputCap_CountsBufferedMODPORecords(t *testing.T) {
+	t.Chdir(t.TempDir())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sessionMgr, err := writer.NewSessionManager(logger, "")
+	if err != nil {
+		t.Fatalf("NewSessionManager returned error: %v", err)
+	}
+	dw, err := writer.NewDatasetWriter(sessionMgr, logger, false, 0)
+	if err != nil {
+		t.Fatalf("NewDatasetWriter returned error: %v", err)
+	}
+	defer func() { _ = dw.Close() }()
+
+	stopped := false
+	orch := &Orchestrator{
+		cfg: &config.Config{Generation: config.GenerationConfig{
+			DatasetMode:    models.DatasetModeMODPO,
+			MaxOutputBytes: 100,
+		}},
+		dataWriter:   dw,
+		logger:       logger,
+		stats:        &models.SessionStats{},
+		stopDispatch: func() { stopped = true },
+	}
+
+	// With max_buffered_records = 0 nothing reaches disk until the final flush
+	record := models.DatasetRecord{Prompt: "Write about a dragon", Chosen: strings.Repeat("The dragon slept. ", 5), Rejected: "It slept."}
+	if _, err := dw.WriteRecord(record); err != nil {
+		t.Fatalf("WriteRecord returned error: %v", err)
+	}
+	orch.checkOutputCap()
+
+	if !stopped || !orch.stats.OutputCapped {
+		t.Errorf("Expected a buffered record past max_output_bytes to stop dispatch (bytes counted: %d)", dw.BytesWritten())
+	}
+}
@@ -0,0 +1,68 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Plan is what phases 1-2 produce (vellumforge2 plan): the subtopics of every main topic and
+// the prompts that would become jobs, so coverage can be reviewed before any pair is generated
+type Plan struct {
+	Topics  []PlanTopic  `json:"topics"`
+	Prompts []PlanPrompt `json:"prompts"`
+}
+
+// PlanTopic is one main topic with its subtopics
+type PlanTopic struct {
+	MainTopic string   `json:"main_topic"`
+	Subtopics []string `json:"subtopics"`
+}
+
+// PlanPrompt is one planned job
+type PlanPrompt struct {
+	ID        int    `json:"id"`
+	MainTopic string `json:"main_topic"`
+	SubTopic  string `json:"sub_topic"`
+	Prompt    string `json:"prompt"`
+}
+
+// Plan runs the subtopics and prompts phases and returns their output without starting the
+// worker pool. No checkpoint or dataset is written; the prompt cache is used as in a run, so a
+// following run reuses the planned prompts while the templates are unchanged
+func (o *Orchestrator) Plan(ctx context.Context) (*Plan, error) {
+	groups, err := o.runSubtopicPhase(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate subtopics: %w", err)
+	}
+	jobs, err := o.runPromptPhase(ctx, groups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate prompts: %w", err)
+	}
+	o.syncReformatStats()
+
+	plan := &Plan{
+		Topics:  make([]PlanTopic, len(groups)),
+		Prompts: make([]PlanPrompt, len(jobs)),
+	}
+	for i, group := range groups {
+		plan.Topics[i] = PlanTopic{MainTopic: group.topic, Subtopics: group.subtopics}
+	}
+	for i, job := range jobs {
+		plan.Prompts[i] = PlanPrompt{ID: job.ID, MainTopic: job.MainTopic, SubTopic: job.SubTopic, Prompt: job.Prompt}
+	}
+	return plan, nil
+}
+
+// Write saves the plan as indented JSON
+func (p *Plan) Write(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestPlan(t *testing.T) {
+	var pairRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		content := `["Story A", "Story B"]`
+		switch {
+		case strings.Contains(string(body), "List subtopics"):
+			content = `["Dragons", "Elves"]`
+		case !strings.Contains(string(body), "Write prompts"):
+			pairRequests++
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` +
+			jsonQuote(content) + `},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				MainTopic:             "Fantasy",
+				NumSubtopics:          2,
+				NumPromptsPerSubtopic: 2,
+				Concurrency:           2,
+			},
+			Models: map[string]config.ModelConfig{"main": {
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				RateLimitPerMinute: 6000,
+			}},
+			PromptTemplates: config.PromptTemplates{
+				SubtopicGeneration: "List subtopics of {{.MainTopic}}",
+				PromptGeneration:   "Write prompts about {{.SubTopic}}",
+			},
+		},
+		secrets:   &config.Secrets{},
+		apiClient: api.NewClient(logger),
+		logger:    logger,
+		stats:     &models.SessionStats{},
+	}
+
+	plan, err := orch.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan returned unexpected error: %v", err)
+	}
+	if pairRequests != 0 {
+		t.Errorf("Expected no requests beyond subtopics and prompts, got %d", pairRequests)
+	}
+	if len(plan.Topics) != 1 || plan.Topics[0].MainTopic != "Fantasy" || len(plan.Topics[0].Subtopics) != 2 {
+		t.Errorf("Expected one Fantasy topic with 2 subtopics, got %+v", plan.Topics)
+	}
+	if len(plan.Prompts) != 4 {
+		t.Fatalf("Expected 4 prompts, got %d", len(plan.Prompts))
+	}
+	for i, prompt := range plan.Prompts {
+		if prompt.ID != i || prompt.MainTopic != "Fantasy" || prompt.Prompt == "" {
+			t.Errorf("Prompt %d: unexpected %+v", i, prompt)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := plan.Write(path); err != nil {
+		t.Fatalf("Write returned unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read plan: %v", err)
+	}
+	var decoded Plan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode plan: %v", err)
+	}
+	if len(decoded.Prompts) != 4 || decoded.Prompts[3].SubTopic != plan.Prompts[3].SubTopic {
+		t.Errorf("Expected the written plan to round-trip, got %+v", decoded)
+	}
+}
@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// promptCacheEntry is the on-disk format of a cached prompt list
+type promptCacheEntry struct {
+	SubTopic     string    `json:"sub_topic"`
+	TemplateHash string    `json:"template_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+	Prompts      []string  `json:"prompts"`
+}
+
+// promptCache stores generated prompts on disk keyed by subtopic + prompt template hash
+// Changing the prompt template, prompt system prompt, or prompts per subtopic changes the
+// template hash, so stale entries are never reused
+type promptCache struct {
+	dir          string
+	ttl          time.Duration // 0 = entries never expire
+	templateHash string
+	logger       *slog.Logger
+}
+
+// newPromptCache creates the cache directory and returns a cache bound to the given template inputs
+func newPromptCache(dir string, ttl time.Duration, template, systemPrompt string, numPrompts int, logger *slog.Logger) (*promptCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create prompt cache directory: %w", err)
+	}
+
+	hash := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%d", template, systemPrompt, numPrompts))
+	return &promptCache{
+		dir:          dir,
+		ttl:          ttl,
+		templateHash: hex.EncodeToString(hash[:]),
+		logger:       logger,
+	}, nil
+}
+
+// path returns the cache file path for a subtopic
+func (c *promptCache) path(subtopic string) string {
+	key := sha256.Sum256([]byte(subtopic + "\x00" + c.templateHash))
+	return filepath.Join(c.dir, hex.EncodeToString(key[:16])+".json")
+}
+
+// Get returns cached prompts for a subtopic, or false if missing, expired, or unreadable
+func (c *promptCache) Get(subtopic string) ([]string, bool) {
+	data, err := os.ReadFile(c.path(subtopic))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry promptCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.logger.Warn("Ignoring corrupt prompt cache entry", "subtopic", subtopic, "error", err)
+		return nil, false
+	}
+	if entry.SubTopic != subtopic || entry.TemplateHash != c.templateHash || len(entry.Prompts) == 0 {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(entry.CreatedAt) > c.ttl {
+		c.logger.Debug("Prompt cache entry expired", "subtopic", subtopic, "created_at", entry.CreatedAt)
+		return nil, false
+	}
+
+	return entry.Prompts, true
+}
+
+// Put stores prompts for a subtopic (written atomically via temp file + rename)
+func (c *promptCache) Put(subtopic string, prompts []string) error {
+	data, err := json.Marshal(promptCacheEntry{
+		SubTopic:     subtopic,
+		TemplateHash: c.templateHash,
+		CreatedAt:    time.Now(),
+		Prompts:      prompts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal prompt cache entry: %w", err)
+	}
+
+	path := c.path(subtopic)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write prompt cache entry: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to save prompt cache entry: %w", err)
+	}
+	return nil
+}
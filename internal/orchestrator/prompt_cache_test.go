@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestPromptCache(t *testing.T, dir, template string, ttl time.Duration) *promptCache {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cache, err := newPromptCache(dir, ttl, template, "system", 5, logger)
+	if err != nil {
+		t.Fatalf("Expected no error creating cache, got: %v", err)
+	}
+	return cache
+}
+
+func TestPromptCache_PutGet(t *testing.T) {
+	cache := newTestPromptCache(t, t.TempDir(), "Generate {{.NumPrompts}} prompts for {{.SubTopic}}", 0)
+
+	if _, ok := cache.Get("Dragons"); ok {
+		t.Fatal("Expected cache miss before Put")
+	}
+
+	prompts := []string{"prompt one", "prompt two"}
+	if err := cache.Put("Dragons", prompts); err != nil {
+		t.Fatalf("Expected no error on Put, got: %v", err)
+	}
+
+	cached, ok := cache.Get("Dragons")
+	if !ok {
+		t.Fatal("Expected cache hit after Put")
+	}
+	if len(cached) != len(prompts) || cached[0] != prompts[0] || cached[1] != prompts[1] {
+		t.Errorf("Expected %v, got %v", prompts, cached)
+	}
+
+	if _, ok := cache.Get("Elves"); ok {
+		t.Error("Expected cache miss for a different subtopic")
+	}
+}
+
+func TestPromptCache_TemplateChangeInvalidates(t *testing.T) {
+	dir := t.TempDir()
+	original := newTestPromptCache(t, dir, "template v1", 0)
+	if err := original.Put("Dragons", []string{"prompt"}); err != nil {
+		t.Fatalf("Expected no error on Put, got: %v", err)
+	}
+
+	changed := newTestPromptCache(t, dir, "template v2", 0)
+	if _, ok := changed.Get("Dragons"); ok {
+		t.Error("Expected cache miss after template change")
+	}
+
+	same := newTestPromptCache(t, dir, "template v1", 0)
+	if _, ok := same.Get("Dragons"); !ok {
+		t.Error("Expected cache hit with unchanged template")
+	}
+}
+
+func TestPromptCache_TTLExpiry(t *testing.T) {
+	cache := newTestPromptCache(t, t.TempDir(), "template", time.Nanosecond)
+	if err := cache.Put("Dragons", []string{"prompt"}); err != nil {
+		t.Fatalf("Expected no error on Put, got: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.Get("Dragons"); ok {
+		t.Error("Expected expired entry to be a cache miss")
+	}
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/lamim/vellumforge2/internal/embeddings"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// filterSimilarPrompts drops jobs whose prompt embedding is at least embeddings.similarity_threshold
+// similar to an earlier kept prompt, keeping the first of each group of near-duplicates
+// Filtering is best effort: when the embeddings endpoint fails every job is kept
+func (o *Orchestrator) filterSimilarPrompts(ctx context.Context, jobs []models.GenerationJob) []models.GenerationJob {
+	if o.embedder == nil || len(jobs) < 2 {
+		return jobs
+	}
+
+	texts := make([]string, len(jobs))
+	for i, job := range jobs {
+		texts[i] = job.Prompt
+	}
+	vectors, err := o.embedder.Embed(ctx, texts)
+	if err != nil {
+		o.logger.Warn("Prompt diversity filtering failed, keeping all prompts", "prompts", len(jobs), "error", err)
+		return jobs
+	}
+
+	keep := embeddings.Diverse(vectors, o.cfg.Embeddings.Threshold())
+	kept := jobs[:0]
+	for i, job := range jobs {
+		if keep[i] {
+			kept = append(kept, job)
+		} else {
+			o.logger.Debug("Dropped near-duplicate prompt", "subtopic", job.SubTopic, "prompt", job.Prompt)
+		}
+	}
+
+	o.logger.Info("Prompt diversity filtering complete",
+		"prompts", len(jobs),
+		"kept", len(kept),
+		"dropped", len(jobs)-len(kept),
+		"similarity_threshold", o.cfg.Embeddings.Threshold())
+	return kept
+}
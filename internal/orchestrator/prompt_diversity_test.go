@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/embeddings"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// newEmbeddingServer gives every prompt mentioning a dragon one vector and every other prompt another
+func newEmbeddingServer(t *testing.T, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		var items []string
+		for i, text := range req.Input {
+			vector := "[0, 1]"
+			if strings.Contains(text, "dragon") {
+				vector = "[1, 0]"
+			}
+			items = append(items, fmt.Sprintf(`{"index": %d, "embedding": %s}`, i, vector))
+		}
+		fmt.Fprintf(w, `{"data": [%s]}`, strings.Join(items, ","))
+	}))
+}
+
+// diversityTestOrchestrator returns an orchestrator whose embedder talks to embCfg.BaseURL
+func diversityTestOrchestrator(embCfg config.EmbeddingsConfig) *Orchestrator {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := api.NewClient(logger)
+	client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	return &Orchestrator{
+		cfg:      &config.Config{Embeddings: embCfg},
+		logger:   logger,
+		embedder: embeddings.New(embCfg, client, ""),
+	}
+}
+
+func diversityJobs() []models.GenerationJob {
+	return []models.GenerationJob{
+		{SubTopic: "Dragons", Prompt: "A dragon hoards books"},
+		{SubTopic: "Dragons", Prompt: "A dragon learns to read"},
+		{SubTopic: "Elves", Prompt: "An elf loses her bow"},
+		{SubTopic: "Elves", Prompt: "An elf opens a bakery"},
+	}
+}
+
+func TestFilterSimilarPrompts(t *testing.T) {
+	server := newEmbeddingServer(t, http.StatusOK)
+	defer server.Close()
+
+	embCfg := config.EmbeddingsConfig{Enabled: true, BaseURL: server.URL, ModelName: "m"}
+	orch := diversityTestOrchestrator(embCfg)
+
+	kept := orch.filterSimilarPrompts(context.Background(), diversityJobs())
+	if len(kept) != 2 || kept[0].Prompt != "A dragon hoards books" || kept[1].Prompt != "An elf loses her bow" {
+		t.Errorf("Expected the first prompt of each near-duplicate group, got %+v", kept)
+	}
+}
+
+func TestFilterSimilarPromptsKeepsAllOnError(t *testing.T) {
+	server := newEmbeddingServer(t, http.StatusInternalServerError)
+	defer server.Close()
+
+	embCfg := config.EmbeddingsConfig{Enabled: true, BaseURL: server.URL, ModelName: "m"}
+	orch := diversityTestOrchestrator(embCfg)
+
+	if kept := orch.filterSimilarPrompts(context.Background(), diversityJobs()); len(kept) != 4 {
+		t.Errorf("Expected every prompt kept when embedding fails, got %d", len(kept))
+	}
+}
+
+func TestFilterSimilarPromptsDisabled(t *testing.T) {
+	orch := &Orchestrator{cfg: &config.Config{}}
+	if kept := orch.filterSimilarPrompts(context.Background(), diversityJobs()); len(kept) != 4 {
+		t.Errorf("Expected filtering to be a no-op without [embeddings], got %d jobs", len(kept))
+	}
+}
@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// generateSubtopicPrompts requests count prompts for a subtopic, either in one request or,
+// with generation.prompt_samples > 1, as several independent samples merged together
+func (o *Orchestrator) generateSubtopicPrompts(ctx context.Context, mainTopic, subtopic string, count int) ([]string, error) {
+	mainModel := o.cfg.Models["main"]
+	samples := o.cfg.Generation.PromptSamples
+	if samples <= 1 {
+		return o.retryOnUndershoot(ctx, "prompts", count, func() ([]string, error) {
+			return o.requestPrompts(ctx, mainModel, mainTopic, subtopic, count)
+		})
+	}
+
+	sampleModel := promptSampleModel(mainModel, o.cfg.Generation.PromptSampleTemperature)
+	perSample := (count + samples - 1) / samples
+
+	var results [][]string
+	var lastErr error
+	for i := 0; i < samples && ctx.Err() == nil; i++ {
+		prompts, err := o.retryOnUndershoot(ctx, "prompts", perSample, func() ([]string, error) {
+			return o.requestPrompts(ctx, sampleModel, mainTopic, subtopic, perSample)
+		})
+		if err != nil {
+			o.logger.Warn("Prompt sample failed, continuing with the others",
+				"subtopic", subtopic,
+				"sample", i+1,
+				"samples", samples,
+				"error", err)
+			lastErr = err
+			continue
+		}
+		results = append(results, prompts)
+	}
+	if len(results) == 0 {
+		if lastErr == nil {
+			lastErr = ctx.Err()
+		}
+		return nil, lastErr
+	}
+
+	merged := mergePromptSamples(results, count)
+
+	// Duplicates across samples can leave the merge short; top it up with one more sample
+	if short := count - len(merged); short > 0 && ctx.Err() == nil {
+		extra, err := o.requestPrompts(ctx, sampleModel, mainTopic, subtopic, short)
+		if err != nil {
+			o.logger.Warn("Prompt top-up sample failed, keeping merged prompts",
+				"subtopic", subtopic,
+				"received", len(merged),
+				"requested", count,
+				"error", err)
+		} else {
+			results = append(results, extra)
+			merged = mergePromptSamples(results, count)
+		}
+	}
+
+	o.logger.Debug("Merged prompt samples",
+		"subtopic", subtopic,
+		"samples", len(results),
+		"per_sample", perSample,
+		"prompts", len(merged))
+	return merged, nil
+}
+
+// promptSampleModel returns the main model config used for sampled prompt requests, with
+// prompt_sample_temperature (when set) overriding both temperature and structure_temperature
+func promptSampleModel(mainModel config.ModelConfig, temperature float64) config.ModelConfig {
+	if temperature > 0 {
+		mainModel.Temperature = temperature
+		mainModel.StructureTemperature = 0
+	}
+	return mainModel
+}
+
+// mergePromptSamples interleaves the samples so each one is represented when trimming,
+// drops prompts repeated within or across samples, and keeps at most count prompts
+func mergePromptSamples(samples [][]string, count int) []string {
+	var interleaved []string
+	for i := 0; ; i++ {
+		added := false
+		for _, sample := range samples {
+			if i < len(sample) {
+				interleaved = append(interleaved, sample[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	merged := deduplicateStrings(interleaved)
+	if len(merged) > count {
+		merged = merged[:count]
+	}
+	return merged
+}
@@ -0,0 +1,125 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestMergePromptSamples(t *testing.T) {
+	samples := [][]string{
+		{"Write about a dragon", "Write about a knight", "Write about a castle"},
+		{"write about a DRAGON ", "Write about a wizard"},
+		{"Write about a ship"},
+	}
+
+	got := mergePromptSamples(samples, 4)
+	// Sample 2's first prompt repeats sample 1's, so its second prompt comes after sample 1's second
+	want := []string{"Write about a dragon", "Write about a ship", "Write about a knight", "Write about a wizard"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if got := mergePromptSamples(samples, 10); len(got) != 5 {
+		t.Errorf("Expected 5 unique prompts, got %v", got)
+	}
+}
+
+func TestPromptSampleModel(t *testing.T) {
+	base := config.ModelConfig{Temperature: 0.7, StructureTemperature: 0.3}
+
+	if got := promptSampleModel(base, 0); got.Temperature != 0.7 || got.StructureTemperature != 0.3 {
+		t.Errorf("Expected temperatures unchanged without an override, got %.2f/%.2f", got.Temperature, got.StructureTemperature)
+	}
+	if got := promptSampleModel(base, 1.2); got.Temperature != 1.2 || got.StructureTemperature != 0 {
+		t.Errorf("Expected temperature 1.2 without structure_temperature, got %.2f/%.2f", got.Temperature, got.StructureTemperature)
+	}
+}
+
+func TestGenerateSubtopicPrompts_Samples(t *testing.T) {
+	// Every sample repeats one shared prompt, so the merge comes up short and needs a top-up
+	countPattern := regexp.MustCompile(`Give (\d+) prompts`)
+	var mu sync.Mutex
+	var requested []int
+	var temperatures []float64
+	next := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		if m := countPattern.FindStringSubmatch(string(body)); m != nil {
+			n, _ = strconv.Atoi(m[1])
+			requested = append(requested, n)
+		}
+		var req struct {
+			Temperature float64 `json:"temperature"`
+		}
+		_ = json.Unmarshal(body, &req)
+		temperatures = append(temperatures, req.Temperature)
+
+		items := []string{"Shared prompt"}
+		for len(items) < n {
+			items = append(items, fmt.Sprintf("Prompt %d", next))
+			next++
+		}
+		content, _ := json.Marshal(items)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` +
+			jsonQuote(string(content)) + `},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{PromptSamples: 3, PromptSampleTemperature: 1.3},
+			Models: map[string]config.ModelConfig{"main": {
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				Temperature:        0.7,
+				MaxOutputTokens:    100,
+				RateLimitPerMinute: 6000,
+			}},
+			PromptTemplates: config.PromptTemplates{PromptGeneration: "Give {{.NumPrompts}} prompts about {{.SubTopic}}"},
+		},
+		secrets:   &config.Secrets{},
+		apiClient: api.NewClient(logger),
+		logger:    logger,
+		stats:     &models.SessionStats{},
+	}
+
+	prompts, err := orch.generateSubtopicPrompts(context.Background(), "Fantasy", "Dragons", 9)
+	if err != nil {
+		t.Fatalf("generateSubtopicPrompts returned unexpected error: %v", err)
+	}
+	// The top-up repeats the shared prompt too, so one prompt stays missing
+	if len(prompts) != 8 {
+		t.Errorf("Expected 8 prompts, got %d: %v", len(prompts), prompts)
+	}
+	if len(deduplicateStrings(prompts)) != len(prompts) {
+		t.Errorf("Expected no duplicate prompts, got %v", prompts)
+	}
+	// 3 samples of 3 yield 7 unique prompts, so a top-up asks for the 2 missing
+	if want := []int{3, 3, 3, 2}; !slices.Equal(requested, want) {
+		t.Errorf("Expected prompt requests %v, got %v", want, requested)
+	}
+	for _, temp := range temperatures {
+		if temp != 1.3 {
+			t.Errorf("Expected sample temperature 1.3, got %.2f", temp)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestWriteRecordMeta(t *testing.T) {
+	result := models.GenerationResult{
+		Job:              models.GenerationJob{Prompt: "prompt"},
+		Chosen:           "chosen",
+		Rejected:         "rejected",
+		ChosenAttempts:   models.AttemptCounts{Network: 2},
+		RejectedAttempts: models.AttemptCounts{Content: 1},
+	}
+
+	tests := []struct {
+		name            string
+		includeMetadata bool
+	}{
+		{"disabled leaves the column out", false},
+		{"enabled records attempts per side", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &preferenceWriter{}
+			orch := &Orchestrator{
+				cfg:        &config.Config{Generation: config.GenerationConfig{IncludeMetadata: tt.includeMetadata}},
+				dataWriter: writer,
+			}
+
+			if err := orch.writeDPORecord(result); err != nil {
+				t.Fatalf("writeDPORecord returned error: %v", err)
+			}
+			if err := orch.writeKTORecord(result); err != nil {
+				t.Fatalf("writeKTORecord returned error: %v", err)
+			}
+			if len(writer.dpoRecords) != 1 || len(writer.ktoRecords) != 2 {
+				t.Fatalf("Expected 1 DPO and 2 KTO rows, got %d and %d", len(writer.dpoRecords), len(writer.ktoRecords))
+			}
+
+			dpo := writer.dpoRecords[0].Meta
+			if !tt.includeMetadata {
+				if dpo != nil || writer.ktoRecords[0].Meta != nil || writer.ktoRecords[1].Meta != nil {
+					t.Error("Expected no _meta with include_metadata disabled")
+				}
+				return
+			}
+
+			if dpo == nil || dpo.Attempts.Chosen == nil || dpo.Attempts.Rejected == nil {
+				t.Fatalf("Expected DPO _meta with both sides, got %+v", dpo)
+			}
+			if *dpo.Attempts.Chosen != result.ChosenAttempts || *dpo.Attempts.Rejected != result.RejectedAttempts {
+				t.Errorf("Expected attempts %+v/%+v, got %+v/%+v",
+					result.ChosenAttempts, result.RejectedAttempts, *dpo.Attempts.Chosen, *dpo.Attempts.Rejected)
+			}
+
+			for _, record := range writer.ktoRecords {
+				if record.Meta == nil {
+					t.Fatal("Expected KTO _meta")
+				}
+				attempts := record.Meta.Attempts
+				if record.Label {
+					if attempts.Chosen == nil || attempts.Rejected != nil || *attempts.Chosen != result.ChosenAttempts {
+						t.Errorf("Expected desirable row to carry only chosen attempts, got %+v", attempts)
+					}
+				} else if attempts.Rejected == nil || attempts.Chosen != nil || *attempts.Rejected != result.RejectedAttempts {
+					t.Errorf("Expected undesirable row to carry only rejected attempts, got %+v", attempts)
+				}
+			}
+		})
+	}
+}
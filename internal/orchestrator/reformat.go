@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/util"
+)
+
+// reformatJSONPrompt asks the model to re-emit a malformed response as strict JSON
+const reformatJSONPrompt = `The response below was supposed to be a JSON array of strings, but it could not be parsed.
+Return the same items as a strictly valid JSON array of strings. Output ONLY the JSON array: no markdown, no code fences, no commentary.
+
+RESPONSE:
+%s`
+
+// reformatStringArray sends malformed content back to the model once and re-parses the reply
+// Used when generation.json_reformat_retry is enabled and all local repairs failed
+func (o *Orchestrator) reformatStringArray(
+	ctx context.Context,
+	model config.ModelConfig,
+	apiKey string,
+	content string,
+	opts StringArrayOptions,
+) ([]string, error) {
+	o.reformatAttempts.Add(1)
+
+	messages := []api.Message{{
+		Role:    "user",
+		Content: fmt.Sprintf(reformatJSONPrompt, content),
+	}}
+	resp, err := o.apiClient.ChatCompletionStructured(ctx, model, apiKey, messages)
+	if err != nil {
+		return nil, fmt.Errorf("reformat request failed: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("reformat request returned empty response")
+	}
+
+	jsonStr := util.RepairJSON(extractJSON(resp.Choices[0].Message.Content))
+	items, _, err := ValidateStringArrayWithOptions(jsonStr, opts)
+	if err != nil {
+		return nil, fmt.Errorf("reformatted response is still invalid: %w", err)
+	}
+
+	o.reformatSuccesses.Add(1)
+	return items, nil
+}
+
+// syncReformatStats copies the JSON reformat counters into the session stats
+func (o *Orchestrator) syncReformatStats() {
+	o.stats.JSONReformatAttempts = int(o.reformatAttempts.Load())
+	o.stats.JSONReformatSuccesses = int(o.reformatSuccesses.Load())
+}
@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestReformatStringArray(t *testing.T) {
+	tests := []struct {
+		name        string
+		reply       string
+		wantItems   []string
+		wantErr     string
+		wantSuccess int
+	}{
+		{
+			name:        "valid reformat",
+			reply:       `["Dragons", "Elves"]`,
+			wantItems:   []string{"Dragons", "Elves"},
+			wantSuccess: 1,
+		},
+		{
+			name:    "still invalid",
+			reply:   `Sorry, here you go: Dragons, Elves`,
+			wantErr: "still invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requestBody = string(body)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":` +
+					jsonQuote(tt.reply) + `},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			orch := &Orchestrator{
+				apiClient: api.NewClient(logger),
+				logger:    logger,
+				stats:     &models.SessionStats{},
+			}
+			model := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				RateLimitPerMinute: 60,
+			}
+
+			items, err := orch.reformatStringArray(context.Background(), model, "", `["Dragons", "Elves"`,
+				StringArrayOptions{MinCount: 1, CoerceObjects: true})
+			orch.syncReformatStats()
+
+			if !strings.Contains(requestBody, `[\"Dragons\", \"Elves\"`) {
+				t.Errorf("Expected malformed content in reformat request, got %s", requestBody)
+			}
+			if orch.stats.JSONReformatAttempts != 1 || orch.stats.JSONReformatSuccesses != tt.wantSuccess {
+				t.Errorf("Expected 1 attempt and %d successes, got %d and %d",
+					tt.wantSuccess, orch.stats.JSONReformatAttempts, orch.stats.JSONReformatSuccesses)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if strings.Join(items, "|") != strings.Join(tt.wantItems, "|") {
+				t.Errorf("Expected items %v, got %v", tt.wantItems, items)
+			}
+		})
+	}
+}
+
+func jsonQuote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestGenerateRejectedResponse_SeesChosen(t *testing.T) {
+	var gotPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req api.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		gotPrompt = req.Messages[len(req.Messages)-1].Content
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"A worse story."},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{RejectedSeesChosen: true},
+			PromptTemplates: config.PromptTemplates{
+				RejectedGeneration: "Prompt: {{.Prompt}}\nMake this worse: {{.Chosen}}",
+			},
+		},
+		secrets:   &config.Secrets{},
+		apiClient: api.NewClient(logger),
+		logger:    logger,
+		stats:     &models.SessionStats{},
+	}
+	model := config.ModelConfig{
+		BaseURL:            server.URL,
+		ModelName:          "test-model",
+		MaxOutputTokens:    100,
+		RateLimitPerMinute: 6000,
+	}
+
+	job := models.GenerationJob{Prompt: "A dragon story"}
+	if _, err := orch.generateRejectedResponse(context.Background(), logger, job, "The dragon slept.", model); err != nil {
+		t.Fatalf("generateRejectedResponse returned unexpected error: %v", err)
+	}
+	want := "Prompt: A dragon story\nMake this worse: The dragon slept."
+	if gotPrompt != want {
+		t.Errorf("Expected rejected prompt %q, got %q", want, gotPrompt)
+	}
+
+	// Without the flag the template has no Chosen value to render
+	orch.cfg.Generation.RejectedSeesChosen = false
+	if _, err := orch.generateRejectedResponse(context.Background(), logger, job, "The dragon slept.", model); err == nil {
+		t.Error("Expected a render error for {{.Chosen}} without rejected_sees_chosen")
+	}
+}
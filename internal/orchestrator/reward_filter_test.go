@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/internal/reward"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// rewardTestOrchestrator returns an orchestrator whose reward model client talks to cfg.BaseURL
+func rewardTestOrchestrator(cfg config.RewardModelConfig) *Orchestrator {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := api.NewClient(logger)
+	client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	return &Orchestrator{
+		cfg:         &config.Config{RewardModel: cfg},
+		rewardModel: reward.New(cfg, client, ""),
+		logger:      logger,
+	}
+}
+
+func TestApplyRewardFiltering(t *testing.T) {
+	// The fake reward model scores a response by its leading number
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text2 []string `json:"text_2"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		var items []string
+		for i, text := range req.Text2 {
+			items = append(items, fmt.Sprintf(`{"index": %d, "score": %s}`, i, strings.Fields(text)[0]))
+		}
+		_, _ = fmt.Fprintf(w, `{"data": [%s]}`, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	threshold := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		cfg      config.RewardModelConfig
+		chosen   string
+		rejected []string
+		want     bool
+	}{
+		{"chosen above minimum", config.RewardModelConfig{MinChosenScore: threshold(0.5)}, "1.2 good", nil, false},
+		{"chosen below minimum", config.RewardModelConfig{MinChosenScore: threshold(0.5)}, "-0.3 bad", nil, true},
+		{"rejected above maximum", config.RewardModelConfig{MaxRejectedScore: threshold(0)}, "2 good", []string{"0.4 ok"}, true},
+		{"margin too small", config.RewardModelConfig{MinMargin: threshold(1)}, "2 good", []string{"1.5 ok"}, true},
+		{"margin large enough", config.RewardModelConfig{MinMargin: threshold(1)}, "2 good", []string{"0.5 bad"}, false},
+		{"every candidate is scored", config.RewardModelConfig{MinMargin: threshold(1)}, "2 good", []string{"0.5 bad", "1.8 close"}, true},
+		{"margin ignored without rejected", config.RewardModelConfig{MinMargin: threshold(1)}, "2 good", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Enabled = true
+			tt.cfg.BaseURL = server.URL + "/score"
+			result := models.GenerationResult{Job: models.GenerationJob{Prompt: "Write a story"}, Chosen: tt.chosen}
+			for _, content := range tt.rejected {
+				result.RejectedList = append(result.RejectedList, models.RejectedResponse{Content: content})
+			}
+			if len(result.RejectedList) > 0 {
+				result.Rejected = result.RejectedList[0].Content
+			}
+			if got, _ := rewardTestOrchestrator(tt.cfg).applyRewardFiltering(result); got != tt.want {
+				t.Errorf("Expected filter=%v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyRewardFiltering_OnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	minScore := 0.5
+	result := models.GenerationResult{Job: models.GenerationJob{Prompt: "Write a story"}, Chosen: "chosen", Rejected: "rejected"}
+	for action, want := range map[string]bool{config.RewardErrorDrop: true, config.RewardErrorKeep: false} {
+		cfg := config.RewardModelConfig{Enabled: true, BaseURL: server.URL, MinChosenScore: &minScore, OnError: action}
+		if got, _ := rewardTestOrchestrator(cfg).applyRewardFiltering(result); got != want {
+			t.Errorf("on_error=%s: expected filter=%v after a failed request, got %v", action, want, got)
+		}
+	}
+}
@@ -19,11 +19,22 @@ func (s *stubWriter) WriteSFTRecord(record models.SFTRecord, reasoning string) e
 }
 
 func (s *stubWriter) WriteDPORecord(models.DPORecord, string, string) error { panic("unexpected call") }
-func (s *stubWriter) WriteKTORecord(models.KTORecord, string) error         { panic("unexpected call") }
-func (s *stubWriter) WriteRecord(models.DatasetRecord) (int, error)         { panic("unexpected call") }
-func (s *stubWriter) UpdateRecord(int, *models.JudgeResult) error           { panic("unexpected call") }
-func (s *stubWriter) Flush() error                                          { return nil }
-func (s *stubWriter) Close() error                                          { return nil }
+func (s *stubWriter) WriteMultiRejectedDPORecord(models.MultiRejectedDPORecord, string, []string) error {
+	panic("unexpected call")
+}
+func (s *stubWriter) WriteConversationalDPORecord(models.ConversationalDPORecord, string, string) error {
+	panic("unexpected call")
+}
+func (s *stubWriter) WriteKTORecord(models.KTORecord, string) error { panic("unexpected call") }
+func (s *stubWriter) WriteRecord(models.DatasetRecord) (int, error) { panic("unexpected call") }
+func (s *stubWriter) UpdateRecord(int, *models.JudgeResult) error   { panic("unexpected call") }
+func (s *stubWriter) MarkJudgeFailed(int) error                     { panic("unexpected call") }
+func (s *stubWriter) SetMinPreferenceMargin(float64)                {}
+func (s *stubWriter) SetMaxBufferedRecords(int)                     {}
+func (s *stubWriter) SetIncludeIDs(bool)                            {}
+func (s *stubWriter) BytesWritten() int64                           { return 0 }
+func (s *stubWriter) Flush() error                                  { return nil }
+func (s *stubWriter) Close() error                                  { return nil }
 
 func TestWriteSFTRecordAlpaca(t *testing.T) {
 	writer := &stubWriter{}
@@ -99,3 +110,56 @@ func TestWriteSFTRecordShareGPT(t *testing.T) {
 		t.Fatalf("sharegpt record should not set instruction/output: %+v", writer.lastSFTRecord)
 	}
 }
+
+func TestWriteSFTRecordOpenAI(t *testing.T) {
+	writer := &stubWriter{}
+	orch := &Orchestrator{
+		cfg: &config.Config{
+			Generation: config.GenerationConfig{
+				DatasetMode: models.DatasetModeSFT,
+				SFTFormat:   models.SFTFormatOpenAI,
+			},
+			PromptTemplates: config.PromptTemplates{
+				ChosenSystemPrompt: "You are a writer.",
+			},
+		},
+		dataWriter: writer,
+	}
+
+	result := models.GenerationResult{
+		Job: models.GenerationJob{
+			Prompt: "say hi",
+		},
+		Chosen: "hello",
+	}
+
+	if err := orch.writeSFTRecord(result); err != nil {
+		t.Fatalf("writeSFTRecord returned error: %v", err)
+	}
+
+	want := []models.OpenAIMessage{
+		{Role: "system", Content: "You are a writer."},
+		{Role: "user", Content: "say hi"},
+		{Role: "assistant", Content: "hello"},
+	}
+	if len(writer.lastSFTRecord.Messages) != len(want) {
+		t.Fatalf("expected %d messages, got %d", len(want), len(writer.lastSFTRecord.Messages))
+	}
+	for i, msg := range want {
+		if writer.lastSFTRecord.Messages[i] != msg {
+			t.Fatalf("unexpected message %d: %+v", i, writer.lastSFTRecord.Messages[i])
+		}
+	}
+	if len(writer.lastSFTRecord.Conversations) != 0 || writer.lastSFTRecord.Output != "" {
+		t.Fatalf("openai record should only set messages: %+v", writer.lastSFTRecord)
+	}
+
+	// Without a system prompt the record starts with the user turn
+	orch.cfg.PromptTemplates.ChosenSystemPrompt = ""
+	if err := orch.writeSFTRecord(result); err != nil {
+		t.Fatalf("writeSFTRecord returned error: %v", err)
+	}
+	if len(writer.lastSFTRecord.Messages) != 2 || writer.lastSFTRecord.Messages[0].Role != "user" {
+		t.Fatalf("expected user/assistant messages only, got %+v", writer.lastSFTRecord.Messages)
+	}
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+)
+
+// jobContext returns the context in-flight jobs run with. Once ctx is done, workers stop taking
+// jobs but those already running keep going for up to grace (generation.shutdown_grace_seconds),
+// so responses that finish in time are still written and checkpointed instead of regenerated on
+// resume. release must be called once the workers have exited.
+// With grace <= 0 jobs are canceled together with ctx.
+func (o *Orchestrator) jobContext(ctx context.Context, grace time.Duration) (jobCtx context.Context, release func()) {
+	if grace <= 0 {
+		return ctx, func() {}
+	}
+
+	jobCtx, cancelJobs := context.WithCancelCause(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		o.logger.Warn("Stopping: finishing in-flight jobs before exit", "grace", grace)
+		select {
+		case <-done:
+		case <-time.After(grace):
+			o.logger.Warn("Shutdown grace period over, abandoning unfinished jobs")
+		}
+		cancelJobs(context.Cause(ctx))
+	}()
+
+	return jobCtx, func() {
+		close(done)
+		cancelJobs(context.Canceled)
+	}
+}
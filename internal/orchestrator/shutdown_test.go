@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestJobContext_GracePeriod(t *testing.T) {
+	orch := &Orchestrator{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	jobCtx, release := orch.jobContext(ctx, 50*time.Millisecond)
+	defer release()
+
+	stopCause := errors.New("stop")
+	cancel(stopCause)
+	time.Sleep(10 * time.Millisecond)
+	if jobCtx.Err() != nil {
+		t.Fatal("Expected in-flight jobs to keep running during the grace period")
+	}
+
+	select {
+	case <-jobCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected jobs to be canceled once the grace period is over")
+	}
+	if !errors.Is(context.Cause(jobCtx), stopCause) {
+		t.Errorf("Expected the run's cancellation cause, got %v", context.Cause(jobCtx))
+	}
+}
+
+func TestJobContext_Release(t *testing.T) {
+	orch := &Orchestrator{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobCtx, release := orch.jobContext(ctx, time.Hour)
+	release()
+	if jobCtx.Err() == nil {
+		t.Error("Expected release to cancel the job context")
+	}
+
+	noGrace, release := orch.jobContext(ctx, 0)
+	defer release()
+	if noGrace != ctx {
+		t.Error("Expected jobs to share the run context without a grace period")
+	}
+}
@@ -0,0 +1,129 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// nearDuplicate records a subtopic dropped by the similarity filter
+type nearDuplicate struct {
+	removed    string
+	similarTo  string
+	similarity float64
+}
+
+// deduplicateSubtopics removes exact duplicates and, when generation.subtopic_dedup_similarity
+// is set, near-duplicates, logging each subtopic the similarity filter removes
+func (o *Orchestrator) deduplicateSubtopics(subtopics []string) []string {
+	unique := deduplicateStrings(subtopics)
+	threshold := o.cfg.Generation.SubtopicDedupSimilarity
+	if threshold <= 0 {
+		return unique
+	}
+
+	kept, removed := deduplicateSimilar(unique, threshold)
+	for _, dup := range removed {
+		o.logger.Info("Removed near-duplicate subtopic",
+			"subtopic", dup.removed,
+			"similar_to", dup.similarTo,
+			"similarity", fmt.Sprintf("%.2f", dup.similarity))
+	}
+	if len(removed) > 0 {
+		o.logger.Info("Near-duplicate subtopic filter applied",
+			"threshold", threshold,
+			"removed", len(removed),
+			"kept", len(kept))
+	}
+	return kept
+}
+
+// deduplicateSimilar drops items whose similarity to an earlier kept item is at least threshold
+// Earlier items win, so subtopics from the first request are kept over retry results
+// Expects items already exact-deduplicated by deduplicateStrings
+func deduplicateSimilar(items []string, threshold float64) ([]string, []nearDuplicate) {
+	kept := make([]string, 0, len(items))
+	keptNorm := make([][]string, 0, len(items))
+	var removed []nearDuplicate
+
+	for _, item := range items {
+		tokens := similarityTokens(item)
+		match, best := -1, 0.0
+		for i, other := range keptNorm {
+			if sim := subtopicSimilarity(tokens, other); sim >= threshold && sim > best {
+				match, best = i, sim
+			}
+		}
+		if match >= 0 {
+			removed = append(removed, nearDuplicate{removed: item, similarTo: kept[match], similarity: best})
+			continue
+		}
+		kept = append(kept, item)
+		keptNorm = append(keptNorm, tokens)
+	}
+
+	return kept, removed
+}
+
+// subtopicSimilarity scores two tokenized subtopics from 0.0 (unrelated) to 1.0 (same words)
+// It takes the higher of word overlap (Dice coefficient) and normalized edit distance, so both
+// reworded ("Dragon lore" vs "Dragons") and misspelled variants are caught
+func subtopicSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(a))
+	for _, tok := range a {
+		counts[tok]++
+	}
+	shared := 0
+	for _, tok := range b {
+		if counts[tok] > 0 {
+			counts[tok]--
+			shared++
+		}
+	}
+	dice := 2 * float64(shared) / float64(len(a)+len(b))
+
+	ra := []rune(strings.Join(a, " "))
+	rb := []rune(strings.Join(b, " "))
+	edit := 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+
+	return max(dice, edit)
+}
+
+// similarityTokens lowercases, splits on non-alphanumerics, and strips plural "s" so
+// "Dragons" and "dragon" compare equal
+func similarityTokens(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, f := range fields {
+		if len(f) > 3 && strings.HasSuffix(f, "s") && !strings.HasSuffix(f, "ss") {
+			fields[i] = f[:len(f)-1]
+		}
+	}
+	return fields
+}
+
+// levenshtein returns the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
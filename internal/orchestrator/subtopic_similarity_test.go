@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubtopicSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"Dragons", "dragon", 1.0, 1.0},
+		{"Dragons", "Dragon lore", 0.6, 0.7},
+		{"Ancient dragon lore", "Dragon lore, ancient", 1.0, 1.0},
+		{"Elven kingdoms", "Elven kingdom", 1.0, 1.0},
+		{"Necromancy", "Necromancey", 0.9, 0.95},
+		{"Sea monsters", "Desert empires", 0.0, 0.4},
+		{"Magic schools", "Schools of magic", 0.8, 0.8},
+		{"", "Dragons", 0.0, 0.0},
+	}
+
+	for _, tt := range tests {
+		got := subtopicSimilarity(similarityTokens(tt.a), similarityTokens(tt.b))
+		if got < tt.min || got > tt.max {
+			t.Errorf("subtopicSimilarity(%q, %q): expected %.2f-%.2f, got %.2f", tt.a, tt.b, tt.min, tt.max, got)
+		}
+	}
+}
+
+func TestDeduplicateSimilar(t *testing.T) {
+	input := []string{"Dragons", "Sea monsters", "Dragon lore", "Desert empires", "Sea monster"}
+
+	kept, removed := deduplicateSimilar(input, 0.6)
+
+	wantKept := []string{"Dragons", "Sea monsters", "Desert empires"}
+	if !reflect.DeepEqual(kept, wantKept) {
+		t.Errorf("Expected kept %v, got %v", wantKept, kept)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("Expected 2 removed, got %d", len(removed))
+	}
+	if removed[0].removed != "Dragon lore" || removed[0].similarTo != "Dragons" {
+		t.Errorf("Expected Dragon lore removed as similar to Dragons, got %+v", removed[0])
+	}
+	if removed[1].removed != "Sea monster" || removed[1].similarTo != "Sea monsters" {
+		t.Errorf("Expected Sea monster removed as similar to Sea monsters, got %+v", removed[1])
+	}
+
+	// A threshold of 1.0 only drops subtopics with the same words
+	kept, _ = deduplicateSimilar(input, 1.0)
+	if len(kept) != 4 {
+		t.Errorf("Expected 4 kept at threshold 1.0, got %v", kept)
+	}
+}
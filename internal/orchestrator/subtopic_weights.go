@@ -0,0 +1,107 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// subtopicWeightKey is the object field holding a subtopic's weight (generation.weighted_subtopics)
+const subtopicWeightKey = "weight"
+
+// recordSubtopicWeights keeps the weights found in a parsed subtopic response
+// The first weight seen for a subtopic wins, matching which duplicate deduplication keeps
+func (o *Orchestrator) recordSubtopicWeights(jsonStr string) {
+	for key, weight := range ElementWeights(jsonStr, subtopicWeightKey, o.cfg.Generation.MaxSubtopicWeight) {
+		if o.subtopicWeights == nil {
+			o.subtopicWeights = make(map[string]int)
+		}
+		if _, seen := o.subtopicWeights[key]; !seen {
+			o.subtopicWeights[key] = weight
+		}
+	}
+}
+
+// keepSubtopicWeights drops weights of subtopics that did not make the final list, and of
+// weight 1 since that is the default, returning what is left for the checkpoint
+func (o *Orchestrator) keepSubtopicWeights(subtopics []string) map[string]int {
+	if len(o.subtopicWeights) == 0 {
+		return nil
+	}
+
+	kept := make(map[string]int)
+	total := 0
+	for _, subtopic := range subtopics {
+		key := subtopicWeightLookup(subtopic)
+		if weight := o.subtopicWeights[key]; weight > 1 {
+			kept[key] = weight
+		}
+		total += o.subtopicWeight(subtopic)
+	}
+	o.subtopicWeights = kept
+
+	o.logger.Info("Subtopic weights applied",
+		"weighted_subtopics", len(kept),
+		"total_weight", total,
+		"expected_prompts", total*o.cfg.Generation.NumPromptsPerSubtopic)
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// weightsFor returns the kept weights of subtopics without changing the orchestrator's weights
+func (o *Orchestrator) weightsFor(subtopics []string) map[string]int {
+	var weights map[string]int
+	for _, subtopic := range subtopics {
+		key := subtopicWeightLookup(subtopic)
+		if weight := o.subtopicWeights[key]; weight > 1 {
+			if weights == nil {
+				weights = make(map[string]int)
+			}
+			weights[key] = weight
+		}
+	}
+	return weights
+}
+
+// subtopicWeight returns the prompt weight of a subtopic (1 unless weighted)
+func (o *Orchestrator) subtopicWeight(subtopic string) int {
+	if weight, ok := o.subtopicWeights[subtopicWeightLookup(subtopic)]; ok {
+		return weight
+	}
+	return 1
+}
+
+// promptsForSubtopic returns how many prompts to request for a subtopic
+func (o *Orchestrator) promptsForSubtopic(subtopic string) int {
+	return o.cfg.Generation.NumPromptsPerSubtopic * o.subtopicWeight(subtopic)
+}
+
+// expectedPromptCount returns the prompts the subtopics should yield in total
+func (o *Orchestrator) expectedPromptCount(subtopics []string) int {
+	total := 0
+	for _, subtopic := range subtopics {
+		total += o.promptsForSubtopic(subtopic)
+	}
+	return total
+}
+
+// promptCacheKey keys cached prompts by main topic and subtopic, plus the weight when it changes the
+// prompt count and the sample count when prompts are merged from several samples
+// The main topic is always part of the key: prompt templates render {{.MainTopic}}, and with
+// generation.main_topics subtopics may repeat across topics
+func (o *Orchestrator) promptCacheKey(mainTopic, subtopic string) string {
+	key := mainTopic + "\x00" + subtopic
+	if weight := o.subtopicWeight(subtopic); weight > 1 {
+		key = fmt.Sprintf("%s\x00x%d", key, weight)
+	}
+	if samples := o.cfg.Generation.PromptSamples; samples > 1 {
+		key = fmt.Sprintf("%s\x00s%d", key, samples)
+	}
+	return key
+}
+
+// subtopicWeightLookup normalizes a subtopic the way deduplicateStrings compares them
+func subtopicWeightLookup(subtopic string) string {
+	return strings.ToLower(strings.TrimSpace(subtopic))
+}
@@ -0,0 +1,79 @@
+package orchestrator
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestElementWeights(t *testing.T) {
+	input := `[{"topic": "Dragons", "weight": 3}, "Elves", {"topic": " ORCS ", "weight": 2.6},
+		{"topic": "Giants", "weight": 50}, {"topic": "Imps", "weight": 0}, {"topic": "Trolls"},
+		{"topic": "dragons", "weight": 1}, {"topic": "Gnomes", "weight": "high"}]`
+
+	got := ElementWeights(input, "weight", 5)
+	want := map[string]int{"dragons": 3, "orcs": 3, "giants": 5, "imps": 1}
+	if len(got) != len(want) {
+		t.Fatalf("Expected weights %v, got %v", want, got)
+	}
+	for key, weight := range want {
+		if got[key] != weight {
+			t.Errorf("Expected weight %d for %q, got %d", weight, key, got[key])
+		}
+	}
+
+	if weights := ElementWeights(`not json`, "weight", 5); len(weights) != 0 {
+		t.Errorf("Expected no weights for invalid JSON, got %v", weights)
+	}
+}
+
+func TestSubtopicWeights(t *testing.T) {
+	orch := &Orchestrator{
+		cfg: &config.Config{Generation: config.GenerationConfig{
+			NumPromptsPerSubtopic: 4,
+			WeightedSubtopics:     true,
+			MaxSubtopicWeight:     5,
+		}},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	orch.recordSubtopicWeights(`[{"topic": "Dragons", "weight": 3}, {"topic": "Elves", "weight": 1}, {"topic": "Orcs", "weight": 2}]`)
+	// A later chunk repeating a subtopic does not override its first weight
+	orch.recordSubtopicWeights(`[{"topic": "dragons", "weight": 5}]`)
+
+	// Orcs lost deduplication, so only Dragons keeps a non-default weight
+	subtopics := []string{"Dragons", "Elves", "Unweighted"}
+	kept := orch.keepSubtopicWeights(subtopics)
+	if len(kept) != 1 || kept["dragons"] != 3 {
+		t.Errorf("Expected only dragons weight 3 kept, got %v", kept)
+	}
+
+	if got := orch.promptsForSubtopic("Dragons"); got != 12 {
+		t.Errorf("Expected 12 prompts for Dragons, got %d", got)
+	}
+	if got := orch.promptsForSubtopic("Unweighted"); got != 4 {
+		t.Errorf("Expected default 4 prompts, got %d", got)
+	}
+	if got := orch.expectedPromptCount(subtopics); got != 20 {
+		t.Errorf("Expected 20 prompts in total, got %d", got)
+	}
+	if orch.promptCacheKey("Fantasy", "Elves") != "Fantasy\x00Elves" || orch.promptCacheKey("Fantasy", "Dragons") == "Fantasy\x00Dragons" {
+		t.Error("Expected only weighted subtopics to get a distinct cache key")
+	}
+}
+
+func TestSubtopicWeights_Unweighted(t *testing.T) {
+	orch := &Orchestrator{
+		cfg:    &config.Config{Generation: config.GenerationConfig{NumPromptsPerSubtopic: 2}},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	if kept := orch.keepSubtopicWeights([]string{"a", "b"}); kept != nil {
+		t.Errorf("Expected no weights without weighted subtopics, got %v", kept)
+	}
+	if got := orch.expectedPromptCount([]string{"a", "b"}); got != 4 {
+		t.Errorf("Expected 4 prompts, got %d", got)
+	}
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import "testing"
+
+func TestShouldSwapModels_Bounds(t *testing.T) {
+	for jobID := 0; jobID < 100; jobID++ {
+		if shouldSwapModels(42, jobID, 0) {
+			t.Fatalf("Expected no swap with probability 0 (job %d)", jobID)
+		}
+		if !shouldSwapModels(42, jobID, 1) {
+			t.Fatalf("Expected swap with probability 1 (job %d)", jobID)
+		}
+	}
+}
+
+func TestShouldSwapModels_Deterministic(t *testing.T) {
+	for jobID := 0; jobID < 100; jobID++ {
+		first := shouldSwapModels(7, jobID, 0.5)
+		second := shouldSwapModels(7, jobID, 0.5)
+		if first != second {
+			t.Fatalf("Expected same decision for seed 7 job %d, got %v then %v", jobID, first, second)
+		}
+	}
+}
+
+func TestShouldSwapModels_Rate(t *testing.T) {
+	const jobs = 10000
+	swapped := 0
+	for jobID := 0; jobID < jobs; jobID++ {
+		if shouldSwapModels(1, jobID, 0.25) {
+			swapped++
+		}
+	}
+
+	rate := float64(swapped) / jobs
+	if rate < 0.22 || rate > 0.28 {
+		t.Errorf("Expected swap rate near 0.25, got %.3f", rate)
+	}
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import "testing"
+
+func TestJitterTemperature_Disabled(t *testing.T) {
+	if got := jitterTemperature(42, 3, jitterPhaseChosen, 0.7, 0); got != 0.7 {
+		t.Errorf("Expected unchanged temperature with jitter 0, got %v", got)
+	}
+}
+
+func TestJitterTemperature_BoundedAndDeterministic(t *testing.T) {
+	differs := false
+	for jobID := 0; jobID < 1000; jobID++ {
+		chosen := jitterTemperature(7, jobID, jitterPhaseChosen, 1.0, 0.3)
+		if chosen < 0.7 || chosen > 1.3 {
+			t.Fatalf("Expected temperature within 1.0 +/- 0.3 (job %d), got %v", jobID, chosen)
+		}
+		if again := jitterTemperature(7, jobID, jitterPhaseChosen, 1.0, 0.3); again != chosen {
+			t.Fatalf("Expected same temperature for seed 7 job %d, got %v then %v", jobID, chosen, again)
+		}
+		if jitterTemperature(7, jobID, jitterPhaseRejected, 1.0, 0.3) != chosen {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Error("Expected chosen and rejected phases to use independent jitter")
+	}
+}
+
+func TestJitterTemperature_Clamped(t *testing.T) {
+	for jobID := 0; jobID < 1000; jobID++ {
+		if got := jitterTemperature(1, jobID, jitterPhaseChosen, 0.1, 0.5); got < 0 {
+			t.Fatalf("Expected temperature clamped at 0 (job %d), got %v", jobID, got)
+		}
+		if got := jitterTemperature(1, jobID, jitterPhaseRejected, 1.9, 0.5); got > 2 {
+			t.Fatalf("Expected temperature clamped at 2 (job %d), got %v", jobID, got)
+		}
+	}
+}
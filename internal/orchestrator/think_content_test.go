@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestSplitEmbeddedThink(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		reasoning     string
+		wantAnswer    string
+		wantReasoning string
+	}{
+		{"no tags", "Just an answer.", "", "Just an answer.", ""},
+		{"embedded think", "<think>plan the scene</think>\n\nThe dragon slept.", "", "The dragon slept.", "plan the scene"},
+		{"thinking tag", "<thinking>hmm</thinking>Answer", "", "Answer", "hmm"},
+		{"dedicated field first", "<think>more</think>Answer", "field reasoning", "Answer", "field reasoning\n\nmore"},
+		{"dedicated field only", "Answer", "field reasoning", "Answer", "field reasoning"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, reasoning := splitEmbeddedThink(tt.content, tt.reasoning)
+			if answer != tt.wantAnswer {
+				t.Errorf("Expected answer %q, got %q", tt.wantAnswer, answer)
+			}
+			if reasoning != tt.wantReasoning {
+				t.Errorf("Expected reasoning %q, got %q", tt.wantReasoning, reasoning)
+			}
+		})
+	}
+}
+
+func TestGenerateRejectedResponse_StripThinkFromContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"<think>keep it flat</think>A plain story."},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		strip         bool
+		wantContent   string
+		wantReasoning string
+	}{
+		{"disabled keeps tags in content", false, "<think>keep it flat</think>A plain story.", ""},
+		{"enabled routes think to reasoning", true, "A plain story.", "keep it flat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			orch := &Orchestrator{
+				cfg: &config.Config{
+					Generation: config.GenerationConfig{
+						StripThinkFromContent:    tt.strip,
+						ReasoningCaptureRejected: true,
+					},
+					PromptTemplates: config.PromptTemplates{RejectedGeneration: "Write badly: {{.Prompt}}"},
+				},
+				secrets:   &config.Secrets{},
+				apiClient: api.NewClient(logger),
+				logger:    logger,
+				stats:     &models.SessionStats{},
+			}
+			model := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				RateLimitPerMinute: 6000,
+			}
+
+			rejection, err := orch.generateRejectedResponse(context.Background(), logger, models.GenerationJob{Prompt: "A story"}, "", model)
+			if err != nil {
+				t.Fatalf("generateRejectedResponse returned unexpected error: %v", err)
+			}
+			if rejection.Content != tt.wantContent {
+				t.Errorf("Expected content %q, got %q", tt.wantContent, rejection.Content)
+			}
+			if rejection.Reasoning != tt.wantReasoning {
+				t.Errorf("Expected reasoning %q, got %q", tt.wantReasoning, rejection.Reasoning)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// stringItemSchema is the item schema of subtopic and prompt lists
+var stringItemSchema = map[string]interface{}{"type": "string"}
+
+// weightedSubtopicSchema is the item schema of generation.weighted_subtopics lists
+var weightedSubtopicSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"topic":           map[string]interface{}{"type": "string"},
+		subtopicWeightKey: map[string]interface{}{"type": "integer"},
+	},
+	"required":             []string{"topic", subtopicWeightKey},
+	"additionalProperties": false,
+}
+
+// requestItems sends a planning request (subtopics or prompts) and returns the response text
+// to parse as a JSON array. With models.main.use_tool_calling the model is forced to call a
+// function returning {"items": [...]} and the items array is returned instead of the content
+func (o *Orchestrator) requestItems(
+	ctx context.Context,
+	model config.ModelConfig,
+	apiKey string,
+	messages []api.Message,
+	description string,
+	itemSchema map[string]interface{},
+) (string, error) {
+	var resp *api.ChatCompletionResponse
+	var err error
+	if model.UseToolCalling {
+		resp, err = o.apiClient.ChatCompletionWithTool(ctx, model, apiKey, messages, api.ItemsTool(description, itemSchema))
+	} else {
+		resp, err = o.apiClient.ChatCompletionStructured(ctx, model, apiKey, messages)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("API returned empty response")
+	}
+	// Absent after the JSON mode fallback, or when the model ignored tool_choice
+	if arguments := resp.ToolArguments(); arguments != "" {
+		return toolCallItems(arguments), nil
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// toolCallItems returns the items array of the tool call arguments, or the arguments as-is
+// when they don't parse so the usual JSON extraction and repair still get a chance
+func toolCallItems(arguments string) string {
+	var args struct {
+		Items json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil || len(args.Items) == 0 {
+		return arguments
+	}
+	return string(args.Items)
+}
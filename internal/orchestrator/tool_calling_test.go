@@ -0,0 +1,24 @@
+package orchestrator
+
+import "testing"
+
+func TestToolCallItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		want      string
+	}{
+		{"items array", `{"items":["a","b"]}`, `["a","b"]`},
+		{"weighted items", `{"items":[{"topic":"a","weight":2}]}`, `[{"topic":"a","weight":2}]`},
+		{"missing items", `{"subtopics":["a"]}`, `{"subtopics":["a"]}`},
+		{"malformed", `{"items":["a",`, `{"items":["a",`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toolCallItems(tt.arguments); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package orchestrator
+
+import (
+	"context"
+	"maps"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// topicSubtopics is the subtopic list of one main topic
+type topicSubtopics struct {
+	topic     string
+	subtopics []string
+}
+
+// flattenSubtopics returns the subtopics of every main topic in topic order
+func flattenSubtopics(groups []topicSubtopics) []string {
+	var all []string
+	for _, group := range groups {
+		all = append(all, group.subtopics...)
+	}
+	return all
+}
+
+// checkpointSubtopics rebuilds the per-topic subtopic lists of a checkpoint whose subtopics
+// phase is complete
+func checkpointSubtopics(cp *models.Checkpoint, topics []string) []topicSubtopics {
+	if len(cp.TopicSubtopics) == 0 {
+		return []topicSubtopics{{topic: topics[0], subtopics: cp.Subtopics}}
+	}
+	groups := make([]topicSubtopics, 0, len(topics))
+	for _, topic := range topics {
+		if subtopics, ok := cp.TopicSubtopics[topic]; ok {
+			groups = append(groups, topicSubtopics{topic: topic, subtopics: subtopics})
+		}
+	}
+	return groups
+}
+
+// resumeCheckpoint returns the checkpoint to resume from, or nil on a fresh run
+func (o *Orchestrator) resumeCheckpoint() *models.Checkpoint {
+	if !o.resumeMode || o.checkpointMgr == nil {
+		return nil
+	}
+	return o.checkpointMgr.GetCheckpoint()
+}
+
+// runSubtopicPhase generates the subtopics of every main topic (phase 1)
+// With generation.main_topics each finished topic is checkpointed, so a resumed run only
+// generates subtopics for the topics it had not reached yet
+func (o *Orchestrator) runSubtopicPhase(ctx context.Context) ([]topicSubtopics, error) {
+	topics := o.cfg.Generation.Topics()
+	multiTopic := o.cfg.Generation.MultiTopic()
+
+	cp := o.resumeCheckpoint()
+	if cp != nil && cp.SubtopicsComplete {
+		o.subtopicWeights = cp.SubtopicWeights
+		o.logger.Info("Resuming from checkpoint: subtopics phase complete", "count", len(cp.Subtopics))
+		return checkpointSubtopics(cp, topics), nil
+	}
+	if cp != nil && len(cp.TopicSubtopics) > 0 {
+		// Weights of the finished topics are only in the checkpoint now
+		o.subtopicWeights = maps.Clone(cp.SubtopicWeights)
+	}
+
+	groups := make([]topicSubtopics, 0, len(topics))
+	for _, topic := range topics {
+		if cp != nil {
+			if subtopics, ok := cp.TopicSubtopics[topic]; ok {
+				o.logger.Info("Resuming from checkpoint: subtopics of topic complete",
+					"main_topic", topic,
+					"count", len(subtopics))
+				groups = append(groups, topicSubtopics{topic: topic, subtopics: subtopics})
+				continue
+			}
+		}
+
+		if multiTopic {
+			o.logger.Info("Generating subtopics for topic", "main_topic", topic)
+		}
+		subtopics, err := o.generateSubtopics(ctx, topic)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, topicSubtopics{topic: topic, subtopics: subtopics})
+
+		if multiTopic && o.checkpointMgr != nil {
+			if err := o.checkpointMgr.MarkTopicSubtopicsComplete(topic, subtopics, o.weightsFor(subtopics)); err != nil {
+				o.logger.Warn("Failed to save topic subtopics checkpoint", "main_topic", topic, "error", err)
+			}
+		}
+	}
+
+	subtopics := flattenSubtopics(groups)
+	weights := o.keepSubtopicWeights(subtopics)
+	if o.checkpointMgr != nil {
+		if err := o.checkpointMgr.MarkSubtopicsComplete(subtopics, weights); err != nil {
+			o.logger.Warn("Failed to save subtopics checkpoint", "error", err)
+		}
+	}
+	return groups, nil
+}
+
+// runPromptPhase generates the prompts of every main topic's subtopics (phase 2)
+// Job IDs continue across topics; with generation.main_topics each finished topic is
+// checkpointed, so a resumed run keeps its jobs and only generates the remaining topics
+func (o *Orchestrator) runPromptPhase(ctx context.Context, groups []topicSubtopics) ([]models.GenerationJob, error) {
+	multiTopic := o.cfg.Generation.MultiTopic()
+
+	cp := o.resumeCheckpoint()
+	if cp != nil && cp.PromptsComplete {
+		o.logger.Info("Resuming from checkpoint: prompts phase complete", "count", len(cp.Prompts))
+		return cp.Prompts, nil
+	}
+
+	var prompts []models.GenerationJob
+	done := make(map[string]bool)
+	if cp != nil && len(cp.PromptTopicsDone) > 0 {
+		prompts = cp.Prompts
+		for _, topic := range cp.PromptTopicsDone {
+			done[topic] = true
+		}
+	}
+
+	for _, group := range groups {
+		if done[group.topic] {
+			o.logger.Info("Resuming from checkpoint: prompts of topic complete", "main_topic", group.topic)
+			continue
+		}
+
+		if multiTopic {
+			o.logger.Info("Generating prompts for topic",
+				"main_topic", group.topic,
+				"subtopics", len(group.subtopics))
+		}
+		jobs, err := o.generatePrompts(ctx, group.topic, group.subtopics, len(prompts))
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, jobs...)
+
+		if multiTopic && o.checkpointMgr != nil {
+			if err := o.checkpointMgr.MarkTopicPromptsComplete(group.topic, jobs); err != nil {
+				o.logger.Warn("Failed to save topic prompts checkpoint", "main_topic", group.topic, "error", err)
+			}
+		}
+	}
+
+	if o.checkpointMgr != nil {
+		if err := o.checkpointMgr.MarkPromptsComplete(prompts); err != nil {
+			o.logger.Warn("Failed to save prompts checkpoint", "error", err)
+		}
+	}
+	return prompts, nil
+}
@@ -0,0 +1,86 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestPromptCacheKeyMainTopics(t *testing.T) {
+	// A single-topic run whose main_topic changed must not reuse prompts for the old topic
+	single := &Orchestrator{cfg: &config.Config{Generation: config.GenerationConfig{MainTopic: "Fantasy"}}}
+	if single.promptCacheKey("Fantasy", "Dragons") == single.promptCacheKey("Science Fiction", "Dragons") {
+		t.Error("Expected the main topic to be part of the single-topic cache key")
+	}
+
+	multi := &Orchestrator{cfg: &config.Config{Generation: config.GenerationConfig{MainTopics: []string{"Fantasy", "History"}}}}
+	if multi.promptCacheKey("Fantasy", "Dragons") == multi.promptCacheKey("History", "Dragons") {
+		t.Error("Expected a subtopic repeated across topics to get distinct cache keys")
+	}
+}
+
+func TestWriteRecordMainTopic(t *testing.T) {
+	result := models.GenerationResult{
+		Job:      models.GenerationJob{MainTopic: "History", Prompt: "prompt"},
+		Chosen:   "chosen",
+		Rejected: "rejected",
+	}
+
+	tests := []struct {
+		name string
+		gen  config.GenerationConfig
+		want string
+	}{
+		{"single topic leaves the column out", config.GenerationConfig{MainTopic: "History"}, ""},
+		{"main_topics tags the record", config.GenerationConfig{MainTopics: []string{"Fantasy", "History"}}, "History"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &preferenceWriter{}
+			orch := &Orchestrator{cfg: &config.Config{Generation: tt.gen}, dataWriter: writer}
+
+			if err := orch.writeDPORecord(result); err != nil {
+				t.Fatalf("writeDPORecord returned error: %v", err)
+			}
+			if err := orch.writeKTORecord(result); err != nil {
+				t.Fatalf("writeKTORecord returned error: %v", err)
+			}
+
+			if len(writer.dpoRecords) != 1 || writer.dpoRecords[0].MainTopic != tt.want {
+				t.Errorf("Expected DPO main_topic %q, got %+v", tt.want, writer.dpoRecords)
+			}
+			if len(writer.ktoRecords) != 2 {
+				t.Fatalf("Expected 2 KTO rows, got %d", len(writer.ktoRecords))
+			}
+			for i, record := range writer.ktoRecords {
+				if record.MainTopic != tt.want {
+					t.Errorf("Row %d: expected KTO main_topic %q, got %q", i, tt.want, record.MainTopic)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckpointSubtopics(t *testing.T) {
+	single := checkpointSubtopics(&models.Checkpoint{Subtopics: []string{"a", "b"}}, []string{"Fantasy"})
+	if len(single) != 1 || single[0].topic != "Fantasy" || len(single[0].subtopics) != 2 {
+		t.Errorf("Expected one Fantasy group with 2 subtopics, got %+v", single)
+	}
+
+	cp := &models.Checkpoint{
+		Subtopics: []string{"a", "b", "c"},
+		TopicSubtopics: map[string][]string{
+			"History": {"c"},
+			"Fantasy": {"a", "b"},
+		},
+	}
+	groups := checkpointSubtopics(cp, []string{"Fantasy", "History"})
+	if len(groups) != 2 || groups[0].topic != "Fantasy" || groups[1].topic != "History" {
+		t.Fatalf("Expected groups in main_topics order, got %+v", groups)
+	}
+	if got := flattenSubtopics(groups); len(got) != 3 || got[0] != "a" || got[2] != "c" {
+		t.Errorf("Expected [a b c], got %v", got)
+	}
+}
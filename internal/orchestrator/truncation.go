@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// raisedMaxTokens returns the max_output_tokens for a truncation retry: current * factor,
+// capped so the estimated prompt plus output still fit in context_size
+// Returns 0 when there is no room to raise the limit
+func raisedMaxTokens(current, contextSize, promptTokens int, factor float64) int {
+	raised := min(int(float64(current)*factor), contextSize-promptTokens)
+	if raised <= current {
+		return 0
+	}
+	return raised
+}
+
+// retryTruncated re-sends a chosen request that stopped at finish_reason "length" once with a
+// higher max_output_tokens (generation.retry_on_truncation)
+// The original response is returned when the limit can't be raised or the retry fails, so the
+// job is validated exactly as it would be with the option off; the retry is counted in attempts
+func (o *Orchestrator) retryTruncated(
+	ctx context.Context,
+	logger *slog.Logger,
+	job models.GenerationJob,
+	model config.ModelConfig,
+	apiKey string,
+	messages []api.Message,
+	resp *api.ChatCompletionResponse,
+	attempts *models.AttemptCounts,
+) *api.ChatCompletionResponse {
+	promptTokens := o.apiClient.EstimatePromptTokens(messages)
+	raised := raisedMaxTokens(model.MaxOutputTokens, model.ContextSize, promptTokens, o.cfg.Generation.TruncationRetryFactor)
+	if raised == 0 {
+		logger.Debug("Response truncated but max_output_tokens cannot be raised within context_size",
+			"job_id", job.ID,
+			"max_tokens", model.MaxOutputTokens,
+			"context_size", model.ContextSize,
+			"estimated_prompt_tokens", promptTokens)
+		return resp
+	}
+
+	o.truncationRetries.Add(1)
+	attempts.Content++
+	logger.Info("Response truncated at max_output_tokens, retrying with a higher limit",
+		"job_id", job.ID,
+		"model", model.ModelName,
+		"max_tokens", model.MaxOutputTokens,
+		"retry_max_tokens", raised)
+
+	model.MaxOutputTokens = raised
+	var retryResp *api.ChatCompletionResponse
+	var err error
+	if model.UseStreaming {
+		retryResp, err = o.apiClient.ChatCompletionStreaming(ctx, model, apiKey, messages)
+	} else {
+		retryResp, err = o.apiClient.ChatCompletion(ctx, model, apiKey, messages)
+	}
+	if err != nil {
+		logger.Warn("Truncation retry failed, keeping truncated response",
+			"job_id", job.ID,
+			"retry_max_tokens", raised,
+			"error", err)
+		return resp
+	}
+	attempts.Network += networkRetries(retryResp)
+
+	if retryResp.Choices[0].FinishReason == "length" {
+		logger.Warn("Response still truncated after raising max_output_tokens",
+			"job_id", job.ID,
+			"retry_max_tokens", raised)
+	} else {
+		o.truncationRecoveries.Add(1)
+	}
+	return retryResp
+}
+
+// syncTruncationStats copies the truncation retry counters into the session stats
+func (o *Orchestrator) syncTruncationStats() {
+	o.stats.TruncationRetries = int(o.truncationRetries.Load())
+	o.stats.TruncationRecoveries = int(o.truncationRecoveries.Load())
+}
@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestRaisedMaxTokens(t *testing.T) {
+	tests := []struct {
+		name         string
+		current      int
+		contextSize  int
+		promptTokens int
+		factor       float64
+		want         int
+	}{
+		{"scaled", 1000, 8192, 100, 2.0, 2000},
+		{"capped by context", 4000, 8192, 1192, 2.0, 7000},
+		{"no room", 4000, 4100, 100, 2.0, 0},
+		{"prompt fills context", 1000, 2048, 2000, 2.0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := raisedMaxTokens(tt.current, tt.contextSize, tt.promptTokens, tt.factor); got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRetryTruncated(t *testing.T) {
+	tests := []struct {
+		name          string
+		contextSize   int
+		wantRequests  int
+		wantMaxTokens int
+		wantFinish    string
+		wantRetries   int
+		wantRecovered int
+	}{
+		{"recovered", 1000, 1, 200, "stop", 1, 1},
+		{"no room to raise", 105, 0, 0, "length", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			var maxTokens int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				var req struct {
+					MaxTokens int `json:"max_tokens"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				maxTokens = req.MaxTokens
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"The end."},"finish_reason":"stop"}]}`))
+			}))
+			defer server.Close()
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			orch := &Orchestrator{
+				cfg:       &config.Config{Generation: config.GenerationConfig{RetryOnTruncation: true, TruncationRetryFactor: 2.0}},
+				apiClient: api.NewClient(logger),
+				logger:    logger,
+				stats:     &models.SessionStats{},
+			}
+			model := config.ModelConfig{
+				BaseURL:            server.URL,
+				ModelName:          "test-model",
+				MaxOutputTokens:    100,
+				ContextSize:        tt.contextSize,
+				RateLimitPerMinute: 60,
+			}
+			messages := []api.Message{{Role: "user", Content: "Write a story."}}
+			truncated := &api.ChatCompletionResponse{Choices: []api.Choice{{FinishReason: "length"}}}
+
+			var attempts models.AttemptCounts
+			resp := orch.retryTruncated(context.Background(), logger, models.GenerationJob{ID: 1}, model, "", messages, truncated, &attempts)
+			orch.syncTruncationStats()
+
+			if requests != tt.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tt.wantRequests, requests)
+			}
+			if maxTokens != tt.wantMaxTokens {
+				t.Errorf("Expected retry max_tokens %d, got %d", tt.wantMaxTokens, maxTokens)
+			}
+			if got := resp.Choices[0].FinishReason; got != tt.wantFinish {
+				t.Errorf("Expected finish_reason %q, got %q", tt.wantFinish, got)
+			}
+			if orch.stats.TruncationRetries != tt.wantRetries || orch.stats.TruncationRecoveries != tt.wantRecovered {
+				t.Errorf("Expected %d retries and %d recoveries, got %d and %d",
+					tt.wantRetries, tt.wantRecovered, orch.stats.TruncationRetries, orch.stats.TruncationRecoveries)
+			}
+			if attempts != (models.AttemptCounts{Content: tt.wantRetries}) {
+				t.Errorf("Expected %d content retries and no network retries, got %+v", tt.wantRetries, attempts)
+			}
+		})
+	}
+}
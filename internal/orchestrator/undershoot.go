@@ -0,0 +1,50 @@
+package orchestrator
+
+import "context"
+
+const (
+	// maxUndershootRetries bounds the extra requests sent when a list request undershoots
+	maxUndershootRetries = 2
+)
+
+// isUndershoot reports whether a list response is drastically short of the requested count
+// (under half), which usually means a confused model rather than normal undershoot
+func isUndershoot(received, requested int) bool {
+	return received*2 < requested
+}
+
+// retryOnUndershoot sends a list request and, with generation.undershoot_retry enabled, re-sends
+// it up to maxUndershootRetries times while the result is under half the requested count
+// The largest result is kept; a failed retry keeps the result already in hand
+func (o *Orchestrator) retryOnUndershoot(ctx context.Context, kind string, requested int, request func() ([]string, error)) ([]string, error) {
+	items, err := request()
+	if err != nil || !o.cfg.Generation.UndershootRetry {
+		return items, err
+	}
+
+	for attempt := 1; attempt <= maxUndershootRetries && isUndershoot(len(items), requested); attempt++ {
+		if ctx.Err() != nil {
+			break
+		}
+		o.logger.Warn("Model returned far fewer items than requested, retrying",
+			"kind", kind,
+			"requested", requested,
+			"received", len(items),
+			"attempt", attempt,
+			"max_attempts", maxUndershootRetries)
+
+		retry, retryErr := request()
+		if retryErr != nil {
+			o.logger.Warn("Undershoot retry failed, keeping previous result",
+				"kind", kind,
+				"received", len(items),
+				"error", retryErr)
+			break
+		}
+		if len(retry) > len(items) {
+			items = retry
+		}
+	}
+
+	return items, nil
+}
@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestRetryOnUndershoot(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		requested int
+		responses [][]string // nil entry = request error
+		wantLen   int
+		wantCalls int
+	}{
+		{
+			name:      "disabled",
+			enabled:   false,
+			requested: 10,
+			responses: [][]string{{"a"}},
+			wantLen:   1,
+			wantCalls: 1,
+		},
+		{
+			name:      "half is not an undershoot",
+			enabled:   true,
+			requested: 10,
+			responses: [][]string{{"a", "b", "c", "d", "e"}},
+			wantLen:   5,
+			wantCalls: 1,
+		},
+		{
+			name:      "retries until enough",
+			enabled:   true,
+			requested: 10,
+			responses: [][]string{{"a"}, {"a", "b", "c", "d", "e", "f", "g", "h"}},
+			wantLen:   8,
+			wantCalls: 2,
+		},
+		{
+			name:      "bounded and keeps largest",
+			enabled:   true,
+			requested: 10,
+			responses: [][]string{{"a", "b"}, {"a"}, {"a", "b", "c"}, {"never", "sent"}},
+			wantLen:   3,
+			wantCalls: 1 + maxUndershootRetries,
+		},
+		{
+			name:      "failed retry keeps previous result",
+			enabled:   true,
+			requested: 10,
+			responses: [][]string{{"a", "b"}, nil},
+			wantLen:   2,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &Orchestrator{
+				cfg:    &config.Config{Generation: config.GenerationConfig{UndershootRetry: tt.enabled}},
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+
+			calls := 0
+			got, err := orch.retryOnUndershoot(context.Background(), "prompts", tt.requested, func() ([]string, error) {
+				resp := tt.responses[calls]
+				calls++
+				if resp == nil {
+					return nil, errors.New("API call failed")
+				}
+				return resp, nil
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("Expected %d items, got %d", tt.wantLen, len(got))
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Expected %d requests, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestRetryOnUndershoot_FirstRequestError(t *testing.T) {
+	orch := &Orchestrator{
+		cfg:    &config.Config{Generation: config.GenerationConfig{UndershootRetry: true}},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	calls := 0
+	_, err := orch.retryOnUndershoot(context.Background(), "subtopics", 10, func() ([]string, error) {
+		calls++
+		return nil, errors.New("API call failed")
+	})
+	if err == nil {
+		t.Fatal("Expected first request error to be returned")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 request, got %d", calls)
+	}
+}
@@ -0,0 +1,43 @@
+package orchestrator
+
+import (
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// normalizeText applies generation.normalize_unicode to s (unchanged when it is off)
+func (o *Orchestrator) normalizeText(s string) string {
+	switch o.cfg.Generation.NormalizeUnicode {
+	case config.UnicodeNFC:
+		return norm.NFC.String(s)
+	case config.UnicodeNFKC:
+		return norm.NFKC.String(s)
+	default:
+		return s
+	}
+}
+
+// normalizeResult normalizes the prompt, responses and reasoning of a result before it is written
+func (o *Orchestrator) normalizeResult(result models.GenerationResult) models.GenerationResult {
+	if o.cfg.Generation.NormalizeUnicode == "" {
+		return result
+	}
+
+	result.Job.Prompt = o.normalizeText(result.Job.Prompt)
+	result.Chosen = o.normalizeText(result.Chosen)
+	result.ChosenReasoning = o.normalizeText(result.ChosenReasoning)
+	result.Rejected = o.normalizeText(result.Rejected)
+	result.RejectedReasoning = o.normalizeText(result.RejectedReasoning)
+	if len(result.RejectedList) > 0 {
+		rejected := make([]models.RejectedResponse, len(result.RejectedList))
+		for i, r := range result.RejectedList {
+			r.Content = o.normalizeText(r.Content)
+			r.Reasoning = o.normalizeText(r.Reasoning)
+			rejected[i] = r
+		}
+		result.RejectedList = rejected
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		form  string
+		input string
+		want  string
+	}{
+		{"off keeps combining accent", "", "cafe\u0301", "cafe\u0301"},
+		{"nfc composes combining accent", config.UnicodeNFC, "cafe\u0301", "caf\u00e9"},
+		{"nfc keeps non-breaking space", config.UnicodeNFC, "10\u00a0km", "10\u00a0km"},
+		{"nfkc folds non-breaking space", config.UnicodeNFKC, "10\u00a0km", "10 km"},
+		{"nfkc folds ligature and full-width", config.UnicodeNFKC, "\ufb01ne \uff21", "fine A"},
+		{"nfkc folds ellipsis", config.UnicodeNFKC, "wait\u2026", "wait..."},
+		{"smart quotes are not compatibility characters", config.UnicodeNFKC, "\u201chi\u201d", "\u201chi\u201d"},
+		{"hangul jamo compose", config.UnicodeNFC, "\u1100\u1161", "\uac00"},
+		{"already normalized", config.UnicodeNFC, "plain ascii", "plain ascii"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &Orchestrator{cfg: &config.Config{Generation: config.GenerationConfig{NormalizeUnicode: tt.form}}}
+			if got := orch.normalizeText(tt.input); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeResult(t *testing.T) {
+	orch := &Orchestrator{cfg: &config.Config{Generation: config.GenerationConfig{NormalizeUnicode: config.UnicodeNFKC}}}
+	list := []models.RejectedResponse{{Content: "a\u00a0b", Reasoning: "\ufb01", Model: "m"}}
+	result := models.GenerationResult{
+		Job:               models.GenerationJob{Prompt: "cafe\u0301"},
+		Chosen:            "x\u00a0y",
+		ChosenReasoning:   "\uff21",
+		Rejected:          "a\u00a0b",
+		RejectedReasoning: "\ufb01",
+		RejectedList:      list,
+	}
+
+	got := orch.normalizeResult(result)
+	if got.Job.Prompt != "caf\u00e9" || got.Chosen != "x y" || got.ChosenReasoning != "A" ||
+		got.Rejected != "a b" || got.RejectedReasoning != "fi" {
+		t.Errorf("Expected every text field normalized, got %+v", got)
+	}
+	if got.RejectedList[0].Content != "a b" || got.RejectedList[0].Reasoning != "fi" || got.RejectedList[0].Model != "m" {
+		t.Errorf("Expected rejected candidates normalized, got %+v", got.RejectedList[0])
+	}
+	if list[0].Content != "a\u00a0b" {
+		t.Error("Expected the original rejected list to be left untouched")
+	}
+}
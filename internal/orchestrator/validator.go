@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 )
 
@@ -29,9 +30,24 @@ func ValidateJSONArray(jsonStr string) (bool, int, error) {
 	return true, len(arr), nil
 }
 
+// StringArrayOptions controls element limits and coercion in ValidateStringArrayWithOptions
+type StringArrayOptions struct {
+	MinCount      int    // Minimum non-empty elements required (0 = no minimum)
+	MaxCount      int    // Maximum non-empty elements allowed (0 = no maximum)
+	CoerceObjects bool   // Accept single-key objects like {"subtopic": "x"} and use their string value
+	WeightKey     string // With CoerceObjects: also accept {"topic": "x", "weight": 3}, ignoring the WeightKey field
+}
+
 // ValidateStringArray validates and unmarshals a JSON array of strings
 // Returns: (strings, actualCount, error)
 func ValidateStringArray(jsonStr string, expectedMin int) ([]string, int, error) {
+	return ValidateStringArrayWithOptions(jsonStr, StringArrayOptions{MinCount: expectedMin})
+}
+
+// ValidateStringArrayWithOptions validates and unmarshals a JSON array of strings,
+// optionally coercing single-key objects to strings and enforcing element limits
+// Returns: (strings, actualCount, error)
+func ValidateStringArrayWithOptions(jsonStr string, opts StringArrayOptions) ([]string, int, error) {
 	// Pre-validate structure and syntax
 	valid, _, err := ValidateJSONArray(jsonStr)
 	if !valid {
@@ -40,7 +56,12 @@ func ValidateStringArray(jsonStr string, expectedMin int) ([]string, int, error)
 
 	// Unmarshal
 	var items []string
-	if err := json.Unmarshal([]byte(jsonStr), &items); err != nil {
+	if opts.CoerceObjects {
+		items, err = coerceStringElements(jsonStr, opts.WeightKey)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else if err := json.Unmarshal([]byte(jsonStr), &items); err != nil {
 		return nil, 0, fmt.Errorf("failed to unmarshal strings: %w", err)
 	}
 
@@ -55,13 +76,109 @@ func ValidateStringArray(jsonStr string, expectedMin int) ([]string, int, error)
 
 	// Check count AFTER filtering
 	actualCount := len(validItems)
-	if actualCount < expectedMin {
-		return nil, actualCount, fmt.Errorf("insufficient elements: got %d, expected at least %d", actualCount, expectedMin)
+	if err := opts.checkCount(actualCount); err != nil {
+		return nil, actualCount, err
 	}
 
 	return validItems, actualCount, nil
 }
 
+// checkCount returns an error when count is outside the MinCount/MaxCount limits
+func (opts StringArrayOptions) checkCount(count int) error {
+	if count < opts.MinCount {
+		return fmt.Errorf("insufficient elements: got %d, expected at least %d", count, opts.MinCount)
+	}
+	if opts.MaxCount > 0 && count > opts.MaxCount {
+		return fmt.Errorf("too many elements: got %d, expected at most %d", count, opts.MaxCount)
+	}
+	return nil
+}
+
+// coerceStringElements unmarshals an array whose elements are strings or single-key
+// objects with a string value (e.g. [{"subtopic": "x"}, "y"] -> ["x", "y"])
+// A non-empty weightKey is dropped from objects before the single-key check
+func coerceStringElements(jsonStr, weightKey string) ([]string, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse array: %w", err)
+	}
+
+	items := make([]string, 0, len(raw))
+	for i, elem := range raw {
+		var str string
+		if err := json.Unmarshal(elem, &str); err == nil {
+			items = append(items, str)
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(elem, &obj); err != nil {
+			return nil, fmt.Errorf("element %d is neither a string nor an object: %s", i, truncateElement(elem))
+		}
+		if weightKey != "" {
+			delete(obj, weightKey)
+		}
+		if len(obj) != 1 {
+			return nil, fmt.Errorf("element %d is an object with %d keys, expected exactly 1: %s", i, len(obj), truncateElement(elem))
+		}
+		for key, value := range obj {
+			if err := json.Unmarshal(value, &str); err != nil {
+				return nil, fmt.Errorf("element %d key %q does not hold a string value", i, key)
+			}
+		}
+		items = append(items, str)
+	}
+
+	return items, nil
+}
+
+// ElementWeights reads the numeric weightKey of each object element in a JSON array
+// (e.g. [{"topic": "x", "weight": 3}, "y"] -> {"x": 3}), keyed like deduplicateStrings
+// (trimmed, lowercased); weights are rounded and clamped to 1..maxWeight
+// Elements without a usable weight are left out, so callers default them to 1
+func ElementWeights(jsonStr, weightKey string, maxWeight int) map[string]int {
+	weights := make(map[string]int)
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &raw); err != nil {
+		return weights
+	}
+
+	for _, elem := range raw {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(elem, &obj); err != nil {
+			continue // Plain strings carry no weight
+		}
+		var weight float64
+		if err := json.Unmarshal(obj[weightKey], &weight); err != nil {
+			continue
+		}
+		delete(obj, weightKey)
+		if len(obj) != 1 {
+			continue
+		}
+		for _, value := range obj {
+			var str string
+			if err := json.Unmarshal(value, &str); err != nil {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(str))
+			if _, seen := weights[key]; key != "" && !seen {
+				weights[key] = max(1, min(maxWeight, int(math.Round(weight))))
+			}
+		}
+	}
+	return weights
+}
+
+// truncateElement shortens a raw JSON element for error messages
+func truncateElement(elem json.RawMessage) string {
+	const maxLen = 80
+	if len(elem) <= maxLen {
+		return string(elem)
+	}
+	return string(elem[:maxLen]) + "..."
+}
+
 // deduplicateStrings removes duplicates while preserving order
 // Uses case-insensitive comparison for duplicate detection
 func deduplicateStrings(items []string) []string {
@@ -0,0 +1,35 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+)
+
+// startStaggered calls start for workers 0..n-1, spreading their starts evenly over warmup
+// (generation.warmup_seconds) so the provider sees a ramp instead of n simultaneous requests.
+// It returns immediately; once ctx is done the remaining workers start at once so they can exit
+// and release their WaitGroup slot. With warmup <= 0 every worker starts right away.
+func (o *Orchestrator) startStaggered(ctx context.Context, n int, warmup time.Duration, start func(i int)) {
+	if warmup <= 0 || n <= 1 {
+		for i := 0; i < n; i++ {
+			start(i)
+		}
+		return
+	}
+
+	interval := warmup / time.Duration(n)
+	o.logger.Info("Warming up workers", "workers", n, "warmup", warmup, "interval", interval)
+
+	start(0)
+	go func() {
+		ticker := time.NewTicker(max(interval, time.Millisecond))
+		defer ticker.Stop()
+		for i := 1; i < n; i++ {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+			start(i)
+		}
+	}()
+}
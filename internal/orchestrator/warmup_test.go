@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartStaggered(t *testing.T) {
+	orch := &Orchestrator{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	starts := make([]time.Time, 4)
+	wg.Add(len(starts))
+	began := time.Now()
+	orch.startStaggered(context.Background(), len(starts), 80*time.Millisecond, func(i int) {
+		mu.Lock()
+		starts[i] = time.Now()
+		mu.Unlock()
+		wg.Done()
+	})
+	wg.Wait()
+
+	if first := starts[0].Sub(began); first > 10*time.Millisecond {
+		t.Errorf("Expected the first worker to start immediately, started after %v", first)
+	}
+	for i := 1; i < len(starts); i++ {
+		if !starts[i].After(starts[i-1]) {
+			t.Errorf("Expected worker %d to start after worker %d", i, i-1)
+		}
+	}
+	if last := starts[3].Sub(began); last < 50*time.Millisecond {
+		t.Errorf("Expected the last worker to start near the end of the warmup, started after %v", last)
+	}
+}
+
+func TestStartStaggeredCanceled(t *testing.T) {
+	orch := &Orchestrator{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(10)
+	began := time.Now()
+	orch.startStaggered(ctx, 10, time.Hour, func(int) { wg.Done() })
+	wg.Wait()
+
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("Expected a canceled warmup to start every worker at once, took %v", elapsed)
+	}
+}
+
+func TestStartStaggeredNoWarmup(t *testing.T) {
+	orch := &Orchestrator{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	started := 0
+	orch.startStaggered(context.Background(), 5, 0, func(int) { started++ })
+	if started != 5 {
+		t.Errorf("Expected 5 workers started synchronously, got %d", started)
+	}
+}
@@ -2,8 +2,11 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"math/rand/v2"
 	"strings"
 	"sync"
 	"time"
@@ -11,14 +14,19 @@ import (
 	"github.com/schollz/progressbar/v3"
 
 	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
 	"github.com/lamim/vellumforge2/internal/util"
+	"github.com/lamim/vellumforge2/internal/writer"
 	"github.com/lamim/vellumforge2/pkg/models"
 )
 
 const resumeProgressRenderDelay = 100 * time.Millisecond
 
+// worker processes jobs until the channel is drained or ctx is done
+// Jobs run with jobCtx, which outlives ctx by the shutdown grace period (see jobContext)
 func (o *Orchestrator) worker(
 	ctx context.Context,
+	jobCtx context.Context,
 	workerID int,
 	jobs <-chan models.GenerationJob,
 	results chan<- models.GenerationResult,
@@ -39,8 +47,11 @@ func (o *Orchestrator) worker(
 
 		// Process job (judge runs async, no retries needed)
 		startTime := time.Now()
-		result := o.processJob(ctx, workerLogger, job)
+		result := o.processJob(jobCtx, workerLogger, job)
 		result.Duration = time.Since(startTime)
+		if jobCtx.Err() == nil {
+			o.timer.jobsProcessed.Add(1) // Jobs abandoned by cancellation would inflate throughput
+		}
 
 		results <- result
 	}
@@ -58,14 +69,46 @@ func (o *Orchestrator) processJob(
 		Job: job,
 	}
 
-	// Generate chosen response (main model)
+	// Pick models for each side; swap_probability occasionally puts the main model on the
+	// rejected side (hard negatives) and the rejected model on the chosen side
+	// Each role's failover switches it to models.<role>_fallback after repeated failures
+	chosenModel, chosenOnFallback := o.mainFailover.Select(o.cfg.Models["main"])
+	chosenFailover, rejectedFailover := o.mainFailover, o.rejectedFailover
+	rejectedModel, hasRejectedModel := o.cfg.RejectedModel()
+	rejectedModel, rejectedOnFallback := rejectedFailover.Select(rejectedModel)
+	generateRejected := hasRejectedModel && o.cfg.Generation.DatasetMode != models.DatasetModeSFT &&
+		o.rejectedCount(job.ID) > 0
+	if generateRejected && shouldSwapModels(o.cfg.Generation.SwapDecisionSeed(), job.ID, o.cfg.Generation.SwapProbability) {
+		chosenModel, rejectedModel = rejectedModel, chosenModel
+		chosenOnFallback, rejectedOnFallback = rejectedOnFallback, chosenOnFallback
+		chosenFailover, rejectedFailover = rejectedFailover, chosenFailover
+		result.Swapped = true
+		logger.Debug("Swapped chosen/rejected models for job",
+			"job_id", job.ID,
+			"chosen_model", chosenModel.ModelName,
+			"rejected_model", rejectedModel.ModelName)
+	}
+	result.ChosenModel = chosenModel.ModelName
+
+	// Perturb each side's temperature for diversity (per-job copies; the shared config is untouched)
+	if jitter := o.cfg.Generation.TemperatureJitter; jitter > 0 {
+		seed := o.cfg.Generation.JitterSeed()
+		chosenModel.Temperature = jitterTemperature(seed, job.ID, jitterPhaseChosen, chosenModel.Temperature, jitter)
+		rejectedModel.Temperature = jitterTemperature(seed, job.ID, jitterPhaseRejected, rejectedModel.Temperature, jitter)
+		logger.Debug("Applied temperature jitter",
+			"job_id", job.ID,
+			"chosen_temperature", chosenModel.Temperature,
+			"rejected_temperature", rejectedModel.Temperature)
+	}
+
+	// Generate chosen response
 	chosenStart := time.Now()
-	mainModel := o.cfg.Models["main"]
-	mainAPIKey := o.secrets.GetAPIKey(mainModel.BaseURL)
+	chosenAPIKey := o.secrets.GetAPIKey(chosenModel.BaseURL)
 
 	// Render chosen generation prompt
 	chosenPrompt, err := util.RenderTemplate(o.cfg.PromptTemplates.ChosenGeneration, map[string]interface{}{
-		"Prompt": job.Prompt,
+		"Prompt":   job.Prompt,
+		"Examples": o.cfg.PromptTemplates.ChosenExamples,
 	})
 	if err != nil {
 		result.Error = fmt.Errorf("failed to render chosen template: %w", err)
@@ -88,27 +131,40 @@ func (o *Orchestrator) processJob(
 	var chosenResp *api.ChatCompletionResponse
 
 	// Use streaming if enabled (bypasses gateway timeouts for long responses)
-	if mainModel.UseStreaming {
-		chosenResp, err = o.apiClient.ChatCompletionStreaming(ctx, mainModel, mainAPIKey, chosenMessages)
+	if chosenModel.UseStreaming {
+		chosenResp, err = o.apiClient.ChatCompletionStreaming(ctx, chosenModel, chosenAPIKey, chosenMessages)
 	} else {
-		chosenResp, err = o.apiClient.ChatCompletion(ctx, mainModel, mainAPIKey, chosenMessages)
+		chosenResp, err = o.apiClient.ChatCompletion(ctx, chosenModel, chosenAPIKey, chosenMessages)
 	}
+	chosenFailover.Record(chosenOnFallback, err)
 
 	if err != nil {
 		result.Error = fmt.Errorf("failed to generate chosen response: %w", err)
 		return result
 	}
+	result.ChosenAttempts.Network = networkRetries(chosenResp)
+	if o.cfg.Generation.RetryOnTruncation && chosenResp.Choices[0].FinishReason == "length" {
+		chosenResp = o.retryTruncated(ctx, logger, job, chosenModel, chosenAPIKey, chosenMessages, chosenResp, &result.ChosenAttempts)
+	}
 	result.Chosen = chosenResp.Choices[0].Message.Content
 	finishReason := chosenResp.Choices[0].FinishReason
+	chosenReasoning := chosenResp.Choices[0].Message.ReasoningContent
+	if o.cfg.Generation.StripThinkFromContent {
+		result.Chosen, chosenReasoning = splitEmbeddedThink(result.Chosen, chosenReasoning)
+	}
 
 	// Capture reasoning content if available (for dual dataset mode)
-	if chosenResp.Choices[0].Message.ReasoningContent != "" {
-		result.ChosenReasoning = chosenResp.Choices[0].Message.ReasoningContent
+	if chosenReasoning != "" {
+		result.ChosenReasoning = chosenReasoning
 		logger.Debug("Captured chosen reasoning content",
 			"job_id", job.ID,
 			"reasoning_length", len(result.ChosenReasoning))
 	}
 
+	if o.cfg.Generation.EnableReasoningCapture {
+		o.warnMissingReasoning(logger, chosenModel, result.Chosen, result.ChosenReasoning)
+	}
+
 	chosenDuration := time.Since(chosenStart)
 
 	// Check for token exhaustion during reasoning phase (thinking models)
@@ -118,11 +174,11 @@ func (o *Orchestrator) processJob(
 			"job_id", job.ID,
 			"reasoning_length", len(result.ChosenReasoning),
 			"finish_reason", finishReason,
-			"max_tokens", mainModel.MaxOutputTokens,
+			"max_tokens", chosenModel.MaxOutputTokens,
 			"prompt_preview", job.Prompt[:min(100, len(job.Prompt))])
 
 		result.Error = fmt.Errorf("token exhaustion: model consumed %d reasoning tokens with max_output_tokens=%d, increase token limit",
-			len(result.ChosenReasoning), mainModel.MaxOutputTokens)
+			len(result.ChosenReasoning), chosenModel.MaxOutputTokens)
 		return result
 	}
 
@@ -163,67 +219,35 @@ func (o *Orchestrator) processJob(
 		return result
 	}
 
-	// Generate rejected response (skip for SFT mode)
+	// Generate rejected response(s) (skip for SFT mode)
 	var rejectedDuration time.Duration
-	rejectedModel, hasRejectedModel := o.cfg.Models["rejected"]
 
-	if hasRejectedModel && o.cfg.Generation.DatasetMode != models.DatasetModeSFT {
+	if generateRejected {
 		rejectedStart := time.Now()
-		rejectedAPIKey := o.secrets.GetAPIKey(rejectedModel.BaseURL)
 
-		// Render rejected generation prompt
-		rejectedPrompt, err := util.RenderTemplate(o.cfg.PromptTemplates.RejectedGeneration, map[string]interface{}{
-			"Prompt": job.Prompt,
-		})
+		rejections, err := o.generateRejectedResponses(ctx, logger, job, result.Chosen, rejectedModel)
+		rejectedFailover.Record(rejectedOnFallback, err)
 		if err != nil {
-			result.Error = fmt.Errorf("failed to render rejected template: %w", err)
+			result.Error = err
 			return result
 		}
-
-		// Build messages with optional system prompt
-		rejectedMessages := []api.Message{}
-		if o.cfg.PromptTemplates.RejectedSystemPrompt != "" {
-			rejectedMessages = append(rejectedMessages, api.Message{
-				Role:    "system",
-				Content: o.cfg.PromptTemplates.RejectedSystemPrompt,
-			})
-		}
-		rejectedMessages = append(rejectedMessages, api.Message{
-			Role:    "user",
-			Content: rejectedPrompt,
-		})
-
-		var rejectedResp *api.ChatCompletionResponse
-
-		// Use streaming if enabled (bypasses gateway timeouts for long responses)
-		if rejectedModel.UseStreaming {
-			rejectedResp, err = o.apiClient.ChatCompletionStreaming(ctx, rejectedModel, rejectedAPIKey, rejectedMessages)
-		} else {
-			rejectedResp, err = o.apiClient.ChatCompletion(ctx, rejectedModel, rejectedAPIKey, rejectedMessages)
-		}
-
-		if err != nil {
-			result.Error = fmt.Errorf("failed to generate rejected response: %w", err)
+		if err := o.resolveIdenticalPairs(ctx, logger, job, result.Chosen, rejectedModel, rejections); err != nil {
+			result.Error = err
 			return result
 		}
-		result.Rejected = rejectedResp.Choices[0].Message.Content
-
-		// Capture reasoning content if available and enabled (for dual dataset mode)
-		if o.cfg.Generation.ReasoningCaptureRejected && rejectedResp.Choices[0].Message.ReasoningContent != "" {
-			result.RejectedReasoning = rejectedResp.Choices[0].Message.ReasoningContent
-			logger.Debug("Captured rejected reasoning content",
-				"job_id", job.ID,
-				"reasoning_length", len(result.RejectedReasoning))
+		result.Rejected = rejections[0].Content
+		result.RejectedReasoning = rejections[0].Reasoning
+		result.RejectedModel = rejections[0].Model
+		for _, rejection := range rejections {
+			result.RejectedAttempts = result.RejectedAttempts.Add(rejection.Attempts)
+		}
+		if len(rejections) > 1 {
+			result.RejectedList = rejections
 		}
 
 		rejectedDuration = time.Since(rejectedStart)
-
-		// Note: We do NOT filter rejected responses for refusal patterns.
-		// Rejected responses with safety patterns ("as an ai", etc.) are valuable
-		// training signals that teach the model what NOT to do. This is the core
-		// purpose of preference tuning - showing the model both good and bad examples.
 	} else {
-		// SFT mode without rejected model
+		// SFT mode without rejected model (or a KTO job that kto_ratio gives no undesirable rows)
 		result.Rejected = ""
 		rejectedDuration = 0
 	}
@@ -236,11 +260,193 @@ func (o *Orchestrator) processJob(
 		"chosen_ms", chosenDuration.Milliseconds(),
 		"rejected_ms", rejectedDuration.Milliseconds(),
 		"total_ms", totalDuration.Milliseconds())
+	o.timer.recordJob(chosenDuration, rejectedDuration, totalDuration, generateRejected)
 
 	return result
 }
 
-func (o *Orchestrator) collectResults(results <-chan models.GenerationResult, wg *sync.WaitGroup, initialProgress int) {
+// generateRejectedResponses generates the job's rejected responses (generation.num_rejected,
+// or the per-job share of generation.kto_ratio in KTO mode); chosen is the job's chosen response
+// Multiple candidates are requested concurrently; the job fails if any of them fails so a
+// job is either fully written or retried as a whole on resume
+func (o *Orchestrator) generateRejectedResponses(
+	ctx context.Context,
+	logger *slog.Logger,
+	job models.GenerationJob,
+	chosen string,
+	model config.ModelConfig,
+) ([]models.RejectedResponse, error) {
+	numRejected := o.rejectedCount(job.ID)
+	if numRejected == 1 {
+		rejection, err := o.generateRejectedResponse(ctx, logger, job, chosen, model)
+		if err != nil {
+			return nil, err
+		}
+		return []models.RejectedResponse{rejection}, nil
+	}
+
+	rejections := make([]models.RejectedResponse, numRejected)
+	errs := make([]error, numRejected)
+	var wg sync.WaitGroup
+	for i := range numRejected {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rejections[i], errs[i] = o.generateRejectedResponse(ctx, logger, job, chosen, model)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("rejected candidate %d/%d: %w", i+1, numRejected, err)
+		}
+	}
+	return rejections, nil
+}
+
+// generateRejectedResponse generates a single rejected response for a job
+// With generation.rejected_sees_chosen the template also gets the chosen response as {{.Chosen}}
+func (o *Orchestrator) generateRejectedResponse(
+	ctx context.Context,
+	logger *slog.Logger,
+	job models.GenerationJob,
+	chosen string,
+	model config.ModelConfig,
+) (models.RejectedResponse, error) {
+	rejection := models.RejectedResponse{Model: model.ModelName}
+	apiKey := o.secrets.GetAPIKey(model.BaseURL)
+
+	// Render rejected generation prompt
+	templateData := map[string]interface{}{
+		"Prompt": job.Prompt,
+	}
+	if o.cfg.Generation.RejectedSeesChosen {
+		templateData["Chosen"] = chosen
+	}
+	rejectedPrompt, err := util.RenderTemplate(o.cfg.PromptTemplates.RejectedGeneration, templateData)
+	if err != nil {
+		return rejection, fmt.Errorf("failed to render rejected template: %w", err)
+	}
+
+	// Build messages with optional system prompt
+	rejectedMessages := []api.Message{}
+	if o.cfg.PromptTemplates.RejectedSystemPrompt != "" {
+		rejectedMessages = append(rejectedMessages, api.Message{
+			Role:    "system",
+			Content: o.cfg.PromptTemplates.RejectedSystemPrompt,
+		})
+	}
+	rejectedMessages = append(rejectedMessages, api.Message{
+		Role:    "user",
+		Content: rejectedPrompt,
+	})
+
+	var rejectedResp *api.ChatCompletionResponse
+
+	// Use streaming if enabled (bypasses gateway timeouts for long responses)
+	if model.UseStreaming {
+		rejectedResp, err = o.apiClient.ChatCompletionStreaming(ctx, model, apiKey, rejectedMessages)
+	} else {
+		rejectedResp, err = o.apiClient.ChatCompletion(ctx, model, apiKey, rejectedMessages)
+	}
+
+	if err != nil {
+		return rejection, fmt.Errorf("failed to generate rejected response: %w", err)
+	}
+	rejection.Attempts.Network = networkRetries(rejectedResp)
+	rejection.Content = rejectedResp.Choices[0].Message.Content
+	rejectedReasoning := rejectedResp.Choices[0].Message.ReasoningContent
+	if o.cfg.Generation.StripThinkFromContent {
+		rejection.Content, rejectedReasoning = splitEmbeddedThink(rejection.Content, rejectedReasoning)
+	}
+
+	// Capture reasoning content if available and enabled (for dual dataset mode)
+	if o.cfg.Generation.ReasoningCaptureRejected && rejectedReasoning != "" {
+		rejection.Reasoning = rejectedReasoning
+		logger.Debug("Captured rejected reasoning content",
+			"job_id", job.ID,
+			"reasoning_length", len(rejection.Reasoning))
+	}
+	if o.cfg.Generation.EnableReasoningCapture && o.cfg.Generation.ReasoningCaptureRejected {
+		o.warnMissingReasoning(logger, model, rejection.Content, rejection.Reasoning)
+	}
+
+	// Note: We do NOT filter rejected responses for refusal patterns.
+	// Rejected responses with safety patterns ("as an ai", etc.) are valuable
+	// training signals that teach the model what NOT to do. This is the core
+	// purpose of preference tuning - showing the model both good and bad examples.
+	return rejection, nil
+}
+
+// networkRetries returns how many times the API client retried the request behind resp
+func networkRetries(resp *api.ChatCompletionResponse) int {
+	return max(0, resp.Attempts-1)
+}
+
+// splitEmbeddedThink moves <think> blocks out of content (generation.strip_think_from_content)
+// Returns the clean answer and the reasoning: the dedicated reasoning field followed by any embedded blocks
+func splitEmbeddedThink(content, reasoning string) (string, string) {
+	if !util.ContainsThinkTags(content) {
+		return content, reasoning
+	}
+	think, answer := util.SplitThinkAndAnswer(content)
+	switch {
+	case think == "":
+	case reasoning == "":
+		reasoning = think
+	default:
+		reasoning = reasoning + "\n\n" + think
+	}
+	return answer, reasoning
+}
+
+// warnMissingReasoning logs a single warning per model when reasoning capture is enabled
+// but the model returned no reasoning, so reasoning datasets aren't silently empty
+func (o *Orchestrator) warnMissingReasoning(logger *slog.Logger, model config.ModelConfig, content, reasoning string) {
+	if reasoning != "" || util.ContainsThinkTags(content) {
+		return
+	}
+	if _, warned := o.reasoningWarned.LoadOrStore(model.ModelName, true); warned {
+		return
+	}
+	logger.Warn("Reasoning capture enabled but model returned no reasoning content; reasoning dataset will match the regular dataset for this model",
+		"model", model.ModelName,
+		"use_streaming", model.UseStreaming)
+}
+
+// shouldSwapModels decides whether a job's chosen/rejected models are swapped
+// The decision depends only on seed and job ID, so it is stable across workers and resumes
+func shouldSwapModels(seed int64, jobID int, probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	if probability >= 1 {
+		return true
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), uint64(jobID)))
+	return rng.Float64() < probability
+}
+
+// Phases get independent temperature jitter streams for the same job
+const (
+	jitterPhaseChosen uint64 = iota + 1
+	jitterPhaseRejected
+)
+
+// jitterTemperature offsets base by a uniform value in [-jitter, +jitter], clamped to [0, 2]
+// Like shouldSwapModels, the offset depends only on seed, job ID, and phase
+func jitterTemperature(seed int64, jobID int, phase uint64, base, jitter float64) float64 {
+	if jitter <= 0 {
+		return base
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed)^(phase<<56), uint64(jobID)))
+	temperature := base + (rng.Float64()*2-1)*jitter
+	return min(max(temperature, 0), 2)
+}
+
+// collectResults writes results as they arrive, or in dispatch order when reorder is set
+func (o *Orchestrator) collectResults(results <-chan models.GenerationResult, wg *sync.WaitGroup, initialProgress int, reorder *resultReorderer) {
 	defer wg.Done()
 
 	bar := progressbar.Default(int64(o.stats.TotalPrompts), "Processing")
@@ -253,77 +459,262 @@ func (o *Orchestrator) collectResults(results <-chan models.GenerationResult, wg
 		_ = bar.Add(initialProgress)
 	}
 
+	failureRate := newFailureRateMonitor(o.cfg.Generation.MaxFailureRate, o.cfg.Generation.FailureRateMinSamples)
+
 	for result := range results {
-		if result.Error != nil {
-			o.logger.Error("Job failed",
-				"job_id", result.Job.ID,
-				"error", result.Error)
-			o.stats.FailureCount++
-		} else {
-			// Apply optional judge filtering (all modes except MO-DPO)
-			shouldFilter := false
-			if o.cfg.JudgeFiltering.Enabled && o.cfg.Generation.DatasetMode != models.DatasetModeMODPO {
-				shouldFilter = o.applyJudgeFiltering(result.Job.Prompt, result.Chosen, result.Rejected)
-				if shouldFilter {
-					o.stats.FilteredCount++
-					o.logger.Debug("Filtered record",
-						"job_id", result.Job.ID,
-						"reason", "below score thresholds")
-				}
+		if reorder == nil {
+			o.handleResult(result, failureRate)
+			_ = bar.Add(1)
+			continue
+		}
+
+		ready, spilled := reorder.Add(result)
+		for _, jobID := range spilled {
+			o.logger.Warn("Ordered output buffer full, writing past slow job (it will be written out of order)",
+				"job_id", jobID,
+				"ordered_output_buffer", o.cfg.Generation.OrderedOutputBuffer)
+		}
+		for _, r := range ready {
+			o.handleResult(r, failureRate)
+			_ = bar.Add(1)
+		}
+	}
+
+	// Run stopped early: earlier jobs never reported, so write what finished
+	if reorder != nil && reorder.Buffered() > 0 {
+		o.logger.Info("Writing buffered results after early stop", "count", reorder.Buffered())
+		for _, r := range reorder.Drain() {
+			o.handleResult(r, failureRate)
+			_ = bar.Add(1)
+		}
+	}
+}
+
+// handleResult records a finished job: failure accounting, optional judge filtering, writing,
+// checkpointing, and the failure rate check
+func (o *Orchestrator) handleResult(result models.GenerationResult, failureRate *failureRateMonitor) {
+	jobFailed := false
+	filtered := false
+	if result.Error != nil {
+		jobFailed = true
+		errorClass := o.recordFailure(result.Error)
+		o.logger.Error("Job failed",
+			"job_id", result.Job.ID,
+			"error_class", errorClass,
+			"error", result.Error)
+		o.keepFailure(result.Job, errorClass, result.Error)
+	} else {
+		result = o.normalizeResult(result)
+
+		// Length ratio check first: it is free, unlike the reward model and judge
+		shouldFilter := false
+		filterReason := ""
+		if ratio, outside := o.lengthRatioOutOfRange(result); outside {
+			if o.cfg.Generation.LengthRatioAction == config.LengthRatioFlag {
+				o.stats.LengthRatioFlagged++
+				result.LengthRatioFlag = &ratio
+				o.logger.Debug("Flagged record",
+					"job_id", result.Job.ID,
+					"reason", "length ratio out of range",
+					"length_ratio", ratio)
+			} else {
+				o.stats.LengthRatioFiltered++
+				shouldFilter = true
+				filterReason = "length ratio out of range"
 			}
+		}
 
-			if !shouldFilter {
-				// Write based on dataset mode
-				err := o.writeRecordByMode(result)
-				if err != nil {
-					o.logger.Error("Failed to write record",
-						"job_id", result.Job.ID,
-						"error", err)
-					o.stats.FailureCount++
-				} else {
-					o.stats.SuccessCount++
-
-					// Checkpoint progress (interval-based)
-					if o.checkpointMgr != nil {
-						if err := o.checkpointMgr.MarkJobComplete(result.Job.ID, o.stats); err != nil {
-							o.logger.Warn("Failed to checkpoint job", "job_id", result.Job.ID, "error", err)
-						}
+		// Apply optional reward model or judge filtering (judge: all modes except MO-DPO)
+		switch {
+		case shouldFilter:
+		case o.rewardModel != nil:
+			shouldFilter, filterReason = o.applyRewardFiltering(result)
+		case o.cfg.JudgeFiltering.Enabled && o.cfg.Generation.DatasetMode != models.DatasetModeMODPO:
+			shouldFilter = o.applyJudgeFiltering(result)
+			filterReason = "below score thresholds"
+		}
+		if shouldFilter {
+			filtered = true
+			o.stats.FilteredCount++
+			o.metrics.RecordRowFiltered()
+			o.logger.Debug("Filtered record",
+				"job_id", result.Job.ID,
+				"reason", filterReason)
+		}
+
+		if !shouldFilter {
+			// Write based on dataset mode
+			err := o.writeRecordByMode(result)
+			if errors.Is(err, writer.ErrRecordSkipped) {
+				filtered = true
+				o.stats.FilteredCount++
+				o.metrics.RecordRowFiltered()
+				o.logger.Debug("Filtered record",
+					"job_id", result.Job.ID,
+					"reason", "record_filter_command")
+			} else if err != nil {
+				jobFailed = true
+				errorClass := o.recordFailure(err)
+				o.logger.Error("Failed to write record",
+					"job_id", result.Job.ID,
+					"error_class", errorClass,
+					"error", err)
+				o.keepFailure(result.Job, errorClass, err)
+			} else {
+				o.stats.SuccessCount++
+				if result.ChosenAttempts.Retried() || result.RejectedAttempts.Retried() {
+					o.stats.RetriedJobs++
+				}
+				o.metrics.RecordRowWritten()
+				o.checkOutputCap()
+
+				// Checkpoint progress (interval-based)
+				if o.checkpointMgr != nil {
+					o.syncJudgeStats()
+					o.syncTruncationStats()
+					o.syncFingerprintStats()
+					if err := o.checkpointMgr.MarkJobComplete(result.Job.ID, o.stats); err != nil {
+						o.logger.Warn("Failed to checkpoint job", "job_id", result.Job.ID, "error", err)
 					}
 				}
 			}
 		}
+	}
+
+	// Failures caused by shutdown say nothing about config health
+	if !filtered && (o.ctx == nil || o.ctx.Err() == nil) && failureRate.Record(jobFailed) {
+		o.abortOnFailureRate(failureRate)
+	}
+}
+
+// checkOutputCap stops dispatching new jobs once the dataset reaches generation.max_output_bytes
+// Jobs already running still finish and are written, so the dataset can end up past the cap
+func (o *Orchestrator) checkOutputCap() {
+	limit := o.cfg.Generation.MaxOutputBytes
+	if limit <= 0 || o.outputCapped || o.stopDispatch == nil {
+		return
+	}
+	written := o.dataWriter.BytesWritten()
+	if written < limit {
+		return
+	}
+	o.outputCapped = true
+	o.stats.OutputCapped = true
+	o.logger.Info("Dataset reached max_output_bytes - finishing in-flight jobs, then finalizing",
+		"bytes_written", written,
+		"max_output_bytes", limit)
+	o.stopDispatch()
+}
+
+// abortOnFailureRate cancels the run once generation.max_failure_rate is exceeded
+// The checkpoint is saved on the way out, so the run can be resumed after fixing the config
+func (o *Orchestrator) abortOnFailureRate(monitor *failureRateMonitor) {
+	o.logger.Error("Job failure rate exceeded max_failure_rate - aborting run",
+		"failure_rate", fmt.Sprintf("%.1f%%", monitor.Rate()*100),
+		"max_failure_rate", fmt.Sprintf("%.1f%%", o.cfg.Generation.MaxFailureRate*100),
+		"jobs_observed", monitor.Samples(),
+		"error_breakdown", o.stats.ErrorCounts)
+	if o.cancelRun != nil {
+		o.cancelRun(errFailureRateExceeded)
+	}
+}
 
-		_ = bar.Add(1)
+// recordFailure counts a failed job and its error class in the session stats
+func (o *Orchestrator) recordFailure(err error) string {
+	errorClass := api.ClassifyError(err)
+	o.stats.FailureCount++
+	if o.stats.ErrorCounts == nil {
+		o.stats.ErrorCounts = make(map[string]int)
+	}
+	o.stats.ErrorCounts[errorClass]++
+	o.metrics.RecordJobFailure(errorClass)
+	return errorClass
+}
+
+// keepFailure appends a failed job to the session's failures file (generation.keep_failed)
+// Failures caused by shutdown are left out: the job was cut short, not broken
+func (o *Orchestrator) keepFailure(job models.GenerationJob, errorClass string, err error) {
+	if o.failureLog == nil || (o.ctx != nil && o.ctx.Err() != nil) {
+		return
+	}
+	if logErr := o.failureLog.Record(job, errorClass, err); logErr != nil {
+		o.logger.Warn("Failed to record failed job", "job_id", job.ID, "error", logErr)
 	}
 }
 
 // applyJudgeFiltering evaluates and filters based on score thresholds
-func (o *Orchestrator) applyJudgeFiltering(prompt, chosen, rejected string) bool {
+// Every rejected candidate is judged: any one scoring above max_rejected_score filters the record
+func (o *Orchestrator) applyJudgeFiltering(result models.GenerationResult) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
 	// Evaluate chosen response
-	chosenScore, err := o.judgeModule.EvaluateForFiltering(ctx, prompt, chosen)
+	chosenScore, err := o.judgeModule.EvaluateForFiltering(ctx, result.Job.Prompt, result.Chosen)
 	if err != nil {
 		o.logger.Warn("Judge filtering failed for chosen response", "error", err)
 		return false // Don't filter on error
 	}
+	if chosenScore < o.cfg.JudgeFiltering.MinChosenScore {
+		return true
+	}
 
-	// Evaluate rejected response (if present)
-	var rejectedScore float64
-	if rejected != "" {
-		rejectedScore, err = o.judgeModule.EvaluateForFiltering(ctx, prompt, rejected)
+	// Evaluate rejected responses (if present)
+	for i, rejected := range rejectedResponses(result) {
+		rejectedScore, err := o.judgeModule.EvaluateForFiltering(ctx, result.Job.Prompt, rejected)
 		if err != nil {
-			o.logger.Warn("Judge filtering failed for rejected response", "error", err)
+			o.logger.Warn("Judge filtering failed for rejected response", "candidate", i+1, "error", err)
 			return false // Don't filter on error
 		}
+		if rejectedScore > o.cfg.JudgeFiltering.MaxRejectedScore {
+			return true
+		}
 	}
+	return false
+}
 
-	// Filter if chosen score too low OR rejected score too high
-	shouldFilter := chosenScore < o.cfg.JudgeFiltering.MinChosenScore ||
-		(rejected != "" && rejectedScore > o.cfg.JudgeFiltering.MaxRejectedScore)
+// rejectedResponses returns the rejected candidates of a result: every entry of RejectedList
+// with num_rejected > 1, otherwise the single rejected response (none in SFT mode)
+func rejectedResponses(result models.GenerationResult) []string {
+	switch {
+	case len(result.RejectedList) > 1:
+		responses := make([]string, len(result.RejectedList))
+		for i, candidate := range result.RejectedList {
+			responses[i] = candidate.Content
+		}
+		return responses
+	case result.Rejected != "":
+		return []string{result.Rejected}
+	}
+	return nil
+}
 
-	return shouldFilter
+// applyRewardFiltering scores chosen and every rejected candidate with the reward model in one
+// request and reports whether the record is filtered, and why: any candidate outside the
+// [reward_model] thresholds filters it, and a scoring failure filters it unless on_error = "keep"
+func (o *Orchestrator) applyRewardFiltering(result models.GenerationResult) (bool, string) {
+	responses := append([]string{result.Chosen}, rejectedResponses(result)...)
+
+	cfg := o.cfg.RewardModel
+	scores, err := o.rewardModel.Score(context.Background(), result.Job.Prompt, responses)
+	if err != nil {
+		o.logger.Warn("Reward model scoring failed",
+			"job_id", result.Job.ID,
+			"on_error", cfg.OnError,
+			"error", err)
+		return cfg.OnError != config.RewardErrorKeep, "reward model error"
+	}
+
+	chosenScore := scores[0]
+	if cfg.MinChosenScore != nil && chosenScore < *cfg.MinChosenScore {
+		return true, "below reward thresholds"
+	}
+	for _, rejectedScore := range scores[1:] {
+		if (cfg.MaxRejectedScore != nil && rejectedScore > *cfg.MaxRejectedScore) ||
+			(cfg.MinMargin != nil && chosenScore-rejectedScore < *cfg.MinMargin) {
+			return true, "below reward thresholds"
+		}
+	}
+	return false, ""
 }
 
 // writeRecordByMode writes the record based on the configured dataset mode
@@ -359,6 +750,7 @@ func (o *Orchestrator) writeSFTRecord(result models.GenerationResult) error {
 			record.MainTopic = result.Job.MainTopic
 			record.SubTopic = result.Job.SubTopic
 		}
+		record.Meta = o.recordMeta(result, &result.ChosenAttempts, nil)
 		return o.dataWriter.WriteSFTRecord(record, result.ChosenReasoning)
 
 	case models.SFTFormatShareGPT:
@@ -372,6 +764,24 @@ func (o *Orchestrator) writeSFTRecord(result models.GenerationResult) error {
 			record.MainTopic = result.Job.MainTopic
 			record.SubTopic = result.Job.SubTopic
 		}
+		record.Meta = o.recordMeta(result, &result.ChosenAttempts, nil)
+		return o.dataWriter.WriteSFTRecord(record, result.ChosenReasoning)
+
+	case models.SFTFormatOpenAI:
+		var messages []models.OpenAIMessage
+		if o.cfg.PromptTemplates.ChosenSystemPrompt != "" {
+			messages = append(messages, models.OpenAIMessage{Role: "system", Content: o.cfg.PromptTemplates.ChosenSystemPrompt})
+		}
+		messages = append(messages,
+			models.OpenAIMessage{Role: "user", Content: result.Job.Prompt},
+			models.OpenAIMessage{Role: "assistant", Content: result.Chosen},
+		)
+		record := models.SFTRecord{Messages: messages}
+		if o.cfg.Generation.IncludeTopicColumns {
+			record.MainTopic = result.Job.MainTopic
+			record.SubTopic = result.Job.SubTopic
+		}
+		record.Meta = o.recordMeta(result, &result.ChosenAttempts, nil)
 		return o.dataWriter.WriteSFTRecord(record, result.ChosenReasoning)
 
 	default:
@@ -380,37 +790,226 @@ func (o *Orchestrator) writeSFTRecord(result models.GenerationResult) error {
 }
 
 // writeDPORecord writes a standard DPO preference pair
+// With num_rejected > 1 the candidates are written as one row each or as a single row
+// with a rejected array, depending on generation.rejected_output_shape
 func (o *Orchestrator) writeDPORecord(result models.GenerationResult) error {
+	if len(result.RejectedList) > 1 {
+		if o.cfg.Generation.RejectedOutputShape == models.RejectedShapeArray {
+			return o.writeMultiRejectedDPORecord(result)
+		}
+		// Validate every pair first so an invalid candidate doesn't leave the job half written
+		rows := make([]func() error, len(result.RejectedList))
+		for i, rejection := range result.RejectedList {
+			if err := writer.ValidateDPORecord(models.DPORecord{Prompt: result.Job.Prompt, Chosen: result.Chosen, Rejected: rejection.Content}); err != nil {
+				return fmt.Errorf("failed to write DPO record for rejected candidate %d: %w", i+1, err)
+			}
+			rows[i] = func() error {
+				if err := o.writeDPOPair(result, rejection); err != nil {
+					return fmt.Errorf("failed to write DPO record for rejected candidate %d: %w", i+1, err)
+				}
+				return nil
+			}
+		}
+		return writeJobRows(rows)
+	}
+
+	return o.writeDPOPair(result, models.RejectedResponse{
+		Content:   result.Rejected,
+		Reasoning: result.RejectedReasoning,
+		Model:     result.RejectedModel,
+		Attempts:  result.RejectedAttempts,
+	})
+}
+
+// writeDPOPair writes one chosen/rejected pair in the configured generation.dpo_format
+func (o *Orchestrator) writeDPOPair(result models.GenerationResult, rejection models.RejectedResponse) error {
+	if o.cfg.Generation.DPOFormat == models.DPOFormatConversational {
+		var prompt []models.OpenAIMessage
+		if o.cfg.PromptTemplates.ChosenSystemPrompt != "" {
+			prompt = append(prompt, models.OpenAIMessage{Role: "system", Content: o.cfg.PromptTemplates.ChosenSystemPrompt})
+		}
+		prompt = append(prompt, models.OpenAIMessage{Role: "user", Content: result.Job.Prompt})
+
+		record := models.ConversationalDPORecord{
+			MainTopic: o.recordMainTopic(result),
+			Prompt:    prompt,
+			Chosen:    []models.OpenAIMessage{{Role: "assistant", Content: result.Chosen}},
+			Rejected:  []models.OpenAIMessage{{Role: "assistant", Content: rejection.Content}},
+			Meta:      o.recordMeta(result, &result.ChosenAttempts, &rejection.Attempts),
+		}
+		if o.cfg.Generation.RecordModelAssignment {
+			record.ChosenModel = result.ChosenModel
+			record.RejectedModel = rejection.Model
+		}
+		return o.dataWriter.WriteConversationalDPORecord(record, result.ChosenReasoning, rejection.Reasoning)
+	}
+
 	record := models.DPORecord{
-		Prompt:   result.Job.Prompt,
-		Chosen:   result.Chosen,
-		Rejected: result.Rejected,
+		System:    o.recordSystemPrompt(),
+		MainTopic: o.recordMainTopic(result),
+		Prompt:    result.Job.Prompt,
+		Chosen:    result.Chosen,
+		Rejected:  rejection.Content,
+		Meta:      o.recordMeta(result, &result.ChosenAttempts, &rejection.Attempts),
 	}
-	return o.dataWriter.WriteDPORecord(record, result.ChosenReasoning, result.RejectedReasoning)
+	if o.cfg.Generation.RecordModelAssignment {
+		record.ChosenModel = result.ChosenModel
+		record.RejectedModel = rejection.Model
+	}
+	return o.dataWriter.WriteDPORecord(record, result.ChosenReasoning, rejection.Reasoning)
 }
 
-// writeKTORecord writes two KTO records (one chosen, one rejected)
+// recordSystemPrompt returns the system column of flat DPO and KTO records
+// (empty unless generation.include_system_prompt is enabled)
+func (o *Orchestrator) recordSystemPrompt() string {
+	if !o.cfg.Generation.IncludeSystemPrompt {
+		return ""
+	}
+	return o.cfg.PromptTemplates.ChosenSystemPrompt
+}
+
+// recordMeta returns the _meta column of a record, nil unless generation.include_metadata is enabled
+// or the result was flagged for its length ratio
+// A nil side is left out, as for KTO rows that carry a single completion
+func (o *Orchestrator) recordMeta(result models.GenerationResult, chosen, rejected *models.AttemptCounts) *models.RecordMeta {
+	var meta *models.RecordMeta
+	if ratio := result.LengthRatioFlag; ratio != nil {
+		meta = &models.RecordMeta{LengthRatioFlagged: true}
+		// An empty rejected response gives +Inf, which JSON cannot hold
+		if !math.IsInf(*ratio, 0) {
+			meta.LengthRatio = *ratio
+		}
+	}
+	if !o.cfg.Generation.IncludeMetadata {
+		return meta
+	}
+	if meta == nil {
+		meta = &models.RecordMeta{}
+	}
+	if chosen != nil {
+		attempts := *chosen
+		meta.Attempts.Chosen = &attempts
+	}
+	if rejected != nil {
+		attempts := *rejected
+		meta.Attempts.Rejected = &attempts
+	}
+	return meta
+}
+
+// recordMainTopic returns the main_topic column of DPO and KTO records, which is only
+// written when generation.main_topics mixes several topics into one dataset
+func (o *Orchestrator) recordMainTopic(result models.GenerationResult) string {
+	if !o.cfg.Generation.MultiTopic() {
+		return ""
+	}
+	return result.Job.MainTopic
+}
+
+// writeMultiRejectedDPORecord writes a single DPO row holding every rejected candidate
+func (o *Orchestrator) writeMultiRejectedDPORecord(result models.GenerationResult) error {
+	record := models.MultiRejectedDPORecord{
+		System:    o.recordSystemPrompt(),
+		MainTopic: o.recordMainTopic(result),
+		Prompt:    result.Job.Prompt,
+		Chosen:    result.Chosen,
+		Rejected:  make([]string, len(result.RejectedList)),
+		Meta:      o.recordMeta(result, &result.ChosenAttempts, &result.RejectedAttempts),
+	}
+	rejectedReasoning := make([]string, len(result.RejectedList))
+	for i, rejection := range result.RejectedList {
+		record.Rejected[i] = rejection.Content
+		rejectedReasoning[i] = rejection.Reasoning
+	}
+	if o.cfg.Generation.RecordModelAssignment {
+		record.ChosenModel = result.ChosenModel
+		record.RejectedModels = make([]string, len(result.RejectedList))
+		for i, rejection := range result.RejectedList {
+			record.RejectedModels[i] = rejection.Model
+		}
+	}
+	return o.dataWriter.WriteMultiRejectedDPORecord(record, result.ChosenReasoning, rejectedReasoning)
+}
+
+// writeKTORecord writes KTO records (one chosen, one per rejected candidate)
+// With kto_ratio, some jobs have no rejected candidates and write only the desirable row
 func (o *Orchestrator) writeKTORecord(result models.GenerationResult) error {
 	// Write chosen record
 	chosenRecord := models.KTORecord{
+		System:     o.recordSystemPrompt(),
+		MainTopic:  o.recordMainTopic(result),
 		Prompt:     result.Job.Prompt,
 		Completion: result.Chosen,
 		Label:      true,
+		Meta:       o.recordMeta(result, &result.ChosenAttempts, nil),
+	}
+	if o.cfg.Generation.RecordModelAssignment {
+		chosenRecord.Model = result.ChosenModel
 	}
-	if err := o.dataWriter.WriteKTORecord(chosenRecord, result.ChosenReasoning); err != nil {
+	// Every row is validated before any is written so an invalid row doesn't leave the job half written
+	if err := writer.ValidateKTORecord(chosenRecord); err != nil {
 		return fmt.Errorf("failed to write KTO chosen record: %w", err)
 	}
-
-	// Write rejected record
-	rejectedRecord := models.KTORecord{
-		Prompt:     result.Job.Prompt,
-		Completion: result.Rejected,
-		Label:      false,
+	rows := []func() error{func() error {
+		if err := o.dataWriter.WriteKTORecord(chosenRecord, result.ChosenReasoning); err != nil {
+			return fmt.Errorf("failed to write KTO chosen record: %w", err)
+		}
+		return nil
+	}}
+
+	// Write rejected record(s)
+	rejections := result.RejectedList
+	if len(rejections) == 0 && o.rejectedCount(result.Job.ID) > 0 {
+		rejections = []models.RejectedResponse{{
+			Content:   result.Rejected,
+			Reasoning: result.RejectedReasoning,
+			Model:     result.RejectedModel,
+			Attempts:  result.RejectedAttempts,
+		}}
 	}
-	if err := o.dataWriter.WriteKTORecord(rejectedRecord, result.RejectedReasoning); err != nil {
-		return fmt.Errorf("failed to write KTO rejected record: %w", err)
+	for _, rejection := range rejections {
+		rejectedRecord := models.KTORecord{
+			System:     o.recordSystemPrompt(),
+			MainTopic:  o.recordMainTopic(result),
+			Prompt:     result.Job.Prompt,
+			Completion: rejection.Content,
+			Label:      false,
+			Meta:       o.recordMeta(result, nil, &rejection.Attempts),
+		}
+		if o.cfg.Generation.RecordModelAssignment {
+			rejectedRecord.Model = rejection.Model
+		}
+		if err := writer.ValidateKTORecord(rejectedRecord); err != nil {
+			return fmt.Errorf("failed to write KTO rejected record: %w", err)
+		}
+		rows = append(rows, func() error {
+			if err := o.dataWriter.WriteKTORecord(rejectedRecord, rejection.Reasoning); err != nil {
+				return fmt.Errorf("failed to write KTO rejected record: %w", err)
+			}
+			return nil
+		})
 	}
 
+	return writeJobRows(rows)
+}
+
+// writeJobRows writes the rows of a job that produces several; a row skipped by the record
+// filter doesn't stop the others, and the job only counts as skipped when every row was
+func writeJobRows(rows []func() error) error {
+	skipped := 0
+	for _, write := range rows {
+		err := write()
+		if errors.Is(err, writer.ErrRecordSkipped) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if skipped > 0 && skipped == len(rows) {
+		return writer.ErrRecordSkipped
+	}
 	return nil
 }
 
@@ -423,6 +1022,16 @@ func (o *Orchestrator) writeMODPORecord(result models.GenerationResult) error {
 		Prompt:    result.Job.Prompt,
 		Chosen:    result.Chosen,
 		Rejected:  result.Rejected,
+		Meta:      o.recordMeta(result, &result.ChosenAttempts, &result.RejectedAttempts),
+	}
+	if o.cfg.Generation.RecordModelAssignment {
+		record.ChosenModel = result.ChosenModel
+		record.RejectedModel = result.RejectedModel
+	}
+
+	// Once the judge circuit breaker has tripped (flag mode), stop calling the judge
+	if o.judgeBreaker != nil && o.judgeBreaker.Tripped() {
+		record.JudgeFailed = true
 	}
 
 	// Note: Judge results will be added asynchronously via background goroutines
@@ -432,6 +1041,11 @@ func (o *Orchestrator) writeMODPORecord(result models.GenerationResult) error {
 		return err
 	}
 
+	if record.JudgeFailed {
+		o.judgeBreaker.RecordSkipped()
+		return nil
+	}
+
 	// Spawn background judge goroutine (non-blocking!)
 	if o.judgeModule != nil {
 		o.pendingJudges.Add(1)
@@ -451,9 +1065,21 @@ func (o *Orchestrator) judgeUpdater(ctx context.Context) {
 				return
 			}
 
+			if update.failed {
+				if err := o.dataWriter.MarkJudgeFailed(update.recordIndex); err != nil {
+					o.logger.Error("Failed to flag record after judge failure",
+						"record_index", update.recordIndex,
+						"error", err)
+				}
+				continue
+			}
+
 			// Update the record with judge results
 			err := o.dataWriter.UpdateRecord(update.recordIndex, update.judgeResult)
-			if err != nil {
+			if errors.Is(err, writer.ErrRecordFlushed) {
+				o.logger.Warn("Judge result arrived after record was written, scores not saved",
+					"record_index", update.recordIndex)
+			} else if err != nil {
 				o.logger.Error("Failed to update record with judge results",
 					"record_index", update.recordIndex,
 					"error", err)
@@ -483,21 +1109,67 @@ func (o *Orchestrator) evaluateJudgeAsync(recordIndex int, prompt, chosen, rejec
 	// Evaluate (this blocks for 70-103s, but doesn't block workers!)
 	judgeResult, err := o.judgeModule.Evaluate(ctx, prompt, chosen, rejected)
 	if err != nil {
+		// Failures caused by shutdown say nothing about judge health
+		if o.ctx.Err() != nil {
+			return
+		}
 		o.logger.Warn("Background judge evaluation failed",
 			"record_index", recordIndex,
 			"error", err)
+		if o.judgeBreaker.RecordFailure() {
+			o.tripJudgeBreaker(err)
+		}
+		o.sendJudgeUpdate(ctx, judgeUpdate{recordIndex: recordIndex, failed: true})
 		return
 	}
+	o.judgeBreaker.RecordSuccess()
 
 	// Send update to updater goroutine
-	select {
-	case o.judgeUpdates <- judgeUpdate{
+	o.sendJudgeUpdate(ctx, judgeUpdate{
 		recordIndex: recordIndex,
 		judgeResult: judgeResult,
-	}:
+	})
+}
+
+// sendJudgeUpdate queues a judge update for the updater goroutine
+func (o *Orchestrator) sendJudgeUpdate(ctx context.Context, update judgeUpdate) {
+	select {
+	case o.judgeUpdates <- update:
 		// Update queued successfully
 	case <-ctx.Done():
 		o.logger.Warn("Judge update dropped due to context cancellation",
-			"record_index", recordIndex)
+			"record_index", update.recordIndex)
+	}
+}
+
+// tripJudgeBreaker handles the judge circuit breaker tripping: either abort the run or
+// keep generating and flag the remaining records judge_failed
+func (o *Orchestrator) tripJudgeBreaker(lastErr error) {
+	threshold := o.cfg.Generation.JudgeFailureThreshold
+	if o.cfg.Generation.JudgeFailureAction == config.JudgeFailureActionFlag {
+		o.logger.Error("Judge circuit breaker tripped - continuing without judge, records will be flagged judge_failed",
+			"consecutive_failures", threshold,
+			"last_error", lastErr)
+		return
+	}
+
+	o.logger.Error("Judge circuit breaker tripped - aborting run",
+		"consecutive_failures", threshold,
+		"last_error", lastErr)
+	if o.cancelRun != nil {
+		o.cancelRun(fmt.Errorf("%w: %d consecutive judge failures (last error: %v); set generation.judge_failure_action = \"flag\" to continue without scores",
+			errJudgeUnavailable, threshold, lastErr))
+	}
+}
+
+// syncJudgeStats copies the breaker's judge counts and the judge cache counters into the session stats
+// Called from the goroutine that owns stats (collector or Run)
+func (o *Orchestrator) syncJudgeStats() {
+	if o.judgeModule != nil {
+		o.stats.JudgeCacheHits, o.stats.JudgeCacheMisses = o.judgeModule.CacheStats()
+	}
+	if o.judgeBreaker == nil {
+		return
 	}
+	o.stats.JudgeSuccesses, o.stats.JudgeFailures = o.judgeBreaker.Counts()
 }
@@ -0,0 +1,85 @@
+package reward
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// Client scores responses with a scalar reward model endpoint ([reward_model])
+// Requests and responses follow vLLM's score API: the prompt is text_1, the responses are text_2,
+// and each data item carries the score of the response at its index
+// Requests go through the shared API client, so [network] TLS settings and [retry] backoff apply
+type Client struct {
+	apiClient  *api.Client
+	endpoint   string
+	model      string
+	apiKey     string
+	maxRetries int
+	timeout    time.Duration
+}
+
+type scoreRequest struct {
+	Model string   `json:"model,omitempty"`
+	Text1 string   `json:"text_1"`
+	Text2 []string `json:"text_2"`
+}
+
+type scoreResponse struct {
+	Data []struct {
+		Index int      `json:"index"`
+		Score *float64 `json:"score"`
+	} `json:"data"`
+}
+
+// New creates a client for the [reward_model] endpoint that sends requests through apiClient
+func New(cfg config.RewardModelConfig, apiClient *api.Client, apiKey string) *Client {
+	return &Client{
+		apiClient:  apiClient,
+		endpoint:   cfg.BaseURL,
+		model:      cfg.ModelName,
+		apiKey:     apiKey,
+		maxRetries: cfg.MaxRetries,
+		timeout:    cfg.Timeout(),
+	}
+}
+
+// Score returns one reward per response to prompt, in order, from a single request
+func (c *Client) Score(ctx context.Context, prompt string, responses []string) ([]float64, error) {
+	body, err := json.Marshal(scoreRequest{Model: c.model, Text1: prompt, Text2: responses})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	respBody, err := c.apiClient.PostJSON(ctx, c.endpoint, c.apiKey, body, c.maxRetries, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed scoreResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(parsed.Data) != len(responses) {
+		return nil, fmt.Errorf("expected %d scores, got %d", len(responses), len(parsed.Data))
+	}
+
+	// Like embeddings, items may come back out of order; index says which response each belongs to
+	scores := make([]float64, len(responses))
+	seen := make([]bool, len(responses))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(responses) || seen[item.Index] {
+			return nil, fmt.Errorf("invalid or repeated score index %d", item.Index)
+		}
+		if item.Score == nil {
+			return nil, fmt.Errorf("missing score for response %d", item.Index)
+		}
+		scores[item.Index] = *item.Score
+		seen[item.Index] = true
+	}
+	return scores, nil
+}
@@ -0,0 +1,111 @@
+package reward
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+// testAPIClient returns an API client that retries once without a real backoff
+func testAPIClient() *api.Client {
+	client := api.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client.SetRetryOptions(api.RetryOptions{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	return client
+}
+
+func TestScoreOrdersByIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Expected bearer auth, got %q", got)
+		}
+		var req scoreRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Model != "rm" || req.Text1 != "Write a story" {
+			t.Errorf("Expected model rm and the prompt as text_1, got %+v", req)
+		}
+
+		// Answer in reverse order; the client must place items by index
+		var items []string
+		for i := len(req.Text2) - 1; i >= 0; i-- {
+			items = append(items, fmt.Sprintf(`{"index": %d, "object": "score", "score": %d.5}`, i, len(req.Text2[i])))
+		}
+		_, _ = fmt.Fprintf(w, `{"data": [%s]}`, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	client := New(config.RewardModelConfig{BaseURL: server.URL + "/score", ModelName: "rm"}, testAPIClient(), "key")
+	scores, err := client.Score(context.Background(), "Write a story", []string{"a", "bbb"})
+	if err != nil {
+		t.Fatalf("Score returned unexpected error: %v", err)
+	}
+	for i, want := range []float64{1.5, 3.5} {
+		if scores[i] != want {
+			t.Errorf("Score %d: expected %v, got %v", i, want, scores[i])
+		}
+	}
+}
+
+func TestScoreErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		errMsg string
+	}{
+		{"http error", http.StatusUnauthorized, `{"error": "bad key"}`, "status 401"},
+		{"server error after retries", http.StatusServiceUnavailable, `overloaded`, "max retries exceeded"},
+		{"missing items", http.StatusOK, `{"data": [{"index": 0, "score": 1}]}`, "expected 2 scores"},
+		{"repeated index", http.StatusOK, `{"data": [{"index": 0, "score": 1}, {"index": 0, "score": 2}]}`, "repeated score index"},
+		{"missing score", http.StatusOK, `{"data": [{"index": 0, "score": 1}, {"index": 1}]}`, "missing score"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := New(config.RewardModelConfig{BaseURL: server.URL}, testAPIClient(), "")
+			_, err := client.Score(context.Background(), "p", []string{"a", "b"})
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestScoreRetriesTransientErrors(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"data": [{"index": 0, "score": 0.25}]}`)
+	}))
+	defer server.Close()
+
+	client := New(config.RewardModelConfig{BaseURL: server.URL}, testAPIClient(), "")
+	scores, err := client.Score(context.Background(), "p", []string{"a"})
+	if err != nil {
+		t.Fatalf("Score returned unexpected error: %v", err)
+	}
+	if len(scores) != 1 || scores[0] != 0.25 || requests != 2 {
+		t.Errorf("Expected score 0.25 after a retry, got %v after %d requests", scores, requests)
+	}
+}
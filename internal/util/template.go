@@ -3,6 +3,7 @@ package util
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 	"text/template"
@@ -13,6 +14,62 @@ var (
 	templateCache sync.Map // map[string]*template.Template
 )
 
+// templateFuncs is the curated set of helpers available in every template
+// Argument order follows Sprig so helpers work at the end of a pipeline:
+//
+//	upper   {{upper .Prompt}}               uppercase a string
+//	lower   {{lower .Prompt}}               lowercase a string
+//	trim    {{trim .Prompt}}                trim leading/trailing whitespace
+//	join    {{join ", " .ExcludeSubtopics}} join a list with a separator
+//	default {{.Genre | default "fantasy"}}  fallback when the value is empty
+//	index   {{index .Items 0}}              built-in text/template indexing into slices/maps
+var templateFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"join":    templateJoin,
+	"default": templateDefault,
+}
+
+// templateJoin joins the elements of a list (any slice or array) with sep
+func templateJoin(sep string, list interface{}) (string, error) {
+	if list == nil {
+		return "", nil
+	}
+	if strs, ok := list.([]string); ok {
+		return strings.Join(strs, sep), nil
+	}
+
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list, got %T", list)
+	}
+	parts := make([]string, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// templateDefault returns def when value is empty (nil, zero value, or empty string/list/map)
+func templateDefault(def interface{}, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
 // RenderTemplate renders a template string with the given data
 // Templates are cached for performance (thread-safe with sync.Map)
 // Includes validation to prevent template injection attacks
@@ -39,6 +96,7 @@ func RenderTemplate(tmpl string, data map[string]interface{}) (string, error) {
 	// Parse template (cache miss)
 	t, err := template.New("prompt").
 		Option("missingkey=error"). // Fail on missing keys to prevent silent errors
+		Funcs(templateFuncs).
 		Parse(tmpl)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
@@ -97,3 +97,59 @@ func TestRenderTemplate_EmptyTemplate(t *testing.T) {
 		t.Errorf("Expected empty result, got '%s'", result)
 	}
 }
+
+func TestRenderTemplate_HelperFunctions(t *testing.T) {
+	data := map[string]interface{}{
+		"Name":   "  Alice  ",
+		"Title":  "The Dragon",
+		"Tags":   []string{"fantasy", "dragons", "adventure"},
+		"Scores": []interface{}{1, 2, 3},
+		"Empty":  "",
+		"Count":  0,
+		"Meta":   map[string]interface{}{"genre": "horror"},
+	}
+
+	tests := []struct {
+		name     string
+		tmpl     string
+		expected string
+	}{
+		{"upper", "{{upper .Title}}", "THE DRAGON"},
+		{"lower", "{{lower .Title}}", "the dragon"},
+		{"trim", "[{{trim .Name}}]", "[Alice]"},
+		{"upper pipeline", "{{.Title | upper}}", "THE DRAGON"},
+		{"join strings", `{{join ", " .Tags}}`, "fantasy, dragons, adventure"},
+		{"join pipeline", `{{.Tags | join "/"}}`, "fantasy/dragons/adventure"},
+		{"join mixed list", `{{join "-" .Scores}}`, "1-2-3"},
+		{"default empty string", `{{.Empty | default "none"}}`, "none"},
+		{"default zero int", `{{.Count | default 5}}`, "5"},
+		{"default keeps value", `{{.Title | default "none"}}`, "The Dragon"},
+		{"index slice", "{{index .Tags 1}}", "dragons"},
+		{"index map", `{{index .Meta "genre"}}`, "horror"},
+		{"chained helpers", `{{.Name | trim | lower}}`, "alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RenderTemplate(tt.tmpl, data)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate_JoinRejectsNonList(t *testing.T) {
+	data := map[string]interface{}{"Title": "The Dragon"}
+
+	_, err := RenderTemplate(`{{join ", " .Title}}`, data)
+	if err == nil {
+		t.Fatal("Expected error when joining a non-list value, got nil")
+	}
+	if !strings.Contains(err.Error(), "expected a list") {
+		t.Errorf("Expected list error, got: %v", err)
+	}
+}
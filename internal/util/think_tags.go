@@ -11,6 +11,9 @@ var (
 	thinkTagRegex = regexp.MustCompile(`(?i)<think(?:ing)?>([\s\S]*?)</think(?:ing)?>`)
 	// Matches Chinese reasoning tags (some Chinese models use these)
 	chineseThinkTagRegex = regexp.MustCompile(`(?i)<思考>([\s\S]*?)</思考>`)
+	// Match lone closing/opening tags left behind by truncated or malformed output
+	orphanCloseTagRegex = regexp.MustCompile(`(?i)</(?:think(?:ing)?|思考)>`)
+	orphanOpenTagRegex  = regexp.MustCompile(`(?i)<(?:think(?:ing)?|思考)>`)
 )
 
 // ContainsThinkTags checks if the response contains think/reasoning tags
@@ -60,3 +63,19 @@ func SplitThinkAndAnswer(response string) (string, string) {
 	answer := StripThinkTags(response)
 	return thinkContent, answer
 }
+
+// StripReasoningFromPrompt removes reasoning blocks and any orphaned reasoning tags from a prompt
+// Prompts should never carry reasoning, so unlike StripThinkTags this also handles unmatched tags:
+// text before a lone closing tag and after a lone opening tag is treated as reasoning and dropped
+func StripReasoningFromPrompt(prompt string) string {
+	result := StripThinkTags(prompt)
+
+	if locs := orphanCloseTagRegex.FindAllStringIndex(result, -1); len(locs) > 0 {
+		result = result[locs[len(locs)-1][1]:]
+	}
+	if loc := orphanOpenTagRegex.FindStringIndex(result); loc != nil {
+		result = result[:loc[0]]
+	}
+
+	return strings.TrimSpace(result)
+}
@@ -124,3 +124,28 @@ func TestSplitThinkAndAnswer(t *testing.T) {
 		t.Errorf("SplitThinkAndAnswer() answer = %q, want %q", answer, expectedAnswer)
 	}
 }
+
+func TestStripReasoningFromPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"clean prompt", "Write a story about dragons", "Write a story about dragons"},
+		{"leading think block", "<think>The user wants a prompt</think>Write a story about dragons", "Write a story about dragons"},
+		{"thinking variant", "<thinking>plan</thinking>\nWrite a poem", "Write a poem"},
+		{"chinese tags", "<思考>计划</思考>Write a poem", "Write a poem"},
+		{"orphan closing tag", "some leaked reasoning</think>Write a story", "Write a story"},
+		{"orphan opening tag", "Write a story <think>partial reasoning", "Write a story"},
+		{"case insensitive", "<THINK>x</THINK>Prompt", "Prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := StripReasoningFromPrompt(tt.input)
+			if result != tt.expected {
+				t.Errorf("StripReasoningFromPrompt() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
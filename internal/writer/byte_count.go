@@ -0,0 +1,30 @@
+package writer
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// countingWriter counts the bytes written through it (generation.max_output_bytes)
+// Not safe for concurrent use; the dataset writers only use it under their mutex
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// newCountingWriter wraps file, starting the count at its current size so resumed sessions
+// count the rows written by earlier runs
+func newCountingWriter(file *os.File) (*countingWriter, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat dataset file: %w", err)
+	}
+	return &countingWriter{w: file, n: info.Size()}, nil
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
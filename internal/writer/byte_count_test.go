@@ -0,0 +1,114 @@
+package writer
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", path, err)
+	}
+	return info.Size()
+}
+
+func TestDatasetWriter_BytesWritten(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sessionMgr := &SessionManager{sessionDir: t.TempDir()}
+	record := models.SFTRecord{Instruction: "Write a story", Output: "Once upon a time"}
+
+	dw, err := NewDatasetWriter(sessionMgr, logger, false, 0)
+	if err != nil {
+		t.Fatalf("NewDatasetWriter returned unexpected error: %v", err)
+	}
+	if got := dw.BytesWritten(); got != 0 {
+		t.Errorf("Expected 0 bytes for a new dataset, got %d", got)
+	}
+	for i := 0; i < 2; i++ {
+		if err := dw.WriteSFTRecord(record, ""); err != nil {
+			t.Fatalf("WriteSFTRecord returned unexpected error: %v", err)
+		}
+	}
+	written := dw.BytesWritten()
+	if err := dw.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+	if size := fileSize(t, sessionMgr.GetDatasetPath()); written != size {
+		t.Errorf("Expected %d bytes (the file size), got %d", size, written)
+	}
+
+	// A resumed session counts the rows already on disk
+	dw, err = NewDatasetWriter(sessionMgr, logger, true, 0)
+	if err != nil {
+		t.Fatalf("NewDatasetWriter (resume) returned unexpected error: %v", err)
+	}
+	defer func() { _ = dw.Close() }()
+	if got := dw.BytesWritten(); got != written {
+		t.Errorf("Expected resumed count to start at %d, got %d", written, got)
+	}
+}
+
+func TestDualDatasetWriter_BytesWrittenCountsBothFiles(t *testing.T) {
+	sessionMgr := &SessionManager{sessionDir: t.TempDir()}
+	dw, err := NewDualDatasetWriter(sessionMgr, slog.New(slog.NewTextHandler(io.Discard, nil)), false, 0)
+	if err != nil {
+		t.Fatalf("NewDualDatasetWriter returned unexpected error: %v", err)
+	}
+	record := models.SFTRecord{Instruction: "Write a story", Output: "Once upon a time"}
+	if err := dw.WriteSFTRecord(record, "thinking it over"); err != nil {
+		t.Fatalf("WriteSFTRecord returned unexpected error: %v", err)
+	}
+	written := dw.BytesWritten()
+	if err := dw.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+
+	want := fileSize(t, sessionMgr.GetDatasetPath()) + fileSize(t, sessionMgr.GetReasoningDatasetPath())
+	if written != want {
+		t.Errorf("Expected %d bytes across both files, got %d", want, written)
+	}
+}
+
+func TestDatasetWriter_BytesWrittenCountsBufferedRecords(t *testing.T) {
+	sessionMgr := &SessionManager{sessionDir: t.TempDir()}
+	dw, err := NewDatasetWriter(sessionMgr, slog.New(slog.NewTextHandler(io.Discard, nil)), false, 0)
+	if err != nil {
+		t.Fatalf("NewDatasetWriter returned unexpected error: %v", err)
+	}
+
+	// MO-DPO records stay in memory until the final flush without max_buffered_records
+	index, err := dw.WriteRecord(models.DatasetRecord{Prompt: "Write a story", Chosen: "Once upon a time", Rejected: "The end"})
+	if err != nil {
+		t.Fatalf("WriteRecord returned unexpected error: %v", err)
+	}
+	buffered := dw.BytesWritten()
+	if buffered == 0 {
+		t.Fatal("Expected the buffered record to count toward BytesWritten")
+	}
+	if size := fileSize(t, sessionMgr.GetDatasetPath()); size != 0 {
+		t.Fatalf("Expected nothing on disk before the flush, got %d bytes", size)
+	}
+
+	// Judge scores grow the record, and the count follows
+	scores := map[string]models.CriteriaScore{"plot": {Score: 4, Reasoning: "Tight pacing"}}
+	if err := dw.UpdateRecord(index, &models.JudgeResult{ChosenScores: scores, ChosenScoreTotal: 4}); err != nil {
+		t.Fatalf("UpdateRecord returned unexpected error: %v", err)
+	}
+	judged := dw.BytesWritten()
+	if judged <= buffered {
+		t.Errorf("Expected judge scores to grow the count past %d, got %d", buffered, judged)
+	}
+
+	if err := dw.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+	if size := fileSize(t, sessionMgr.GetDatasetPath()); judged != size {
+		t.Errorf("Expected the buffered count %d to match the flushed file size %d", judged, size)
+	}
+}
@@ -1,7 +1,6 @@
 package writer
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
@@ -12,10 +11,12 @@ import (
 
 // DatasetWriter handles thread-safe writing to the dataset file
 type DatasetWriter struct {
-	file    *os.File
-	mu      sync.Mutex
-	logger  *slog.Logger
-	records []models.DatasetRecord // In-memory buffer for async judge updates
+	file   *os.File
+	out    *countingWriter // Writes to file, counting the bytes for BytesWritten
+	mu     sync.Mutex
+	logger *slog.Logger
+	buffer *recordBuffer // In-memory buffer for async judge updates
+	ids    bool          // Assign content-derived record IDs (generation.include_id)
 }
 
 // NewDatasetWriter creates a new dataset writer
@@ -49,23 +50,44 @@ func NewDatasetWriter(sessionMgr *SessionManager, logger *slog.Logger, resumeMod
 		initialCapacity = 1024
 	}
 
+	out, err := newCountingWriter(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
 	return &DatasetWriter{
-		file:    file,
-		logger:  logger,
-		records: make([]models.DatasetRecord, 0, initialCapacity),
+		file:   file,
+		out:    out,
+		logger: logger,
+		buffer: newRecordBuffer(initialCapacity),
 	}, nil
 }
 
 // WriteRecord writes a single record to the in-memory buffer and returns its index
-// The record will be written to file during Close()
+// The record will be written to file during Close(), or earlier by a spill once judged
 // This is used for MO-DPO mode (full feature set)
 func (dw *DatasetWriter) WriteRecord(record models.DatasetRecord) (int, error) {
+	if err := ValidateDatasetRecord(record); err != nil {
+		return -1, err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
 	// Add to in-memory buffer
-	index := len(dw.records)
-	dw.records = append(dw.records, record)
+	index := dw.buffer.add(record)
+	if dw.buffer.overLimit() {
+		count, err := dw.buffer.spill(dw.out)
+		if err != nil {
+			return index, fmt.Errorf("failed to spill judged records: %w", err)
+		}
+		dw.logger.Debug("Spilled judged records to disk", "count", count, "pending", dw.buffer.live)
+	}
 
 	return index, nil
 }
@@ -73,15 +95,23 @@ func (dw *DatasetWriter) WriteRecord(record models.DatasetRecord) (int, error) {
 // WriteSFTRecord writes an SFT record directly to file (bypasses buffer)
 // reasoning parameter is ignored in single dataset mode (for interface compatibility)
 func (dw *DatasetWriter) WriteSFTRecord(record models.SFTRecord, reasoning string) error {
+	if err := ValidateSFTRecord(record); err != nil {
+		return err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	data, err := json.Marshal(record)
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	data, err := encodeLine(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal SFT record: %w", err)
 	}
 
-	if _, err := dw.file.Write(append(data, '\n')); err != nil {
+	if _, err := dw.out.Write(data); err != nil {
 		return fmt.Errorf("failed to write SFT record: %w", err)
 	}
 
@@ -91,34 +121,102 @@ func (dw *DatasetWriter) WriteSFTRecord(record models.SFTRecord, reasoning strin
 // WriteDPORecord writes a DPO record directly to file (bypasses buffer)
 // reasoning parameters are ignored in single dataset mode (for interface compatibility)
 func (dw *DatasetWriter) WriteDPORecord(record models.DPORecord, chosenReasoning, rejectedReasoning string) error {
+	if err := ValidateDPORecord(record); err != nil {
+		return err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	data, err := json.Marshal(record)
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	data, err := encodeLine(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal DPO record: %w", err)
 	}
 
-	if _, err := dw.file.Write(append(data, '\n')); err != nil {
+	if _, err := dw.out.Write(data); err != nil {
 		return fmt.Errorf("failed to write DPO record: %w", err)
 	}
 
 	return nil
 }
 
+// WriteMultiRejectedDPORecord writes a multi-rejected DPO record directly to file (bypasses buffer)
+// reasoning parameters are ignored in single dataset mode (for interface compatibility)
+func (dw *DatasetWriter) WriteMultiRejectedDPORecord(record models.MultiRejectedDPORecord, chosenReasoning string, rejectedReasoning []string) error {
+	if err := ValidateMultiRejectedDPORecord(record); err != nil {
+		return err
+	}
+
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	data, err := encodeLine(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal multi-rejected DPO record: %w", err)
+	}
+
+	if _, err := dw.out.Write(data); err != nil {
+		return fmt.Errorf("failed to write multi-rejected DPO record: %w", err)
+	}
+
+	return nil
+}
+
+// WriteConversationalDPORecord writes a conversational DPO record directly to file (bypasses buffer)
+// reasoning parameters are ignored in single dataset mode (for interface compatibility)
+func (dw *DatasetWriter) WriteConversationalDPORecord(record models.ConversationalDPORecord, chosenReasoning, rejectedReasoning string) error {
+	if err := ValidateConversationalDPORecord(record); err != nil {
+		return err
+	}
+
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	data, err := encodeLine(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversational DPO record: %w", err)
+	}
+
+	if _, err := dw.out.Write(data); err != nil {
+		return fmt.Errorf("failed to write conversational DPO record: %w", err)
+	}
+
+	return nil
+}
+
 // WriteKTORecord writes a KTO record directly to file (bypasses buffer)
 // KTO mode generates 2 rows per preference pair (one chosen, one rejected)
 // reasoning parameter is ignored in single dataset mode (for interface compatibility)
 func (dw *DatasetWriter) WriteKTORecord(record models.KTORecord, reasoning string) error {
+	if err := ValidateKTORecord(record); err != nil {
+		return err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	data, err := json.Marshal(record)
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	data, err := encodeLine(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal KTO record: %w", err)
 	}
 
-	if _, err := dw.file.Write(append(data, '\n')); err != nil {
+	if _, err := dw.out.Write(data); err != nil {
 		return fmt.Errorf("failed to write KTO record: %w", err)
 	}
 
@@ -126,41 +224,71 @@ func (dw *DatasetWriter) WriteKTORecord(record models.KTORecord, reasoning strin
 }
 
 // UpdateRecord updates a previously written record with judge results
+// Returns ErrRecordFlushed if the record was already written to disk
 func (dw *DatasetWriter) UpdateRecord(index int, judgeResult *models.JudgeResult) error {
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	if index < 0 || index >= len(dw.records) {
-		return fmt.Errorf("invalid record index: %d", index)
-	}
+	return dw.buffer.update(index, judgeResult)
+}
 
-	// Update judge fields in memory
-	dw.records[index].ChosenScores = judgeResult.ChosenScores
-	dw.records[index].RejectedScores = judgeResult.RejectedScores
-	dw.records[index].ChosenScoreTotal = judgeResult.ChosenScoreTotal
-	dw.records[index].RejectedScoreTotal = judgeResult.RejectedScoreTotal
-	dw.records[index].PreferenceMargin = judgeResult.PreferenceMargin
+// MarkJudgeFailed flags a previously written record whose judge evaluation failed
+func (dw *DatasetWriter) MarkJudgeFailed(index int) error {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
 
-	return nil
+	return dw.buffer.markJudgeFailed(index)
 }
 
-// Flush writes all buffered records to disk
+// SetMinPreferenceMargin sets the minimum preference margin applied to buffered records when flushed
+func (dw *DatasetWriter) SetMinPreferenceMargin(margin float64) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	dw.buffer.filter.minMargin = margin
+}
+
+// SetMaxBufferedRecords bounds the MO-DPO buffer: past limit records, judged ones are spilled to disk
+func (dw *DatasetWriter) SetMaxBufferedRecords(limit int) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	dw.buffer.limit = limit
+}
+
+// SetIncludeIDs enables content-derived record IDs for records written from now on
+func (dw *DatasetWriter) SetIncludeIDs(enabled bool) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	dw.ids = enabled
+}
+
+// BytesWritten returns the size of the dataset file, including rows from earlier runs of a
+// resumed session and MO-DPO records still buffered in memory
+func (dw *DatasetWriter) BytesWritten() int64 {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	return dw.out.n + dw.buffer.pendingBytes
+}
+
+// Flush writes all buffered records to disk, including any still waiting on the judge
 func (dw *DatasetWriter) Flush() error {
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	dw.logger.Info("Flushing records to disk", "count", len(dw.records))
+	return dw.flushLocked()
+}
 
-	for i, record := range dw.records {
-		data, err := json.Marshal(record)
-		if err != nil {
-			return fmt.Errorf("failed to marshal record %d: %w", i, err)
-		}
+// flushLocked writes and clears the buffer; callers must hold dw.mu
+func (dw *DatasetWriter) flushLocked() error {
+	dw.logger.Info("Flushing records to disk", "count", dw.buffer.live, "spilled", dw.buffer.spilled)
 
-		if _, err := dw.file.Write(append(data, '\n')); err != nil {
-			return fmt.Errorf("failed to write record %d: %w", i, err)
-		}
+	if _, err := dw.buffer.flush(dw.out); err != nil {
+		return err
 	}
+	dw.buffer.filter.log(dw.logger)
 
 	dw.logger.Info("Successfully flushed all records")
 	return nil
@@ -174,16 +302,8 @@ func (dw *DatasetWriter) Close() error {
 	defer dw.mu.Unlock()
 
 	// Flush all buffered records to disk (inline to maintain lock)
-	dw.logger.Info("Flushing records to disk before close", "count", len(dw.records))
-	for i, record := range dw.records {
-		data, err := json.Marshal(record)
-		if err != nil {
-			return fmt.Errorf("failed to marshal record %d: %w", i, err)
-		}
-
-		if _, err := dw.file.Write(append(data, '\n')); err != nil {
-			return fmt.Errorf("failed to write record %d: %w", i, err)
-		}
+	if err := dw.flushLocked(); err != nil {
+		return err
 	}
 
 	// Sync to ensure all data is written to disk
@@ -1,7 +1,6 @@
 package writer
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
@@ -16,9 +15,12 @@ import (
 type DualDatasetWriter struct {
 	regularFile   *os.File
 	reasoningFile *os.File
+	regularOut    *countingWriter // Writes to regularFile, counting the bytes for BytesWritten
+	reasoningOut  *countingWriter // Writes to reasoningFile, counting the bytes for BytesWritten
 	mu            sync.Mutex
 	logger        *slog.Logger
-	records       []models.DatasetRecord // In-memory buffer for async judge updates (regular only)
+	buffer        *recordBuffer // In-memory buffer for async judge updates (regular only)
+	ids           bool          // Assign content-derived record IDs (generation.include_id)
 }
 
 // NewDualDatasetWriter creates a writer that outputs both regular and reasoning datasets
@@ -66,11 +68,26 @@ func NewDualDatasetWriter(sessionMgr *SessionManager, logger *slog.Logger, resum
 		initialCapacity = 1024
 	}
 
+	regularOut, err := newCountingWriter(regularFile)
+	if err != nil {
+		_ = regularFile.Close()
+		_ = reasoningFile.Close()
+		return nil, err
+	}
+	reasoningOut, err := newCountingWriter(reasoningFile)
+	if err != nil {
+		_ = regularFile.Close()
+		_ = reasoningFile.Close()
+		return nil, err
+	}
+
 	return &DualDatasetWriter{
 		regularFile:   regularFile,
 		reasoningFile: reasoningFile,
+		regularOut:    regularOut,
+		reasoningOut:  reasoningOut,
 		logger:        logger,
-		records:       make([]models.DatasetRecord, 0, initialCapacity),
+		buffer:        newRecordBuffer(initialCapacity),
 	}, nil
 }
 
@@ -78,28 +95,36 @@ func NewDualDatasetWriter(sessionMgr *SessionManager, logger *slog.Logger, resum
 // Regular: standard output
 // Reasoning: output with <think> tags if reasoning content present
 func (dw *DualDatasetWriter) WriteSFTRecord(record models.SFTRecord, reasoning string) error {
+	if err := ValidateSFTRecord(record); err != nil {
+		return err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
 	// Write regular record (without reasoning)
-	regularData, err := json.Marshal(record)
+	regularData, err := encodeLine(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal regular SFT record: %w", err)
 	}
 
-	if _, err := dw.regularFile.Write(append(regularData, '\n')); err != nil {
+	if _, err := dw.regularOut.Write(regularData); err != nil {
 		return fmt.Errorf("failed to write regular SFT record: %w", err)
 	}
 
 	// Write reasoning record (with think tags if reasoning present)
 	reasoningRecord := applyReasoningToSFTRecord(record, reasoning)
 
-	reasoningData, err := json.Marshal(reasoningRecord)
+	reasoningData, err := encodeLine(reasoningRecord)
 	if err != nil {
 		return fmt.Errorf("failed to marshal reasoning SFT record: %w", err)
 	}
 
-	if _, err := dw.reasoningFile.Write(append(reasoningData, '\n')); err != nil {
+	if _, err := dw.reasoningOut.Write(reasoningData); err != nil {
 		return fmt.Errorf("failed to write reasoning SFT record: %w", err)
 	}
 
@@ -108,16 +133,24 @@ func (dw *DualDatasetWriter) WriteSFTRecord(record models.SFTRecord, reasoning s
 
 // WriteDPORecord writes a DPO record to both datasets
 func (dw *DualDatasetWriter) WriteDPORecord(record models.DPORecord, chosenReasoning, rejectedReasoning string) error {
+	if err := ValidateDPORecord(record); err != nil {
+		return err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
 	// Write regular record (without reasoning)
-	regularData, err := json.Marshal(record)
+	regularData, err := encodeLine(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal regular DPO record: %w", err)
 	}
 
-	if _, err := dw.regularFile.Write(append(regularData, '\n')); err != nil {
+	if _, err := dw.regularOut.Write(regularData); err != nil {
 		return fmt.Errorf("failed to write regular DPO record: %w", err)
 	}
 
@@ -130,30 +163,126 @@ func (dw *DualDatasetWriter) WriteDPORecord(record models.DPORecord, chosenReaso
 		reasoningRecord.Rejected = util.CombineReasoningAndContent(rejectedReasoning, record.Rejected)
 	}
 
-	reasoningData, err := json.Marshal(reasoningRecord)
+	reasoningData, err := encodeLine(reasoningRecord)
 	if err != nil {
 		return fmt.Errorf("failed to marshal reasoning DPO record: %w", err)
 	}
 
-	if _, err := dw.reasoningFile.Write(append(reasoningData, '\n')); err != nil {
+	if _, err := dw.reasoningOut.Write(reasoningData); err != nil {
 		return fmt.Errorf("failed to write reasoning DPO record: %w", err)
 	}
 
 	return nil
 }
 
+// WriteMultiRejectedDPORecord writes a multi-rejected DPO record to both datasets
+func (dw *DualDatasetWriter) WriteMultiRejectedDPORecord(record models.MultiRejectedDPORecord, chosenReasoning string, rejectedReasoning []string) error {
+	if err := ValidateMultiRejectedDPORecord(record); err != nil {
+		return err
+	}
+
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	// Write regular record (without reasoning)
+	regularData, err := encodeLine(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal regular multi-rejected DPO record: %w", err)
+	}
+
+	if _, err := dw.regularOut.Write(regularData); err != nil {
+		return fmt.Errorf("failed to write regular multi-rejected DPO record: %w", err)
+	}
+
+	// Write reasoning record (with think tags if reasoning present)
+	reasoningRecord := record
+	if chosenReasoning != "" {
+		reasoningRecord.Chosen = util.CombineReasoningAndContent(chosenReasoning, record.Chosen)
+	}
+	reasoningRecord.Rejected = make([]string, len(record.Rejected))
+	for i, rejected := range record.Rejected {
+		reasoningRecord.Rejected[i] = rejected
+		if i < len(rejectedReasoning) && rejectedReasoning[i] != "" {
+			reasoningRecord.Rejected[i] = util.CombineReasoningAndContent(rejectedReasoning[i], rejected)
+		}
+	}
+
+	reasoningData, err := encodeLine(reasoningRecord)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reasoning multi-rejected DPO record: %w", err)
+	}
+
+	if _, err := dw.reasoningOut.Write(reasoningData); err != nil {
+		return fmt.Errorf("failed to write reasoning multi-rejected DPO record: %w", err)
+	}
+
+	return nil
+}
+
+// WriteConversationalDPORecord writes a conversational DPO record to both datasets
+func (dw *DualDatasetWriter) WriteConversationalDPORecord(record models.ConversationalDPORecord, chosenReasoning, rejectedReasoning string) error {
+	if err := ValidateConversationalDPORecord(record); err != nil {
+		return err
+	}
+
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	// Write regular record (without reasoning)
+	regularData, err := encodeLine(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal regular conversational DPO record: %w", err)
+	}
+
+	if _, err := dw.regularOut.Write(regularData); err != nil {
+		return fmt.Errorf("failed to write regular conversational DPO record: %w", err)
+	}
+
+	// Write reasoning record (with think tags on the assistant messages if reasoning present)
+	reasoningRecord := record
+	reasoningRecord.Chosen = applyReasoningToMessages(record.Chosen, chosenReasoning)
+	reasoningRecord.Rejected = applyReasoningToMessages(record.Rejected, rejectedReasoning)
+
+	reasoningData, err := encodeLine(reasoningRecord)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reasoning conversational DPO record: %w", err)
+	}
+
+	if _, err := dw.reasoningOut.Write(reasoningData); err != nil {
+		return fmt.Errorf("failed to write reasoning conversational DPO record: %w", err)
+	}
+
+	return nil
+}
+
 // WriteKTORecord writes a KTO record to both datasets
 func (dw *DualDatasetWriter) WriteKTORecord(record models.KTORecord, reasoning string) error {
+	if err := ValidateKTORecord(record); err != nil {
+		return err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
 	// Write regular record (without reasoning)
-	regularData, err := json.Marshal(record)
+	regularData, err := encodeLine(record)
 	if err != nil {
 		return fmt.Errorf("failed to marshal regular KTO record: %w", err)
 	}
 
-	if _, err := dw.regularFile.Write(append(regularData, '\n')); err != nil {
+	if _, err := dw.regularOut.Write(regularData); err != nil {
 		return fmt.Errorf("failed to write regular KTO record: %w", err)
 	}
 
@@ -163,18 +292,39 @@ func (dw *DualDatasetWriter) WriteKTORecord(record models.KTORecord, reasoning s
 		reasoningRecord.Completion = util.CombineReasoningAndContent(reasoning, record.Completion)
 	}
 
-	reasoningData, err := json.Marshal(reasoningRecord)
+	reasoningData, err := encodeLine(reasoningRecord)
 	if err != nil {
 		return fmt.Errorf("failed to marshal reasoning KTO record: %w", err)
 	}
 
-	if _, err := dw.reasoningFile.Write(append(reasoningData, '\n')); err != nil {
+	if _, err := dw.reasoningOut.Write(reasoningData); err != nil {
 		return fmt.Errorf("failed to write reasoning KTO record: %w", err)
 	}
 
 	return nil
 }
 
+// applyReasoningToMessages returns a copy of messages with reasoning merged into the last
+// assistant message (appending one if there is none); the input slice is not modified
+func applyReasoningToMessages(messages []models.OpenAIMessage, reasoning string) []models.OpenAIMessage {
+	if reasoning == "" {
+		return messages
+	}
+
+	updated := append([]models.OpenAIMessage(nil), messages...)
+	for i := len(updated) - 1; i >= 0; i-- {
+		if strings.ToLower(updated[i].Role) == "assistant" {
+			updated[i].Content = util.CombineReasoningAndContent(reasoning, updated[i].Content)
+			return updated
+		}
+	}
+
+	return append(updated, models.OpenAIMessage{
+		Role:    "assistant",
+		Content: util.CombineReasoningAndContent(reasoning, ""),
+	})
+}
+
 // applyReasoningToSFTRecord returns a copy of record with reasoning merged into the answer portion
 func applyReasoningToSFTRecord(record models.SFTRecord, reasoning string) models.SFTRecord {
 	if reasoning == "" {
@@ -183,6 +333,11 @@ func applyReasoningToSFTRecord(record models.SFTRecord, reasoning string) models
 
 	reasoningRecord := record
 
+	if len(reasoningRecord.Messages) > 0 {
+		reasoningRecord.Messages = applyReasoningToMessages(record.Messages, reasoning)
+		return reasoningRecord
+	}
+
 	if len(reasoningRecord.Conversations) > 0 {
 		for i := len(reasoningRecord.Conversations) - 1; i >= 0; i-- {
 			from := strings.ToLower(reasoningRecord.Conversations[i].From)
@@ -211,53 +366,93 @@ func applyReasoningToSFTRecord(record models.SFTRecord, reasoning string) models
 // Returns the record index for later updates
 // Only written to regular dataset (reasoning dataset doesn't support MO-DPO buffering)
 func (dw *DualDatasetWriter) WriteRecord(record models.DatasetRecord) (int, error) {
+	if err := ValidateDatasetRecord(record); err != nil {
+		return -1, err
+	}
+
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	index := len(dw.records)
-	dw.records = append(dw.records, record)
+	if dw.ids {
+		record.ID = recordID(record)
+	}
+
+	index := dw.buffer.add(record)
+	if dw.buffer.overLimit() {
+		count, err := dw.buffer.spill(dw.regularOut)
+		if err != nil {
+			return index, fmt.Errorf("failed to spill judged records: %w", err)
+		}
+		dw.logger.Debug("Spilled judged records to regular dataset", "count", count, "pending", dw.buffer.live)
+	}
 	return index, nil
 }
 
 // UpdateRecord updates a record with judge results
 // Only applies to regular dataset (reasoning dataset written immediately)
+// Returns ErrRecordFlushed if the record was already written to disk
 func (dw *DualDatasetWriter) UpdateRecord(recordIndex int, judgeResult *models.JudgeResult) error {
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	if recordIndex < 0 || recordIndex >= len(dw.records) {
-		return fmt.Errorf("invalid record index: %d (total: %d)", recordIndex, len(dw.records))
-	}
+	return dw.buffer.update(recordIndex, judgeResult)
+}
 
-	// Update judge fields in memory (same as DatasetWriter)
-	dw.records[recordIndex].ChosenScores = judgeResult.ChosenScores
-	dw.records[recordIndex].RejectedScores = judgeResult.RejectedScores
-	dw.records[recordIndex].ChosenScoreTotal = judgeResult.ChosenScoreTotal
-	dw.records[recordIndex].RejectedScoreTotal = judgeResult.RejectedScoreTotal
-	dw.records[recordIndex].PreferenceMargin = judgeResult.PreferenceMargin
+// MarkJudgeFailed flags a buffered record whose judge evaluation failed
+func (dw *DualDatasetWriter) MarkJudgeFailed(recordIndex int) error {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
 
-	return nil
+	return dw.buffer.markJudgeFailed(recordIndex)
 }
 
-// Flush writes all buffered records to the regular dataset file
+// SetMinPreferenceMargin sets the minimum preference margin applied to buffered records when flushed
+func (dw *DualDatasetWriter) SetMinPreferenceMargin(margin float64) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	dw.buffer.filter.minMargin = margin
+}
+
+// SetMaxBufferedRecords bounds the MO-DPO buffer: past limit records, judged ones are spilled to disk
+func (dw *DualDatasetWriter) SetMaxBufferedRecords(limit int) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	dw.buffer.limit = limit
+}
+
+// SetIncludeIDs enables content-derived record IDs for records written from now on
+func (dw *DualDatasetWriter) SetIncludeIDs(enabled bool) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	dw.ids = enabled
+}
+
+// BytesWritten returns the combined size of both dataset files, including rows from earlier
+// runs of a resumed session and MO-DPO records still buffered in memory
+func (dw *DualDatasetWriter) BytesWritten() int64 {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	return dw.regularOut.n + dw.reasoningOut.n + dw.buffer.pendingBytes
+}
+
+// Flush writes all buffered records to the regular dataset file, including any still waiting on the judge
 // Reasoning dataset is written immediately, so no flush needed
 func (dw *DualDatasetWriter) Flush() error {
 	dw.mu.Lock()
 	defer dw.mu.Unlock()
 
-	for _, record := range dw.records {
-		data, err := json.Marshal(record)
-		if err != nil {
-			return fmt.Errorf("failed to marshal record: %w", err)
-		}
-
-		if _, err := dw.regularFile.Write(append(data, '\n')); err != nil {
-			return fmt.Errorf("failed to write record: %w", err)
-		}
+	spilled := dw.buffer.spilled
+	count, err := dw.buffer.flush(dw.regularOut)
+	if err != nil {
+		return err
 	}
+	dw.buffer.filter.log(dw.logger)
 
-	dw.logger.Info("Flushed buffered records to regular dataset", "count", len(dw.records))
-	dw.records = dw.records[:0] // Clear buffer
+	dw.logger.Info("Flushed buffered records to regular dataset", "count", count, "spilled", spilled)
 	return nil
 }
 
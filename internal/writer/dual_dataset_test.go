@@ -44,3 +44,43 @@ func TestApplyReasoningToSFTRecordShareGPT(t *testing.T) {
 		t.Fatalf("expected assistant message to be wrapped, got %q", updated.Conversations[1].Value)
 	}
 }
+
+func TestApplyReasoningToSFTRecordOpenAI(t *testing.T) {
+	record := models.SFTRecord{
+		Messages: []models.OpenAIMessage{
+			{Role: "system", Content: "be helpful"},
+			{Role: "user", Content: "hello"},
+			{Role: "assistant", Content: "response"},
+		},
+	}
+	reasoning := "reasoning block"
+
+	updated := applyReasoningToSFTRecord(record, reasoning)
+	if len(updated.Messages) != 3 {
+		t.Fatalf("expected messages length 3, got %d", len(updated.Messages))
+	}
+
+	expected := util.CombineReasoningAndContent(reasoning, "response")
+	if updated.Messages[2].Content != expected {
+		t.Fatalf("expected assistant message to be wrapped, got %q", updated.Messages[2].Content)
+	}
+	if record.Messages[2].Content != "response" {
+		t.Fatalf("expected original record to be left unchanged, got %q", record.Messages[2].Content)
+	}
+}
+
+func TestApplyReasoningToMessages(t *testing.T) {
+	messages := []models.OpenAIMessage{{Role: "assistant", Content: "response"}}
+
+	updated := applyReasoningToMessages(messages, "reasoning block")
+	expected := util.CombineReasoningAndContent("reasoning block", "response")
+	if updated[0].Content != expected {
+		t.Fatalf("expected assistant message to be wrapped, got %q", updated[0].Content)
+	}
+	if messages[0].Content != "response" {
+		t.Fatalf("expected input messages to be unchanged, got %q", messages[0].Content)
+	}
+	if unchanged := applyReasoningToMessages(messages, ""); unchanged[0].Content != "response" {
+		t.Fatalf("expected no change without reasoning, got %q", unchanged[0].Content)
+	}
+}
@@ -0,0 +1,44 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// encodeLine marshals record as one compact JSONL line: no BOM, LF line endings inside
+// string values, and a single trailing LF. Every dataset file is written through it so
+// the HF viewer and line-based training tools see one record per line
+func encodeLine(record any) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(normalizeNewlines(data), '\n'), nil
+}
+
+// normalizeNewlines rewrites the escaped CRLF and lone CR sequences in compact JSON to LF
+// Only escape pairs are touched, so an escaped backslash followed by "r" is left alone
+func normalizeNewlines(data []byte) []byte {
+	if !bytes.Contains(data, []byte(`\r`)) {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] != '\\' || i+1 == len(data) {
+			out = append(out, data[i])
+			continue
+		}
+		if data[i+1] != 'r' {
+			out = append(out, data[i], data[i+1])
+			i++
+			continue
+		}
+		i++
+		if bytes.HasPrefix(data[i+1:], []byte(`\n`)) {
+			continue // CRLF: the \n that follows is kept
+		}
+		out = append(out, '\\', 'n')
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no carriage returns", `{"a":"x\ny"}`, `{"a":"x\ny"}`},
+		{"CRLF becomes LF", `{"a":"x\r\ny\r\n"}`, `{"a":"x\ny\n"}`},
+		{"lone CR becomes LF", `{"a":"x\ry"}`, `{"a":"x\ny"}`},
+		{"escaped backslash before r is kept", `{"a":"C:\\run"}`, `{"a":"C:\\run"}`},
+		{"escaped backslash then CR", `{"a":"\\\r"}`, `{"a":"\\\n"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(normalizeNewlines([]byte(tt.input))); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeLine(t *testing.T) {
+	record := models.DPORecord{Prompt: "p", Chosen: "line one\r\nline two", Rejected: "r"}
+	line, err := encodeLine(record)
+	if err != nil {
+		t.Fatalf("encodeLine returned unexpected error: %v", err)
+	}
+
+	if bytes.Count(line, []byte("\n")) != 1 || line[len(line)-1] != '\n' {
+		t.Errorf("Expected exactly one trailing LF, got %q", line)
+	}
+	if bytes.HasPrefix(line, []byte("\xef\xbb\xbf")) {
+		t.Error("Expected no BOM")
+	}
+
+	var decoded models.DPORecord
+	if err := json.Unmarshal(line, &decoded); err != nil {
+		t.Fatalf("Failed to decode line: %v", err)
+	}
+	if decoded.Chosen != "line one\nline two" {
+		t.Errorf("Expected LF line endings in chosen, got %q", decoded.Chosen)
+	}
+}
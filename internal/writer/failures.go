@@ -0,0 +1,87 @@
+package writer
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// FailuresFile is the session file failed jobs are written to with generation.keep_failed
+const FailuresFile = "failures.jsonl"
+
+// FailedJob is a single line in the failures file
+type FailedJob struct {
+	JobID      int       `json:"job_id"`
+	MainTopic  string    `json:"main_topic,omitempty"`
+	SubTopic   string    `json:"sub_topic"`
+	Prompt     string    `json:"prompt"`
+	ErrorClass string    `json:"error_class"`
+	Error      string    `json:"error"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// FailureLog appends failed jobs to a JSONL file
+// The file is opened lazily so that clean runs don't leave an empty failures file behind
+type FailureLog struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+// NewFailureLog creates a failure log at path. When resume is false any previous
+// failures file is removed so it only reflects the current session
+func NewFailureLog(path string, resume bool) (*FailureLog, error) {
+	if !resume {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale failures file: %w", err)
+		}
+	}
+	return &FailureLog{path: path}, nil
+}
+
+// Record appends a failed job to the failures file
+func (f *FailureLog) Record(job models.GenerationJob, errorClass string, jobErr error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open failures file: %w", err)
+		}
+		f.file = file
+	}
+
+	line, err := encodeLine(FailedJob{
+		JobID:      job.ID,
+		MainTopic:  job.MainTopic,
+		SubTopic:   job.SubTopic,
+		Prompt:     job.Prompt,
+		ErrorClass: errorClass,
+		Error:      jobErr.Error(),
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal failure record: %w", err)
+	}
+	if _, err := f.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write failure record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file if it was opened
+func (f *FailureLog) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
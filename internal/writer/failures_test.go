@@ -0,0 +1,81 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func readFailedJobs(t *testing.T, path string) []FailedJob {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open failures file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var jobs []FailedJob
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var job FailedJob
+		if err := json.Unmarshal(scanner.Bytes(), &job); err != nil {
+			t.Fatalf("Failed to parse failures line %q: %v", scanner.Text(), err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func TestFailureLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FailuresFile)
+
+	log, err := NewFailureLog(path, false)
+	if err != nil {
+		t.Fatalf("NewFailureLog returned error: %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected no failures file when nothing failed")
+	}
+
+	log, _ = NewFailureLog(path, false)
+	job := models.GenerationJob{ID: 3, MainTopic: "Fantasy", SubTopic: "Dragons", Prompt: "Write about a dragon"}
+	if err := log.Record(job, "rate_limit", errors.New("status 429")); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	_ = log.Close()
+
+	jobs := readFailedJobs(t, path)
+	if len(jobs) != 1 {
+		t.Fatalf("Expected 1 failed job, got %d", len(jobs))
+	}
+	got := jobs[0]
+	if got.JobID != 3 || got.MainTopic != "Fantasy" || got.SubTopic != "Dragons" || got.Prompt != job.Prompt {
+		t.Errorf("Expected the job's ID, topics and prompt, got %+v", got)
+	}
+	if got.ErrorClass != "rate_limit" || got.Error != "status 429" {
+		t.Errorf("Expected rate_limit / status 429, got %q / %q", got.ErrorClass, got.Error)
+	}
+
+	// Resuming appends; a fresh session starts over
+	log, _ = NewFailureLog(path, true)
+	_ = log.Record(models.GenerationJob{ID: 4}, "timeout", errors.New("deadline exceeded"))
+	_ = log.Close()
+	if jobs := readFailedJobs(t, path); len(jobs) != 2 {
+		t.Errorf("Expected 2 failed jobs after resume, got %d", len(jobs))
+	}
+
+	if _, err := NewFailureLog(path, false); err != nil {
+		t.Fatalf("NewFailureLog returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected a non-resumed session to remove the stale failures file")
+	}
+}
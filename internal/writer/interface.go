@@ -13,6 +13,14 @@ type Writer interface {
 	// chosenReasoning and rejectedReasoning are used only in dual dataset mode
 	WriteDPORecord(record models.DPORecord, chosenReasoning, rejectedReasoning string) error
 
+	// WriteMultiRejectedDPORecord writes a DPO record with several rejected responses
+	// chosenReasoning and rejectedReasoning (one per rejected response) are used only in dual dataset mode
+	WriteMultiRejectedDPORecord(record models.MultiRejectedDPORecord, chosenReasoning string, rejectedReasoning []string) error
+
+	// WriteConversationalDPORecord writes a DPO record with message-list prompt/chosen/rejected
+	// chosenReasoning and rejectedReasoning are used only in dual dataset mode
+	WriteConversationalDPORecord(record models.ConversationalDPORecord, chosenReasoning, rejectedReasoning string) error
+
 	// WriteKTORecord writes a KTO record
 	// reasoning parameter is used only in dual dataset mode
 	WriteKTORecord(record models.KTORecord, reasoning string) error
@@ -24,7 +32,25 @@ type Writer interface {
 	// UpdateRecord updates a record with judge results
 	UpdateRecord(recordIndex int, judgeResult *models.JudgeResult) error
 
-	// Flush writes all buffered records to disk
+	// MarkJudgeFailed flags a buffered record whose judge evaluation failed
+	MarkJudgeFailed(recordIndex int) error
+
+	// SetMinPreferenceMargin drops buffered MO-DPO records whose judge preference margin
+	// is below margin when they are flushed (0 disables filtering)
+	SetMinPreferenceMargin(margin float64)
+
+	// SetMaxBufferedRecords caps the MO-DPO in-memory buffer: once more than limit records are
+	// buffered, judged records are written to disk early (0 keeps everything until Flush)
+	SetMaxBufferedRecords(limit int)
+
+	// SetIncludeIDs makes every record carry an id derived from its content (assigned when
+	// the record is written, so MO-DPO judge updates keep it)
+	SetIncludeIDs(enabled bool)
+
+	// BytesWritten returns the bytes in the dataset file(s) so far (generation.max_output_bytes)
+	BytesWritten() int64
+
+	// Flush writes all buffered records to disk, including those still waiting on the judge
 	Flush() error
 
 	// Close closes the writer
@@ -2,8 +2,11 @@ package writer
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
+
+	"github.com/lamim/vellumforge2/internal/config"
 )
 
 // multiHandler wraps multiple handlers to write to multiple destinations
@@ -22,6 +25,10 @@ func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
 
 func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
 	for _, handler := range h.handlers {
+		// Handlers can have different levels, so each one filters the record itself
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
 		if err := handler.Handle(ctx, r); err != nil {
 			return err
 		}
@@ -45,27 +52,42 @@ func (h *multiHandler) WithGroup(name string) slog.Handler {
 	return &multiHandler{handlers: handlers}
 }
 
+// LogOptions sets the format (config.LogFormatText or config.LogFormatJSON) and level of the
+// console and session log file handlers independently
+type LogOptions struct {
+	ConsoleFormat string // Default: text
+	ConsoleLevel  slog.Level
+	FileFormat    string // Default: json
+	FileLevel     slog.Level
+}
+
 // SetupLogger creates a multi-handler logger that writes to both stdout and the session log file
-func SetupLogger(sessionMgr *SessionManager, logLevel slog.Level) (*slog.Logger, *os.File, error) {
+func SetupLogger(sessionMgr *SessionManager, opts LogOptions) (*slog.Logger, *os.File, error) {
 	// Open log file with buffering
 	logFile, err := os.OpenFile(sessionMgr.GetLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Create handlers for both stdout (text) and file (JSON)
-	textHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
-	})
-
-	jsonHandler := slog.NewJSONHandler(logFile, &slog.HandlerOptions{
-		Level: logLevel,
-	})
-
 	// Use multi-handler to write to both
 	logger := slog.New(&multiHandler{
-		handlers: []slog.Handler{textHandler, jsonHandler},
+		handlers: []slog.Handler{
+			newLogHandler(os.Stdout, opts.ConsoleFormat, config.LogFormatText, opts.ConsoleLevel),
+			newLogHandler(logFile, opts.FileFormat, config.LogFormatJSON, opts.FileLevel),
+		},
 	})
 
 	return logger, logFile, nil
 }
+
+// newLogHandler returns a text or JSON handler for w, using defaultFormat when format is empty
+func newLogHandler(w io.Writer, format, defaultFormat string, level slog.Level) slog.Handler {
+	if format == "" {
+		format = defaultFormat
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if format == config.LogFormatJSON {
+		return slog.NewJSONHandler(w, handlerOpts)
+	}
+	return slog.NewTextHandler(w, handlerOpts)
+}
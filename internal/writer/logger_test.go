@@ -0,0 +1,61 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/internal/config"
+)
+
+func TestNewLogHandlerFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		defaultFormat string
+		wantJSON      bool
+	}{
+		{"default text", "", config.LogFormatText, false},
+		{"default json", "", config.LogFormatJSON, true},
+		{"json overrides default", config.LogFormatJSON, config.LogFormatText, true},
+		{"text overrides default", config.LogFormatText, config.LogFormatJSON, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(newLogHandler(&buf, tt.format, tt.defaultFormat, slog.LevelInfo)).Info("hello", "k", "v")
+			isJSON := json.Valid(bytes.TrimSpace(buf.Bytes()))
+			if isJSON != tt.wantJSON {
+				t.Errorf("Expected JSON %v, got %q", tt.wantJSON, buf.String())
+			}
+		})
+	}
+}
+
+func TestMultiHandlerIndependentLevels(t *testing.T) {
+	var console, file bytes.Buffer
+	logger := slog.New(&multiHandler{handlers: []slog.Handler{
+		newLogHandler(&console, config.LogFormatText, config.LogFormatText, slog.LevelWarn),
+		newLogHandler(&file, config.LogFormatJSON, config.LogFormatJSON, slog.LevelDebug),
+	}})
+
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("Expected debug enabled while any handler accepts it")
+	}
+	logger.Debug("details")
+	logger.Warn("careful")
+
+	if strings.Contains(console.String(), "details") {
+		t.Errorf("Expected console to drop debug records, got %q", console.String())
+	}
+	if !strings.Contains(console.String(), "careful") {
+		t.Errorf("Expected console to keep warn records, got %q", console.String())
+	}
+	if !strings.Contains(file.String(), "details") || !strings.Contains(file.String(), "careful") {
+		t.Errorf("Expected file to keep debug and warn records, got %q", file.String())
+	}
+}
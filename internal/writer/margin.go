@@ -0,0 +1,102 @@
+package writer
+
+import (
+	"log/slog"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// marginBuckets are the upper bounds used to log the preference margin distribution
+var marginBuckets = []struct {
+	label string
+	upper float64
+}{
+	{"<0", 0},
+	{"0-0.5", 0.5},
+	{"0.5-1", 1},
+	{"1-2", 2},
+	{">=2", 0}, // catch-all, upper bound unused
+}
+
+// marginFilter drops judged records whose preference margin is below minMargin and accumulates
+// the margin distribution across several batches, so spilled and final flushes are reported together
+// Records without judge scores (e.g. judge failures) are kept as-is
+// A minMargin of 0 disables filtering but still tracks the margin distribution
+type marginFilter struct {
+	minMargin            float64
+	counts               []int
+	kept, dropped        int
+	unscored, scored     int
+	sum, lowest, highest float64
+}
+
+func newMarginFilter(minMargin float64) *marginFilter {
+	return &marginFilter{minMargin: minMargin, counts: make([]int, len(marginBuckets))}
+}
+
+// apply returns the records of batch that pass the filter; batch is not modified
+func (f *marginFilter) apply(batch []models.DatasetRecord) []models.DatasetRecord {
+	kept := make([]models.DatasetRecord, 0, len(batch))
+	for _, record := range batch {
+		if record.ChosenScores == nil && record.RejectedScores == nil {
+			f.unscored++
+			f.kept++
+			kept = append(kept, record)
+			continue
+		}
+
+		margin := record.PreferenceMargin
+		if f.scored == 0 || margin < f.lowest {
+			f.lowest = margin
+		}
+		if f.scored == 0 || margin > f.highest {
+			f.highest = margin
+		}
+		f.sum += margin
+		f.scored++
+		f.counts[marginBucket(margin)]++
+
+		if f.minMargin > 0 && margin < f.minMargin {
+			f.dropped++
+			continue
+		}
+		f.kept++
+		kept = append(kept, record)
+	}
+	return kept
+}
+
+// log reports the margin distribution and filter outcome of every batch applied so far
+func (f *marginFilter) log(logger *slog.Logger) {
+	if f.scored > 0 {
+		distribution := make([]any, 0, len(marginBuckets)*2)
+		for i, bucket := range marginBuckets {
+			distribution = append(distribution, bucket.label, f.counts[i])
+		}
+		logger.Info("Preference margin distribution",
+			"scored", f.scored,
+			"min", f.lowest,
+			"max", f.highest,
+			"mean", f.sum/float64(f.scored),
+			slog.Group("buckets", distribution...))
+	}
+
+	if f.minMargin > 0 {
+		logger.Info("Applied minimum preference margin filter",
+			"min_preference_margin", f.minMargin,
+			"kept", f.kept,
+			"dropped", f.dropped,
+			"unscored", f.unscored)
+	}
+}
+
+// marginBucket returns the index in marginBuckets for a preference margin
+func marginBucket(margin float64) int {
+	last := len(marginBuckets) - 1
+	for i, bucket := range marginBuckets[:last] {
+		if margin < bucket.upper {
+			return i
+		}
+	}
+	return last
+}
@@ -0,0 +1,83 @@
+package writer
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func scoredRecord(prompt string, margin float64) models.DatasetRecord {
+	return models.DatasetRecord{
+		Prompt:           prompt,
+		ChosenScores:     map[string]models.CriteriaScore{"plot": {Score: 4}},
+		RejectedScores:   map[string]models.CriteriaScore{"plot": {Score: 3}},
+		PreferenceMargin: margin,
+	}
+}
+
+func TestMarginFilter(t *testing.T) {
+	records := []models.DatasetRecord{
+		scoredRecord("strong", 1.5),
+		scoredRecord("weak", 0.2),
+		scoredRecord("inverted", -0.5),
+		scoredRecord("boundary", 0.5),
+		{Prompt: "unscored"},
+	}
+
+	tests := []struct {
+		name      string
+		minMargin float64
+		expected  []string
+		wantLog   string
+	}{
+		{"disabled", 0, []string{"strong", "weak", "inverted", "boundary", "unscored"}, "scored=4"},
+		{"threshold keeps boundary", 0.5, []string{"strong", "boundary", "unscored"}, "kept=3 dropped=2 unscored=1"},
+		{"high threshold", 2.0, []string{"unscored"}, "kept=1 dropped=4 unscored=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			filter := newMarginFilter(tt.minMargin)
+			kept := filter.apply(records)
+			filter.log(slog.New(slog.NewTextHandler(&logs, nil)))
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("Expected log containing %q, got %s", tt.wantLog, logs.String())
+			}
+			if len(kept) != len(tt.expected) {
+				t.Fatalf("Expected %d records, got %d", len(tt.expected), len(kept))
+			}
+			for i, prompt := range tt.expected {
+				if kept[i].Prompt != prompt {
+					t.Errorf("Expected record %d to be %q, got %q", i, prompt, kept[i].Prompt)
+				}
+			}
+		})
+	}
+
+	if len(records) != 5 {
+		t.Errorf("Expected input records to be left untouched, got %d", len(records))
+	}
+}
+
+func TestMarginBucket(t *testing.T) {
+	tests := []struct {
+		margin   float64
+		expected string
+	}{
+		{-1.0, "<0"},
+		{0, "0-0.5"},
+		{0.7, "0.5-1"},
+		{1.0, "1-2"},
+		{3.5, ">=2"},
+	}
+
+	for _, tt := range tests {
+		if got := marginBuckets[marginBucket(tt.margin)].label; got != tt.expected {
+			t.Errorf("Expected margin %.2f in bucket %s, got %s", tt.margin, tt.expected, got)
+		}
+	}
+}
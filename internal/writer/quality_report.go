@@ -0,0 +1,269 @@
+package writer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// QualityReport summarizes chosen vs rejected responses in a written dataset
+// Lengths are counted in words; SFT datasets have no rejected side and get no report
+type QualityReport struct {
+	DatasetMode          models.DatasetMode `json:"dataset_mode"`
+	Rows                 int                `json:"rows"`
+	ChosenLength         Distribution       `json:"chosen_length"`          // KTO: label=true completions
+	RejectedLength       Distribution       `json:"rejected_length"`        // KTO: label=false completions
+	Pairs                int                `json:"pairs,omitempty"`        // Chosen/rejected comparisons (one per rejected response)
+	ChosenLongerFraction float64            `json:"chosen_longer_fraction"` // Fraction of pairs where chosen has more words
+	Scores               *ScoreReport       `json:"scores,omitempty"`       // MO-DPO only
+	SkippedRows          int                `json:"skipped_rows,omitempty"` // Rows without usable chosen/rejected fields
+}
+
+// ScoreReport summarizes judge scores of MO-DPO rows
+type ScoreReport struct {
+	Scored                  int           `json:"scored"`
+	Unscored                int           `json:"unscored"` // Judge failed or was skipped
+	ChosenTotal             Distribution  `json:"chosen_total"`
+	RejectedTotal           Distribution  `json:"rejected_total"`
+	Margin                  Distribution  `json:"margin"`
+	MarginBuckets           []BucketCount `json:"margin_buckets"`
+	ChosenOutscoredFraction float64       `json:"chosen_outscored_fraction"` // Fraction of scored rows where chosen total > rejected total
+}
+
+// BucketCount is the number of rows in one preference margin bucket
+type BucketCount struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// Distribution holds summary statistics for a set of values
+type Distribution struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// newDistribution computes summary statistics (zero values for an empty set)
+func newDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	return Distribution{
+		Count:  len(sorted),
+		Mean:   sum / float64(len(sorted)),
+		Median: median,
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// qualityRow is the union of the fields the report reads from any preference mode
+type qualityRow struct {
+	Chosen             json.RawMessage                 `json:"chosen"`
+	Rejected           json.RawMessage                 `json:"rejected"`
+	Completion         string                          `json:"completion"`
+	Label              *bool                           `json:"label"`
+	ChosenScores       map[string]models.CriteriaScore `json:"chosen_scores"`
+	RejectedScores     map[string]models.CriteriaScore `json:"rejected_scores"`
+	ChosenScoreTotal   float64                         `json:"chosen_score_total"`
+	RejectedScoreTotal float64                         `json:"rejected_score_total"`
+	PreferenceMargin   float64                         `json:"preference_margin"`
+}
+
+// responseTexts extracts responses from a chosen/rejected field: a plain string,
+// a list of strings (multi-rejected DPO), or a message list (conversational DPO)
+func responseTexts(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []string{text}, nil
+	}
+	var texts []string
+	if err := json.Unmarshal(raw, &texts); err == nil {
+		return texts, nil
+	}
+	var messages []models.OpenAIMessage
+	if err := json.Unmarshal(raw, &messages); err != nil {
+		return nil, fmt.Errorf("unsupported response field: %w", err)
+	}
+	// A conversational response is one assistant turn; join defensively if there are more
+	parts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		parts = append(parts, message.Content)
+	}
+	return []string{strings.Join(parts, "\n")}, nil
+}
+
+func wordCount(text string) float64 {
+	return float64(len(strings.Fields(text)))
+}
+
+// BuildQualityReport reads a written dataset and computes the chosen vs rejected report
+// Returns nil for SFT mode, which has nothing to compare
+func BuildQualityReport(datasetPath string, mode models.DatasetMode) (*QualityReport, error) {
+	if mode == models.DatasetModeSFT {
+		return nil, nil
+	}
+
+	file, err := os.Open(datasetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	report := &QualityReport{DatasetMode: mode}
+	var chosenLengths, rejectedLengths []float64
+	var chosenTotals, rejectedTotals, margins []float64
+	longer, outscored, unscored := 0, 0, 0
+	buckets := make([]int, len(marginBuckets))
+
+	decoder := json.NewDecoder(file)
+	for {
+		var row qualityRow
+		if err := decoder.Decode(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// The decoder cannot resynchronize after malformed JSON
+				return nil, fmt.Errorf("failed to parse dataset row %d: %w", report.Rows+1, err)
+			}
+			report.Rows++
+			report.SkippedRows++
+			continue
+		}
+		report.Rows++
+
+		if mode == models.DatasetModeKTO {
+			if row.Label == nil {
+				report.SkippedRows++
+				continue
+			}
+			if *row.Label {
+				chosenLengths = append(chosenLengths, wordCount(row.Completion))
+			} else {
+				rejectedLengths = append(rejectedLengths, wordCount(row.Completion))
+			}
+			continue
+		}
+
+		chosen, errChosen := responseTexts(row.Chosen)
+		rejected, errRejected := responseTexts(row.Rejected)
+		if errChosen != nil || errRejected != nil || len(chosen) != 1 {
+			report.SkippedRows++
+			continue
+		}
+		chosenWords := wordCount(chosen[0])
+		chosenLengths = append(chosenLengths, chosenWords)
+		for _, text := range rejected {
+			rejectedWords := wordCount(text)
+			rejectedLengths = append(rejectedLengths, rejectedWords)
+			report.Pairs++
+			if chosenWords > rejectedWords {
+				longer++
+			}
+		}
+
+		if mode == models.DatasetModeMODPO {
+			if row.ChosenScores == nil && row.RejectedScores == nil {
+				unscored++
+				continue
+			}
+			chosenTotals = append(chosenTotals, row.ChosenScoreTotal)
+			rejectedTotals = append(rejectedTotals, row.RejectedScoreTotal)
+			margins = append(margins, row.PreferenceMargin)
+			buckets[marginBucket(row.PreferenceMargin)]++
+			if row.ChosenScoreTotal > row.RejectedScoreTotal {
+				outscored++
+			}
+		}
+	}
+
+	report.ChosenLength = newDistribution(chosenLengths)
+	report.RejectedLength = newDistribution(rejectedLengths)
+	if report.Pairs > 0 {
+		report.ChosenLongerFraction = float64(longer) / float64(report.Pairs)
+	}
+
+	if mode == models.DatasetModeMODPO {
+		scores := &ScoreReport{
+			Scored:        len(margins),
+			Unscored:      unscored,
+			ChosenTotal:   newDistribution(chosenTotals),
+			RejectedTotal: newDistribution(rejectedTotals),
+			Margin:        newDistribution(margins),
+			MarginBuckets: make([]BucketCount, len(marginBuckets)),
+		}
+		for i, bucket := range marginBuckets {
+			scores.MarginBuckets[i] = BucketCount{Label: bucket.label, Count: buckets[i]}
+		}
+		if scores.Scored > 0 {
+			scores.ChosenOutscoredFraction = float64(outscored) / float64(scores.Scored)
+		}
+		report.Scores = scores
+	}
+
+	return report, nil
+}
+
+// WriteQualityReport builds the quality report for the session dataset, writes it to
+// quality_report.json in the session directory and logs a summary
+// Must be called after the dataset writer is closed so buffered MO-DPO rows are on disk
+func WriteQualityReport(sessionMgr *SessionManager, mode models.DatasetMode, logger *slog.Logger) (*QualityReport, error) {
+	report, err := BuildQualityReport(sessionMgr.GetDatasetPath(), mode)
+	if err != nil || report == nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal quality report: %w", err)
+	}
+	path := sessionMgr.GetQualityReportPath()
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write quality report: %w", err)
+	}
+
+	logger.Info("Dataset quality report",
+		"path", path,
+		"rows", report.Rows,
+		"chosen_mean_words", report.ChosenLength.Mean,
+		"rejected_mean_words", report.RejectedLength.Mean,
+		"chosen_longer_fraction", report.ChosenLongerFraction)
+	if report.Scores != nil && report.Scores.Scored > 0 {
+		logger.Info("Dataset score report",
+			"scored", report.Scores.Scored,
+			"unscored", report.Scores.Unscored,
+			"chosen_mean_total", report.Scores.ChosenTotal.Mean,
+			"rejected_mean_total", report.Scores.RejectedTotal.Mean,
+			"mean_margin", report.Scores.Margin.Mean,
+			"chosen_outscored_fraction", report.Scores.ChosenOutscoredFraction)
+	}
+	if report.SkippedRows > 0 {
+		logger.Warn("Quality report skipped unparseable rows", "skipped", report.SkippedRows)
+	}
+	return report, nil
+}
@@ -0,0 +1,154 @@
+package writer
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func writeJSONL(t *testing.T, path string, rows ...any) {
+	t.Helper()
+	var data []byte
+	for _, row := range rows {
+		line, err := json.Marshal(row)
+		if err != nil {
+			t.Fatalf("Failed to marshal row: %v", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write dataset: %v", err)
+	}
+}
+
+func TestBuildQualityReport_MODPO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.jsonl")
+	scores := map[string]models.CriteriaScore{"plot": {Score: 4}}
+	writeJSONL(t, path,
+		models.DatasetRecord{Prompt: "a", Chosen: "one two three four", Rejected: "one two",
+			ChosenScores: scores, RejectedScores: scores, ChosenScoreTotal: 4, RejectedScoreTotal: 3, PreferenceMargin: 1},
+		models.DatasetRecord{Prompt: "b", Chosen: "one two", Rejected: "one two three four five six",
+			ChosenScores: scores, RejectedScores: scores, ChosenScoreTotal: 3, RejectedScoreTotal: 3.5, PreferenceMargin: -0.5},
+		models.DatasetRecord{Prompt: "c", Chosen: "one two three", Rejected: "one", JudgeFailed: true},
+	)
+
+	report, err := BuildQualityReport(path, models.DatasetModeMODPO)
+	if err != nil {
+		t.Fatalf("BuildQualityReport returned unexpected error: %v", err)
+	}
+	if report.Rows != 3 || report.Pairs != 3 {
+		t.Errorf("Expected 3 rows and 3 pairs, got %d rows and %d pairs", report.Rows, report.Pairs)
+	}
+	if want := (Distribution{Count: 3, Mean: 3, Median: 3, Min: 2, Max: 4}); report.ChosenLength != want {
+		t.Errorf("Expected chosen length %+v, got %+v", want, report.ChosenLength)
+	}
+	if report.RejectedLength.Median != 2 || report.RejectedLength.Max != 6 {
+		t.Errorf("Expected rejected median 2 and max 6, got %+v", report.RejectedLength)
+	}
+	if got := report.ChosenLongerFraction; got < 0.66 || got > 0.67 {
+		t.Errorf("Expected chosen longer in 2/3 pairs, got %f", got)
+	}
+
+	if report.Scores == nil {
+		t.Fatalf("Expected a score report for MO-DPO")
+	}
+	if report.Scores.Scored != 2 || report.Scores.Unscored != 1 {
+		t.Errorf("Expected 2 scored and 1 unscored, got %d and %d", report.Scores.Scored, report.Scores.Unscored)
+	}
+	if report.Scores.ChosenOutscoredFraction != 0.5 {
+		t.Errorf("Expected chosen outscored fraction 0.5, got %f", report.Scores.ChosenOutscoredFraction)
+	}
+	if report.Scores.Margin.Mean != 0.25 {
+		t.Errorf("Expected mean margin 0.25, got %f", report.Scores.Margin.Mean)
+	}
+	counts := map[string]int{}
+	for _, bucket := range report.Scores.MarginBuckets {
+		counts[bucket.Label] = bucket.Count
+	}
+	if counts["<0"] != 1 || counts["1-2"] != 1 {
+		t.Errorf("Expected one margin in <0 and one in 1-2, got %v", report.Scores.MarginBuckets)
+	}
+}
+
+func TestBuildQualityReport_DPOFormats(t *testing.T) {
+	dir := t.TempDir()
+
+	conversational := filepath.Join(dir, "conversational.jsonl")
+	writeJSONL(t, conversational, models.ConversationalDPORecord{
+		Prompt:   []models.OpenAIMessage{{Role: "user", Content: "hi"}},
+		Chosen:   []models.OpenAIMessage{{Role: "assistant", Content: "a longer reply"}},
+		Rejected: []models.OpenAIMessage{{Role: "assistant", Content: "short"}},
+	})
+	report, err := BuildQualityReport(conversational, models.DatasetModeDPO)
+	if err != nil {
+		t.Fatalf("BuildQualityReport returned unexpected error: %v", err)
+	}
+	if report.ChosenLength.Mean != 3 || report.RejectedLength.Mean != 1 || report.ChosenLongerFraction != 1 {
+		t.Errorf("Unexpected conversational report: %+v", report)
+	}
+	if report.Scores != nil {
+		t.Errorf("Expected no score report for DPO")
+	}
+
+	multi := filepath.Join(dir, "multi.jsonl")
+	writeJSONL(t, multi, models.MultiRejectedDPORecord{Prompt: "p", Chosen: "one two", Rejected: []string{"one", "one two three"}})
+	report, err = BuildQualityReport(multi, models.DatasetModeDPO)
+	if err != nil {
+		t.Fatalf("BuildQualityReport returned unexpected error: %v", err)
+	}
+	if report.Pairs != 2 || report.ChosenLongerFraction != 0.5 {
+		t.Errorf("Expected 2 pairs with chosen longer in half, got %d pairs and %f", report.Pairs, report.ChosenLongerFraction)
+	}
+}
+
+func TestBuildQualityReport_KTO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.jsonl")
+	writeJSONL(t, path,
+		models.KTORecord{Prompt: "p", Completion: "one two three", Label: true},
+		models.KTORecord{Prompt: "p", Completion: "one", Label: false},
+		map[string]string{"prompt": "p"}, // No label
+	)
+
+	report, err := BuildQualityReport(path, models.DatasetModeKTO)
+	if err != nil {
+		t.Fatalf("BuildQualityReport returned unexpected error: %v", err)
+	}
+	if report.ChosenLength.Mean != 3 || report.RejectedLength.Mean != 1 {
+		t.Errorf("Expected desirable 3 words and undesirable 1, got %+v / %+v", report.ChosenLength, report.RejectedLength)
+	}
+	if report.SkippedRows != 1 {
+		t.Errorf("Expected 1 skipped row, got %d", report.SkippedRows)
+	}
+}
+
+func TestBuildQualityReport_SFT(t *testing.T) {
+	report, err := BuildQualityReport(filepath.Join(t.TempDir(), "missing.jsonl"), models.DatasetModeSFT)
+	if err != nil || report != nil {
+		t.Errorf("Expected no report for SFT, got %+v, %v", report, err)
+	}
+}
+
+func TestWriteQualityReport(t *testing.T) {
+	sessionMgr := &SessionManager{sessionDir: t.TempDir(), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	writeJSONL(t, sessionMgr.GetDatasetPath(), models.DPORecord{Prompt: "p", Chosen: "one two", Rejected: "one"})
+
+	if _, err := WriteQualityReport(sessionMgr, models.DatasetModeDPO, sessionMgr.logger); err != nil {
+		t.Fatalf("WriteQualityReport returned unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(sessionMgr.GetQualityReportPath())
+	if err != nil {
+		t.Fatalf("Expected quality report file: %v", err)
+	}
+	var report QualityReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse quality report: %v", err)
+	}
+	if report.Rows != 1 || report.DatasetMode != models.DatasetModeDPO {
+		t.Errorf("Unexpected report contents: %+v", report)
+	}
+}
@@ -0,0 +1,168 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// ErrRecordFlushed is returned when a judge update targets a record already written to disk
+var ErrRecordFlushed = errors.New("record already flushed to disk")
+
+// bufferedRecord is an MO-DPO record waiting in memory for its judge result
+type bufferedRecord struct {
+	record models.DatasetRecord
+	judged bool  // Judge scores arrived or the judge gave up; safe to write
+	size   int64 // Encoded size of record, counted in pendingBytes
+}
+
+// recordBuffer holds MO-DPO records until the async judge finishes with them
+// Record indexes are global: records[i] holds index base+i, nil once the record was spilled
+// With a limit set, judged records are spilled to disk in index order whenever more than
+// limit records are buffered, so only records still waiting on the judge stay in memory
+type recordBuffer struct {
+	records []*bufferedRecord
+	base    int // Index of records[0]
+	live    int // Non-nil entries in records
+	judged  int // Live entries whose judge result is in
+	limit   int // Max buffered records before spilling (0 = unbounded)
+	spilled int // Records written early by spills
+	filter  *marginFilter
+
+	// pendingBytes is the encoded size of the live records, so max_output_bytes sees rows that
+	// have not reached disk yet (an estimate: the margin filter may still drop some at flush)
+	pendingBytes int64
+}
+
+func newRecordBuffer(capacity int) *recordBuffer {
+	return &recordBuffer{
+		records: make([]*bufferedRecord, 0, capacity),
+		filter:  newMarginFilter(0),
+	}
+}
+
+// add buffers record and returns its index; records already flagged judge_failed count as judged
+func (b *recordBuffer) add(record models.DatasetRecord) int {
+	index := b.base + len(b.records)
+	entry := &bufferedRecord{record: record, judged: record.JudgeFailed}
+	b.measure(entry)
+	b.records = append(b.records, entry)
+	b.live++
+	if record.JudgeFailed {
+		b.judged++
+	}
+	return index
+}
+
+// get returns the buffered record at index, or an error if it is out of range or already spilled
+func (b *recordBuffer) get(index int) (*bufferedRecord, error) {
+	total := b.base + len(b.records)
+	if index < 0 || index >= total {
+		return nil, fmt.Errorf("invalid record index: %d (total: %d)", index, total)
+	}
+	if index < b.base || b.records[index-b.base] == nil {
+		return nil, fmt.Errorf("record index %d: %w", index, ErrRecordFlushed)
+	}
+	return b.records[index-b.base], nil
+}
+
+// update applies judge results to the record at index and marks it judged
+func (b *recordBuffer) update(index int, judgeResult *models.JudgeResult) error {
+	entry, err := b.get(index)
+	if err != nil {
+		return err
+	}
+
+	entry.record.ChosenScores = judgeResult.ChosenScores
+	entry.record.RejectedScores = judgeResult.RejectedScores
+	entry.record.ChosenScoreTotal = judgeResult.ChosenScoreTotal
+	entry.record.RejectedScoreTotal = judgeResult.RejectedScoreTotal
+	entry.record.PreferenceMargin = judgeResult.PreferenceMargin
+	b.measure(entry)
+	b.markJudged(entry)
+	return nil
+}
+
+// markJudgeFailed flags the record at index judge_failed and marks it judged
+func (b *recordBuffer) markJudgeFailed(index int) error {
+	entry, err := b.get(index)
+	if err != nil {
+		return err
+	}
+
+	entry.record.JudgeFailed = true
+	b.measure(entry)
+	b.markJudged(entry)
+	return nil
+}
+
+func (b *recordBuffer) markJudged(entry *bufferedRecord) {
+	if !entry.judged {
+		entry.judged = true
+		b.judged++
+	}
+}
+
+// measure updates pendingBytes for entry's current encoded size
+// A record that fails to encode counts as empty here; drain reports the error when writing it
+func (b *recordBuffer) measure(entry *bufferedRecord) {
+	var size int64
+	if data, err := encodeLine(entry.record); err == nil {
+		size = int64(len(data))
+	}
+	b.pendingBytes += size - entry.size
+	entry.size = size
+}
+
+// overLimit reports whether a spill is due: too many records buffered and at least one can be written
+func (b *recordBuffer) overLimit() bool {
+	return b.limit > 0 && b.live > b.limit && b.judged > 0
+}
+
+// spill writes judged records to w in index order and evicts them, returning how many were evicted
+func (b *recordBuffer) spill(w io.Writer) (int, error) {
+	count, err := b.drain(w, false)
+	b.spilled += count
+	return count, err
+}
+
+// flush writes every buffered record to w, judged or not, and empties the buffer
+func (b *recordBuffer) flush(w io.Writer) (int, error) {
+	return b.drain(w, true)
+}
+
+func (b *recordBuffer) drain(w io.Writer, all bool) (int, error) {
+	var batch []models.DatasetRecord
+	for i, entry := range b.records {
+		if entry == nil || (!all && !entry.judged) {
+			continue
+		}
+		batch = append(batch, entry.record)
+		b.records[i] = nil
+		b.live--
+		b.pendingBytes -= entry.size
+		if entry.judged {
+			b.judged--
+		}
+	}
+
+	// Drop the evicted prefix so indexes below base resolve to ErrRecordFlushed
+	for len(b.records) > 0 && b.records[0] == nil {
+		b.records = b.records[1:]
+		b.base++
+	}
+
+	for i, record := range b.filter.apply(batch) {
+		data, err := encodeLine(record)
+		if err != nil {
+			return len(batch), fmt.Errorf("failed to marshal record %d: %w", i, err)
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return len(batch), fmt.Errorf("failed to write record %d: %w", i, err)
+		}
+	}
+	return len(batch), nil
+}
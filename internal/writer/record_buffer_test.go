@@ -0,0 +1,126 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func readDatasetPrompts(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open dataset: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var prompts []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record models.DatasetRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to parse dataset line: %v", err)
+		}
+		prompts = append(prompts, record.Prompt)
+	}
+	return prompts
+}
+
+func modpoRecord(prompt string) models.DatasetRecord {
+	return models.DatasetRecord{Prompt: prompt, Chosen: "good " + prompt, Rejected: "bad " + prompt}
+}
+
+func TestRecordBuffer_SpillsOnlyJudgedRecords(t *testing.T) {
+	sessionMgr := &SessionManager{sessionDir: t.TempDir()}
+	dw, err := NewDatasetWriter(sessionMgr, slog.New(slog.NewTextHandler(io.Discard, nil)), false, 0)
+	if err != nil {
+		t.Fatalf("NewDatasetWriter returned unexpected error: %v", err)
+	}
+	dw.SetMaxBufferedRecords(2)
+
+	for _, prompt := range []string{"p0", "p1"} {
+		if _, err := dw.WriteRecord(modpoRecord(prompt)); err != nil {
+			t.Fatalf("WriteRecord returned unexpected error: %v", err)
+		}
+	}
+	judged := &models.JudgeResult{
+		ChosenScores:     map[string]models.CriteriaScore{"plot": {Score: 5}},
+		RejectedScores:   map[string]models.CriteriaScore{"plot": {Score: 2}},
+		PreferenceMargin: 3,
+	}
+	if err := dw.UpdateRecord(1, judged); err != nil {
+		t.Fatalf("UpdateRecord returned unexpected error: %v", err)
+	}
+
+	// Third record exceeds the limit: only the judged p1 is spilled, pending p0 and p2 stay buffered
+	if _, err := dw.WriteRecord(modpoRecord("p2")); err != nil {
+		t.Fatalf("WriteRecord returned unexpected error: %v", err)
+	}
+	if dw.buffer.live != 2 || dw.buffer.spilled != 1 {
+		t.Errorf("Expected 2 buffered and 1 spilled, got %d and %d", dw.buffer.live, dw.buffer.spilled)
+	}
+	if got := readDatasetPrompts(t, sessionMgr.GetDatasetPath()); len(got) != 1 || got[0] != "p1" {
+		t.Errorf("Expected only p1 on disk after spill, got %v", got)
+	}
+
+	if err := dw.UpdateRecord(1, judged); !errors.Is(err, ErrRecordFlushed) {
+		t.Errorf("Expected ErrRecordFlushed for spilled record, got %v", err)
+	}
+	if err := dw.MarkJudgeFailed(0); err != nil {
+		t.Errorf("Expected pending record to accept updates after spill, got %v", err)
+	}
+	if err := dw.UpdateRecord(3, judged); err == nil || errors.Is(err, ErrRecordFlushed) {
+		t.Errorf("Expected invalid index error, got %v", err)
+	}
+
+	if err := dw.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+	got := readDatasetPrompts(t, sessionMgr.GetDatasetPath())
+	want := []string{"p1", "p0", "p2"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v on disk, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected record %d to be %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestRecordBuffer_Unbounded(t *testing.T) {
+	buffer := newRecordBuffer(0)
+	for i := 0; i < 5; i++ {
+		buffer.add(models.DatasetRecord{Prompt: "p", JudgeFailed: true})
+	}
+	if buffer.overLimit() {
+		t.Error("Expected an unbounded buffer never to spill")
+	}
+
+	buffer.limit = 4
+	if !buffer.overLimit() {
+		t.Error("Expected judged records over the limit to trigger a spill")
+	}
+}
+
+func TestRecordBuffer_PendingOverLimitDoesNotSpill(t *testing.T) {
+	buffer := newRecordBuffer(0)
+	buffer.limit = 1
+	buffer.add(modpoRecord("p0"))
+	buffer.add(modpoRecord("p1"))
+	if buffer.overLimit() {
+		t.Error("Expected no spill while every buffered record waits on the judge")
+	}
+	if err := buffer.markJudgeFailed(0); err != nil {
+		t.Fatalf("markJudgeFailed returned unexpected error: %v", err)
+	}
+	if !buffer.overLimit() {
+		t.Error("Expected a spill once a record is judged")
+	}
+}
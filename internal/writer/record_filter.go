@@ -0,0 +1,199 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lamim/vellumforge2/internal/util"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// ErrRecordSkipped is returned by FilteredWriter when the record filter command asked
+// for a record to be dropped, so callers can count it as filtered rather than failed
+var ErrRecordSkipped = errors.New("record skipped by record_filter_command")
+
+// DefaultRecordFilterTimeout bounds a single run of the record filter command
+const DefaultRecordFilterTimeout = 30 * time.Second
+
+// filterWaitDelay is how long a killed filter's stdout is drained before Wait gives up,
+// so a grandchild holding the pipe open can't hang the writer
+const filterWaitDelay = 2 * time.Second
+
+// RecordFilter runs an external command per record (generation.record_filter_command)
+// The record JSON is written to the command's stdin; its stdout is the transformed record,
+// or nothing to skip the record. Concurrent runs are bounded by a fixed pool of slots
+type RecordFilter struct {
+	command string
+	timeout time.Duration
+	slots   chan struct{}
+}
+
+// NewRecordFilter creates a filter running command through the shell with the given
+// per-record timeout (0 uses DefaultRecordFilterTimeout)
+func NewRecordFilter(command string, timeout time.Duration) *RecordFilter {
+	if timeout <= 0 {
+		timeout = DefaultRecordFilterTimeout
+	}
+	return &RecordFilter{
+		command: command,
+		timeout: timeout,
+		slots:   make(chan struct{}, runtime.NumCPU()),
+	}
+}
+
+// Run passes input to the command and returns its trimmed stdout (empty means skip)
+func (f *RecordFilter) Run(input []byte) ([]byte, error) {
+	f.slots <- struct{}{}
+	defer func() { <-f.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, f.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.WaitDelay = filterWaitDelay
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("record filter command timed out after %v", f.timeout)
+		}
+		return nil, fmt.Errorf("record filter command failed: %w (stderr: %s)", err, util.TruncateString(strings.TrimSpace(stderr.String()), 200))
+	}
+	return bytes.TrimSpace(stdout.Bytes()), nil
+}
+
+// shellCommand runs command through the platform shell so pipes and quoting work
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// applyRecordFilter marshals record, runs it through the filter and decodes the output back
+// into the same record type. Returns ErrRecordSkipped when the command printed nothing
+func applyRecordFilter[T any](f *RecordFilter, record T) (T, error) {
+	var filtered T
+	input, err := json.Marshal(record)
+	if err != nil {
+		return filtered, fmt.Errorf("failed to marshal record for filter: %w", err)
+	}
+
+	output, err := f.Run(input)
+	if err != nil {
+		return filtered, err
+	}
+	if len(output) == 0 {
+		return filtered, ErrRecordSkipped
+	}
+	if err := json.Unmarshal(output, &filtered); err != nil {
+		return filtered, fmt.Errorf("record filter command returned invalid JSON: %w (output: %s)", err, util.TruncateString(string(output), 200))
+	}
+	return filtered, nil
+}
+
+// FilteredWriter passes every record through a RecordFilter before handing it to the
+// wrapped Writer, which still validates the result and assigns IDs
+// Reasoning arguments are passed through unfiltered
+type FilteredWriter struct {
+	Writer
+	filter   *RecordFilter
+	logger   *slog.Logger
+	passed   atomic.Int64
+	skipped  atomic.Int64
+	failures atomic.Int64
+}
+
+// NewFilteredWriter wraps inner so records are transformed by filter before being written
+func NewFilteredWriter(inner Writer, filter *RecordFilter, logger *slog.Logger) *FilteredWriter {
+	return &FilteredWriter{Writer: inner, filter: filter, logger: logger}
+}
+
+// track counts a filter outcome and passes err on
+func (fw *FilteredWriter) track(err error) error {
+	switch {
+	case err == nil:
+		fw.passed.Add(1)
+	case errors.Is(err, ErrRecordSkipped):
+		fw.skipped.Add(1)
+	default:
+		fw.failures.Add(1)
+	}
+	return err
+}
+
+// WriteSFTRecord filters an SFT record before writing it
+func (fw *FilteredWriter) WriteSFTRecord(record models.SFTRecord, reasoning string) error {
+	record, err := applyRecordFilter(fw.filter, record)
+	if err := fw.track(err); err != nil {
+		return err
+	}
+	return fw.Writer.WriteSFTRecord(record, reasoning)
+}
+
+// WriteDPORecord filters a DPO record before writing it
+func (fw *FilteredWriter) WriteDPORecord(record models.DPORecord, chosenReasoning, rejectedReasoning string) error {
+	record, err := applyRecordFilter(fw.filter, record)
+	if err := fw.track(err); err != nil {
+		return err
+	}
+	return fw.Writer.WriteDPORecord(record, chosenReasoning, rejectedReasoning)
+}
+
+// WriteMultiRejectedDPORecord filters a multi-rejected DPO record before writing it
+func (fw *FilteredWriter) WriteMultiRejectedDPORecord(record models.MultiRejectedDPORecord, chosenReasoning string, rejectedReasoning []string) error {
+	record, err := applyRecordFilter(fw.filter, record)
+	if err := fw.track(err); err != nil {
+		return err
+	}
+	return fw.Writer.WriteMultiRejectedDPORecord(record, chosenReasoning, rejectedReasoning)
+}
+
+// WriteConversationalDPORecord filters a conversational DPO record before writing it
+func (fw *FilteredWriter) WriteConversationalDPORecord(record models.ConversationalDPORecord, chosenReasoning, rejectedReasoning string) error {
+	record, err := applyRecordFilter(fw.filter, record)
+	if err := fw.track(err); err != nil {
+		return err
+	}
+	return fw.Writer.WriteConversationalDPORecord(record, chosenReasoning, rejectedReasoning)
+}
+
+// WriteKTORecord filters a KTO record before writing it
+func (fw *FilteredWriter) WriteKTORecord(record models.KTORecord, reasoning string) error {
+	record, err := applyRecordFilter(fw.filter, record)
+	if err := fw.track(err); err != nil {
+		return err
+	}
+	return fw.Writer.WriteKTORecord(record, reasoning)
+}
+
+// WriteRecord filters an MO-DPO record before buffering it; the filter sees the record
+// before judge results are added
+func (fw *FilteredWriter) WriteRecord(record models.DatasetRecord) (int, error) {
+	record, err := applyRecordFilter(fw.filter, record)
+	if err := fw.track(err); err != nil {
+		return -1, err
+	}
+	return fw.Writer.WriteRecord(record)
+}
+
+// Close logs the filter summary and closes the wrapped writer
+func (fw *FilteredWriter) Close() error {
+	fw.logger.Info("Record filter summary",
+		"passed", fw.passed.Load(),
+		"skipped", fw.skipped.Load(),
+		"failed", fw.failures.Load())
+	return fw.Writer.Close()
+}
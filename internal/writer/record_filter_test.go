@@ -0,0 +1,96 @@
+package writer
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestApplyRecordFilter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("filter commands use sh")
+	}
+
+	record := models.DPORecord{Prompt: "p", Chosen: "a dragon", Rejected: "r"}
+	tests := []struct {
+		name    string
+		command string
+		timeout time.Duration
+		want    string
+		wantErr string
+		skipped bool
+	}{
+		{name: "transform", command: "sed 's/dragon/wyrm/'", want: "a wyrm"},
+		{name: "passthrough", command: "cat", want: "a dragon"},
+		{name: "empty output skips", command: "cat > /dev/null", skipped: true},
+		{name: "non-zero exit", command: "echo broken >&2; exit 3", wantErr: "broken"},
+		{name: "invalid JSON", command: "echo not-json", wantErr: "invalid JSON"},
+		{name: "timeout", command: "exec sleep 5", timeout: 100 * time.Millisecond, wantErr: "timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyRecordFilter(NewRecordFilter(tt.command, tt.timeout), record)
+			switch {
+			case tt.skipped:
+				if !errors.Is(err, ErrRecordSkipped) {
+					t.Errorf("Expected ErrRecordSkipped, got %v", err)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+			case err != nil:
+				t.Fatalf("Unexpected error: %v", err)
+			case got.Chosen != tt.want || got.Prompt != "p":
+				t.Errorf("Expected chosen %q with prompt kept, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilteredWriter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("filter commands use sh")
+	}
+
+	sessionMgr := &SessionManager{sessionDir: t.TempDir()}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	inner, err := NewDatasetWriter(sessionMgr, logger, false, 0)
+	if err != nil {
+		t.Fatalf("NewDatasetWriter returned unexpected error: %v", err)
+	}
+	inner.SetIncludeIDs(true)
+
+	// Drop records whose prompt mentions "secret", rewrite the rest
+	filter := NewRecordFilter(`grep -v secret | sed 's/"keep"/"KEEP"/'`, time.Second)
+	fw := NewFilteredWriter(inner, filter, logger)
+
+	if err := fw.WriteKTORecord(models.KTORecord{Prompt: "keep", Completion: "c", Label: true}, ""); err != nil {
+		t.Fatalf("WriteKTORecord returned unexpected error: %v", err)
+	}
+	if err := fw.WriteKTORecord(models.KTORecord{Prompt: "secret", Completion: "c", Label: true}, ""); !errors.Is(err, ErrRecordSkipped) {
+		t.Errorf("Expected ErrRecordSkipped, got %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+
+	ids := readRecordIDs(t, sessionMgr.GetDatasetPath())
+	if len(ids) != 1 {
+		t.Fatalf("Expected one written record, got %d", len(ids))
+	}
+	// The ID is assigned after filtering, so it matches the written content
+	if want := recordID(models.KTORecord{Prompt: "KEEP", Completion: "c", Label: true}); ids[0] != want {
+		t.Errorf("Expected ID of the filtered record %s, got %s", want, ids[0])
+	}
+	if fw.passed.Load() != 1 || fw.skipped.Load() != 1 {
+		t.Errorf("Expected 1 passed and 1 skipped, got %d and %d", fw.passed.Load(), fw.skipped.Load())
+	}
+}
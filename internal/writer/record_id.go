@@ -0,0 +1,55 @@
+package writer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// recordID derives a deterministic ID from a record's content (generation.include_id)
+// Identical content gets identical IDs, so duplicates across runs and merged datasets can be found by ID
+// Only the training content is hashed: system, prompt, chosen, rejected or completion and label
+// (the message columns for SFT). Topics, model names, judge scores and _meta are left out, so
+// toggling record_model_assignment, include_metadata or main_topics doesn't change IDs;
+// line endings are normalized first, so the ID matches the content as written
+func recordID(record any) string {
+	data, err := json.Marshal(contentOf(record))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(normalizeNewlines(data))
+	return hex.EncodeToString(sum[:16])
+}
+
+// recordContent holds the fields of a record that recordID hashes
+type recordContent struct {
+	System     string `json:"system,omitempty"`
+	Prompt     any    `json:"prompt"`
+	Chosen     any    `json:"chosen,omitempty"`
+	Rejected   any    `json:"rejected,omitempty"`
+	Completion string `json:"completion,omitempty"`
+	Label      *bool  `json:"label,omitempty"`
+}
+
+// contentOf returns the content fields of record
+func contentOf(record any) any {
+	switch r := record.(type) {
+	case models.SFTRecord:
+		// Every remaining SFT column (instruction, output, history, conversations, messages) is content
+		r.ID, r.MainTopic, r.SubTopic, r.Meta = "", "", "", nil
+		return r
+	case models.DPORecord:
+		return recordContent{System: r.System, Prompt: r.Prompt, Chosen: r.Chosen, Rejected: r.Rejected}
+	case models.ConversationalDPORecord:
+		return recordContent{Prompt: r.Prompt, Chosen: r.Chosen, Rejected: r.Rejected}
+	case models.MultiRejectedDPORecord:
+		return recordContent{System: r.System, Prompt: r.Prompt, Chosen: r.Chosen, Rejected: r.Rejected}
+	case models.KTORecord:
+		return recordContent{System: r.System, Prompt: r.Prompt, Completion: r.Completion, Label: &r.Label}
+	case models.DatasetRecord:
+		return recordContent{Prompt: r.Prompt, Chosen: r.Chosen, Rejected: r.Rejected}
+	}
+	return record
+}
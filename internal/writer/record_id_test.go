@@ -0,0 +1,151 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func readRecordIDs(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open dataset: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var row struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("Failed to parse dataset line: %v", err)
+		}
+		ids = append(ids, row.ID)
+	}
+	return ids
+}
+
+func TestRecordID_Deterministic(t *testing.T) {
+	a := models.DPORecord{Prompt: "p", Chosen: "c", Rejected: "r1"}
+	b := models.DPORecord{Prompt: "p", Chosen: "c", Rejected: "r2"}
+
+	if recordID(a) != recordID(a) {
+		t.Error("Expected the same record to get the same ID")
+	}
+	if recordID(a) == recordID(b) {
+		t.Error("Expected rows differing only in rejected to get different IDs")
+	}
+	if len(recordID(a)) != 32 {
+		t.Errorf("Expected a 32 character ID, got %q", recordID(a))
+	}
+}
+
+func TestRecordID_IgnoresMeta(t *testing.T) {
+	plain := models.DPORecord{Prompt: "p", Chosen: "c", Rejected: "r"}
+	withMeta := plain
+	withMeta.Meta = &models.RecordMeta{Attempts: models.RecordAttempts{Chosen: &models.AttemptCounts{Network: 2}}}
+
+	if recordID(plain) != recordID(withMeta) {
+		t.Error("Expected _meta to leave the record ID unchanged")
+	}
+	if withMeta.Meta == nil {
+		t.Error("Expected recordID to leave the caller's _meta in place")
+	}
+}
+
+func TestRecordID_ContentFieldsOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      any
+		variant   any
+		wantEqual bool
+	}{
+		{"dpo topic and models",
+			models.DPORecord{Prompt: "p", Chosen: "c", Rejected: "r"},
+			models.DPORecord{ID: "old", MainTopic: "Fantasy", Prompt: "p", Chosen: "c", Rejected: "r", ChosenModel: "a", RejectedModel: "b"},
+			true},
+		{"dpo system", models.DPORecord{Prompt: "p", Chosen: "c", Rejected: "r"}, models.DPORecord{System: "s", Prompt: "p", Chosen: "c", Rejected: "r"}, false},
+		{"kto label", models.KTORecord{Prompt: "p", Completion: "c", Label: true}, models.KTORecord{Prompt: "p", Completion: "c"}, false},
+		{"kto model", models.KTORecord{Prompt: "p", Completion: "c", Label: true}, models.KTORecord{Prompt: "p", Completion: "c", Label: true, Model: "m"}, true},
+		{"sft topics",
+			models.SFTRecord{Instruction: "i", Output: "o"},
+			models.SFTRecord{MainTopic: "Fantasy", SubTopic: "Elves", Instruction: "i", Output: "o"},
+			true},
+		{"mo-dpo judge fields",
+			models.DatasetRecord{Prompt: "p", Chosen: "c", Rejected: "r"},
+			models.DatasetRecord{MainTopic: "Fantasy", SubTopic: "Elves", Prompt: "p", Chosen: "c", Rejected: "r", PreferenceMargin: 2, JudgeFailed: true},
+			true},
+		{"multi rejected list", models.MultiRejectedDPORecord{Prompt: "p", Chosen: "c", Rejected: []string{"a"}}, models.MultiRejectedDPORecord{Prompt: "p", Chosen: "c", Rejected: []string{"a", "b"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordID(tt.base) == recordID(tt.variant); got != tt.wantEqual {
+				t.Errorf("Expected equal IDs %v, got %v", tt.wantEqual, got)
+			}
+		})
+	}
+}
+
+func TestDatasetWriter_IncludeIDsSurvivesJudgeUpdate(t *testing.T) {
+	sessionMgr := &SessionManager{sessionDir: t.TempDir()}
+	dw, err := NewDatasetWriter(sessionMgr, slog.New(slog.NewTextHandler(io.Discard, nil)), false, 0)
+	if err != nil {
+		t.Fatalf("NewDatasetWriter returned unexpected error: %v", err)
+	}
+	dw.SetIncludeIDs(true)
+
+	record := modpoRecord("p0")
+	index, err := dw.WriteRecord(record)
+	if err != nil {
+		t.Fatalf("WriteRecord returned unexpected error: %v", err)
+	}
+	if err := dw.UpdateRecord(index, &models.JudgeResult{PreferenceMargin: 2}); err != nil {
+		t.Fatalf("UpdateRecord returned unexpected error: %v", err)
+	}
+	if err := dw.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+
+	ids := readRecordIDs(t, sessionMgr.GetDatasetPath())
+	if len(ids) != 1 || ids[0] != recordID(record) {
+		t.Errorf("Expected the ID assigned at WriteRecord time (%s), got %v", recordID(record), ids)
+	}
+}
+
+func TestDualDatasetWriter_IncludeIDs(t *testing.T) {
+	sessionMgr := &SessionManager{sessionDir: t.TempDir()}
+	dw, err := NewDualDatasetWriter(sessionMgr, slog.New(slog.NewTextHandler(io.Discard, nil)), false, 0)
+	if err != nil {
+		t.Fatalf("NewDualDatasetWriter returned unexpected error: %v", err)
+	}
+
+	record := models.KTORecord{Prompt: "p", Completion: "c", Label: true}
+	if err := dw.WriteKTORecord(record, ""); err != nil {
+		t.Fatalf("WriteKTORecord returned unexpected error: %v", err)
+	}
+	dw.SetIncludeIDs(true)
+	if err := dw.WriteKTORecord(record, "thinking"); err != nil {
+		t.Fatalf("WriteKTORecord returned unexpected error: %v", err)
+	}
+	if err := dw.Close(); err != nil {
+		t.Fatalf("Close returned unexpected error: %v", err)
+	}
+
+	regular := readRecordIDs(t, sessionMgr.GetDatasetPath())
+	reasoning := readRecordIDs(t, sessionMgr.GetReasoningDatasetPath())
+	if len(regular) != 2 || regular[0] != "" || regular[1] == "" {
+		t.Fatalf("Expected only the second regular row to carry an ID, got %v", regular)
+	}
+	if len(reasoning) != 2 || reasoning[1] != regular[1] {
+		t.Errorf("Expected reasoning row to share the regular row's ID %s, got %v", regular[1], reasoning)
+	}
+}
@@ -0,0 +1,135 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lamim/vellumforge2/internal/api"
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+// ErrInvalidRecord is wrapped by every record validation error (it is api.ErrInvalidRecord,
+// so failure statistics classify it without matching on the message)
+var ErrInvalidRecord = api.ErrInvalidRecord
+
+// ValidateSFTRecord checks that an SFT record has a non-empty prompt and output
+// The format is detected from the populated fields (messages, conversations, or alpaca)
+func ValidateSFTRecord(record models.SFTRecord) error {
+	switch {
+	case len(record.Messages) > 0:
+		hasAssistant := false
+		for i, msg := range record.Messages {
+			if isBlank(msg.Content) {
+				return invalidRecord("SFT record has empty %s message at index %d", msg.Role, i)
+			}
+			hasAssistant = hasAssistant || msg.Role == "assistant"
+		}
+		if !hasAssistant {
+			return invalidRecord("SFT record has no assistant message")
+		}
+	case len(record.Conversations) > 0:
+		hasOutput := false
+		for i, msg := range record.Conversations {
+			if isBlank(msg.Value) {
+				return invalidRecord("SFT record has empty %s turn at index %d", msg.From, i)
+			}
+			hasOutput = hasOutput || msg.From == "gpt"
+		}
+		if !hasOutput {
+			return invalidRecord("SFT record has no gpt turn")
+		}
+	default:
+		if isBlank(record.Instruction) {
+			return invalidRecord("SFT record has empty instruction")
+		}
+		if isBlank(record.Output) {
+			return invalidRecord("SFT record has empty output")
+		}
+	}
+	return nil
+}
+
+// ValidateDPORecord checks that a DPO record has a non-empty prompt, chosen, and rejected
+func ValidateDPORecord(record models.DPORecord) error {
+	return requireFields("DPO",
+		"prompt", record.Prompt,
+		"chosen", record.Chosen,
+		"rejected", record.Rejected)
+}
+
+// ValidateMultiRejectedDPORecord checks that a multi-rejected DPO record has a non-empty
+// prompt and chosen, and at least one rejected response with none of them empty
+func ValidateMultiRejectedDPORecord(record models.MultiRejectedDPORecord) error {
+	if err := requireFields("DPO", "prompt", record.Prompt, "chosen", record.Chosen); err != nil {
+		return err
+	}
+	if len(record.Rejected) == 0 {
+		return invalidRecord("DPO record has no rejected responses")
+	}
+	for i, rejected := range record.Rejected {
+		if isBlank(rejected) {
+			return invalidRecord("DPO record has empty rejected at index %d", i)
+		}
+	}
+	return nil
+}
+
+// ValidateConversationalDPORecord checks that a conversational DPO record has a user message in
+// the prompt and an assistant message in chosen and rejected, with no empty message content
+func ValidateConversationalDPORecord(record models.ConversationalDPORecord) error {
+	for _, side := range []struct {
+		field    string
+		role     string
+		messages []models.OpenAIMessage
+	}{
+		{"prompt", "user", record.Prompt},
+		{"chosen", "assistant", record.Chosen},
+		{"rejected", "assistant", record.Rejected},
+	} {
+		hasRole := false
+		for i, msg := range side.messages {
+			if isBlank(msg.Content) {
+				return invalidRecord("DPO record has empty %s message at %s index %d", msg.Role, side.field, i)
+			}
+			hasRole = hasRole || msg.Role == side.role
+		}
+		if !hasRole {
+			return invalidRecord("DPO record has no %s message in %s", side.role, side.field)
+		}
+	}
+	return nil
+}
+
+// ValidateKTORecord checks that a KTO record has a non-empty prompt and completion
+func ValidateKTORecord(record models.KTORecord) error {
+	return requireFields("KTO",
+		"prompt", record.Prompt,
+		"completion", record.Completion)
+}
+
+// ValidateDatasetRecord checks that an MO-DPO record has a non-empty prompt, chosen, and rejected
+// Judge scores are not required (they are added asynchronously and may be missing on failure)
+func ValidateDatasetRecord(record models.DatasetRecord) error {
+	return requireFields("MO-DPO",
+		"prompt", record.Prompt,
+		"chosen", record.Chosen,
+		"rejected", record.Rejected)
+}
+
+// requireFields takes alternating field name/value pairs and reports the first blank value
+func requireFields(kind string, pairs ...string) error {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if isBlank(pairs[i+1]) {
+			return invalidRecord("%s record has empty %s", kind, pairs[i])
+		}
+	}
+	return nil
+}
+
+func invalidRecord(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRecord, fmt.Sprintf(format, args...))
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
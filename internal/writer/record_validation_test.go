@@ -0,0 +1,51 @@
+package writer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lamim/vellumforge2/pkg/models"
+)
+
+func TestValidateRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string // substring of expected error, empty for valid records
+	}{
+		{"dpo valid", ValidateDPORecord(models.DPORecord{Prompt: "p", Chosen: "c", Rejected: "r"}), ""},
+		{"dpo empty rejected", ValidateDPORecord(models.DPORecord{Prompt: "p", Chosen: "c", Rejected: "  "}), "empty rejected"},
+		{"dpo empty prompt", ValidateDPORecord(models.DPORecord{Chosen: "c", Rejected: "r"}), "empty prompt"},
+		{"multi valid", ValidateMultiRejectedDPORecord(models.MultiRejectedDPORecord{Prompt: "p", Chosen: "c", Rejected: []string{"r1", "r2"}}), ""},
+		{"multi no rejected", ValidateMultiRejectedDPORecord(models.MultiRejectedDPORecord{Prompt: "p", Chosen: "c"}), "no rejected"},
+		{"multi empty entry", ValidateMultiRejectedDPORecord(models.MultiRejectedDPORecord{Prompt: "p", Chosen: "c", Rejected: []string{"r1", ""}}), "index 1"},
+		{"kto empty completion", ValidateKTORecord(models.KTORecord{Prompt: "p"}), "empty completion"},
+		{"mo-dpo empty chosen", ValidateDatasetRecord(models.DatasetRecord{Prompt: "p", Rejected: "r"}), "empty chosen"},
+		{"alpaca valid", ValidateSFTRecord(models.SFTRecord{Instruction: "p", Output: "o"}), ""},
+		{"alpaca empty output", ValidateSFTRecord(models.SFTRecord{Instruction: "p"}), "empty output"},
+		{"sharegpt empty gpt", ValidateSFTRecord(models.SFTRecord{Conversations: []models.ShareGPTMessage{
+			{From: "human", Value: "p"}, {From: "gpt", Value: ""},
+		}}), "empty gpt turn"},
+		{"openai no assistant", ValidateSFTRecord(models.SFTRecord{Messages: []models.OpenAIMessage{
+			{Role: "user", Content: "p"},
+		}}), "no assistant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == "" {
+				if tt.err != nil {
+					t.Errorf("Expected no error, got: %v", tt.err)
+				}
+				return
+			}
+			if !errors.Is(tt.err, ErrInvalidRecord) {
+				t.Fatalf("Expected ErrInvalidRecord, got: %v", tt.err)
+			}
+			if !strings.Contains(tt.err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, tt.err)
+			}
+		})
+	}
+}
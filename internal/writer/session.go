@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/lamim/vellumforge2/internal/config"
 )
 
 // SessionManager manages session directories and files
@@ -78,11 +80,22 @@ func (sm *SessionManager) GetConfigBackupPath() string {
 	return filepath.Join(sm.sessionDir, "config.toml.bak")
 }
 
-// BackupConfig copies the config file to the session directory
+// GetQualityReportPath returns the full path to the post-generation quality report
+func (sm *SessionManager) GetQualityReportPath() string {
+	return filepath.Join(sm.sessionDir, "quality_report.json")
+}
+
+// GetFailuresPath returns the full path to the failed-jobs log (generation.keep_failed)
+func (sm *SessionManager) GetFailuresPath() string {
+	return filepath.Join(sm.sessionDir, FailuresFile)
+}
+
+// BackupConfig copies the config (file, stdin, or URL source) to the session directory
 func (sm *SessionManager) BackupConfig(configPath string) error {
-	source, err := os.ReadFile(configPath)
+	// ReadSource returns the same bytes Load parsed, also for stdin and URL configs
+	source, err := config.ReadSource(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return err
 	}
 
 	backupPath := sm.GetConfigBackupPath()
@@ -23,8 +23,9 @@ type Checkpoint struct {
 	CurrentPhase CheckpointPhase `json:"current_phase"`
 
 	// Phase 1: Subtopics (completed = we have the full list)
-	SubtopicsComplete bool     `json:"subtopics_complete"`
-	Subtopics         []string `json:"subtopics"`
+	SubtopicsComplete bool           `json:"subtopics_complete"`
+	Subtopics         []string       `json:"subtopics"`
+	SubtopicWeights   map[string]int `json:"subtopic_weights,omitempty"` // Prompt weights above 1, keyed by lowercased subtopic (weighted_subtopics)
 
 	// Phase 2: Prompts (completed = we have all prompts for all subtopics)
 	PromptsComplete   bool            `json:"prompts_complete"`
@@ -32,6 +33,10 @@ type Checkpoint struct {
 	FailedSubtopics   []string        `json:"failed_subtopics"`    // Subtopics that failed prompt generation
 	PromptSuccessRate float64         `json:"prompt_success_rate"` // Success rate for prompt generation phase
 
+	// Multi-topic runs (generation.main_topics): phases 1 and 2 are saved per finished topic
+	TopicSubtopics   map[string][]string `json:"topic_subtopics,omitempty"`    // Subtopics of each topic whose subtopic phase finished
+	PromptTopicsDone []string            `json:"prompt_topics_done,omitempty"` // Topics whose jobs are already in Prompts
+
 	// Phase 3: Preference Pairs (track which jobs are done)
 	CompletedJobIDs map[int]bool `json:"completed_job_ids"` // job_id -> true
 
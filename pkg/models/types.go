@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
 
 // DatasetMode represents the type of dataset to generate
 type DatasetMode string
@@ -22,10 +26,32 @@ type SFTFormat string
 const (
 	SFTFormatAlpaca   SFTFormat = "alpaca"
 	SFTFormatShareGPT SFTFormat = "sharegpt"
+	SFTFormatOpenAI   SFTFormat = "openai" // OpenAI fine-tuning format: {"messages": [{"role": ..., "content": ...}]}
+)
+
+// DPOFormat represents the JSON serialization style for DPO mode outputs
+type DPOFormat string
+
+const (
+	// DPOFormatStandard writes prompt/chosen/rejected as plain strings
+	DPOFormatStandard DPOFormat = "standard"
+	// DPOFormatConversational writes prompt/chosen/rejected as message lists (TRL conversational format)
+	DPOFormatConversational DPOFormat = "conversational"
+)
+
+// RejectedShape controls how multiple rejected responses per prompt are written in DPO mode
+type RejectedShape string
+
+const (
+	// RejectedShapeRows writes one DPO row per rejected response, repeating prompt and chosen
+	RejectedShapeRows RejectedShape = "rows"
+	// RejectedShapeArray writes a single DPO row with all rejected responses in an array
+	RejectedShapeArray RejectedShape = "array"
 )
 
 // DatasetRecord represents a single record in the MO-DPO dataset (full feature set)
 type DatasetRecord struct {
+	ID                 string                   `json:"id,omitempty"` // Set when generation.include_id is enabled
 	MainTopic          string                   `json:"main_topic"`
 	SubTopic           string                   `json:"sub_topic"`
 	Prompt             string                   `json:"prompt"`
@@ -36,6 +62,10 @@ type DatasetRecord struct {
 	ChosenScoreTotal   float64                  `json:"chosen_score_total,omitempty"`
 	RejectedScoreTotal float64                  `json:"rejected_score_total,omitempty"`
 	PreferenceMargin   float64                  `json:"preference_margin,omitempty"`
+	ChosenModel        string                   `json:"chosen_model,omitempty"`   // Set when generation.record_model_assignment is enabled
+	RejectedModel      string                   `json:"rejected_model,omitempty"` // Set when generation.record_model_assignment is enabled
+	JudgeFailed        bool                     `json:"judge_failed,omitempty"`   // Judge evaluation failed or was skipped; scores are missing
+	Meta               *RecordMeta              `json:"_meta,omitempty"`          // Set when generation.include_metadata is enabled
 }
 
 // ShareGPTMessage represents a single conversational turn in ShareGPT format
@@ -44,8 +74,16 @@ type ShareGPTMessage struct {
 	Value string `json:"value"`
 }
 
-// SFTRecord can represent either Alpaca-style or ShareGPT-style outputs
+// OpenAIMessage represents a single chat message in OpenAI fine-tuning format
+type OpenAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SFTRecord can represent Alpaca-style, ShareGPT-style, or OpenAI messages-style outputs
 type SFTRecord struct {
+	ID string `json:"id,omitempty"` // Set when generation.include_id is enabled
+
 	MainTopic string `json:"main_topic,omitempty"`
 	SubTopic  string `json:"sub_topic,omitempty"`
 
@@ -58,26 +96,99 @@ type SFTRecord struct {
 
 	// ShareGPT fields
 	Conversations []ShareGPTMessage `json:"conversations,omitempty"`
+
+	// OpenAI fine-tuning fields
+	Messages []OpenAIMessage `json:"messages,omitempty"`
+
+	Meta *RecordMeta `json:"_meta,omitempty"` // Set when generation.include_metadata is enabled
 }
 
 // DPORecord represents a standard DPO preference pair
 type DPORecord struct {
-	Prompt   string `json:"prompt"`
-	Chosen   string `json:"chosen"`
-	Rejected string `json:"rejected"`
+	ID            string      `json:"id,omitempty"`         // Set when generation.include_id is enabled
+	System        string      `json:"system,omitempty"`     // chosen_system_prompt with generation.include_system_prompt, or the system prompt of OpenAI-format SFT input (transform)
+	MainTopic     string      `json:"main_topic,omitempty"` // Set when generation.main_topics is used
+	Prompt        string      `json:"prompt"`
+	Chosen        string      `json:"chosen"`
+	Rejected      string      `json:"rejected"`
+	ChosenModel   string      `json:"chosen_model,omitempty"`   // Set when generation.record_model_assignment is enabled
+	RejectedModel string      `json:"rejected_model,omitempty"` // Set when generation.record_model_assignment is enabled
+	Meta          *RecordMeta `json:"_meta,omitempty"`          // Set when generation.include_metadata is enabled
+}
+
+// ConversationalDPORecord represents a DPO record in TRL's conversational format:
+// the prompt is a list of system/user messages, chosen and rejected are assistant messages
+type ConversationalDPORecord struct {
+	ID            string          `json:"id,omitempty"`         // Set when generation.include_id is enabled
+	MainTopic     string          `json:"main_topic,omitempty"` // Set when generation.main_topics is used
+	Prompt        []OpenAIMessage `json:"prompt"`
+	Chosen        []OpenAIMessage `json:"chosen"`
+	Rejected      []OpenAIMessage `json:"rejected"`
+	ChosenModel   string          `json:"chosen_model,omitempty"`   // Set when generation.record_model_assignment is enabled
+	RejectedModel string          `json:"rejected_model,omitempty"` // Set when generation.record_model_assignment is enabled
+	Meta          *RecordMeta     `json:"_meta,omitempty"`          // Set when generation.include_metadata is enabled
+}
+
+// MultiRejectedDPORecord represents a DPO record with several rejected responses for one chosen
+type MultiRejectedDPORecord struct {
+	ID             string      `json:"id,omitempty"`         // Set when generation.include_id is enabled
+	System         string      `json:"system,omitempty"`     // Set when generation.include_system_prompt is enabled
+	MainTopic      string      `json:"main_topic,omitempty"` // Set when generation.main_topics is used
+	Prompt         string      `json:"prompt"`
+	Chosen         string      `json:"chosen"`
+	Rejected       []string    `json:"rejected"`
+	ChosenModel    string      `json:"chosen_model,omitempty"`    // Set when generation.record_model_assignment is enabled
+	RejectedModels []string    `json:"rejected_models,omitempty"` // Set when generation.record_model_assignment is enabled
+	Meta           *RecordMeta `json:"_meta,omitempty"`           // Set when generation.include_metadata is enabled
 }
 
 // KTORecord represents an unpaired preference record with binary label
 type KTORecord struct {
-	Prompt     string `json:"prompt"`
-	Completion string `json:"completion"`
-	Label      bool   `json:"label"`
+	ID         string      `json:"id,omitempty"`         // Set when generation.include_id is enabled
+	System     string      `json:"system,omitempty"`     // Set when generation.include_system_prompt is enabled
+	MainTopic  string      `json:"main_topic,omitempty"` // Set when generation.main_topics is used
+	Prompt     string      `json:"prompt"`
+	Completion string      `json:"completion"`
+	Label      bool        `json:"label"`
+	Model      string      `json:"model,omitempty"` // Set when generation.record_model_assignment is enabled
+	Meta       *RecordMeta `json:"_meta,omitempty"` // Set when generation.include_metadata is enabled
 }
 
 // CriteriaScore represents the score and reasoning for a single rubric criterion
 type CriteriaScore struct {
 	Score     int    `json:"score"`
 	Reasoning string `json:"reasoning"`
+	Rounded   bool   `json:"-"` // The judge returned a fractional score that was rounded to the nearest integer
+}
+
+// maxCriteriaScore bounds decoded scores so absurd values still convert to int safely
+const maxCriteriaScore = 1 << 30
+
+// UnmarshalJSON decodes a criterion score and fills Reasoning from the "rationale" or
+// "explanation" field some judge models use instead of "reasoning"
+// Fractional scores are rounded to the nearest integer and flagged with Rounded
+func (c *CriteriaScore) UnmarshalJSON(data []byte) error {
+	type criteriaScore CriteriaScore // avoids recursing into this method
+	var raw struct {
+		criteriaScore
+		Score       float64 `json:"score"` // Shadows the embedded int so fractional scores decode
+		Rationale   string  `json:"rationale"`
+		Explanation string  `json:"explanation"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = CriteriaScore(raw.criteriaScore)
+	rounded := math.Round(max(min(raw.Score, maxCriteriaScore), -maxCriteriaScore))
+	c.Score = int(rounded)
+	c.Rounded = raw.Score != math.Trunc(raw.Score)
+	if c.Reasoning == "" {
+		c.Reasoning = raw.Rationale
+	}
+	if c.Reasoning == "" {
+		c.Reasoning = raw.Explanation
+	}
+	return nil
 }
 
 // GenerationJob represents a task to generate a preference pair
@@ -88,13 +199,59 @@ type GenerationJob struct {
 	Prompt    string
 }
 
+// RejectedResponse is a single rejected candidate generated for a job
+type RejectedResponse struct {
+	Content   string
+	Reasoning string // Chain-of-Thought reasoning (if captured)
+	Model     string
+	Attempts  AttemptCounts // Retries spent on this candidate
+}
+
+// AttemptCounts counts the retries behind one response
+type AttemptCounts struct {
+	Network int `json:"network"` // Requests the API client retried after errors, 429s or timeouts
+	Content int `json:"content"` // Regenerations of a received response (truncation retry, identical pair regen)
+}
+
+// Add returns the sum of two counts
+func (a AttemptCounts) Add(b AttemptCounts) AttemptCounts {
+	return AttemptCounts{Network: a.Network + b.Network, Content: a.Content + b.Content}
+}
+
+// Retried reports whether any retry was needed
+func (a AttemptCounts) Retried() bool {
+	return a.Network > 0 || a.Content > 0
+}
+
+// RecordAttempts holds the retries behind each side of a record
+type RecordAttempts struct {
+	Chosen   *AttemptCounts `json:"chosen,omitempty"`
+	Rejected *AttemptCounts `json:"rejected,omitempty"` // Summed over the record's rejected candidates
+}
+
+// RecordMeta is the _meta column written with generation.include_metadata
+// Rows kept with length_ratio_action = "flag" always carry it, with only the length fields
+// when include_metadata is off
+type RecordMeta struct {
+	Attempts           RecordAttempts `json:"attempts,omitzero"`
+	LengthRatioFlagged bool           `json:"length_ratio_flagged,omitempty"` // Chosen/rejected length ratio is outside length_ratio_min/max
+	LengthRatio        float64        `json:"length_ratio,omitempty"`         // The out-of-range ratio (left out when a rejected response is empty)
+}
+
 // GenerationResult represents the result of generating a preference pair
 type GenerationResult struct {
 	Job               GenerationJob
 	Chosen            string
 	ChosenReasoning   string // Chain-of-Thought reasoning for chosen response (if captured)
 	Rejected          string
-	RejectedReasoning string // Chain-of-Thought reasoning for rejected response (if captured)
+	RejectedReasoning string             // Chain-of-Thought reasoning for rejected response (if captured)
+	ChosenModel       string             // Model that generated the chosen response
+	RejectedModel     string             // Model that generated the rejected response (empty in SFT mode)
+	Swapped           bool               // True when swap_probability assigned the rejected model to the chosen side
+	ChosenAttempts    AttemptCounts      // Retries spent on the chosen response
+	RejectedAttempts  AttemptCounts      // Retries spent on the rejected responses, summed over candidates
+	RejectedList      []RejectedResponse // All rejected candidates when num_rejected > 1 (Rejected mirrors the first)
+	LengthRatioFlag   *float64           // Out-of-range length ratio of a record kept with length_ratio_action = "flag"
 	JudgeResult       *JudgeResult
 	Error             error
 	Duration          time.Duration
@@ -111,12 +268,26 @@ type JudgeResult struct {
 
 // SessionStats tracks statistics for a generation session
 type SessionStats struct {
-	StartTime       time.Time
-	EndTime         time.Time
-	TotalPrompts    int
-	SuccessCount    int
-	FailureCount    int
-	FilteredCount   int // Number of records filtered by judge
-	TotalDuration   time.Duration
-	AverageDuration time.Duration
+	StartTime             time.Time
+	EndTime               time.Time
+	TotalPrompts          int
+	SuccessCount          int
+	FailureCount          int
+	FilteredCount         int                 // Number of records filtered by judge
+	LengthRatioFiltered   int                 // Records dropped for a chosen/rejected length ratio outside generation.length_ratio_min/max
+	LengthRatioFlagged    int                 // Out-of-range length ratio records written anyway (length_ratio_action = "flag")
+	ErrorCounts           map[string]int      // Failures broken down by error class (rate_limit, timeout, auth, ...)
+	JudgeSuccesses        int                 // MO-DPO judge evaluations that returned scores
+	JudgeFailures         int                 // MO-DPO judge evaluations that failed or were skipped by the circuit breaker
+	JudgeCacheHits        int                 // Judge evaluations answered from the judge cache (generation.enable_judge_cache)
+	JudgeCacheMisses      int                 // Judge evaluations with no cached scores, sent to the judge model
+	JSONReformatAttempts  int                 // Reformat requests sent for unparseable subtopic/prompt JSON
+	JSONReformatSuccesses int                 // Reformat requests that produced valid JSON
+	TruncationRetries     int                 // Chosen responses retried with a higher max_tokens after finish_reason "length"
+	TruncationRecoveries  int                 // Truncation retries that finished within the raised limit
+	RetriedJobs           int                 // Written jobs that needed at least one network or content retry
+	SystemFingerprints    map[string][]string // system_fingerprint values each model returned, in the order first seen
+	OutputCapped          bool                // Dispatch stopped early because the dataset reached generation.max_output_bytes
+	TotalDuration         time.Duration
+	AverageDuration       time.Duration
 }